@@ -2,11 +2,14 @@ package core
 
 import (
 	// The following are necessary as they register handlers in their init functions.
+	_ "v2ray.com/core/app/api"
 	_ "v2ray.com/core/app/dns"
+	_ "v2ray.com/core/app/observatory"
 	_ "v2ray.com/core/app/proxy"
 	_ "v2ray.com/core/app/router"
 
 	_ "v2ray.com/core/proxy/blackhole"
+	_ "v2ray.com/core/proxy/dnsserver"
 	_ "v2ray.com/core/proxy/dokodemo"
 	_ "v2ray.com/core/proxy/freedom"
 	_ "v2ray.com/core/proxy/http"
@@ -15,6 +18,7 @@ import (
 	_ "v2ray.com/core/proxy/vmess/inbound"
 	_ "v2ray.com/core/proxy/vmess/outbound"
 
+	_ "v2ray.com/core/transport/internet/grpc"
 	_ "v2ray.com/core/transport/internet/kcp"
 	_ "v2ray.com/core/transport/internet/tcp"
 	_ "v2ray.com/core/transport/internet/tls"
@@ -1,12 +1,132 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
 	"v2ray.com/core/app"
+	"v2ray.com/core/common/loader"
+	"v2ray.com/core/common/log"
+	"v2ray.com/core/transport/internet"
 )
 
 const (
 	APP_ID = app.ID(5)
 )
 
+var (
+	ErrInvalidRequest = errors.New("Api: Invalid request.")
+)
+
+// ApiServer is an admin API over HTTP. Other components register their handlers through Handle()
+// during application initialization.
 type ApiServer struct {
+	sync.Mutex
+	config   *Config
+	mux      *http.ServeMux
+	handlers map[string]*handlerSlot
+	listener net.Listener
+}
+
+// handlerSlot serves with the latest handler registered for its pattern.
+type handlerSlot struct {
+	handler atomic.Value
+}
+
+func (this *handlerSlot) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	this.handler.Load().(http.Handler).ServeHTTP(writer, request)
+}
+
+func NewApiServer(space app.Space, config *Config) *ApiServer {
+	server := &ApiServer{
+		config:   config,
+		mux:      http.NewServeMux(),
+		handlers: make(map[string]*handlerSlot),
+	}
+	server.Handle("/inbound/throttled", NewCounterHandler(internet.ThrottledConnections))
+	space.InitializeApplication(func() error {
+		return server.Start()
+	})
+	return server
+}
+
+// Handle registers a handler for the given path pattern. It replaces the handler registered before for
+// the same pattern, e.g., by an outbound that is reloaded.
+func (this *ApiServer) Handle(pattern string, handler http.Handler) {
+	this.Lock()
+	defer this.Unlock()
+
+	slot, found := this.handlers[pattern]
+	if !found {
+		slot = new(handlerSlot)
+		this.handlers[pattern] = slot
+		this.mux.Handle(pattern, slot)
+	}
+	slot.handler.Store(handler)
+}
+
+func (this *ApiServer) Start() error {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.listener != nil {
+		return nil
+	}
+
+	dest := this.config.GetListenDestination()
+	listener, err := net.Listen("tcp", dest.NetAddr())
+	if err != nil {
+		log.Error("Api: Failed to listen on ", dest, ": ", err)
+		return err
+	}
+	this.listener = listener
+	log.Info("Api: Listening on ", dest)
+
+	go http.Serve(listener, this.mux)
+	return nil
+}
+
+func (this *ApiServer) Release() {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.listener != nil {
+		this.listener.Close()
+		this.listener = nil
+	}
+}
+
+// WriteJSON writes the given value as JSON response.
+func WriteJSON(writer http.ResponseWriter, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(writer).Encode(value); err != nil {
+		log.Warning("Api: Failed to write response: ", err)
+	}
+}
+
+// WriteError writes an error response with the given status code.
+func WriteError(writer http.ResponseWriter, code int, err error) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(code)
+	json.NewEncoder(writer).Encode(map[string]string{
+		"error": err.Error(),
+	})
+}
+
+type ApiServerFactory struct{}
+
+func (ApiServerFactory) Create(space app.Space, config interface{}) (app.Application, error) {
+	return NewApiServer(space, config.(*Config)), nil
+}
+
+func (ApiServerFactory) AppId() app.ID {
+	return APP_ID
+}
+
+func init() {
+	app.RegisterApplicationFactory(loader.GetType(new(Config)), ApiServerFactory{})
 }
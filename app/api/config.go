@@ -4,6 +4,13 @@ import (
 	v2net "v2ray.com/core/common/net"
 )
 
-type Config struct {
-	DirectPort v2net.Port
+func (this *Config) GetListenAddress() v2net.Address {
+	if this.Listen == nil {
+		return v2net.LocalHostIP
+	}
+	return this.Listen.AsAddress()
+}
+
+func (this *Config) GetListenDestination() v2net.Destination {
+	return v2net.TCPDestination(this.GetListenAddress(), v2net.Port(this.Port))
 }
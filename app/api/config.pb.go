@@ -0,0 +1,71 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/app/api/config.proto
+// DO NOT EDIT!
+
+/*
+Package api is a generated protocol buffer package.
+
+It is generated from these files:
+	v2ray.com/core/app/api/config.proto
+
+It has these top-level messages:
+	Config
+*/
+package api
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import v2ray_core_common_net "v2ray.com/core/common/net"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type Config struct {
+	// IP address for the admin API to listen on. 127.0.0.1 if unset.
+	Listen *v2ray_core_common_net.IPOrDomain `protobuf:"bytes,1,opt,name=listen" json:"listen,omitempty"`
+	// Port of the admin API.
+	Port uint32 `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+}
+
+func (m *Config) Reset()                    { *m = Config{} }
+func (m *Config) String() string            { return proto.CompactTextString(m) }
+func (*Config) ProtoMessage()               {}
+func (*Config) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *Config) GetListen() *v2ray_core_common_net.IPOrDomain {
+	if m != nil {
+		return m.Listen
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Config)(nil), "v2ray.core.app.api.Config")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/app/api/config.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 192 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x52, 0x2e, 0x33, 0x2a, 0x4a,
+	0xac, 0xd4, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xce, 0x2f, 0x4a, 0xd5, 0x4f, 0x2c, 0x28, 0xd0, 0x4f,
+	0x2c, 0xc8, 0xd4, 0x4f, 0xce, 0xcf, 0x4b, 0xcb, 0x4c, 0xd7, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17,
+	0x12, 0x82, 0x29, 0x2a, 0x4a, 0xd5, 0x4b, 0x2c, 0x28, 0xd0, 0x4b, 0x2c, 0xc8, 0x94, 0x52, 0x47,
+	0xd3, 0x98, 0x9c, 0x9f, 0x9b, 0x9b, 0x9f, 0xa7, 0x9f, 0x97, 0x5a, 0xa2, 0x9f, 0x98, 0x92, 0x52,
+	0x94, 0x5a, 0x5c, 0x0c, 0xd1, 0xac, 0x14, 0xce, 0xc5, 0xe6, 0x0c, 0x36, 0x4c, 0xc8, 0x92, 0x8b,
+	0x2d, 0x27, 0xb3, 0xb8, 0x24, 0x35, 0x4f, 0x82, 0x51, 0x81, 0x51, 0x83, 0xdb, 0x48, 0x51, 0x0f,
+	0xc9, 0x5c, 0x88, 0x7e, 0xbd, 0xbc, 0xd4, 0x12, 0x3d, 0xcf, 0x00, 0xff, 0x22, 0x97, 0xfc, 0xdc,
+	0xc4, 0xcc, 0xbc, 0x20, 0xa8, 0x06, 0x21, 0x21, 0x2e, 0x96, 0x82, 0xfc, 0xa2, 0x12, 0x09, 0x26,
+	0x05, 0x46, 0x0d, 0xde, 0x20, 0x30, 0xdb, 0x49, 0x8b, 0x4b, 0x2c, 0x39, 0x3f, 0x57, 0x0f, 0xd3,
+	0x6d, 0x4e, 0xdc, 0x10, 0x0b, 0x03, 0x40, 0xf6, 0x47, 0x31, 0x27, 0x16, 0x64, 0x26, 0xb1, 0x81,
+	0xdd, 0x62, 0x0c, 0x08, 0x00, 0x00, 0xff, 0xff, 0x1b, 0xe8, 0xc1, 0xfa, 0xef, 0x00, 0x00, 0x00,
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+
+	"v2ray.com/core/common/protocol"
+)
+
+const (
+	// Redacted replaces secrets, e.g., passwords and keys, in states returned by the API.
+	Redacted = "[redacted]"
+)
+
+type UserState struct {
+	Email string `json:"email,omitempty"`
+	Level uint32 `json:"level"`
+	// Always Redacted if the user has an account.
+	Account string `json:"account,omitempty"`
+}
+
+type ServerState struct {
+	Server  string      `json:"server"`
+	Weight  uint32      `json:"weight"`
+	Circuit string      `json:"circuit"`
+	Users   []UserState `json:"users"`
+}
+
+// NewServerStates returns the current states of servers in serverList, without secrets of their users.
+func NewServerStates(serverList *protocol.ServerList) []ServerState {
+	servers := serverList.Servers()
+	states := make([]ServerState, len(servers))
+	for idx, server := range servers {
+		users := server.Users()
+		state := ServerState{
+			Server:  server.Destination().NetAddr(),
+			Weight:  server.Weight(),
+			Circuit: server.CircuitBreaker().State().String(),
+			Users:   make([]UserState, len(users)),
+		}
+		for userIdx, user := range users {
+			state.Users[userIdx] = UserState{
+				Email: user.Email,
+				Level: user.Level,
+			}
+			if user.Account != nil {
+				state.Users[userIdx].Account = Redacted
+			}
+		}
+		states[idx] = state
+	}
+	return states
+}
+
+// ConfigHandler returns the running config from snapshot on GET. The snapshot must not contain secrets.
+type ConfigHandler struct {
+	snapshot func() (interface{}, error)
+}
+
+func NewConfigHandler(snapshot func() (interface{}, error)) *ConfigHandler {
+	return &ConfigHandler{
+		snapshot: snapshot,
+	}
+}
+
+func (this *ConfigHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		WriteError(writer, http.StatusMethodNotAllowed, ErrInvalidRequest)
+		return
+	}
+	state, err := this.snapshot()
+	if err != nil {
+		WriteError(writer, http.StatusInternalServerError, err)
+		return
+	}
+	WriteJSON(writer, state)
+}
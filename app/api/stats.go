@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"v2ray.com/core/common/log"
+	"v2ray.com/core/common/stats"
+)
+
+// CounterHandler exposes counters in a CounterSet.
+// GET returns current values. POST returns values accumulated since last reset, and resets them to zero.
+type CounterHandler struct {
+	counters *stats.CounterSet
+}
+
+func NewCounterHandler(counters *stats.CounterSet) *CounterHandler {
+	return &CounterHandler{
+		counters: counters,
+	}
+}
+
+func (this *CounterHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+		WriteJSON(writer, this.counters.Snapshot())
+	case http.MethodPost:
+		WriteJSON(writer, this.counters.SnapshotAndReset())
+		log.Info("Api: Counters reset by ", request.RemoteAddr)
+	default:
+		WriteError(writer, http.StatusMethodNotAllowed, ErrInvalidRequest)
+	}
+}
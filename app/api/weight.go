@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+)
+
+const (
+	MaxServerWeight = 10000
+)
+
+type ServerWeight struct {
+	Server string `json:"server"`
+	Weight uint32 `json:"weight"`
+}
+
+// ServerWeightHandler reads and adjusts weights of servers in a ServerList.
+// GET returns all weights. POST with a ServerWeight body updates the weight of one server.
+type ServerWeightHandler struct {
+	serverList *protocol.ServerList
+}
+
+func NewServerWeightHandler(serverList *protocol.ServerList) *ServerWeightHandler {
+	return &ServerWeightHandler{
+		serverList: serverList,
+	}
+}
+
+func (this *ServerWeightHandler) Weights() []ServerWeight {
+	servers := this.serverList.Servers()
+	weights := make([]ServerWeight, len(servers))
+	for idx, server := range servers {
+		weights[idx] = ServerWeight{
+			Server: server.Destination().NetAddr(),
+			Weight: server.Weight(),
+		}
+	}
+	return weights
+}
+
+func (this *ServerWeightHandler) SetWeight(request *ServerWeight) error {
+	if request.Weight > MaxServerWeight {
+		return errors.New("Api: Weight must not be larger than 10000.")
+	}
+	host, rawPort, err := net.SplitHostPort(request.Server)
+	if err != nil {
+		return errors.New("Api: Invalid server address: " + request.Server)
+	}
+	port, err := v2net.PortFromString(rawPort)
+	if err != nil {
+		return errors.New("Api: Invalid server port: " + rawPort)
+	}
+	server := this.serverList.FindServer(v2net.TCPDestination(v2net.ParseAddress(host), port))
+	if server == nil {
+		return errors.New("Api: Server not found: " + request.Server)
+	}
+	server.SetWeight(request.Weight)
+	log.Info("Api: Weight of server ", request.Server, " is set to ", request.Weight)
+	return nil
+}
+
+func (this *ServerWeightHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut:
+		weight := new(ServerWeight)
+		if err := json.NewDecoder(request.Body).Decode(weight); err != nil {
+			WriteError(writer, http.StatusBadRequest, ErrInvalidRequest)
+			return
+		}
+		if err := this.SetWeight(weight); err != nil {
+			WriteError(writer, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		WriteError(writer, http.StatusMethodNotAllowed, ErrInvalidRequest)
+		return
+	}
+	WriteJSON(writer, this.Weights())
+}
@@ -6,14 +6,15 @@ import (
 	"v2ray.com/core/app/router"
 	"v2ray.com/core/common/alloc"
 	"v2ray.com/core/common/log"
-	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/ray"
 )
 
 type DefaultDispatcher struct {
-	ohm    proxyman.OutboundHandlerManager
-	router *router.Router
+	ohm        proxyman.OutboundHandlerManager
+	router     *router.Router
+	failClosed bool
 }
 
 func NewDefaultDispatcher(space app.Space) *DefaultDispatcher {
@@ -43,16 +44,67 @@ func (this *DefaultDispatcher) Release() {
 
 }
 
+// SetFailClosed sets whether connections routed to a nonexisting outbound are dropped, instead of going
+// through the default outbound.
+func (this *DefaultDispatcher) SetFailClosed(failClosed bool) {
+	this.failClosed = failClosed
+}
+
 func (this *DefaultDispatcher) DispatchToOutbound(session *proxy.SessionInfo) ray.InboundRay {
 	direct := ray.NewRay()
+	passive := session.Inbound != nil && session.Inbound.AllowPassiveConnection
+
+	if !passive && this.router != nil && this.router.NeedsProtocol() {
+		// The first payload is awaited before dispatching anyway, so sniffing doesn't delay the session.
+		go this.sniffAndDispatch(session, direct)
+		return direct
+	}
+
+	dispatcher, session, err := this.route(session)
+	if err != nil {
+		reject(direct, err)
+		return direct
+	}
+
+	if passive {
+		go proxy.DispatchSession(dispatcher, session, alloc.NewLocalBuffer(32).Clear(), direct)
+	} else {
+		go this.FilterPacketAndDispatch(session, direct, dispatcher)
+	}
+
+	return direct
+}
+
+// route picks the outbound for session, and returns it with a copy of session that has the route. It
+// returns an error if session should be rejected.
+func (this *DefaultDispatcher) route(session *proxy.SessionInfo) (proxy.OutboundHandler, *proxy.SessionInfo, error) {
 	dispatcher := this.ohm.GetDefaultHandler()
 	destination := session.Destination
+	dispatcherTag := ""
+	route := proxy.DefaultRoute()
 
-	if this.router != nil {
-		if tag, err := this.router.TakeDetour(session); err == nil {
+	if tag := session.OutboundTag; len(tag) > 0 {
+		// Clients select outbounds to avoid others, so the session doesn't fall back to the default.
+		handler := this.ohm.GetHandler(tag)
+		if handler == nil {
+			log.Warning("DefaultDispatcher: Nonexisting selected tag: ", tag, ". Rejecting [", destination, "].")
+			return nil, session, proxy.ErrConnectionRejected
+		}
+		log.Info("DefaultDispatcher: Taking selected outbound [", tag, "] for [", destination, "].")
+		dispatcher = handler
+		dispatcherTag = tag
+		route = proxy.SelectedRoute(tag)
+	} else if this.router != nil {
+		if picked, err := this.router.PickRoute(session); err == nil {
+			tag := picked.OutboundTag
 			if handler := this.ohm.GetHandler(tag); handler != nil {
-				log.Info("DefaultDispatcher: Taking detour [", tag, "] for [", destination, "].")
+				log.Info("DefaultDispatcher: Taking detour [", tag, "] for [", destination, "] by ", picked, ".")
 				dispatcher = handler
+				dispatcherTag = tag
+				route = picked
+			} else if this.failClosed {
+				log.Warning("DefaultDispatcher: Nonexisting tag: ", tag, ". Rejecting [", destination, "].")
+				return nil, session, proxy.ErrConnectionRejected
 			} else {
 				log.Warning("DefaultDispatcher: Nonexisting tag: ", tag)
 			}
@@ -60,24 +112,70 @@ func (this *DefaultDispatcher) DispatchToOutbound(session *proxy.SessionInfo) ra
 			log.Info("DefaultDispatcher: Default route for ", destination)
 		}
 	}
+	if !proxy.SupportsNetwork(dispatcher, destination.Network) {
+		// The default outbound takes the session instead, unless fail-closed or selected by the client.
+		defaultHandler := this.ohm.GetDefaultHandler()
+		if this.failClosed || route.Reason == proxy.RouteSelected || defaultHandler == nil || dispatcher == defaultHandler || !proxy.SupportsNetwork(defaultHandler, destination.Network) {
+			log.Warning("DefaultDispatcher: Outbound [", dispatcherTag, "] doesn't support ", destination.Network, ". Rejecting [", destination, "].")
+			return nil, session, proxy.ErrNetworkUnsupported
+		}
+		log.Warning("DefaultDispatcher: Outbound [", dispatcherTag, "] doesn't support ", destination.Network, ". Taking default route for [", destination, "].")
+		dispatcher = defaultHandler
+		dispatcherTag = ""
+		route = proxy.DefaultRoute()
+	}
+	// The session may be shared by other dispatches of the inbound.
+	routed := *session
+	routed.Route = route
+	// Outbounds see the tags of the connection, which are those of its inbound unless set by it.
+	routed.Tags = session.GetTags()
 
-	if session.Inbound != nil && session.Inbound.AllowPassiveConnection {
-		go dispatcher.Dispatch(destination, alloc.NewLocalBuffer(32).Clear(), direct)
-	} else {
-		go this.FilterPacketAndDispatch(destination, direct, dispatcher)
+	if chain := session.Chain.Append(internet.FindOutboundChain(session.Source)...); len(chain) > 0 {
+		if proxyman.IsInChain(this.ohm, dispatcher, chain) {
+			log.Warning("DefaultDispatcher: Loop detected in outbound chain ", chain, ". Rejecting [", destination, "].")
+			return nil, session, proxy.ErrConnectionRejected
+		}
+		// Connections the outbound dials for the session carry the chain.
+		routed.Chain = chain
 	}
+	return dispatcher, &routed, nil
+}
 
-	return direct
+// sniffAndDispatch reads the first payload in link, and dispatches the session by its protocol.
+func (this *DefaultDispatcher) sniffAndDispatch(session *proxy.SessionInfo, link ray.OutboundRay) {
+	payload, err := link.OutboundInput().Read()
+	if err != nil {
+		log.Info("DefaultDispatcher: No payload towards ", session.Destination, ", stopping now.")
+		link.OutboundInput().Release()
+		link.OutboundOutput().Release()
+		return
+	}
+	sniffed := *session
+	sniffed.Protocol = proxy.SniffProtocol(payload.Value)
+
+	dispatcher, routed, err := this.route(&sniffed)
+	if err != nil {
+		payload.Release()
+		reject(link, err)
+		return
+	}
+	proxy.DispatchSession(dispatcher, routed, payload, link)
+}
+
+// reject closes link with err, without dispatching it to any outbound.
+func reject(link ray.OutboundRay, err error) {
+	link.OutboundInput().Release()
+	link.OutboundOutput().CloseError(err)
 }
 
 // Private: Visible for testing.
-func (this *DefaultDispatcher) FilterPacketAndDispatch(destination v2net.Destination, link ray.OutboundRay, dispatcher proxy.OutboundHandler) {
+func (this *DefaultDispatcher) FilterPacketAndDispatch(session *proxy.SessionInfo, link ray.OutboundRay, dispatcher proxy.OutboundHandler) {
 	payload, err := link.OutboundInput().Read()
 	if err != nil {
-		log.Info("DefaultDispatcher: No payload towards ", destination, ", stopping now.")
+		log.Info("DefaultDispatcher: No payload towards ", session.Destination, ", stopping now.")
 		link.OutboundInput().Release()
 		link.OutboundOutput().Release()
 		return
 	}
-	dispatcher.Dispatch(destination, payload, link)
+	proxy.DispatchSession(dispatcher, session, payload, link)
 }
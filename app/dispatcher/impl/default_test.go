@@ -0,0 +1,352 @@
+package impl_test
+
+import (
+	"net"
+	"testing"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/dispatcher"
+	. "v2ray.com/core/app/dispatcher/impl"
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	_ "v2ray.com/core/transport/internet/tcp"
+	"v2ray.com/core/transport/ray"
+)
+
+type countingOutbound struct {
+	dispatched chan v2net.Destination
+}
+
+func (this *countingOutbound) Dispatch(destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	payload.Release()
+	link.OutboundInput().Release()
+	link.OutboundOutput().Close()
+	this.dispatched <- destination
+	return nil
+}
+
+func setupDispatcher(assert *assert.Assert, failClosed bool) (*DefaultDispatcher, *countingOutbound) {
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+
+	outbound := &countingOutbound{
+		dispatched: make(chan v2net.Destination, 1),
+	}
+	outboundManager := proxyman.NewDefaultOutboundHandlerManager()
+	outboundManager.SetDefaultHandler(outbound)
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, outboundManager)
+
+	space.BindApp(router.APP_ID, router.NewRouter(&router.Config{
+		Rule: []*router.RoutingRule{
+			{
+				Tag: "missing",
+				NetworkList: &v2net.NetworkList{
+					Network: []v2net.Network{v2net.Network_TCP},
+				},
+			},
+		},
+	}, space))
+
+	d := NewDefaultDispatcher(space)
+	d.SetFailClosed(failClosed)
+	space.BindApp(dispatcher.APP_ID, d)
+	assert.Error(space.Initialize()).IsNil()
+	return d, outbound
+}
+
+func dispatch(d *DefaultDispatcher) ray.InboundRay {
+	link := d.DispatchToOutbound(&proxy.SessionInfo{
+		Source:      v2net.TCPDestination(v2net.LocalHostIP, 10000),
+		Destination: v2net.TCPDestination(v2net.IPAddress([]byte{1, 2, 3, 4}), 80),
+	})
+	link.InboundInput().Write(alloc.NewLocalBuffer(32).Clear().AppendString("test"))
+	link.InboundInput().Close()
+	return link
+}
+
+func TestMissingTagUsesDefaultOutbound(t *testing.T) {
+	assert := assert.On(t)
+
+	d, outbound := setupDispatcher(assert, false)
+	dispatch(d)
+	dest := <-outbound.dispatched
+	assert.Port(dest.Port).Equals(v2net.Port(80))
+}
+
+func TestFailClosedRejectsMissingTag(t *testing.T) {
+	assert := assert.On(t)
+
+	d, outbound := setupDispatcher(assert, true)
+	link := dispatch(d)
+
+	_, err := link.InboundOutput().Read()
+	assert.Error(err).IsNotNil()
+	select {
+	case <-outbound.dispatched:
+		t.Error("Connection is dispatched to default outbound.")
+	default:
+	}
+}
+
+func TestRouteBySniffedProtocol(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+
+	defaultOutbound := &countingOutbound{
+		dispatched: make(chan v2net.Destination, 1),
+	}
+	tlsOutbound := &countingOutbound{
+		dispatched: make(chan v2net.Destination, 1),
+	}
+	outboundManager := proxyman.NewDefaultOutboundHandlerManager()
+	outboundManager.SetDefaultHandler(defaultOutbound)
+	outboundManager.SetHandler("tls", tlsOutbound)
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, outboundManager)
+
+	space.BindApp(router.APP_ID, router.NewRouter(&router.Config{
+		Rule: []*router.RoutingRule{
+			{
+				Tag:      "tls",
+				Protocol: []string{proxy.ProtocolTLS},
+			},
+		},
+	}, space))
+
+	d := NewDefaultDispatcher(space)
+	space.BindApp(dispatcher.APP_ID, d)
+	assert.Error(space.Initialize()).IsNil()
+
+	dispatch(d)
+	dest := <-defaultOutbound.dispatched
+	assert.Port(dest.Port).Equals(v2net.Port(80))
+
+	link := d.DispatchToOutbound(&proxy.SessionInfo{
+		Source:      v2net.TCPDestination(v2net.LocalHostIP, 10000),
+		Destination: v2net.TCPDestination(v2net.IPAddress([]byte{1, 2, 3, 4}), 443),
+	})
+	link.InboundInput().Write(alloc.NewLocalBuffer(32).Clear().Append([]byte{0x16, 0x03, 0x01, 0x00, 0x10, 0x01}))
+	link.InboundInput().Close()
+	dest = <-tlsOutbound.dispatched
+	assert.Port(dest.Port).Equals(v2net.Port(443))
+}
+
+// tcpOnlyOutbound is an outbound that can't reach UDP destinations.
+type tcpOnlyOutbound struct {
+	countingOutbound
+}
+
+func (this *tcpOnlyOutbound) SupportsNetwork(network v2net.Network) bool {
+	return network != v2net.Network_UDP
+}
+
+func TestNetworkMismatch(t *testing.T) {
+	assert := assert.On(t)
+
+	for _, failClosed := range []bool{false, true} {
+		space := app.NewSpace()
+		space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+
+		defaultOutbound := &countingOutbound{
+			dispatched: make(chan v2net.Destination, 1),
+		}
+		tcpOutbound := &tcpOnlyOutbound{
+			countingOutbound: countingOutbound{
+				dispatched: make(chan v2net.Destination, 1),
+			},
+		}
+		outboundManager := proxyman.NewDefaultOutboundHandlerManager()
+		outboundManager.SetDefaultHandler(defaultOutbound)
+		outboundManager.SetHandler("tcp-only", tcpOutbound)
+		space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, outboundManager)
+
+		space.BindApp(router.APP_ID, router.NewRouter(&router.Config{
+			Rule: []*router.RoutingRule{
+				{
+					Tag: "tcp-only",
+					NetworkList: &v2net.NetworkList{
+						Network: []v2net.Network{v2net.Network_TCP, v2net.Network_UDP},
+					},
+				},
+			},
+		}, space))
+
+		d := NewDefaultDispatcher(space)
+		d.SetFailClosed(failClosed)
+		space.BindApp(dispatcher.APP_ID, d)
+		assert.Error(space.Initialize()).IsNil()
+
+		dispatch(d)
+		dest := <-tcpOutbound.dispatched
+		assert.Port(dest.Port).Equals(v2net.Port(80))
+
+		link := d.DispatchToOutbound(&proxy.SessionInfo{
+			Source:      v2net.UDPDestination(v2net.LocalHostIP, 10000),
+			Destination: v2net.UDPDestination(v2net.IPAddress([]byte{1, 2, 3, 4}), 53),
+		})
+		link.InboundInput().Write(alloc.NewLocalBuffer(32).Clear().AppendString("test"))
+		link.InboundInput().Close()
+		if failClosed {
+			assert.Error(link.InboundOutput().WaitEstablished()).Equals(proxy.ErrNetworkUnsupported)
+		} else {
+			dest = <-defaultOutbound.dispatched
+			assert.Port(dest.Port).Equals(v2net.Port(53))
+		}
+	}
+}
+
+func TestMissingSelectedTagIsRejected(t *testing.T) {
+	assert := assert.On(t)
+
+	d, outbound := setupDispatcher(assert, false)
+	link := d.DispatchToOutbound(&proxy.SessionInfo{
+		Source:      v2net.TCPDestination(v2net.LocalHostIP, 10000),
+		Destination: v2net.UDPDestination(v2net.IPAddress([]byte{1, 2, 3, 4}), 53),
+		OutboundTag: "missing",
+	})
+	link.InboundInput().Close()
+
+	_, err := link.InboundOutput().Read()
+	assert.Error(err).IsNotNil()
+	select {
+	case <-outbound.dispatched:
+		t.Error("Connection is dispatched to default outbound.")
+	default:
+	}
+}
+
+type chainOutbound struct {
+	chains chan internet.OutboundChain
+}
+
+func (this *chainOutbound) Dispatch(destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	return this.DispatchSession(&proxy.SessionInfo{Destination: destination}, payload, link)
+}
+
+func (this *chainOutbound) DispatchSession(session *proxy.SessionInfo, payload *alloc.Buffer, link ray.OutboundRay) error {
+	payload.Release()
+	link.OutboundInput().Release()
+	link.OutboundOutput().Close()
+	this.chains <- session.Chain
+	return nil
+}
+
+func TestOutboundChainOfSession(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+	outboundA := &chainOutbound{
+		chains: make(chan internet.OutboundChain, 1),
+	}
+	outboundC := &chainOutbound{
+		chains: make(chan internet.OutboundChain, 1),
+	}
+	outboundManager := proxyman.NewDefaultOutboundHandlerManager()
+	outboundManager.SetDefaultHandler(outboundC)
+	outboundManager.SetHandler("a", outboundA)
+	outboundManager.SetHandler("c", outboundC)
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, outboundManager)
+	d := NewDefaultDispatcher(space)
+	space.BindApp(dispatcher.APP_ID, d)
+	assert.Error(space.Initialize()).IsNil()
+
+	// The connection comes back from outbound "b", which carries a session of outbound "a".
+	conn, err := internet.Dial(v2net.LocalHostIP, v2net.DestinationFromAddr(listener.Addr()), internet.DialerOptions{
+		Stream: &internet.StreamConfig{Network: v2net.Network_RawTCP},
+		Tag:    "b",
+		Chain:  internet.OutboundChain{"a"},
+	})
+	assert.Error(err).IsNil()
+	source := v2net.DestinationFromAddr(conn.LocalAddr())
+	dispatchTo := func(tag string) ray.InboundRay {
+		link := d.DispatchToOutbound(&proxy.SessionInfo{
+			Source:      source,
+			Destination: v2net.TCPDestination(v2net.IPAddress([]byte{1, 2, 3, 4}), 80),
+			OutboundTag: tag,
+		})
+		link.InboundInput().Write(alloc.NewLocalBuffer(32).Clear().AppendString("test"))
+		link.InboundInput().Close()
+		return link
+	}
+
+	// Going through "a" again is a loop.
+	link := dispatchTo("a")
+	assert.Error(link.InboundOutput().WaitEstablished()).Equals(proxy.ErrConnectionRejected)
+	select {
+	case <-outboundA.chains:
+		t.Error("Loop is dispatched.")
+	default:
+	}
+
+	// Another outbound is not, and its session carries the chain.
+	dispatchTo("c")
+	assert.String((<-outboundC.chains).String()).Equals("a -> b")
+
+	// Closed connections are forgotten.
+	conn.Close()
+	dispatchTo("a")
+	assert.Int(len(<-outboundA.chains)).Equals(0)
+}
+
+type tagsOutbound struct {
+	tags chan map[string]string
+}
+
+func (this *tagsOutbound) Dispatch(destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	return this.DispatchSession(&proxy.SessionInfo{Destination: destination}, payload, link)
+}
+
+func (this *tagsOutbound) DispatchSession(session *proxy.SessionInfo, payload *alloc.Buffer, link ray.OutboundRay) error {
+	payload.Release()
+	link.OutboundInput().Release()
+	link.OutboundOutput().Close()
+	this.tags <- session.Tags
+	return nil
+}
+
+func TestTagsOfSession(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+	outbound := &tagsOutbound{
+		tags: make(chan map[string]string, 1),
+	}
+	outboundManager := proxyman.NewDefaultOutboundHandlerManager()
+	outboundManager.SetDefaultHandler(outbound)
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, outboundManager)
+	d := NewDefaultDispatcher(space)
+	space.BindApp(dispatcher.APP_ID, d)
+	assert.Error(space.Initialize()).IsNil()
+
+	inbound := &proxy.InboundHandlerMeta{
+		ConnectionTags: map[string]string{"tenant": "a"},
+	}
+	dispatchWith := func(tags map[string]string) map[string]string {
+		link := d.DispatchToOutbound(&proxy.SessionInfo{
+			Source:      v2net.TCPDestination(v2net.LocalHostIP, 10000),
+			Destination: v2net.TCPDestination(v2net.IPAddress([]byte{1, 2, 3, 4}), 80),
+			Inbound:     inbound,
+			Tags:        tags,
+		})
+		link.InboundInput().Write(alloc.NewLocalBuffer(32).Clear().AppendString("test"))
+		link.InboundInput().Close()
+		return <-outbound.tags
+	}
+
+	// Sessions carry the tags of their inbound, unless the inbound sets others.
+	assert.String(dispatchWith(nil)["tenant"]).Equals("a")
+	assert.String(dispatchWith(map[string]string{"tenant": "b"})["tenant"]).Equals("b")
+}
@@ -1,20 +1,83 @@
 package dns
 
 import (
+	"crypto/tls"
 	"net"
+	"strings"
 
 	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
 )
 
-func (this *Config) GetInternalHosts() map[string]net.IP {
-	hosts := make(map[string]net.IP)
+var (
+	defaultResolveOrder = []ResolveStage{ResolveStage_Static, ResolveStage_NameServer}
+)
+
+func (this *Config) GetInternalHosts() map[string][]net.IP {
+	hosts := make(map[string][]net.IP)
+	addHost := func(domain string, address *v2net.IPOrDomain) {
+		ip := address.AsAddress()
+		if ip.Family().IsDomain() {
+			log.Warning("DNS: Ignoring domain address in static hosts: ", ip.Domain())
+			return
+		}
+		domain = normalizeDomain(domain)
+		hosts[domain] = append(hosts[domain], ip.IP())
+	}
 	for domain, ipOrDomain := range this.GetHosts() {
-		address := ipOrDomain.AsAddress()
-		if address.Family().IsDomain() {
-			log.Warning("DNS: Ignoring domain address in static hosts: ", address.Domain())
-			continue
+		addHost(domain, ipOrDomain)
+	}
+	for _, mapping := range this.GetHostMapping() {
+		for _, ipOrDomain := range mapping.Ip {
+			addHost(mapping.Domain, ipOrDomain)
 		}
-		hosts[domain] = address.IP()
 	}
 	return hosts
 }
+
+func (this *Config) GetEffectiveResolveOrder() []ResolveStage {
+	if this == nil || len(this.ResolveOrder) == 0 {
+		return defaultResolveOrder
+	}
+	return this.ResolveOrder
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(domain), ".")
+}
+
+func (this *TLSNameServerConfig) GetDestination() v2net.Destination {
+	dest := this.Address.AsDestination()
+	dest.Network = v2net.Network_TCP
+	if dest.Port == 0 {
+		dest.Port = DefaultTLSPort
+	}
+	return dest
+}
+
+// GetServerName returns the host name to verify certificate of the resolver against.
+func (this *TLSNameServerConfig) GetServerName() string {
+	if len(this.ServerName) > 0 {
+		return this.ServerName
+	}
+	address := this.Address.Address.AsAddress()
+	if address.Family().IsDomain() {
+		return address.Domain()
+	}
+	// Certificate is verified against the IP address.
+	return address.IP().String()
+}
+
+// Build creates the TLSNameServer, whose queries go through the outbound with defaultTag if the config
+// has no tag.
+func (this *TLSNameServerConfig) Build(defaultTag string) *TLSNameServer {
+	tlsConfig := &tls.Config{
+		ServerName:         this.GetServerName(),
+		ClientSessionCache: tls.NewLRUClientSessionCache(4),
+	}
+	tag := this.OutboundTag
+	if len(tag) == 0 {
+		tag = defaultTag
+	}
+	return NewTLSNameServer(this.GetDestination(), tlsConfig, tag)
+}
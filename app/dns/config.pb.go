@@ -10,6 +10,9 @@ It is generated from these files:
 
 It has these top-level messages:
 	Config
+	DomainNameServer
+	TLSNameServerConfig
+	HostMapping
 */
 package dns
 
@@ -18,6 +21,7 @@ import fmt "fmt"
 import math "math"
 import v2ray_core_common_net "v2ray.com/core/common/net"
 import v2ray_core_common_net2 "v2ray.com/core/common/net"
+import v2ray_core_common_log "v2ray.com/core/common/log"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -30,12 +34,68 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type ResolveStage int32
+
+const (
+	// Static hosts in config.
+	ResolveStage_Static ResolveStage = 0
+	// Hosts file of the system, e.g., /etc/hosts.
+	ResolveStage_SystemHosts ResolveStage = 1
+	// Name servers in config.
+	ResolveStage_NameServer ResolveStage = 2
+)
+
+var ResolveStage_name = map[int32]string{
+	0: "Static",
+	1: "SystemHosts",
+	2: "NameServer",
+}
+var ResolveStage_value = map[string]int32{
+	"Static":      0,
+	"SystemHosts": 1,
+	"NameServer":  2,
+}
+
+func (x ResolveStage) String() string {
+	return proto.EnumName(ResolveStage_name, int32(x))
+}
+func (ResolveStage) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
 type Config struct {
-	// Nameservers used by this DNS. Only traditional UDP servers are support at the moment.
+	// Nameservers used by this DNS. Only traditional UDP servers are supported here. See also
+	// tls_name_servers.
 	// A special value 'localhost' as a domain address can be set to use DNS on local system.
 	NameServers []*v2ray_core_common_net2.Endpoint `protobuf:"bytes,1,rep,name=NameServers" json:"NameServers,omitempty"`
 	// Static hosts. Domain to IP.
+	// Deprecated: Use host_mapping.
 	Hosts map[string]*v2ray_core_common_net.IPOrDomain `protobuf:"bytes,2,rep,name=Hosts" json:"Hosts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Static hosts. Each domain may map to multiple IPv4 and IPv6 addresses.
+	HostMapping []*HostMapping `protobuf:"bytes,3,rep,name=host_mapping,json=hostMapping" json:"host_mapping,omitempty"`
+	// Stages to resolve a domain, in order. The first stage that returns an IP wins. Stages not in the
+	// list are disabled. If empty, static hosts and then name servers are used.
+	ResolveOrder []ResolveStage `protobuf:"varint,4,rep,packed,name=resolve_order,json=resolveOrder,enum=v2ray.core.app.dns.ResolveStage" json:"resolve_order,omitempty"`
+	// Path of the hosts file for SystemHosts stage. If empty, system default path is used.
+	HostsFile string `protobuf:"bytes,5,opt,name=hosts_file,json=hostsFile" json:"hosts_file,omitempty"`
+	// Whether outbound connections resolve domains through this DNS. Otherwise they use system resolver.
+	// Note that queries to name servers are dispatched by router too.
+	ResolveOutbound bool `protobuf:"varint,6,opt,name=resolve_outbound,json=resolveOutbound" json:"resolve_outbound,omitempty"`
+	// DNS-over-TLS resolvers. They are queried after name servers above, in order.
+	TlsNameServers []*TLSNameServerConfig `protobuf:"bytes,7,rep,name=tls_name_servers,json=tlsNameServers" json:"tls_name_servers,omitempty"`
+	// Name servers for specific domains. A domain is resolved by the rule with the longest matching
+	// domain, or by the global name servers above if none matches.
+	DomainNameServers []*DomainNameServer `protobuf:"bytes,8,rep,name=domain_name_servers,json=domainNameServers" json:"domain_name_servers,omitempty"`
+	// Level to log every resolution at, with the requester, answers, TTL and the resolver that answered.
+	// Resolutions are not logged if it is Disabled.
+	QueryLogLevel v2ray_core_common_log.LogLevel `protobuf:"varint,9,opt,name=query_log_level,json=queryLogLevel,enum=v2ray.core.common.log.LogLevel" json:"query_log_level,omitempty"`
+	// Tag of the outbound to send queries to name servers through, e.g., a Shadowsocks outbound, or a
+	// freedom outbound for direct queries. Queries are dispatched by router if empty. A resolution on
+	// behalf of an outbound never goes through the outbound itself, e.g., when it resolves its server.
+	OutboundTag string `protobuf:"bytes,10,opt,name=outbound_tag,json=outboundTag" json:"outbound_tag,omitempty"`
+	// Minimum and maximum seconds to cache answers of name servers, regardless of their TTL. There is no
+	// limit if 0. Setting a minimum reduces queries for domains with very low TTL, at the risk of using
+	// stale IPs.
+	MinTtl uint32 `protobuf:"varint,11,opt,name=min_ttl,json=minTtl" json:"min_ttl,omitempty"`
+	MaxTtl uint32 `protobuf:"varint,12,opt,name=max_ttl,json=maxTtl" json:"max_ttl,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
@@ -57,29 +117,147 @@ func (m *Config) GetHosts() map[string]*v2ray_core_common_net.IPOrDomain {
 	return nil
 }
 
+func (m *Config) GetHostMapping() []*HostMapping {
+	if m != nil {
+		return m.HostMapping
+	}
+	return nil
+}
+
+func (m *Config) GetTlsNameServers() []*TLSNameServerConfig {
+	if m != nil {
+		return m.TlsNameServers
+	}
+	return nil
+}
+
+func (m *Config) GetDomainNameServers() []*DomainNameServer {
+	if m != nil {
+		return m.DomainNameServers
+	}
+	return nil
+}
+
+type DomainNameServer struct {
+	// Domains that this rule applies to, including their subdomains, e.g., "v2ray.com" matches both
+	// "v2ray.com" and "www.v2ray.com".
+	Domain         []string                           `protobuf:"bytes,1,rep,name=domain" json:"domain,omitempty"`
+	NameServers    []*v2ray_core_common_net2.Endpoint `protobuf:"bytes,2,rep,name=name_servers,json=nameServers" json:"name_servers,omitempty"`
+	TlsNameServers []*TLSNameServerConfig             `protobuf:"bytes,3,rep,name=tls_name_servers,json=tlsNameServers" json:"tls_name_servers,omitempty"`
+	// Tag of the outbound to send queries of this rule through. outbound_tag in Config is used if empty.
+	OutboundTag string `protobuf:"bytes,4,opt,name=outbound_tag,json=outboundTag" json:"outbound_tag,omitempty"`
+}
+
+func (m *DomainNameServer) Reset()                    { *m = DomainNameServer{} }
+func (m *DomainNameServer) String() string            { return proto.CompactTextString(m) }
+func (*DomainNameServer) ProtoMessage()               {}
+func (*DomainNameServer) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *DomainNameServer) GetNameServers() []*v2ray_core_common_net2.Endpoint {
+	if m != nil {
+		return m.NameServers
+	}
+	return nil
+}
+
+func (m *DomainNameServer) GetTlsNameServers() []*TLSNameServerConfig {
+	if m != nil {
+		return m.TlsNameServers
+	}
+	return nil
+}
+
+type TLSNameServerConfig struct {
+	// Address of the resolver. Port is 853 if not set.
+	Address *v2ray_core_common_net2.Endpoint `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	// Host name to verify certificate of the resolver against. If empty, domain in address is used.
+	ServerName string `protobuf:"bytes,2,opt,name=server_name,json=serverName" json:"server_name,omitempty"`
+	// Tag of the outbound to send queries through. outbound_tag of the enclosing rule or Config is used if
+	// empty, and queries are sent directly if all of them are empty.
+	OutboundTag string `protobuf:"bytes,3,opt,name=outbound_tag,json=outboundTag" json:"outbound_tag,omitempty"`
+}
+
+func (m *TLSNameServerConfig) Reset()                    { *m = TLSNameServerConfig{} }
+func (m *TLSNameServerConfig) String() string            { return proto.CompactTextString(m) }
+func (*TLSNameServerConfig) ProtoMessage()               {}
+func (*TLSNameServerConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *TLSNameServerConfig) GetAddress() *v2ray_core_common_net2.Endpoint {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+type HostMapping struct {
+	Domain string                              `protobuf:"bytes,1,opt,name=domain" json:"domain,omitempty"`
+	Ip     []*v2ray_core_common_net.IPOrDomain `protobuf:"bytes,2,rep,name=ip" json:"ip,omitempty"`
+}
+
+func (m *HostMapping) Reset()                    { *m = HostMapping{} }
+func (m *HostMapping) String() string            { return proto.CompactTextString(m) }
+func (*HostMapping) ProtoMessage()               {}
+func (*HostMapping) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *HostMapping) GetIp() []*v2ray_core_common_net.IPOrDomain {
+	if m != nil {
+		return m.Ip
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Config)(nil), "v2ray.core.app.dns.Config")
+	proto.RegisterType((*DomainNameServer)(nil), "v2ray.core.app.dns.DomainNameServer")
+	proto.RegisterType((*TLSNameServerConfig)(nil), "v2ray.core.app.dns.TLSNameServerConfig")
+	proto.RegisterType((*HostMapping)(nil), "v2ray.core.app.dns.HostMapping")
+	proto.RegisterEnum("v2ray.core.app.dns.ResolveStage", ResolveStage_name, ResolveStage_value)
 }
 
 func init() { proto.RegisterFile("v2ray.com/core/app/dns/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 272 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x90, 0x31, 0x4b, 0xc4, 0x30,
-	0x14, 0x80, 0x49, 0xcb, 0x1d, 0x98, 0x2e, 0x92, 0x41, 0x4a, 0x17, 0xab, 0x22, 0x16, 0x85, 0x04,
-	0xea, 0xa0, 0xe8, 0xe4, 0xe9, 0x81, 0x2e, 0x7a, 0xd4, 0x4d, 0xa7, 0xd8, 0x44, 0x29, 0x9a, 0xf7,
-	0x42, 0x12, 0x0b, 0xfd, 0xc1, 0xfe, 0x0f, 0xb9, 0x06, 0xf1, 0xd0, 0xbb, 0x2d, 0x84, 0xef, 0xcb,
-	0xfb, 0xf2, 0xe8, 0x41, 0x5f, 0x3b, 0x39, 0xf0, 0x16, 0x8d, 0x68, 0xd1, 0x69, 0x21, 0xad, 0x15,
-	0x0a, 0xbc, 0x68, 0x11, 0x5e, 0xbb, 0x37, 0x6e, 0x1d, 0x06, 0x64, 0xec, 0x07, 0x72, 0x9a, 0x4b,
-	0x6b, 0xb9, 0x02, 0x5f, 0x1c, 0xfd, 0x11, 0x5b, 0x34, 0x06, 0x41, 0x80, 0x0e, 0x42, 0x2a, 0xe5,
-	0xb4, 0xf7, 0x51, 0x2e, 0x4e, 0x36, 0x83, 0x4a, 0xfb, 0xd0, 0x81, 0x0c, 0x1d, 0x42, 0x84, 0xf7,
-	0xbf, 0x08, 0x9d, 0x5e, 0x8f, 0xa3, 0xd9, 0x15, 0xcd, 0xee, 0xa5, 0xd1, 0x8f, 0xda, 0xf5, 0xda,
-	0xf9, 0x9c, 0x94, 0x69, 0x95, 0xd5, 0xbb, 0x7c, 0x25, 0x25, 0xbe, 0xc4, 0x41, 0x07, 0x3e, 0x07,
-	0x65, 0xb1, 0x83, 0xd0, 0xac, 0x3a, 0xec, 0x92, 0x4e, 0x6e, 0xd1, 0x07, 0x9f, 0x27, 0xa3, 0x7c,
-	0xc8, 0xff, 0xff, 0x83, 0xc7, 0x69, 0x7c, 0xe4, 0xe6, 0x10, 0xdc, 0xd0, 0x44, 0xa7, 0x78, 0xa6,
-	0xf4, 0xf7, 0x92, 0x6d, 0xd3, 0xf4, 0x5d, 0x0f, 0x39, 0x29, 0x49, 0xb5, 0xd5, 0x2c, 0x8f, 0xec,
-	0x8c, 0x4e, 0x7a, 0xf9, 0xf1, 0xa9, 0xf3, 0xa4, 0x24, 0x55, 0x56, 0xef, 0x6d, 0x28, 0xbb, 0x5b,
-	0x3c, 0xb8, 0x1b, 0x34, 0xb2, 0x83, 0x26, 0xf2, 0x17, 0xc9, 0x39, 0x99, 0x1d, 0xd3, 0x9d, 0x16,
-	0xcd, 0x9a, 0x9e, 0x59, 0x16, 0x83, 0x16, 0xcb, 0x75, 0x3c, 0xa5, 0x0a, 0xfc, 0xcb, 0x74, 0x5c,
-	0xcd, 0xe9, 0x77, 0x00, 0x00, 0x00, 0xff, 0xff, 0x43, 0x85, 0xa5, 0x73, 0xab, 0x01, 0x00, 0x00,
+	// 671 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xa4, 0x94, 0x5d, 0x6f, 0xd3, 0x3c,
+	0x14, 0xc7, 0x9f, 0xb4, 0x5b, 0xb7, 0x9e, 0x74, 0x5d, 0x1e, 0x4f, 0x1a, 0xd1, 0x24, 0xb4, 0x6c,
+	0xbc, 0x2c, 0x0c, 0x29, 0x15, 0xe5, 0x82, 0x97, 0x5d, 0x31, 0x18, 0x2f, 0xd2, 0x60, 0xc3, 0xed,
+	0x05, 0x82, 0x8b, 0xc8, 0x6b, 0xbc, 0x2c, 0xc2, 0xb1, 0x43, 0xec, 0x56, 0xeb, 0x25, 0xdf, 0x81,
+	0x8f, 0xc8, 0x07, 0x41, 0x71, 0x12, 0x9a, 0xb6, 0x99, 0x34, 0x89, 0xbb, 0xf8, 0xf8, 0x7f, 0xfe,
+	0x3e, 0xfe, 0xe5, 0x1c, 0xc3, 0xbd, 0x49, 0x3f, 0x25, 0x53, 0x6f, 0x24, 0xe2, 0xde, 0x48, 0xa4,
+	0xb4, 0x47, 0x92, 0xa4, 0x17, 0x70, 0xd9, 0x1b, 0x09, 0x7e, 0x19, 0x85, 0x5e, 0x92, 0x0a, 0x25,
+	0x10, 0x2a, 0x45, 0x29, 0xf5, 0x48, 0x92, 0x78, 0x01, 0x97, 0x3b, 0x07, 0x0b, 0x89, 0x23, 0x11,
+	0xc7, 0x82, 0xf7, 0x38, 0x55, 0x3d, 0x12, 0x04, 0x29, 0x95, 0x32, 0x4f, 0xde, 0x79, 0x7c, 0xb3,
+	0x30, 0xa0, 0x52, 0x45, 0x9c, 0xa8, 0x48, 0xf0, 0x42, 0xfc, 0xb0, 0x5e, 0xcc, 0x44, 0x38, 0x57,
+	0xd1, 0xfe, 0xcf, 0x16, 0xb4, 0x5e, 0xeb, 0x00, 0x7a, 0x05, 0xe6, 0x27, 0x12, 0xd3, 0x01, 0x4d,
+	0x27, 0x34, 0x95, 0xb6, 0xe1, 0x34, 0x5d, 0xb3, 0xbf, 0xeb, 0x55, 0x4a, 0xce, 0x4d, 0x3c, 0x4e,
+	0x95, 0x77, 0xc2, 0x83, 0x44, 0x44, 0x5c, 0xe1, 0x6a, 0x0e, 0x3a, 0x82, 0xd5, 0xf7, 0x42, 0x2a,
+	0x69, 0x37, 0x74, 0xf2, 0x03, 0x6f, 0xf9, 0xbe, 0x5e, 0x7e, 0x9a, 0xa7, 0x75, 0x27, 0x5c, 0xa5,
+	0x53, 0x9c, 0xe7, 0xa0, 0x63, 0xe8, 0x5c, 0x09, 0xa9, 0xfc, 0x98, 0x24, 0x49, 0xc4, 0x43, 0xbb,
+	0xb9, 0x5c, 0x40, 0xe9, 0x91, 0x25, 0x7c, 0xcc, 0x65, 0xd8, 0xbc, 0x9a, 0x2d, 0xd0, 0x09, 0x6c,
+	0xa4, 0x54, 0x0a, 0x36, 0xa1, 0xbe, 0x48, 0x03, 0x9a, 0xda, 0x2b, 0x4e, 0xd3, 0xed, 0xf6, 0x9d,
+	0x3a, 0x13, 0x9c, 0x0b, 0x07, 0x8a, 0x84, 0x14, 0x77, 0x8a, 0xb4, 0xb3, 0x2c, 0x0b, 0xdd, 0x05,
+	0xc8, 0x5c, 0xa5, 0x7f, 0x19, 0x31, 0x6a, 0xaf, 0x3a, 0x86, 0xdb, 0xc6, 0x6d, 0x1d, 0x79, 0x1b,
+	0x31, 0x8a, 0x1e, 0x81, 0xf5, 0xf7, 0x94, 0xb1, 0xba, 0x10, 0x63, 0x1e, 0xd8, 0x2d, 0xc7, 0x70,
+	0xd7, 0xf1, 0x66, 0x69, 0x53, 0x84, 0xd1, 0x67, 0xb0, 0x14, 0x93, 0x3e, 0x27, 0x31, 0xf5, 0x65,
+	0x41, 0x76, 0x4d, 0x5f, 0xec, 0xa0, 0xae, 0xa6, 0xe1, 0xe9, 0x60, 0xc6, 0x33, 0x27, 0x85, 0xbb,
+	0x8a, 0xc9, 0x2a, 0xe4, 0x21, 0x6c, 0x05, 0x22, 0x26, 0x11, 0x9f, 0x77, 0x5d, 0xd7, 0xae, 0xf7,
+	0xeb, 0x5c, 0xdf, 0x68, 0xf9, 0xcc, 0x03, 0xff, 0x1f, 0x2c, 0x44, 0x24, 0x7a, 0x07, 0x9b, 0x3f,
+	0xc6, 0x34, 0x9d, 0xfa, 0x4c, 0x84, 0x3e, 0xa3, 0x13, 0xca, 0xec, 0xb6, 0x63, 0xb8, 0xdd, 0xda,
+	0x0e, 0x60, 0x22, 0xf4, 0x4e, 0x45, 0x78, 0x9a, 0xc9, 0xf0, 0x86, 0xce, 0x2b, 0x97, 0x68, 0x0f,
+	0x3a, 0x25, 0x14, 0x5f, 0x91, 0xd0, 0x06, 0x4d, 0xcf, 0x2c, 0x63, 0x43, 0x12, 0xa2, 0x3b, 0xb0,
+	0x16, 0x47, 0xdc, 0x57, 0x8a, 0xd9, 0xa6, 0x63, 0xb8, 0x1b, 0xb8, 0x15, 0x47, 0x7c, 0xa8, 0x98,
+	0xde, 0x20, 0xd7, 0x7a, 0xa3, 0x53, 0x6c, 0x90, 0xeb, 0xa1, 0x62, 0x3b, 0xdf, 0x00, 0x66, 0x0d,
+	0x83, 0x2c, 0x68, 0x7e, 0xa7, 0x53, 0xdb, 0xd0, 0xce, 0xd9, 0x27, 0x7a, 0x06, 0xab, 0x13, 0xc2,
+	0xc6, 0xd4, 0x6e, 0x38, 0x86, 0x6b, 0xf6, 0xf7, 0x6e, 0xe8, 0xda, 0x0f, 0xe7, 0x67, 0x69, 0x0e,
+	0x03, 0xe7, 0xfa, 0x97, 0x8d, 0xe7, 0xc6, 0xfe, 0x6f, 0x03, 0xac, 0x45, 0x44, 0x68, 0x1b, 0x5a,
+	0x39, 0x24, 0x3d, 0x08, 0x6d, 0x5c, 0xac, 0xb2, 0x2e, 0x9d, 0xc3, 0xde, 0xb8, 0xe5, 0x98, 0xf0,
+	0x0a, 0xeb, 0xba, 0xa6, 0x68, 0xfe, 0x5b, 0x53, 0x2c, 0x52, 0x5f, 0x59, 0xa2, 0xbe, 0xff, 0xcb,
+	0x80, 0xad, 0x1a, 0x2b, 0xf4, 0x02, 0xd6, 0x8a, 0x87, 0x46, 0x13, 0xbd, 0xc5, 0x65, 0x4a, 0x3d,
+	0xda, 0x05, 0x33, 0xaf, 0x5f, 0xdf, 0x45, 0xc3, 0x6f, 0x63, 0xc8, 0x43, 0xd9, 0x39, 0x4b, 0x65,
+	0x35, 0x97, 0xcb, 0xfa, 0x02, 0x66, 0x65, 0x9c, 0xe7, 0xb8, 0x1b, 0x15, 0xee, 0x4f, 0xa0, 0x11,
+	0x25, 0x05, 0xed, 0x5b, 0xfc, 0xde, 0x46, 0x94, 0x1c, 0x1e, 0x41, 0xa7, 0x3a, 0xe3, 0x08, 0xa0,
+	0x35, 0x50, 0x44, 0x45, 0x23, 0xeb, 0x3f, 0xb4, 0x09, 0xe6, 0x60, 0x2a, 0x15, 0x8d, 0x75, 0x5b,
+	0x59, 0x06, 0xea, 0x02, 0xcc, 0xc8, 0x58, 0x8d, 0xe3, 0x43, 0xd8, 0x1e, 0x89, 0xb8, 0xe6, 0x77,
+	0x1c, 0x9b, 0x39, 0xb7, 0xf3, 0xec, 0xfd, 0xfc, 0xda, 0x0c, 0xb8, 0xbc, 0x68, 0xe9, 0xb7, 0xf4,
+	0xe9, 0x9f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x0e, 0x5e, 0x2c, 0xf6, 0x04, 0x06, 0x00, 0x00,
 }
@@ -13,4 +13,7 @@ const (
 // A DnsCache is an internal cache of DNS resolutions.
 type Server interface {
 	Get(domain string) []net.IP
+	// GetFor is the same as Get, with the requester of the resolution, e.g., tag of an outbound, for query
+	// logs.
+	GetFor(domain string, requester string) []net.IP
 }
@@ -0,0 +1,102 @@
+package dns
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+)
+
+const (
+	hostsFileCheckInterval = time.Second * 5
+)
+
+// SystemHosts resolves domains from a hosts file. The file is reloaded when it is modified.
+type SystemHosts struct {
+	sync.Mutex
+	path      string
+	modTime   time.Time
+	lastCheck time.Time
+	hosts     map[string][]net.IP
+}
+
+func NewSystemHosts(path string) *SystemHosts {
+	return &SystemHosts{
+		path:  path,
+		hosts: make(map[string][]net.IP),
+	}
+}
+
+func (this *SystemHosts) Lookup(domain string) []net.IP {
+	this.Lock()
+	defer this.Unlock()
+
+	if now := time.Now(); now.Sub(this.lastCheck) >= hostsFileCheckInterval {
+		this.lastCheck = now
+		this.reload()
+	}
+	return this.hosts[normalizeDomain(domain)]
+}
+
+func (this *SystemHosts) reload() {
+	info, err := os.Stat(this.path)
+	if err != nil {
+		if len(this.hosts) > 0 {
+			log.Warning("DNS: Failed to read hosts file ", this.path, ": ", err)
+			this.hosts = make(map[string][]net.IP)
+		}
+		return
+	}
+	if info.ModTime().Equal(this.modTime) {
+		return
+	}
+
+	file, err := os.Open(this.path)
+	if err != nil {
+		log.Warning("DNS: Failed to read hosts file ", this.path, ": ", err)
+		return
+	}
+	defer file.Close()
+
+	this.hosts = ParseHosts(file)
+	this.modTime = info.ModTime()
+	log.Debug("DNS: Loaded ", len(this.hosts), " domains from ", this.path)
+}
+
+// ParseHosts parses content in hosts file format, i.e., lines of an IP followed by its domains.
+func ParseHosts(reader io.Reader) map[string][]net.IP {
+	hosts := make(map[string][]net.IP)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ipStr := fields[0]
+		// Zone of link-local IPv6 addresses is not usable for outbound connections.
+		if idx := strings.IndexByte(ipStr, '%'); idx >= 0 {
+			ipStr = ipStr[:idx]
+		}
+		ip := net.ParseIP(ipStr)
+		if ip == nil {
+			continue
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		for _, domain := range fields[1:] {
+			domain = normalizeDomain(domain)
+			hosts[domain] = append(hosts[domain], ip)
+		}
+	}
+	return hosts
+}
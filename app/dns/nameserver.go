@@ -6,12 +6,15 @@ import (
 	"time"
 
 	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/common/alloc"
 	"v2ray.com/core/common/dice"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/internet/udp"
+	"v2ray.com/core/transport/ray"
 
 	"github.com/miekg/dns"
 )
@@ -33,6 +36,27 @@ type ARecord struct {
 
 type NameServer interface {
 	QueryA(domain string) <-chan *ARecord
+	String() string
+}
+
+// outboundNameServer is a NameServer whose queries go through an outbound.
+type outboundNameServer interface {
+	// OutboundTag returns tag of the outbound, or empty if queries are not sent through a specific outbound.
+	OutboundTag() string
+}
+
+// closableNameServer is a NameServer that holds resources, e.g., connections, until it is closed.
+type closableNameServer interface {
+	Close()
+}
+
+// closeNameServers closes the name servers that hold resources.
+func closeNameServers(servers []NameServer) {
+	for _, server := range servers {
+		if closable, ok := server.(closableNameServer); ok {
+			closable.Close()
+		}
+	}
 }
 
 type PendingRequest struct {
@@ -45,6 +69,7 @@ type UDPNameServer struct {
 	address     v2net.Destination
 	requests    map[uint16]*PendingRequest
 	udpServer   *udp.UDPServer
+	outboundTag string
 	nextCleanup time.Time
 }
 
@@ -57,6 +82,58 @@ func NewUDPNameServer(address v2net.Destination, dispatcher dispatcher.PacketDis
 	return s
 }
 
+// NewUDPNameServerWithOutbound creates a UDPNameServer whose queries are dispatched to the outbound with
+// tag, instead of the one picked by router.
+func NewUDPNameServerWithOutbound(address v2net.Destination, ohm proxyman.OutboundHandlerManager, fallback dispatcher.PacketDispatcher, tag string) *UDPNameServer {
+	s := NewUDPNameServer(address, &outboundDispatcher{
+		ohm:      ohm,
+		fallback: fallback,
+		tag:      tag,
+	})
+	s.outboundTag = tag
+	return s
+}
+
+// outboundDispatcher dispatches all sessions to the outbound with tag. Sessions are dispatched by
+// fallback if the outbound doesn't exist.
+type outboundDispatcher struct {
+	ohm      proxyman.OutboundHandlerManager
+	fallback dispatcher.PacketDispatcher
+	tag      string
+}
+
+func (this *outboundDispatcher) DispatchToOutbound(session *proxy.SessionInfo) ray.InboundRay {
+	handler := this.ohm.GetHandler(this.tag)
+	if handler == nil {
+		log.Warning("DNS: Outbound for name servers is not found: ", this.tag)
+		return this.fallback.DispatchToOutbound(session)
+	}
+	direct := ray.NewRay()
+	// Queries are for outbounds as well, so they must not loop through an outbound they came from.
+	chain := session.Chain.Append(internet.FindOutboundChain(session.Source)...)
+	if proxyman.IsInChain(this.ohm, handler, chain) {
+		log.Warning("DNS: Loop detected in outbound chain ", chain, ". Rejecting query to ", session.Destination, ".")
+		direct.OutboundInput().Release()
+		direct.OutboundOutput().CloseError(proxy.ErrConnectionRejected)
+		return direct
+	}
+	if len(chain) > 0 {
+		chained := *session
+		chained.Chain = chain
+		session = &chained
+	}
+	go func() {
+		payload, err := direct.OutboundInput().Read()
+		if err != nil {
+			direct.OutboundInput().Release()
+			direct.OutboundOutput().Release()
+			return
+		}
+		proxy.DispatchSession(handler, session, payload, direct)
+	}()
+	return direct
+}
+
 // Private: Visible for testing.
 func (this *UDPNameServer) Cleanup() {
 	expiredRequests := make([]uint16, 0, 16)
@@ -108,11 +185,7 @@ func (this *UDPNameServer) HandleResponse(dest v2net.Destination, payload *alloc
 		log.Warning("DNS: Failed to parse DNS response: ", err)
 		return
 	}
-	record := &ARecord{
-		IPs: make([]net.IP, 0, 16),
-	}
 	id := msg.Id
-	ttl := DefaultTTL
 	log.Debug("DNS: Handling response for id ", id, " content: ", msg.String())
 
 	this.Lock()
@@ -124,6 +197,17 @@ func (this *UDPNameServer) HandleResponse(dest v2net.Destination, payload *alloc
 	delete(this.requests, id)
 	this.Unlock()
 
+	request.response <- recordFromMsg(msg)
+	close(request.response)
+}
+
+// recordFromMsg returns an ARecord with all A and AAAA answers in msg. The record expires by the minimum
+// TTL of the answers.
+func recordFromMsg(msg *dns.Msg) *ARecord {
+	record := &ARecord{
+		IPs: make([]net.IP, 0, 16),
+	}
+	ttl := DefaultTTL
 	for _, rr := range msg.Answer {
 		switch rr := rr.(type) {
 		case *dns.A:
@@ -139,12 +223,14 @@ func (this *UDPNameServer) HandleResponse(dest v2net.Destination, payload *alloc
 		}
 	}
 	record.Expire = time.Now().Add(time.Second * time.Duration(ttl))
-
-	request.response <- record
-	close(request.response)
+	return record
 }
 
 func (this *UDPNameServer) BuildQueryA(domain string, id uint16) *alloc.Buffer {
+	return buildQueryA(domain, id)
+}
+
+func buildQueryA(domain string, id uint16) *alloc.Buffer {
 	buffer := alloc.NewBuffer()
 	msg := new(dns.Msg)
 	msg.Id = id
@@ -166,6 +252,14 @@ func (this *UDPNameServer) DispatchQuery(payload *alloc.Buffer) {
 	this.udpServer.Dispatch(&proxy.SessionInfo{Source: pseudoDestination, Destination: this.address}, payload, this.HandleResponse)
 }
 
+func (this *UDPNameServer) String() string {
+	return this.address.String()
+}
+
+func (this *UDPNameServer) OutboundTag() string {
+	return this.outboundTag
+}
+
 func (this *UDPNameServer) QueryA(domain string) <-chan *ARecord {
 	response := make(chan *ARecord, 1)
 	id := this.AssignUnusedID(response)
@@ -192,6 +286,10 @@ func (this *UDPNameServer) QueryA(domain string) <-chan *ARecord {
 type LocalNameServer struct {
 }
 
+func (this *LocalNameServer) String() string {
+	return "localhost"
+}
+
 func (this *LocalNameServer) QueryA(domain string) <-chan *ARecord {
 	response := make(chan *ARecord, 1)
 
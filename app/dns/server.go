@@ -2,14 +2,19 @@ package dns
 
 import (
 	"net"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/app/proxyman"
 	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/platform"
+	"v2ray.com/core/transport/internet"
 
 	"github.com/miekg/dns"
 )
@@ -24,17 +29,36 @@ type DomainRecord struct {
 
 type CacheServer struct {
 	sync.RWMutex
-	space   app.Space
-	hosts   map[string]net.IP
-	records map[string]*DomainRecord
-	servers []NameServer
+	dispatcher dispatcher.PacketDispatcher
+	ohm        proxyman.OutboundHandlerManager
+	resolvers  atomic.Value
+	records    map[string]*DomainRecord
+}
+
+// resolverSet is the configured part of CacheServer, which is replaced as a whole on reload.
+type resolverSet struct {
+	hosts       map[string][]net.IP
+	systemHosts *SystemHosts
+	order       []ResolveStage
+	servers     []NameServer
+	// Name servers of domain rules, by domain.
+	domainServers map[string][]NameServer
+	queryLogLevel log.LogLevel
+	// Limits of time to cache answers of name servers, or 0 for no limit.
+	minTTL time.Duration
+	maxTTL time.Duration
+	// Outbounds of name servers, or nil if there is no outbound manager.
+	ohm proxyman.OutboundHandlerManager
 }
 
 func NewCacheServer(space app.Space, config *Config) *CacheServer {
 	server := &CacheServer{
 		records: make(map[string]*DomainRecord),
-		servers: make([]NameServer, len(config.NameServers)),
-		hosts:   config.GetInternalHosts(),
+	}
+	// Static hosts are available before initialization.
+	server.resolvers.Store(newResolverSet(config))
+	if config.ResolveOutbound {
+		internet.UseDomainResolver(server)
 	}
 	space.InitializeApplication(func() error {
 		if !space.HasApp(dispatcher.APP_ID) {
@@ -42,29 +66,167 @@ func NewCacheServer(space app.Space, config *Config) *CacheServer {
 			return app.ErrMissingApplication
 		}
 
-		dispatcher := space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher)
-		for idx, destPB := range config.NameServers {
-			address := destPB.Address.AsAddress()
-			if address.Family().IsDomain() && address.Domain() == "localhost" {
-				server.servers[idx] = &LocalNameServer{}
-			} else {
-				dest := destPB.AsDestination()
-				if dest.Network == v2net.Network_Unknown {
-					dest.Network = v2net.Network_UDP
-				}
-				if dest.Network == v2net.Network_UDP {
-					server.servers[idx] = NewUDPNameServer(dest, dispatcher)
-				}
-			}
-		}
-		if len(config.NameServers) == 0 {
-			server.servers = append(server.servers, &LocalNameServer{})
+		server.dispatcher = space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher)
+		if space.HasApp(proxyman.APP_ID_OUTBOUND_MANAGER) {
+			server.ohm = space.GetApp(proxyman.APP_ID_OUTBOUND_MANAGER).(proxyman.OutboundHandlerManager)
 		}
+		resolvers := newResolverSet(config)
+		resolvers.buildNameServers(config, server.dispatcher, server.ohm)
+		server.resolvers.Store(resolvers)
 		return nil
 	})
 	return server
 }
 
+// newResolverSet creates a resolverSet without name servers.
+func newResolverSet(config *Config) *resolverSet {
+	resolvers := &resolverSet{
+		hosts:         config.GetInternalHosts(),
+		order:         config.GetEffectiveResolveOrder(),
+		domainServers: make(map[string][]NameServer),
+		queryLogLevel: config.QueryLogLevel,
+		minTTL:        time.Duration(config.MinTtl) * time.Second,
+		maxTTL:        time.Duration(config.MaxTtl) * time.Second,
+	}
+	hostsFile := config.HostsFile
+	if len(hostsFile) == 0 {
+		hostsFile = platform.HostsFile()
+	}
+	resolvers.systemHosts = NewSystemHosts(hostsFile)
+	return resolvers
+}
+
+func (this *resolverSet) buildNameServers(config *Config, dispatcher dispatcher.PacketDispatcher, ohm proxyman.OutboundHandlerManager) {
+	this.ohm = ohm
+	this.servers = buildNameServers(config.NameServers, config.TlsNameServers, dispatcher, ohm, config.OutboundTag)
+	if len(this.servers) == 0 {
+		this.servers = append(this.servers, &LocalNameServer{})
+	}
+	for _, rule := range config.DomainNameServers {
+		tag := rule.OutboundTag
+		if len(tag) == 0 {
+			tag = config.OutboundTag
+		}
+		servers := buildNameServers(rule.NameServers, rule.TlsNameServers, dispatcher, ohm, tag)
+		for _, domain := range rule.Domain {
+			this.domainServers[normalizeDomain(domain)] = servers
+		}
+	}
+}
+
+// close closes all name servers of the set.
+func (this *resolverSet) close() {
+	closeNameServers(this.servers)
+	for _, servers := range this.domainServers {
+		closeNameServers(servers)
+	}
+}
+
+// buildNameServers creates name servers whose queries go through the outbound with tag, or are dispatched
+// by router if tag is empty.
+func buildNameServers(endpoints []*v2net.Endpoint, tlsServers []*TLSNameServerConfig, dispatcher dispatcher.PacketDispatcher, ohm proxyman.OutboundHandlerManager, tag string) []NameServer {
+	servers := make([]NameServer, 0, len(endpoints)+len(tlsServers))
+	for _, destPB := range endpoints {
+		address := destPB.Address.AsAddress()
+		if address.Family().IsDomain() && address.Domain() == "localhost" {
+			servers = append(servers, &LocalNameServer{})
+			continue
+		}
+		dest := destPB.AsDestination()
+		if dest.Network == v2net.Network_Unknown {
+			dest.Network = v2net.Network_UDP
+		}
+		if dest.Network != v2net.Network_UDP {
+			log.Warning("DNS: Ignoring name server of unsupported network: ", dest)
+			continue
+		}
+		if len(tag) > 0 && ohm != nil {
+			servers = append(servers, NewUDPNameServerWithOutbound(dest, ohm, dispatcher, tag))
+		} else {
+			servers = append(servers, NewUDPNameServer(dest, dispatcher))
+		}
+	}
+	for _, tlsServer := range tlsServers {
+		servers = append(servers, tlsServer.Build(tag))
+	}
+	return servers
+}
+
+// serversFor returns name servers for domain, by the longest matching domain rule. Name servers that
+// query through the outbound with requester tag are left out, so that an outbound doesn't wait for a
+// resolution that goes through itself. Global name servers are used if the rule has no name server
+// left, and the system resolver if neither has.
+func (this *resolverSet) serversFor(domain string, requester string) []NameServer {
+	servers := this.servers
+	domain = normalizeDomain(domain)
+	for len(domain) > 0 {
+		if ruleServers, found := this.domainServers[domain]; found {
+			servers = ruleServers
+			break
+		}
+		idx := strings.IndexByte(domain, '.')
+		if idx < 0 {
+			break
+		}
+		domain = domain[idx+1:]
+	}
+	if len(requester) == 0 {
+		return servers
+	}
+	for _, candidates := range [][]NameServer{servers, this.servers} {
+		if allowed := excludeOutbound(candidates, requester, this.ohm); len(allowed) > 0 {
+			return allowed
+		}
+	}
+	log.Info("DNS: All name servers go through outbound ", requester, ", using system resolver.")
+	return []NameServer{&LocalNameServer{}}
+}
+
+// excludeOutbound returns servers that don't query through the outbound with tag, by the same check of
+// outbound chains as the dispatcher, which also matches the outbound by its handler in ohm.
+func excludeOutbound(servers []NameServer, tag string, ohm proxyman.OutboundHandlerManager) []NameServer {
+	chain := internet.OutboundChain{tag}
+	allowed := make([]NameServer, 0, len(servers))
+	for _, server := range servers {
+		if outboundServer, ok := server.(outboundNameServer); ok && isOutboundInChain(ohm, outboundServer.OutboundTag(), chain) {
+			continue
+		}
+		allowed = append(allowed, server)
+	}
+	return allowed
+}
+
+// isOutboundInChain returns true if the outbound with tag is in chain. Outbounds without handler in ohm
+// are matched by tag.
+func isOutboundInChain(ohm proxyman.OutboundHandlerManager, tag string, chain internet.OutboundChain) bool {
+	if len(tag) == 0 {
+		return false
+	}
+	if chain.Has(tag) {
+		return true
+	}
+	if ohm == nil {
+		return false
+	}
+	handler := ohm.GetHandler(tag)
+	return handler != nil && proxyman.IsInChain(ohm, handler, chain)
+}
+
+// Reload replaces hosts, resolve order and name servers with the ones in config, and clears cached
+// records. Previous name servers are closed, so queries in progress on them fail. ResolveOutbound is not
+// changed.
+func (this *CacheServer) Reload(config *Config) {
+	resolvers := newResolverSet(config)
+	resolvers.buildNameServers(config, this.dispatcher, this.ohm)
+	previous := this.resolvers.Load().(*resolverSet)
+	this.resolvers.Store(resolvers)
+	previous.close()
+
+	this.Lock()
+	this.records = make(map[string]*DomainRecord)
+	this.Unlock()
+}
+
 func (this *CacheServer) Release() {
 
 }
@@ -81,36 +243,120 @@ func (this *CacheServer) GetCached(domain string) []net.IP {
 }
 
 func (this *CacheServer) Get(domain string) []net.IP {
-	if ip, found := this.hosts[domain]; found {
-		return []net.IP{ip}
+	return this.GetFor(domain, "")
+}
+
+// GetFor resolves domain on behalf of requester, e.g., tag of an outbound. Queries for the requester never
+// go through the requesting outbound, whether by its tag or by its handler.
+func (this *CacheServer) GetFor(domain string, requester string) []net.IP {
+	resolvers := this.resolvers.Load().(*resolverSet)
+	for _, stage := range resolvers.order {
+		var ips []net.IP
+		var ttl time.Duration
+		source := stage.String()
+		switch stage {
+		case ResolveStage_Static:
+			ips = resolvers.hosts[normalizeDomain(domain)]
+		case ResolveStage_SystemHosts:
+			ips = resolvers.systemHosts.Lookup(domain)
+		case ResolveStage_NameServer:
+			ips, ttl, source = this.queryNameServers(resolvers, domain, requester)
+		}
+		if len(ips) > 0 {
+			log.Debug("DNS: Resolved ", domain, " by ", stage)
+			logQuery(resolvers.queryLogLevel, requester, domain, ips, ttl, source)
+			return ips
+		}
 	}
 
+	log.Debug("DNS: Returning nil for domain ", domain)
+	logQuery(resolvers.queryLogLevel, requester, domain, nil, 0, "")
+	return nil
+}
+
+// queryNameServers returns IPs of domain, with their remaining TTL and the name server that answered.
+func (this *CacheServer) queryNameServers(resolvers *resolverSet, domain string, requester string) ([]net.IP, time.Duration, string) {
 	domain = dns.Fqdn(domain)
-	ips := this.GetCached(domain)
-	if ips != nil {
-		return ips
+	this.RLock()
+	record, found := this.records[domain]
+	this.RUnlock()
+	if found && record.A.Expire.After(time.Now()) {
+		return record.A.IPs, record.A.Expire.Sub(time.Now()), "cache"
 	}
 
-	for _, server := range this.servers {
+	for _, server := range resolvers.serversFor(domain, requester) {
 		response := server.QueryA(domain)
 		select {
 		case a, open := <-response:
 			if !open || a == nil {
 				continue
 			}
+			a = resolvers.clampTTL(a)
 			this.Lock()
 			this.records[domain] = &DomainRecord{
 				A: a,
 			}
 			this.Unlock()
 			log.Debug("DNS: Returning ", len(a.IPs), " IPs for domain ", domain)
-			return a.IPs
+			return a.IPs, a.Expire.Sub(time.Now()), server.String()
 		case <-time.After(QueryTimeout):
 		}
 	}
+	return nil, 0, ""
+}
 
-	log.Debug("DNS: Returning nil for domain ", domain)
-	return nil
+// clampTTL returns a copy of record that expires within the minimum and maximum TTL, or record itself if
+// it already does.
+func (this *resolverSet) clampTTL(record *ARecord) *ARecord {
+	now := time.Now()
+	expire := record.Expire
+	if this.minTTL > 0 && expire.Before(now.Add(this.minTTL)) {
+		expire = now.Add(this.minTTL)
+	}
+	if this.maxTTL > 0 && expire.After(now.Add(this.maxTTL)) {
+		expire = now.Add(this.maxTTL)
+	}
+	if expire.Equal(record.Expire) {
+		return record
+	}
+	return &ARecord{
+		IPs:    record.IPs,
+		Expire: expire,
+	}
+}
+
+// logQuery logs answers to a query of domain by their record types, e.g., A for IPv4 and AAAA for IPv6.
+func logQuery(level log.LogLevel, requester string, domain string, ips []net.IP, ttl time.Duration, source string) {
+	if level == log.LogLevel_Disabled {
+		return
+	}
+	if len(requester) == 0 {
+		requester = "unknown"
+	}
+	if len(ips) == 0 {
+		log.Print(level, "DNS|Query: [", requester, "] ", domain, ": no answer")
+		return
+	}
+	var a, aaaa []string
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			a = append(a, ip.String())
+		} else {
+			aaaa = append(aaaa, ip.String())
+		}
+	}
+	answers := make([]string, 0, 2)
+	if len(a) > 0 {
+		answers = append(answers, "A: "+strings.Join(a, ","))
+	}
+	if len(aaaa) > 0 {
+		answers = append(answers, "AAAA: "+strings.Join(aaaa, ","))
+	}
+	if ttl > 0 {
+		log.Print(level, "DNS|Query: [", requester, "] ", domain, " ", strings.Join(answers, " "), " TTL ", int(ttl.Seconds()), "s by ", source)
+	} else {
+		log.Print(level, "DNS|Query: [", requester, "] ", domain, " ", strings.Join(answers, " "), " by ", source)
+	}
 }
 
 type CacheServerFactory struct{}
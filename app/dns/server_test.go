@@ -0,0 +1,273 @@
+package dns_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/dispatcher"
+	. "v2ray.com/core/app/dns"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseHosts(t *testing.T) {
+	assert := assert.On(t)
+
+	hosts := ParseHosts(strings.NewReader(`
+# comment
+127.0.0.1	localhost www.v2ray.com
+::1 localhost # IPv6
+fe80::1%lo0 link.local
+invalid v2ray.com
+`))
+	assert.Int(len(hosts["localhost"])).Equals(2)
+	assert.IP(hosts["localhost"][0]).Equals(net.IP{127, 0, 0, 1})
+	assert.IP(hosts["localhost"][1]).Equals(net.IPv6loopback)
+	assert.IP(hosts["www.v2ray.com"][0]).Equals(net.IP{127, 0, 0, 1})
+	assert.Int(len(hosts["link.local"])).Equals(1)
+	assert.Int(len(hosts["v2ray.com"])).Equals(0)
+}
+
+func TestResolveOrder(t *testing.T) {
+	assert := assert.On(t)
+
+	file, err := ioutil.TempFile("", "v2ray-hosts")
+	assert.Error(err).IsNil()
+	defer os.Remove(file.Name())
+	file.WriteString("10.0.0.1 www.v2ray.com\n10.0.0.2 hosts.v2ray.com\n")
+	file.Close()
+
+	config := &Config{
+		HostMapping: []*HostMapping{
+			{
+				Domain: "www.v2ray.com",
+				Ip: []*v2net.IPOrDomain{
+					v2net.NewIPOrDomain(v2net.LocalHostIP),
+					v2net.NewIPOrDomain(v2net.IPAddress(net.IPv6loopback)),
+				},
+			},
+		},
+		HostsFile: file.Name(),
+	}
+
+	// Name servers are not reached, as the space is not initialized.
+	config.ResolveOrder = []ResolveStage{ResolveStage_Static, ResolveStage_SystemHosts}
+	server := NewCacheServer(app.NewSpace(), config)
+	assert.Int(len(server.Get("www.v2ray.com"))).Equals(2)
+	assert.IP(server.Get("WWW.V2RAY.COM.")[1]).Equals(net.IPv6loopback)
+	assert.IP(server.Get("hosts.v2ray.com")[0]).Equals(net.IP{10, 0, 0, 2})
+
+	config.ResolveOrder = []ResolveStage{ResolveStage_SystemHosts, ResolveStage_Static}
+	server = NewCacheServer(app.NewSpace(), config)
+	ips := server.Get("www.v2ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0]).Equals(net.IP{10, 0, 0, 1})
+
+	config.ResolveOrder = []ResolveStage{ResolveStage_Static}
+	server = NewCacheServer(app.NewSpace(), config)
+	assert.Int(len(server.Get("hosts.v2ray.com"))).Equals(0)
+}
+
+func TestQueryLog(t *testing.T) {
+	assert := assert.On(t)
+
+	output := new(bytes.Buffer)
+	log.InitErrorLoggerWithWriter(output)
+	log.SetLogLevel(log.LogLevel_Info)
+	config := &Config{
+		HostMapping: []*HostMapping{
+			{
+				Domain: "www.v2ray.com",
+				Ip: []*v2net.IPOrDomain{
+					v2net.NewIPOrDomain(v2net.LocalHostIP),
+					v2net.NewIPOrDomain(v2net.IPAddress(net.IPv6loopback)),
+				},
+			},
+		},
+		ResolveOrder:  []ResolveStage{ResolveStage_Static},
+		QueryLogLevel: log.LogLevel_Info,
+	}
+	server := NewCacheServer(app.NewSpace(), config)
+	ips := server.GetFor("www.v2ray.com", "direct")
+	assert.Int(len(ips)).Equals(2)
+	assert.IP(ips[0]).Equals(net.IP{127, 0, 0, 1})
+	assert.Int(len(server.GetFor("unknown.v2ray.com", "direct"))).Equals(0)
+	log.InitErrorLoggerWithWriter(os.Stdout)
+	log.SetLogLevel(log.LogLevel_Debug)
+
+	assert.String(output.String()).Contains("[Info]DNS|Query: [direct] www.v2ray.com A: 127.0.0.1 AAAA: ::1 by Static")
+	assert.String(output.String()).Contains("[Info]DNS|Query: [direct] unknown.v2ray.com: no answer")
+}
+
+// answeringOutbound answers all DNS queries with ip and ttl.
+type answeringOutbound struct {
+	ip      net.IP
+	ttl     uint32
+	queries int32
+}
+
+func (this *answeringOutbound) Dispatch(destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	defer link.OutboundOutput().Close()
+	for {
+		query := new(dns.Msg)
+		if err := query.Unpack(payload.Value); err == nil {
+			atomic.AddInt32(&this.queries, 1)
+			reply := new(dns.Msg)
+			reply.SetReply(query)
+			reply.Answer = append(reply.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: this.ttl},
+				A:   this.ip,
+			})
+			packed, _ := reply.Pack()
+			link.OutboundOutput().Write(alloc.NewLocalBuffer(2048).Clear().Append(packed))
+		}
+		payload.Release()
+
+		var err error
+		payload, err = link.OutboundInput().Read()
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// routingDispatcher dispatches all sessions to its handler, as if they are picked by router.
+type routingDispatcher struct {
+	handler proxy.OutboundHandler
+}
+
+func (this *routingDispatcher) DispatchToOutbound(session *proxy.SessionInfo) ray.InboundRay {
+	link := ray.NewRay()
+	go func() {
+		payload, err := link.OutboundInput().Read()
+		if err != nil {
+			return
+		}
+		this.handler.Dispatch(session.Destination, payload, link)
+	}()
+	return link
+}
+
+func (this *routingDispatcher) Release() {}
+
+func TestNameServerOutbound(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	outboundManager := proxyman.NewDefaultOutboundHandlerManager()
+	outboundManager.SetHandler("proxy", &answeringOutbound{ip: net.IP{10, 0, 0, 1}, ttl: 60})
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, outboundManager)
+	space.BindApp(dispatcher.APP_ID, &routingDispatcher{
+		handler: &answeringOutbound{ip: net.IP{10, 0, 0, 2}, ttl: 60},
+	})
+
+	nameServer := &v2net.Endpoint{
+		Network: v2net.Network_UDP,
+		Address: v2net.NewIPOrDomain(v2net.IPAddress([]byte{8, 8, 8, 8})),
+		Port:    53,
+	}
+	server := NewCacheServer(space, &Config{
+		NameServers: []*v2net.Endpoint{nameServer},
+		DomainNameServers: []*DomainNameServer{
+			{
+				Domain:      []string{"v2ray.com"},
+				NameServers: []*v2net.Endpoint{nameServer},
+				OutboundTag: "proxy",
+			},
+		},
+	})
+	assert.Error(space.Initialize()).IsNil()
+
+	ips := server.Get("www.v2ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0]).Equals(net.IP{10, 0, 0, 1})
+	ips = server.Get("www.google.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0]).Equals(net.IP{10, 0, 0, 2})
+
+	// The outbound resolves its own server without going through itself.
+	ips = server.GetFor("server.v2ray.com", "proxy")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0]).Equals(net.IP{10, 0, 0, 2})
+}
+
+func TestNameServerOutboundOfAnotherTag(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	proxyOutbound := &answeringOutbound{ip: net.IP{10, 0, 0, 1}, ttl: 60}
+	outboundManager := proxyman.NewDefaultOutboundHandlerManager()
+	outboundManager.SetHandler("proxy", proxyOutbound)
+	outboundManager.SetHandler("alias", proxyOutbound)
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, outboundManager)
+	space.BindApp(dispatcher.APP_ID, &routingDispatcher{
+		handler: &answeringOutbound{ip: net.IP{10, 0, 0, 2}, ttl: 60},
+	})
+
+	nameServer := &v2net.Endpoint{
+		Network: v2net.Network_UDP,
+		Address: v2net.NewIPOrDomain(v2net.IPAddress([]byte{8, 8, 8, 8})),
+		Port:    53,
+	}
+	server := NewCacheServer(space, &Config{
+		NameServers: []*v2net.Endpoint{nameServer},
+		DomainNameServers: []*DomainNameServer{
+			{
+				Domain:      []string{"v2ray.com"},
+				NameServers: []*v2net.Endpoint{nameServer},
+				OutboundTag: "alias",
+			},
+		},
+	})
+	assert.Error(space.Initialize()).IsNil()
+
+	// The name server goes through the same outbound under another tag, which is a loop as well.
+	ips := server.GetFor("server.v2ray.com", "proxy")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0]).Equals(net.IP{10, 0, 0, 2})
+}
+
+func TestMinimumTTL(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	outbound := &answeringOutbound{ip: net.IP{10, 0, 0, 1}}
+	space.BindApp(dispatcher.APP_ID, &routingDispatcher{
+		handler: outbound,
+	})
+	server := NewCacheServer(space, &Config{
+		NameServers: []*v2net.Endpoint{
+			{
+				Network: v2net.Network_UDP,
+				Address: v2net.NewIPOrDomain(v2net.IPAddress([]byte{8, 8, 8, 8})),
+				Port:    53,
+			},
+		},
+		MinTtl: 60,
+		MaxTtl: 120,
+	})
+	assert.Error(space.Initialize()).IsNil()
+
+	// The answer has a TTL of 0, but is cached for the minimum TTL.
+	ips := server.Get("www.v2ray.com")
+	assert.Int(len(ips)).Equals(1)
+	assert.IP(ips[0]).Equals(net.IP{10, 0, 0, 1})
+	time.Sleep(time.Millisecond * 100)
+	assert.Int(len(server.GetCached("www.v2ray.com."))).Equals(1)
+	assert.Int(len(server.Get("www.v2ray.com"))).Equals(1)
+	assert.Int(int(atomic.LoadInt32(&outbound.queries))).Equals(1)
+}
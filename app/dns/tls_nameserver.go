@@ -0,0 +1,291 @@
+package dns
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/serial"
+	"v2ray.com/core/transport/internet"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	DefaultTLSPort = v2net.Port(853)
+)
+
+var (
+	ErrNameServerClosed = errors.New("DNS: Name server is closed.")
+)
+
+// TLSNameServer is a DNS-over-TLS resolver. Queries are pipelined on a single TLS connection, which is
+// established on first query and re-established after it is closed.
+type TLSNameServer struct {
+	sync.Mutex
+	address   v2net.Destination
+	tlsConfig *tls.Config
+	options   internet.DialerOptions
+	conn      net.Conn
+	// Whether a connection is being established, without the lock held. connected is signaled when it is
+	// done.
+	connecting bool
+	connected  *sync.Cond
+	closed     bool
+	requests   map[uint16]*PendingRequest
+}
+
+// NewTLSNameServer creates a TLSNameServer that connects to address with the given TLS config. Queries
+// go through the outbound with tag if it is not empty.
+func NewTLSNameServer(address v2net.Destination, tlsConfig *tls.Config, tag string) *TLSNameServer {
+	address.Network = v2net.Network_TCP
+	options := internet.DialerOptions{
+		Stream: &internet.StreamConfig{
+			Network: v2net.Network_TCP,
+		},
+	}
+	if len(tag) > 0 {
+		options.Proxy = &internet.ProxyConfig{
+			Tag: tag,
+		}
+	}
+	server := &TLSNameServer{
+		address:   address,
+		tlsConfig: tlsConfig,
+		options:   options,
+		requests:  make(map[uint16]*PendingRequest),
+	}
+	server.connected = sync.NewCond(&server.Mutex)
+	return server
+}
+
+func (this *TLSNameServer) String() string {
+	return "tls:" + this.address.NetAddr()
+}
+
+func (this *TLSNameServer) OutboundTag() string {
+	if this.options.Proxy == nil {
+		return ""
+	}
+	return this.options.Proxy.Tag
+}
+
+func (this *TLSNameServer) QueryA(domain string) <-chan *ARecord {
+	response := make(chan *ARecord, 1)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.closed {
+		close(response)
+		return response
+	}
+	conn, err := this.getConnection()
+	if err != nil {
+		log.Warning("DNS: Failed to connect to TLS name server ", this.address, ": ", err)
+		close(response)
+		return response
+	}
+	id := this.assignUnusedID(response)
+
+	query := buildQueryA(domain, id)
+	defer query.Release()
+	// Each message is prefixed by its length over TCP.
+	frame := make([]byte, 0, 2+query.Len())
+	frame = serial.Uint16ToBytes(uint16(query.Len()), frame)
+	frame = append(frame, query.Value...)
+
+	conn.SetWriteDeadline(time.Now().Add(QueryTimeout))
+	if _, err := conn.Write(frame); err != nil {
+		log.Warning("DNS: Failed to send query to TLS name server ", this.address, ": ", err)
+		this.closeConnection(conn)
+	}
+	return response
+}
+
+// getConnection returns the current connection, or establishes a new one. It must be called with the lock
+// held, which is released while connecting, so that queries the dial depends on don't wait for the lock.
+func (this *TLSNameServer) getConnection() (net.Conn, error) {
+	for this.connecting {
+		this.connected.Wait()
+	}
+	if this.conn != nil {
+		return this.conn, nil
+	}
+
+	this.connecting = true
+	this.Unlock()
+	conn, err := this.connect()
+	this.Lock()
+	this.connecting = false
+	this.connected.Broadcast()
+
+	if err != nil {
+		return nil, err
+	}
+	if this.closed {
+		conn.Close()
+		return nil, ErrNameServerClosed
+	}
+	this.conn = conn
+	go this.readResponses(conn)
+	return conn, nil
+}
+
+// connect establishes a new connection and completes its TLS handshake.
+func (this *TLSNameServer) connect() (net.Conn, error) {
+	rawConn, err := this.dial()
+	if err != nil {
+		return nil, err
+	}
+	rawConn.SetReusable(false)
+	conn := tls.Client(rawConn, this.tlsConfig)
+	conn.SetDeadline(time.Now().Add(QueryTimeout))
+	if err := conn.Handshake(); err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// dial connects to the server. Domain of a server without outbound is resolved by system resolver, as
+// resolving it by CacheServer may query this server, which is not connected yet. Outbounds never resolve
+// domains through name servers that go through themselves.
+func (this *TLSNameServer) dial() (internet.Connection, error) {
+	if this.options.Proxy != nil || !this.address.Address.Family().IsDomain() {
+		return internet.Dial(nil, this.address, this.options)
+	}
+	ips, err := net.LookupIP(this.address.Address.Domain())
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		dest := this.address
+		dest.Address = v2net.IPAddress(ip)
+		var conn internet.Connection
+		conn, err = internet.Dial(nil, dest, this.options)
+		if err == nil {
+			return conn, nil
+		}
+		log.Debug("DNS: Failed to connect to TLS name server ", dest, ": ", err)
+	}
+	return nil, err
+}
+
+// Close closes the connection, and fails pending and further queries.
+func (this *TLSNameServer) Close() {
+	this.Lock()
+	defer this.Unlock()
+
+	this.closed = true
+	if this.conn != nil {
+		this.closeConnection(this.conn)
+	}
+}
+
+// closeConnection closes conn if it is the current connection, and fails all pending requests on it. It
+// must be called with the lock held.
+func (this *TLSNameServer) closeConnection(conn net.Conn) {
+	if this.conn != conn {
+		return
+	}
+	conn.Close()
+	this.conn = nil
+	for id, request := range this.requests {
+		close(request.response)
+		delete(this.requests, id)
+	}
+}
+
+// assignUnusedID must be called with the lock held.
+func (this *TLSNameServer) assignUnusedID(response chan<- *ARecord) uint16 {
+	now := time.Now()
+	for id, request := range this.requests {
+		if request.expire.Before(now) {
+			close(request.response)
+			delete(this.requests, id)
+		}
+	}
+
+	for {
+		id := uint16(dice.Roll(65536))
+		if _, found := this.requests[id]; found {
+			continue
+		}
+		this.requests[id] = &PendingRequest{
+			expire:   now.Add(QueryTimeout),
+			response: response,
+		}
+		return id
+	}
+}
+
+func (this *TLSNameServer) readResponses(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		payload, err := readTCPMessage(reader)
+		if err != nil {
+			if err != io.EOF {
+				log.Info("DNS: Connection to TLS name server ", this.address, " closed: ", err)
+			}
+			break
+		}
+		this.handleResponse(payload)
+		payload.Release()
+	}
+
+	this.Lock()
+	this.closeConnection(conn)
+	this.Unlock()
+}
+
+func (this *TLSNameServer) handleResponse(payload *alloc.Buffer) {
+	msg := new(dns.Msg)
+	if err := msg.Unpack(payload.Value); err != nil {
+		log.Warning("DNS: Failed to parse DNS response: ", err)
+		return
+	}
+
+	this.Lock()
+	request, found := this.requests[msg.Id]
+	if found {
+		delete(this.requests, msg.Id)
+	}
+	this.Unlock()
+	if !found {
+		return
+	}
+
+	request.response <- recordFromMsg(msg)
+	close(request.response)
+}
+
+// readTCPMessage reads a DNS message prefixed by its length. Messages are up to 64KB.
+func readTCPMessage(reader io.Reader) (*alloc.Buffer, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+	length := int(serial.BytesToUint16(header[:]))
+	buffer := alloc.NewBufferWithSize(length)
+	if length > len(buffer.Value) {
+		// Just beyond a large buffer.
+		buffer.Release()
+		buffer = alloc.NewLocalBuffer(length)
+	}
+	buffer.Slice(0, length)
+	if _, err := io.ReadFull(reader, buffer.Value); err != nil {
+		buffer.Release()
+		return nil, err
+	}
+	return buffer, nil
+}
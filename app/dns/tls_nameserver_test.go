@@ -0,0 +1,231 @@
+package dns_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/dispatcher"
+	. "v2ray.com/core/app/dns"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/serial"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	_ "v2ray.com/core/transport/internet/tcp"
+
+	"github.com/miekg/dns"
+)
+
+// startTLSStub starts a DoT server for dns.v2ray.com. It reads two queries before answering, and answers
+// them in reverse order, so the client has to pipeline its queries.
+func startTLSStub(assert *assert.Assert) (net.Listener, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Error(err).IsNil()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"dns.v2ray.com"},
+	}
+	rawCert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Error(err).IsNil()
+	cert, err := x509.ParseCertificate(rawCert)
+	assert.Error(err).IsNil()
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{rawCert}, PrivateKey: key}},
+	})
+	assert.Error(err).IsNil()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTLSStub(conn)
+		}
+	}()
+	return listener, pool
+}
+
+func serveTLSStub(conn net.Conn) {
+	defer conn.Close()
+
+	queries := make([]*dns.Msg, 0, 2)
+	for len(queries) < 2 {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		payload := make([]byte, serial.BytesToUint16(header))
+		if _, err := io.ReadFull(conn, payload); err != nil {
+			return
+		}
+		query := new(dns.Msg)
+		if err := query.Unpack(payload); err != nil {
+			return
+		}
+		queries = append(queries, query)
+	}
+
+	for idx := len(queries) - 1; idx >= 0; idx-- {
+		query := queries[idx]
+		response := new(dns.Msg)
+		response.SetReply(query)
+		ip := net.IP{10, 0, 0, 1}
+		if query.Question[0].Name == "v2.v2ray.com." {
+			ip = net.IP{10, 0, 0, 2}
+		}
+		records := 1
+		if query.Question[0].Name == "large.v2ray.com." {
+			// Larger than a buffer.
+			records = 1000
+		}
+		for i := 0; i < records; i++ {
+			response.Answer = append(response.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: query.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   ip,
+			})
+		}
+		payload, _ := response.Pack()
+		conn.Write(append(serial.Uint16ToBytes(uint16(len(payload)), nil), payload...))
+	}
+}
+
+func TestTLSNameServer(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, pool := startTLSStub(assert)
+	defer listener.Close()
+	address := v2net.DestinationFromAddr(listener.Addr())
+
+	server := NewTLSNameServer(address, &tls.Config{ServerName: "dns.v2ray.com", RootCAs: pool}, "")
+	response1 := server.QueryA("v1.v2ray.com")
+	response2 := server.QueryA("v2.v2ray.com")
+
+	record := <-response2
+	assert.Pointer(record).IsNotNil()
+	assert.IP(record.IPs[0]).Equals(net.IP{10, 0, 0, 2})
+	record = <-response1
+	assert.Pointer(record).IsNotNil()
+	assert.IP(record.IPs[0]).Equals(net.IP{10, 0, 0, 1})
+	assert.Bool(record.Expire.Before(time.Now().Add(time.Minute + time.Second))).IsTrue()
+}
+
+func TestTLSNameServerOfLargeResponse(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, pool := startTLSStub(assert)
+	defer listener.Close()
+	address := v2net.DestinationFromAddr(listener.Addr())
+
+	server := NewTLSNameServer(address, &tls.Config{ServerName: "dns.v2ray.com", RootCAs: pool}, "")
+	response1 := server.QueryA("large.v2ray.com")
+	response2 := server.QueryA("v2.v2ray.com")
+
+	record := <-response1
+	assert.Pointer(record).IsNotNil()
+	assert.Int(len(record.IPs)).Equals(1000)
+	record = <-response2
+	assert.Pointer(record).IsNotNil()
+	assert.IP(record.IPs[0]).Equals(net.IP{10, 0, 0, 2})
+}
+
+func TestTLSNameServerVerifiesCertificate(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, pool := startTLSStub(assert)
+	defer listener.Close()
+	address := v2net.DestinationFromAddr(listener.Addr())
+
+	server := NewTLSNameServer(address, &tls.Config{ServerName: "evil.v2ray.com", RootCAs: pool}, "")
+	record, open := <-server.QueryA("v1.v2ray.com")
+	assert.Pointer(record).IsNil()
+	assert.Bool(open).IsFalse()
+}
+
+func TestTLSNameServerOfDomainResolvingOutbound(t *testing.T) {
+	assert := assert.On(t)
+
+	// The server only reports whether a ClientHello arrives, as it has no certificate for localhost.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	handshaking := make(chan bool, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		header := make([]byte, 1)
+		_, err = io.ReadFull(conn, header)
+		handshaking <- err == nil && header[0] == 0x16
+	}()
+
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, &routingDispatcher{
+		handler: &answeringOutbound{ip: net.IP{10, 0, 0, 1}},
+	})
+	server := NewCacheServer(space, &Config{
+		TlsNameServers: []*TLSNameServerConfig{
+			{
+				Address: &v2net.Endpoint{
+					Address: v2net.NewIPOrDomain(v2net.DomainAddress("localhost")),
+					Port:    uint32(v2net.DestinationFromAddr(listener.Addr()).Port),
+				},
+			},
+		},
+		// Address of the name server can only be resolved by the name server itself.
+		ResolveOrder:    []ResolveStage{ResolveStage_NameServer},
+		ResolveOutbound: true,
+	})
+	defer internet.UseDomainResolver(nil)
+	assert.Error(space.Initialize()).IsNil()
+
+	done := make(chan []net.IP, 1)
+	go func() {
+		done <- server.Get("v1.v2ray.com")
+	}()
+	select {
+	case <-done:
+	case <-time.After(QueryTimeout * 2):
+		t.Fatal("Query to TLS name server doesn't finish.")
+	}
+	assert.Bool(<-handshaking).IsTrue()
+}
+
+func TestTLSNameServerClose(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, pool := startTLSStub(assert)
+	defer listener.Close()
+	address := v2net.DestinationFromAddr(listener.Addr())
+
+	// The stub doesn't answer until the second query, so the first one is pending on close.
+	server := NewTLSNameServer(address, &tls.Config{ServerName: "dns.v2ray.com", RootCAs: pool}, "")
+	response := server.QueryA("v1.v2ray.com")
+	server.Close()
+	select {
+	case record, open := <-response:
+		assert.Pointer(record).IsNil()
+		assert.Bool(open).IsFalse()
+	case <-time.After(time.Second):
+		t.Fatal("Pending query doesn't fail on close.")
+	}
+
+	record, open := <-server.QueryA("v2.v2ray.com")
+	assert.Pointer(record).IsNil()
+	assert.Bool(open).IsFalse()
+}
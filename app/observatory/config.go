@@ -0,0 +1,64 @@
+package observatory
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	defaultInterval    = 300 * time.Second
+	defaultTimeout     = 10 * time.Second
+	defaultConcurrency = 4
+	defaultURL         = "http://www.gstatic.com/generate_204"
+)
+
+func (this *Config) GetEffectiveInterval() time.Duration {
+	if this.Interval == 0 {
+		return defaultInterval
+	}
+	return time.Duration(this.Interval) * time.Second
+}
+
+func (this *Config) GetEffectiveTimeout() time.Duration {
+	if this.Timeout == 0 {
+		return defaultTimeout
+	}
+	return time.Duration(this.Timeout) * time.Second
+}
+
+func (this *Config) GetEffectiveConcurrency() int {
+	if this.Concurrency == 0 {
+		return defaultConcurrency
+	}
+	return int(this.Concurrency)
+}
+
+// BuildProbe returns the destination of probes and the HTTP request that they send.
+func (this *Config) BuildProbe() (v2net.Destination, []byte, error) {
+	rawURL := this.Url
+	if len(rawURL) == 0 {
+		rawURL = defaultURL
+	}
+	probeURL, err := url.Parse(rawURL)
+	if err != nil {
+		return v2net.Destination{}, nil, errors.New("Observatory: Invalid URL: " + err.Error())
+	}
+	if probeURL.Scheme != "http" {
+		return v2net.Destination{}, nil, errors.New("Observatory: Only HTTP URLs are supported: " + rawURL)
+	}
+	port := v2net.Port(80)
+	if len(probeURL.Port()) > 0 {
+		portValue, err := strconv.ParseUint(probeURL.Port(), 10, 16)
+		if err != nil || portValue == 0 {
+			return v2net.Destination{}, nil, errors.New("Observatory: Invalid port in URL: " + rawURL)
+		}
+		port = v2net.Port(portValue)
+	}
+	dest := v2net.TCPDestination(v2net.ParseAddress(probeURL.Hostname()), port)
+	request := "GET " + probeURL.RequestURI() + " HTTP/1.1\r\nHost: " + probeURL.Host + "\r\nConnection: close\r\n\r\n"
+	return dest, []byte(request), nil
+}
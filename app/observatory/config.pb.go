@@ -0,0 +1,72 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/app/observatory/config.proto
+// DO NOT EDIT!
+
+/*
+Package observatory is a generated protocol buffer package.
+
+It is generated from these files:
+	v2ray.com/core/app/observatory/config.proto
+
+It has these top-level messages:
+	Config
+*/
+package observatory
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type Config struct {
+	// Tags of outbounds to probe. All outbounds that support probing if empty, including untagged ones,
+	// whose scores are under names of "untagged#<n>".
+	OutboundTag []string `protobuf:"bytes,1,rep,name=outbound_tag,json=outboundTag" json:"outbound_tag,omitempty"`
+	// Seconds between two probes of each server. Default to 300.
+	Interval uint32 `protobuf:"varint,2,opt,name=interval" json:"interval,omitempty"`
+	// HTTP URL that probes request through servers. Default to http://www.gstatic.com/generate_204.
+	Url string `protobuf:"bytes,3,opt,name=url" json:"url,omitempty"`
+	// Maximum number of probes in progress at the same time. Default to 4.
+	Concurrency uint32 `protobuf:"varint,4,opt,name=concurrency" json:"concurrency,omitempty"`
+	// Seconds to wait for the response of each probe. Default to 10.
+	Timeout uint32 `protobuf:"varint,5,opt,name=timeout" json:"timeout,omitempty"`
+}
+
+func (m *Config) Reset()                    { *m = Config{} }
+func (m *Config) String() string            { return proto.CompactTextString(m) }
+func (*Config) ProtoMessage()               {}
+func (*Config) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func init() {
+	proto.RegisterType((*Config)(nil), "v2ray.core.app.observatory.Config")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/app/observatory/config.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 216 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x8f, 0x41, 0x4b, 0xc5, 0x30,
+	0x10, 0x84, 0x89, 0xd5, 0xa7, 0x6f, 0xa3, 0x20, 0x39, 0x85, 0x77, 0x90, 0xe8, 0xa9, 0x20, 0x24,
+	0xa0, 0x37, 0x8f, 0xcf, 0x3f, 0x20, 0xc5, 0x93, 0x17, 0x49, 0x63, 0x2c, 0x85, 0x36, 0x1b, 0xd6,
+	0xa4, 0xd0, 0x1f, 0xe2, 0xff, 0x95, 0x46, 0x2a, 0xbd, 0x78, 0xdb, 0x9d, 0xf9, 0x06, 0x66, 0xe0,
+	0x7e, 0x7a, 0x20, 0x3b, 0x6b, 0x87, 0xa3, 0x71, 0x48, 0xde, 0xd8, 0x18, 0x0d, 0xb6, 0x5f, 0x9e,
+	0x26, 0x9b, 0x90, 0x66, 0xe3, 0x30, 0x7c, 0xf6, 0x9d, 0x8e, 0x84, 0x09, 0xc5, 0x61, 0x85, 0xc9,
+	0x6b, 0x1b, 0xa3, 0xde, 0x80, 0x77, 0xdf, 0x0c, 0x76, 0xcf, 0x05, 0x16, 0xb7, 0x70, 0x89, 0x39,
+	0xb5, 0x98, 0xc3, 0xc7, 0x7b, 0xb2, 0x9d, 0x64, 0xaa, 0xaa, 0xf7, 0x0d, 0x5f, 0xb5, 0x57, 0xdb,
+	0x89, 0x03, 0x5c, 0xf4, 0x21, 0x2d, 0xe1, 0x41, 0x9e, 0x28, 0x56, 0x5f, 0x35, 0x7f, 0xbf, 0xb8,
+	0x86, 0x2a, 0xd3, 0x20, 0x2b, 0xc5, 0xea, 0x7d, 0xb3, 0x9c, 0x42, 0x01, 0x77, 0x18, 0x5c, 0x26,
+	0xf2, 0xc1, 0xcd, 0xf2, 0xb4, 0x04, 0xb6, 0x92, 0x90, 0x70, 0x9e, 0xfa, 0xd1, 0x63, 0x4e, 0xf2,
+	0xac, 0xb8, 0xeb, 0x7b, 0x7c, 0x82, 0x1b, 0x87, 0xa3, 0xfe, 0xbf, 0xf9, 0x91, 0xff, 0xd6, 0x7e,
+	0x59, 0x26, 0xbe, 0xf1, 0x8d, 0xd3, 0xee, 0xca, 0xec, 0xc7, 0x9f, 0x00, 0x00, 0x00, 0xff, 0xff,
+	0x7e, 0xe7, 0xb5, 0xa8, 0x25, 0x01, 0x00, 0x00,
+}
@@ -0,0 +1,368 @@
+package observatory
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/api"
+	"v2ray.com/core/common/loader"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+)
+
+const (
+	APP_ID = app.ID(8)
+
+	// Weight of each new probe in the success rate.
+	successWeight = 0.2
+
+	// Prefix of names that untagged outbounds are registered with, which are followed by a number.
+	UntaggedPrefix = "untagged#"
+)
+
+// Prober is an outbound handler whose servers can be probed by the observatory.
+type Prober interface {
+	// ProbeServers returns the servers to probe.
+	ProbeServers() []*protocol.ServerSpec
+	// Probe sends request to dest through server, and returns once the response starts.
+	Probe(server *protocol.ServerSpec, dest v2net.Destination, request []byte, timeout time.Duration) error
+}
+
+// Score is the state of a server from its recent probes.
+type Score struct {
+	Server string `json:"server"`
+	// Moving average latency of successful probes, in nanoseconds. 0 if no probe succeeded yet.
+	Latency time.Duration `json:"latency"`
+	// Moving average of probe results, from 0 for all failures to 1 for all successes.
+	SuccessRate float64   `json:"successRate"`
+	Probes      uint64    `json:"probes"`
+	LastProbe   time.Time `json:"lastProbe"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// ProbeResult is a probe of a server, which is sent to subscribers of its outbound.
+type ProbeResult struct {
+	Server  *protocol.ServerSpec
+	Latency time.Duration
+	// Error of the probe, or nil if it succeeded.
+	Err   error
+	Score Score
+}
+
+type observedOutbound struct {
+	prober      Prober
+	scores      map[string]*Score
+	subscribers []func(result *ProbeResult)
+}
+
+// Observatory probes servers of outbounds periodically, and keeps their scores. Outbounds register
+// themselves during application initialization, and subscribe to results of their servers.
+type Observatory struct {
+	sync.Mutex
+	config    *Config
+	tags      map[string]bool
+	dest      v2net.Destination
+	request   []byte
+	outbounds map[string]*observedOutbound
+	slots     chan bool
+	done      chan bool
+	started   bool
+	// Number of untagged outbounds registered so far.
+	untagged  int
+	closeOnce sync.Once
+}
+
+func NewObservatory(config *Config, space app.Space) (*Observatory, error) {
+	dest, request, err := config.BuildProbe()
+	if err != nil {
+		return nil, err
+	}
+	observatory := &Observatory{
+		config:    config,
+		tags:      make(map[string]bool),
+		dest:      dest,
+		request:   request,
+		outbounds: make(map[string]*observedOutbound),
+		slots:     make(chan bool, config.GetEffectiveConcurrency()),
+		done:      make(chan bool),
+	}
+	for _, tag := range config.OutboundTag {
+		observatory.tags[tag] = true
+	}
+	space.InitializeApplication(func() error {
+		if space.HasApp(api.APP_ID) {
+			space.GetApp(api.APP_ID).(*api.ApiServer).Handle("/observatory", http.HandlerFunc(observatory.serveScores))
+		}
+		observatory.Start()
+		return nil
+	})
+	return observatory, nil
+}
+
+// Register adds the outbound of tag to be probed, unless it is not in the configured tags, with an
+// optional subscriber of its results. It replaces the outbound registered before with the same tag, e.g.,
+// when outbounds are reloaded. An outbound that is registered after start is probed right away.
+func (this *Observatory) Register(tag string, prober Prober, subscriber func(result *ProbeResult)) {
+	if len(this.tags) > 0 && !this.tags[tag] {
+		return
+	}
+
+	this.Lock()
+	outbound := &observedOutbound{
+		prober: prober,
+		scores: make(map[string]*Score),
+	}
+	if subscriber != nil {
+		outbound.subscribers = append(outbound.subscribers, subscriber)
+	}
+	this.outbounds[tag] = outbound
+	started := this.started
+	this.Unlock()
+
+	log.Info("Observatory: Observing outbound [", tag, "].")
+	if started {
+		go this.probeOutbound(outbound)
+	}
+}
+
+// RegisterUntagged adds an outbound without tag to be probed, unless only configured tags are. It returns
+// the internal name that the outbound is registered with, or empty if it is not registered.
+func (this *Observatory) RegisterUntagged(prober Prober, subscriber func(result *ProbeResult)) string {
+	if len(this.tags) > 0 {
+		return ""
+	}
+
+	this.Lock()
+	var name string
+	for {
+		this.untagged++
+		name = UntaggedPrefix + strconv.Itoa(this.untagged)
+		if _, found := this.outbounds[name]; !found {
+			break
+		}
+	}
+	this.Unlock()
+
+	this.Register(name, prober, subscriber)
+	return name
+}
+
+// Unregister stops probing the outbound of tag, if it is still registered with prober, e.g., when the
+// outbound is closed. An outbound that replaces it with the same tag is not affected.
+func (this *Observatory) Unregister(tag string, prober Prober) {
+	this.Lock()
+	defer this.Unlock()
+
+	if outbound, found := this.outbounds[tag]; found && outbound.prober == prober {
+		delete(this.outbounds, tag)
+	}
+}
+
+// Subscribe adds a handler of probe results of the outbound with tag. It does nothing if the outbound is
+// not registered.
+func (this *Observatory) Subscribe(tag string, handler func(result *ProbeResult)) {
+	this.Lock()
+	defer this.Unlock()
+
+	if outbound, found := this.outbounds[tag]; found {
+		outbound.subscribers = append(outbound.subscribers, handler)
+	}
+}
+
+// Scores returns scores of servers of the outbound with tag, sorted by server.
+func (this *Observatory) Scores(tag string) []Score {
+	this.Lock()
+	defer this.Unlock()
+
+	outbound, found := this.outbounds[tag]
+	if !found {
+		return nil
+	}
+	scores := make([]Score, 0, len(outbound.scores))
+	for _, score := range outbound.scores {
+		scores = append(scores, *score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].Server < scores[j].Server
+	})
+	return scores
+}
+
+// Start probes all servers in every interval. The first probes are sent when outbounds are registered.
+func (this *Observatory) Start() {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.started {
+		return
+	}
+	this.started = true
+	go this.run()
+}
+
+func (this *Observatory) run() {
+	ticker := time.NewTicker(this.config.GetEffectiveInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-this.done:
+			return
+		case <-ticker.C:
+			this.ProbeAll()
+		}
+	}
+}
+
+// ProbeAll probes all servers of all outbounds, and waits for the results.
+func (this *Observatory) ProbeAll() {
+	this.Lock()
+	outbounds := make([]*observedOutbound, 0, len(this.outbounds))
+	for _, outbound := range this.outbounds {
+		outbounds = append(outbounds, outbound)
+	}
+	this.Unlock()
+
+	var wg sync.WaitGroup
+	for _, outbound := range outbounds {
+		wg.Add(1)
+		go func(outbound *observedOutbound) {
+			defer wg.Done()
+			this.probeOutbound(outbound)
+		}(outbound)
+	}
+	wg.Wait()
+}
+
+func (this *Observatory) probeOutbound(outbound *observedOutbound) {
+	servers := outbound.prober.ProbeServers()
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		select {
+		case this.slots <- true:
+		case <-this.done:
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(server *protocol.ServerSpec) {
+			defer wg.Done()
+			defer func() { <-this.slots }()
+			this.probe(outbound, server)
+		}(server)
+	}
+	wg.Wait()
+
+	// Servers may be removed, e.g., by subscriptions.
+	current := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		current[server.Destination().NetAddr()] = true
+	}
+	this.Lock()
+	for key := range outbound.scores {
+		if !current[key] {
+			delete(outbound.scores, key)
+		}
+	}
+	this.Unlock()
+}
+
+func (this *Observatory) probe(outbound *observedOutbound, server *protocol.ServerSpec) {
+	start := time.Now()
+	err := outbound.prober.Probe(server, this.dest, this.request, this.config.GetEffectiveTimeout())
+	result := &ProbeResult{
+		Server: server,
+		Err:    err,
+	}
+	if err == nil {
+		result.Latency = time.Since(start)
+	} else {
+		log.Debug("Observatory: Probe through ", server.Destination(), " failed: ", err)
+	}
+
+	key := server.Destination().NetAddr()
+	this.Lock()
+	score, found := outbound.scores[key]
+	if !found {
+		score = &Score{
+			Server: key,
+		}
+		outbound.scores[key] = score
+	}
+	score.update(result, start)
+	result.Score = *score
+	subscribers := outbound.subscribers
+	this.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(result)
+	}
+}
+
+func (this *Score) update(result *ProbeResult, start time.Time) {
+	success := 0.0
+	if result.Err == nil {
+		success = 1
+		if this.Latency == 0 {
+			this.Latency = result.Latency
+		} else {
+			this.Latency += time.Duration(float64(result.Latency-this.Latency) * successWeight)
+		}
+		this.LastError = ""
+	} else {
+		this.LastError = result.Err.Error()
+	}
+	if this.Probes == 0 {
+		this.SuccessRate = success
+	} else {
+		this.SuccessRate += (success - this.SuccessRate) * successWeight
+	}
+	this.Probes++
+	this.LastProbe = start
+}
+
+// serveScores returns scores of all outbounds by their tags on GET.
+func (this *Observatory) serveScores(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		api.WriteError(writer, http.StatusMethodNotAllowed, api.ErrInvalidRequest)
+		return
+	}
+	this.Lock()
+	tags := make([]string, 0, len(this.outbounds))
+	for tag := range this.outbounds {
+		tags = append(tags, tag)
+	}
+	this.Unlock()
+
+	scores := make(map[string][]Score, len(tags))
+	for _, tag := range tags {
+		scores[tag] = this.Scores(tag)
+	}
+	api.WriteJSON(writer, scores)
+}
+
+// Release stops probing. Probes in progress are not affected.
+func (this *Observatory) Release() {
+	this.closeOnce.Do(func() {
+		close(this.done)
+	})
+}
+
+type ObservatoryFactory struct{}
+
+func (ObservatoryFactory) Create(space app.Space, config interface{}) (app.Application, error) {
+	return NewObservatory(config.(*Config), space)
+}
+
+func (ObservatoryFactory) AppId() app.ID {
+	return APP_ID
+}
+
+func init() {
+	app.RegisterApplicationFactory(loader.GetType(new(Config)), ObservatoryFactory{})
+}
@@ -0,0 +1,143 @@
+package observatory_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app"
+	. "v2ray.com/core/app/observatory"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/testing/assert"
+)
+
+type testProber struct {
+	sync.Mutex
+	servers  []*protocol.ServerSpec
+	failing  map[string]bool
+	requests []string
+}
+
+func (this *testProber) ProbeServers() []*protocol.ServerSpec {
+	this.Lock()
+	defer this.Unlock()
+	return this.servers
+}
+
+func (this *testProber) Probe(server *protocol.ServerSpec, dest v2net.Destination, request []byte, timeout time.Duration) error {
+	this.Lock()
+	defer this.Unlock()
+
+	this.requests = append(this.requests, dest.String()+" "+string(request))
+	if this.failing[server.Destination().NetAddr()] {
+		return errors.New("probe failed")
+	}
+	return nil
+}
+
+func newTestServer(port v2net.Port) *protocol.ServerSpec {
+	return protocol.NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, port), protocol.AlwaysValid())
+}
+
+func TestObservatory(t *testing.T) {
+	assert := assert.On(t)
+
+	observatory, err := NewObservatory(&Config{
+		Url: "http://v2ray.com:8080/generate_204",
+	}, app.NewSpace())
+	assert.Error(err).IsNil()
+	defer observatory.Release()
+
+	prober := &testProber{
+		servers: []*protocol.ServerSpec{newTestServer(8388), newTestServer(8389)},
+		failing: map[string]bool{"127.0.0.1:8389": true},
+	}
+	var results []*ProbeResult
+	var resultLock sync.Mutex
+	observatory.Register("proxy", prober, func(result *ProbeResult) {
+		resultLock.Lock()
+		defer resultLock.Unlock()
+		results = append(results, result)
+	})
+
+	observatory.ProbeAll()
+	assert.String(prober.requests[0]).Equals("tcp:v2ray.com:8080 GET /generate_204 HTTP/1.1\r\nHost: v2ray.com:8080\r\nConnection: close\r\n\r\n")
+	assert.Int(len(results)).Equals(2)
+	scores := observatory.Scores("proxy")
+	assert.Int(len(scores)).Equals(2)
+	assert.String(scores[0].Server).Equals("127.0.0.1:8388")
+	assert.Bool(scores[0].SuccessRate == 1).IsTrue()
+	assert.String(scores[1].Server).Equals("127.0.0.1:8389")
+	assert.Bool(scores[1].SuccessRate == 0).IsTrue()
+	assert.String(scores[1].LastError).Equals("probe failed")
+
+	// The server recovers, and its success rate goes up gradually.
+	prober.failing = nil
+	observatory.ProbeAll()
+	scores = observatory.Scores("proxy")
+	assert.Bool(scores[1].SuccessRate > 0 && scores[1].SuccessRate < 1).IsTrue()
+	assert.Int64(int64(scores[1].Probes)).Equals(2)
+	assert.String(scores[1].LastError).Equals("")
+
+	// Scores of removed servers are dropped.
+	prober.servers = prober.servers[:1]
+	observatory.ProbeAll()
+	assert.Int(len(observatory.Scores("proxy"))).Equals(1)
+}
+
+func TestObservatoryOutboundTags(t *testing.T) {
+	assert := assert.On(t)
+
+	observatory, err := NewObservatory(&Config{
+		OutboundTag: []string{"proxy"},
+	}, app.NewSpace())
+	assert.Error(err).IsNil()
+	defer observatory.Release()
+
+	prober := &testProber{
+		servers: []*protocol.ServerSpec{newTestServer(8388)},
+	}
+	observatory.Register("direct", prober, nil)
+	observatory.ProbeAll()
+	assert.Int(len(prober.requests)).Equals(0)
+	assert.Int(len(observatory.Scores("direct"))).Equals(0)
+
+	_, err = NewObservatory(&Config{Url: "https://v2ray.com/"}, app.NewSpace())
+	assert.Error(err).IsNotNil()
+}
+
+func TestObservatoryUntaggedOutbounds(t *testing.T) {
+	assert := assert.On(t)
+
+	observatory, err := NewObservatory(&Config{}, app.NewSpace())
+	assert.Error(err).IsNil()
+	defer observatory.Release()
+
+	first := &testProber{
+		servers: []*protocol.ServerSpec{newTestServer(8388)},
+	}
+	second := &testProber{
+		servers: []*protocol.ServerSpec{newTestServer(8389)},
+	}
+	firstName := observatory.RegisterUntagged(first, nil)
+	secondName := observatory.RegisterUntagged(second, nil)
+	assert.String(firstName).Equals(UntaggedPrefix + "1")
+	assert.String(secondName).Equals(UntaggedPrefix + "2")
+	observatory.ProbeAll()
+	assert.Int(len(observatory.Scores(firstName))).Equals(1)
+	assert.Int(len(observatory.Scores(secondName))).Equals(1)
+
+	// Only its own prober unregisters an outbound.
+	observatory.Unregister(firstName, second)
+	assert.Int(len(observatory.Scores(firstName))).Equals(1)
+	observatory.Unregister(firstName, first)
+	assert.Int(len(observatory.Scores(firstName))).Equals(0)
+
+	// Untagged outbounds are not in configured tags.
+	tagged, err := NewObservatory(&Config{OutboundTag: []string{"proxy"}}, app.NewSpace())
+	assert.Error(err).IsNil()
+	defer tagged.Release()
+	assert.String(tagged.RegisterUntagged(first, nil)).Equals("")
+}
@@ -12,6 +12,7 @@ import (
 	v2io "v2ray.com/core/common/io"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	v2proxy "v2ray.com/core/proxy"
 	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/ray"
 )
@@ -20,8 +21,14 @@ const (
 	APP_ID = 7
 )
 
+var (
+	ErrOutboundLoop     = errors.New("Proxy: Loop detected in outbound chain.")
+	ErrOutboundNotFound = errors.New("Proxy: Outbound handler not found.")
+)
+
 type OutboundProxy struct {
 	outboundManager proxyman.OutboundHandlerManager
+	failClosed      bool
 }
 
 func NewOutboundProxy(space app.Space) *OutboundProxy {
@@ -36,6 +43,11 @@ func NewOutboundProxy(space app.Space) *OutboundProxy {
 	return proxy
 }
 
+// SetFailClosed sets whether dialing through a nonexisting outbound fails, instead of dialing directly.
+func (this *OutboundProxy) SetFailClosed(failClosed bool) {
+	this.failClosed = failClosed
+}
+
 func (this *OutboundProxy) RegisterDialer() {
 	internet.ProxyDialer = this.Dial
 }
@@ -44,12 +56,27 @@ func (this *OutboundProxy) Dial(src v2net.Address, dest v2net.Destination, optio
 	handler := this.outboundManager.GetHandler(options.Proxy.Tag)
 	if handler == nil {
 		log.Warning("Proxy: Failed to get outbound handler with tag: ", options.Proxy.Tag)
+		if this.failClosed {
+			return nil, ErrOutboundNotFound
+		}
 		return internet.Dial(src, dest, internet.DialerOptions{
-			Stream: options.Stream,
+			Stream:          options.Stream,
+			Tag:             options.Tag,
+			Chain:           options.Chain,
+			SourcePortRange: options.SourcePortRange,
 		})
 	}
+	chain := options.Chain.Append(options.Tag)
+	if chain.Has(options.Proxy.Tag) {
+		log.Warning("Proxy: Loop detected in outbound chain: ", chain, " -> ", options.Proxy.Tag)
+		return nil, ErrOutboundLoop
+	}
 	stream := ray.NewRay()
-	go handler.Dispatch(dest, alloc.NewLocalBuffer(32).Clear(), stream)
+	session := &v2proxy.SessionInfo{
+		Destination: dest,
+		Chain:       chain,
+	}
+	go v2proxy.DispatchSession(handler, session, alloc.NewLocalBuffer(32).Clear(), stream)
 	return NewProxyConnection(src, dest, stream), nil
 }
 
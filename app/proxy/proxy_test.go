@@ -66,3 +66,34 @@ func TestProxyDial(t *testing.T) {
 	conn.Close()
 	tcpServer.Close()
 }
+
+func TestProxyDialFailClosed(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+
+	proxy := NewOutboundProxy(space)
+	proxy.SetFailClosed(true)
+	space.BindApp(APP_ID, proxy)
+
+	assert.Error(space.Initialize()).IsNil()
+
+	tcpServer := &tcp.Server{
+		MsgProcessor: func(b []byte) []byte { return b },
+	}
+	dest, err := tcpServer.Start()
+	assert.Error(err).IsNil()
+	defer tcpServer.Close()
+
+	// The server is reachable directly, but must not be dialed as the outbound is missing.
+	_, err = proxy.Dial(v2net.LocalHostIP, dest, internet.DialerOptions{
+		Stream: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+		Proxy: &internet.ProxyConfig{
+			Tag: "missing",
+		},
+	})
+	assert.Error(err).Equals(ErrOutboundNotFound)
+}
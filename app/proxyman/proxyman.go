@@ -5,6 +5,7 @@ import (
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/internet"
 )
 
 const (
@@ -67,3 +68,26 @@ func (this *DefaultOutboundHandlerManager) SetHandler(tag string, handler proxy.
 
 	this.taggedHandler[tag] = handler
 }
+
+// ReplaceHandlers replaces the default handler and all tagged handlers at once. Connections in progress
+// keep using the handlers they started with.
+func (this *DefaultOutboundHandlerManager) ReplaceHandlers(defaultHandler proxy.OutboundHandler, taggedHandler map[string]proxy.OutboundHandler) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.defaultHandler = defaultHandler
+	this.taggedHandler = taggedHandler
+}
+
+// IsInChain returns true if the given handler of ohm is one of the outbounds in the chain.
+func IsInChain(ohm OutboundHandlerManager, handler proxy.OutboundHandler, chain internet.OutboundChain) bool {
+	for _, tag := range chain {
+		if tag == "" && handler == ohm.GetDefaultHandler() {
+			return true
+		}
+		if tagged := ohm.GetHandler(tag); tagged != nil && tagged == handler {
+			return true
+		}
+	}
+	return false
+}
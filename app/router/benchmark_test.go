@@ -0,0 +1,49 @@
+package router_test
+
+import (
+	"testing"
+
+	. "v2ray.com/core/app/router"
+	"v2ray.com/core/common/serial"
+)
+
+// largeRuleSet returns a config with a rule of size IPv4 networks and size/16 IPv6 networks, which is
+// about the size of GeoIP data for a large country.
+func largeRuleSet(size int) *Config {
+	cidrs := make([]*CIDR, 0, size+size/16)
+	for i := 0; i < size; i++ {
+		cidrs = append(cidrs, &CIDR{
+			Ip:     serial.Uint32ToBytes(uint32(i)<<8, nil),
+			Prefix: 24,
+		})
+	}
+	for i := 0; i < size/16; i++ {
+		ip := make([]byte, 16)
+		ip[0] = 0x24
+		serial.Uint32ToBytes(uint32(i), ip[2:2])
+		cidrs = append(cidrs, &CIDR{
+			Ip:     ip,
+			Prefix: 48,
+		})
+	}
+	return &Config{
+		Rule: []*RoutingRule{
+			{
+				Tag:  "test",
+				Cidr: cidrs,
+			},
+		},
+	}
+}
+
+func BenchmarkCompileLargeRuleSet(b *testing.B) {
+	config := largeRuleSet(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := CompileRules(config); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -169,6 +169,20 @@ func (this *PortMatcher) Apply(session *proxy.SessionInfo) bool {
 	return this.port.Contains(session.Destination.Port)
 }
 
+type PortListMatcher struct {
+	ports *v2net.PortList
+}
+
+func NewPortListMatcher(ports *v2net.PortList) *PortListMatcher {
+	return &PortListMatcher{
+		ports: ports,
+	}
+}
+
+func (this *PortListMatcher) Apply(session *proxy.SessionInfo) bool {
+	return this.ports.Contains(session.Destination.Port)
+}
+
 type NetworkMatcher struct {
 	network *v2net.NetworkList
 }
@@ -227,3 +241,45 @@ func (this *InboundTagMatcher) Apply(session *proxy.SessionInfo) bool {
 	}
 	return false
 }
+
+type ProtocolMatcher struct {
+	protocols []string
+}
+
+func NewProtocolMatcher(protocols []string) *ProtocolMatcher {
+	return &ProtocolMatcher{
+		protocols: protocols,
+	}
+}
+
+func (this *ProtocolMatcher) Apply(session *proxy.SessionInfo) bool {
+	if len(session.Protocol) == 0 {
+		return false
+	}
+	for _, protocol := range this.protocols {
+		if protocol == session.Protocol {
+			return true
+		}
+	}
+	return false
+}
+
+type ConnectionTagMatcher struct {
+	tags map[string]string
+}
+
+func NewConnectionTagMatcher(tags map[string]string) *ConnectionTagMatcher {
+	return &ConnectionTagMatcher{
+		tags: tags,
+	}
+}
+
+func (this *ConnectionTagMatcher) Apply(session *proxy.SessionInfo) bool {
+	sessionTags := session.GetTags()
+	for key, value := range this.tags {
+		if sessionValue, found := sessionTags[key]; !found || sessionValue != value {
+			return false
+		}
+	}
+	return true
+}
@@ -3,6 +3,7 @@ package router
 import (
 	"errors"
 	"net"
+	"time"
 
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/proxy"
@@ -11,12 +12,37 @@ import (
 type Rule struct {
 	Tag       string
 	Condition Condition
+	// Reason of routes taken by this rule.
+	Reason proxy.RouteReason
+	Policy *proxy.Policy
 }
 
 func (this *Rule) Apply(session *proxy.SessionInfo) bool {
 	return this.Condition.Apply(session)
 }
 
+func (this *ConnectionPolicy) Build() *proxy.Policy {
+	return &proxy.Policy{
+		ConnectTimeout:   time.Duration(this.ConnectTimeout) * time.Second,
+		HandshakeTimeout: time.Duration(this.HandshakeTimeout) * time.Second,
+		IdleTimeout:      time.Duration(this.IdleTimeout) * time.Second,
+	}
+}
+
+// RouteReason returns the reason of routes taken by this rule, by the destination field it matches.
+func (this *RoutingRule) RouteReason() proxy.RouteReason {
+	switch {
+	case len(this.Domain) > 0:
+		return proxy.RouteDomain
+	case len(this.Cidr) > 0:
+		return proxy.RouteIP
+	case len(this.Protocol) > 0:
+		return proxy.RouteProtocol
+	default:
+		return proxy.RouteRule
+	}
+}
+
 func (this *RoutingRule) BuildCondition() (Condition, error) {
 	conds := NewConditionChan()
 
@@ -37,77 +63,31 @@ func (this *RoutingRule) BuildCondition() (Condition, error) {
 	}
 
 	if len(this.Cidr) > 0 {
-		ipv4Net := v2net.NewIPNet()
-		ipv6Cond := NewAnyCondition()
-		hasIpv6 := false
-
-		for _, ip := range this.Cidr {
-			switch len(ip.Ip) {
-			case net.IPv4len:
-				ipv4Net.AddIP(ip.Ip, byte(ip.Prefix))
-			case net.IPv6len:
-				hasIpv6 = true
-				matcher, err := NewCIDRMatcher(ip.Ip, ip.Prefix, false)
-				if err != nil {
-					return nil, err
-				}
-				ipv6Cond.Add(matcher)
-			default:
-				return nil, errors.New("Router: Invalid IP length.")
-			}
-		}
-
-		if !ipv4Net.IsEmpty() && hasIpv6 {
-			cond := NewAnyCondition()
-			cond.Add(NewIPv4Matcher(ipv4Net, false))
-			cond.Add(ipv6Cond)
-			conds.Add(cond)
-		} else if !ipv4Net.IsEmpty() {
-			conds.Add(NewIPv4Matcher(ipv4Net, false))
-		} else if hasIpv6 {
-			conds.Add(ipv6Cond)
+		cond, err := buildCIDRCondition(this.Cidr, false)
+		if err != nil {
+			return nil, err
 		}
+		conds.Add(cond)
 	}
 
 	if this.PortRange != nil {
 		conds.Add(NewPortMatcher(*this.PortRange))
 	}
 
+	if this.PortList != nil && len(this.PortList.Range) > 0 {
+		conds.Add(NewPortListMatcher(this.PortList))
+	}
+
 	if this.NetworkList != nil {
 		conds.Add(NewNetworkMatcher(this.NetworkList))
 	}
 
 	if len(this.SourceCidr) > 0 {
-		ipv4Net := v2net.NewIPNet()
-		ipv6Cond := NewAnyCondition()
-		hasIpv6 := false
-
-		for _, ip := range this.SourceCidr {
-			switch len(ip.Ip) {
-			case net.IPv4len:
-				ipv4Net.AddIP(ip.Ip, byte(ip.Prefix))
-			case net.IPv6len:
-				hasIpv6 = true
-				matcher, err := NewCIDRMatcher(ip.Ip, ip.Prefix, true)
-				if err != nil {
-					return nil, err
-				}
-				ipv6Cond.Add(matcher)
-			default:
-				return nil, errors.New("Router: Invalid IP length.")
-			}
-		}
-
-		if !ipv4Net.IsEmpty() && hasIpv6 {
-			cond := NewAnyCondition()
-			cond.Add(NewIPv4Matcher(ipv4Net, true))
-			cond.Add(ipv6Cond)
-			conds.Add(cond)
-		} else if !ipv4Net.IsEmpty() {
-			conds.Add(NewIPv4Matcher(ipv4Net, true))
-		} else if hasIpv6 {
-			conds.Add(ipv6Cond)
+		cond, err := buildCIDRCondition(this.SourceCidr, true)
+		if err != nil {
+			return nil, err
 		}
+		conds.Add(cond)
 	}
 
 	if len(this.UserEmail) > 0 {
@@ -118,9 +98,51 @@ func (this *RoutingRule) BuildCondition() (Condition, error) {
 		conds.Add(NewInboundTagMatcher(this.InboundTag))
 	}
 
+	if len(this.Protocol) > 0 {
+		conds.Add(NewProtocolMatcher(this.Protocol))
+	}
+
+	if len(this.ConnectionTag) > 0 {
+		conds.Add(NewConnectionTagMatcher(this.ConnectionTag))
+	}
+
 	if conds.Len() == 0 {
 		return nil, errors.New("Router: This rule has no effective fields.")
 	}
 
 	return conds, nil
 }
+
+// buildCIDRCondition builds a condition that matches any of cidrs. IPv4 networks are added into a single
+// IPNet as they are read, which is sized for all of them up front, so that large lists such as GeoIP data
+// don't keep more than one copy of the table in memory while it grows.
+func buildCIDRCondition(cidrs []*CIDR, onSource bool) (Condition, error) {
+	ipv4Net := v2net.NewIPNetWithCapacity(len(cidrs))
+	ipv6Cond := NewAnyCondition()
+
+	for _, ip := range cidrs {
+		switch len(ip.Ip) {
+		case net.IPv4len:
+			ipv4Net.AddIP(ip.Ip, byte(ip.Prefix))
+		case net.IPv6len:
+			matcher, err := NewCIDRMatcher(ip.Ip, ip.Prefix, onSource)
+			if err != nil {
+				return nil, err
+			}
+			ipv6Cond.Add(matcher)
+		default:
+			return nil, errors.New("Router: Invalid IP length.")
+		}
+	}
+
+	if ipv4Net.IsEmpty() {
+		return ipv6Cond, nil
+	}
+	if ipv6Cond.Len() == 0 {
+		return NewIPv4Matcher(ipv4Net, onSource), nil
+	}
+	cond := NewAnyCondition()
+	cond.Add(NewIPv4Matcher(ipv4Net, onSource))
+	cond.Add(ipv6Cond)
+	return cond, nil
+}
@@ -12,6 +12,7 @@ It has these top-level messages:
 	Domain
 	CIDR
 	RoutingRule
+	ConnectionPolicy
 	Config
 */
 package router
@@ -82,7 +83,7 @@ var Config_DomainStrategy_value = map[string]int32{
 func (x Config_DomainStrategy) String() string {
 	return proto.EnumName(Config_DomainStrategy_name, int32(x))
 }
-func (Config_DomainStrategy) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{3, 0} }
+func (Config_DomainStrategy) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{4, 0} }
 
 // Domain for routing decision.
 type Domain struct {
@@ -119,6 +120,17 @@ type RoutingRule struct {
 	SourceCidr  []*CIDR                             `protobuf:"bytes,6,rep,name=source_cidr,json=sourceCidr" json:"source_cidr,omitempty"`
 	UserEmail   []string                            `protobuf:"bytes,7,rep,name=user_email,json=userEmail" json:"user_email,omitempty"`
 	InboundTag  []string                            `protobuf:"bytes,8,rep,name=inbound_tag,json=inboundTag" json:"inbound_tag,omitempty"`
+	// Destination ports. A rule matches if the port is in any of the ranges. It is used together with
+	// port_range if both are set.
+	PortList *v2ray_core_common_net.PortList `protobuf:"bytes,9,opt,name=port_list,json=portList" json:"port_list,omitempty"`
+	// Application protocols sniffed from the first payload of sessions, e.g., "tls". A rule with protocols
+	// delays routing of sessions until their first payload arrives.
+	Protocol []string `protobuf:"bytes,10,rep,name=protocol" json:"protocol,omitempty"`
+	// Name of the policy in Config.policy for sessions that match this rule. Outbounds use their own
+	// settings if empty.
+	Policy string `protobuf:"bytes,11,opt,name=policy" json:"policy,omitempty"`
+	// Tags of connections. A rule matches if the connection has all the tags with the same values.
+	ConnectionTag map[string]string `protobuf:"bytes,12,rep,name=connection_tag,json=connectionTag" json:"connection_tag,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *RoutingRule) Reset()                    { *m = RoutingRule{} }
@@ -161,15 +173,47 @@ func (m *RoutingRule) GetSourceCidr() []*CIDR {
 	return nil
 }
 
+func (m *RoutingRule) GetPortList() *v2ray_core_common_net.PortList {
+	if m != nil {
+		return m.PortList
+	}
+	return nil
+}
+
+func (m *RoutingRule) GetConnectionTag() map[string]string {
+	if m != nil {
+		return m.ConnectionTag
+	}
+	return nil
+}
+
+// Connection settings for sessions of a routing rule. All timeouts are in seconds, and 0 means the
+// default of the outbound.
+type ConnectionPolicy struct {
+	// Timeout of connecting to servers.
+	ConnectTimeout uint32 `protobuf:"varint,1,opt,name=connect_timeout,json=connectTimeout" json:"connect_timeout,omitempty"`
+	// Timeout of waiting for servers to respond, after requests are sent.
+	HandshakeTimeout uint32 `protobuf:"varint,2,opt,name=handshake_timeout,json=handshakeTimeout" json:"handshake_timeout,omitempty"`
+	// Maximum time between two reads from servers, after they respond.
+	IdleTimeout uint32 `protobuf:"varint,3,opt,name=idle_timeout,json=idleTimeout" json:"idle_timeout,omitempty"`
+}
+
+func (m *ConnectionPolicy) Reset()                    { *m = ConnectionPolicy{} }
+func (m *ConnectionPolicy) String() string            { return proto.CompactTextString(m) }
+func (*ConnectionPolicy) ProtoMessage()               {}
+func (*ConnectionPolicy) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
 type Config struct {
 	DomainStrategy Config_DomainStrategy `protobuf:"varint,1,opt,name=domain_strategy,json=domainStrategy,enum=v2ray.core.app.router.Config_DomainStrategy" json:"domain_strategy,omitempty"`
 	Rule           []*RoutingRule        `protobuf:"bytes,2,rep,name=rule" json:"rule,omitempty"`
+	// Connection policies by name, which are referred by rules.
+	Policy map[string]*ConnectionPolicy `protobuf:"bytes,3,rep,name=policy" json:"policy,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
 func (m *Config) String() string            { return proto.CompactTextString(m) }
 func (*Config) ProtoMessage()               {}
-func (*Config) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*Config) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 func (m *Config) GetRule() []*RoutingRule {
 	if m != nil {
@@ -178,10 +222,18 @@ func (m *Config) GetRule() []*RoutingRule {
 	return nil
 }
 
+func (m *Config) GetPolicy() map[string]*ConnectionPolicy {
+	if m != nil {
+		return m.Policy
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Domain)(nil), "v2ray.core.app.router.Domain")
 	proto.RegisterType((*CIDR)(nil), "v2ray.core.app.router.CIDR")
 	proto.RegisterType((*RoutingRule)(nil), "v2ray.core.app.router.RoutingRule")
+	proto.RegisterType((*ConnectionPolicy)(nil), "v2ray.core.app.router.ConnectionPolicy")
 	proto.RegisterType((*Config)(nil), "v2ray.core.app.router.Config")
 	proto.RegisterEnum("v2ray.core.app.router.Domain_Type", Domain_Type_name, Domain_Type_value)
 	proto.RegisterEnum("v2ray.core.app.router.Config_DomainStrategy", Config_DomainStrategy_name, Config_DomainStrategy_value)
@@ -190,38 +242,50 @@ func init() {
 func init() { proto.RegisterFile("v2ray.com/core/app/router/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 520 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x93, 0xd1, 0x6e, 0xd3, 0x3e,
-	0x14, 0xc6, 0xff, 0x69, 0xb3, 0xfc, 0x97, 0x93, 0x51, 0x22, 0x0b, 0x50, 0x18, 0x4c, 0x44, 0x11,
-	0x82, 0x5e, 0xa0, 0x04, 0x15, 0x01, 0x37, 0x48, 0x88, 0x75, 0xbb, 0xa8, 0x04, 0x53, 0x65, 0xb6,
-	0x1b, 0x6e, 0x22, 0x2f, 0x75, 0x83, 0x45, 0x62, 0x5b, 0x8e, 0x33, 0xd6, 0x87, 0xe0, 0xdd, 0x78,
-	0x24, 0x64, 0x3b, 0x13, 0x1b, 0x5a, 0xe1, 0xce, 0xe7, 0xf4, 0xf7, 0x1d, 0x7f, 0x3d, 0xfe, 0x02,
-	0xcf, 0x2e, 0x66, 0x8a, 0x6c, 0xf2, 0x4a, 0xb4, 0x45, 0x25, 0x14, 0x2d, 0x88, 0x94, 0x85, 0x12,
-	0xbd, 0xa6, 0xaa, 0xa8, 0x04, 0x5f, 0xb3, 0x3a, 0x97, 0x4a, 0x68, 0x81, 0xee, 0x5f, 0x71, 0x8a,
-	0xe6, 0x44, 0xca, 0xdc, 0x31, 0xfb, 0x4f, 0xff, 0x90, 0x57, 0xa2, 0x6d, 0x05, 0x2f, 0x38, 0xd5,
-	0x85, 0x14, 0x4a, 0x3b, 0xf1, 0xfe, 0xf3, 0xed, 0x14, 0xa7, 0xfa, 0xbb, 0x50, 0xdf, 0x1c, 0x98,
-	0x69, 0x08, 0x8e, 0x44, 0x4b, 0x18, 0x47, 0x6f, 0xc0, 0xd7, 0x1b, 0x49, 0x13, 0x2f, 0xf5, 0xa6,
-	0x93, 0x59, 0x96, 0xdf, 0x7a, 0x7d, 0xee, 0xe0, 0xfc, 0x74, 0x23, 0x29, 0xb6, 0x3c, 0xba, 0x07,
-	0x3b, 0x17, 0xa4, 0xe9, 0x69, 0x32, 0x4a, 0xbd, 0x69, 0x88, 0x5d, 0x91, 0x3d, 0x06, 0xdf, 0x30,
-	0x28, 0x84, 0x9d, 0x65, 0x43, 0x18, 0x8f, 0xff, 0x33, 0x47, 0x4c, 0x6b, 0x7a, 0x19, 0x7b, 0x59,
-	0x0e, 0xfe, 0x7c, 0x71, 0x84, 0xd1, 0x04, 0x46, 0x4c, 0xda, 0x1b, 0xf7, 0xf0, 0x88, 0x49, 0xf4,
-	0x00, 0x02, 0xa9, 0xe8, 0x9a, 0x5d, 0xda, 0x61, 0x77, 0xf0, 0x50, 0x65, 0x3f, 0xc6, 0x10, 0x61,
-	0xd1, 0x6b, 0xc6, 0x6b, 0xdc, 0x37, 0x14, 0xc5, 0x30, 0xd6, 0xa4, 0xb6, 0xc2, 0x10, 0x9b, 0x23,
-	0x7a, 0x0d, 0xc1, 0xca, 0x5a, 0x4b, 0x46, 0xe9, 0x78, 0x1a, 0xcd, 0x0e, 0xfe, 0xea, 0x1f, 0x0f,
-	0x30, 0x2a, 0xc0, 0xaf, 0xd8, 0x4a, 0x25, 0x63, 0x2b, 0x7a, 0xb4, 0x45, 0x64, 0xbc, 0x62, 0x0b,
-	0xa2, 0xf7, 0x00, 0x66, 0xcd, 0xa5, 0x22, 0xbc, 0xa6, 0x89, 0x9f, 0x7a, 0xd3, 0x68, 0x96, 0x5e,
-	0x97, 0xb9, 0x4d, 0xe7, 0x9c, 0xea, 0x7c, 0x29, 0x94, 0xc6, 0x86, 0xc3, 0xa1, 0xbc, 0x3a, 0xa2,
-	0x63, 0xd8, 0x1b, 0x5e, 0xa0, 0x6c, 0x58, 0xa7, 0x93, 0x1d, 0x3b, 0x22, 0xdb, 0x32, 0xe2, 0xc4,
-	0xa1, 0x1f, 0x59, 0xa7, 0x71, 0xc4, 0x7f, 0x17, 0xe8, 0x1d, 0x44, 0x9d, 0xe8, 0x55, 0x45, 0x4b,
-	0xeb, 0x3f, 0xf8, 0xb7, 0x7f, 0x70, 0xfc, 0xdc, 0xfc, 0x8b, 0x03, 0x80, 0xbe, 0xa3, 0xaa, 0xa4,
-	0x2d, 0x61, 0x4d, 0xf2, 0x7f, 0x3a, 0x9e, 0x86, 0x38, 0x34, 0x9d, 0x63, 0xd3, 0x40, 0x4f, 0x20,
-	0x62, 0xfc, 0x5c, 0xf4, 0x7c, 0x55, 0x9a, 0x35, 0xef, 0xda, 0xdf, 0x61, 0x68, 0x9d, 0x92, 0x3a,
-	0xfb, 0xe9, 0x41, 0x30, 0xb7, 0x61, 0x45, 0x67, 0x70, 0xd7, 0xed, 0xb2, 0xec, 0xb4, 0x22, 0x9a,
-	0xd6, 0x9b, 0x21, 0x41, 0x2f, 0xb6, 0x99, 0x71, 0x21, 0x77, 0x0f, 0xf1, 0x79, 0xd0, 0xe0, 0xc9,
-	0xea, 0x46, 0x6d, 0xd2, 0xa8, 0xfa, 0x86, 0x0e, 0xaf, 0xb9, 0x2d, 0x8d, 0xd7, 0x32, 0x81, 0x2d,
-	0x9f, 0xbd, 0x85, 0xc9, 0xcd, 0xc9, 0x68, 0x17, 0xfc, 0x0f, 0xdd, 0xa2, 0x73, 0x01, 0x3c, 0xeb,
-	0xe8, 0x42, 0xc6, 0x1e, 0x8a, 0x61, 0x6f, 0x21, 0x17, 0xeb, 0x13, 0xc1, 0x3f, 0x11, 0x5d, 0x7d,
-	0x8d, 0x47, 0x87, 0x2f, 0xe1, 0x61, 0x25, 0xda, 0xdb, 0xef, 0x39, 0x8c, 0x9c, 0xe9, 0xa5, 0xf9,
-	0x64, 0xbe, 0x04, 0xae, 0x79, 0x1e, 0xd8, 0x2f, 0xe8, 0xd5, 0xaf, 0x00, 0x00, 0x00, 0xff, 0xff,
-	0xe4, 0x89, 0x6f, 0xa7, 0xd1, 0x03, 0x00, 0x00,
+	// 716 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x54, 0xdd, 0x6e, 0xd3, 0x48,
+	0x14, 0x5e, 0xe7, 0x6f, 0x93, 0xe3, 0x34, 0xf5, 0x8e, 0x76, 0x57, 0xde, 0x2c, 0x55, 0x83, 0x85,
+	0x68, 0x10, 0xc8, 0x41, 0x41, 0x05, 0x84, 0x8a, 0xa0, 0x4d, 0x7b, 0x11, 0x09, 0xaa, 0x68, 0x48,
+	0x6f, 0x10, 0x52, 0xe4, 0x3a, 0x53, 0x77, 0x14, 0x67, 0x66, 0x34, 0x1e, 0x97, 0xfa, 0x19, 0x78,
+	0x08, 0x1e, 0x93, 0x5b, 0xe4, 0x19, 0x27, 0x4d, 0x21, 0x69, 0xef, 0xe6, 0x1c, 0x7f, 0xdf, 0xf9,
+	0xfd, 0x8e, 0xe1, 0xf1, 0x55, 0x5f, 0x06, 0x99, 0x1f, 0xf2, 0x79, 0x2f, 0xe4, 0x92, 0xf4, 0x02,
+	0x21, 0x7a, 0x92, 0xa7, 0x8a, 0xc8, 0x5e, 0xc8, 0xd9, 0x05, 0x8d, 0x7c, 0x21, 0xb9, 0xe2, 0xe8,
+	0x9f, 0x05, 0x4e, 0x12, 0x3f, 0x10, 0xc2, 0x37, 0x98, 0xf6, 0xa3, 0x5f, 0xe8, 0x21, 0x9f, 0xcf,
+	0x39, 0xeb, 0x31, 0xa2, 0x7a, 0x82, 0x4b, 0x65, 0xc8, 0xed, 0xbd, 0xcd, 0x28, 0x46, 0xd4, 0x57,
+	0x2e, 0x67, 0x06, 0xe8, 0x29, 0xa8, 0x1d, 0xf3, 0x79, 0x40, 0x19, 0x7a, 0x09, 0x15, 0x95, 0x09,
+	0xe2, 0x5a, 0x1d, 0xab, 0xdb, 0xea, 0x7b, 0xfe, 0xda, 0xf4, 0xbe, 0x01, 0xfb, 0xe3, 0x4c, 0x10,
+	0xac, 0xf1, 0xe8, 0x6f, 0xa8, 0x5e, 0x05, 0x71, 0x4a, 0xdc, 0x52, 0xc7, 0xea, 0x36, 0xb0, 0x31,
+	0xbc, 0x07, 0x50, 0xc9, 0x31, 0xa8, 0x01, 0xd5, 0x51, 0x1c, 0x50, 0xe6, 0xfc, 0x91, 0x3f, 0x31,
+	0x89, 0xc8, 0xb5, 0x63, 0x79, 0x3e, 0x54, 0x06, 0xc3, 0x63, 0x8c, 0x5a, 0x50, 0xa2, 0x42, 0x67,
+	0x6c, 0xe2, 0x12, 0x15, 0xe8, 0x5f, 0xa8, 0x09, 0x49, 0x2e, 0xe8, 0xb5, 0x0e, 0xb6, 0x85, 0x0b,
+	0xcb, 0xfb, 0x5e, 0x05, 0x1b, 0xf3, 0x54, 0x51, 0x16, 0xe1, 0x34, 0x26, 0xc8, 0x81, 0xb2, 0x0a,
+	0x22, 0x4d, 0x6c, 0xe0, 0xfc, 0x89, 0xf6, 0xa1, 0x36, 0xd5, 0xa5, 0xb9, 0xa5, 0x4e, 0xb9, 0x6b,
+	0xf7, 0x77, 0xee, 0xac, 0x1f, 0x17, 0x60, 0xd4, 0x83, 0x4a, 0x48, 0xa7, 0xd2, 0x2d, 0x6b, 0xd2,
+	0xff, 0x1b, 0x48, 0x79, 0xad, 0x58, 0x03, 0xd1, 0x3b, 0x80, 0x7c, 0xcc, 0x13, 0x19, 0xb0, 0x88,
+	0xb8, 0x95, 0x8e, 0xd5, 0xb5, 0xfb, 0x9d, 0x55, 0x9a, 0x99, 0xb4, 0xcf, 0x88, 0xf2, 0x47, 0x5c,
+	0x2a, 0x9c, 0xe3, 0x70, 0x43, 0x2c, 0x9e, 0xe8, 0x04, 0x9a, 0xc5, 0x06, 0x26, 0x31, 0x4d, 0x94,
+	0x5b, 0xd5, 0x21, 0xbc, 0x0d, 0x21, 0x4e, 0x0d, 0xf4, 0x03, 0x4d, 0x14, 0xb6, 0xd9, 0x8d, 0x81,
+	0x0e, 0xc0, 0x4e, 0x78, 0x2a, 0x43, 0x32, 0xd1, 0xf5, 0xd7, 0xee, 0xaf, 0x1f, 0x0c, 0x7e, 0x90,
+	0x77, 0xb1, 0x03, 0x90, 0x26, 0x44, 0x4e, 0xc8, 0x3c, 0xa0, 0xb1, 0xfb, 0x67, 0xa7, 0xdc, 0x6d,
+	0xe0, 0x46, 0xee, 0x39, 0xc9, 0x1d, 0x68, 0x17, 0x6c, 0xca, 0xce, 0x79, 0xca, 0xa6, 0x93, 0x7c,
+	0xcc, 0x75, 0xfd, 0x1d, 0x0a, 0xd7, 0x38, 0x88, 0xd0, 0x01, 0xe8, 0x8e, 0x4c, 0x07, 0x0d, 0xdd,
+	0xc1, 0xee, 0x1d, 0x43, 0xd0, 0xe5, 0xd7, 0x45, 0xf1, 0x42, 0x6d, 0xa8, 0x6b, 0xf1, 0x85, 0x3c,
+	0x76, 0x41, 0xc7, 0x5e, 0xda, 0x5a, 0x01, 0x3c, 0xa6, 0x61, 0xe6, 0xda, 0x7a, 0xb9, 0x85, 0x85,
+	0xbe, 0x40, 0x2b, 0xe4, 0x8c, 0x91, 0x50, 0x51, 0xce, 0x74, 0x55, 0x4d, 0xdd, 0xf2, 0xfe, 0x86,
+	0x96, 0x57, 0xd4, 0xe2, 0x0f, 0x96, 0xc4, 0x71, 0x10, 0x9d, 0x30, 0x25, 0x33, 0xbc, 0x15, 0xae,
+	0xfa, 0xda, 0xef, 0x01, 0xfd, 0x0e, 0xca, 0x55, 0x36, 0x23, 0xd9, 0x42, 0x65, 0x33, 0x92, 0xad,
+	0xd7, 0xfa, 0x9b, 0xd2, 0x6b, 0xcb, 0xfb, 0x66, 0x81, 0x73, 0x13, 0x62, 0x64, 0x8a, 0xde, 0x83,
+	0xed, 0x22, 0xcf, 0x44, 0xd1, 0x39, 0xe1, 0xa9, 0xd2, 0xc1, 0xb6, 0xf0, 0xa2, 0x97, 0xb1, 0xf1,
+	0xa2, 0xa7, 0xf0, 0xd7, 0x65, 0xc0, 0xa6, 0xc9, 0x65, 0x30, 0x23, 0x4b, 0xa8, 0x39, 0x01, 0x67,
+	0xf9, 0x61, 0x01, 0x7e, 0x08, 0x4d, 0x3a, 0x8d, 0x6f, 0x70, 0x65, 0x8d, 0xb3, 0x73, 0x5f, 0x01,
+	0xf1, 0x7e, 0x94, 0xa0, 0x36, 0xd0, 0x3f, 0x13, 0x74, 0x06, 0xdb, 0x46, 0xeb, 0x93, 0x44, 0xc9,
+	0x40, 0x91, 0x28, 0x2b, 0x2e, 0xfc, 0xd9, 0x26, 0xb1, 0x68, 0x5e, 0x71, 0x28, 0x9f, 0x0a, 0x0e,
+	0x6e, 0x4d, 0x6f, 0xd9, 0xf9, 0xdf, 0x42, 0xa6, 0x31, 0x29, 0xae, 0xcd, 0xbb, 0x7f, 0x0b, 0x58,
+	0xe3, 0xd1, 0xe1, 0x72, 0xbf, 0xe6, 0xe4, 0x9e, 0xdc, 0x5d, 0x85, 0x19, 0xa4, 0xd9, 0x59, 0x41,
+	0x6c, 0x9f, 0x83, 0xbd, 0xe2, 0x5e, 0xb3, 0xa5, 0xb7, 0xab, 0x5b, 0xb2, 0xfb, 0x7b, 0x9b, 0x53,
+	0xdc, 0x5a, 0xd7, 0xea, 0x3a, 0x5f, 0x41, 0xeb, 0xf6, 0x00, 0x50, 0x1d, 0x2a, 0x87, 0xc9, 0x30,
+	0x31, 0xff, 0xb1, 0xb3, 0x84, 0x0c, 0x85, 0x63, 0x21, 0x07, 0x9a, 0x43, 0x31, 0xbc, 0x38, 0xe5,
+	0xec, 0x63, 0xa0, 0xc2, 0x4b, 0xa7, 0x74, 0xf4, 0x1c, 0xfe, 0x0b, 0xf9, 0x7c, 0x7d, 0xc6, 0x23,
+	0xdb, 0x74, 0x35, 0xca, 0xc5, 0xfe, 0xb9, 0x66, 0x9c, 0xe7, 0x35, 0xad, 0xfd, 0x17, 0x3f, 0x03,
+	0x00, 0x00, 0xff, 0xff, 0x59, 0x22, 0xea, 0x69, 0x18, 0x06, 0x00, 0x00,
 }
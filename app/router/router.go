@@ -2,8 +2,10 @@ package router
 
 import (
 	"errors"
+	"sync/atomic"
 
 	"v2ray.com/core/app"
+	"v2ray.com/core/app/api"
 	"v2ray.com/core/app/dns"
 	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
@@ -18,49 +20,151 @@ const (
 var (
 	ErrInvalidRule      = errors.New("Invalid Rule")
 	ErrNoRuleApplicable = errors.New("No rule applicable")
+	ErrUnknownPolicy    = errors.New("Router: Unknown policy.")
 )
 
-type Router struct {
+// RuleSet is a compiled set of routing rules, which can be swapped into a Router at runtime.
+type RuleSet struct {
 	domainStrategy Config_DomainStrategy
 	rules          []Rule
+	// Whether any rule matches sniffed protocols.
+	sniffing bool
+}
+
+// CompileRules builds conditions of all rules in config.
+func CompileRules(config *Config) (*RuleSet, error) {
+	ruleSet := &RuleSet{
+		domainStrategy: config.DomainStrategy,
+		rules:          make([]Rule, len(config.Rule)),
+	}
+	for idx, rule := range config.Rule {
+		if rule == nil {
+			return nil, ErrInvalidRule
+		}
+		ruleSet.rules[idx].Tag = rule.Tag
+		ruleSet.rules[idx].Reason = rule.RouteReason()
+		if len(rule.Policy) > 0 {
+			policy, found := config.Policy[rule.Policy]
+			if !found || policy == nil {
+				log.Error("Router: Policy not found: ", rule.Policy)
+				return nil, ErrUnknownPolicy
+			}
+			ruleSet.rules[idx].Policy = policy.Build()
+		}
+		if len(rule.Protocol) > 0 {
+			ruleSet.sniffing = true
+		}
+		cond, err := rule.BuildCondition()
+		if err != nil {
+			return nil, err
+		}
+		ruleSet.rules[idx].Condition = cond
+	}
+	return ruleSet, nil
+}
+
+func (this *RuleSet) Len() int {
+	return len(this.rules)
+}
+
+type Router struct {
+	// Current *RuleSet.
+	ruleSet atomic.Value
+	// Current *RuleSet of overrides.
+	overrides atomic.Value
 	//	cache          *RoutingTable
 	dnsServer dns.Server
 }
 
 func NewRouter(config *Config, space app.Space) *Router {
 	r := &Router{
-		domainStrategy: config.DomainStrategy,
 		//cache:          NewRoutingTable(),
-		rules: make([]Rule, len(config.Rule)),
 	}
+	r.ruleSet.Store(&RuleSet{
+		domainStrategy: config.DomainStrategy,
+	})
+	r.overrides.Store(&RuleSet{})
 
 	space.InitializeApplication(func() error {
-		for idx, rule := range config.Rule {
-			r.rules[idx].Tag = rule.Tag
-			cond, err := rule.BuildCondition()
-			if err != nil {
-				return err
-			}
-			r.rules[idx].Condition = cond
+		ruleSet, err := CompileRules(config)
+		if err != nil {
+			return err
 		}
+		r.SetRuleSet(ruleSet)
 
 		if !space.HasApp(dns.APP_ID) {
 			log.Error("Router: DNS is not found in the space.")
 			return app.ErrMissingApplication
 		}
 		r.dnsServer = space.GetApp(dns.APP_ID).(dns.Server)
+
+		if space.HasApp(api.APP_ID) {
+			apiServer := space.GetApp(api.APP_ID).(*api.ApiServer)
+			apiServer.Handle("/router/rules", &RulesHandler{router: r})
+			apiServer.Handle("/router/overrides", &RulesHandler{router: r, overrides: true})
+		}
 		return nil
 	})
 	return r
 }
 
+// NeedsProtocol returns true if current rules match protocols of sessions, which are sniffed from their
+// first payloads.
+func (this *Router) NeedsProtocol() bool {
+	return this.GetRuleSet().sniffing || this.GetOverrides().sniffing
+}
+
+// SetRuleSet replaces rules of the router. Sessions that are already routed are not affected.
+func (this *Router) SetRuleSet(ruleSet *RuleSet) {
+	this.ruleSet.Store(ruleSet)
+}
+
+func (this *Router) GetRuleSet() *RuleSet {
+	return this.ruleSet.Load().(*RuleSet)
+}
+
+// UpdateRules compiles rules in config and replaces current rules with them. Current rules are kept
+// if any rule is invalid.
+func (this *Router) UpdateRules(config *Config) error {
+	ruleSet, err := CompileRules(config)
+	if err != nil {
+		return err
+	}
+	this.SetRuleSet(ruleSet)
+	log.Info("Router: ", ruleSet.Len(), " rules loaded.")
+	return nil
+}
+
+// SetOverrides replaces override rules with rules in config. Overrides take precedence over routing
+// rules, and are kept when routing rules are replaced. Domain strategy of config is not used, so overrides
+// only match resolved IPs of sessions that have them.
+func (this *Router) SetOverrides(config *Config) error {
+	overrides, err := CompileRules(config)
+	if err != nil {
+		return err
+	}
+	this.overrides.Store(overrides)
+	log.Info("Router: ", overrides.Len(), " overrides loaded.")
+	return nil
+}
+
+// ClearOverrides removes all override rules.
+func (this *Router) ClearOverrides() {
+	this.overrides.Store(&RuleSet{})
+	log.Info("Router: Overrides cleared.")
+}
+
+func (this *Router) GetOverrides() *RuleSet {
+	return this.overrides.Load().(*RuleSet)
+}
+
 func (this *Router) Release() {
 
 }
 
 // Private: Visible for testing.
 func (this *Router) ResolveIP(dest v2net.Destination) []v2net.Destination {
-	ips := this.dnsServer.Get(dest.Address.Domain())
+	ips := this.dnsServer.GetFor(dest.Address.Domain(), "router")
 	if len(ips) == 0 {
 		return nil
 	}
@@ -75,44 +179,68 @@ func (this *Router) ResolveIP(dest v2net.Destination) []v2net.Destination {
 	return dests
 }
 
-func (this *Router) takeDetourWithoutCache(session *proxy.SessionInfo) (string, error) {
-	for _, rule := range this.rules {
+// PickRoute returns the route of the first override or rule that matches session. It returns
+// ErrNoRuleApplicable if nothing matches.
+func (this *Router) PickRoute(session *proxy.SessionInfo) (*proxy.Route, error) {
+	for idx, rule := range this.GetOverrides().rules {
 		if rule.Apply(session) {
-			return rule.Tag, nil
+			return &proxy.Route{
+				Rule:        idx,
+				OutboundTag: rule.Tag,
+				Reason:      proxy.RouteOverride,
+				Policy:      rule.Policy,
+			}, nil
+		}
+	}
+
+	// Uses the same rules throughout the session, even if rules are replaced meanwhile.
+	ruleSet := this.GetRuleSet()
+	for idx, rule := range ruleSet.rules {
+		if rule.Apply(session) {
+			return &proxy.Route{
+				Rule:        idx,
+				OutboundTag: rule.Tag,
+				Reason:      rule.Reason,
+				Policy:      rule.Policy,
+			}, nil
 		}
 	}
 	dest := session.Destination
-	if this.domainStrategy == Config_IpIfNonMatch && dest.Address.Family().IsDomain() {
+	if ruleSet.domainStrategy == Config_IpIfNonMatch && dest.Address.Family().IsDomain() {
 		log.Info("Router: Looking up IP for ", dest)
 		ipDests := this.ResolveIP(dest)
 		if ipDests != nil {
 			for _, ipDest := range ipDests {
 				log.Info("Router: Trying IP ", ipDest)
-				for _, rule := range this.rules {
+				for idx, rule := range ruleSet.rules {
 					if rule.Apply(&proxy.SessionInfo{
 						Source:      session.Source,
 						Destination: ipDest,
 						User:        session.User,
+						Inbound:     session.Inbound,
+						Protocol:    session.Protocol,
 					}) {
-						return rule.Tag, nil
+						return &proxy.Route{
+							Rule:        idx,
+							OutboundTag: rule.Tag,
+							Reason:      proxy.RouteResolvedIP,
+							Policy:      rule.Policy,
+						}, nil
 					}
 				}
 			}
 		}
 	}
 
-	return "", ErrNoRuleApplicable
+	return nil, ErrNoRuleApplicable
 }
 
 func (this *Router) TakeDetour(session *proxy.SessionInfo) (string, error) {
-	//destStr := dest.String()
-	//found, tag, err := this.cache.Get(destStr)
-	//if !found {
-	tag, err := this.takeDetourWithoutCache(session)
-	//this.cache.Set(destStr, tag, err)
-	return tag, err
-	//}
-	//return tag, err
+	route, err := this.PickRoute(session)
+	if err != nil {
+		return "", err
+	}
+	return route.OutboundTag, nil
 }
 
 type RouterFactory struct{}
@@ -40,3 +40,267 @@ func TestSimpleRouter(t *testing.T) {
 	assert.Error(err).IsNil()
 	assert.String(tag).Equals("test")
 }
+
+func TestRuleSetReplacement(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{}))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	r := NewRouter(&Config{
+		Rule: []*RoutingRule{
+			{
+				Tag: "old",
+				NetworkList: &v2net.NetworkList{
+					Network: []v2net.Network{v2net.Network_TCP},
+				},
+			},
+		},
+	}, space)
+	space.BindApp(APP_ID, r)
+	assert.Error(space.Initialize()).IsNil()
+
+	session := &proxy.SessionInfo{Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80)}
+	tag, err := r.TakeDetour(session)
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("old")
+
+	assert.Error(r.UpdateRules(&Config{
+		Rule: []*RoutingRule{
+			{
+				Tag: "new",
+				Domain: []*Domain{
+					{Type: Domain_Plain, Value: "v2ray"},
+				},
+			},
+		},
+	})).IsNil()
+	tag, err = r.TakeDetour(session)
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("new")
+
+	// Invalid rules don't replace current ones.
+	assert.Error(r.UpdateRules(&Config{
+		Rule: []*RoutingRule{
+			{
+				Tag: "invalid",
+				Domain: []*Domain{
+					{Type: Domain_Regex, Value: "(v2ray"},
+				},
+			},
+		},
+	})).IsNotNil()
+	tag, err = r.TakeDetour(session)
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("new")
+	assert.Int(r.GetRuleSet().Len()).Equals(1)
+}
+
+func TestInboundTagWithResolvedIP(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{
+		HostMapping: []*dns.HostMapping{
+			{
+				Domain: "local.v2ray.com",
+				Ip:     []*v2net.IPOrDomain{v2net.NewIPOrDomain(v2net.LocalHostIP)},
+			},
+		},
+		ResolveOrder: []dns.ResolveStage{dns.ResolveStage_Static},
+	}))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	r := NewRouter(&Config{
+		DomainStrategy: Config_IpIfNonMatch,
+		Rule: []*RoutingRule{
+			{
+				Tag:        "local",
+				InboundTag: []string{"socks"},
+				Cidr: []*CIDR{
+					{Ip: []byte{127, 0, 0, 0}, Prefix: 8},
+				},
+			},
+		},
+	}, space)
+	space.BindApp(APP_ID, r)
+	assert.Error(space.Initialize()).IsNil()
+
+	dest := v2net.TCPDestination(v2net.DomainAddress("local.v2ray.com"), 80)
+	tag, err := r.TakeDetour(&proxy.SessionInfo{
+		Destination: dest,
+		Inbound:     &proxy.InboundHandlerMeta{Tag: "socks"},
+	})
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("local")
+
+	_, err = r.TakeDetour(&proxy.SessionInfo{
+		Destination: dest,
+		Inbound:     &proxy.InboundHandlerMeta{Tag: "transparent"},
+	})
+	assert.Error(err).Equals(ErrNoRuleApplicable)
+}
+
+func TestPickRoute(t *testing.T) {
+	assert := assert.On(t)
+
+	space := app.NewSpace()
+	space.BindApp(dns.APP_ID, dns.NewCacheServer(space, &dns.Config{
+		HostMapping: []*dns.HostMapping{
+			{
+				Domain: "local.v2ray.com",
+				Ip:     []*v2net.IPOrDomain{v2net.NewIPOrDomain(v2net.LocalHostIP)},
+			},
+		},
+		ResolveOrder: []dns.ResolveStage{dns.ResolveStage_Static},
+	}))
+	space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(space))
+	space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, proxyman.NewDefaultOutboundHandlerManager())
+	r := NewRouter(&Config{
+		DomainStrategy: Config_IpIfNonMatch,
+		Rule: []*RoutingRule{
+			{
+				Tag: "domain",
+				Domain: []*Domain{
+					{Type: Domain_Plain, Value: "www.v2ray.com"},
+				},
+			},
+			{
+				Tag: "ip",
+				Cidr: []*CIDR{
+					{Ip: []byte{127, 0, 0, 0}, Prefix: 8},
+				},
+			},
+			{
+				Tag:       "port",
+				PortRange: &v2net.PortRange{From: 443, To: 443},
+			},
+		},
+	}, space)
+	space.BindApp(APP_ID, r)
+	assert.Error(space.Initialize()).IsNil()
+
+	route, err := r.PickRoute(&proxy.SessionInfo{Destination: v2net.TCPDestination(v2net.DomainAddress("www.v2ray.com"), 80)})
+	assert.Error(err).IsNil()
+	assert.Int(route.Rule).Equals(0)
+	assert.String(route.OutboundTag).Equals("domain")
+	assert.String(string(route.Reason)).Equals(string(proxy.RouteDomain))
+
+	route, err = r.PickRoute(&proxy.SessionInfo{Destination: v2net.TCPDestination(v2net.LocalHostIP, 80)})
+	assert.Error(err).IsNil()
+	assert.Int(route.Rule).Equals(1)
+	assert.String(string(route.Reason)).Equals(string(proxy.RouteIP))
+
+	route, err = r.PickRoute(&proxy.SessionInfo{Destination: v2net.TCPDestination(v2net.DomainAddress("local.v2ray.com"), 80)})
+	assert.Error(err).IsNil()
+	assert.Int(route.Rule).Equals(1)
+	assert.String(string(route.Reason)).Equals(string(proxy.RouteResolvedIP))
+	assert.String(route.String()).Equals("rule 1 (resolved ip) to [ip]")
+
+	route, err = r.PickRoute(&proxy.SessionInfo{Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 443)})
+	assert.Error(err).IsNil()
+	assert.Int(route.Rule).Equals(2)
+	assert.String(string(route.Reason)).Equals(string(proxy.RouteRule))
+
+	_, err = r.PickRoute(&proxy.SessionInfo{Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80)})
+	assert.Error(err).Equals(ErrNoRuleApplicable)
+}
+
+func TestRoutePolicy(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &Config{
+		Rule: []*RoutingRule{
+			{
+				Tag:       "overseas",
+				PortRange: &v2net.PortRange{From: 443, To: 443},
+				Policy:    "slow",
+			},
+			{
+				Tag:       "local",
+				PortRange: &v2net.PortRange{From: 80, To: 80},
+			},
+		},
+		Policy: map[string]*ConnectionPolicy{
+			"slow": {ConnectTimeout: 30, IdleTimeout: 600},
+		},
+	}
+	ruleSet, err := CompileRules(config)
+	assert.Error(err).IsNil()
+
+	r := NewRouter(&Config{}, app.NewSpace())
+	r.SetRuleSet(ruleSet)
+
+	route, err := r.PickRoute(&proxy.SessionInfo{Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 443)})
+	assert.Error(err).IsNil()
+	assert.Int(int(route.GetPolicy().ConnectTimeout.Seconds())).Equals(30)
+	assert.Int(int(route.GetPolicy().HandshakeTimeout)).Equals(0)
+	assert.Int(int(route.GetPolicy().IdleTimeout.Seconds())).Equals(600)
+
+	route, err = r.PickRoute(&proxy.SessionInfo{Destination: v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80)})
+	assert.Error(err).IsNil()
+	assert.Pointer(route.Policy).IsNil()
+	assert.Int(int(route.GetPolicy().ConnectTimeout)).Equals(0)
+
+	config.Rule[1].Policy = "missing"
+	_, err = CompileRules(config)
+	assert.Error(err).Equals(ErrUnknownPolicy)
+}
+
+func TestRouteOverrides(t *testing.T) {
+	assert := assert.On(t)
+
+	r := NewRouter(&Config{}, app.NewSpace())
+	ruleSet, err := CompileRules(&Config{
+		Rule: []*RoutingRule{
+			{
+				Tag:       "static",
+				PortRange: &v2net.PortRange{From: 80, To: 80},
+			},
+		},
+	})
+	assert.Error(err).IsNil()
+	r.SetRuleSet(ruleSet)
+
+	assert.Error(r.SetOverrides(&Config{
+		Rule: []*RoutingRule{
+			{
+				Tag:           "group-b",
+				ConnectionTag: map[string]string{"tenant": "x"},
+			},
+		},
+	})).IsNil()
+
+	destination := v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80)
+	route, err := r.PickRoute(&proxy.SessionInfo{
+		Destination: destination,
+		Tags:        map[string]string{"tenant": "x", "region": "eu"},
+	})
+	assert.Error(err).IsNil()
+	assert.String(route.OutboundTag).Equals("group-b")
+	assert.String(string(route.Reason)).Equals(string(proxy.RouteOverride))
+
+	route, err = r.PickRoute(&proxy.SessionInfo{
+		Destination: destination,
+		Tags:        map[string]string{"tenant": "y"},
+	})
+	assert.Error(err).IsNil()
+	assert.String(route.OutboundTag).Equals("static")
+
+	// Overrides are kept when rules are replaced, until they are cleared.
+	assert.Error(r.UpdateRules(&Config{})).IsNil()
+	tenantX := &proxy.SessionInfo{
+		Destination: destination,
+		Inbound: &proxy.InboundHandlerMeta{
+			ConnectionTags: map[string]string{"tenant": "x"},
+		},
+	}
+	tag, err := r.TakeDetour(tenantX)
+	assert.Error(err).IsNil()
+	assert.String(tag).Equals("group-b")
+
+	r.ClearOverrides()
+	_, err = r.TakeDetour(tenantX)
+	assert.Error(err).Equals(ErrNoRuleApplicable)
+}
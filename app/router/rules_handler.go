@@ -0,0 +1,77 @@
+package router
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"v2ray.com/core/app/api"
+)
+
+const (
+	maxRulesSize = 16 * 1024 * 1024
+)
+
+var (
+	// RulesLoader parses routing settings in the format of config file. It is set by the config loader.
+	RulesLoader func(data []byte) (*Config, error)
+
+	ErrRulesLoaderMissing = errors.New("Router: No loader for routing rules.")
+)
+
+type RulesStatus struct {
+	Rules int `json:"rules"`
+}
+
+// RulesHandler serves current rules on GET, and replaces them on PUT or POST with routing settings in
+// the request body. Handler of overrides also removes all overrides on DELETE.
+type RulesHandler struct {
+	router *Router
+	// Whether the handler serves overrides instead of routing rules.
+	overrides bool
+}
+
+func (this *RulesHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		if !this.overrides {
+			api.WriteError(writer, http.StatusMethodNotAllowed, api.ErrInvalidRequest)
+			return
+		}
+		this.router.ClearOverrides()
+	case http.MethodPost, http.MethodPut:
+		if RulesLoader == nil {
+			api.WriteError(writer, http.StatusNotImplemented, ErrRulesLoaderMissing)
+			return
+		}
+		data, err := ioutil.ReadAll(http.MaxBytesReader(writer, request.Body, maxRulesSize))
+		if err != nil {
+			api.WriteError(writer, http.StatusBadRequest, api.ErrInvalidRequest)
+			return
+		}
+		config, err := RulesLoader(data)
+		if err != nil {
+			api.WriteError(writer, http.StatusBadRequest, err)
+			return
+		}
+		update := this.router.UpdateRules
+		if this.overrides {
+			update = this.router.SetOverrides
+		}
+		if err := update(config); err != nil {
+			api.WriteError(writer, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		api.WriteError(writer, http.StatusMethodNotAllowed, api.ErrInvalidRequest)
+		return
+	}
+	ruleSet := this.router.GetRuleSet()
+	if this.overrides {
+		ruleSet = this.router.GetOverrides()
+	}
+	api.WriteJSON(writer, &RulesStatus{
+		Rules: ruleSet.Len(),
+	})
+}
@@ -33,6 +33,13 @@ func BenchmarkChaCha20IETF(b *testing.B) {
 	benchmarkStream(b, c)
 }
 
+func BenchmarkChaCha20Parallel(b *testing.B) {
+	key := make([]byte, 32)
+	nonce := make([]byte, 8)
+	c := NewParallelChaCha20Stream(key, nonce, 4)
+	benchmarkStream(b, c)
+}
+
 func BenchmarkAESEncryption(b *testing.B) {
 	key := make([]byte, 32)
 	iv := make([]byte, 16)
@@ -75,3 +75,24 @@ func TestChaCha20Decoding(t *testing.T) {
 	stream2.XORKeyStream(x, x)
 	assert.Bytes(x).Equals(payload)
 }
+
+func TestParallelChaCha20Stream(t *testing.T) {
+	assert := assert.On(t)
+
+	key := make([]byte, 32)
+	rand.Read(key)
+	iv := make([]byte, 8)
+	rand.Read(iv)
+
+	serial := NewChaCha20Stream(key, iv)
+	parallel := NewParallelChaCha20Stream(key, iv, 4)
+	// Sizes below and above the parallel threshold, not aligned to blocks.
+	for _, size := range []int{1, 100, 64 * 1024, 13, 100001, 32 * 1024} {
+		input := make([]byte, size)
+		rand.Read(input)
+		expected := make([]byte, size)
+		serial.XORKeyStream(expected, input)
+		parallel.XORKeyStream(input, input)
+		assert.Bytes(input).Equals(expected)
+	}
+}
@@ -78,3 +78,15 @@ func (s *ChaCha20Stream) XORKeyStream(dst, src []byte) {
 		}
 	}
 }
+
+// Fork returns a copy of the stream, positioned n bytes ahead in the keystream.
+func (s *ChaCha20Stream) Fork(n int) *ChaCha20Stream {
+	fork := *s
+	position := s.offset + n
+	fork.offset = position % blockSize
+	if blocks := position / blockSize; blocks > 0 {
+		fork.state[12] += uint32(blocks)
+		ChaCha20Block(&fork.state, fork.block[:], fork.rounds)
+	}
+	return &fork
+}
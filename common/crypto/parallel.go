@@ -0,0 +1,61 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"sync"
+
+	"v2ray.com/core/common/crypto/internal"
+)
+
+const (
+	// Minimum bytes for each goroutine. Smaller inputs are XORed serially, as goroutines cost more than
+	// they save.
+	minParallelSegment = 16 * 1024
+)
+
+// ParallelChaCha20Stream splits large inputs into segments, and XORs them on multiple goroutines. The
+// keystream of ChaCha20 only depends on the position in it, so segments are independent, and the output
+// is the same as NewChaCha20Stream.
+type ParallelChaCha20Stream struct {
+	stream  *internal.ChaCha20Stream
+	workers int
+}
+
+// NewParallelChaCha20Stream creates a ChaCha20 stream that XORs on up to workers goroutines. Caller must
+// ensure the length of key is 32 bytes, and length of IV is either 8 or 12 bytes.
+func NewParallelChaCha20Stream(key []byte, iv []byte, workers int) cipher.Stream {
+	return &ParallelChaCha20Stream{
+		stream:  internal.NewChaCha20Stream(key, iv, 20),
+		workers: workers,
+	}
+}
+
+func (this *ParallelChaCha20Stream) XORKeyStream(dst, src []byte) {
+	workers := len(src) / minParallelSegment
+	if workers > this.workers {
+		workers = this.workers
+	}
+	if workers <= 1 {
+		this.stream.XORKeyStream(dst, src)
+		return
+	}
+
+	// Segments are whole blocks, which is the fastest if the stream is at a block boundary.
+	segment := (len(src)/workers + 63) &^ 63
+	var wg sync.WaitGroup
+	for start := segment; start < len(src); start += segment {
+		end := start + segment
+		if end > len(src) {
+			end = len(src)
+		}
+		wg.Add(1)
+		go func(stream *internal.ChaCha20Stream, dst, src []byte) {
+			defer wg.Done()
+			stream.XORKeyStream(dst, src)
+		}(this.stream.Fork(start), dst[start:end], src[start:end])
+	}
+	next := this.stream.Fork(len(src))
+	this.stream.XORKeyStream(dst[:segment], src[:segment])
+	wg.Wait()
+	this.stream = next
+}
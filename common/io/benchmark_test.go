@@ -0,0 +1,41 @@
+package io_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	. "v2ray.com/core/common/io"
+)
+
+const benchSize = 16 * 1024 * 1024
+
+func benchmarkPipe(b *testing.B, bufferSize int) {
+	b.SetBytes(benchSize)
+	content := make([]byte, benchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader := NewSizedReader(bytes.NewReader(content), bufferSize)
+		Pipe(reader, NewAdaptiveWriter(ioutil.Discard))
+	}
+}
+
+func BenchmarkPipeAdaptive(b *testing.B) {
+	benchmarkPipe(b, 0)
+}
+
+func BenchmarkPipe1K(b *testing.B) {
+	benchmarkPipe(b, 1024)
+}
+
+func BenchmarkPipe8K(b *testing.B) {
+	benchmarkPipe(b, 8*1024)
+}
+
+func BenchmarkPipe32K(b *testing.B) {
+	benchmarkPipe(b, 32*1024)
+}
+
+func BenchmarkPipe64K(b *testing.B) {
+	benchmarkPipe(b, 64*1024)
+}
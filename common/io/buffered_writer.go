@@ -7,9 +7,67 @@ import (
 	"v2ray.com/core/common/alloc"
 )
 
+// memoryQuota limits the total bytes of buffers allocated by BufferedWriters.
+type memoryQuota struct {
+	sync.Mutex
+	freed *sync.Cond
+	// 0 for unlimited.
+	limit int64
+	used  int64
+}
+
+func newMemoryQuota() *memoryQuota {
+	quota := new(memoryQuota)
+	quota.freed = sync.NewCond(&quota.Mutex)
+	return quota
+}
+
+// Reserve takes size bytes from the quota, and waits until they are freed if they would exceed the limit.
+// It never waits when nothing is taken, so that a limit below size lets writers go one at a time.
+func (this *memoryQuota) Reserve(size int) {
+	this.Lock()
+	defer this.Unlock()
+
+	for this.limit > 0 && this.used > 0 && this.used+int64(size) > this.limit {
+		this.freed.Wait()
+	}
+	this.used += int64(size)
+}
+
+func (this *memoryQuota) Free(size int) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.used -= int64(size)
+	this.freed.Broadcast()
+}
+
+var cacheQuota = newMemoryQuota()
+
+// SetBufferedWriterMemoryLimit sets the maximum bytes of buffers allocated by all BufferedWriters. When the
+// limit is reached, BufferedWriters that need a buffer wait until others free theirs. 0 for unlimited.
+func SetBufferedWriterMemoryLimit(limit int64) {
+	cacheQuota.Lock()
+	defer cacheQuota.Unlock()
+
+	cacheQuota.limit = limit
+	cacheQuota.freed.Broadcast()
+}
+
+// BufferedWriterMemoryUsage returns bytes of buffers allocated by all BufferedWriters.
+func BufferedWriterMemoryUsage() int64 {
+	cacheQuota.Lock()
+	defer cacheQuota.Unlock()
+
+	return cacheQuota.used
+}
+
+// BufferedWriter caches writes until SetCached(false). Its buffer is only allocated once it is needed, and
+// freed once it no longer caches.
 type BufferedWriter struct {
 	sync.Mutex
 	writer io.Writer
+	// Taken from cacheQuota, or nil if not allocated.
 	buffer *alloc.Buffer
 	cached bool
 }
@@ -17,11 +75,29 @@ type BufferedWriter struct {
 func NewBufferedWriter(rawWriter io.Writer) *BufferedWriter {
 	return &BufferedWriter{
 		writer: rawWriter,
-		buffer: alloc.NewBuffer().Clear(),
 		cached: true,
 	}
 }
 
+// allocate allocates the buffer if there is none, after waiting for cacheQuota.
+func (this *BufferedWriter) allocate() {
+	if this.buffer != nil {
+		return
+	}
+	cacheQuota.Reserve(alloc.BufferSize)
+	this.buffer = alloc.NewBuffer().Clear()
+}
+
+// free returns the buffer to its pool and cacheQuota, if there is one.
+func (this *BufferedWriter) free() {
+	if this.buffer == nil {
+		return
+	}
+	this.buffer.Release()
+	this.buffer = nil
+	cacheQuota.Free(alloc.BufferSize)
+}
+
 func (this *BufferedWriter) ReadFrom(reader io.Reader) (int64, error) {
 	this.Lock()
 	defer this.Unlock()
@@ -30,6 +106,12 @@ func (this *BufferedWriter) ReadFrom(reader io.Reader) (int64, error) {
 		return 0, io.EOF
 	}
 
+	this.allocate()
+	defer func() {
+		if !this.cached {
+			this.free()
+		}
+	}()
 	totalBytes := int64(0)
 	for {
 		nBytes, err := this.buffer.FillFrom(reader)
@@ -55,6 +137,7 @@ func (this *BufferedWriter) Write(b []byte) (int, error) {
 	if !this.cached {
 		return this.writer.Write(b)
 	}
+	this.allocate()
 	nBytes, _ := this.buffer.Write(b)
 	if this.buffer.IsFull() {
 		this.FlushWithoutLock()
@@ -74,6 +157,9 @@ func (this *BufferedWriter) Flush() error {
 }
 
 func (this *BufferedWriter) FlushWithoutLock() error {
+	if this.buffer == nil {
+		return nil
+	}
 	defer this.buffer.Clear()
 	for !this.buffer.IsEmpty() {
 		nBytes, err := this.writer.Write(this.buffer.Value)
@@ -89,10 +175,18 @@ func (this *BufferedWriter) Cached() bool {
 	return this.cached
 }
 
+// SetCached sets whether writes are cached. Cached writes are flushed, and the buffer is freed, once
+// writes are no longer cached.
 func (this *BufferedWriter) SetCached(cached bool) {
 	this.cached = cached
-	if !cached && !this.buffer.IsEmpty() {
-		this.Flush()
+	if !cached {
+		this.Lock()
+		defer this.Unlock()
+
+		if this.writer != nil {
+			this.FlushWithoutLock()
+		}
+		this.free()
 	}
 }
 
@@ -102,7 +196,6 @@ func (this *BufferedWriter) Release() {
 	this.Lock()
 	defer this.Unlock()
 
-	this.buffer.Release()
-	this.buffer = nil
+	this.free()
 	this.writer = nil
 }
@@ -1,7 +1,9 @@
 package io_test
 
 import (
+	"bytes"
 	"testing"
+	"time"
 
 	"v2ray.com/core/common/alloc"
 	. "v2ray.com/core/common/io"
@@ -27,3 +29,90 @@ func TestBufferedWriter(t *testing.T) {
 	writer.SetCached(false)
 	assert.Int(content.Len()).Equals(16)
 }
+
+func TestBufferedWriterMemoryLimit(t *testing.T) {
+	assert := assert.On(t)
+
+	SetBufferedWriterMemoryLimit(alloc.BufferSize)
+	defer SetBufferedWriterMemoryLimit(0)
+
+	content1 := alloc.NewLargeBuffer().Clear()
+	writer1 := NewBufferedWriter(content1)
+	content2 := alloc.NewLargeBuffer().Clear()
+	writer2 := NewBufferedWriter(content2)
+	assert.Int(int(BufferedWriterMemoryUsage())).Equals(0)
+
+	payload := make([]byte, 16)
+
+	nBytes, err := writer1.Write(payload)
+	assert.Int(nBytes).Equals(16)
+	assert.Error(err).IsNil()
+	assert.Bool(content1.IsEmpty()).IsTrue()
+	assert.Int(int(BufferedWriterMemoryUsage())).Equals(alloc.BufferSize)
+
+	// Over the limit, so the second writer waits for the buffer of the first one.
+	written := make(chan bool, 1)
+	go func() {
+		writer2.Write(payload)
+		written <- true
+	}()
+	select {
+	case <-written:
+		assert.Fail("Write is not blocked.")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Bool(content2.IsEmpty()).IsTrue()
+
+	writer1.SetCached(false)
+	assert.Int(content1.Len()).Equals(16)
+	<-written
+	assert.Bool(content2.IsEmpty()).IsTrue()
+	assert.Int(int(BufferedWriterMemoryUsage())).Equals(alloc.BufferSize)
+
+	// Writes that are not cached need no buffer.
+	nBytes, err = writer1.Write(payload)
+	assert.Int(nBytes).Equals(16)
+	assert.Error(err).IsNil()
+	assert.Int(content1.Len()).Equals(32)
+
+	writer2.Release()
+	assert.Int(content2.Len()).Equals(16)
+	assert.Int(int(BufferedWriterMemoryUsage())).Equals(0)
+	writer1.Release()
+	assert.Int(int(BufferedWriterMemoryUsage())).Equals(0)
+}
+
+func TestBufferedWriterReadFromMemoryLimit(t *testing.T) {
+	assert := assert.On(t)
+
+	SetBufferedWriterMemoryLimit(alloc.BufferSize)
+	defer SetBufferedWriterMemoryLimit(0)
+
+	cachedWriter := NewBufferedWriter(alloc.NewLargeBuffer().Clear())
+	_, err := cachedWriter.Write(make([]byte, 16))
+	assert.Error(err).IsNil()
+
+	// ReadFrom takes a buffer as well, so it waits for the cached writer.
+	content := alloc.NewLargeBuffer().Clear()
+	writer := NewBufferedWriter(content)
+	writer.SetCached(false)
+	copied := make(chan bool, 1)
+	go func() {
+		nBytes, err := writer.ReadFrom(bytes.NewReader(make([]byte, 100)))
+		assert.Int64(nBytes).Equals(100)
+		assert.Error(err).IsNil()
+		copied <- true
+	}()
+	select {
+	case <-copied:
+		assert.Fail("ReadFrom is not blocked.")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cachedWriter.Release()
+	<-copied
+	assert.Int(content.Len()).Equals(100)
+	// The buffer is freed after ReadFrom, as the writer isn't cached.
+	assert.Int(int(BufferedWriterMemoryUsage())).Equals(0)
+	writer.Release()
+}
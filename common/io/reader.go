@@ -50,3 +50,40 @@ func (this *AdaptiveReader) Read() (*alloc.Buffer, error) {
 func (this *AdaptiveReader) Release() {
 	this.reader = nil
 }
+
+// SizedReader is a Reader that reads at most a fixed number of bytes into each buffer.
+type SizedReader struct {
+	reader io.Reader
+	size   int
+}
+
+// NewSizedReader creates a Reader that reads at most size bytes at a time, up to alloc.LargeBufferSize.
+// It returns an AdaptiveReader if size is 0. The Reader doesn't take the ownership of reader.
+func NewSizedReader(reader io.Reader, size int) Reader {
+	if size <= 0 {
+		return NewAdaptiveReader(reader)
+	}
+	if size > alloc.LargeBufferSize {
+		size = alloc.LargeBufferSize
+	}
+	return &SizedReader{
+		reader: reader,
+		size:   size,
+	}
+}
+
+// Read implements Reader.Read().
+func (this *SizedReader) Read() (*alloc.Buffer, error) {
+	buffer := alloc.NewBufferWithSize(this.size).Clear()
+	nBytes, err := this.reader.Read(buffer.Value[:this.size])
+	if err != nil {
+		buffer.Release()
+		return nil, err
+	}
+	buffer.Value = buffer.Value[:nBytes]
+	return buffer, nil
+}
+
+func (this *SizedReader) Release() {
+	this.reader = nil
+}
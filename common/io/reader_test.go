@@ -25,3 +25,28 @@ func TestAdaptiveReader(t *testing.T) {
 	assert.Bool(b2.IsFull()).IsTrue()
 	assert.Int(b2.Len()).Equals(alloc.LargeBufferSize)
 }
+
+func TestSizedReader(t *testing.T) {
+	assert := assert.On(t)
+
+	rawContent := make([]byte, 10000)
+
+	reader := NewSizedReader(bytes.NewBuffer(rawContent), 4096)
+	b1, err := reader.Read()
+	assert.Error(err).IsNil()
+	assert.Int(b1.Len()).Equals(4096)
+
+	b2, err := reader.Read()
+	assert.Error(err).IsNil()
+	assert.Int(b2.Len()).Equals(4096)
+
+	b3, err := reader.Read()
+	assert.Error(err).IsNil()
+	assert.Int(b3.Len()).Equals(10000 - 8192)
+
+	// Sizes beyond the largest buffer are capped.
+	reader = NewSizedReader(bytes.NewBuffer(make([]byte, 1024*1024)), 1024*1024)
+	b4, err := reader.Read()
+	assert.Error(err).IsNil()
+	assert.Int(b4.Len()).Equals(alloc.LargeBufferSize)
+}
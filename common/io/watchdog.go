@@ -0,0 +1,158 @@
+package io
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"v2ray.com/core/common/stats"
+)
+
+// Progress is the last time that a watched pipe moved data. A nil Progress tracks nothing.
+type Progress struct {
+	// Unix time in nanoseconds.
+	last int64
+	// Returns bytes moved by the pipe so far, or nil if progress is only recorded by Touch.
+	moved func() int64
+	// Bytes moved at the last check.
+	seen    int64
+	onStall func()
+	closed  bool
+}
+
+// Touch records progress now.
+func (this *Progress) Touch() {
+	if this == nil {
+		return
+	}
+	atomic.StoreInt64(&this.last, time.Now().UnixNano())
+}
+
+func (this *Progress) idle(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, atomic.LoadInt64(&this.last)))
+}
+
+// update records progress if the pipe has moved any bytes since the last check. Caller must hold the lock
+// of the watchdog.
+func (this *Progress) update() {
+	if this.moved == nil {
+		return
+	}
+	if moved := this.moved(); moved != this.seen {
+		this.seen = moved
+		this.Touch()
+	}
+}
+
+// Watchdog finds pipes that make no progress for longer than a threshold, while their connections are
+// still open. Counter "stalled" in counters is the number of such pipes at the last check, and "closed"
+// is the number of pipes closed for stalling. A nil Watchdog watches nothing.
+type Watchdog struct {
+	sync.Mutex
+	threshold    time.Duration
+	closeStalled bool
+	watched      map[*Progress]bool
+	counters     *stats.CounterSet
+	done         chan bool
+	closeOnce    sync.Once
+}
+
+// NewWatchdog creates a Watchdog with threshold. Stalled pipes are closed by their onStall callbacks if
+// closeStalled is true.
+func NewWatchdog(threshold time.Duration, closeStalled bool, counters *stats.CounterSet) *Watchdog {
+	return &Watchdog{
+		threshold:    threshold,
+		closeStalled: closeStalled,
+		watched:      make(map[*Progress]bool),
+		counters:     counters,
+		done:         make(chan bool),
+	}
+}
+
+// Watch starts watching a pipe, which has made progress now. moved returns the bytes that the pipe has
+// moved so far, e.g., on its connection, so that every byte counts as progress, even if it is only part of
+// a larger write or of a frame still being read. If moved is nil, progress is recorded by Touch only.
+// onStall closes the pipe, and must not block.
+func (this *Watchdog) Watch(moved func() int64, onStall func()) *Progress {
+	if this == nil {
+		return nil
+	}
+	progress := &Progress{
+		moved:   moved,
+		onStall: onStall,
+	}
+	if moved != nil {
+		progress.seen = moved()
+	}
+	progress.Touch()
+
+	this.Lock()
+	defer this.Unlock()
+	this.watched[progress] = true
+	return progress
+}
+
+// Unwatch stops watching a pipe when it is finished.
+func (this *Watchdog) Unwatch(progress *Progress) {
+	if this == nil || progress == nil {
+		return
+	}
+	this.Lock()
+	defer this.Unlock()
+	delete(this.watched, progress)
+}
+
+// Check counts stalled pipes, and closes them if enabled.
+func (this *Watchdog) Check() {
+	this.Lock()
+	defer this.Unlock()
+
+	now := time.Now()
+	stalled := int64(0)
+	for progress := range this.watched {
+		progress.update()
+		if progress.closed || progress.idle(now) <= this.threshold {
+			continue
+		}
+		stalled++
+		if this.closeStalled {
+			progress.closed = true
+			progress.onStall()
+			this.counters.Get("closed").Add(1)
+		}
+	}
+	gauge := this.counters.Get("stalled")
+	gauge.Add(stalled - gauge.Value())
+}
+
+// Start checks pipes in every half of the threshold. It must be called only once.
+func (this *Watchdog) Start() {
+	if this == nil {
+		return
+	}
+	go this.run()
+}
+
+// Close stops checking pipes.
+func (this *Watchdog) Close() {
+	if this == nil {
+		return
+	}
+	this.closeOnce.Do(func() {
+		close(this.done)
+	})
+}
+
+func (this *Watchdog) run() {
+	ticker := time.NewTicker(this.threshold / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-this.done:
+			return
+		case <-ticker.C:
+			this.Check()
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package io_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "v2ray.com/core/common/io"
+	"v2ray.com/core/common/stats"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestWatchdog(t *testing.T) {
+	assert := assert.On(t)
+
+	counters := stats.NewCounterSet()
+	watchdog := NewWatchdog(50*time.Millisecond, true, counters)
+	stalledCalls := 0
+	stalled := watchdog.Watch(nil, func() {
+		stalledCalls++
+	})
+	active := watchdog.Watch(nil, func() {
+		assert.Fail("Active pipe is closed.")
+	})
+	finished := watchdog.Watch(nil, func() {
+		assert.Fail("Finished pipe is closed.")
+	})
+	watchdog.Unwatch(finished)
+
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		active.Touch()
+	}
+	watchdog.Check()
+	assert.Int(stalledCalls).Equals(1)
+	assert.Int64(counters.Get("stalled").Value()).Equals(1)
+	assert.Int64(counters.Get("closed").Value()).Equals(1)
+
+	// A closed pipe is not closed again, nor counted as stalled.
+	watchdog.Check()
+	assert.Int(stalledCalls).Equals(1)
+	assert.Int64(counters.Get("stalled").Value()).Equals(0)
+	watchdog.Unwatch(stalled)
+	watchdog.Unwatch(active)
+}
+
+func TestWatchdogOfTricklingPipe(t *testing.T) {
+	assert := assert.On(t)
+
+	counters := stats.NewCounterSet()
+	watchdog := NewWatchdog(50*time.Millisecond, true, counters)
+	// A byte at a time moves, but no write of the pipe finishes.
+	var moved int64
+	trickling := watchdog.Watch(func() int64 {
+		return atomic.LoadInt64(&moved)
+	}, func() {
+		assert.Fail("Trickling pipe is closed.")
+	})
+	stalledCalls := 0
+	stalled := watchdog.Watch(func() int64 {
+		return 100
+	}, func() {
+		stalledCalls++
+	})
+
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt64(&moved, 1)
+		watchdog.Check()
+	}
+	assert.Int(stalledCalls).Equals(1)
+	assert.Int64(counters.Get("closed").Value()).Equals(1)
+	watchdog.Unwatch(trickling)
+	watchdog.Unwatch(stalled)
+}
+
+func TestNilWatchdog(t *testing.T) {
+	assert := assert.On(t)
+
+	var watchdog *Watchdog
+	progress := watchdog.Watch(nil, func() {})
+	assert.Pointer(progress).IsNil()
+	progress.Touch()
+	watchdog.Unwatch(progress)
+}
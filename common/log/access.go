@@ -29,10 +29,16 @@ func InitAccessLogger(file string) error {
 
 // Access writes an access log.
 func Access(from, to interface{}, status AccessStatus, reason interface{}) {
+	AccessWithTags(from, to, status, reason, nil)
+}
+
+// AccessWithTags writes an access log with tags of the connection.
+func AccessWithTags(from, to interface{}, status AccessStatus, reason interface{}, tags map[string]string) {
 	accessLoggerInstance.Log(&internal.AccessLog{
 		From:   from,
 		To:     to,
 		Status: string(status),
 		Reason: reason,
+		Tags:   tags,
 	})
 }
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -59,14 +60,23 @@ type AccessLog struct {
 	To     interface{}
 	Status string
 	Reason interface{}
+	Tags   map[string]string
 }
 
 func (this *AccessLog) Release() {
 	this.From = nil
 	this.To = nil
 	this.Reason = nil
+	this.Tags = nil
 }
 
 func (this *AccessLog) String() string {
-	return strings.Join([]string{InterfaceToString(this.From), this.Status, InterfaceToString(this.To), InterfaceToString(this.Reason)}, " ")
+	values := []string{InterfaceToString(this.From), this.Status, InterfaceToString(this.To), InterfaceToString(this.Reason)}
+	if len(this.Tags) > 0 {
+		// Tags are appended as a JSON object, with keys sorted.
+		if tags, err := json.Marshal(this.Tags); err == nil {
+			values = append(values, string(tags))
+		}
+	}
+	return strings.Join(values, " ")
 }
@@ -23,3 +23,20 @@ func TestAccessLog(t *testing.T) {
 	assert.String(entryStr).Contains("test_reason")
 	assert.String(entryStr).Contains("Accepted")
 }
+
+func TestAccessLogWithTags(t *testing.T) {
+	assert := assert.On(t)
+
+	entry := &AccessLog{
+		From:   "test_from",
+		To:     "test_to",
+		Status: "Accepted",
+		Reason: "test_reason",
+		Tags: map[string]string{
+			"tenant": "a",
+			"plan":   "free",
+		},
+	}
+
+	assert.String(entry.String()).Equals(`test_from Accepted test_to test_reason {"plan":"free","tenant":"a"}`)
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"io"
 	"log"
 	"os"
 	"time"
@@ -30,8 +31,13 @@ type StdOutLogWriter struct {
 }
 
 func NewStdOutLogWriter() LogWriter {
+	return NewWriterLogWriter(os.Stdout)
+}
+
+// NewWriterLogWriter creates a LogWriter that writes into writer, as StdOutLogWriter does into stdout.
+func NewWriterLogWriter(writer io.Writer) LogWriter {
 	return &StdOutLogWriter{
-		logger: log.New(os.Stdout, "", log.Ldate|log.Ltime),
+		logger: log.New(writer, "", log.Ldate|log.Ltime),
 		cancel: signal.NewCloseSignal(),
 	}
 }
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"io"
+
 	"v2ray.com/core/common/log/internal"
 )
 
@@ -45,6 +47,26 @@ func InitErrorLogger(file string) error {
 	return nil
 }
 
+// InitErrorLoggerWithWriter initializes the error logger to write into writer, e.g., to check logs in
+// tests. Like InitErrorLogger, it takes effect on the next SetLogLevel().
+func InitErrorLoggerWithWriter(writer io.Writer) {
+	streamLoggerInstance = internal.NewWriterLogWriter(writer)
+}
+
+// Print outputs a log in the given level. Nothing is logged if level is Disabled.
+func Print(level LogLevel, v ...interface{}) {
+	switch level {
+	case LogLevel_Debug:
+		Debug(v...)
+	case LogLevel_Info:
+		Info(v...)
+	case LogLevel_Warning:
+		Warning(v...)
+	case LogLevel_Error:
+		Error(v...)
+	}
+}
+
 // Debug outputs a debug log with given format and optional arguments.
 func Debug(v ...interface{}) {
 	debugLogger.Log(&internal.ErrorLog{
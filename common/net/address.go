@@ -2,6 +2,7 @@ package net
 
 import (
 	"net"
+	"strings"
 
 	"v2ray.com/core/common/log"
 	"v2ray.com/core/common/predicate"
@@ -53,12 +54,18 @@ type Address interface {
 }
 
 // ParseAddress parses a string into an Address. The return value will be an IPAddress when
-// the string is in the form of IPv4 or IPv6 address, or a DomainAddress otherwise.
+// the string is in the form of IPv4 or IPv6 address, with an optional zone for IPv6 such as
+// "fe80::1%eth0", or a DomainAddress otherwise.
 func ParseAddress(addr string) Address {
 	ip := net.ParseIP(addr)
 	if ip != nil {
 		return IPAddress(ip)
 	}
+	if idx := strings.LastIndexByte(addr, '%'); idx > 0 {
+		if ip := net.ParseIP(addr[:idx]); ip != nil && ip.To4() == nil {
+			return IPv6ZoneAddress(ip, addr[idx+1:])
+		}
+	}
 	return DomainAddress(addr)
 }
 
@@ -85,6 +92,28 @@ func IPAddress(ip []byte) Address {
 	}
 }
 
+// IPv6ZoneAddress creates an Address with given IPv6 address and zone, e.g., the interface of a
+// link-local address. It is the same as IPAddress() if zone is empty or ip is not IPv6.
+func IPv6ZoneAddress(ip []byte, zone string) Address {
+	addr := IPAddress(ip)
+	if len(zone) == 0 || addr == nil || !addr.Family().IsIPv6() {
+		return addr
+	}
+	return &zonedIPv6Address{
+		ipv6Address: *(addr.(*ipv6Address)),
+		zone:        zone,
+	}
+}
+
+// AddressZone returns zone of an IPv6 address, or empty if it has none. Zones are only meaningful on
+// the local host, and are dropped when an address is sent to a remote server or saved in IPOrDomain.
+func AddressZone(addr Address) string {
+	if zoned, ok := addr.(*zonedIPv6Address); ok {
+		return zoned.zone
+	}
+	return ""
+}
+
 // DomainAddress creates an Address with given domain.
 func DomainAddress(domain string) Address {
 	var addr domainAddress = domainAddress(domain)
@@ -161,6 +190,23 @@ func (this *ipv6Address) Equals(another Address) bool {
 		this[15] == anotherIPv6[15]
 }
 
+type zonedIPv6Address struct {
+	ipv6Address
+	zone string
+}
+
+func (this *zonedIPv6Address) String() string {
+	return "[" + this.IP().String() + "%" + this.zone + "]"
+}
+
+func (this *zonedIPv6Address) Equals(another Address) bool {
+	anotherZoned, ok := another.(*zonedIPv6Address)
+	if !ok {
+		return false
+	}
+	return this.zone == anotherZoned.zone && this.ipv6Address.Equals(&anotherZoned.ipv6Address)
+}
+
 type domainAddress string
 
 func (addr *domainAddress) IP() net.IP {
@@ -199,3 +245,18 @@ func (this *IPOrDomain) AsAddress() Address {
 	}
 	panic("Common|Net: Invalid address.")
 }
+
+func NewIPOrDomain(addr Address) *IPOrDomain {
+	if addr.Family().IsDomain() {
+		return &IPOrDomain{
+			Address: &IPOrDomain_Domain{
+				Domain: addr.Domain(),
+			},
+		}
+	}
+	return &IPOrDomain{
+		Address: &IPOrDomain_Ip{
+			Ip: []byte(addr.IP()),
+		},
+	}
+}
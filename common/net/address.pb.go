@@ -16,6 +16,7 @@ It has these top-level messages:
 	Endpoint
 	NetworkList
 	PortRange
+	PortList
 */
 package net
 
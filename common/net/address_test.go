@@ -120,3 +120,23 @@ func TestDomainAddressEquals(t *testing.T) {
 	addr4 := v2net.IPAddress([]byte{1, 3, 3, 4, 5, 6, 7, 8, 9, 10, 1, 2, 3, 4, 5, 6})
 	assert.Bool(addr.Equals(addr4)).IsFalse()
 }
+
+func TestIPv6ZoneAddress(t *testing.T) {
+	assert := assert.On(t)
+
+	addr := v2net.ParseAddress("fe80::1%eth0")
+	assert.Address(addr).IsIPv6()
+	assert.IP(addr.IP()).Equals(net.ParseIP("fe80::1"))
+	assert.String(v2net.AddressZone(addr)).Equals("eth0")
+	assert.Address(addr).EqualsString("[fe80::1%eth0]")
+
+	assert.Bool(addr.Equals(v2net.ParseAddress("fe80::1%eth0"))).IsTrue()
+	assert.Bool(addr.Equals(v2net.ParseAddress("fe80::1%eth1"))).IsFalse()
+	assert.Bool(addr.Equals(v2net.ParseAddress("fe80::1"))).IsFalse()
+
+	assert.String(v2net.AddressZone(v2net.ParseAddress("fe80::1"))).Equals("")
+	assert.Address(v2net.ParseAddress("1.2.3.4%eth0")).IsDomain()
+
+	dest := v2net.DestinationFromAddr(&net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 80, Zone: "eth0"})
+	assert.String(dest.NetAddr()).Equals("[fe80::1%eth0]:80")
+}
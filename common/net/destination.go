@@ -14,9 +14,9 @@ type Destination struct {
 func DestinationFromAddr(addr net.Addr) Destination {
 	switch addr := addr.(type) {
 	case *net.TCPAddr:
-		return TCPDestination(IPAddress(addr.IP), Port(addr.Port))
+		return TCPDestination(IPv6ZoneAddress(addr.IP, addr.Zone), Port(addr.Port))
 	case *net.UDPAddr:
-		return UDPDestination(IPAddress(addr.IP), Port(addr.Port))
+		return UDPDestination(IPv6ZoneAddress(addr.IP, addr.Zone), Port(addr.Port))
 	default:
 		panic("Unknown address type.")
 	}
@@ -13,8 +13,13 @@ type IPNet struct {
 }
 
 func NewIPNet() *IPNet {
+	return NewIPNetWithCapacity(1024)
+}
+
+// NewIPNetWithCapacity creates an empty IPNet with room for the given number of networks.
+func NewIPNetWithCapacity(capacity int) *IPNet {
 	return &IPNet{
-		cache: make(map[uint32]byte, 1024),
+		cache: make(map[uint32]byte, capacity),
 	}
 }
 
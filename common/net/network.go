@@ -17,6 +17,8 @@ func ParseNetwork(nwStr string) Network {
 		return Network_KCP
 	case "ws":
 		return Network_WebSocket
+	case "grpc":
+		return Network_GRPC
 	default:
 		return Network_Unknown
 	}
@@ -49,6 +51,8 @@ func (this Network) UrlPrefix() string {
 		return "kcp"
 	case Network_WebSocket:
 		return "ws"
+	case Network_GRPC:
+		return "grpc"
 	default:
 		return "unknown"
 	}
@@ -24,6 +24,8 @@ const (
 	Network_UDP       Network = 3
 	Network_KCP       Network = 4
 	Network_WebSocket Network = 5
+	// Bidirectional gRPC stream over HTTP/2.
+	Network_GRPC Network = 6
 )
 
 var Network_name = map[int32]string{
@@ -33,6 +35,7 @@ var Network_name = map[int32]string{
 	3: "UDP",
 	4: "KCP",
 	5: "WebSocket",
+	6: "GRPC",
 }
 var Network_value = map[string]int32{
 	"Unknown":   0,
@@ -41,6 +44,7 @@ var Network_value = map[string]int32{
 	"UDP":       3,
 	"KCP":       4,
 	"WebSocket": 5,
+	"GRPC":      6,
 }
 
 func (x Network) String() string {
@@ -65,18 +69,19 @@ func init() {
 func init() { proto.RegisterFile("v2ray.com/core/common/net/network.proto", fileDescriptor2) }
 
 var fileDescriptor2 = []byte{
-	// 203 bytes of a gzipped FileDescriptorProto
+	// 213 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x52, 0x2f, 0x33, 0x2a, 0x4a,
 	0xac, 0xd4, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xce, 0x2f, 0x4a, 0xd5, 0x4f, 0xce, 0xcf, 0xcd, 0xcd,
 	0xcf, 0xd3, 0xcf, 0x4b, 0x2d, 0x01, 0xe1, 0xf2, 0xfc, 0xa2, 0x6c, 0xbd, 0x82, 0xa2, 0xfc, 0x92,
 	0x7c, 0x21, 0x51, 0x98, 0xc2, 0xa2, 0x54, 0x3d, 0x88, 0x22, 0xbd, 0xbc, 0xd4, 0x12, 0x25, 0x77,
 	0x2e, 0x6e, 0x3f, 0x88, 0x3a, 0x9f, 0xcc, 0xe2, 0x12, 0x21, 0x0b, 0x2e, 0x76, 0xa8, 0x36, 0x09,
 	0x46, 0x05, 0x66, 0x0d, 0x3e, 0x23, 0x39, 0x3d, 0xac, 0xfa, 0xf4, 0xa0, 0x9a, 0x82, 0x60, 0xca,
-	0xb5, 0x7c, 0xb8, 0xd8, 0xa1, 0x62, 0x42, 0xdc, 0x5c, 0xec, 0xa1, 0x79, 0xd9, 0x79, 0xf9, 0xe5,
+	0xb5, 0xc2, 0xb8, 0xd8, 0xa1, 0x62, 0x42, 0xdc, 0x5c, 0xec, 0xa1, 0x79, 0xd9, 0x79, 0xf9, 0xe5,
 	0x79, 0x02, 0x0c, 0x42, 0x5c, 0x5c, 0x6c, 0x41, 0x89, 0xe5, 0x21, 0xce, 0x01, 0x02, 0x8c, 0x42,
 	0xec, 0x5c, 0xcc, 0x20, 0x06, 0x13, 0x88, 0x11, 0xea, 0x12, 0x20, 0xc0, 0x0c, 0x62, 0x78, 0x3b,
 	0x07, 0x08, 0xb0, 0x08, 0xf1, 0x72, 0x71, 0x86, 0xa7, 0x26, 0x05, 0xe7, 0x27, 0x67, 0xa7, 0x96,
-	0x08, 0xb0, 0x3a, 0xe9, 0x71, 0x49, 0x26, 0xe7, 0xe7, 0x62, 0xb7, 0xdb, 0x89, 0x07, 0x6a, 0x51,
-	0x00, 0xc8, 0x63, 0x51, 0xcc, 0x79, 0xa9, 0x25, 0x49, 0x6c, 0x60, 0x4f, 0x1a, 0x03, 0x02, 0x00,
-	0x00, 0xff, 0xff, 0x6e, 0x52, 0xf1, 0x92, 0x0f, 0x01, 0x00, 0x00,
+	0x08, 0xb0, 0x0a, 0x71, 0x70, 0xb1, 0xb8, 0x07, 0x05, 0x38, 0x0b, 0xb0, 0x39, 0xe9, 0x71, 0x49,
+	0x26, 0xe7, 0xe7, 0x62, 0x77, 0x85, 0x13, 0x0f, 0xd4, 0xca, 0x00, 0x90, 0x17, 0xa3, 0x98, 0xf3,
+	0x52, 0x4b, 0x92, 0xd8, 0xc0, 0xde, 0x35, 0x06, 0x04, 0x00, 0x00, 0xff, 0xff, 0xa2, 0xdf, 0x73,
+	0x7d, 0x19, 0x01, 0x00, 0x00,
 }
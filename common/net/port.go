@@ -67,3 +67,13 @@ func (this PortRange) ToPort() Port {
 func (this PortRange) Contains(port Port) bool {
 	return this.FromPort() <= port && port <= this.ToPort()
 }
+
+// Contains returns true if the given port is within any range of this PortList.
+func (this *PortList) Contains(port Port) bool {
+	for _, portRange := range this.Range {
+		if portRange.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package net
 import (
 	"io"
 	"net"
+	"sync"
 	"time"
 )
 
@@ -10,16 +11,20 @@ var (
 	emptyTime time.Time
 )
 
+// TimeOutReader reads from a connection, with each read bounded by the timeout. A read deadline set by
+// SetReadDeadline() is kept by later reads, so that the connection may be stopped from other goroutines.
 type TimeOutReader struct {
 	timeout    uint32
 	connection net.Conn
 	worker     io.Reader
+	deadline   *readDeadline
 }
 
 func NewTimeOutReader(timeout uint32 /* seconds */, connection net.Conn) *TimeOutReader {
 	reader := &TimeOutReader{
 		connection: connection,
 		timeout:    0,
+		deadline:   &readDeadline{},
 	}
 	reader.SetTimeOut(timeout)
 	return reader
@@ -42,6 +47,7 @@ func (reader *TimeOutReader) SetTimeOut(value uint32) {
 		reader.worker = &timedReaderWorker{
 			timeout:    value,
 			connection: reader.connection,
+			deadline:   reader.deadline,
 		}
 	} else {
 		reader.worker = &noOpReaderWorker{
@@ -50,21 +56,54 @@ func (reader *TimeOutReader) SetTimeOut(value uint32) {
 	}
 }
 
+// SetReadDeadline sets the read deadline of the connection, which applies to all reads until it is
+// changed, along with the timeout. It is safe to call concurrently with Read().
+func (reader *TimeOutReader) SetReadDeadline(t time.Time) error {
+	return reader.deadline.set(reader.connection, t)
+}
+
 func (reader *TimeOutReader) Release() {
 	reader.connection = nil
 	reader.worker = nil
 }
 
+// readDeadline is the read deadline of a connection set explicitly, as opposed to by the timeout.
+type readDeadline struct {
+	sync.Mutex
+	deadline time.Time
+}
+
+func (this *readDeadline) set(connection net.Conn, t time.Time) error {
+	this.Lock()
+	defer this.Unlock()
+
+	this.deadline = t
+	return connection.SetReadDeadline(t)
+}
+
+// apply sets the read deadline of connection to t, unless the explicit deadline is earlier. A zero t
+// restores the explicit deadline.
+func (this *readDeadline) apply(connection net.Conn, t time.Time) {
+	this.Lock()
+	defer this.Unlock()
+
+	if t.IsZero() || (!this.deadline.IsZero() && this.deadline.Before(t)) {
+		t = this.deadline
+	}
+	connection.SetReadDeadline(t)
+}
+
 type timedReaderWorker struct {
 	timeout    uint32
 	connection net.Conn
+	deadline   *readDeadline
 }
 
 func (this *timedReaderWorker) Read(p []byte) (int, error) {
 	deadline := time.Duration(this.timeout) * time.Second
-	this.connection.SetReadDeadline(time.Now().Add(deadline))
+	this.deadline.apply(this.connection, time.Now().Add(deadline))
 	nBytes, err := this.connection.Read(p)
-	this.connection.SetReadDeadline(emptyTime)
+	this.deadline.apply(this.connection, emptyTime)
 	return nBytes, err
 }
 
@@ -1,7 +1,9 @@
 package net_test
 
 import (
+	"net"
 	"testing"
+	"time"
 
 	. "v2ray.com/core/common/net"
 	"v2ray.com/core/testing/assert"
@@ -17,3 +19,25 @@ func TestTimeOutSettings(t *testing.T) {
 	reader.SetTimeOut(9)
 	assert.Uint32(reader.GetTimeOut()).Equals(9)
 }
+
+func TestTimeOutReaderKeepsReadDeadline(t *testing.T) {
+	assert := assert.On(t)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	reader := NewTimeOutReader(8, client)
+	// Set by another goroutine to stop reading, before the next read.
+	assert.Error(reader.SetReadDeadline(time.Now())).IsNil()
+	start := time.Now()
+	_, err := reader.Read(make([]byte, 1))
+	assert.Error(err).IsNotNil()
+	assert.Bool(time.Since(start) < time.Second).IsTrue()
+
+	assert.Error(reader.SetReadDeadline(time.Time{})).IsNil()
+	go server.Write([]byte{1})
+	nBytes, err := reader.Read(make([]byte, 1))
+	assert.Error(err).IsNil()
+	assert.Int(nBytes).Equals(1)
+}
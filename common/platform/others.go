@@ -13,3 +13,8 @@ func ExpandEnv(s string) string {
 func LineSeparator() string {
 	return "\n"
 }
+
+// HostsFile returns path of the hosts file of the system.
+func HostsFile() string {
+	return "/etc/hosts"
+}
@@ -2,6 +2,10 @@
 
 package platform
 
+import (
+	"os"
+)
+
 func ExpandEnv(s string) string {
 	// TODO
 	return s
@@ -10,3 +14,8 @@ func ExpandEnv(s string) string {
 func LineSeparator() string {
 	return "\r\n"
 }
+
+// HostsFile returns path of the hosts file of the system.
+func HostsFile() string {
+	return os.Getenv("SystemRoot") + "\\System32\\drivers\\etc\\hosts"
+}
@@ -0,0 +1,189 @@
+package protocol
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCircuitOpen = errors.New("Circuit breaker is open.")
+)
+
+type CircuitState int
+
+const (
+	CircuitClosed = CircuitState(iota)
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (this CircuitState) String() string {
+	switch this {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+func (this *CircuitBreakerConfig) GetEffectiveFailureThreshold() uint32 {
+	if this.FailureThreshold == 0 {
+		return 5
+	}
+	return this.FailureThreshold
+}
+
+func (this *CircuitBreakerConfig) GetEffectiveOpenTimeout() time.Duration {
+	if this.OpenTimeout == 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(this.OpenTimeout) * time.Second
+}
+
+func (this *CircuitBreakerConfig) GetEffectiveSuccessThreshold() uint32 {
+	if this.SuccessThreshold == 0 {
+		return 1
+	}
+	return this.SuccessThreshold
+}
+
+// CircuitBreaker tracks dial results of a server. After too many consecutive failures, the circuit opens
+// and dials fail immediately. Once the open timeout passes, the circuit becomes half-open and lets one
+// probe through at a time, until enough probes succeed to close it again.
+// A nil CircuitBreaker is always closed.
+type CircuitBreaker struct {
+	sync.Mutex
+	config    *CircuitBreakerConfig
+	state     CircuitState
+	failures  uint32
+	successes uint32
+	openedAt  time.Time
+	probing   bool
+}
+
+func NewCircuitBreaker(config *CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		config: config,
+		state:  CircuitClosed,
+	}
+}
+
+// State returns the current state of the circuit. An open circuit is reported as half-open once its
+// timeout passes.
+func (this *CircuitBreaker) State() CircuitState {
+	if this == nil {
+		return CircuitClosed
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.refresh()
+	return this.state
+}
+
+// Available returns true if a dial may be allowed now. Unlike Allow(), it doesn't take the probe of a
+// half-open circuit.
+func (this *CircuitBreaker) Available() bool {
+	if this == nil {
+		return true
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.refresh()
+	switch this.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		return !this.probing
+	default:
+		return true
+	}
+}
+
+// Allow returns true if a dial should be made. In half-open state, only one caller gets true until the
+// result of its dial is reported.
+func (this *CircuitBreaker) Allow() bool {
+	if this == nil {
+		return true
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.refresh()
+	switch this.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if this.probing {
+			return false
+		}
+		this.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// OnSuccess reports a successful dial.
+func (this *CircuitBreaker) OnSuccess() {
+	if this == nil {
+		return
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.failures = 0
+	if this.state != CircuitHalfOpen {
+		return
+	}
+	this.probing = false
+	this.successes++
+	if this.successes >= this.config.GetEffectiveSuccessThreshold() {
+		this.state = CircuitClosed
+		this.successes = 0
+	}
+}
+
+// OnFailure reports a failed dial.
+func (this *CircuitBreaker) OnFailure() {
+	if this == nil {
+		return
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	switch this.state {
+	case CircuitHalfOpen:
+		this.open()
+	case CircuitClosed:
+		this.failures++
+		if this.failures >= this.config.GetEffectiveFailureThreshold() {
+			this.open()
+		}
+	}
+}
+
+func (this *CircuitBreaker) open() {
+	this.state = CircuitOpen
+	this.openedAt = time.Now()
+	this.failures = 0
+	this.successes = 0
+	this.probing = false
+}
+
+// refresh moves an open circuit to half-open if the open timeout has passed. Caller must hold the lock.
+func (this *CircuitBreaker) refresh() {
+	if this.state == CircuitOpen && time.Since(this.openedAt) >= this.config.GetEffectiveOpenTimeout() {
+		this.state = CircuitHalfOpen
+		this.probing = false
+	}
+}
@@ -0,0 +1,70 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/common/protocol/circuit_breaker.proto
+// DO NOT EDIT!
+
+/*
+Package protocol is a generated protocol buffer package.
+
+It is generated from these files:
+	v2ray.com/core/common/protocol/circuit_breaker.proto
+	v2ray.com/core/common/protocol/server_spec.proto
+	v2ray.com/core/common/protocol/user.proto
+
+It has these top-level messages:
+	CircuitBreakerConfig
+	ServerEndpoint
+	User
+*/
+package protocol
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type CircuitBreakerConfig struct {
+	// Number of consecutive failures before the circuit opens. Default 5.
+	FailureThreshold uint32 `protobuf:"varint,1,opt,name=failure_threshold,json=failureThreshold" json:"failure_threshold,omitempty"`
+	// Seconds to wait before an open circuit lets a probe through. Default 30.
+	OpenTimeout uint32 `protobuf:"varint,2,opt,name=open_timeout,json=openTimeout" json:"open_timeout,omitempty"`
+	// Number of successful probes before a half-open circuit closes. Default 1.
+	SuccessThreshold uint32 `protobuf:"varint,3,opt,name=success_threshold,json=successThreshold" json:"success_threshold,omitempty"`
+}
+
+func (m *CircuitBreakerConfig) Reset()                    { *m = CircuitBreakerConfig{} }
+func (m *CircuitBreakerConfig) String() string            { return proto.CompactTextString(m) }
+func (*CircuitBreakerConfig) ProtoMessage()               {}
+func (*CircuitBreakerConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func init() {
+	proto.RegisterType((*CircuitBreakerConfig)(nil), "v2ray.core.common.protocol.CircuitBreakerConfig")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/common/protocol/circuit_breaker.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 208 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x32, 0x29, 0x33, 0x2a, 0x4a,
+	0xac, 0xd4, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xce, 0x2f, 0x4a, 0xd5, 0x4f, 0xce, 0xcf, 0xcd, 0xcd,
+	0xcf, 0xd3, 0x2f, 0x28, 0xca, 0x2f, 0xc9, 0x4f, 0xce, 0xcf, 0xd1, 0x4f, 0xce, 0x2c, 0x4a, 0x2e,
+	0xcd, 0x2c, 0x89, 0x4f, 0x2a, 0x4a, 0x4d, 0xcc, 0x4e, 0x2d, 0xd2, 0x03, 0x4b, 0x08, 0x49, 0xc1,
+	0x74, 0x15, 0xa5, 0xea, 0x41, 0x74, 0xe8, 0xc1, 0x74, 0x28, 0x4d, 0x66, 0xe4, 0x12, 0x71, 0x86,
+	0xe8, 0x72, 0x82, 0x68, 0x72, 0xce, 0xcf, 0x4b, 0xcb, 0x4c, 0x17, 0xd2, 0xe6, 0x12, 0x4c, 0x4b,
+	0xcc, 0xcc, 0x29, 0x2d, 0x4a, 0x8d, 0x2f, 0xc9, 0x28, 0x4a, 0x2d, 0xce, 0xc8, 0xcf, 0x49, 0x91,
+	0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0d, 0x12, 0x80, 0x4a, 0x84, 0xc0, 0xc4, 0x85, 0x14, 0xb9, 0x78,
+	0xf2, 0x0b, 0x52, 0xf3, 0xe2, 0x4b, 0x32, 0x73, 0x53, 0xf3, 0x4b, 0x4b, 0x24, 0x98, 0xc0, 0xea,
+	0xb8, 0x41, 0x62, 0x21, 0x10, 0x21, 0x90, 0x79, 0xc5, 0xa5, 0xc9, 0xc9, 0xa9, 0xc5, 0xc5, 0x48,
+	0xe6, 0x31, 0x43, 0xcc, 0x83, 0x4a, 0xc0, 0xcd, 0x73, 0xb2, 0xe7, 0x92, 0x4b, 0xce, 0xcf, 0xd5,
+	0xc3, 0xed, 0x6e, 0x27, 0x61, 0x54, 0x47, 0x07, 0x80, 0xc4, 0xa3, 0x38, 0x60, 0xd2, 0x49, 0x6c,
+	0x60, 0x96, 0x31, 0x20, 0x00, 0x00, 0xff, 0xff, 0xe5, 0xe4, 0x84, 0x94, 0x31, 0x01, 0x00, 0x00,
+}
@@ -0,0 +1,80 @@
+package protocol_test
+
+import (
+	"testing"
+	"time"
+
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/common/protocol"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	assert := assert.On(t)
+
+	breaker := NewCircuitBreaker(&CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      1,
+		SuccessThreshold: 2,
+	})
+	assert.Bool(breaker.Allow()).IsTrue()
+	breaker.OnFailure()
+	assert.Bool(breaker.State() == CircuitClosed).IsTrue()
+	breaker.OnFailure()
+	assert.Bool(breaker.State() == CircuitOpen).IsTrue()
+	assert.Bool(breaker.Allow()).IsFalse()
+
+	time.Sleep(1100 * time.Millisecond)
+	assert.Bool(breaker.State() == CircuitHalfOpen).IsTrue()
+	assert.Bool(breaker.Available()).IsTrue()
+	assert.Bool(breaker.Allow()).IsTrue()
+	// Only one probe at a time.
+	assert.Bool(breaker.Available()).IsFalse()
+	assert.Bool(breaker.Allow()).IsFalse()
+	breaker.OnSuccess()
+	assert.Bool(breaker.State() == CircuitHalfOpen).IsTrue()
+	assert.Bool(breaker.Allow()).IsTrue()
+	breaker.OnSuccess()
+	assert.Bool(breaker.State() == CircuitClosed).IsTrue()
+
+	breaker.OnFailure()
+	breaker.OnFailure()
+	time.Sleep(1100 * time.Millisecond)
+	assert.Bool(breaker.Allow()).IsTrue()
+	breaker.OnFailure()
+	assert.Bool(breaker.State() == CircuitOpen).IsTrue()
+}
+
+func TestNilCircuitBreaker(t *testing.T) {
+	assert := assert.On(t)
+
+	var breaker *CircuitBreaker
+	assert.Bool(breaker.Allow()).IsTrue()
+	breaker.OnFailure()
+	assert.Bool(breaker.State() == CircuitClosed).IsTrue()
+}
+
+func TestPickerSkipsOpenCircuit(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewServerList()
+	list.SetCircuitBreaker(&CircuitBreakerConfig{FailureThreshold: 1})
+	list.AddServer(NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(1)), AlwaysValid()))
+	list.AddServer(NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(2)), AlwaysValid()))
+
+	picker := NewWeightedRoundRobinServerPicker(list)
+	list.GetServer(0).CircuitBreaker().OnFailure()
+	for i := 0; i < 4; i++ {
+		assert.Port(picker.PickServer().Destination().Port).Equals(2)
+	}
+
+	list.GetServer(1).CircuitBreaker().OnFailure()
+	assert.Pointer(picker.PickServer()).IsNil()
+
+	// Circuit state survives replacing servers with the same destination.
+	list.ReplaceServers([]*ServerSpec{
+		NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(1)), AlwaysValid()),
+		NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(3)), AlwaysValid()),
+	})
+	assert.Port(picker.PickServer().Destination().Port).Equals(3)
+}
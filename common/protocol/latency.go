@@ -0,0 +1,47 @@
+package protocol
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Weight of each new sample in the average latency.
+	latencyWeight = 0.2
+)
+
+// LatencyTracker keeps the exponentially weighted moving average of latency samples of a server.
+type LatencyTracker struct {
+	sync.Mutex
+	average time.Duration
+}
+
+// Update adds a latency sample to the average.
+func (this *LatencyTracker) Update(latency time.Duration) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.average == 0 {
+		this.average = latency
+		return
+	}
+	this.average += time.Duration(float64(latency-this.average) * latencyWeight)
+}
+
+// Average returns the average latency, or 0 if there is no sample yet.
+func (this *LatencyTracker) Average() time.Duration {
+	this.Lock()
+	defer this.Unlock()
+
+	return this.average
+}
+
+// copyFrom sets the average latency to that of other.
+func (this *LatencyTracker) copyFrom(other *LatencyTracker) {
+	average := other.Average()
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.average = average
+}
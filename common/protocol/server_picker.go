@@ -1,12 +1,22 @@
 package protocol
 
 import (
+	"math"
 	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
 )
 
 type ServerList struct {
 	sync.RWMutex
 	servers []*ServerSpec
+	breaker *CircuitBreakerConfig
+	// Maximum number of servers, or 0 for unlimited.
+	limit uint32
+	// Called after servers are replaced, or nil.
+	onReplace func()
 }
 
 func NewServerList() *ServerList {
@@ -17,9 +27,46 @@ func (this *ServerList) AddServer(server *ServerSpec) {
 	this.Lock()
 	defer this.Unlock()
 
+	if this.limit > 0 && uint32(len(this.servers)) >= this.limit {
+		log.Warning("Protocol: Server list is full with ", this.limit, " servers. Dropping ", server.Destination(), ".")
+		return
+	}
+	if this.breaker != nil && server.CircuitBreaker() == nil {
+		server.SetCircuitBreaker(NewCircuitBreaker(this.breaker))
+	}
 	this.servers = append(this.servers, server)
 }
 
+// SetCircuitBreaker enables circuit breakers with the given config on all current and future servers
+// in this list.
+func (this *ServerList) SetCircuitBreaker(config *CircuitBreakerConfig) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.breaker = config
+	for _, server := range this.servers {
+		server.SetCircuitBreaker(NewCircuitBreaker(config))
+	}
+}
+
+// SetLimit sets the maximum number of servers in this list, where 0 is unlimited. Servers added beyond it
+// are dropped with a warning. Servers already in the list are kept.
+func (this *ServerList) SetLimit(limit uint32) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.limit = limit
+}
+
+// OnReplace sets callback to be called after servers in this list are replaced, e.g., by a subscription
+// refresh.
+func (this *ServerList) OnReplace(callback func()) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.onReplace = callback
+}
+
 func (this *ServerList) Size() uint32 {
 	this.RLock()
 	defer this.RUnlock()
@@ -46,6 +93,80 @@ func (this *ServerList) GetServer(idx uint32) *ServerSpec {
 	}
 }
 
+// FindServer returns the server with the given destination, or nil if not found. Network is ignored.
+func (this *ServerList) FindServer(dest v2net.Destination) *ServerSpec {
+	this.RLock()
+	defer this.RUnlock()
+
+	for _, server := range this.servers {
+		serverDest := server.Destination()
+		if serverDest.Port == dest.Port && serverDest.Address.Equals(dest.Address) {
+			return server
+		}
+	}
+	return nil
+}
+
+// Servers returns a snapshot of all servers in this list.
+func (this *ServerList) Servers() []*ServerSpec {
+	this.RLock()
+	defer this.RUnlock()
+
+	servers := make([]*ServerSpec, len(this.servers))
+	copy(servers, this.servers)
+	return servers
+}
+
+// ReplaceServers atomically replaces all servers in this list. Weight, circuit breaker and latencies of a
+// server are kept if a server with the same destination exists in the list before. Servers beyond the
+// limit are dropped with a warning.
+func (this *ServerList) ReplaceServers(servers []*ServerSpec) {
+	this.replaceServers(servers, true)
+}
+
+// UpdateServers is the same as ReplaceServers, except that servers keep their own weights, e.g., weights
+// given by a ServerSource.
+func (this *ServerList) UpdateServers(servers []*ServerSpec) {
+	this.replaceServers(servers, false)
+}
+
+func (this *ServerList) replaceServers(servers []*ServerSpec, keepWeight bool) {
+	this.Lock()
+	defer func() {
+		onReplace := this.onReplace
+		this.Unlock()
+		if onReplace != nil {
+			onReplace()
+		}
+	}()
+
+	if this.limit > 0 && uint32(len(servers)) > this.limit {
+		log.Warning("Protocol: Dropping ", uint32(len(servers))-this.limit, " servers beyond the limit of ", this.limit, ".")
+		servers = servers[:this.limit]
+	}
+
+	for _, server := range servers {
+		for _, existing := range this.servers {
+			if existing == server {
+				break
+			}
+			if existing.Destination().NetAddr() == server.Destination().NetAddr() {
+				if keepWeight {
+					server.SetWeight(existing.Weight())
+				}
+				server.SetCircuitBreaker(existing.CircuitBreaker())
+				server.inheritLatency(existing)
+				break
+			}
+		}
+		if this.breaker != nil && server.CircuitBreaker() == nil {
+			server.SetCircuitBreaker(NewCircuitBreaker(this.breaker))
+		}
+	}
+	this.servers = make([]*ServerSpec, len(servers))
+	copy(this.servers, servers)
+}
+
 // Private: Visible for testing.
 func (this *ServerList) RemoveServer(idx uint32) {
 	n := len(this.servers)
@@ -88,3 +209,224 @@ func (this *RoundRobinServerPicker) PickServer() *ServerSpec {
 
 	return server
 }
+
+// WeightedRoundRobinServerPicker picks servers in smooth weighted round-robin order. Weights are read
+// on every pick, so changes from ServerSpec.SetWeight() take effect immediately. Servers whose circuit
+// breaker is open are skipped.
+type WeightedRoundRobinServerPicker struct {
+	sync.Mutex
+	serverlist *ServerList
+	current    map[*ServerSpec]int64
+}
+
+func NewWeightedRoundRobinServerPicker(serverlist *ServerList) *WeightedRoundRobinServerPicker {
+	return &WeightedRoundRobinServerPicker{
+		serverlist: serverlist,
+		current:    make(map[*ServerSpec]int64),
+	}
+}
+
+func (this *WeightedRoundRobinServerPicker) ServerList() *ServerList {
+	return this.serverlist
+}
+
+// PickServer implements ServerPicker.PickServer(). It returns nil if no server has positive weight and
+// an available circuit.
+func (this *WeightedRoundRobinServerPicker) PickServer() *ServerSpec {
+	this.Lock()
+	defer this.Unlock()
+
+	var picked *ServerSpec
+	total := int64(0)
+	seen := make(map[*ServerSpec]bool)
+	for idx := uint32(0); idx < this.serverlist.Size(); idx++ {
+		server := this.serverlist.GetServer(idx)
+		if server == nil {
+			break
+		}
+		seen[server] = true
+		weight := int64(server.Weight())
+		if weight == 0 || !server.CircuitBreaker().Available() {
+			delete(this.current, server)
+			continue
+		}
+		total += weight
+		this.current[server] += weight
+		if picked == nil || this.current[server] > this.current[picked] {
+			picked = server
+		}
+	}
+
+	for server := range this.current {
+		if !seen[server] {
+			delete(this.current, server)
+		}
+	}
+
+	if picked != nil {
+		this.current[picked] -= total
+	}
+	return picked
+}
+
+// GeoLocation is the location of a region, in degrees.
+type GeoLocation struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Distance returns the great-circle distance to another location, in radians.
+func (this GeoLocation) Distance(another GeoLocation) float64 {
+	const radian = math.Pi / 180
+	lat1, lat2 := this.Latitude*radian, another.Latitude*radian
+	dLat, dLon := lat2-lat1, (another.Longitude-this.Longitude)*radian
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * math.Asin(math.Sqrt(a))
+}
+
+// GeoProximityServerPicker picks the server whose region is the nearest to the region of a destination.
+// Servers equally near are picked in turn.
+type GeoProximityServerPicker struct {
+	sync.Mutex
+	serverlist *ServerList
+	locations  map[string]GeoLocation
+	next       uint32
+}
+
+func NewGeoProximityServerPicker(serverlist *ServerList, locations map[string]GeoLocation) *GeoProximityServerPicker {
+	return &GeoProximityServerPicker{
+		serverlist: serverlist,
+		locations:  locations,
+	}
+}
+
+// PickServerFor returns a nearest available server to region. It returns nil if the location of region
+// is unknown, or no server of a known region is available.
+func (this *GeoProximityServerPicker) PickServerFor(region string) *ServerSpec {
+	location, found := this.locations[region]
+	if !found {
+		return nil
+	}
+
+	var nearest []*ServerSpec
+	minDistance := math.Inf(1)
+	for _, server := range this.serverlist.Servers() {
+		serverLocation, found := this.locations[server.Region()]
+		if !found || !server.IsValid() || server.Weight() == 0 || !server.CircuitBreaker().Available() {
+			continue
+		}
+		distance := location.Distance(serverLocation)
+		switch {
+		case distance < minDistance:
+			minDistance = distance
+			nearest = append(nearest[:0], server)
+		case distance == minDistance:
+			nearest = append(nearest, server)
+		}
+	}
+	if len(nearest) == 0 {
+		return nil
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	this.next++
+	return nearest[this.next%uint32(len(nearest))]
+}
+
+type stickyEntry struct {
+	server *ServerSpec
+	expire time.Time
+}
+
+// StickyServerPicker picks the same server for requests from the same source address, as long as the
+// server is still in the list and available, and the source has used it within the timeout. Otherwise
+// it picks a new server from the underlying picker.
+type StickyServerPicker struct {
+	sync.Mutex
+	picker      ServerPicker
+	serverlist  *ServerList
+	timeout     time.Duration
+	entries     map[string]*stickyEntry
+	lastCleanup time.Time
+}
+
+func NewStickyServerPicker(picker ServerPicker, serverlist *ServerList, timeout time.Duration) *StickyServerPicker {
+	return &StickyServerPicker{
+		picker:      picker,
+		serverlist:  serverlist,
+		timeout:     timeout,
+		entries:     make(map[string]*stickyEntry),
+		lastCleanup: time.Now(),
+	}
+}
+
+// PickServer implements ServerPicker.PickServer(). It does not stick to any server.
+func (this *StickyServerPicker) PickServer() *ServerSpec {
+	return this.picker.PickServer()
+}
+
+// PickServerFor picks a server for a request from source. Port of source is ignored, so all connections
+// from the same client go to the same server.
+func (this *StickyServerPicker) PickServerFor(source v2net.Destination) *ServerSpec {
+	if source.Address == nil {
+		return this.picker.PickServer()
+	}
+	key := source.Address.String()
+	now := time.Now()
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.cleanup(now)
+	if entry, found := this.entries[key]; found && entry.expire.After(now) && this.isUsable(entry.server) {
+		entry.expire = now.Add(this.timeout)
+		return entry.server
+	}
+
+	server := this.picker.PickServer()
+	if server == nil {
+		delete(this.entries, key)
+		return nil
+	}
+	this.entries[key] = &stickyEntry{
+		server: server,
+		expire: now.Add(this.timeout),
+	}
+	return server
+}
+
+// OnFailure tells that server failed for a request from source. The next request from source picks a new
+// server.
+func (this *StickyServerPicker) OnFailure(source v2net.Destination, server *ServerSpec) {
+	if source.Address == nil {
+		return
+	}
+	this.Lock()
+	defer this.Unlock()
+
+	key := source.Address.String()
+	if entry, found := this.entries[key]; found && entry.server == server {
+		delete(this.entries, key)
+	}
+}
+
+func (this *StickyServerPicker) isUsable(server *ServerSpec) bool {
+	if server.Weight() == 0 || !server.CircuitBreaker().Available() {
+		return false
+	}
+	// A server that is removed or replaced in the list is not used any more.
+	return this.serverlist.FindServer(server.Destination()) == server
+}
+
+func (this *StickyServerPicker) cleanup(now time.Time) {
+	if now.Sub(this.lastCleanup) < this.timeout {
+		return
+	}
+	this.lastCleanup = now
+	for key, entry := range this.entries {
+		if !entry.expire.After(now) {
+			delete(this.entries, key)
+		}
+	}
+}
@@ -28,6 +28,42 @@ func TestServerList(t *testing.T) {
 	assert.Port(server.Destination().Port).Equals(1)
 }
 
+func TestServerListLimit(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewServerList()
+	list.SetLimit(2)
+	for port := 1; port <= 3; port++ {
+		list.AddServer(NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(port)), AlwaysValid()))
+	}
+	assert.Uint32(list.Size()).Equals(2)
+
+	list.ReplaceServers([]*ServerSpec{
+		NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(4)), AlwaysValid()),
+		NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(5)), AlwaysValid()),
+		NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(6)), AlwaysValid()),
+	})
+	assert.Uint32(list.Size()).Equals(2)
+	assert.Port(list.GetServer(1).Destination().Port).Equals(5)
+}
+
+func TestServerListOnReplace(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewServerList()
+	var sizes []uint32
+	list.OnReplace(func() {
+		sizes = append(sizes, list.Size())
+	})
+	list.AddServer(NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(1)), AlwaysValid()))
+	list.UpdateServers([]*ServerSpec{
+		NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(2)), AlwaysValid()),
+		NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(3)), AlwaysValid()),
+	})
+	assert.Int(len(sizes)).Equals(1)
+	assert.Uint32(sizes[0]).Equals(2)
+}
+
 func TestServerPicker(t *testing.T) {
 	assert := assert.On(t)
 
@@ -52,3 +88,92 @@ func TestServerPicker(t *testing.T) {
 	server = picker.PickServer()
 	assert.Port(server.Destination().Port).Equals(1)
 }
+
+func TestWeightedServerPicker(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewServerList()
+	list.AddServer(NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(1)), AlwaysValid()))
+	list.AddServer(NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(2)), AlwaysValid()))
+	list.GetServer(0).SetWeight(3)
+
+	picker := NewWeightedRoundRobinServerPicker(list)
+	counts := make(map[v2net.Port]int)
+	for i := 0; i < 8; i++ {
+		counts[picker.PickServer().Destination().Port]++
+	}
+	assert.Int(counts[1]).Equals(6)
+	assert.Int(counts[2]).Equals(2)
+
+	list.GetServer(0).SetWeight(0)
+	for i := 0; i < 4; i++ {
+		assert.Port(picker.PickServer().Destination().Port).Equals(2)
+	}
+
+	list.GetServer(1).SetWeight(0)
+	assert.Pointer(picker.PickServer()).IsNil()
+}
+
+func TestStickyServerPicker(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewServerList()
+	list.AddServer(NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(1)), AlwaysValid()))
+	list.AddServer(NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(2)), AlwaysValid()))
+
+	picker := NewStickyServerPicker(NewWeightedRoundRobinServerPicker(list), list, time.Second)
+	source1 := v2net.TCPDestination(v2net.IPAddress([]byte{10, 0, 0, 1}), v2net.Port(10000))
+	source2 := v2net.TCPDestination(v2net.IPAddress([]byte{10, 0, 0, 2}), v2net.Port(10000))
+
+	server := picker.PickServerFor(source1)
+	assert.Port(server.Destination().Port).Equals(1)
+	assert.Port(picker.PickServerFor(source2).Destination().Port).Equals(2)
+
+	// Another connection from the same client sticks to the same server.
+	source1.Port = v2net.Port(10001)
+	for i := 0; i < 4; i++ {
+		assert.Pointer(picker.PickServerFor(source1)).Equals(server)
+	}
+
+	server2 := picker.PickServerFor(source2)
+	picker.OnFailure(source2, server2)
+	assert.Port(picker.PickServerFor(source2).Destination().Port).Equals(1)
+
+	time.Sleep(1500 * time.Millisecond)
+	server = picker.PickServerFor(source1)
+	assert.Port(server.Destination().Port).Equals(2)
+
+	server.SetWeight(0)
+	assert.Port(picker.PickServerFor(source1).Destination().Port).Equals(1)
+}
+
+func TestGeoProximityServerPicker(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewServerList()
+	for port, region := range map[uint32]string{1: "jp", 2: "us", 3: "us", 4: ""} {
+		list.AddServer(NewServerSpecFromPB(ServerEndpoint{
+			Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+			Port:    port,
+			Region:  region,
+		}))
+	}
+	picker := NewGeoProximityServerPicker(list, map[string]GeoLocation{
+		"jp": {Latitude: 35.7, Longitude: 139.7},
+		"us": {Latitude: 37.8, Longitude: -122.4},
+		"de": {Latitude: 50.1, Longitude: 8.7},
+		"kr": {Latitude: 37.6, Longitude: 127.0},
+	})
+
+	assert.Port(picker.PickServerFor("kr").Destination().Port).Equals(1)
+	ports := make(map[v2net.Port]bool)
+	for i := 0; i < 4; i++ {
+		ports[picker.PickServerFor("us").Destination().Port] = true
+	}
+	assert.Int(len(ports)).Equals(2)
+	assert.Bool(ports[2] && ports[3]).IsTrue()
+	assert.Pointer(picker.PickServerFor("unknown")).IsNil()
+
+	list.FindServer(v2net.TCPDestination(v2net.LocalHostIP, 1)).SetWeight(0)
+	assert.Bool(picker.PickServerFor("kr").Destination().Port != 1).IsTrue()
+}
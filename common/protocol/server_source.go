@@ -0,0 +1,116 @@
+package protocol
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+)
+
+const (
+	DefaultServerSourceInterval = time.Minute
+)
+
+var (
+	ErrEmptyServerSource = errors.New("Protocol: No server in server source.")
+)
+
+// ServerSource provides the current servers for a ServerList, e.g., from a database or a service registry.
+type ServerSource interface {
+	// Servers returns the current servers.
+	Servers() ([]*ServerSpec, error)
+	// Changes returns a channel that receives a value whenever the servers change, or nil if the source
+	// doesn't notify changes.
+	Changes() <-chan struct{}
+}
+
+// StaticServerSource is a ServerSource of servers that never change.
+type StaticServerSource []*ServerSpec
+
+func (this StaticServerSource) Servers() ([]*ServerSpec, error) {
+	return this, nil
+}
+
+func (this StaticServerSource) Changes() <-chan struct{} {
+	return nil
+}
+
+// ServerSourceWatcher refreshes a ServerList from a ServerSource on every interval and on every change
+// notification. Servers already in the list when the watcher is created are always kept. If a refresh
+// fails, the last known good list stays in use.
+type ServerSourceWatcher struct {
+	sync.Mutex
+	source     ServerSource
+	serverList *ServerList
+	static     []*ServerSpec
+	interval   time.Duration
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewServerSourceWatcher creates a watcher that refreshes serverList from source. DefaultServerSourceInterval
+// is used if interval is 0.
+func NewServerSourceWatcher(source ServerSource, serverList *ServerList, interval time.Duration) *ServerSourceWatcher {
+	if interval == 0 {
+		interval = DefaultServerSourceInterval
+	}
+	return &ServerSourceWatcher{
+		source:     source,
+		serverList: serverList,
+		static:     serverList.Servers(),
+		interval:   interval,
+		done:       make(chan struct{}),
+	}
+}
+
+// Refresh queries the source once and updates the ServerList. The list is left untouched if the query fails
+// or returns no server.
+func (this *ServerSourceWatcher) Refresh() error {
+	this.Lock()
+	defer this.Unlock()
+
+	servers, err := this.source.Servers()
+	if err != nil {
+		return errors.New("Protocol: Failed to query server source: " + err.Error())
+	}
+	if len(servers) == 0 {
+		return ErrEmptyServerSource
+	}
+	all := make([]*ServerSpec, 0, len(this.static)+len(servers))
+	all = append(all, this.static...)
+	all = append(all, servers...)
+	// Weights are those of the source, while circuit breakers and latencies of known servers go on.
+	this.serverList.UpdateServers(all)
+	log.Info("Protocol: ", len(servers), " servers loaded from server source.")
+	return nil
+}
+
+// Start refreshes the list immediately and then on every interval or change, until Close() is called.
+func (this *ServerSourceWatcher) Start() {
+	go func() {
+		changes := this.source.Changes()
+		for {
+			if err := this.Refresh(); err != nil {
+				log.Warning("Protocol: Using last known servers: ", err)
+			}
+			select {
+			case <-this.done:
+				return
+			case _, open := <-changes:
+				if !open {
+					// The source stops notifying changes, and is only refreshed on interval.
+					changes = nil
+				}
+			case <-time.After(this.interval):
+			}
+		}
+	}()
+}
+
+// Close stops refreshing. It is safe to call Close more than once.
+func (this *ServerSourceWatcher) Close() {
+	this.closeOnce.Do(func() {
+		close(this.done)
+	})
+}
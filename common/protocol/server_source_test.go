@@ -0,0 +1,141 @@
+package protocol_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/common/protocol"
+	"v2ray.com/core/testing/assert"
+)
+
+// testServerSource serves ports as servers on localhost, with weight if set, and fails if err is set.
+type testServerSource struct {
+	sync.Mutex
+	ports   []int
+	weight  uint32
+	err     error
+	changes chan struct{}
+	queries int
+}
+
+func (this *testServerSource) Servers() ([]*ServerSpec, error) {
+	this.Lock()
+	defer this.Unlock()
+	this.queries++
+	if this.err != nil {
+		return nil, this.err
+	}
+	servers := make([]*ServerSpec, len(this.ports))
+	for idx, port := range this.ports {
+		servers[idx] = NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(port)), AlwaysValid())
+		if this.weight > 0 {
+			servers[idx].SetWeight(this.weight)
+		}
+	}
+	return servers, nil
+}
+
+func (this *testServerSource) Changes() <-chan struct{} {
+	return this.changes
+}
+
+func (this *testServerSource) set(ports []int, err error) {
+	this.Lock()
+	this.ports = ports
+	this.err = err
+	this.Unlock()
+	this.changes <- struct{}{}
+}
+
+// waitForSize waits until list has size servers, for at most a second.
+func waitForSize(list *ServerList, size uint32) uint32 {
+	for i := 0; i < 100 && list.Size() != size; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return list.Size()
+}
+
+func TestServerSourceWatcher(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewServerList()
+	list.AddServer(NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(1)), AlwaysValid()))
+	source := &testServerSource{
+		ports:   []int{2, 3},
+		changes: make(chan struct{}),
+	}
+	watcher := NewServerSourceWatcher(source, list, time.Hour)
+	watcher.Start()
+	defer watcher.Close()
+	assert.Uint32(waitForSize(list, 3)).Equals(3)
+
+	// Servers from the source are refreshed on change, and configured servers are kept.
+	source.set([]int{4}, nil)
+	assert.Uint32(waitForSize(list, 2)).Equals(2)
+	assert.Port(list.GetServer(0).Destination().Port).Equals(1)
+	assert.Port(list.GetServer(1).Destination().Port).Equals(4)
+
+	// Last known servers stay if the source fails.
+	source.set(nil, errors.New("unavailable"))
+	source.set([]int{}, nil)
+	assert.Error(watcher.Refresh()).Equals(ErrEmptyServerSource)
+	assert.Uint32(list.Size()).Equals(2)
+}
+
+func TestServerSourceWatcherKeepsLatency(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewServerList()
+	source := &testServerSource{
+		ports: []int{1},
+	}
+	watcher := NewServerSourceWatcher(source, list, time.Hour)
+	assert.Error(watcher.Refresh()).IsNil()
+	server := list.GetServer(0)
+	server.Latency().Update(100 * time.Millisecond)
+	server.ConnectLatency().Update(20 * time.Millisecond)
+	server.ProbeLatency().Update(300 * time.Millisecond)
+
+	// The source changes the weight of the server, whose latencies are kept.
+	source.weight = 5
+	assert.Error(watcher.Refresh()).IsNil()
+	refreshed := list.GetServer(0)
+	assert.Bool(refreshed != server).IsTrue()
+	assert.Uint32(refreshed.Weight()).Equals(5)
+	assert.Int64(int64(refreshed.Latency().Average())).Equals(int64(100 * time.Millisecond))
+	assert.Int64(int64(refreshed.ConnectLatency().Average())).Equals(int64(20 * time.Millisecond))
+	assert.Int64(int64(refreshed.ProbeLatency().Average())).Equals(int64(300 * time.Millisecond))
+}
+
+func TestServerSourceWatcherClosedChanges(t *testing.T) {
+	assert := assert.On(t)
+
+	source := &testServerSource{
+		ports:   []int{1},
+		changes: make(chan struct{}),
+	}
+	close(source.changes)
+	watcher := NewServerSourceWatcher(source, NewServerList(), time.Hour)
+	watcher.Start()
+	defer watcher.Close()
+
+	// Closing changes takes one more refresh, and then the source is only refreshed on interval.
+	time.Sleep(100 * time.Millisecond)
+	source.Lock()
+	assert.Int(source.queries).Equals(2)
+	source.Unlock()
+}
+
+func TestStaticServerSource(t *testing.T) {
+	assert := assert.On(t)
+
+	list := NewServerList()
+	watcher := NewServerSourceWatcher(StaticServerSource{
+		NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(1)), AlwaysValid()),
+	}, list, 0)
+	assert.Error(watcher.Refresh()).IsNil()
+	assert.Uint32(list.Size()).Equals(1)
+}
@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"v2ray.com/core/common/dice"
@@ -45,22 +46,36 @@ func (this *TimeoutValidStrategy) Invalidate() {
 
 type ServerSpec struct {
 	sync.RWMutex
-	dest  v2net.Destination
-	users []*User
-	valid ValidationStrategy
+	dest    v2net.Destination
+	users   []*User
+	valid   ValidationStrategy
+	weight  uint32
+	breaker *CircuitBreaker
+	latency LatencyTracker
+	// Time of connecting to this server, including the handshakes of its transport.
+	connectLatency LatencyTracker
+	// Time of probes through this server, which include the response of their destination.
+	probeLatency LatencyTracker
+	region       string
 }
 
 func NewServerSpec(dest v2net.Destination, valid ValidationStrategy, users ...*User) *ServerSpec {
 	return &ServerSpec{
-		dest:  dest,
-		users: users,
-		valid: valid,
+		dest:   dest,
+		users:  users,
+		valid:  valid,
+		weight: 1,
 	}
 }
 
 func NewServerSpecFromPB(spec ServerEndpoint) *ServerSpec {
 	dest := v2net.TCPDestination(spec.Address.AsAddress(), v2net.Port(spec.Port))
-	return NewServerSpec(dest, AlwaysValid(), spec.User...)
+	server := NewServerSpec(dest, AlwaysValid(), spec.User...)
+	if spec.Weight > 0 {
+		server.SetWeight(spec.Weight)
+	}
+	server.region = spec.Region
+	return server
 }
 
 func (this *ServerSpec) Destination() v2net.Destination {
@@ -95,6 +110,16 @@ func (this *ServerSpec) AddUser(user *User) {
 	this.users = append(this.users, user)
 }
 
+// Users returns a copy of the users of this server.
+func (this *ServerSpec) Users() []*User {
+	this.RLock()
+	defer this.RUnlock()
+
+	users := make([]*User, len(this.users))
+	copy(users, this.users)
+	return users
+}
+
 func (this *ServerSpec) PickUser() *User {
 	userCount := len(this.users)
 	return this.users[dice.Roll(userCount)]
@@ -107,3 +132,58 @@ func (this *ServerSpec) IsValid() bool {
 func (this *ServerSpec) Invalidate() {
 	this.valid.Invalidate()
 }
+
+// Weight returns the relative weight of this server. A server with weight 0 receives no new connections.
+func (this *ServerSpec) Weight() uint32 {
+	return atomic.LoadUint32(&this.weight)
+}
+
+// SetWeight changes the weight of this server. It is safe to call concurrently with pickers.
+func (this *ServerSpec) SetWeight(weight uint32) {
+	atomic.StoreUint32(&this.weight, weight)
+}
+
+// CircuitBreaker returns the circuit breaker of this server, or nil if it doesn't have one.
+func (this *ServerSpec) CircuitBreaker() *CircuitBreaker {
+	this.RLock()
+	defer this.RUnlock()
+
+	return this.breaker
+}
+
+func (this *ServerSpec) SetCircuitBreaker(breaker *CircuitBreaker) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.breaker = breaker
+}
+
+// Region returns the region of this server, or empty if unknown.
+func (this *ServerSpec) Region() string {
+	return this.region
+}
+
+// Latency returns the tracker of handshake latency of this server.
+func (this *ServerSpec) Latency() *LatencyTracker {
+	return &this.latency
+}
+
+// ConnectLatency returns the tracker of the time it takes to connect to this server, including the
+// handshakes of the transport, such as TLS.
+func (this *ServerSpec) ConnectLatency() *LatencyTracker {
+	return &this.connectLatency
+}
+
+// ProbeLatency returns the tracker of the time of probes through this server, until their responses
+// start, e.g., by the observatory.
+func (this *ServerSpec) ProbeLatency() *LatencyTracker {
+	return &this.probeLatency
+}
+
+// inheritLatency copies the latencies of existing, which this server replaces, so that they don't start
+// over on every refresh of the server list.
+func (this *ServerSpec) inheritLatency(existing *ServerSpec) {
+	this.latency.copyFrom(&existing.latency)
+	this.connectLatency.copyFrom(&existing.connectLatency)
+	this.probeLatency.copyFrom(&existing.probeLatency)
+}
@@ -2,17 +2,6 @@
 // source: v2ray.com/core/common/protocol/server_spec.proto
 // DO NOT EDIT!
 
-/*
-Package protocol is a generated protocol buffer package.
-
-It is generated from these files:
-	v2ray.com/core/common/protocol/server_spec.proto
-	v2ray.com/core/common/protocol/user.proto
-
-It has these top-level messages:
-	ServerEndpoint
-	User
-*/
 package protocol
 
 import proto "github.com/golang/protobuf/proto"
@@ -25,22 +14,20 @@ var _ = proto.Marshal
 var _ = fmt.Errorf
 var _ = math.Inf
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the proto package it is being compiled against.
-// A compilation error at this line likely means your copy of the
-// proto package needs to be updated.
-const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
-
 type ServerEndpoint struct {
 	Address *v2ray_core_common_net.IPOrDomain `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
 	Port    uint32                            `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
 	User    []*User                           `protobuf:"bytes,3,rep,name=user" json:"user,omitempty"`
+	// Relative weight of this server for weighted pickers. 0 is treated as 1.
+	Weight uint32 `protobuf:"varint,4,opt,name=weight" json:"weight,omitempty"`
+	// Region of this server, e.g., a country code, for pickers by geographic proximity.
+	Region string `protobuf:"bytes,5,opt,name=region" json:"region,omitempty"`
 }
 
 func (m *ServerEndpoint) Reset()                    { *m = ServerEndpoint{} }
 func (m *ServerEndpoint) String() string            { return proto.CompactTextString(m) }
 func (*ServerEndpoint) ProtoMessage()               {}
-func (*ServerEndpoint) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+func (*ServerEndpoint) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{0} }
 
 func (m *ServerEndpoint) GetAddress() *v2ray_core_common_net.IPOrDomain {
 	if m != nil {
@@ -60,23 +47,25 @@ func init() {
 	proto.RegisterType((*ServerEndpoint)(nil), "v2ray.core.common.protocol.ServerEndpoint")
 }
 
-func init() { proto.RegisterFile("v2ray.com/core/common/protocol/server_spec.proto", fileDescriptor0) }
+func init() { proto.RegisterFile("v2ray.com/core/common/protocol/server_spec.proto", fileDescriptor1) }
 
-var fileDescriptor0 = []byte{
-	// 230 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x8f, 0x3d, 0x4b, 0x04, 0x31,
-	0x10, 0x86, 0x59, 0xef, 0x50, 0xc9, 0xa1, 0x42, 0xaa, 0x65, 0x0b, 0x89, 0x36, 0xae, 0xcd, 0x44,
-	0x56, 0xbb, 0xeb, 0x0e, 0x2d, 0xac, 0x3c, 0xf6, 0xb0, 0xb1, 0x91, 0x35, 0x3b, 0xc5, 0x81, 0xc9,
-	0x84, 0x49, 0x3c, 0xf0, 0x97, 0xf8, 0x77, 0x65, 0x13, 0x53, 0xf9, 0xd5, 0x0d, 0x6f, 0x9e, 0xf7,
-	0x23, 0xe2, 0x6a, 0xd7, 0xf1, 0xf0, 0x0e, 0x86, 0xac, 0x36, 0xc4, 0xa8, 0x0d, 0x59, 0x4b, 0x4e,
-	0x7b, 0xa6, 0x48, 0x86, 0x5e, 0x75, 0x40, 0xde, 0x21, 0x3f, 0x07, 0x8f, 0x06, 0x92, 0x28, 0x9b,
-	0xe2, 0x60, 0x84, 0x4c, 0x43, 0xa1, 0x9b, 0x8b, 0x9f, 0xd3, 0x1c, 0x46, 0x3d, 0x8c, 0x23, 0x63,
-	0x08, 0x99, 0x6d, 0x2e, 0xff, 0xa9, 0x7d, 0x0b, 0xc8, 0x19, 0x3d, 0xff, 0xa8, 0xc4, 0xf1, 0x26,
-	0xad, 0xb8, 0x73, 0xa3, 0xa7, 0xad, 0x8b, 0x72, 0x29, 0x0e, 0xbe, 0xe2, 0xea, 0x4a, 0x55, 0xed,
-	0xa2, 0x3b, 0x83, 0xef, 0xa3, 0x1c, 0x46, 0xb8, 0x5f, 0x3f, 0xf0, 0x2d, 0xd9, 0x61, 0xeb, 0xfa,
-	0xe2, 0x90, 0x52, 0xcc, 0x3d, 0x71, 0xac, 0xf7, 0x54, 0xd5, 0x1e, 0xf5, 0xe9, 0x96, 0x37, 0x62,
-	0x3e, 0x35, 0xd6, 0x33, 0x35, 0x6b, 0x17, 0x9d, 0x82, 0xdf, 0xbf, 0x08, 0x8f, 0x01, 0xb9, 0x4f,
-	0xf4, 0x6a, 0x29, 0x4e, 0x0d, 0xd9, 0x3f, 0xe0, 0xd5, 0x49, 0x1e, 0xbe, 0xf1, 0x68, 0xd6, 0x93,
-	0xf6, 0x74, 0x58, 0x9e, 0x5e, 0xf6, 0xd3, 0x75, 0xfd, 0x19, 0x00, 0x00, 0xff, 0xff, 0xbd, 0x28,
-	0xb3, 0x3a, 0x81, 0x01, 0x00, 0x00,
+var fileDescriptor1 = []byte{
+	// 259 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x8f, 0x3f, 0x4f, 0xc3, 0x30,
+	0x10, 0xc5, 0x15, 0x1a, 0x0a, 0xb8, 0x02, 0x24, 0x0f, 0x28, 0xca, 0x80, 0x0c, 0x0b, 0x61, 0xb1,
+	0x51, 0x60, 0xeb, 0x56, 0xc1, 0xc0, 0x44, 0x95, 0x8a, 0x85, 0x05, 0x05, 0xe7, 0x54, 0x22, 0x61,
+	0x9f, 0x75, 0x36, 0x45, 0x7c, 0x42, 0xbe, 0x16, 0x8a, 0x93, 0x4c, 0xfc, 0xe9, 0x76, 0xf7, 0xee,
+	0x77, 0xef, 0xee, 0xb1, 0xab, 0x4d, 0x49, 0xf5, 0xa7, 0xd4, 0x68, 0x94, 0x46, 0x02, 0xa5, 0xd1,
+	0x18, 0xb4, 0xca, 0x11, 0x06, 0xd4, 0xf8, 0xa6, 0x3c, 0xd0, 0x06, 0xe8, 0xd9, 0x3b, 0xd0, 0x32,
+	0x8a, 0x3c, 0x1f, 0x37, 0x08, 0x64, 0x4f, 0xcb, 0x91, 0xce, 0x2f, 0x7e, 0x77, 0xb3, 0x10, 0x54,
+	0xdd, 0x34, 0x04, 0xde, 0xf7, 0x6c, 0x7e, 0xb9, 0xe5, 0xec, 0xbb, 0x07, 0xea, 0xd1, 0xf3, 0xaf,
+	0x84, 0x1d, 0xad, 0xe2, 0x17, 0x77, 0xb6, 0x71, 0xd8, 0xda, 0xc0, 0xe7, 0x6c, 0x6f, 0xb0, 0xcb,
+	0x12, 0x91, 0x14, 0xb3, 0xf2, 0x4c, 0xfe, 0x7c, 0xca, 0x42, 0x90, 0xf7, 0xcb, 0x07, 0xba, 0x45,
+	0x53, 0xb7, 0xb6, 0x1a, 0x37, 0x38, 0x67, 0xa9, 0x43, 0x0a, 0xd9, 0x8e, 0x48, 0x8a, 0xc3, 0x2a,
+	0xd6, 0xfc, 0x86, 0xa5, 0xdd, 0xc5, 0x6c, 0x22, 0x26, 0xc5, 0xac, 0x14, 0xf2, 0xef, 0x88, 0xf2,
+	0xd1, 0x03, 0x55, 0x91, 0xe6, 0x27, 0x6c, 0xfa, 0x01, 0xed, 0xfa, 0x35, 0x64, 0x69, 0xf4, 0x1a,
+	0xba, 0x4e, 0x27, 0x58, 0xb7, 0x68, 0xb3, 0x5d, 0x91, 0x14, 0x07, 0xd5, 0xd0, 0x2d, 0xe6, 0xec,
+	0x54, 0xa3, 0xf9, 0xc7, 0x7c, 0x71, 0xdc, 0x07, 0x5d, 0x39, 0xd0, 0xcb, 0x4e, 0x7b, 0xda, 0x1f,
+	0x47, 0x2f, 0xd3, 0x58, 0x5d, 0x7f, 0x07, 0x00, 0x00, 0xff, 0xff, 0x7c, 0xcf, 0xb8, 0x0c, 0xb1,
+	0x01, 0x00, 0x00,
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	v2net "v2ray.com/core/common/net"
 	. "v2ray.com/core/common/protocol"
 	"v2ray.com/core/testing/assert"
 )
@@ -29,3 +30,16 @@ func TestTimeoutValidStrategy(t *testing.T) {
 	strategy.Invalidate()
 	assert.Bool(strategy.IsValid()).IsFalse()
 }
+
+func TestServerLatency(t *testing.T) {
+	assert := assert.On(t)
+
+	server := NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, 443), AlwaysValid())
+	latency := server.Latency()
+	assert.Int64(int64(latency.Average())).Equals(0)
+
+	latency.Update(100 * time.Millisecond)
+	assert.Int64(int64(latency.Average())).Equals(int64(100 * time.Millisecond))
+	latency.Update(600 * time.Millisecond)
+	assert.Int64(int64(latency.Average())).Equals(int64(200 * time.Millisecond))
+}
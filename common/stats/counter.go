@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a 64-bit counter that is safe for concurrent use.
+type Counter struct {
+	value int64
+}
+
+// Add adds delta to the counter and returns the new value.
+func (this *Counter) Add(delta int64) int64 {
+	if this == nil {
+		return 0
+	}
+	return atomic.AddInt64(&this.value, delta)
+}
+
+func (this *Counter) Value() int64 {
+	if this == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&this.value)
+}
+
+// Reset sets the counter to zero and returns its value before reset, in one atomic operation. Any Add()
+// either counts towards the returned value or towards the value after reset, so nothing is lost.
+func (this *Counter) Reset() int64 {
+	if this == nil {
+		return 0
+	}
+	return atomic.SwapInt64(&this.value, 0)
+}
+
+// CounterSet is a set of named counters. Counters are created on first use.
+type CounterSet struct {
+	sync.RWMutex
+	counters map[string]*Counter
+}
+
+func NewCounterSet() *CounterSet {
+	return &CounterSet{
+		counters: make(map[string]*Counter),
+	}
+}
+
+// Get returns the counter of the given name, creating it if necessary.
+func (this *CounterSet) Get(name string) *Counter {
+	this.RLock()
+	counter, found := this.counters[name]
+	this.RUnlock()
+	if found {
+		return counter
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	if counter, found := this.counters[name]; found {
+		return counter
+	}
+	counter = new(Counter)
+	this.counters[name] = counter
+	return counter
+}
+
+// GetBounded returns the counter of the given name, creating it only if the set has fewer than max
+// counters. Otherwise it returns the counter of fallback, so that names from clients don't grow the set
+// without a bound.
+func (this *CounterSet) GetBounded(name string, max int, fallback string) *Counter {
+	this.RLock()
+	counter, found := this.counters[name]
+	full := len(this.counters) >= max
+	this.RUnlock()
+	if found {
+		return counter
+	}
+	if full {
+		return this.Get(fallback)
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	if counter, found := this.counters[name]; found {
+		return counter
+	}
+	if len(this.counters) >= max {
+		name = fallback
+		if counter, found := this.counters[name]; found {
+			return counter
+		}
+	}
+	counter = new(Counter)
+	this.counters[name] = counter
+	return counter
+}
+
+// Snapshot returns current values of all counters.
+func (this *CounterSet) Snapshot() map[string]int64 {
+	this.RLock()
+	defer this.RUnlock()
+
+	values := make(map[string]int64, len(this.counters))
+	for name, counter := range this.counters {
+		values[name] = counter.Value()
+	}
+	return values
+}
+
+// SnapshotAndReset returns values of all counters accumulated since last reset, and resets them to zero.
+// Each counter is read and zeroed atomically, so updates during the snapshot go to the next period.
+func (this *CounterSet) SnapshotAndReset() map[string]int64 {
+	this.RLock()
+	defer this.RUnlock()
+
+	values := make(map[string]int64, len(this.counters))
+	for name, counter := range this.counters {
+		values[name] = counter.Reset()
+	}
+	return values
+}
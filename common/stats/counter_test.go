@@ -0,0 +1,77 @@
+package stats_test
+
+import (
+	"sync"
+	"testing"
+
+	. "v2ray.com/core/common/stats"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestCounterReset(t *testing.T) {
+	assert := assert.On(t)
+
+	counter := new(Counter)
+	assert.Int64(counter.Add(3)).Equals(3)
+	assert.Int64(counter.Add(4)).Equals(7)
+	assert.Int64(counter.Reset()).Equals(7)
+	assert.Int64(counter.Value()).Equals(0)
+}
+
+func TestCounterSetNoLossOnReset(t *testing.T) {
+	assert := assert.On(t)
+
+	const (
+		writers = 8
+		adds    = 10000
+	)
+
+	counters := NewCounterSet()
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counter := counters.Get("server")
+			for j := 0; j < adds; j++ {
+				counter.Add(1)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	var total int64
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				total += counters.SnapshotAndReset()["server"]
+			}
+		}
+	}()
+
+	wg.Wait()
+	done <- struct{}{}
+	total += counters.SnapshotAndReset()["server"]
+	assert.Int64(total).Equals(writers * adds)
+	assert.Int64(counters.Get("server").Value()).Equals(0)
+}
+
+func TestCounterSetGetBounded(t *testing.T) {
+	assert := assert.On(t)
+
+	counters := NewCounterSet()
+	counters.GetBounded("a", 2, "others").Add(1)
+	counters.GetBounded("b", 2, "others").Add(1)
+	counters.GetBounded("c", 2, "others").Add(1)
+	counters.GetBounded("d", 2, "others").Add(1)
+	counters.GetBounded("a", 2, "others").Add(1)
+
+	snapshot := counters.Snapshot()
+	assert.Int(len(snapshot)).Equals(3)
+	assert.Int64(snapshot["a"]).Equals(2)
+	assert.Int64(snapshot["b"]).Equals(1)
+	assert.Int64(snapshot["others"]).Equals(2)
+}
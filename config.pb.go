@@ -148,6 +148,9 @@ type InboundConnectionConfig struct {
 	AllocationStrategy     *AllocationStrategy                         `protobuf:"bytes,5,opt,name=allocation_strategy,json=allocationStrategy" json:"allocation_strategy,omitempty"`
 	StreamSettings         *v2ray_core_transport_internet.StreamConfig `protobuf:"bytes,6,opt,name=stream_settings,json=streamSettings" json:"stream_settings,omitempty"`
 	AllowPassiveConnection bool                                        `protobuf:"varint,7,opt,name=allow_passive_connection,json=allowPassiveConnection" json:"allow_passive_connection,omitempty"`
+	// Tags attached to all connections of this handler. They are shown in access logs, and in logs and
+	// stats of outbounds that support them.
+	ConnectionTags map[string]string `protobuf:"bytes,8,rep,name=connection_tags,json=connectionTags" json:"connection_tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *InboundConnectionConfig) Reset()                    { *m = InboundConnectionConfig{} }
@@ -190,6 +193,13 @@ func (m *InboundConnectionConfig) GetStreamSettings() *v2ray_core_transport_inte
 	return nil
 }
 
+func (m *InboundConnectionConfig) GetConnectionTags() map[string]string {
+	if m != nil {
+		return m.ConnectionTags
+	}
+	return nil
+}
+
 // Config for an outbound connection handler.
 type OutboundConnectionConfig struct {
 	Settings *v2ray_core_common_loader.TypedSettings `protobuf:"bytes,1,opt,name=settings" json:"settings,omitempty"`
@@ -198,6 +208,11 @@ type OutboundConnectionConfig struct {
 	StreamSettings *v2ray_core_transport_internet.StreamConfig `protobuf:"bytes,3,opt,name=stream_settings,json=streamSettings" json:"stream_settings,omitempty"`
 	ProxySettings  *v2ray_core_transport_internet.ProxyConfig  `protobuf:"bytes,5,opt,name=proxy_settings,json=proxySettings" json:"proxy_settings,omitempty"`
 	Tag            string                                      `protobuf:"bytes,4,opt,name=tag" json:"tag,omitempty"`
+	// Maximum bytes buffered in all connections of the outbound, in both directions. Reading from the faster
+	// side of connections pauses until the slower side drains. Each direction of a connection may still
+	// buffer one read when it has nothing else buffered, so a stalled connection doesn't stop the others.
+	// Unlimited if 0.
+	MaxInFlightBytes uint64 `protobuf:"varint,6,opt,name=max_in_flight_bytes,json=maxInFlightBytes" json:"max_in_flight_bytes,omitempty"`
 }
 
 func (m *OutboundConnectionConfig) Reset()                    { *m = OutboundConnectionConfig{} }
@@ -242,6 +257,9 @@ type Config struct {
 	// App configuration. Must be one in the app directory.
 	App       []*v2ray_core_common_loader.TypedSettings `protobuf:"bytes,4,rep,name=app" json:"app,omitempty"`
 	Transport *v2ray_core_transport.Config              `protobuf:"bytes,5,opt,name=transport" json:"transport,omitempty"`
+	// Drops connections whose outbound is unavailable, instead of sending them through the default
+	// outbound or directly.
+	FailClosed bool `protobuf:"varint,6,opt,name=fail_closed,json=failClosed" json:"fail_closed,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
@@ -298,50 +316,57 @@ func init() {
 func init() { proto.RegisterFile("v2ray.com/core/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 719 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xb4, 0x95, 0xdd, 0x6e, 0xd3, 0x30,
-	0x1c, 0xc5, 0x97, 0xb6, 0xeb, 0xda, 0x7f, 0xb7, 0x52, 0x19, 0x04, 0x61, 0x30, 0x54, 0xba, 0xaf,
-	0x32, 0x50, 0x2a, 0x8a, 0x10, 0x1f, 0x12, 0x8c, 0xad, 0x03, 0x69, 0x20, 0xd1, 0xe2, 0xee, 0x8a,
-	0x9b, 0xca, 0x4b, 0xbd, 0x2c, 0x52, 0x62, 0x47, 0x8e, 0xbb, 0xad, 0x8f, 0xc0, 0xe3, 0xf0, 0x2a,
-	0x3c, 0x01, 0x8f, 0x82, 0xec, 0xb8, 0x69, 0x47, 0xdb, 0x6d, 0x12, 0xe2, 0x2e, 0x8d, 0xcf, 0xef,
-	0xd8, 0x39, 0xc7, 0x76, 0xe1, 0xc1, 0x59, 0x53, 0x90, 0xa1, 0xe3, 0xf2, 0xb0, 0xe1, 0x72, 0x41,
-	0x1b, 0x2e, 0x67, 0x27, 0xbe, 0xe7, 0x44, 0x82, 0x4b, 0x8e, 0x60, 0x34, 0x28, 0xe8, 0xea, 0xf6,
-	0x94, 0x30, 0x0c, 0x39, 0x6b, 0x04, 0x9c, 0xf4, 0xa9, 0x68, 0xc8, 0x61, 0x44, 0x13, 0x68, 0x75,
-	0x63, 0xb6, 0x90, 0x51, 0xd9, 0x88, 0xb8, 0x90, 0x46, 0xb5, 0x3d, 0x5f, 0x45, 0xfa, 0x7d, 0x41,
-	0xe3, 0xd8, 0x08, 0xb7, 0xe6, 0xcd, 0xeb, 0x5d, 0x5a, 0xeb, 0xaa, 0xf3, 0x97, 0x4e, 0x0a, 0xc2,
-	0x62, 0x35, 0x61, 0xc3, 0x67, 0x92, 0x0a, 0x65, 0x7c, 0x49, 0xbf, 0x39, 0x57, 0x3f, 0x29, 0xab,
-	0xbd, 0x84, 0xb5, 0xbd, 0x20, 0xe0, 0x2e, 0x91, 0x3e, 0x67, 0x5d, 0x29, 0x88, 0xa4, 0xde, 0xb0,
-	0xc5, 0x99, 0x3b, 0x10, 0x82, 0x32, 0x77, 0x88, 0xee, 0xc0, 0xe2, 0x19, 0x09, 0x06, 0xd4, 0xb6,
-	0xaa, 0x56, 0x7d, 0x05, 0x27, 0x3f, 0x6a, 0xcf, 0xe1, 0xfe, 0x34, 0x86, 0xe9, 0x89, 0xa0, 0xf1,
-	0xe9, 0x1c, 0xe4, 0x47, 0x06, 0xd0, 0x34, 0x83, 0x5e, 0x41, 0x4e, 0x85, 0xab, 0xb5, 0xe5, 0xe6,
-	0xba, 0x33, 0xae, 0xc4, 0x99, 0x56, 0x3b, 0x47, 0xc3, 0x88, 0x62, 0x0d, 0xa0, 0x2f, 0x50, 0x72,
-	0xc7, 0xeb, 0xb4, 0x33, 0x55, 0xab, 0x5e, 0x6a, 0x3e, 0xb9, 0x9a, 0x9f, 0xf8, 0x30, 0x3c, 0x49,
-	0xa3, 0x5d, 0x58, 0x12, 0xc9, 0xea, 0xed, 0xac, 0x36, 0xda, 0xbc, 0xda, 0xc8, 0x7c, 0x2a, 0x1e,
-	0x51, 0xb5, 0x67, 0x90, 0x53, 0x6b, 0x43, 0x00, 0xf9, 0xbd, 0xe0, 0x9c, 0x0c, 0xe3, 0xca, 0x82,
-	0x7a, 0xc6, 0x84, 0xf5, 0x79, 0x58, 0xb1, 0xd0, 0x32, 0x14, 0x3e, 0x5e, 0xa8, 0x9e, 0x48, 0x50,
-	0xc9, 0xd4, 0x7e, 0x65, 0xe1, 0xde, 0x21, 0x3b, 0xe6, 0x03, 0xd6, 0x6f, 0x71, 0xc6, 0xa8, 0xab,
-	0xbc, 0x5b, 0xba, 0x17, 0xd4, 0x82, 0x42, 0x4c, 0xa5, 0xf4, 0x99, 0x17, 0xeb, 0x50, 0x4a, 0xcd,
-	0xed, 0xc9, 0xb5, 0x24, 0xfb, 0xc3, 0x49, 0xf6, 0xa5, 0xce, 0xa3, 0xdf, 0x35, 0x72, 0x9c, 0x82,
-	0x68, 0x17, 0x40, 0x75, 0xdd, 0x13, 0x84, 0x79, 0xd4, 0x64, 0x53, 0x9d, 0x61, 0xc3, 0xa8, 0x74,
-	0x3a, 0x5c, 0x48, 0xac, 0x74, 0xb8, 0x18, 0x8d, 0x1e, 0xd1, 0x7b, 0x28, 0x06, 0x7e, 0x2c, 0x29,
-	0xeb, 0x71, 0x66, 0x22, 0x79, 0x3c, 0x87, 0x3f, 0xec, 0xb4, 0xc5, 0x01, 0x0f, 0x89, 0xcf, 0x70,
-	0x21, 0x61, 0xda, 0x0c, 0x55, 0x20, 0x2b, 0x89, 0x67, 0xe7, 0xaa, 0x56, 0xbd, 0x88, 0xd5, 0x23,
-	0x6a, 0xc3, 0x6d, 0x92, 0xe6, 0xd8, 0x8b, 0x4d, 0x90, 0xf6, 0xa2, 0xf6, 0x7e, 0x74, 0x4d, 0xdc,
-	0x88, 0x4c, 0xef, 0x9c, 0x23, 0xb8, 0x15, 0x4b, 0x41, 0x49, 0xd8, 0x4b, 0xf3, 0xca, 0x6b, 0xb3,
-	0xa7, 0x93, 0x66, 0xe9, 0xbe, 0x77, 0x46, 0xe7, 0xc4, 0xe9, 0x6a, 0x2a, 0x89, 0x1b, 0x97, 0x13,
-	0x8f, 0x51, 0x86, 0xe8, 0x35, 0xd8, 0x6a, 0xae, 0xf3, 0x5e, 0x44, 0xe2, 0xd8, 0x3f, 0xa3, 0x3d,
-	0x37, 0x2d, 0xc8, 0x5e, 0xaa, 0x5a, 0xf5, 0x02, 0xbe, 0xab, 0xc7, 0x3b, 0xc9, 0xf0, 0xb8, 0xbe,
-	0xda, 0xef, 0x0c, 0xd8, 0xed, 0x81, 0xfc, 0x8f, 0xad, 0x1e, 0xc0, 0x72, 0x4c, 0x59, 0xbf, 0x27,
-	0x4f, 0x05, 0x1f, 0x78, 0xa7, 0xa6, 0xd7, 0x1b, 0xf4, 0x52, 0x52, 0xd8, 0x51, 0x42, 0xcd, 0xca,
-	0x2d, 0xfb, 0xef, 0xb9, 0x7d, 0x83, 0x72, 0x24, 0xf8, 0xc5, 0x70, 0x6c, 0x9a, 0x34, 0xbb, 0x73,
-	0x8d, 0x69, 0x47, 0x41, 0xc6, 0x73, 0x45, 0x3b, 0xa4, 0x96, 0x53, 0x7b, 0xa8, 0xf6, 0x33, 0x03,
-	0x79, 0x13, 0xe8, 0x3b, 0x58, 0xf2, 0x93, 0x13, 0x64, 0x5b, 0xd5, 0x6c, 0xbd, 0x74, 0xf9, 0xea,
-	0x98, 0x73, 0xb8, 0xf0, 0x88, 0x41, 0x1f, 0xa0, 0xc0, 0x4d, 0x57, 0x76, 0x46, 0xf3, 0x1b, 0x93,
-	0xfc, 0xbc, 0x1e, 0x71, 0x4a, 0xa1, 0x06, 0x64, 0x03, 0xee, 0x99, 0xe8, 0xd6, 0x66, 0x96, 0xe9,
-	0x39, 0x86, 0x52, 0x4a, 0xf4, 0x06, 0xb2, 0x24, 0x8a, 0xec, 0x9c, 0x9e, 0xed, 0xc6, 0xed, 0x2b,
-	0x06, 0xbd, 0x85, 0x62, 0x1a, 0x9d, 0xc9, 0xf5, 0xe1, 0xec, 0x5c, 0xcd, 0x84, 0x63, 0xf9, 0xce,
-	0x16, 0x2c, 0x27, 0x2f, 0x3f, 0x71, 0x11, 0x12, 0xa9, 0x6e, 0xa2, 0x8e, 0xba, 0xfa, 0x8f, 0x07,
-	0x27, 0x95, 0x05, 0x54, 0x80, 0xdc, 0xe7, 0x6e, 0xfb, 0x6b, 0xc5, 0xda, 0x5f, 0x87, 0xb2, 0xcb,
-	0xc3, 0x09, 0xd7, 0xfd, 0x52, 0xc2, 0x69, 0xf5, 0xf7, 0x9c, 0x7a, 0x75, 0x9c, 0xd7, 0xff, 0x1a,
-	0x2f, 0xfe, 0x04, 0x00, 0x00, 0xff, 0xff, 0xe4, 0x6f, 0x70, 0x9e, 0x57, 0x07, 0x00, 0x00,
+	// 830 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xb4, 0x55, 0xe1, 0x73, 0xdb, 0x34,
+	0x1c, 0x9d, 0x93, 0xac, 0x4d, 0x7e, 0xe9, 0xb2, 0x9c, 0xca, 0x81, 0x29, 0x0c, 0x42, 0xba, 0xad,
+	0x61, 0x80, 0x73, 0x84, 0xe3, 0x36, 0x76, 0x07, 0xa3, 0xcd, 0xb6, 0xbb, 0xc2, 0x1d, 0x09, 0x6a,
+	0x3f, 0xf1, 0xc5, 0xa8, 0xb6, 0xe2, 0xfa, 0xb0, 0x25, 0x9f, 0xa4, 0x74, 0xf5, 0x9f, 0xc0, 0x27,
+	0xfe, 0x37, 0xfe, 0x19, 0xbe, 0x72, 0x92, 0x1c, 0xdb, 0x25, 0xc9, 0x36, 0x8e, 0xe3, 0x9b, 0xfc,
+	0xd3, 0x7b, 0x4f, 0xf2, 0x7b, 0xcf, 0x09, 0x7c, 0x70, 0x35, 0x11, 0x24, 0xf7, 0x02, 0x9e, 0x8e,
+	0x03, 0x2e, 0xe8, 0x38, 0xe0, 0x6c, 0x11, 0x47, 0x5e, 0x26, 0xb8, 0xe2, 0x08, 0x56, 0x9b, 0x82,
+	0x1e, 0x1c, 0xad, 0x01, 0xd3, 0x94, 0xb3, 0x71, 0xc2, 0x49, 0x48, 0xc5, 0x58, 0xe5, 0x19, 0xb5,
+	0xa4, 0x83, 0xfb, 0x9b, 0x81, 0x8c, 0xaa, 0x71, 0xc6, 0x85, 0x2a, 0x50, 0x47, 0xdb, 0x51, 0x24,
+	0x0c, 0x05, 0x95, 0xb2, 0x00, 0x3e, 0xdc, 0x76, 0x6e, 0x74, 0xe3, 0xae, 0x07, 0xde, 0x3f, 0x70,
+	0x4a, 0x10, 0x26, 0xf5, 0x81, 0xe3, 0x98, 0x29, 0x2a, 0xb4, 0xf0, 0x0d, 0xfc, 0x83, 0xad, 0xf8,
+	0x3a, 0x6c, 0xf8, 0x35, 0xdc, 0x3b, 0x4e, 0x12, 0x1e, 0x10, 0x15, 0x73, 0x76, 0xa6, 0x04, 0x51,
+	0x34, 0xca, 0xa7, 0x9c, 0x05, 0x4b, 0x21, 0x28, 0x0b, 0x72, 0xf4, 0x0e, 0xdc, 0xbe, 0x22, 0xc9,
+	0x92, 0xba, 0xce, 0xc0, 0x19, 0xdd, 0xc1, 0xf6, 0x61, 0xf8, 0x25, 0xbc, 0xbf, 0x4e, 0xc3, 0x74,
+	0x21, 0xa8, 0xbc, 0xdc, 0x42, 0xf9, 0xbd, 0x01, 0x68, 0x9d, 0x83, 0x1e, 0x43, 0x4b, 0x9b, 0x6b,
+	0xb0, 0xbd, 0xc9, 0xa1, 0x57, 0x45, 0xe2, 0xad, 0xa3, 0xbd, 0xf3, 0x3c, 0xa3, 0xd8, 0x10, 0xd0,
+	0x8f, 0xd0, 0x0d, 0xaa, 0x7b, 0xba, 0x8d, 0x81, 0x33, 0xea, 0x4e, 0x3e, 0x7d, 0x3d, 0xbf, 0xf6,
+	0x62, 0xb8, 0xce, 0x46, 0xcf, 0x60, 0x57, 0xd8, 0xdb, 0xbb, 0x4d, 0x23, 0xf4, 0xe0, 0xf5, 0x42,
+	0xc5, 0xab, 0xe2, 0x15, 0x6b, 0xf8, 0x39, 0xb4, 0xf4, 0xdd, 0x10, 0xc0, 0xce, 0x71, 0xf2, 0x8a,
+	0xe4, 0xb2, 0x7f, 0x4b, 0xaf, 0x31, 0x61, 0x21, 0x4f, 0xfb, 0x0e, 0xda, 0x83, 0xf6, 0x8b, 0x6b,
+	0x9d, 0x13, 0x49, 0xfa, 0x8d, 0xe1, 0x5f, 0x2d, 0x78, 0xef, 0x94, 0x5d, 0xf0, 0x25, 0x0b, 0xa7,
+	0x9c, 0x31, 0x1a, 0x68, 0xed, 0xa9, 0xc9, 0x05, 0x4d, 0xa1, 0x2d, 0xa9, 0x52, 0x31, 0x8b, 0xa4,
+	0x31, 0xa5, 0x3b, 0x39, 0xaa, 0xdf, 0xc5, 0xf6, 0xc3, 0xb3, 0xbd, 0x34, 0x7e, 0x84, 0x67, 0x05,
+	0x1c, 0x97, 0x44, 0xf4, 0x0c, 0x40, 0x67, 0xed, 0x0b, 0xc2, 0x22, 0x5a, 0x78, 0x33, 0xd8, 0x20,
+	0xc3, 0xa8, 0xf2, 0xe6, 0x5c, 0x28, 0xac, 0x71, 0xb8, 0x93, 0xad, 0x96, 0xe8, 0x3b, 0xe8, 0x24,
+	0xb1, 0x54, 0x94, 0xf9, 0x9c, 0x15, 0x96, 0x7c, 0xb2, 0x85, 0x7f, 0x3a, 0x9f, 0x89, 0xe7, 0x3c,
+	0x25, 0x31, 0xc3, 0x6d, 0xcb, 0x99, 0x31, 0xd4, 0x87, 0xa6, 0x22, 0x91, 0xdb, 0x1a, 0x38, 0xa3,
+	0x0e, 0xd6, 0x4b, 0x34, 0x83, 0x7d, 0x52, 0xfa, 0xe8, 0xcb, 0xc2, 0x48, 0xf7, 0xb6, 0xd1, 0xfe,
+	0xe8, 0x0d, 0x76, 0x23, 0xb2, 0xde, 0x9c, 0x73, 0xb8, 0x2b, 0x95, 0xa0, 0x24, 0xf5, 0x4b, 0xbf,
+	0x76, 0x8c, 0xd8, 0x67, 0x75, 0xb1, 0xb2, 0xf7, 0xde, 0xea, 0x3b, 0xf1, 0xce, 0x0c, 0xcb, 0xda,
+	0x8d, 0x7b, 0x56, 0x63, 0xe5, 0x21, 0x7a, 0x02, 0xae, 0x3e, 0xeb, 0x95, 0x9f, 0x11, 0x29, 0xe3,
+	0x2b, 0xea, 0x07, 0x65, 0x40, 0xee, 0xee, 0xc0, 0x19, 0xb5, 0xf1, 0xbb, 0x66, 0x7f, 0x6e, 0xb7,
+	0xab, 0xf8, 0xd0, 0xaf, 0x70, 0xb7, 0xc2, 0xfa, 0x8a, 0x44, 0xd2, 0x6d, 0x0f, 0x9a, 0xa3, 0xee,
+	0xe4, 0x71, 0xfd, 0x3e, 0x5b, 0x62, 0xf7, 0xaa, 0xc1, 0x39, 0x89, 0xe4, 0x0b, 0xa6, 0x44, 0x8e,
+	0x7b, 0xc1, 0x8d, 0xe1, 0xc1, 0x31, 0xec, 0x6f, 0x80, 0x69, 0xaf, 0x7f, 0xa3, 0xb9, 0x29, 0x4b,
+	0x07, 0xeb, 0x65, 0xf5, 0x05, 0x36, 0xcc, 0xcc, 0x3e, 0x3c, 0x6d, 0x3c, 0x71, 0x86, 0x7f, 0x34,
+	0xc1, 0x9d, 0x2d, 0xd5, 0xff, 0x58, 0xbd, 0xe7, 0xb0, 0x27, 0x29, 0x0b, 0x7d, 0x75, 0x29, 0xf8,
+	0x32, 0xba, 0x74, 0x1b, 0x6f, 0x5b, 0x9e, 0xae, 0xa6, 0x9d, 0x5b, 0xd6, 0xa6, 0x70, 0x9b, 0xff,
+	0x3d, 0xdc, 0x9f, 0xa1, 0x97, 0x09, 0x7e, 0x9d, 0x57, 0xa2, 0xb6, 0x7e, 0x8f, 0xde, 0x20, 0x3a,
+	0xd7, 0xa4, 0x42, 0xf3, 0x8e, 0x51, 0x28, 0x25, 0xd7, 0x8b, 0xfe, 0x05, 0xec, 0xa7, 0xe4, 0xda,
+	0x8f, 0x99, 0xbf, 0x48, 0xe2, 0xe8, 0x52, 0xf9, 0x17, 0xb9, 0xa2, 0xb6, 0x9b, 0x2d, 0xdc, 0x4f,
+	0xc9, 0xf5, 0x29, 0x7b, 0x69, 0x36, 0x4e, 0xf4, 0x7c, 0xf8, 0x67, 0x03, 0x76, 0x0a, 0xff, 0xbf,
+	0x85, 0xdd, 0xd8, 0xd6, 0xc3, 0x75, 0x4c, 0x73, 0x0e, 0xdf, 0xa2, 0x39, 0x78, 0xc5, 0x41, 0xdf,
+	0x43, 0x9b, 0x17, 0xd1, 0xba, 0x0d, 0xc3, 0xbf, 0x5f, 0xe7, 0x6f, 0x8b, 0x1d, 0x97, 0x2c, 0x34,
+	0x86, 0x66, 0xc2, 0xa3, 0xc2, 0xe9, 0x7b, 0x1b, 0xb3, 0x8f, 0xbc, 0x82, 0xa5, 0x91, 0xe8, 0x1b,
+	0x68, 0x92, 0x2c, 0x73, 0x5b, 0x83, 0xe6, 0xbf, 0x29, 0x8b, 0xe6, 0xa0, 0xa7, 0xd0, 0x29, 0x9d,
+	0x2e, 0x62, 0xf8, 0x70, 0x73, 0x0c, 0xc5, 0x81, 0x15, 0x1c, 0x7d, 0x0c, 0xdd, 0x05, 0x89, 0x13,
+	0x3f, 0x48, 0xb8, 0xa4, 0xa1, 0xb1, 0xb6, 0x8d, 0x41, 0x8f, 0xa6, 0x66, 0xf2, 0xe8, 0x21, 0xec,
+	0x59, 0xd6, 0x4b, 0x2e, 0x52, 0xa2, 0xf4, 0xcf, 0xef, 0x5c, 0x70, 0xc5, 0x2f, 0x96, 0x8b, 0xfe,
+	0x2d, 0xd4, 0x86, 0xd6, 0x0f, 0x67, 0xb3, 0x9f, 0xfa, 0xce, 0xc9, 0x21, 0xf4, 0x02, 0x9e, 0xd6,
+	0x8e, 0x3d, 0xe9, 0x5a, 0x9e, 0x41, 0xff, 0xd2, 0xd2, 0xa3, 0x8b, 0x1d, 0xf3, 0x57, 0xf9, 0xd5,
+	0xdf, 0x01, 0x00, 0x00, 0xff, 0xff, 0xbf, 0xbf, 0x27, 0xa3, 0x4c, 0x08, 0x00, 0x00,
 }
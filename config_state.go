@@ -0,0 +1,121 @@
+package core
+
+import (
+	"encoding/json"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/api"
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common/loader"
+	"v2ray.com/core/proxy"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+// loadedConfig is the config that a Point runs with, and the outbound handlers created from it, in the
+// same order.
+type loadedConfig struct {
+	config           *Config
+	outboundHandlers []proxy.OutboundHandler
+}
+
+type OutboundState struct {
+	Tag      string `json:"tag,omitempty"`
+	Protocol string `json:"protocol"`
+	// Description of the running handler, if it is a DescribableOutboundHandler.
+	Settings interface{} `json:"settings,omitempty"`
+}
+
+// ConfigState is the running config of a Point, which is returned by the admin API. Settings of
+// outbounds are only included if they describe themselves without secrets.
+type ConfigState struct {
+	Outbounds []OutboundState `json:"outbounds"`
+	Routing   json.RawMessage `json:"routing,omitempty"`
+	DNS       json.RawMessage `json:"dns"`
+}
+
+func (this *Point) setLoadedConfig(config *Config, outboundHandlers []proxy.OutboundHandler) {
+	this.loaded.Store(&loadedConfig{
+		config:           config,
+		outboundHandlers: outboundHandlers,
+	})
+}
+
+// registerConfigApi serves the running config at /config of the admin API, if there is one.
+func (this *Point) registerConfigApi(space app.Space) {
+	space.InitializeApplication(func() error {
+		if space.HasApp(api.APP_ID) {
+			apiServer := space.GetApp(api.APP_ID).(*api.ApiServer)
+			apiServer.Handle("/config", api.NewConfigHandler(func() (interface{}, error) {
+				return this.ConfigState()
+			}))
+		}
+		return nil
+	})
+}
+
+// ConfigState returns the running config, including runtime changes of outbounds such as servers from
+// subscriptions and adjusted weights.
+func (this *Point) ConfigState() (*ConfigState, error) {
+	loaded := this.loaded.Load().(*loadedConfig)
+	state := &ConfigState{
+		Outbounds: make([]OutboundState, len(loaded.config.Outbound)),
+	}
+	for idx, outbound := range loaded.config.Outbound {
+		state.Outbounds[idx] = OutboundState{
+			Tag:      outbound.Tag,
+			Protocol: outbound.Settings.Type,
+		}
+		if handler, ok := loaded.outboundHandlers[idx].(proxy.DescribableOutboundHandler); ok {
+			state.Outbounds[idx].Settings = handler.Describe()
+		}
+	}
+
+	var routerConfig *router.Config
+	dnsConfig := defaultDNSConfig()
+	for _, appSettings := range loaded.config.App {
+		switch appSettings.Type {
+		case loader.GetType(new(router.Config)), loader.GetType(new(dns.Config)):
+		default:
+			continue
+		}
+		instance, err := appSettings.GetInstance()
+		if err != nil {
+			return nil, err
+		}
+		switch config := instance.(type) {
+		case *router.Config:
+			routerConfig = config
+		case *dns.Config:
+			dnsConfig = config
+		}
+	}
+	// Routing rules are only replaced on reload if router runs from the start.
+	if this.space.HasApp(router.APP_ID) {
+		if routerConfig == nil {
+			routerConfig = new(router.Config)
+		}
+		routing, err := marshalJSON(routerConfig)
+		if err != nil {
+			return nil, err
+		}
+		state.Routing = routing
+	}
+	dnsState, err := marshalJSON(dnsConfig)
+	if err != nil {
+		return nil, err
+	}
+	state.DNS = dnsState
+	return state, nil
+}
+
+func marshalJSON(message proto.Message) (json.RawMessage, error) {
+	marshaler := &jsonpb.Marshaler{OrigName: true}
+	value, err := marshaler.MarshalToString(message)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(value), nil
+}
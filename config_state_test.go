@@ -0,0 +1,76 @@
+package core_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	. "v2ray.com/core"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestConfigState(t *testing.T) {
+	assert := assert.On(t)
+
+	port := v2net.Port(dice.Roll(20000) + 10000)
+	config := newReloadConfig(port, v2net.TCPDestination(v2net.LocalHostIP, 80), &router.Config{})
+	config.Outbound = append(config.Outbound, &OutboundConnectionConfig{
+		Tag: "proxy",
+		Settings: loader.NewTypedSettings(&shadowsocks.ClientConfig{
+			Server: []*protocol.ServerEndpoint{
+				{
+					Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+					Port:    8388,
+					User: []*protocol.User{
+						{
+							Email: "love@v2ray.com",
+							Account: loader.NewTypedSettings(&shadowsocks.Account{
+								Password:   "secret-password",
+								CipherType: shadowsocks.CipherType_AES_128_CFB,
+							}),
+						},
+					},
+				},
+			},
+		}),
+	})
+	point, err := NewPoint(config)
+	assert.Error(err).IsNil()
+	defer point.Close()
+
+	state, err := point.ConfigState()
+	assert.Error(err).IsNil()
+	assert.Int(len(state.Outbounds)).Equals(3)
+	assert.String(state.Outbounds[2].Tag).Equals("proxy")
+	description := state.Outbounds[2].Settings.(*shadowsocks.ClientDescription)
+	assert.Int(len(description.Servers)).Equals(1)
+	assert.String(description.Servers[0].Server).Equals("127.0.0.1:8388")
+	assert.String(description.Servers[0].Users[0].Email).Equals("love@v2ray.com")
+
+	encoded, err := json.Marshal(state)
+	assert.Error(err).IsNil()
+	assert.Bool(strings.Contains(string(encoded), "secret-password")).IsFalse()
+	assert.Bool(strings.Contains(string(encoded), "[redacted]")).IsTrue()
+
+	// Reloaded config is returned.
+	assert.Error(point.Reload(newReloadConfig(port, v2net.TCPDestination(v2net.LocalHostIP, 80), &router.Config{
+		Rule: []*router.RoutingRule{
+			{
+				Tag: "blocked",
+				NetworkList: &v2net.NetworkList{
+					Network: []v2net.Network{v2net.Network_TCP},
+				},
+			},
+		},
+	}))).IsNil()
+	state, err = point.ConfigState()
+	assert.Error(err).IsNil()
+	assert.Int(len(state.Outbounds)).Equals(2)
+	assert.Bool(strings.Contains(string(state.Routing), "blocked")).IsTrue()
+}
@@ -34,6 +34,7 @@ func NewInboundDetourHandlerAlways(space app.Space, config *InboundConnectionCon
 			Tag:                    config.Tag,
 			StreamSettings:         config.StreamSettings,
 			AllowPassiveConnection: config.AllowPassiveConnection,
+			ConnectionTags:         config.ConnectionTags,
 		})
 		if err != nil {
 			log.Error("Failed to create inbound connection handler: ", err)
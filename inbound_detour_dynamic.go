@@ -42,6 +42,7 @@ func NewInboundDetourHandlerDynamic(space app.Space, config *InboundConnectionCo
 		Tag:                    config.Tag,
 		StreamSettings:         config.StreamSettings,
 		AllowPassiveConnection: config.AllowPassiveConnection,
+		ConnectionTags:         config.ConnectionTags,
 	})
 	if err != nil {
 		log.Error("Point: Failed to create inbound connection handler: ", err)
@@ -109,7 +110,8 @@ func (this *InboundDetourHandlerDynamic) refresh() error {
 			port := this.pickUnusedPort()
 			ichConfig, _ := config.GetTypedSettings()
 			ich, err := proxyregistry.CreateInboundHandler(config.Settings.Type, this.space, ichConfig, &proxy.InboundHandlerMeta{
-				Address: config.GetListenOnValue(), Port: port, Tag: config.Tag, StreamSettings: config.StreamSettings})
+				Address: config.GetListenOnValue(), Port: port, Tag: config.Tag, StreamSettings: config.StreamSettings,
+				ConnectionTags: config.ConnectionTags})
 			if err != nil {
 				delete(this.portsInUse, port)
 				return err
@@ -41,7 +41,7 @@ func GetConfigFormat() core.ConfigFormat {
 	}
 }
 
-func startV2Ray() *core.Point {
+func loadConfig() *core.Config {
 	if len(configFile) == 0 {
 		log.Error("Config file is not set.")
 		return nil
@@ -64,6 +64,14 @@ func startV2Ray() *core.Point {
 		log.Error("Failed to read config file (", configFile, "): ", configFile, err)
 		return nil
 	}
+	return config
+}
+
+func startV2Ray() *core.Point {
+	config := loadConfig()
+	if config == nil {
+		return nil
+	}
 
 	vPoint, err := core.NewPoint(config)
 	if err != nil {
@@ -85,6 +93,22 @@ func startV2Ray() *core.Point {
 	return vPoint
 }
 
+// reloadV2Ray re-reads the config file and applies it to point. The current config is kept on any error.
+func reloadV2Ray(point *core.Point) {
+	if configFile == "stdin:" {
+		log.Warning("Config from stdin can't be reloaded.")
+		return
+	}
+	config := loadConfig()
+	if config == nil {
+		log.Warning("Keeping current config.")
+		return
+	}
+	if err := point.Reload(config); err != nil {
+		log.Error("Failed to reload config, keeping current config: ", err)
+	}
+}
+
 func main() {
 	flag.Parse()
 
@@ -96,9 +120,15 @@ func main() {
 
 	if point := startV2Ray(); point != nil {
 		osSignals := make(chan os.Signal, 1)
-		signal.Notify(osSignals, os.Interrupt, os.Kill, syscall.SIGTERM)
+		signal.Notify(osSignals, os.Interrupt, os.Kill, syscall.SIGTERM, syscall.SIGHUP)
 
-		<-osSignals
+		for sig := range osSignals {
+			if sig != syscall.SIGHUP {
+				break
+			}
+			log.Warning("Reloading config on SIGHUP.")
+			reloadV2Ray(point)
+		}
 		point.Close()
 	}
 	log.Close()
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"errors"
+	"sync"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/protocol"
+)
+
+var (
+	ErrAuthenticatorNotFound = errors.New("Proxy: Authenticator not found.")
+)
+
+// Authenticator verifies credentials that a client presents to an inbound proxy.
+type Authenticator interface {
+	// Authenticate returns the user of the given credentials, or ErrInvalidAuthentication if they are
+	// not valid. The user is attached to the session, so router rules and other parts can use it.
+	Authenticate(username, password string) (*protocol.User, error)
+}
+
+var (
+	authenticators      = make(map[string]Authenticator)
+	authenticatorsMutex sync.RWMutex
+)
+
+// RegisterAuthenticator makes an Authenticator available to inbound proxies by name.
+func RegisterAuthenticator(name string, authenticator Authenticator) error {
+	authenticatorsMutex.Lock()
+	defer authenticatorsMutex.Unlock()
+
+	if _, found := authenticators[name]; found {
+		return common.ErrDuplicatedName
+	}
+	authenticators[name] = authenticator
+	return nil
+}
+
+func MustRegisterAuthenticator(name string, authenticator Authenticator) {
+	if err := RegisterAuthenticator(name, authenticator); err != nil {
+		panic(err)
+	}
+}
+
+func GetAuthenticator(name string) (Authenticator, error) {
+	authenticatorsMutex.RLock()
+	defer authenticatorsMutex.RUnlock()
+
+	authenticator, found := authenticators[name]
+	if !found {
+		return nil, ErrAuthenticatorNotFound
+	}
+	return authenticator, nil
+}
+
+// StaticAuthenticator authenticates users against a fixed list of usernames and passwords. Username is
+// used as email of the authenticated user.
+type StaticAuthenticator struct {
+	accounts map[string]string
+}
+
+func NewStaticAuthenticator(accounts map[string]string) *StaticAuthenticator {
+	return &StaticAuthenticator{
+		accounts: accounts,
+	}
+}
+
+// Authenticate implements Authenticator.Authenticate().
+func (this *StaticAuthenticator) Authenticate(username, password string) (*protocol.User, error) {
+	storedPassword, found := this.accounts[username]
+	if !found || storedPassword != password {
+		return nil, ErrInvalidAuthentication
+	}
+	return &protocol.User{
+		Email: username,
+	}, nil
+}
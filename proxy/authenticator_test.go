@@ -0,0 +1,54 @@
+package proxy_test
+
+import (
+	"testing"
+
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/protocol"
+	. "v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+)
+
+type testAuthenticator struct{}
+
+func (this *testAuthenticator) Authenticate(username, password string) (*protocol.User, error) {
+	if password != "backend-password" {
+		return nil, ErrInvalidAuthentication
+	}
+	return &protocol.User{
+		Email: username + "@backend",
+		Level: 1,
+	}, nil
+}
+
+func TestStaticAuthenticator(t *testing.T) {
+	assert := assert.On(t)
+
+	authenticator := NewStaticAuthenticator(map[string]string{
+		"v2ray": "password",
+	})
+	user, err := authenticator.Authenticate("v2ray", "password")
+	assert.Error(err).IsNil()
+	assert.String(user.Email).Equals("v2ray")
+
+	_, err = authenticator.Authenticate("v2ray", "wrong")
+	assert.Error(err).Equals(ErrInvalidAuthentication)
+	_, err = authenticator.Authenticate("nobody", "password")
+	assert.Error(err).Equals(ErrInvalidAuthentication)
+}
+
+func TestRegisteredAuthenticator(t *testing.T) {
+	assert := assert.On(t)
+
+	_, err := GetAuthenticator("test-backend")
+	assert.Error(err).Equals(ErrAuthenticatorNotFound)
+
+	assert.Error(RegisterAuthenticator("test-backend", new(testAuthenticator))).IsNil()
+	assert.Error(RegisterAuthenticator("test-backend", new(testAuthenticator))).Equals(common.ErrDuplicatedName)
+
+	authenticator, err := GetAuthenticator("test-backend")
+	assert.Error(err).IsNil()
+	user, err := authenticator.Authenticate("v2ray", "backend-password")
+	assert.Error(err).IsNil()
+	assert.String(user.Email).Equals("v2ray@backend")
+}
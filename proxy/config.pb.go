@@ -0,0 +1,63 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/proxy/config.proto
+// DO NOT EDIT!
+
+/*
+Package proxy is a generated protocol buffer package.
+
+It is generated from these files:
+	v2ray.com/core/proxy/config.proto
+
+It has these top-level messages:
+	OutboundSelectionConfig
+*/
+package proxy
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// Selection of the outbound by clients of an inbound, through a username of "outbound:<tag>". On inbounds
+// with password authentication, the username is "outbound:<tag>/<username>", and the username and the
+// password are authenticated as usual. The selected outbound takes precedence over routing rules.
+type OutboundSelectionConfig struct {
+	// Networks in CIDR notation of clients that can select the outbound, e.g., "10.0.0.0/8". Only
+	// clients on loopback addresses can if empty.
+	TrustedNetwork []string `protobuf:"bytes,1,rep,name=trusted_network,json=trustedNetwork" json:"trusted_network,omitempty"`
+}
+
+func (m *OutboundSelectionConfig) Reset()                    { *m = OutboundSelectionConfig{} }
+func (m *OutboundSelectionConfig) String() string            { return proto.CompactTextString(m) }
+func (*OutboundSelectionConfig) ProtoMessage()               {}
+func (*OutboundSelectionConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func init() {
+	proto.RegisterType((*OutboundSelectionConfig)(nil), "v2ray.core.proxy.OutboundSelectionConfig")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/proxy/config.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 150 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x52, 0x2c, 0x33, 0x2a, 0x4a,
+	0xac, 0xd4, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xce, 0x2f, 0x4a, 0xd5, 0x2f, 0x28, 0xca, 0xaf, 0xa8,
+	0xd4, 0x4f, 0xce, 0xcf, 0x4b, 0xcb, 0x4c, 0xd7, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x12, 0x80,
+	0x29, 0x29, 0x4a, 0xd5, 0x03, 0x4b, 0x2b, 0x39, 0x71, 0x89, 0xfb, 0x97, 0x96, 0x24, 0xe5, 0x97,
+	0xe6, 0xa5, 0x04, 0xa7, 0xe6, 0xa4, 0x26, 0x97, 0x64, 0xe6, 0xe7, 0x39, 0x83, 0xb5, 0x08, 0xa9,
+	0x73, 0xf1, 0x97, 0x14, 0x95, 0x16, 0x97, 0xa4, 0xa6, 0xc4, 0xe7, 0xa5, 0x96, 0x94, 0xe7, 0x17,
+	0x65, 0x4b, 0x30, 0x2a, 0x30, 0x6b, 0x70, 0x06, 0xf1, 0x41, 0x85, 0xfd, 0x20, 0xa2, 0x4e, 0x5a,
+	0x5c, 0x22, 0xc9, 0xf9, 0xb9, 0x7a, 0xe8, 0x66, 0x3b, 0x71, 0x43, 0x0c, 0x0a, 0x00, 0x59, 0x1d,
+	0xc5, 0x0a, 0x16, 0x4b, 0x62, 0x03, 0x3b, 0xc4, 0x18, 0x10, 0x00, 0x00, 0xff, 0xff, 0x79, 0x64,
+	0xea, 0x1d, 0xad, 0x00, 0x00, 0x00,
+}
@@ -0,0 +1,23 @@
+package dnsserver
+
+import (
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	DefaultTTL = 60
+)
+
+func (this *Config) GetEffectiveTTL() uint32 {
+	if this.Ttl == 0 {
+		return DefaultTTL
+	}
+	return this.Ttl
+}
+
+func (this *Config) HasNetwork(network v2net.Network) bool {
+	if this.NetworkList == nil || len(this.NetworkList.Network) == 0 {
+		return true
+	}
+	return this.NetworkList.HasNetwork(network)
+}
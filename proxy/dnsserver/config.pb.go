@@ -0,0 +1,74 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/proxy/dnsserver/config.proto
+// DO NOT EDIT!
+
+/*
+Package dnsserver is a generated protocol buffer package.
+
+It is generated from these files:
+	v2ray.com/core/proxy/dnsserver/config.proto
+
+It has these top-level messages:
+	Config
+*/
+package dnsserver
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import v2ray_core_common_net "v2ray.com/core/common/net"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// A DNS server that answers A and AAAA queries with the DNS app, including its static hosts, cache and
+// name servers for specific domains.
+type Config struct {
+	// Networks to serve DNS on. Both TCP and UDP if not set.
+	NetworkList *v2ray_core_common_net.NetworkList `protobuf:"bytes,1,opt,name=network_list,json=networkList" json:"network_list,omitempty"`
+	// TTL in seconds of answers. Default to 60.
+	Ttl uint32 `protobuf:"varint,2,opt,name=ttl" json:"ttl,omitempty"`
+}
+
+func (m *Config) Reset()                    { *m = Config{} }
+func (m *Config) String() string            { return proto.CompactTextString(m) }
+func (*Config) ProtoMessage()               {}
+func (*Config) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *Config) GetNetworkList() *v2ray_core_common_net.NetworkList {
+	if m != nil {
+		return m.NetworkList
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Config)(nil), "v2ray.core.proxy.dnsserver.Config")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/proxy/dnsserver/config.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 196 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xd2, 0x2e, 0x33, 0x2a, 0x4a,
+	0xac, 0xd4, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xce, 0x2f, 0x4a, 0xd5, 0x2f, 0x28, 0xca, 0xaf, 0xa8,
+	0xd4, 0x4f, 0xc9, 0x2b, 0x2e, 0x4e, 0x2d, 0x2a, 0x4b, 0x2d, 0xd2, 0x4f, 0xce, 0xcf, 0x4b, 0xcb,
+	0x4c, 0xd7, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x92, 0x82, 0x29, 0x2e, 0x4a, 0xd5, 0x03, 0x2b,
+	0xd4, 0x83, 0x2b, 0x94, 0x52, 0x47, 0x33, 0x28, 0x39, 0x3f, 0x37, 0x37, 0x3f, 0x4f, 0x3f, 0x2f,
+	0xb5, 0x04, 0x84, 0xcb, 0xf3, 0x8b, 0xb2, 0x21, 0x86, 0x28, 0x25, 0x72, 0xb1, 0x39, 0x83, 0x0d,
+	0x15, 0x72, 0xe5, 0xe2, 0x81, 0x4a, 0xc5, 0xe7, 0x64, 0x16, 0x97, 0x48, 0x30, 0x2a, 0x30, 0x6a,
+	0x70, 0x1b, 0x29, 0xe9, 0x21, 0xd9, 0x02, 0x31, 0x45, 0x2f, 0x2f, 0xb5, 0x44, 0xcf, 0x0f, 0xa2,
+	0xd4, 0x27, 0xb3, 0xb8, 0x24, 0x88, 0x3b, 0x0f, 0xc1, 0x11, 0x12, 0xe0, 0x62, 0x2e, 0x29, 0xc9,
+	0x91, 0x60, 0x52, 0x60, 0xd4, 0xe0, 0x0d, 0x02, 0x31, 0x9d, 0x2c, 0xb8, 0xe4, 0x92, 0xf3, 0x73,
+	0xf5, 0x70, 0xbb, 0xd6, 0x89, 0x1b, 0xe2, 0x84, 0x80, 0xa2, 0xfc, 0x92, 0xfc, 0x28, 0x4e, 0xb8,
+	0x78, 0x12, 0x1b, 0xd8, 0x8d, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0xde, 0x68, 0x57, 0x0b,
+	0x17, 0x01, 0x00, 0x00,
+}
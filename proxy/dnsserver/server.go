@@ -0,0 +1,269 @@
+package dnsserver
+
+import (
+	"io"
+	"sync"
+
+	"v2ray.com/core/app"
+	appdns "v2ray.com/core/app/dns"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/loader"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/serial"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/proxy/registry"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/udp"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// Maximum size of UDP responses without EDNS0.
+	minUDPSize = 512
+	// UDP payload size advertised to EDNS0 clients.
+	maxUDPSize = 4096
+
+	// Seconds to wait for the next query on an idle TCP connection.
+	tcpIdleTimeout = 16
+)
+
+type Server struct {
+	sync.Mutex
+	config      *Config
+	meta        *proxy.InboundHandlerMeta
+	resolver    appdns.Server
+	accepting   bool
+	tcpListener *internet.TCPHub
+	udpHub      *udp.UDPHub
+}
+
+func NewServer(config *Config, space app.Space, meta *proxy.InboundHandlerMeta) *Server {
+	server := &Server{
+		config: config,
+		meta:   meta,
+	}
+	space.InitializeApplication(func() error {
+		if !space.HasApp(appdns.APP_ID) {
+			log.Error("DNS|Server: DNS app is not found in the space.")
+			return app.ErrMissingApplication
+		}
+		server.resolver = space.GetApp(appdns.APP_ID).(appdns.Server)
+		return nil
+	})
+	return server
+}
+
+func (this *Server) Port() v2net.Port {
+	return this.meta.Port
+}
+
+func (this *Server) Close() {
+	this.Lock()
+	defer this.Unlock()
+
+	this.accepting = false
+	if this.tcpListener != nil {
+		this.tcpListener.Close()
+		this.tcpListener = nil
+	}
+	if this.udpHub != nil {
+		this.udpHub.Close()
+		this.udpHub = nil
+	}
+}
+
+func (this *Server) Start() error {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.accepting {
+		return nil
+	}
+
+	if this.config.HasNetwork(v2net.Network_TCP) {
+		tcpListener, err := internet.ListenTCP(this.meta.Address, this.meta.Port, this.handleTCPConnection, this.meta.StreamSettings)
+		if err != nil {
+			log.Error("DNS|Server: Failed to listen TCP on ", this.meta.Address, ":", this.meta.Port, ": ", err)
+			return err
+		}
+		this.tcpListener = tcpListener
+	}
+	if this.config.HasNetwork(v2net.Network_UDP) {
+		udpHub, err := udp.ListenUDP(this.meta.Address, this.meta.Port, udp.ListenOption{
+			Callback: this.handleUDPPayload,
+		})
+		if err != nil {
+			log.Error("DNS|Server: Failed to listen UDP on ", this.meta.Address, ":", this.meta.Port, ": ", err)
+			if this.tcpListener != nil {
+				this.tcpListener.Close()
+				this.tcpListener = nil
+			}
+			return err
+		}
+		this.udpHub = udpHub
+	}
+	this.accepting = true
+	return nil
+}
+
+func (this *Server) handleUDPPayload(payload *alloc.Buffer, session *proxy.SessionInfo) {
+	defer payload.Release()
+
+	response := this.HandleQuery(payload.Value, true)
+	if response == nil {
+		return
+	}
+
+	this.Lock()
+	hub := this.udpHub
+	this.Unlock()
+	if hub != nil {
+		hub.WriteTo(response, session.Source)
+	}
+}
+
+func (this *Server) handleTCPConnection(conn internet.Connection) {
+	defer conn.Close()
+
+	reader := v2net.NewTimeOutReader(tcpIdleTimeout, conn)
+	defer reader.Release()
+
+	header := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return
+		}
+		query := make([]byte, serial.BytesToUint16(header))
+		if _, err := io.ReadFull(reader, query); err != nil {
+			log.Info("DNS|Server: Failed to read query from ", conn.RemoteAddr(), ": ", err)
+			return
+		}
+		response := this.HandleQuery(query, false)
+		if response == nil {
+			return
+		}
+		frame := serial.Uint16ToBytes(uint16(len(response)), make([]byte, 0, 2+len(response)))
+		if _, err := conn.Write(append(frame, response...)); err != nil {
+			return
+		}
+	}
+}
+
+// HandleQuery returns the packed response to a DNS query, or nil if the query is not a valid DNS message.
+// Responses over UDP are truncated to the size allowed by the client.
+func (this *Server) HandleQuery(rawQuery []byte, overUDP bool) []byte {
+	query := new(dns.Msg)
+	if err := query.Unpack(rawQuery); err != nil {
+		log.Info("DNS|Server: Ignoring invalid query: ", err)
+		return nil
+	}
+	if query.Response {
+		return nil
+	}
+
+	response := this.buildResponse(query)
+
+	maxSize := dns.MaxMsgSize
+	if overUDP {
+		maxSize = minUDPSize
+		if opt := query.IsEdns0(); opt != nil && int(opt.UDPSize()) > maxSize {
+			maxSize = int(opt.UDPSize())
+			if maxSize > maxUDPSize {
+				maxSize = maxUDPSize
+			}
+		}
+	}
+	rawResponse, err := response.Pack()
+	if err == nil && len(rawResponse) > maxSize {
+		rawResponse, err = truncate(response, maxSize)
+	}
+	if err != nil {
+		log.Warning("DNS|Server: Failed to pack response: ", err)
+		return nil
+	}
+	return rawResponse
+}
+
+func (this *Server) buildResponse(query *dns.Msg) *dns.Msg {
+	response := new(dns.Msg)
+	response.SetReply(query)
+	response.RecursionAvailable = true
+	if query.IsEdns0() != nil {
+		response.SetEdns0(maxUDPSize, false)
+	}
+
+	if query.Opcode != dns.OpcodeQuery || len(query.Question) != 1 {
+		response.Rcode = dns.RcodeNotImplemented
+		return response
+	}
+	question := query.Question[0]
+	if question.Qclass != dns.ClassINET || (question.Qtype != dns.TypeA && question.Qtype != dns.TypeAAAA) {
+		response.Rcode = dns.RcodeNotImplemented
+		return response
+	}
+
+	domain := question.Name
+	ips := this.resolver.GetFor(domain, this.meta.Tag)
+	if len(ips) == 0 {
+		log.Info("DNS|Server: Failed to resolve ", domain)
+		response.Rcode = dns.RcodeServerFailure
+		return response
+	}
+
+	ttl := this.config.GetEffectiveTTL()
+	for _, ip := range ips {
+		header := dns.RR_Header{
+			Name:   domain,
+			Rrtype: question.Qtype,
+			Class:  dns.ClassINET,
+			Ttl:    ttl,
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			if question.Qtype == dns.TypeA {
+				response.Answer = append(response.Answer, &dns.A{Hdr: header, A: ip4})
+			}
+		} else if question.Qtype == dns.TypeAAAA {
+			response.Answer = append(response.Answer, &dns.AAAA{Hdr: header, AAAA: ip.To16()})
+		}
+	}
+	// The domain exists, but may have no address of the queried type.
+	log.Debug("DNS|Server: Answering ", len(response.Answer), " records for ", domain)
+	return response
+}
+
+// truncate removes answers from response until it fits into maxSize bytes, and sets the TC bit to tell
+// the client to retry over TCP.
+func truncate(response *dns.Msg, maxSize int) ([]byte, error) {
+	opt := response.IsEdns0()
+	response.Truncated = true
+	response.Ns = nil
+	response.Extra = nil
+	if opt != nil {
+		response.Extra = []dns.RR{opt}
+	}
+	for {
+		rawResponse, err := response.Pack()
+		if err != nil || len(rawResponse) <= maxSize || len(response.Answer) == 0 {
+			return rawResponse, err
+		}
+		response.Answer = response.Answer[:len(response.Answer)-1]
+	}
+}
+
+type Factory struct{}
+
+func (this *Factory) StreamCapability() v2net.NetworkList {
+	return v2net.NetworkList{
+		Network: []v2net.Network{v2net.Network_RawTCP},
+	}
+}
+
+func (this *Factory) Create(space app.Space, rawConfig interface{}, meta *proxy.InboundHandlerMeta) (proxy.InboundHandler, error) {
+	return NewServer(rawConfig.(*Config), space, meta), nil
+}
+
+func init() {
+	registry.MustRegisterInboundHandlerCreator(loader.GetType(new(Config)), new(Factory))
+}
@@ -0,0 +1,156 @@
+package dnsserver_test
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app"
+	appdns "v2ray.com/core/app/dns"
+	"v2ray.com/core/common/dice"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/serial"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/dnsserver"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	_ "v2ray.com/core/transport/internet/tcp"
+
+	"github.com/miekg/dns"
+)
+
+type staticResolver struct {
+	ips map[string][]net.IP
+}
+
+func (this *staticResolver) Get(domain string) []net.IP {
+	return this.ips[dns.Fqdn(domain)]
+}
+
+func (this *staticResolver) GetFor(domain string, requester string) []net.IP {
+	return this.Get(domain)
+}
+
+func (this *staticResolver) Release() {}
+
+func startServer(assert *assert.Assert) v2net.Port {
+	many := make([]net.IP, 0, 64)
+	for i := 0; i < 64; i++ {
+		many = append(many, net.IP{10, 0, 1, byte(i)})
+	}
+	space := app.NewSpace()
+	space.BindApp(appdns.APP_ID, &staticResolver{
+		ips: map[string][]net.IP{
+			"www.v2ray.com.":  {net.IP{10, 0, 0, 1}, net.IPv6loopback},
+			"many.v2ray.com.": many,
+		},
+	})
+
+	port := v2net.Port(dice.Roll(20000) + 10000)
+	server := NewServer(&Config{}, space, &proxy.InboundHandlerMeta{
+		Address: v2net.LocalHostIP,
+		Port:    port,
+		StreamSettings: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+	})
+	assert.Error(space.Initialize()).IsNil()
+	assert.Error(server.Start()).IsNil()
+	return port
+}
+
+func newQuery(domain string, qtype uint16, udpSize uint16) []byte {
+	query := new(dns.Msg)
+	query.SetQuestion(domain, qtype)
+	if udpSize > 0 {
+		query.SetEdns0(udpSize, false)
+	}
+	payload, _ := query.Pack()
+	return payload
+}
+
+func queryUDP(assert *assert.Assert, port v2net.Port, query []byte) (*dns.Msg, int) {
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IP{127, 0, 0, 1}, Port: int(port)})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	_, err = conn.Write(query)
+	assert.Error(err).IsNil()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buffer := make([]byte, 65536)
+	nBytes, err := conn.Read(buffer)
+	assert.Error(err).IsNil()
+	response := new(dns.Msg)
+	if err := response.Unpack(buffer[:nBytes]); err != dns.ErrTruncated {
+		assert.Error(err).IsNil()
+	}
+	return response, nBytes
+}
+
+func TestUDPQuery(t *testing.T) {
+	assert := assert.On(t)
+
+	port := startServer(assert)
+
+	response, _ := queryUDP(assert, port, newQuery("www.v2ray.com.", dns.TypeA, 0))
+	assert.Int(response.Rcode).Equals(dns.RcodeSuccess)
+	assert.Int(len(response.Answer)).Equals(1)
+	assert.IP(response.Answer[0].(*dns.A).A.To4()).Equals(net.IP{10, 0, 0, 1})
+
+	response, _ = queryUDP(assert, port, newQuery("www.v2ray.com.", dns.TypeAAAA, 0))
+	assert.Int(len(response.Answer)).Equals(1)
+	assert.IP(response.Answer[0].(*dns.AAAA).AAAA).Equals(net.IPv6loopback)
+
+	response, _ = queryUDP(assert, port, newQuery("unknown.v2ray.com.", dns.TypeA, 0))
+	assert.Int(response.Rcode).Equals(dns.RcodeServerFailure)
+
+	response, _ = queryUDP(assert, port, newQuery("www.v2ray.com.", dns.TypeMX, 0))
+	assert.Int(response.Rcode).Equals(dns.RcodeNotImplemented)
+}
+
+func TestUDPTruncation(t *testing.T) {
+	assert := assert.On(t)
+
+	port := startServer(assert)
+
+	response, size := queryUDP(assert, port, newQuery("many.v2ray.com.", dns.TypeA, 0))
+	assert.Bool(response.Truncated).IsTrue()
+	assert.Int(size).LessThan(513)
+	assert.Bool(len(response.Answer) < 64).IsTrue()
+
+	response, size = queryUDP(assert, port, newQuery("many.v2ray.com.", dns.TypeA, 4096))
+	assert.Bool(response.Truncated).IsFalse()
+	assert.Int(size).GreaterThan(512)
+	assert.Int(len(response.Answer)).Equals(64)
+	assert.Pointer(response.IsEdns0()).IsNotNil()
+}
+
+func TestTCPQuery(t *testing.T) {
+	assert := assert.On(t)
+
+	port := startServer(assert)
+
+	conn, err := net.Dial("tcp", (&net.TCPAddr{IP: net.IP{127, 0, 0, 1}, Port: int(port)}).String())
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	// Two queries on the same connection.
+	for i := 0; i < 2; i++ {
+		query := newQuery("many.v2ray.com.", dns.TypeA, 0)
+		_, err = conn.Write(append(serial.Uint16ToBytes(uint16(len(query)), nil), query...))
+		assert.Error(err).IsNil()
+
+		header := make([]byte, 2)
+		_, err = io.ReadFull(conn, header)
+		assert.Error(err).IsNil()
+		payload := make([]byte, serial.BytesToUint16(header))
+		_, err = io.ReadFull(conn, payload)
+		assert.Error(err).IsNil()
+
+		response := new(dns.Msg)
+		assert.Error(response.Unpack(payload)).IsNil()
+		assert.Bool(response.Truncated).IsFalse()
+		assert.Int(len(response.Answer)).Equals(64)
+	}
+}
@@ -30,12 +30,38 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// Destination of connections, after the first payload is sniffed.
+type DestinationOverride int32
+
+const (
+	// Destination is not changed.
+	DestinationOverride_None DestinationOverride = 0
+	// Destination is changed to the domain in HTTP Host header or TLS SNI, if there is one. The port is
+	// not changed.
+	DestinationOverride_SniffedDomain DestinationOverride = 1
+)
+
+var DestinationOverride_name = map[int32]string{
+	0: "None",
+	1: "SniffedDomain",
+}
+var DestinationOverride_value = map[string]int32{
+	"None":          0,
+	"SniffedDomain": 1,
+}
+
+func (x DestinationOverride) String() string {
+	return proto.EnumName(DestinationOverride_name, int32(x))
+}
+func (DestinationOverride) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
 type Config struct {
-	Address        *v2ray_core_common_net.IPOrDomain   `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
-	Port           uint32                              `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
-	NetworkList    *v2ray_core_common_net1.NetworkList `protobuf:"bytes,3,opt,name=network_list,json=networkList" json:"network_list,omitempty"`
-	Timeout        uint32                              `protobuf:"varint,4,opt,name=timeout" json:"timeout,omitempty"`
-	FollowRedirect bool                                `protobuf:"varint,5,opt,name=follow_redirect,json=followRedirect" json:"follow_redirect,omitempty"`
+	Address             *v2ray_core_common_net.IPOrDomain   `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Port                uint32                              `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+	NetworkList         *v2ray_core_common_net1.NetworkList `protobuf:"bytes,3,opt,name=network_list,json=networkList" json:"network_list,omitempty"`
+	Timeout             uint32                              `protobuf:"varint,4,opt,name=timeout" json:"timeout,omitempty"`
+	FollowRedirect      bool                                `protobuf:"varint,5,opt,name=follow_redirect,json=followRedirect" json:"follow_redirect,omitempty"`
+	DestinationOverride DestinationOverride                 `protobuf:"varint,6,opt,name=destination_override,json=destinationOverride,enum=v2ray.core.proxy.dokodemo.DestinationOverride" json:"destination_override,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
@@ -59,28 +85,33 @@ func (m *Config) GetNetworkList() *v2ray_core_common_net1.NetworkList {
 
 func init() {
 	proto.RegisterType((*Config)(nil), "v2ray.core.proxy.dokodemo.Config")
+	proto.RegisterEnum("v2ray.core.proxy.dokodemo.DestinationOverride", DestinationOverride_name, DestinationOverride_value)
 }
 
 func init() { proto.RegisterFile("v2ray.com/core/proxy/dokodemo/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 275 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x7c, 0x90, 0x4f, 0x4b, 0x33, 0x31,
-	0x10, 0xc6, 0xd9, 0xf7, 0xad, 0x6d, 0xc9, 0xfa, 0x07, 0x72, 0x8a, 0x82, 0xb0, 0xf6, 0xd2, 0xc5,
-	0x43, 0x02, 0x15, 0xbc, 0x78, 0xab, 0x7a, 0x10, 0x44, 0xcb, 0x1e, 0xbd, 0x94, 0x35, 0x9b, 0x4a,
-	0xe8, 0x26, 0xb3, 0xcc, 0x8e, 0xd6, 0x7e, 0x5e, 0xbf, 0x88, 0x98, 0xec, 0xa2, 0x08, 0xf5, 0x96,
-	0x79, 0xf8, 0xe5, 0x37, 0xc3, 0xc3, 0xce, 0xdf, 0x66, 0x58, 0x6e, 0xa5, 0x06, 0xa7, 0x34, 0xa0,
-	0x51, 0x0d, 0xc2, 0xfb, 0x56, 0x55, 0xb0, 0x86, 0xca, 0x38, 0x50, 0x1a, 0xfc, 0xca, 0xbe, 0xc8,
-	0x06, 0x81, 0x80, 0x1f, 0xf7, 0x2c, 0x1a, 0x19, 0x38, 0xd9, 0x73, 0x27, 0xd3, 0x5f, 0x1a, 0x0d,
-	0xce, 0x81, 0x57, 0xde, 0x90, 0x2a, 0xab, 0x0a, 0x4d, 0xdb, 0x46, 0xc7, 0x5f, 0xa0, 0x37, 0xb4,
-	0x01, 0x5c, 0x47, 0x70, 0xf2, 0x91, 0xb0, 0xe1, 0x75, 0xd8, 0xce, 0xaf, 0xd8, 0xa8, 0x93, 0x88,
-	0x24, 0x4b, 0xf2, 0x74, 0x76, 0x26, 0x7f, 0x5c, 0x12, 0x0d, 0xd2, 0x1b, 0x92, 0x77, 0x8b, 0x47,
-	0xbc, 0x01, 0x57, 0x5a, 0x5f, 0xf4, 0x3f, 0x38, 0x67, 0x83, 0x06, 0x90, 0xc4, 0xbf, 0x2c, 0xc9,
-	0x0f, 0x8a, 0xf0, 0xe6, 0xb7, 0x6c, 0xbf, 0x5b, 0xb6, 0xac, 0x6d, 0x4b, 0xe2, 0x7f, 0xb0, 0x4e,
-	0x76, 0x58, 0x1f, 0x22, 0x7a, 0x6f, 0x5b, 0x2a, 0x52, 0xff, 0x3d, 0x70, 0xc1, 0x46, 0x64, 0x9d,
-	0x81, 0x57, 0x12, 0x83, 0x60, 0xef, 0x47, 0x3e, 0x65, 0x47, 0x2b, 0xa8, 0x6b, 0xd8, 0x2c, 0xd1,
-	0x54, 0x16, 0x8d, 0x26, 0xb1, 0x97, 0x25, 0xf9, 0xb8, 0x38, 0x8c, 0x71, 0xd1, 0xa5, 0xf3, 0x4b,
-	0x76, 0xaa, 0xc1, 0xc9, 0x9d, 0xc5, 0xce, 0xd3, 0xd8, 0xc1, 0xe2, 0xab, 0x93, 0xa7, 0x71, 0x1f,
-	0x3f, 0x0f, 0x43, 0x49, 0x17, 0x9f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x21, 0x97, 0x31, 0xca, 0xbf,
-	0x01, 0x00, 0x00,
+	// 337 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x7c, 0x91, 0x41, 0x4f, 0xf2, 0x40,
+	0x10, 0x86, 0xbf, 0xf2, 0x21, 0x90, 0x45, 0x10, 0x17, 0x0f, 0xd5, 0xc4, 0xa4, 0x72, 0xa1, 0xe1,
+	0xb0, 0x4d, 0x6a, 0xe2, 0xc5, 0x1b, 0xe2, 0xc1, 0xc4, 0x00, 0xa9, 0x37, 0x2f, 0xa4, 0x76, 0xa7,
+	0x66, 0x03, 0xdd, 0x21, 0xd3, 0x15, 0xe4, 0x0f, 0xfa, 0xbb, 0x4c, 0xba, 0x6d, 0x30, 0x2a, 0xde,
+	0x76, 0xde, 0x3c, 0xf3, 0xcc, 0x66, 0x86, 0x8d, 0x36, 0x21, 0xc5, 0x3b, 0x91, 0x60, 0x16, 0x24,
+	0x48, 0x10, 0xac, 0x09, 0xdf, 0x77, 0x81, 0xc4, 0x25, 0x4a, 0xc8, 0x30, 0x48, 0x50, 0xa7, 0xea,
+	0x55, 0xac, 0x09, 0x0d, 0xf2, 0xf3, 0x8a, 0x25, 0x10, 0x05, 0x27, 0x2a, 0xee, 0x62, 0xf8, 0x4d,
+	0x93, 0x60, 0x96, 0xa1, 0x0e, 0x34, 0x98, 0x20, 0x96, 0x92, 0x20, 0xcf, 0xad, 0xe3, 0x2f, 0x50,
+	0x83, 0xd9, 0x22, 0x2d, 0x2d, 0x38, 0xf8, 0xa8, 0xb1, 0xc6, 0x5d, 0x31, 0x9d, 0xdf, 0xb2, 0x66,
+	0x29, 0x71, 0x1d, 0xcf, 0xf1, 0xdb, 0xe1, 0x95, 0xf8, 0xf2, 0x13, 0x6b, 0x10, 0x1a, 0x8c, 0x78,
+	0x98, 0xcf, 0x68, 0x82, 0x59, 0xac, 0x74, 0x54, 0x75, 0x70, 0xce, 0xea, 0x6b, 0x24, 0xe3, 0xd6,
+	0x3c, 0xc7, 0xef, 0x44, 0xc5, 0x9b, 0xdf, 0xb3, 0xe3, 0x72, 0xd8, 0x62, 0xa5, 0x72, 0xe3, 0xfe,
+	0x2f, 0xac, 0x83, 0x03, 0xd6, 0xa9, 0x45, 0x1f, 0x55, 0x6e, 0xa2, 0xb6, 0xde, 0x17, 0xdc, 0x65,
+	0x4d, 0xa3, 0x32, 0xc0, 0x37, 0xe3, 0xd6, 0x0b, 0x7b, 0x55, 0xf2, 0x21, 0x3b, 0x49, 0x71, 0xb5,
+	0xc2, 0xed, 0x82, 0x40, 0x2a, 0x82, 0xc4, 0xb8, 0x47, 0x9e, 0xe3, 0xb7, 0xa2, 0xae, 0x8d, 0xa3,
+	0x32, 0xe5, 0x31, 0x3b, 0x93, 0x90, 0x1b, 0xa5, 0x63, 0xa3, 0x50, 0x2f, 0x70, 0x03, 0x44, 0x4a,
+	0x82, 0xdb, 0xf0, 0x1c, 0xbf, 0x1b, 0x0a, 0x71, 0x70, 0xe3, 0x62, 0xb2, 0x6f, 0x9b, 0x95, 0x5d,
+	0x51, 0x5f, 0xfe, 0x0c, 0x47, 0x21, 0xeb, 0xff, 0xc2, 0xf2, 0x16, 0xab, 0x4f, 0x51, 0x43, 0xef,
+	0x1f, 0x3f, 0x65, 0x9d, 0x27, 0xad, 0xd2, 0x14, 0xa4, 0xdd, 0x5d, 0xcf, 0x19, 0xdf, 0xb0, 0xcb,
+	0x04, 0xb3, 0xc3, 0xd3, 0xc7, 0x6d, 0x7b, 0x9a, 0x39, 0xa1, 0xc1, 0xe7, 0x56, 0x15, 0xbf, 0x34,
+	0x8a, 0xdb, 0x5d, 0x7f, 0x06, 0x00, 0x00, 0xff, 0xff, 0x20, 0xda, 0x87, 0x5b, 0x56, 0x02, 0x00,
+	0x00,
 }
@@ -1,7 +1,9 @@
 package dokodemo
 
 import (
+	"net"
 	"sync"
+	"time"
 
 	"v2ray.com/core/app"
 	"v2ray.com/core/app/dispatcher"
@@ -16,6 +18,12 @@ import (
 	"v2ray.com/core/transport/internet/udp"
 )
 
+const (
+	// Time to wait for the first payload to sniff. Connections in which server speaks first are forwarded
+	// to the original destination after this.
+	sniffTimeout = 2 * time.Second
+)
+
 type DokodemoDoor struct {
 	tcpMutex         sync.RWMutex
 	udpMutex         sync.RWMutex
@@ -159,6 +167,22 @@ func (this *DokodemoDoor) HandleTCPConnection(conn internet.Connection) {
 		log.Info("Dokodemo: Unknown destination, stop forwarding...")
 		return
 	}
+
+	var firstPayload *alloc.Buffer
+	if this.config.DestinationOverride == DestinationOverride_SniffedDomain && !dest.Address.Family().IsDomain() {
+		var err error
+		firstPayload, err = readFirstPayload(conn)
+		if err != nil {
+			log.Info("Dokodemo: Failed to read first payload: ", err)
+			return
+		}
+		if firstPayload != nil {
+			if domain, found := proxy.SniffDomain(firstPayload.Value); found {
+				log.Info("Dokodemo: Sniffed domain ", domain, " for ", dest)
+				dest = v2net.TCPDestination(v2net.DomainAddress(domain), dest.Port)
+			}
+		}
+	}
 	log.Info("Dokodemo: Handling request to ", dest)
 
 	ray := this.packetDispatcher.DispatchToOutbound(&proxy.SessionInfo{
@@ -175,6 +199,14 @@ func (this *DokodemoDoor) HandleTCPConnection(conn internet.Connection) {
 
 	wg.Add(1)
 	go func() {
+		if firstPayload != nil {
+			if err := ray.InboundInput().Write(firstPayload); err != nil {
+				wg.Done()
+				ray.InboundInput().Close()
+				return
+			}
+		}
+
 		v2reader := v2io.NewAdaptiveReader(reader)
 		defer v2reader.Release()
 
@@ -195,6 +227,24 @@ func (this *DokodemoDoor) HandleTCPConnection(conn internet.Connection) {
 	wg.Wait()
 }
 
+// readFirstPayload reads the first payload from conn to sniff. It returns nil payload without error if
+// client sends nothing in time.
+func readFirstPayload(conn internet.Connection) (*alloc.Buffer, error) {
+	buffer := alloc.NewBuffer()
+	conn.SetReadDeadline(time.Now().Add(sniffTimeout))
+	nBytes, err := conn.Read(buffer.Value)
+	conn.SetReadDeadline(time.Time{})
+	if nBytes > 0 {
+		buffer.Slice(0, nBytes)
+		return buffer, nil
+	}
+	buffer.Release()
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return nil, nil
+	}
+	return nil, err
+}
+
 type Factory struct{}
 
 func (this *Factory) StreamCapability() v2net.NetworkList {
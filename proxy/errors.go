@@ -2,10 +2,77 @@ package proxy
 
 import (
 	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"v2ray.com/core/transport/internet"
 )
 
 var (
 	ErrInvalidAuthentication  = errors.New("Invalid authentication.")
 	ErrInvalidProtocolVersion = errors.New("Invalid protocol version.")
 	ErrAlreadyListening       = errors.New("Already listening on another port.")
+	ErrConnectionRejected     = errors.New("Connection rejected.")
+	ErrNetworkUnsupported     = errors.New("Network of destination is not supported by outbound.")
+	ErrIPv6Unreachable        = errors.New("IPv6 destination is not reachable by outbound.")
 )
+
+// FailureReason is the reason that an outbound fails to connect, for inbounds to report to clients.
+type FailureReason int
+
+const (
+	FailureGeneral FailureReason = iota
+	FailureRejected
+	FailureNetworkUnreachable
+	FailureHostUnreachable
+	FailureConnectionRefused
+	FailureTimeout
+)
+
+// GetFailureReason returns the reason of an error from outbound.
+func GetFailureReason(err error) FailureReason {
+	if layerErr, ok := err.(*internet.LayerError); ok {
+		err = layerErr.Err
+	}
+	switch err {
+	case ErrConnectionRejected:
+		return FailureRejected
+	case ErrNetworkUnsupported, ErrIPv6Unreachable:
+		return FailureNetworkUnreachable
+	case internet.ErrDomainNotResolved:
+		return FailureHostUnreachable
+	}
+	for {
+		switch typedErr := err.(type) {
+		case *net.OpError:
+			if typedErr.Timeout() {
+				return FailureTimeout
+			}
+			err = typedErr.Err
+		case *os.SyscallError:
+			err = typedErr.Err
+		case *net.DNSError:
+			return FailureHostUnreachable
+		case syscall.Errno:
+			switch typedErr {
+			case syscall.ECONNREFUSED:
+				return FailureConnectionRefused
+			case syscall.EHOSTUNREACH:
+				return FailureHostUnreachable
+			case syscall.ENETUNREACH:
+				return FailureNetworkUnreachable
+			case syscall.ETIMEDOUT:
+				return FailureTimeout
+			}
+			return FailureGeneral
+		case net.Error:
+			if typedErr.Timeout() {
+				return FailureTimeout
+			}
+			return FailureGeneral
+		default:
+			return FailureGeneral
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package proxy_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	. "v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+)
+
+func TestFailureReason(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	address := listener.Addr().String()
+	listener.Close()
+	_, err = net.Dial("tcp", address)
+	assert.Error(err).IsNotNil()
+	assert.Bool(GetFailureReason(err) == FailureConnectionRefused).IsTrue()
+
+	assert.Bool(GetFailureReason(ErrConnectionRejected) == FailureRejected).IsTrue()
+	assert.Bool(GetFailureReason(internet.ErrDomainNotResolved) == FailureHostUnreachable).IsTrue()
+	assert.Bool(GetFailureReason(&net.DNSError{Err: "no such host", Name: "v2ray.invalid"}) == FailureHostUnreachable).IsTrue()
+	assert.Bool(GetFailureReason(errors.New("unknown")) == FailureGeneral).IsTrue()
+}
@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/ray"
+)
+
+// flowControlledOutboundHandler limits bytes in flight of all connections of an outbound. When the limit is
+// reached, writers of the connections wait until readers catch up, so the faster side stops reading.
+type flowControlledOutboundHandler struct {
+	handler OutboundHandler
+	limiter *ray.InFlightLimiter
+}
+
+// NewFlowControlledOutboundHandler returns handler with at most maxBytes in flight in its connections,
+// in both directions. Bytes read before the session reaches the handler, such as the first payload, are not
+// counted.
+func NewFlowControlledOutboundHandler(handler OutboundHandler, maxBytes int64) OutboundHandler {
+	return &flowControlledOutboundHandler{
+		handler: handler,
+		limiter: ray.NewInFlightLimiter(maxBytes),
+	}
+}
+
+func (this *flowControlledOutboundHandler) Dispatch(destination v2net.Destination, payload *alloc.Buffer, link ray.OutboundRay) error {
+	ray.LimitInFlight(link, this.limiter)
+	return this.handler.Dispatch(destination, payload, link)
+}
+
+func (this *flowControlledOutboundHandler) DispatchSession(session *SessionInfo, payload *alloc.Buffer, link ray.OutboundRay) error {
+	ray.LimitInFlight(link, this.limiter)
+	return DispatchSession(this.handler, session, payload, link)
+}
+
+func (this *flowControlledOutboundHandler) Close() {
+	CloseOutboundHandler(this.handler)
+}
+
+func (this *flowControlledOutboundHandler) Describe() interface{} {
+	if describable, ok := this.handler.(DescribableOutboundHandler); ok {
+		return describable.Describe()
+	}
+	return nil
+}
+
+func (this *flowControlledOutboundHandler) SupportsNetwork(network v2net.Network) bool {
+	return SupportsNetwork(this.handler, network)
+}
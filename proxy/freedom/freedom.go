@@ -51,7 +51,7 @@ func (this *FreedomConnection) ResolveIP(destination v2net.Destination) v2net.De
 		return destination
 	}
 
-	ips := this.dns.Get(destination.Address.Domain())
+	ips := this.dns.GetFor(destination.Address.Domain(), this.meta.Tag)
 	if len(ips) == 0 {
 		log.Info("Freedom: DNS returns nil answer. Keep domain as is.")
 		return destination
@@ -69,6 +69,15 @@ func (this *FreedomConnection) ResolveIP(destination v2net.Destination) v2net.De
 }
 
 func (this *FreedomConnection) Dispatch(destination v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error {
+	return this.dispatch(destination, this.meta.GetDialerOptions(), payload, ray)
+}
+
+// DispatchSession implements SessionOutboundHandler.DispatchSession().
+func (this *FreedomConnection) DispatchSession(session *proxy.SessionInfo, payload *alloc.Buffer, ray ray.OutboundRay) error {
+	return this.dispatch(session.Destination, this.meta.GetSessionDialerOptions(session), payload, ray)
+}
+
+func (this *FreedomConnection) dispatch(destination v2net.Destination, options internet.DialerOptions, payload *alloc.Buffer, ray ray.OutboundRay) error {
 	log.Info("Freedom: Opening connection to ", destination)
 
 	defer payload.Release()
@@ -76,22 +85,42 @@ func (this *FreedomConnection) Dispatch(destination v2net.Destination, payload *
 	defer ray.OutboundOutput().Close()
 
 	var conn internet.Connection
+	requested := destination
 	if this.domainStrategy == Config_USE_IP && destination.Address.Family().IsDomain() {
 		destination = this.ResolveIP(destination)
 	}
+	var lastErr error
 	err := retry.Timed(5, 100).On(func() error {
-		rawConn, err := internet.Dial(this.meta.Address, destination, this.meta.GetDialerOptions())
+		rawConn, err := internet.Dial(this.meta.Address, destination, options)
 		if err != nil {
+			lastErr = err
 			return err
 		}
 		conn = rawConn
 		return nil
 	})
 	if err != nil {
-		log.Warning("Freedom: Failed to open connection to ", destination, ": ", err)
+		log.Warning("Freedom: Failed to open connection to ", destination, ": ", lastErr)
+		// The last error tells the inbound why the connection failed.
+		ray.OutboundOutput().CloseError(lastErr)
+		if this.meta.Hooks != nil {
+			this.onClose(requested, destination, nil, lastErr)
+		}
 		return err
 	}
 	defer conn.Close()
+	ray.OutboundOutput().Established()
+	tcpConn, isTCP := internet.UnwrapConnection(conn).(*tcp.RawConnection)
+	if hooks := this.meta.Hooks; hooks != nil {
+		hooked := &hookedConn{Connection: conn}
+		conn = hooked
+		hooks.OnOpen(&proxy.ConnectionEvent{
+			Tag:         this.meta.Tag,
+			Destination: requested,
+			Server:      destination,
+		})
+		defer this.onClose(requested, destination, hooked, nil)
+	}
 
 	input := ray.OutboundInput()
 	output := ray.OutboundOutput()
@@ -105,7 +134,7 @@ func (this *FreedomConnection) Dispatch(destination v2net.Destination, payload *
 		defer v2writer.Release()
 
 		v2io.Pipe(input, v2writer)
-		if tcpConn, ok := conn.(*tcp.RawConnection); ok {
+		if isTCP {
 			tcpConn.CloseWrite()
 		}
 	}()
@@ -128,6 +157,10 @@ func (this *FreedomConnection) Dispatch(destination v2net.Destination, payload *
 	return nil
 }
 
+func (this *FreedomConnection) SupportsNetwork(network v2net.Network) bool {
+	return this.meta.GetDialerOptions().SupportsNetwork(network)
+}
+
 type FreedomFactory struct{}
 
 func (this *FreedomFactory) StreamCapability() v2net.NetworkList {
@@ -1,6 +1,9 @@
 package freedom_test
 
 import (
+	"io/ioutil"
+	"net"
+	"sync"
 	"testing"
 
 	"v2ray.com/core/app"
@@ -114,3 +117,133 @@ func TestIPResolution(t *testing.T) {
 	assert.Destination(ipDest).IsTCP()
 	assert.Address(ipDest.Address).Equals(v2net.LocalHostIP)
 }
+
+// recordingHooks records names of events, and the event of OnClose.
+type recordingHooks struct {
+	sync.Mutex
+	names  []string
+	closed *proxy.ConnectionEvent
+}
+
+func (this *recordingHooks) record(name string, event *proxy.ConnectionEvent) {
+	this.Lock()
+	defer this.Unlock()
+	this.names = append(this.names, name)
+	if name == "close" {
+		this.closed = event
+	}
+}
+
+func (this *recordingHooks) OnOpen(event *proxy.ConnectionEvent) {
+	this.record("open", event)
+}
+
+func (this *recordingHooks) OnHandshake(event *proxy.ConnectionEvent) {
+	this.record("handshake", event)
+}
+
+func (this *recordingHooks) OnClose(event *proxy.ConnectionEvent) {
+	this.record("close", event)
+}
+
+func TestConnectionHooks(t *testing.T) {
+	assert := assert.On(t)
+
+	tcpServer := &tcp.Server{
+		MsgProcessor: func(data []byte) []byte {
+			return data
+		},
+	}
+	_, err := tcpServer.Start()
+	assert.Error(err).IsNil()
+	defer tcpServer.Close()
+
+	hooks := new(recordingHooks)
+	// The test server doesn't close connections, so the connection is closed by timeout.
+	freedom := NewFreedomConnection(
+		&Config{Timeout: 1},
+		app.NewSpace(),
+		&proxy.OutboundHandlerMeta{
+			Tag:     "direct",
+			Address: v2net.AnyIP,
+			StreamSettings: &internet.StreamConfig{
+				Network: v2net.Network_RawTCP,
+			},
+			Hooks: hooks,
+		})
+
+	traffic := ray.NewRay()
+	destination := v2net.TCPDestination(v2net.LocalHostIP, tcpServer.Port)
+	payload := alloc.NewLocalBuffer(2048).Clear().Append([]byte("ping"))
+	result := make(chan error, 1)
+	go func() {
+		result <- freedom.Dispatch(destination, payload, traffic)
+	}()
+	respPayload, err := traffic.InboundOutput().Read()
+	assert.Error(err).IsNil()
+	assert.Bytes(respPayload.Value).Equals([]byte("ping"))
+	traffic.InboundInput().Close()
+	assert.Error(<-result).IsNil()
+
+	hooks.Lock()
+	assert.Int(len(hooks.names)).Equals(2)
+	assert.String(hooks.names[0]).Equals("open")
+	assert.String(hooks.names[1]).Equals("close")
+	assert.String(hooks.closed.Tag).Equals("direct")
+	assert.Destination(hooks.closed.Server).EqualsString(destination.String())
+	assert.Int64(hooks.closed.Sent).Equals(4)
+	assert.Int64(hooks.closed.Received).Equals(4)
+	assert.Error(hooks.closed.Error).IsNil()
+	hooks.Unlock()
+
+	// Failed connections are also reported.
+	err = freedom.Dispatch(v2net.TCPDestination(v2net.LocalHostIP, 128), alloc.NewLocalBuffer(2048).Clear(), ray.NewRay())
+	assert.Error(err).IsNotNil()
+	assert.Error(hooks.closed.Error).IsNotNil()
+}
+
+func TestHalfClose(t *testing.T) {
+	assert := assert.On(t)
+
+	// The server responds only after the request ends.
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request, _ := ioutil.ReadAll(conn)
+		conn.Write(append([]byte("Processed: "), request...))
+	}()
+
+	space := app.NewSpace()
+	freedom := NewFreedomConnection(
+		&Config{},
+		space,
+		&proxy.OutboundHandlerMeta{
+			Address: v2net.AnyIP,
+			StreamSettings: &internet.StreamConfig{
+				Network: v2net.Network_RawTCP,
+			},
+		})
+	space.Initialize()
+
+	traffic := ray.NewRay()
+	payload := alloc.NewLocalBuffer(2048).Clear().AppendString("request")
+	go freedom.Dispatch(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(listener.Addr().(*net.TCPAddr).Port)), payload, traffic)
+	traffic.InboundInput().Close()
+
+	var response []byte
+	for {
+		buffer, err := traffic.InboundOutput().Read()
+		if err != nil {
+			break
+		}
+		response = append(response, buffer.Value...)
+		buffer.Release()
+	}
+	assert.String(string(response)).Equals("Processed: request")
+}
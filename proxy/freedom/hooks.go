@@ -0,0 +1,43 @@
+package freedom
+
+import (
+	"sync/atomic"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/internet"
+)
+
+// hookedConn counts bytes on a connection for hooks of the outbound. It is only used if there are hooks.
+type hookedConn struct {
+	internet.Connection
+	sent     int64
+	received int64
+}
+
+func (this *hookedConn) Read(b []byte) (int, error) {
+	nBytes, err := this.Connection.Read(b)
+	atomic.AddInt64(&this.received, int64(nBytes))
+	return nBytes, err
+}
+
+func (this *hookedConn) Write(b []byte) (int, error) {
+	nBytes, err := this.Connection.Write(b)
+	atomic.AddInt64(&this.sent, int64(nBytes))
+	return nBytes, err
+}
+
+// onClose calls OnClose of hooks with bytes on conn, which is nil if the connection failed.
+func (this *FreedomConnection) onClose(destination v2net.Destination, server v2net.Destination, conn *hookedConn, err error) {
+	event := &proxy.ConnectionEvent{
+		Tag:         this.meta.Tag,
+		Destination: destination,
+		Server:      server,
+		Error:       err,
+	}
+	if conn != nil {
+		event.Sent = atomic.LoadInt64(&conn.sent)
+		event.Received = atomic.LoadInt64(&conn.received)
+	}
+	this.meta.Hooks.OnClose(event)
+}
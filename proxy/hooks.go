@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"time"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+// ConnectionHooks receives lifecycle events of outbound connections, for embedders to build their own
+// accounting or alerting. Methods are called on the goroutine of the connection, so they should return
+// quickly.
+type ConnectionHooks interface {
+	// OnOpen is called when a connection to the server is established. It is called again for each server
+	// that a request fails over to.
+	OnOpen(event *ConnectionEvent)
+	// OnHandshake is called when the server responds to the request, for protocols with a handshake.
+	OnHandshake(event *ConnectionEvent)
+	// OnClose is called when the request finishes, including requests that fail before OnOpen.
+	OnClose(event *ConnectionEvent)
+}
+
+type ConnectionEvent struct {
+	// Tag of the outbound handler.
+	Tag         string
+	Destination v2net.Destination
+	// Server that the connection is sent to, or the destination itself for direct connections. It is
+	// empty if the request fails before a server is picked.
+	Server v2net.Destination
+	// Layers of the connection from the bottom, e.g., "tcp", "tls" and "shadowsocks", or nil if unknown.
+	Layers []string
+	// Layer that the request fails at, or empty if it is unknown, or the request doesn't fail at
+	// connecting or handshaking. Only set in OnClose.
+	FailedLayer string
+	// Bytes sent and received on the connection, and the error that the request fails with. They are only
+	// set in OnClose.
+	Sent     int64
+	Received int64
+	Error    error
+	// Time of each step to establish the connection, for handlers that measure them. Steps that are yet
+	// to happen are 0.
+	Timing ConnectionTiming
+}
+
+// ConnectionTiming is the time of each step to establish a connection. Steps that are skipped are 0.
+type ConnectionTiming struct {
+	// Time waiting for other dials to the same destination.
+	Queued time.Duration
+	// Time of failed dials before the successful one, including the time between them.
+	Retried time.Duration
+	// Time of the successful dial, including handshakes of transport layers, e.g., TLS.
+	Dial time.Duration
+	// Time from connecting until the request is sent, including waiting for other handshakes.
+	Request time.Duration
+	// Time from sending the request until the first byte of response. It includes the time for the server
+	// to reach the destination, and for the destination to respond.
+	Response time.Duration
+}
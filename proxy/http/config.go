@@ -1 +1,17 @@
 package http
+
+import (
+	"v2ray.com/core/proxy"
+)
+
+// BuildAuthenticator returns the authenticator for proxy authentication, or nil if clients are not
+// authenticated.
+func (this *ServerConfig) BuildAuthenticator() (proxy.Authenticator, error) {
+	if len(this.Authenticator) > 0 {
+		return proxy.GetAuthenticator(this.Authenticator)
+	}
+	if len(this.Accounts) > 0 {
+		return proxy.NewStaticAuthenticator(this.Accounts), nil
+	}
+	return nil, nil
+}
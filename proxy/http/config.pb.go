@@ -9,6 +9,7 @@ It is generated from these files:
 	v2ray.com/core/proxy/http/config.proto
 
 It has these top-level messages:
+	HeaderRule
 	ServerConfig
 	ClientConfig
 */
@@ -17,6 +18,7 @@ package http
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import v2ray_core_proxy "v2ray.com/core/proxy"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -29,15 +31,98 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type HeaderRule_Operation int32
+
+const (
+	// Adds the value to the header.
+	HeaderRule_Add HeaderRule_Operation = 0
+	// Removes all values of the header.
+	HeaderRule_Remove HeaderRule_Operation = 1
+	// Replaces all values of the header with the value. The header is added if missing.
+	HeaderRule_Replace HeaderRule_Operation = 2
+)
+
+var HeaderRule_Operation_name = map[int32]string{
+	0: "Add",
+	1: "Remove",
+	2: "Replace",
+}
+var HeaderRule_Operation_value = map[string]int32{
+	"Add":     0,
+	"Remove":  1,
+	"Replace": 2,
+}
+
+func (x HeaderRule_Operation) String() string {
+	return proto.EnumName(HeaderRule_Operation_name, int32(x))
+}
+func (HeaderRule_Operation) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0} }
+
+// An operation on a header of HTTP messages. Header names are case-insensitive.
+type HeaderRule struct {
+	Operation HeaderRule_Operation `protobuf:"varint,1,opt,name=operation,enum=v2ray.core.proxy.http.HeaderRule_Operation" json:"operation,omitempty"`
+	Name      string               `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Value     string               `protobuf:"bytes,3,opt,name=value" json:"value,omitempty"`
+}
+
+func (m *HeaderRule) Reset()                    { *m = HeaderRule{} }
+func (m *HeaderRule) String() string            { return proto.CompactTextString(m) }
+func (*HeaderRule) ProtoMessage()               {}
+func (*HeaderRule) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
 // Config for HTTP proxy server.
 type ServerConfig struct {
 	Timeout uint32 `protobuf:"varint,1,opt,name=timeout" json:"timeout,omitempty"`
+	// Accounts for proxy authentication. Clients are not authenticated if neither accounts nor
+	// authenticator is set.
+	Accounts map[string]string `protobuf:"bytes,2,rep,name=accounts" json:"accounts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Name of a registered authenticator for proxy authentication.
+	Authenticator string `protobuf:"bytes,3,opt,name=authenticator" json:"authenticator,omitempty"`
+	// Rules applied in order to plain HTTP requests, after hop-by-hop headers are removed. The Host header
+	// may be changed as well. Requests through CONNECT are not changed.
+	RequestHeader []*HeaderRule `protobuf:"bytes,4,rep,name=request_header,json=requestHeader" json:"request_header,omitempty"`
+	// Rules applied in order to responses of plain HTTP requests.
+	ResponseHeader []*HeaderRule `protobuf:"bytes,5,rep,name=response_header,json=responseHeader" json:"response_header,omitempty"`
+	// Whether to remove hop-by-hop headers from responses of plain HTTP requests, before response rules.
+	StripResponseHopByHop bool `protobuf:"varint,6,opt,name=strip_response_hop_by_hop,json=stripResponseHopByHop" json:"strip_response_hop_by_hop,omitempty"`
+	// Outbound selection by trusted clients, or nil if clients can't select the outbound. Clients of
+	// servers without authentication select it through the Proxy-Authorization header, whose password is
+	// ignored.
+	OutboundSelection *v2ray_core_proxy.OutboundSelectionConfig `protobuf:"bytes,7,opt,name=outbound_selection,json=outboundSelection" json:"outbound_selection,omitempty"`
 }
 
 func (m *ServerConfig) Reset()                    { *m = ServerConfig{} }
 func (m *ServerConfig) String() string            { return proto.CompactTextString(m) }
 func (*ServerConfig) ProtoMessage()               {}
-func (*ServerConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+func (*ServerConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *ServerConfig) GetAccounts() map[string]string {
+	if m != nil {
+		return m.Accounts
+	}
+	return nil
+}
+
+func (m *ServerConfig) GetRequestHeader() []*HeaderRule {
+	if m != nil {
+		return m.RequestHeader
+	}
+	return nil
+}
+
+func (m *ServerConfig) GetResponseHeader() []*HeaderRule {
+	if m != nil {
+		return m.ResponseHeader
+	}
+	return nil
+}
+
+func (m *ServerConfig) GetOutboundSelection() *v2ray_core_proxy.OutboundSelectionConfig {
+	if m != nil {
+		return m.OutboundSelection
+	}
+	return nil
+}
 
 // ClientConfig for HTTP proxy client.
 type ClientConfig struct {
@@ -46,25 +131,46 @@ type ClientConfig struct {
 func (m *ClientConfig) Reset()                    { *m = ClientConfig{} }
 func (m *ClientConfig) String() string            { return proto.CompactTextString(m) }
 func (*ClientConfig) ProtoMessage()               {}
-func (*ClientConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*ClientConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
 
 func init() {
+	proto.RegisterType((*HeaderRule)(nil), "v2ray.core.proxy.http.HeaderRule")
 	proto.RegisterType((*ServerConfig)(nil), "v2ray.core.proxy.http.ServerConfig")
 	proto.RegisterType((*ClientConfig)(nil), "v2ray.core.proxy.http.ClientConfig")
+	proto.RegisterEnum("v2ray.core.proxy.http.HeaderRule_Operation", HeaderRule_Operation_name, HeaderRule_Operation_value)
 }
 
 func init() { proto.RegisterFile("v2ray.com/core/proxy/http/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 147 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x52, 0x2b, 0x33, 0x2a, 0x4a,
-	0xac, 0xd4, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xce, 0x2f, 0x4a, 0xd5, 0x2f, 0x28, 0xca, 0xaf, 0xa8,
-	0xd4, 0xcf, 0x28, 0x29, 0x29, 0xd0, 0x4f, 0xce, 0xcf, 0x4b, 0xcb, 0x4c, 0xd7, 0x2b, 0x28, 0xca,
-	0x2f, 0xc9, 0x17, 0x12, 0x85, 0xa9, 0x2b, 0x4a, 0xd5, 0x03, 0xab, 0xd1, 0x03, 0xa9, 0x51, 0xd2,
-	0xe0, 0xe2, 0x09, 0x4e, 0x2d, 0x2a, 0x4b, 0x2d, 0x72, 0x06, 0x2b, 0x16, 0x92, 0xe0, 0x62, 0x2f,
-	0xc9, 0xcc, 0x4d, 0xcd, 0x2f, 0x2d, 0x91, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0d, 0x82, 0x71, 0x95,
-	0xf8, 0xb8, 0x78, 0x9c, 0x73, 0x32, 0x53, 0xf3, 0x4a, 0x20, 0x2a, 0x9d, 0xf4, 0xb8, 0x24, 0x93,
-	0xf3, 0x73, 0xf5, 0xb0, 0x1a, 0xeb, 0xc4, 0x0d, 0x51, 0x14, 0x00, 0xb2, 0x3a, 0x8a, 0x05, 0x24,
-	0x94, 0xc4, 0x06, 0x76, 0x87, 0x31, 0x20, 0x00, 0x00, 0xff, 0xff, 0xc7, 0x71, 0x5d, 0x1f, 0xb1,
-	0x00, 0x00, 0x00,
+	// 456 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x93, 0xc1, 0x6f, 0xd3, 0x30,
+	0x14, 0xc6, 0x49, 0xda, 0xb5, 0xeb, 0xeb, 0x5a, 0x8a, 0xc5, 0xa4, 0x6c, 0xa7, 0xac, 0x42, 0x28,
+	0x08, 0x91, 0x8a, 0x72, 0x99, 0xe0, 0xb4, 0x4e, 0x48, 0x05, 0x09, 0x0d, 0x79, 0x17, 0xc4, 0xa5,
+	0x4a, 0xdd, 0x07, 0x8d, 0x48, 0xfc, 0x8c, 0xe3, 0x54, 0xe4, 0xce, 0x1f, 0xc4, 0x9f, 0x88, 0xe2,
+	0x24, 0x6b, 0x0b, 0x45, 0xec, 0x14, 0xfb, 0xe5, 0xfb, 0x7e, 0xf1, 0xfb, 0xf2, 0x0c, 0x4f, 0x37,
+	0x53, 0x1d, 0x15, 0xa1, 0xa0, 0x74, 0x22, 0x48, 0xe3, 0x44, 0x69, 0xfa, 0x51, 0x4c, 0xd6, 0xc6,
+	0xa8, 0x89, 0x20, 0xf9, 0x25, 0xfe, 0x1a, 0x2a, 0x4d, 0x86, 0xd8, 0x69, 0xa3, 0xd3, 0x18, 0x5a,
+	0x4d, 0x58, 0x6a, 0xce, 0x2f, 0x0e, 0xda, 0x77, 0x9d, 0xe3, 0x5f, 0x0e, 0xc0, 0x1c, 0xa3, 0x15,
+	0x6a, 0x9e, 0x27, 0xc8, 0xde, 0x41, 0x8f, 0x14, 0xea, 0xc8, 0xc4, 0x24, 0x3d, 0xc7, 0x77, 0x82,
+	0xe1, 0xf4, 0x79, 0x78, 0x10, 0x1e, 0x6e, 0x5d, 0xe1, 0x4d, 0x63, 0xe1, 0x5b, 0x37, 0x63, 0xd0,
+	0x96, 0x51, 0x8a, 0x9e, 0xeb, 0x3b, 0x41, 0x8f, 0xdb, 0x35, 0x7b, 0x0c, 0x47, 0x9b, 0x28, 0xc9,
+	0xd1, 0x6b, 0xd9, 0x62, 0xb5, 0x19, 0xbf, 0x80, 0xde, 0x1d, 0x81, 0x75, 0xa1, 0x75, 0xb5, 0x5a,
+	0x8d, 0x1e, 0x30, 0x80, 0x0e, 0xc7, 0x94, 0x36, 0x38, 0x72, 0x58, 0x1f, 0xba, 0x1c, 0x55, 0x12,
+	0x09, 0x1c, 0xb9, 0xe3, 0x9f, 0x6d, 0x38, 0xb9, 0x45, 0xbd, 0x41, 0x7d, 0x6d, 0x3b, 0x61, 0x1e,
+	0x74, 0x4d, 0x9c, 0x22, 0xe5, 0xc6, 0x1e, 0x79, 0xc0, 0x9b, 0x2d, 0xfb, 0x00, 0xc7, 0x91, 0x10,
+	0x94, 0x4b, 0x93, 0x79, 0xae, 0xdf, 0x0a, 0xfa, 0xd3, 0x97, 0xff, 0xe8, 0x66, 0x17, 0x18, 0x5e,
+	0xd5, 0x9e, 0xb7, 0xd2, 0xe8, 0x82, 0xdf, 0x21, 0xd8, 0x13, 0x18, 0x44, 0xb9, 0x59, 0xa3, 0x34,
+	0xb1, 0x88, 0x0c, 0xe9, 0xba, 0x8d, 0xfd, 0x22, 0x9b, 0xc3, 0x50, 0xe3, 0xf7, 0x1c, 0x33, 0xb3,
+	0x58, 0xdb, 0x8c, 0xbc, 0xb6, 0xfd, 0xf4, 0xc5, 0x7f, 0x83, 0xe4, 0x83, 0xda, 0x58, 0x95, 0xd8,
+	0x7b, 0x78, 0xa8, 0x31, 0x53, 0x24, 0x33, 0x6c, 0x50, 0x47, 0xf7, 0x45, 0x0d, 0x1b, 0x67, 0xcd,
+	0xba, 0x84, 0xb3, 0xcc, 0xe8, 0x58, 0x2d, 0xb6, 0x44, 0x52, 0x8b, 0x65, 0x51, 0x3e, 0xbc, 0x8e,
+	0xef, 0x04, 0xc7, 0xfc, 0xd4, 0x0a, 0x78, 0xe3, 0x23, 0x35, 0x2b, 0xe6, 0xa4, 0xd8, 0x27, 0x60,
+	0x94, 0x9b, 0x25, 0xe5, 0x72, 0xb5, 0xc8, 0x30, 0x41, 0x61, 0x87, 0xa3, 0xeb, 0x3b, 0x41, 0x7f,
+	0xfa, 0xec, 0xef, 0x83, 0xdc, 0xd4, 0xda, 0xdb, 0x46, 0x5a, 0x85, 0xca, 0x1f, 0xd1, 0x9f, 0x2f,
+	0xce, 0xdf, 0xc0, 0x60, 0x2f, 0x6a, 0x36, 0x82, 0xd6, 0x37, 0x2c, 0xec, 0x5f, 0xec, 0xf1, 0x72,
+	0xb9, 0x9d, 0x18, 0x77, 0x67, 0x62, 0x5e, 0xbb, 0x97, 0xce, 0x78, 0x08, 0x27, 0xd7, 0x49, 0x8c,
+	0xd2, 0x54, 0xfc, 0x59, 0x08, 0x67, 0x82, 0xd2, 0xc3, 0xc1, 0xcc, 0xfa, 0x95, 0xe8, 0x63, 0x39,
+	0xf3, 0x9f, 0xdb, 0x65, 0x69, 0xd9, 0xb1, 0x17, 0xe0, 0xd5, 0xef, 0x00, 0x00, 0x00, 0xff, 0xff,
+	0x49, 0xd7, 0xaf, 0xb3, 0x64, 0x03, 0x00, 0x00,
 }
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/http"
+)
+
+// ApplyHeaderRules applies rules to header in order.
+func ApplyHeaderRules(header http.Header, rules []*HeaderRule) {
+	for _, rule := range rules {
+		switch rule.Operation {
+		case HeaderRule_Add:
+			header.Add(rule.Name, rule.Value)
+		case HeaderRule_Remove:
+			header.Del(rule.Name)
+		case HeaderRule_Replace:
+			header.Set(rule.Name, rule.Value)
+		}
+	}
+}
+
+// ApplyRequestHeaderRules applies rules to header of request. Rules on the Host header change the host
+// of request.
+func ApplyRequestHeaderRules(request *http.Request, rules []*HeaderRule) {
+	if len(rules) == 0 {
+		return
+	}
+	// Host is not in header of requests.
+	request.Header.Set("Host", request.Host)
+	ApplyHeaderRules(request.Header, rules)
+	request.Host = request.Header.Get("Host")
+	request.Header.Del("Host")
+}
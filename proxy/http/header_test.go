@@ -0,0 +1,44 @@
+package http_test
+
+import (
+	"net/http"
+	"testing"
+
+	. "v2ray.com/core/proxy/http"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestApplyHeaderRules(t *testing.T) {
+	assert := assert.On(t)
+
+	header := http.Header{}
+	header.Set("Server", "nginx")
+	header.Set("X-Powered-By", "PHP")
+	header.Add("Via", "1.1 a")
+
+	ApplyHeaderRules(header, []*HeaderRule{
+		{Operation: HeaderRule_Remove, Name: "x-powered-by"},
+		{Operation: HeaderRule_Replace, Name: "Server", Value: "v2ray"},
+		{Operation: HeaderRule_Add, Name: "Via", Value: "1.1 b"},
+		{Operation: HeaderRule_Replace, Name: "X-Frame-Options", Value: "DENY"},
+	})
+	assert.String(header.Get("X-Powered-By")).Equals("")
+	assert.String(header.Get("Server")).Equals("v2ray")
+	assert.Int(len(header["Via"])).Equals(2)
+	assert.String(header.Get("X-Frame-Options")).Equals("DENY")
+}
+
+func TestApplyRequestHeaderRules(t *testing.T) {
+	assert := assert.On(t)
+
+	request, err := http.NewRequest("GET", "http://www.v2ray.com/", nil)
+	assert.Error(err).IsNil()
+
+	ApplyRequestHeaderRules(request, []*HeaderRule{
+		{Operation: HeaderRule_Replace, Name: "Host", Value: "v2ray.com"},
+		{Operation: HeaderRule_Add, Name: "X-Forwarded-Proto", Value: "http"},
+	})
+	assert.String(request.Host).Equals("v2ray.com")
+	assert.String(request.Header.Get("Host")).Equals("")
+	assert.String(request.Header.Get("X-Forwarded-Proto")).Equals("http")
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"bufio"
+	"encoding/base64"
 	"io"
 	"net"
 	"net/http"
@@ -16,6 +17,7 @@ import (
 	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/proxy/registry"
 	"v2ray.com/core/transport/internet"
@@ -27,6 +29,8 @@ type Server struct {
 	sync.Mutex
 	accepting        bool
 	packetDispatcher dispatcher.PacketDispatcher
+	authenticator    proxy.Authenticator
+	selector         *proxy.OutboundSelector
 	config           *ServerConfig
 	tcpListener      *internet.TCPHub
 	meta             *proxy.InboundHandlerMeta
@@ -35,6 +39,7 @@ type Server struct {
 func NewServer(config *ServerConfig, packetDispatcher dispatcher.PacketDispatcher, meta *proxy.InboundHandlerMeta) *Server {
 	return &Server{
 		packetDispatcher: packetDispatcher,
+		selector:         proxy.NewOutboundSelector(config.OutboundSelection),
 		config:           config,
 		meta:             meta,
 	}
@@ -120,12 +125,27 @@ func (this *Server) handleConnection(conn internet.Connection) {
 		log.Warning("HTTP: Malformed proxy host (", host, "): ", err)
 		return
 	}
-	log.Access(conn.RemoteAddr(), request.URL, log.AccessAccepted, "")
 	session := &proxy.SessionInfo{
 		Source:      v2net.DestinationFromAddr(conn.RemoteAddr()),
 		Destination: dest,
 		Inbound:     this.meta,
 	}
+	if this.authenticator != nil {
+		user, err := this.authenticate(request, session)
+		if err != nil {
+			log.AccessWithTags(conn.RemoteAddr(), request.URL, log.AccessRejected, err, this.meta.ConnectionTags)
+			response := this.GenerateResponse(407, "Proxy Authentication Required")
+			response.Header.Set("Proxy-Authenticate", "Basic realm=\"proxy\"")
+			response.Write(conn)
+			return
+		}
+		session.User = user
+	} else if this.selector != nil {
+		if username, _, ok := ParseBasicAuth(request.Header.Get("Proxy-Authorization")); ok {
+			session.OutboundTag, _ = this.selector.Select(session.Source, username)
+		}
+	}
+	log.AccessWithTags(conn.RemoteAddr(), request.URL, log.AccessAccepted, "", this.meta.ConnectionTags)
 	if strings.ToUpper(request.Method) == "CONNECT" {
 		this.handleConnect(request, session, reader, conn)
 	} else {
@@ -133,7 +153,54 @@ func (this *Server) handleConnection(conn internet.Connection) {
 	}
 }
 
+// authenticate verifies the basic credentials in Proxy-Authorization header of the request. The outbound
+// selected in the username, if any, is set in session.
+func (this *Server) authenticate(request *http.Request, session *proxy.SessionInfo) (*protocol.User, error) {
+	username, password, ok := ParseBasicAuth(request.Header.Get("Proxy-Authorization"))
+	if !ok {
+		return nil, proxy.ErrInvalidAuthentication
+	}
+	session.OutboundTag, username = this.selector.Select(session.Source, username)
+	user, err := this.authenticator.Authenticate(username, password)
+	if err != nil {
+		if err != proxy.ErrInvalidAuthentication {
+			log.Warning("HTTP: Failed to authenticate user: ", err)
+		}
+		return nil, proxy.ErrInvalidAuthentication
+	}
+	return user, nil
+}
+
+// ParseBasicAuth parses credentials in the form of "Basic base64(username:password)".
+func ParseBasicAuth(auth string) (username, password string, ok bool) {
+	const prefix = "Basic "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(auth[len(prefix):]))
+	if err != nil {
+		return
+	}
+	pair := strings.SplitN(string(decoded), ":", 2)
+	if len(pair) != 2 {
+		return
+	}
+	return pair[0], pair[1], true
+}
+
 func (this *Server) handleConnect(request *http.Request, session *proxy.SessionInfo, reader io.Reader, writer io.Writer) {
+	ray := this.packetDispatcher.DispatchToOutbound(session)
+	if this.meta.AllowPassiveConnection {
+		// The outbound connects before the client sends anything, so its failure can be reported.
+		if err := ray.InboundOutput().WaitEstablished(); err != nil {
+			log.Info("HTTP: Failed to connect to ", session.Destination, ": ", err)
+			ray.InboundInput().Close()
+			ray.InboundOutput().Release()
+			this.GenerateFailureResponse(err).Write(writer)
+			return
+		}
+	}
+
 	response := &http.Response{
 		Status:        "200 OK",
 		StatusCode:    200,
@@ -147,7 +214,6 @@ func (this *Server) handleConnect(request *http.Request, session *proxy.SessionI
 	}
 	response.Write(writer)
 
-	ray := this.packetDispatcher.DispatchToOutbound(session)
 	this.transport(reader, writer, ray)
 }
 
@@ -181,22 +247,26 @@ func StripHopByHopHeaders(request *http.Request) {
 	// http://www.w3.org/Protocols/rfc2616/rfc2616-sec13.html#sec13.5.1
 	// https://www.mnot.net/blog/2011/07/11/what_proxies_must_do
 
-	request.Header.Del("Proxy-Connection")
-	request.Header.Del("Proxy-Authenticate")
-	request.Header.Del("Proxy-Authorization")
-	request.Header.Del("TE")
-	request.Header.Del("Trailers")
-	request.Header.Del("Transfer-Encoding")
-	request.Header.Del("Upgrade")
+	stripHopByHopHeaders(request.Header)
+}
+
+func stripHopByHopHeaders(header http.Header) {
+	header.Del("Proxy-Connection")
+	header.Del("Proxy-Authenticate")
+	header.Del("Proxy-Authorization")
+	header.Del("TE")
+	header.Del("Trailers")
+	header.Del("Transfer-Encoding")
+	header.Del("Upgrade")
 
 	// TODO: support keep-alive
-	connections := request.Header.Get("Connection")
-	request.Header.Set("Connection", "close")
+	connections := header.Get("Connection")
+	header.Set("Connection", "close")
 	if len(connections) == 0 {
 		return
 	}
 	for _, h := range strings.Split(connections, ",") {
-		request.Header.Del(strings.TrimSpace(h))
+		header.Del(strings.TrimSpace(h))
 	}
 }
 
@@ -216,6 +286,20 @@ func (this *Server) GenerateResponse(statusCode int, status string) *http.Respon
 	}
 }
 
+// GenerateFailureResponse returns the response to a request that fails for err from outbound.
+func (this *Server) GenerateFailureResponse(err error) *http.Response {
+	switch proxy.GetFailureReason(err) {
+	case proxy.FailureRejected:
+		return this.GenerateResponse(403, "Forbidden")
+	case proxy.FailureTimeout:
+		return this.GenerateResponse(504, "Gateway Timeout")
+	case proxy.FailureGeneral:
+		return this.GenerateResponse(503, "Service Unavailable")
+	default:
+		return this.GenerateResponse(502, "Bad Gateway")
+	}
+}
+
 func (this *Server) handlePlainHTTP(request *http.Request, session *proxy.SessionInfo, reader *bufio.Reader, writer io.Writer) {
 	if len(request.URL.Host) <= 0 {
 		response := this.GenerateResponse(400, "Bad Request")
@@ -226,6 +310,7 @@ func (this *Server) handlePlainHTTP(request *http.Request, session *proxy.Sessio
 
 	request.Host = request.URL.Host
 	StripHopByHopHeaders(request)
+	ApplyRequestHeaderRules(request, this.config.RequestHeader)
 
 	ray := this.packetDispatcher.DispatchToOutbound(session)
 	defer ray.InboundInput().Close()
@@ -252,6 +337,14 @@ func (this *Server) handlePlainHTTP(request *http.Request, session *proxy.Sessio
 		if err != nil {
 			log.Warning("HTTP: Failed to read response: ", err)
 			response = this.GenerateResponse(503, "Service Unavailable")
+			if outboundErr := ray.InboundOutput().Err(); outboundErr != nil {
+				response = this.GenerateFailureResponse(outboundErr)
+			}
+		} else {
+			if this.config.StripResponseHopByHop {
+				stripHopByHopHeaders(response.Header)
+			}
+			ApplyHeaderRules(response.Header, this.config.ResponseHeader)
 		}
 		responseWriter := v2io.NewBufferedWriter(writer)
 		err = response.Write(responseWriter)
@@ -276,10 +369,18 @@ func (this *ServerFactory) Create(space app.Space, rawConfig interface{}, meta *
 	if !space.HasApp(dispatcher.APP_ID) {
 		return nil, common.ErrBadConfiguration
 	}
-	return NewServer(
-		rawConfig.(*ServerConfig),
+	config := rawConfig.(*ServerConfig)
+	authenticator, err := config.BuildAuthenticator()
+	if err != nil {
+		log.Error("HTTP: Failed to find authenticator ", config.Authenticator, ": ", err)
+		return nil, err
+	}
+	server := NewServer(
+		config,
 		space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher),
-		meta), nil
+		meta)
+	server.authenticator = authenticator
+	return server, nil
 }
 
 func init() {
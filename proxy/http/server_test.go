@@ -2,6 +2,7 @@ package http_test
 
 import (
 	"bufio"
+	"net"
 	"net/http"
 	"strings"
 	"testing"
@@ -13,6 +14,7 @@ import (
 	. "v2ray.com/core/proxy/http"
 	"v2ray.com/core/testing/assert"
 	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
 
 	_ "v2ray.com/core/transport/internet/tcp"
 )
@@ -77,3 +79,67 @@ func TestNormalGetRequest(t *testing.T) {
 	assert.Error(err).IsNil()
 	assert.Int(resp.StatusCode).Equals(400)
 }
+
+func TestParseBasicAuth(t *testing.T) {
+	assert := assert.On(t)
+
+	// base64("v2ray:pass:word")
+	username, password, ok := ParseBasicAuth("Basic djJyYXk6cGFzczp3b3Jk")
+	assert.Bool(ok).IsTrue()
+	assert.String(username).Equals("v2ray")
+	assert.String(password).Equals("pass:word")
+
+	_, _, ok = ParseBasicAuth("")
+	assert.Bool(ok).IsFalse()
+	_, _, ok = ParseBasicAuth("Bearer djJyYXk6cGFzczp3b3Jk")
+	assert.Bool(ok).IsFalse()
+	_, _, ok = ParseBasicAuth("Basic !!!")
+	assert.Bool(ok).IsFalse()
+}
+
+func TestConnectFailureResponse(t *testing.T) {
+	assert := assert.On(t)
+
+	testPacketDispatcher := testdispatcher.NewTestPacketDispatcher(func(destination v2net.Destination, traffic ray.OutboundRay) {
+		conn, err := net.Dial("tcp", destination.NetAddr())
+		if err != nil {
+			traffic.OutboundOutput().CloseError(err)
+			return
+		}
+		conn.Close()
+		traffic.OutboundOutput().Close()
+	})
+	go func() {
+		for range testPacketDispatcher.Destination {
+		}
+	}()
+
+	port := v2net.Port(dice.Roll(20000) + 10000)
+	httpProxy := NewServer(
+		&ServerConfig{},
+		testPacketDispatcher,
+		&proxy.InboundHandlerMeta{
+			Address:                v2net.LocalHostIP,
+			Port:                   port,
+			AllowPassiveConnection: true,
+			StreamSettings: &internet.StreamConfig{
+				Network: v2net.Network_RawTCP,
+			}})
+	defer httpProxy.Close()
+	assert.Error(httpProxy.Start()).IsNil()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	target := listener.Addr().String()
+	listener.Close()
+
+	conn, err := net.Dial("tcp", "127.0.0.1:"+port.String())
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	_, err = conn.Write([]byte("CONNECT " + target + " HTTP/1.1\r\nHost: " + target + "\r\n\r\n"))
+	assert.Error(err).IsNil()
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	assert.Error(err).IsNil()
+	assert.Int(resp.StatusCode).Equals(502)
+}
@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"net"
+	"strings"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	outboundSelectionPrefix = "outbound:"
+)
+
+// OutboundSelector parses the outbound that trusted clients select through usernames. A nil
+// OutboundSelector selects nothing.
+type OutboundSelector struct {
+	trusted []*net.IPNet
+}
+
+// NewOutboundSelector creates an OutboundSelector. It returns nil if config is nil.
+func NewOutboundSelector(config *OutboundSelectionConfig) *OutboundSelector {
+	if config == nil {
+		return nil
+	}
+	selector := new(OutboundSelector)
+	for _, network := range config.TrustedNetwork {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			log.Warning("Proxy: Ignoring invalid trusted network of outbound selection: ", network)
+			continue
+		}
+		selector.trusted = append(selector.trusted, ipNet)
+	}
+	return selector
+}
+
+func (this *OutboundSelector) isTrusted(source v2net.Destination) bool {
+	if source.Address == nil || !source.Address.Family().Either(v2net.AddressFamilyIPv4, v2net.AddressFamilyIPv6) {
+		return false
+	}
+	ip := source.Address.IP()
+	if len(this.trusted) == 0 {
+		return ip.IsLoopback()
+	}
+	for _, network := range this.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Select parses username from source in the form of "outbound:<tag>" or "outbound:<tag>/<username>".
+// It returns the selected tag, or an empty tag if nothing is selected, and the username to authenticate.
+// Usernames of untrusted clients are returned as is.
+func (this *OutboundSelector) Select(source v2net.Destination, username string) (tag string, rest string) {
+	if this == nil || !strings.HasPrefix(username, outboundSelectionPrefix) {
+		return "", username
+	}
+	if !this.isTrusted(source) {
+		log.Warning("Proxy: Ignoring outbound selection from untrusted client ", source)
+		return "", username
+	}
+	tag = username[len(outboundSelectionPrefix):]
+	if idx := strings.Index(tag, "/"); idx >= 0 {
+		tag, rest = tag[:idx], tag[idx+1:]
+	}
+	return tag, rest
+}
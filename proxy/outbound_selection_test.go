@@ -0,0 +1,44 @@
+package proxy_test
+
+import (
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestOutboundSelection(t *testing.T) {
+	assert := assert.On(t)
+
+	local := v2net.TCPDestination(v2net.LocalHostIP, 10000)
+	remote := v2net.TCPDestination(v2net.IPAddress([]byte{10, 1, 2, 3}), 10000)
+
+	selector := NewOutboundSelector(&OutboundSelectionConfig{})
+	tag, username := selector.Select(local, "outbound:ss-jp")
+	assert.String(tag).Equals("ss-jp")
+	assert.String(username).Equals("")
+	tag, username = selector.Select(local, "outbound:ss-jp/v2ray")
+	assert.String(tag).Equals("ss-jp")
+	assert.String(username).Equals("v2ray")
+	tag, username = selector.Select(local, "v2ray")
+	assert.String(tag).Equals("")
+	assert.String(username).Equals("v2ray")
+	// Only loopback clients are trusted by default.
+	tag, username = selector.Select(remote, "outbound:ss-jp")
+	assert.String(tag).Equals("")
+	assert.String(username).Equals("outbound:ss-jp")
+
+	selector = NewOutboundSelector(&OutboundSelectionConfig{
+		TrustedNetwork: []string{"10.0.0.0/8"},
+	})
+	tag, _ = selector.Select(remote, "outbound:ss-jp")
+	assert.String(tag).Equals("ss-jp")
+	tag, _ = selector.Select(local, "outbound:ss-jp")
+	assert.String(tag).Equals("")
+
+	selector = NewOutboundSelector(nil)
+	tag, username = selector.Select(local, "outbound:ss-jp")
+	assert.String(tag).Equals("")
+	assert.String(username).Equals("outbound:ss-jp")
+}
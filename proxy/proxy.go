@@ -21,6 +21,29 @@ type SessionInfo struct {
 	Destination v2net.Destination
 	User        *protocol.User
 	Inbound     *InboundHandlerMeta
+	// Tags of the connection. Inbounds may set them, otherwise the dispatcher sets them to tags of the
+	// inbound. Tags must not be changed after the session is dispatched.
+	Tags map[string]string
+	// Routing decision of the session, or nil if the session is not dispatched by the router.
+	Route *Route
+	// Application protocol sniffed from the first payload, or empty if it is unknown or not sniffed.
+	Protocol string
+	// Tag of the outbound selected by the client, which takes precedence over routing rules. Empty if the
+	// client selects none.
+	OutboundTag string
+	// Outbounds of this instance the session has gone through before, or nil if it comes from outside.
+	Chain internet.OutboundChain
+}
+
+// GetTags returns tags of the session, or tags of its inbound if the session has none.
+func (this *SessionInfo) GetTags() map[string]string {
+	if this == nil {
+		return nil
+	}
+	if this.Tags == nil && this.Inbound != nil {
+		return this.Inbound.ConnectionTags
+	}
+	return this.Tags
 }
 
 type InboundHandlerMeta struct {
@@ -29,6 +52,8 @@ type InboundHandlerMeta struct {
 	Port                   v2net.Port
 	AllowPassiveConnection bool
 	StreamSettings         *internet.StreamConfig
+	// Tags attached to all connections of this handler.
+	ConnectionTags map[string]string
 }
 
 type OutboundHandlerMeta struct {
@@ -36,13 +61,27 @@ type OutboundHandlerMeta struct {
 	Address        v2net.Address
 	StreamSettings *internet.StreamConfig
 	ProxySettings  *internet.ProxyConfig
+	// Hooks of connections of this handler, or nil if there is none.
+	Hooks ConnectionHooks
 }
 
 func (this *OutboundHandlerMeta) GetDialerOptions() internet.DialerOptions {
 	return internet.DialerOptions{
-		Stream: this.StreamSettings,
-		Proxy:  this.ProxySettings,
+		Stream:          this.StreamSettings,
+		Proxy:           this.ProxySettings,
+		Tag:             this.Tag,
+		SourcePortRange: this.StreamSettings.GetSourcePortRange(),
+	}
+}
+
+// GetSessionDialerOptions returns the options to dial connections of the session, which carry its
+// outbound chain.
+func (this *OutboundHandlerMeta) GetSessionDialerOptions(session *SessionInfo) internet.DialerOptions {
+	options := this.GetDialerOptions()
+	if session != nil {
+		options.Chain = session.Chain
 	}
+	return options
 }
 
 // An InboundHandler handles inbound network connections to V2Ray.
@@ -60,3 +99,57 @@ type OutboundHandler interface {
 	// Dispatch sends one or more Packets to its destination.
 	Dispatch(destination v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error
 }
+
+// A SessionOutboundHandler is an OutboundHandler that uses information of the whole session, such as
+// its source. Dispatcher calls DispatchSession() instead of Dispatch() on such handlers.
+type SessionOutboundHandler interface {
+	OutboundHandler
+	// DispatchSession sends one or more Packets to the destination of the session.
+	DispatchSession(session *SessionInfo, payload *alloc.Buffer, ray ray.OutboundRay) error
+}
+
+// A ClosableOutboundHandler is an OutboundHandler with background work, such as refreshing its servers.
+type ClosableOutboundHandler interface {
+	OutboundHandler
+	// Close stops background work of the handler. Connections in progress are not affected. It is safe to
+	// call Close more than once.
+	Close()
+}
+
+// A DescribableOutboundHandler is an OutboundHandler that describes its settings at runtime, e.g., its
+// current servers. The description is encoded as JSON, and must not contain secrets.
+type DescribableOutboundHandler interface {
+	OutboundHandler
+	Describe() interface{}
+}
+
+// A NetworkRestrictedOutboundHandler is an OutboundHandler that can't reach destinations in some
+// networks, e.g., UDP through an upstream proxy. This is about networks of destinations, unlike
+// StreamCapability of factories, which is about transports to servers.
+type NetworkRestrictedOutboundHandler interface {
+	OutboundHandler
+	SupportsNetwork(network v2net.Network) bool
+}
+
+// SupportsNetwork returns true if handler can reach destinations in network.
+func SupportsNetwork(handler OutboundHandler, network v2net.Network) bool {
+	if restricted, ok := handler.(NetworkRestrictedOutboundHandler); ok {
+		return restricted.SupportsNetwork(network)
+	}
+	return true
+}
+
+// CloseOutboundHandler closes handler if it is a ClosableOutboundHandler.
+func CloseOutboundHandler(handler OutboundHandler) {
+	if closable, ok := handler.(ClosableOutboundHandler); ok {
+		closable.Close()
+	}
+}
+
+// DispatchSession dispatches the session to handler, with session info if the handler supports it.
+func DispatchSession(handler OutboundHandler, session *SessionInfo, payload *alloc.Buffer, ray ray.OutboundRay) error {
+	if sessionHandler, ok := handler.(SessionOutboundHandler); ok {
+		return sessionHandler.DispatchSession(session, payload, ray)
+	}
+	return handler.Dispatch(session.Destination, payload, ray)
+}
@@ -0,0 +1,25 @@
+package proxy_test
+
+import (
+	"testing"
+
+	. "v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestSessionTags(t *testing.T) {
+	assert := assert.On(t)
+
+	session := &SessionInfo{
+		Inbound: &InboundHandlerMeta{
+			ConnectionTags: map[string]string{"tenant": "a"},
+		},
+	}
+	assert.String(session.GetTags()["tenant"]).Equals("a")
+
+	session.Tags = map[string]string{"tenant": "b"}
+	assert.String(session.GetTags()["tenant"]).Equals("b")
+
+	session = nil
+	assert.Int(len(session.GetTags())).Equals(0)
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"strconv"
+	"time"
+)
+
+// RouteReason tells why a route is taken.
+type RouteReason string
+
+const (
+	// No rule matches the session, or there is no router.
+	RouteDefault = RouteReason("default")
+	// A rule with domains matches the destination domain.
+	RouteDomain = RouteReason("domain")
+	// A rule with IPs matches the destination IP.
+	RouteIP = RouteReason("ip")
+	// A rule with protocols matches the sniffed protocol.
+	RouteProtocol = RouteReason("protocol")
+	// A rule matches an IP resolved from the destination domain.
+	RouteResolvedIP = RouteReason("resolved ip")
+	// A rule matches the session by other fields, e.g., port or inbound tag.
+	RouteRule = RouteReason("rule")
+	// An override rule set at runtime matches the session.
+	RouteOverride = RouteReason("override")
+	// The client selects the outbound through the inbound.
+	RouteSelected = RouteReason("selected")
+)
+
+// Route is the routing decision for a session.
+type Route struct {
+	// Index of the matched rule in routing settings, or in overrides if the reason is RouteOverride. -1 if
+	// no rule matches, or if the outbound is selected by the client.
+	Rule int
+	// Tag of the outbound that the session goes through. Empty for the default outbound.
+	OutboundTag string
+	Reason      RouteReason
+	// Connection settings of the matched rule, or nil if the outbound uses its own.
+	Policy *Policy
+}
+
+// Policy is the connection settings for sessions of a route. Zero timeouts are defaults of the outbound.
+type Policy struct {
+	ConnectTimeout time.Duration
+	// Time to wait for the response after the request is sent.
+	HandshakeTimeout time.Duration
+	// Maximum time between two reads of the response.
+	IdleTimeout time.Duration
+}
+
+// GetPolicy returns the policy of the route, or an empty policy if there is none.
+func (this *Route) GetPolicy() *Policy {
+	if this == nil || this.Policy == nil {
+		return &Policy{}
+	}
+	return this.Policy
+}
+
+// DefaultRoute returns a route to the default outbound.
+func DefaultRoute() *Route {
+	return &Route{
+		Rule:   -1,
+		Reason: RouteDefault,
+	}
+}
+
+// SelectedRoute returns a route to the outbound of tag, selected by the client.
+func SelectedRoute(tag string) *Route {
+	return &Route{
+		Rule:        -1,
+		OutboundTag: tag,
+		Reason:      RouteSelected,
+	}
+}
+
+func (this *Route) String() string {
+	if this != nil && this.Reason == RouteSelected {
+		return "selection of [" + this.OutboundTag + "]"
+	}
+	if this == nil || this.Rule < 0 {
+		return "default route"
+	}
+	return "rule " + strconv.Itoa(this.Rule) + " (" + string(this.Reason) + ") to [" + this.OutboundTag + "]"
+}
@@ -0,0 +1,190 @@
+package shadowsocks
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+)
+
+const (
+	// Largest write to a TCP connection that takes tokens at once. Larger writes are split, so that
+	// connections sharing a shaper take turns.
+	shaperQuantum = 16 * 1024
+)
+
+// BandwidthShaper limits the total rate of writes of all connections that share it with a token bucket.
+// Tokens are reserved in the order of writes, so that waiting connections are served in turn. A nil
+// BandwidthShaper doesn't limit anything.
+type BandwidthShaper struct {
+	sync.Mutex
+	// Bytes per second.
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBandwidthShaper creates a shaper by config, or returns nil if config has no limit.
+func NewBandwidthShaper(config *BandwidthLimitConfig) *BandwidthShaper {
+	if config == nil || config.Rate == 0 {
+		return nil
+	}
+	burst := float64(config.Burst)
+	if burst == 0 {
+		burst = float64(config.Rate) / 10
+		if burst < shaperQuantum {
+			burst = shaperQuantum
+		}
+	}
+	return &BandwidthShaper{
+		rate:   float64(config.Rate),
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve takes size tokens, and returns the time to wait until they are available.
+func (this *BandwidthShaper) reserve(size int) time.Duration {
+	this.Lock()
+	defer this.Unlock()
+
+	now := time.Now()
+	this.tokens += now.Sub(this.last).Seconds() * this.rate
+	if this.tokens > this.burst {
+		this.tokens = this.burst
+	}
+	this.last = now
+	this.tokens -= float64(size)
+	if this.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-this.tokens / this.rate * float64(time.Second))
+}
+
+// refund gives back size tokens of a reservation that is not sent.
+func (this *BandwidthShaper) refund(size int) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.tokens += float64(size)
+}
+
+// Wait blocks until size bytes may be sent. It fails with os.ErrDeadlineExceeded if deadline, unless
+// zero, passes before that, or with io.ErrClosedPipe if closed is closed. Tokens of a failed wait are
+// given back.
+func (this *BandwidthShaper) Wait(size int, deadline time.Time, closed <-chan bool) error {
+	if this == nil {
+		return nil
+	}
+	delay := this.reserve(size)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		deadlineTimer := time.NewTimer(deadline.Sub(time.Now()))
+		defer deadlineTimer.Stop()
+		expired = deadlineTimer.C
+	}
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-expired:
+		this.refund(size)
+		return os.ErrDeadlineExceeded
+	case <-closed:
+		this.refund(size)
+		return io.ErrClosedPipe
+	}
+}
+
+// Wrap returns a connection of network that waits for the shaper before each write.
+func (this *BandwidthShaper) Wrap(conn internet.Connection, network v2net.Network) internet.Connection {
+	if this == nil {
+		return conn
+	}
+	return &shapedConn{
+		Connection: conn,
+		shaper:     this,
+		datagram:   network == v2net.Network_UDP,
+		closed:     make(chan bool),
+	}
+}
+
+// shapedConn waits for its shaper before writes. Waits end with an error once the connection is closed, or
+// at the write deadline as of the start of the wait.
+type shapedConn struct {
+	internet.Connection
+	shaper *BandwidthShaper
+	// Whether writes are datagrams, which are never split.
+	datagram bool
+
+	deadlineLock  sync.Mutex
+	writeDeadline time.Time
+	closed        chan bool
+	closeOnce     sync.Once
+}
+
+func (this *shapedConn) wait(size int) error {
+	this.deadlineLock.Lock()
+	deadline := this.writeDeadline
+	this.deadlineLock.Unlock()
+	return this.shaper.Wait(size, deadline, this.closed)
+}
+
+func (this *shapedConn) Write(b []byte) (int, error) {
+	if this.datagram {
+		if err := this.wait(len(b)); err != nil {
+			return 0, err
+		}
+		return this.Connection.Write(b)
+	}
+	written := 0
+	for written < len(b) {
+		chunk := len(b) - written
+		if chunk > shaperQuantum {
+			chunk = shaperQuantum
+		}
+		if err := this.wait(chunk); err != nil {
+			return written, err
+		}
+		nBytes, err := this.Connection.Write(b[written : written+chunk])
+		written += nBytes
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (this *shapedConn) SetDeadline(t time.Time) error {
+	this.setWriteDeadline(t)
+	return this.Connection.SetDeadline(t)
+}
+
+func (this *shapedConn) SetWriteDeadline(t time.Time) error {
+	this.setWriteDeadline(t)
+	return this.Connection.SetWriteDeadline(t)
+}
+
+func (this *shapedConn) setWriteDeadline(t time.Time) {
+	this.deadlineLock.Lock()
+	defer this.deadlineLock.Unlock()
+
+	this.writeDeadline = t
+}
+
+func (this *shapedConn) Close() error {
+	this.closeOnce.Do(func() {
+		close(this.closed)
+	})
+	return this.Connection.Close()
+}
@@ -0,0 +1,82 @@
+package shadowsocks_test
+
+import (
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestBandwidthShaper(t *testing.T) {
+	assert := assert.On(t)
+
+	shaper := NewBandwidthShaper(&BandwidthLimitConfig{
+		Rate:  1024 * 1024,
+		Burst: 64 * 1024,
+	})
+	start := time.Now()
+	// Burst is sent right away.
+	assert.Error(shaper.Wait(64*1024, time.Time{}, nil)).IsNil()
+	assert.Bool(time.Since(start) < 50*time.Millisecond).IsTrue()
+	assert.Error(shaper.Wait(256*1024, time.Time{}, nil)).IsNil()
+	assert.Bool(time.Since(start) >= 200*time.Millisecond).IsTrue()
+
+	assert.Error(NewBandwidthShaper(nil).Wait(1024*1024*1024, time.Time{}, nil)).IsNil()
+}
+
+func TestBandwidthShaperFairness(t *testing.T) {
+	assert := assert.On(t)
+
+	shaper := NewBandwidthShaper(&BandwidthLimitConfig{
+		Rate:  1024 * 1024,
+		Burst: 16 * 1024,
+	})
+	deadline := time.Now().Add(300 * time.Millisecond)
+	var wg sync.WaitGroup
+	sent := make([]int, 2)
+	for idx := range sent {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				shaper.Wait(16*1024, time.Time{}, nil)
+				sent[idx] += 16 * 1024
+			}
+		}(idx)
+	}
+	wg.Wait()
+	// Both connections share the rate.
+	assert.Bool(sent[0] >= 2*sent[1]/3).IsTrue()
+	assert.Bool(sent[1] >= 2*sent[0]/3).IsTrue()
+	assert.Bool(sent[0]+sent[1] <= 400*1024).IsTrue()
+}
+
+func TestBandwidthShaperCancel(t *testing.T) {
+	assert := assert.On(t)
+
+	shaper := NewBandwidthShaper(&BandwidthLimitConfig{
+		Rate:  1024 * 1024,
+		Burst: 16 * 1024,
+	})
+	assert.Error(shaper.Wait(16*1024, time.Time{}, nil)).IsNil()
+
+	// A wait past the deadline fails at the deadline.
+	start := time.Now()
+	err := shaper.Wait(1024*1024, start.Add(100*time.Millisecond), nil)
+	assert.Error(err).Equals(os.ErrDeadlineExceeded)
+	assert.Bool(time.Since(start) < 500*time.Millisecond).IsTrue()
+
+	// So does a wait of a closed connection.
+	closed := make(chan bool)
+	close(closed)
+	assert.Error(shaper.Wait(1024*1024, time.Time{}, closed)).Equals(io.ErrClosedPipe)
+
+	// Tokens of failed waits are given back.
+	start = time.Now()
+	assert.Error(shaper.Wait(16*1024, time.Time{}, nil)).IsNil()
+	assert.Bool(time.Since(start) < 100*time.Millisecond).IsTrue()
+}
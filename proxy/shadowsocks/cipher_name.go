@@ -0,0 +1,102 @@
+package shadowsocks
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	// Misspelled names within this edit distance of a supported method get a suggestion.
+	maxCipherNameDistance = 2
+)
+
+var (
+	// Supported cipher methods, in the order of suggestions.
+	cipherTypes = []CipherType{
+		CipherType_AES_128_CFB,
+		CipherType_AES_256_CFB,
+		CipherType_CHACHA20,
+		CipherType_CHACHA20_IEFT,
+	}
+
+	cipherNames = map[CipherType]string{
+		CipherType_AES_128_CFB:   "aes-128-cfb",
+		CipherType_AES_256_CFB:   "aes-256-cfb",
+		CipherType_CHACHA20:      "chacha20",
+		CipherType_CHACHA20_IEFT: "chacha20-ietf",
+	}
+)
+
+// normalizeCipherName removes case and separators from name, so that "AES-256-CFB", "aes_256_cfb" and
+// "aes256cfb" are the same method.
+func normalizeCipherName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '_', ' ', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// CipherTypeFromName returns the CipherType of a cipher method name, such as "aes-256-cfb". Case and
+// separators in name are ignored. The error of an unknown name suggests the closest supported method.
+func CipherTypeFromName(name string) (CipherType, error) {
+	normalized := normalizeCipherName(name)
+	for _, cipherType := range cipherTypes {
+		if normalizeCipherName(cipherNames[cipherType]) == normalized {
+			return cipherType, nil
+		}
+	}
+
+	names := make([]string, len(cipherTypes))
+	suggestion := ""
+	bestDistance := maxCipherNameDistance + 1
+	for idx, cipherType := range cipherTypes {
+		names[idx] = cipherNames[cipherType]
+		if distance := editDistance(normalized, normalizeCipherName(names[idx])); distance < bestDistance {
+			bestDistance = distance
+			suggestion = names[idx]
+		}
+	}
+	if len(suggestion) > 0 {
+		return CipherType_UNKNOWN, errors.New("Shadowsocks: Unknown cipher method: " + name + ". Did you mean " + suggestion + "?")
+	}
+	return CipherType_UNKNOWN, errors.New("Shadowsocks: Unknown cipher method: " + name + ". Supported methods are: " + strings.Join(names, ", ") + ".")
+}
+
+// Name returns the method name of this cipher type, or empty string if unknown.
+func (this CipherType) Name() string {
+	return cipherNames[this]
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
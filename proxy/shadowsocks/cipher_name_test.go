@@ -0,0 +1,29 @@
+package shadowsocks_test
+
+import (
+	"testing"
+
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestCipherTypeFromName(t *testing.T) {
+	assert := assert.On(t)
+
+	for _, name := range []string{"aes-256-cfb", "AES-256-CFB", "aes256cfb", "aes_256_cfb", " Aes-256-Cfb "} {
+		cipherType, err := CipherTypeFromName(name)
+		assert.Error(err).IsNil()
+		assert.String(cipherType.String()).Equals(CipherType_AES_256_CFB.String())
+	}
+	cipherType, err := CipherTypeFromName("ChaCha20-IETF")
+	assert.Error(err).IsNil()
+	assert.String(cipherType.Name()).Equals("chacha20-ietf")
+
+	_, err = CipherTypeFromName("aes-265-cfb")
+	assert.String(err.Error()).Equals("Shadowsocks: Unknown cipher method: aes-265-cfb. Did you mean aes-256-cfb?")
+	_, err = CipherTypeFromName("chacha2O")
+	assert.String(err.Error()).Equals("Shadowsocks: Unknown cipher method: chacha2O. Did you mean chacha20?")
+
+	_, err = CipherTypeFromName("rc4-md5")
+	assert.String(err.Error()).Equals("Shadowsocks: Unknown cipher method: rc4-md5. Supported methods are: aes-128-cfb, aes-256-cfb, chacha20, chacha20-ietf.")
+}
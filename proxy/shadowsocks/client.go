@@ -1,68 +1,359 @@
 package shadowsocks
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
 
-	"sync"
 	"v2ray.com/core/app"
+	"v2ray.com/core/app/api"
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/observatory"
 	"v2ray.com/core/common/alloc"
 	v2io "v2ray.com/core/common/io"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/common/retry"
+	"v2ray.com/core/common/stats"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/shadowtls"
 	"v2ray.com/core/transport/ray"
 )
 
+const (
+	// Seconds to wait for a UDP response.
+	udpTimeout = 16
+	// Seconds to wait for the server to close, after the client stops taking a TCP response.
+	trailingDataTimeout = 2
+	// Time that a server is used without one-time auth of accounts in auto mode, after it rejects a
+	// request with one-time auth, or with it after it accepts one.
+	otaFallbackTimeout = time.Hour
+	// Time in which a server closes a request that it rejects.
+	otaRejectionTimeout = time.Second
+	// Maximum time to probe whether a server rejects one-time auth.
+	otaProbeTimeout = 4 * time.Second
+	// Time that a server is used with a connection per request, after it rejects connection reuse in auto
+	// mode, or with connection reuse after it accepts it.
+	reuseFallbackTimeout = 10 * time.Minute
+)
+
+var (
+	ErrConnectTimeout         = errors.New("Shadowsocks|Client: Timed out connecting to server.")
+	ErrSubscriptionWithSource = errors.New("Shadowsocks|Client: Subscription can't be used with a server source.")
+)
+
 type Client struct {
 	serverPicker protocol.ServerPicker
 	meta         *proxy.OutboundHandlerMeta
+	dispatchLog  *DispatchLogConfig
+	redundancy   *RedundancyConfig
+	failover     *ResponseFailoverConfig
+	dialLimiter  *DialLimiter
+	proxyHeader  uint32
+	counters     *stats.CounterSet
+	sticky       *protocol.StickyServerPicker
+	bufferSize   int
+	trailing     *TrailingDataConfig
+	fetcher      *SubscriptionFetcher
+	// Watcher of the server source of embedders, or nil if there is none.
+	sourceWatcher *protocol.ServerSourceWatcher
+	// Maximum time to wait for the first payload of TCP requests.
+	handshakeDelay time.Duration
+	serverList     *protocol.ServerList
+	domainServers  DomainServerTable
+	// Traffic by tags of sessions.
+	tagCounters *stats.CounterSet
+	// Results of TCP handshakes by cipher method.
+	handshakes *stats.CounterSet
+	warmup     *WarmupPool
+	health     *HealthChecker
+	adaptive   *AdaptiveTimeoutConfig
+	// Servers that reject one-time auth.
+	ota *featureFallback
+	// Servers that reject compression.
+	compression *featureFallback
+	// Idle connections for sequential requests, and servers that reject connection reuse. Both are nil if
+	// connection reuse is disabled.
+	reuse         *ReusePool
+	reuseFallback *featureFallback
+	// Regions of destinations and the picker by proximity to them, or nil if disabled.
+	geoRegions *GeoRegionTable
+	geoPicker  *protocol.GeoProximityServerPicker
+	// DNS to find the regions of domains, or nil if not available.
+	dns dns.Server
+	// Maximum lifetime of TCP connections, or 0 for unlimited.
+	maxLifetime time.Duration
+	watchdog    *v2io.Watchdog
+	// Stalled TCP requests found by the watchdog.
+	stalls *stats.CounterSet
+	// UDP associations by whether they received any response.
+	udpResponses *stats.CounterSet
+	udpTracker   *UDPResponseTracker
+	// Observatory that probes servers, and the name this client is registered with, or nil if there is none.
+	observatory *observatory.Observatory
+	observedAs  string
+	// Limit of TCP handshakes in progress, or nil for unlimited.
+	handshakeLimiter *HandshakeLimiter
+	// Limit of bandwidth to servers, or nil for unlimited.
+	shaper       *BandwidthShaper
+	connectRetry *ConnectRetryConfig
+	ipv6Policy   IPv6DestinationPolicy
 }
 
+// NewClient creates a client of servers in config and in its subscription, if any.
 func NewClient(config *ClientConfig, space app.Space, meta *proxy.OutboundHandlerMeta) (*Client, error) {
+	return NewClientWithSource(config, space, meta, nil, 0)
+}
+
+// NewClientWithSource creates a client that also uses servers from source, queried on every interval and
+// whenever the source notifies a change. Servers in config are always kept. It is the same as NewClient if
+// source is nil.
+func NewClientWithSource(config *ClientConfig, space app.Space, meta *proxy.OutboundHandlerMeta, source protocol.ServerSource, interval time.Duration) (*Client, error) {
+	if source != nil && config.Subscription != nil {
+		return nil, ErrSubscriptionWithSource
+	}
 	serverList := protocol.NewServerList()
+	if config.CircuitBreaker != nil {
+		serverList.SetCircuitBreaker(config.CircuitBreaker)
+	}
 	for _, rec := range config.Server {
 		serverList.AddServer(protocol.NewServerSpecFromPB(*rec))
 	}
 	client := &Client{
-		serverPicker: protocol.NewRoundRobinServerPicker(serverList),
-		meta:         meta,
+		serverPicker:     protocol.NewWeightedRoundRobinServerPicker(serverList),
+		meta:             meta,
+		dispatchLog:      config.DispatchLog,
+		redundancy:       config.Redundancy,
+		failover:         config.ResponseFailover,
+		dialLimiter:      NewDialLimiter(config.DialConcurrency),
+		proxyHeader:      config.ProxyProtocol,
+		counters:         stats.NewCounterSet(),
+		tagCounters:      stats.NewCounterSet(),
+		handshakes:       stats.NewCounterSet(),
+		bufferSize:       int(config.BufferSize),
+		trailing:         config.TrailingData,
+		handshakeDelay:   time.Duration(config.HandshakeDelay) * time.Millisecond,
+		serverList:       serverList,
+		domainServers:    NewDomainServerTable(config.DomainServer),
+		adaptive:         config.AdaptiveTimeout,
+		maxLifetime:      time.Duration(config.MaxConnectionLifetime) * time.Second,
+		stalls:           stats.NewCounterSet(),
+		udpResponses:     stats.NewCounterSet(),
+		handshakeLimiter: NewHandshakeLimiter(config.HandshakeLimit),
+		shaper:           NewBandwidthShaper(config.BandwidthLimit),
+		connectRetry:     config.ConnectRetry,
+		ipv6Policy:       config.Ipv6Destination,
+		ota:              newFeatureFallback(otaFallbackTimeout),
+		compression:      newFeatureFallback(compressionFallbackTimeout),
+	}
+	client.udpTracker = NewUDPResponseTracker(config.UdpResponse, client.udpResponses)
+	if client.ipv6Policy != IPv6DestinationPolicy_AsIs {
+		log.Info("Shadowsocks|Client: IPv6 destinations are handled by policy ", client.ipv6Policy, ".")
+	}
+	if config.ConnectionReuse != nil {
+		client.reuse = NewReusePool(config.ConnectionReuse, client.maxLifetime)
+		client.reuseFallback = newFeatureFallback(reuseFallbackTimeout)
+	}
+	if config.GeoProximity != nil {
+		regions, err := NewGeoRegionTable(config.GeoProximity)
+		if err != nil {
+			return nil, err
+		}
+		client.geoRegions = regions
+		client.geoPicker = protocol.NewGeoProximityServerPicker(serverList, regions.Locations())
+		space.InitializeApplication(func() error {
+			if space.HasApp(dns.APP_ID) {
+				client.dns = space.GetApp(dns.APP_ID).(dns.Server)
+			}
+			return nil
+		})
+	}
+	if config.StickyTimeout > 0 {
+		client.sticky = protocol.NewStickyServerPicker(client.serverPicker, serverList, time.Duration(config.StickyTimeout)*time.Second)
+	}
+
+	client.warmup = NewWarmupPool(config.Warmup, func(server v2net.Destination) (internet.Connection, error) {
+		server.Network = v2net.Network_TCP
+		return internet.Dial(meta.Address, server, meta.GetDialerOptions())
+	})
+	if client.warmup != nil {
+		// Servers of subscriptions and server sources come and go with their refreshes.
+		serverList.OnReplace(func() {
+			client.warmup.Update(warmupServers(serverList))
+		})
+		space.InitializeApplication(func() error {
+			client.warmup.Start(warmupServers(serverList))
+			return nil
+		})
+	}
+
+	if healthCheck := config.HealthCheck; healthCheck != nil {
+		client.health = NewHealthChecker(healthCheck, serverList, func(server *protocol.ServerSpec) error {
+			return client.checkServer(healthCheck, server, nil)
+		})
+		space.InitializeApplication(func() error {
+			client.health.Start()
+			return nil
+		})
+	}
+
+	if watchdog := config.PipeWatchdog; watchdog != nil {
+		client.watchdog = v2io.NewWatchdog(watchdog.GetEffectiveStallThreshold(), watchdog.CloseStalled, client.stalls)
+		space.InitializeApplication(func() error {
+			client.watchdog.Start()
+			return nil
+		})
+	}
+
+	if config.Subscription != nil {
+		fetcher := NewSubscriptionFetcher(config.Subscription, serverList)
+		client.fetcher = fetcher
+		space.InitializeApplication(func() error {
+			fetcher.Start()
+			return nil
+		})
+	}
+
+	if source != nil {
+		watcher := protocol.NewServerSourceWatcher(source, serverList, interval)
+		client.sourceWatcher = watcher
+		space.InitializeApplication(func() error {
+			watcher.Start()
+			return nil
+		})
+	}
+
+	var speedTester *SpeedTester
+	if config.SpeedTest != nil {
+		tester, err := NewSpeedTester(config.SpeedTest, client)
+		if err != nil {
+			return nil, err
+		}
+		speedTester = tester
 	}
 
+	if len(meta.Tag) > 0 {
+		space.InitializeApplication(func() error {
+			if space.HasApp(api.APP_ID) {
+				apiServer := space.GetApp(api.APP_ID).(*api.ApiServer)
+				apiServer.Handle("/outbound/"+meta.Tag+"/weights", api.NewServerWeightHandler(serverList))
+				apiServer.Handle("/outbound/"+meta.Tag+"/stats", api.NewCounterHandler(client.counters))
+				apiServer.Handle("/outbound/"+meta.Tag+"/tag-stats", api.NewCounterHandler(client.tagCounters))
+				apiServer.Handle("/outbound/"+meta.Tag+"/handshakes", api.NewCounterHandler(client.handshakes))
+				apiServer.Handle("/outbound/"+meta.Tag+"/stalls", api.NewCounterHandler(client.stalls))
+				apiServer.Handle("/outbound/"+meta.Tag+"/udp-responses", api.NewCounterHandler(client.udpResponses))
+				if client.handshakeLimiter != nil {
+					apiServer.Handle("/outbound/"+meta.Tag+"/handshake-queue", api.NewCounterHandler(client.handshakeLimiter.Counters()))
+				}
+				if speedTester != nil {
+					apiServer.Handle("/outbound/"+meta.Tag+"/speed-test", speedTester)
+				}
+			}
+			return nil
+		})
+	}
+	space.InitializeApplication(func() error {
+		if space.HasApp(observatory.APP_ID) {
+			client.observatory = space.GetApp(observatory.APP_ID).(*observatory.Observatory)
+			client.observedAs = meta.Tag
+			if len(meta.Tag) > 0 {
+				client.observatory.Register(meta.Tag, client, client.onProbe)
+			} else {
+				client.observedAs = client.observatory.RegisterUntagged(client, client.onProbe)
+			}
+		}
+		return nil
+	})
+
 	return client, nil
 }
 
+// Dispatch implements OutboundHandler.Dispatch().
 func (this *Client) Dispatch(destination v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error {
-	defer payload.Release()
+	return this.DispatchSession(&proxy.SessionInfo{Destination: destination}, payload, ray)
+}
+
+// DispatchSession implements SessionOutboundHandler.DispatchSession().
+func (this *Client) DispatchSession(session *proxy.SessionInfo, payload *alloc.Buffer, ray ray.OutboundRay) error {
 	defer ray.OutboundInput().Release()
 	defer ray.OutboundOutput().Close()
 
-	network := destination.Network
+	session, payload, err := this.applyIPv6Policy(session, payload, ray.OutboundInput())
+	defer payload.Release()
+	destination := session.Destination
 
-	var server *protocol.ServerSpec
-	var conn internet.Connection
+	logger := newDispatchLogger(this.dispatchLog, this.meta, destination, session.GetTags(), session.Route)
+	if err != nil {
+		logger.OnFinish(nil, err)
+		ray.OutboundOutput().CloseError(err)
+		return err
+	}
+	var conn *countingConn
+	if this.redundancy.AppliesTo(destination) {
+		conn, err = this.dispatchRedundant(destination, session.Chain, payload, ray, logger)
+	} else if this.failover.AppliesTo(destination) {
+		conn, err = this.dispatchWithFailover(session, payload, ray, logger)
+	} else {
+		conn, err = this.dispatch(session, payload, ray, logger, nil, nil)
+	}
+	logger.OnFinish(conn, err)
+	if err != nil {
+		ray.OutboundOutput().CloseError(err)
+	}
+	return err
+}
 
-	err := retry.Timed(5, 100).On(func() error {
-		server = this.serverPicker.PickServer()
-		dest := server.Destination()
-		dest.Network = network
-		rawConn, err := internet.Dial(this.meta.Address, dest, this.meta.GetDialerOptions())
-		if err != nil {
-			return err
+// pickServer picks a server for a request, by the domain server rules, by proximity to the destination,
+// or sticking to the previous server of the source if enabled.
+func (this *Client) pickServer(session *proxy.SessionInfo) *protocol.ServerSpec {
+	if dest, found := this.domainServers.Lookup(session.Destination); found {
+		server := this.serverList.FindServer(dest)
+		if server != nil && server.Weight() > 0 && server.CircuitBreaker().Available() {
+			return server
 		}
-		conn = rawConn
+		log.Info("Shadowsocks|Client: Server ", dest, " for ", session.Destination, " is not available.")
+	}
+	if this.geoPicker != nil {
+		if region := this.destinationRegion(session.Destination); len(region) > 0 {
+			if server := this.geoPicker.PickServerFor(region); server != nil {
+				return server
+			}
+		}
+	}
+	if this.sticky != nil {
+		return this.sticky.PickServerFor(session.Source)
+	}
+	return this.serverPicker.PickServer()
+}
 
-		return nil
-	})
-	if err != nil {
-		return errors.New("Shadowsocks|Client: Failed to find an available destination:" + err.Error())
+// destinationRegion returns the region of dest, or empty if unknown. Domains are resolved by DNS if
+// available, and their regions are cached.
+func (this *Client) destinationRegion(dest v2net.Destination) string {
+	if !dest.Address.Family().IsDomain() {
+		return this.geoRegions.Region(dest.Address.IP())
 	}
-	log.Info("Shadowsocks|Client: Tunneling request to ", destination, " via ", server.Destination())
+	if this.dns == nil {
+		return ""
+	}
+	return this.geoRegions.DomainRegion(dest.Address.Domain(), func(domain string) []net.IP {
+		return this.dns.GetFor(domain, this.meta.Tag)
+	})
+}
 
-	conn.SetReusable(false)
+func (this *Client) unstick(source v2net.Destination, server *protocol.ServerSpec) {
+	if this.sticky != nil {
+		this.sticky.OnFailure(source, server)
+	}
+}
 
+// newRequest creates a request to destination with a user picked from server.
+func newRequest(destination v2net.Destination, server *protocol.ServerSpec) (*protocol.RequestHeader, *ShadowsocksAccount, error) {
 	request := &protocol.RequestHeader{
 		Version: Version,
 		Address: destination.Address,
@@ -75,89 +366,600 @@ func (this *Client) Dispatch(destination v2net.Destination, payload *alloc.Buffe
 	}
 
 	user := server.PickUser()
-	rawAccount, err := user.GetTypedAccount()
+	account, err := getAccount(user, request.Command)
 	if err != nil {
-		return errors.New("Shadowsocks|Client: Failed to get a valid user account: " + err.Error())
+		return nil, nil, errors.New("Shadowsocks|Client: Failed to get a valid user account: " + err.Error())
 	}
-	account := rawAccount.(*ShadowsocksAccount)
 	request.User = user
 
 	if account.OneTimeAuth == Account_Auto || account.OneTimeAuth == Account_Enabled {
 		request.Option |= RequestOptionOneTimeAuth
 	}
+	return request, account, nil
+}
+
+// dispatch sends the request to a server, and copies data in both directions until they finish. If attempt
+// is not nil, the request fails over to another server if this one doesn't start responding in time. If
+// through is not nil, the request is sent through it instead of a picked server.
+func (this *Client) dispatch(session *proxy.SessionInfo, payload *alloc.Buffer, ray ray.OutboundRay, logger *dispatchLogger, attempt *failoverAttempt, through *protocol.ServerSpec) (*countingConn, error) {
+	destination := session.Destination
+	network := destination.Network
+	policy := session.Route.GetPolicy()
+
+	if network == v2net.Network_TCP && payload.IsEmpty() && this.handshakeDelay > 0 {
+		firstPayload, err := waitForPayload(ray.OutboundInput(), this.handshakeDelay)
+		if err != nil {
+			log.Info("Shadowsocks|Client: Request to ", destination, " closed before sending any data.")
+			return nil, nil
+		}
+		if firstPayload != nil {
+			defer firstPayload.Release()
+			payload = firstPayload
+		}
+	}
+
+	var server *protocol.ServerSpec
+	var conn internet.Connection
+	var reused *reusedSession
+
+	queueStart := time.Now()
+	release := this.dialLimiter.Acquire(destination)
+	logger.OnDialAllowed(time.Since(queueStart))
+	err := retry.Timed(this.connectRetry.GetEffectiveServerAttempts(), 100).On(func() error {
+		if through != nil {
+			server = through
+		} else {
+			server = this.pickServer(session)
+		}
+		if attempt != nil {
+			server = attempt.avoid(server, this.serverPicker)
+		}
+		if server == nil {
+			// Either no server is configured, or all circuits are open. Fail without waiting.
+			return nil
+		}
+		breaker := server.CircuitBreaker()
+		if !breaker.Allow() {
+			this.unstick(session.Source, server)
+			return protocol.ErrCircuitOpen
+		}
+		dest := server.Destination()
+		dest.Network = network
+		if network == v2net.Network_TCP && attempt == nil {
+			if reused = this.reuse.get(dest); reused != nil {
+				// Same as a warm connection below.
+				breaker.OnSuccess()
+				return nil
+			}
+		}
+		var rawConn internet.Connection
+		if network == v2net.Network_TCP {
+			rawConn = this.warmup.Get(dest)
+		}
+		if rawConn != nil {
+			// The warm connection is as good as a successful dial, and releases the probe of a half-open
+			// circuit. It is dialed ahead of the session, so it goes on with the outbound chain of the session.
+			internet.TrackOutboundChain(rawConn, this.meta.GetSessionDialerOptions(session))
+			breaker.OnSuccess()
+			conn = rawConn
+			return nil
+		}
+		timeout := policy.ConnectTimeout
+		var latency *protocol.LatencyTracker
+		if network == v2net.Network_TCP {
+			latency = server.ConnectLatency()
+			if adaptiveTimeout := this.adaptive.GetTimeout(latency.Average()); adaptiveTimeout > 0 {
+				timeout = adaptiveTimeout
+			}
+		}
+		rawConn, err := this.dialServer(dest, session, timeout, latency, logger)
+		if err != nil {
+			logger.OnDialFailure(err)
+			breaker.OnFailure()
+			this.unstick(session.Source, server)
+			return err
+		}
+		breaker.OnSuccess()
+		conn = rawConn
+
+		return nil
+	})
+	release()
+	if err == nil && reused != nil {
+		return this.dispatchReused(session, reused, server, payload, ray, logger)
+	}
+	if err == nil && conn == nil {
+		err = protocol.ErrNoServerAvailable
+	}
+	if err != nil {
+		return nil, errors.New("Shadowsocks|Client: Failed to find an available destination:" + err.Error())
+	}
+	logger.OnStart(server.Destination())
+	ray.OutboundOutput().Established()
+
+	conn.SetReusable(false)
+	// Set when the connection is kept for the next request.
+	kept := false
+	defer func(conn internet.Connection) {
+		if !kept {
+			conn.Close()
+		}
+	}(conn)
+	counter := newCountingConn(conn, server.Destination(), this.counters)
+	counter.countTags(this.tagCounters, logger.tags)
+	conn = this.shaper.Wrap(counter, network)
+
+	request, account, err := newRequest(destination, server)
+	if err != nil {
+		return counter, err
+	}
+	if account.OneTimeAuth == Account_Auto && !this.ota.Allowed(server.Destination()) {
+		request.Option.Clear(RequestOptionOneTimeAuth)
+	}
+	user := request.User
 
 	if request.Command == protocol.RequestCommandTCP {
+		if this.shouldCompress(session, payload, account, server) {
+			request.Option.Set(RequestOptionCompression)
+		}
+		if attempt == nil && this.shouldReuse(request, account, server) {
+			request.Option.Set(protocol.RequestOptionConnectionReuse)
+		}
+		conn, err = this.wrapTCPConn(conn, account, server, session.Source)
+		if err != nil {
+			return counter, err
+		}
+
 		bufferedWriter := v2io.NewBufferedWriter(conn)
-		defer bufferedWriter.Release()
+		defer func() {
+			if !kept {
+				bufferedWriter.Release()
+			}
+		}()
 
+		releaseHandshake, err := this.handshakeLimiter.Acquire()
+		if err != nil {
+			return counter, err
+		}
 		bodyWriter, err := WriteTCPRequest(request, bufferedWriter)
+		if err != nil {
+			releaseHandshake()
+			this.countHandshake(account, false)
+			return counter, errors.New("Shadowsock|Client: Failed to write request: " + err.Error())
+		}
 		defer bodyWriter.Release()
 
+		// A request is sent again without one-time auth, if the server may not support it and the request
+		// is safe to send again. Either way it is sent only once the server rejects the first one.
+		otaRetriable := attempt == nil && account.OneTimeAuth == Account_Auto &&
+			request.Option.Has(RequestOptionOneTimeAuth) && !this.ota.Confirmed(server.Destination()) &&
+			(payload.IsEmpty() || isIdempotentRequest(payload.Value))
+		sent := payload
+		if attempt != nil || otaRetriable {
+			// Payload is encrypted in place, so a copy of it is sent, to keep it for the next request.
+			sent = alloc.NewBufferWithSize(payload.Len()).Clear().Append(payload.Value)
+		}
+		err = bodyWriter.Write(sent)
+		releaseHandshake()
 		if err != nil {
-			return errors.New("Shadowsock|Client: Failed to write request: " + err.Error())
+			this.countHandshake(account, false)
+			return counter, errors.New("Shadowsocks|Client: Failed to write payload: " + err.Error())
 		}
 
-		if err := bodyWriter.Write(payload); err != nil {
-			return errors.New("Shadowsocks|Client: Failed to write payload: " + err.Error())
+		bufferedWriter.SetCached(false)
+		logger.OnRequest()
+		requestTime := time.Now()
+		timedReader := v2net.NewTimeOutReader(timeoutSeconds(policy.HandshakeTimeout), conn)
+		var responseStream io.Reader = timedReader
+		if attempt != nil && attempt.timeout > 0 {
+			start, err := waitForResponseStart(conn, attempt.timeout)
+			if err != nil {
+				attempt.onFailure(server)
+				server.CircuitBreaker().OnFailure()
+				this.unstick(session.Source, server)
+				this.countHandshake(account, false)
+				return counter, errors.New("Shadowsocks|Client: No response from " + server.Destination().String() + ": " + err.Error())
+			}
+			responseStream = io.MultiReader(bytes.NewReader(start), timedReader)
+		} else if otaRetriable {
+			// Nothing else is sent until the server either starts responding or doesn't close in time.
+			start, err := waitForResponseStart(conn, otaRejectionTimeout)
+			if err == nil || err == ErrResponseTimeout {
+				this.ota.OnAccepted(server.Destination())
+			}
+			if err == nil {
+				responseStream = io.MultiReader(bytes.NewReader(start), timedReader)
+			}
+			if err != nil && err != ErrResponseTimeout {
+				this.countHandshake(account, false)
+				if !this.probeOneTimeAuth(server, destination) {
+					return counter, errors.New("Shadowsocks|Client: Server " + server.Destination().String() + " closed the request: " + err.Error())
+				}
+				log.Info("Shadowsocks|Client: Sending request to ", destination, " through ", server.Destination(), " again without one-time auth.")
+				conn.Close()
+				return this.dispatch(session, payload, ray, logger, nil, server)
+			}
+		}
+		expired := limitLifetime(timedReader, ray.OutboundInput(), this.maxLifetime)
+		// Progress is counted in bytes on the connection, so that a server trickling a frame is not stalled.
+		progress := this.watchdog.Watch(func() int64 {
+			return counter.Sent() + counter.Received()
+		}, func() {
+			log.Info("Shadowsocks|Client: Closing stalled request to ", destination, " through ", server.Destination(), ".")
+			endTransfer(timedReader, ray.OutboundInput())
+		})
+		defer this.watchdog.Unwatch(progress)
+		frameWriter, _ := bodyWriter.(*FrameWriter)
+		var endUpload func() error
+		if frameWriter != nil {
+			endUpload = frameWriter.End
+		}
+		if compressed, ok := bodyWriter.(*CompressedWriter); ok {
+			endUpload = compressed.End
 		}
+		var frameReader *FrameReader
+		err = this.transfer(timedReader, bodyWriter, ray, func() error {
+			responseReader, err := ReadTCPResponseWithConfig(request, responseStream, this.bufferSize, this.trailing)
+			this.countHandshake(account, err == nil)
+			if err != nil {
+				this.onHandshakeFailure(request, account, server, destination, err)
+			}
+			if err == nil {
+				if request.Option.Has(RequestOptionOneTimeAuth) {
+					this.ota.OnAccepted(server.Destination())
+				}
+				if request.Option.Has(protocol.RequestOptionConnectionReuse) {
+					this.reuseFallback.OnAccepted(server.Destination())
+				}
+				if request.Option.Has(RequestOptionCompression) {
+					this.compression.OnAccepted(server.Destination())
+				}
+				logger.OnHandshake()
+				server.Latency().Update(time.Since(requestTime))
+			}
+			if err != nil {
+				if _, ok := err.(*ResponseError); ok {
+					return err
+				}
+				return errors.New("Shadowsocks|Client: Failed to read response: " + err.Error())
+			}
+			timedReader.SetTimeOut(timeoutSeconds(policy.IdleTimeout))
+			frameReader, _ = responseReader.reader.(*FrameReader)
+			return v2io.Pipe(responseReader, &finishingWriter{
+				reader: responseReader,
+				writer: ray.OutboundOutput(),
+				onFinish: func() {
+					// The client has closed, so anything more from the server is trailing data.
+					timedReader.SetTimeOut(trailingDataTimeout)
+				},
+			})
+		}, endUpload)
+		if expired() {
+			log.Info("Shadowsocks|Client: Connection to ", server.Destination(), " for ", destination, " reached its maximum lifetime.")
+			return counter, nil
+		}
+		if err == nil && frameWriter != nil && frameReader != nil && frameReader.Ended() {
+			// Counted before the connection may be used by the next request.
+			snapshot := counter.since(0, 0)
+			kept = this.reuse.put(server.Destination(), &reusedSession{
+				conn:           conn,
+				counter:        counter,
+				user:           user,
+				bufferedWriter: bufferedWriter,
+				writer:         frameWriter,
+				timedReader:    timedReader,
+				reader:         frameReader,
+				created:        requestTime,
+			})
+			if kept {
+				return snapshot, nil
+			}
+		}
+		return counter, err
+	}
 
-		var responseMutex sync.Mutex
-		responseMutex.Lock()
+	if request.Command == protocol.RequestCommandUDP {
+		monitor := NewUDPSizeMonitor(server.Destination())
+		conn = &udpMonitoredConn{
+			Connection: conn,
+			monitor:    monitor,
+		}
+		timeout := uint32(udpTimeout)
+		if policy.IdleTimeout > 0 {
+			timeout = timeoutSeconds(policy.IdleTimeout)
+		}
+		timedReader := v2net.NewTimeOutReader(timeout, conn)
 
-		go func() {
-			defer responseMutex.Unlock()
+		writer := &UDPWriter{
+			Writer:  conn,
+			Request: request,
+		}
+		if err := writer.Write(payload); err != nil {
+			return counter, errors.New("Shadowsocks|Client: Failed to write payload: " + err.Error())
+		}
+		err := this.transfer(timedReader, writer, ray, func() error {
+			reader := &UDPReader{
+				Reader:    timedReader,
+				User:      user,
+				Malformed: this.counters.Get(server.Destination().NetAddr() + ">>>malformed"),
+			}
+			v2io.Pipe(reader, ray.OutboundOutput())
+			// UDP session ends when no response is received in time.
+			return nil
+		}, nil)
+		this.udpTracker.OnFinish(server.Destination(), destination, monitor.Responses())
+		return counter, err
+	}
 
-			responseReader, err := ReadTCPResponse(user, conn)
-			if err != nil {
-				log.Warning("Shadowsocks|Client: Failed to read response: " + err.Error())
-				return
+	return counter, nil
+}
+
+// dialServer connects to dest for the session, dialing again right away on failure, up to the dials of each
+// server. Each dial times out by timeout on its own. The time of the successful dial is added to latency,
+// if not nil.
+func (this *Client) dialServer(dest v2net.Destination, session *proxy.SessionInfo, timeout time.Duration, latency *protocol.LatencyTracker, logger *dispatchLogger) (internet.Connection, error) {
+	options := this.meta.GetSessionDialerOptions(session)
+	var err error
+	for i := 0; i < this.connectRetry.GetEffectivePerServer(); i++ {
+		var conn internet.Connection
+		start := time.Now()
+		logger.OnDial()
+		conn, err = dialWithTimeout(this.meta.Address, dest, options, timeout)
+		if err == nil {
+			if latency != nil {
+				latency.Update(time.Since(start))
 			}
+			return conn, nil
+		}
+		log.Debug("Shadowsocks|Client: Failed to connect to ", dest, ": ", err)
+	}
+	return nil, err
+}
 
-			v2io.Pipe(responseReader, ray.OutboundOutput())
-		}()
+// onHandshakeFailure stops using connection reuse or compression with server, or probes whether server
+// rejects one-time auth, if it may have rejected request to destination for them. Connection reuse is
+// blamed before compression, and compression before one-time auth, as requests with connection reuse have
+// neither of the others.
+func (this *Client) onHandshakeFailure(request *protocol.RequestHeader, account *ShadowsocksAccount, server *protocol.ServerSpec, destination v2net.Destination, err error) {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		// Servers reject unknown requests by closing, not by waiting.
+		return
+	}
+	if request.Option.Has(protocol.RequestOptionConnectionReuse) {
+		if account.ConnectionReuse == Account_ReuseAuto && !this.reuseFallback.Confirmed(server.Destination()) {
+			log.Info("Shadowsocks|Client: Server ", server.Destination(), " may not support connection reuse, falling back to a connection per request.")
+			this.reuseFallback.OnRejected(server.Destination())
+		}
+		return
+	}
+	if request.Option.Has(RequestOptionCompression) {
+		if !this.compression.Confirmed(server.Destination()) {
+			log.Info("Shadowsocks|Client: Server ", server.Destination(), " may not support compression, falling back to uncompressed requests.")
+			this.compression.OnRejected(server.Destination())
+		}
+		return
+	}
+	if account.OneTimeAuth == Account_Auto && request.Option.Has(RequestOptionOneTimeAuth) && !this.ota.Confirmed(server.Destination()) {
+		this.probeOneTimeAuth(server, destination)
+	}
+}
 
-		bufferedWriter.SetCached(false)
-		v2io.Pipe(ray.OutboundInput(), bodyWriter)
+// shouldCompress returns true if the TCP request of session to server with account should be compressed.
+// Requests starting with a TLS handshake are not, as encrypted data doesn't compress.
+func (this *Client) shouldCompress(session *proxy.SessionInfo, payload *alloc.Buffer, account *ShadowsocksAccount, server *protocol.ServerSpec) bool {
+	if !account.Compression || !this.compression.Allowed(server.Destination()) {
+		return false
+	}
+	return session.Protocol != proxy.ProtocolTLS && proxy.SniffProtocol(payload.Value) != proxy.ProtocolTLS
+}
 
-		responseMutex.Lock()
+// wrapTCPConn adds PROXY protocol header and plugins of account to a TCP connection to server, for a
+// request from source.
+func (this *Client) wrapTCPConn(conn internet.Connection, account *ShadowsocksAccount, server *protocol.ServerSpec, source v2net.Destination) (internet.Connection, error) {
+	if this.proxyHeader > 0 {
+		header, err := EncodeProxyProtocolHeader(this.proxyHeader, source, v2net.DestinationFromAddr(conn.RemoteAddr()))
+		if err != nil {
+			return nil, err
+		}
+		// Header goes before anything else on the connection, including obfuscation.
+		conn = &proxyProtocolConn{
+			Connection: conn,
+			header:     header,
+		}
 	}
+	if account.Obfs != nil {
+		conn = NewObfsHTTPConn(conn, account.Obfs, server.Destination())
+	}
+	if account.ShadowTLS != nil {
+		tlsConn, err := shadowtls.Client(conn, account.ShadowTLS)
+		if err != nil {
+			return nil, errors.New("Shadowsocks|Client: Failed to handshake with ShadowTLS server: " + err.Error())
+		}
+		conn = tlsConn
+	}
+	return conn, nil
+}
 
-	if request.Command == protocol.RequestCommandUDP {
-		timedReader := v2net.NewTimeOutReader(16, conn)
-		var responseMutex sync.Mutex
-		responseMutex.Lock()
+// waitForPayload waits for the first non-empty payload from input until timeout. It returns nil if
+// nothing arrives in time, or an error if input is closed before any data.
+func waitForPayload(input ray.InputStream, timeout time.Duration) (*alloc.Buffer, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			return nil, nil
+		}
+		payload, err := input.ReadTimeout(remaining)
+		if err == ray.ErrIOTimeout {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !payload.IsEmpty() {
+			return payload, nil
+		}
+		payload.Release()
+	}
+}
 
+// dialWithTimeout dials to dest from src with options, or returns ErrConnectTimeout if it doesn't finish
+// within timeout. Connecting is cancelled at the timeout, and a connection made by a transport that
+// finishes anyway is closed. There is no timeout if it is 0.
+func dialWithTimeout(src v2net.Address, dest v2net.Destination, options internet.DialerOptions, timeout time.Duration) (internet.Connection, error) {
+	if timeout == 0 {
+		return internet.Dial(src, dest, options)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	options.Context = ctx
+	type dialResult struct {
+		conn internet.Connection
+		err  error
+	}
+	done := make(chan dialResult, 1)
+	go func() {
+		conn, err := internet.Dial(src, dest, options)
+		done <- dialResult{conn: conn, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.conn, result.err
+	case <-ctx.Done():
 		go func() {
-			defer responseMutex.Unlock()
-
-			reader := &UDPReader{
-				Reader: timedReader,
-				User:   user,
+			if result := <-done; result.conn != nil {
+				result.conn.Close()
 			}
-
-			v2io.Pipe(reader, ray.OutboundOutput())
 		}()
+		return nil, ErrConnectTimeout
+	}
+}
 
-		writer := &UDPWriter{
-			Writer:  conn,
-			Request: request,
+// limitLifetime ends the transfer on conn and input when lifetime passes. There is no limit if lifetime is
+// 0. The returned function stops the limit, and returns true if the transfer has been ended by it.
+func limitLifetime(conn readDeadliner, input ray.InputStream, lifetime time.Duration) func() bool {
+	if lifetime == 0 {
+		return func() bool { return false }
+	}
+	var expired int32
+	timer := time.AfterFunc(lifetime, func() {
+		atomic.StoreInt32(&expired, 1)
+		endTransfer(conn, input)
+	})
+	return func() bool {
+		timer.Stop()
+		return atomic.LoadInt32(&expired) == 1
+	}
+}
+
+// readDeadliner is a connection, or a reader of it, whose reads fail after a deadline.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// endTransfer stops a transfer on conn from another goroutine, by closing input and failing the pending
+// read of the response.
+func endTransfer(conn readDeadliner, input ray.InputStream) {
+	input.Release()
+	conn.SetReadDeadline(time.Now())
+}
+
+// timeoutSeconds converts timeout to seconds for v2net.TimeOutReader, where 0 is no timeout.
+func timeoutSeconds(timeout time.Duration) uint32 {
+	return uint32(timeout / time.Second)
+}
+
+// transfer copies data from ray to the server via writer, while readResponse copies data back in another
+// goroutine. It returns after both directions finish. If the server stops responding with an error, the
+// upload is stopped too. If the server only finishes its response, upload continues until the client
+// closes its side, and then endUpload is called if not nil.
+func (this *Client) transfer(conn readDeadliner, writer v2io.Writer, ray ray.OutboundRay, readResponse func() error, endUpload func() error) error {
+	responseDone := make(chan error, 1)
+	go func() {
+		err := readResponse()
+		if err == io.EOF {
+			err = nil
 		}
-		if err := writer.Write(payload); err != nil {
-			return errors.New("Shadowsocks|Client: Failed to write payload: " + err.Error())
+		if err != nil {
+			ray.OutboundInput().Release()
+		}
+		responseDone <- err
+	}()
+
+	uploadErr := v2io.Pipe(ray.OutboundInput(), writer)
+	if uploadErr == io.EOF {
+		uploadErr = nil
+		if endUpload != nil {
+			uploadErr = endUpload()
 		}
-		v2io.Pipe(ray.OutboundInput(), writer)
-
-		responseMutex.Lock()
+	}
+	if uploadErr != nil {
+		// The connection is no longer usable. Fails the pending read to unblock the response goroutine.
+		conn.SetReadDeadline(time.Now())
 	}
 
+	if err := <-responseDone; err != nil {
+		return err
+	}
+	if uploadErr != nil {
+		return errors.New("Shadowsocks|Client: Failed to write payload: " + uploadErr.Error())
+	}
 	return nil
 }
 
+// countHandshake counts the result of a TCP handshake with account. A handshake succeeds if the server
+// responds.
+func (this *Client) countHandshake(account *ShadowsocksAccount, success bool) {
+	method := account.CipherType.Name()
+	if success {
+		this.handshakes.Get(method + ">>>success").Add(1)
+	} else {
+		this.handshakes.Get(method + ">>>failure").Add(1)
+	}
+}
+
+type ClientDescription struct {
+	Servers []api.ServerState `json:"servers"`
+}
+
+// Describe implements DescribableOutboundHandler.Describe(). Servers include the ones from the
+// subscription or the server source, with their current weights.
+func (this *Client) Describe() interface{} {
+	return &ClientDescription{
+		Servers: api.NewServerStates(this.serverList),
+	}
+}
+
+// Close stops refreshing the subscription or the server source, checking servers and watching for stalls,
+// if any. Connections in progress are not affected. It is safe to call Close more than once.
+func (this *Client) Close() {
+	if this.fetcher != nil {
+		this.fetcher.Close()
+	}
+	if this.sourceWatcher != nil {
+		this.sourceWatcher.Close()
+	}
+	this.warmup.Close()
+	this.reuse.Close()
+	this.health.Close()
+	this.watchdog.Close()
+	if this.observatory != nil {
+		this.observatory.Unregister(this.observedAs, this)
+	}
+}
+
+// SupportsNetwork returns true if requests to destinations in network can be sent. UDP requests are sent
+// to servers in UDP, which doesn't work through upstream proxy.
+func (this *Client) SupportsNetwork(network v2net.Network) bool {
+	return this.meta.GetDialerOptions().SupportsNetwork(network)
+}
+
 type ClientFactory struct{}
 
 func (this *ClientFactory) StreamCapability() v2net.NetworkList {
 	return v2net.NetworkList{
-		Network: []v2net.Network{v2net.Network_TCP, v2net.Network_RawTCP},
+		Network: []v2net.Network{v2net.Network_TCP, v2net.Network_RawTCP, v2net.Network_GRPC},
 	}
 }
 
@@ -0,0 +1,774 @@
+package shadowsocks_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	_ "v2ray.com/core/transport/internet/tcp"
+	"v2ray.com/core/transport/ray"
+)
+
+var (
+	testAccount = &Account{
+		Password:   "v2ray-password",
+		CipherType: CipherType_AES_128_CFB,
+		Ota:        Account_Disabled,
+	}
+	testDestination = v2net.TCPDestination(v2net.DomainAddress("www.v2ray.com"), v2net.Port(80))
+)
+
+func newTestUser() *protocol.User {
+	return &protocol.User{
+		Account: loader.NewTypedSettings(testAccount),
+	}
+}
+
+type testServer struct {
+	listener *net.TCPListener
+	done     chan bool
+}
+
+// startTestServer accepts one Shadowsocks connection and passes it to handler.
+func startTestServer(assert *assert.Assert, handler func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer)) *testServer {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	server := &testServer{
+		listener: listener,
+		done:     make(chan bool),
+	}
+	go func() {
+		defer close(server.done)
+
+		conn, err := listener.AcceptTCP()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		request, reader, err := ReadTCPSession(newTestUser(), conn)
+		assert.Error(err).IsNil()
+		assert.Address(request.Address).Equals(testDestination.Address)
+		writer, err := WriteTCPResponse(request, conn)
+		assert.Error(err).IsNil()
+		handler(conn, reader, writer)
+	}()
+	return server
+}
+
+func (this *testServer) Close() {
+	this.listener.Close()
+	<-this.done
+}
+
+func (this *testServer) Port() v2net.Port {
+	return v2net.Port(this.listener.Addr().(*net.TCPAddr).Port)
+}
+
+func newTestClient(assert *assert.Assert, port v2net.Port) proxy.OutboundHandler {
+	return newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(port),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+	})
+}
+
+func newTestClientWithConfig(assert *assert.Assert, config *ClientConfig) proxy.OutboundHandler {
+	space := app.NewSpace()
+	client, err := NewClient(config, space, &proxy.OutboundHandlerMeta{
+		Address: v2net.AnyIP,
+		StreamSettings: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+	})
+	assert.Error(err).IsNil()
+	assert.Error(space.Initialize()).IsNil()
+	return client
+}
+
+// readAll reads from reader until size bytes are received.
+func readAll(assert *assert.Assert, reader v2io.Reader, size int) string {
+	var data []byte
+	for len(data) < size {
+		buffer, err := reader.Read()
+		assert.Error(err).IsNil()
+		data = append(data, buffer.Value...)
+		buffer.Release()
+	}
+	return string(data)
+}
+
+func dispatch(client proxy.OutboundHandler, payload string, traffic ray.Ray) chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- client.Dispatch(testDestination, alloc.NewLocalBuffer(2048).Clear().AppendString(payload), traffic)
+	}()
+	return result
+}
+
+func waitForDispatch(assert *assert.Assert, result chan error) error {
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(time.Second * 10):
+		assert.Fail("Dispatch didn't return.")
+		return nil
+	}
+}
+
+// assertNoGoroutineLeak waits for goroutines to exit, until there are no more than before.
+func assertNoGoroutineLeak(assert *assert.Assert, before int) {
+	for i := 0; i < 100; i++ {
+		if runtime.NumGoroutine() <= before {
+			return
+		}
+		time.Sleep(time.Millisecond * 20)
+	}
+	buffer := make([]byte, 1<<16)
+	assert.Fail("Goroutines leaked: " + string(buffer[:runtime.Stack(buffer, true)]))
+}
+
+func TestClientServerHalfClose(t *testing.T) {
+	assert := assert.On(t)
+	goroutines := runtime.NumGoroutine()
+
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readAll(assert, reader, 5)).Equals("first")
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+		assert.Error(conn.CloseWrite()).IsNil()
+
+		// Upload continues after the response is finished.
+		assert.String(readAll(assert, reader, 6)).Equals("second")
+		_, err := reader.Read()
+		assert.Error(err).IsNotNil()
+	})
+	client := newTestClient(assert, server.Port())
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "first", traffic)
+
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	assert.Error(traffic.InboundInput().Write(alloc.NewLocalBuffer(2048).Clear().AppendString("second"))).IsNil()
+	traffic.InboundInput().Close()
+
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+	_, err := traffic.InboundOutput().Read()
+	assert.Error(err).IsNotNil()
+	server.Close()
+	assertNoGoroutineLeak(assert, goroutines)
+}
+
+func TestClientSimultaneousClose(t *testing.T) {
+	assert := assert.On(t)
+	goroutines := runtime.NumGoroutine()
+
+	traffic := ray.NewRay()
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readAll(assert, reader, 7)).Equals("request")
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+		traffic.InboundInput().Close()
+	})
+	client := newTestClient(assert, server.Port())
+
+	result := dispatch(client, "request", traffic)
+
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	server.Close()
+	assertNoGoroutineLeak(assert, goroutines)
+}
+
+func TestClientConnectionResetMidStream(t *testing.T) {
+	assert := assert.On(t)
+	goroutines := runtime.NumGoroutine()
+
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readAll(assert, reader, 7)).Equals("request")
+		// Resets the connection.
+		conn.SetLinger(0)
+	})
+	client := newTestClient(assert, server.Port())
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "request", traffic)
+
+	// Client keeps its side open, while Dispatch must still return.
+	assert.Error(waitForDispatch(assert, result)).IsNotNil()
+	assert.Error(traffic.InboundInput().Write(alloc.NewLocalBuffer(2048).Clear().AppendString("more"))).IsNotNil()
+	server.Close()
+	assertNoGoroutineLeak(assert, goroutines)
+}
+
+func TestClientPartialResponseReset(t *testing.T) {
+	assert := assert.On(t)
+
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readAll(assert, reader, 7)).Equals("request")
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("partial"))).IsNil()
+		// Resets the connection after part of the response.
+		conn.SetLinger(0)
+	})
+	client := newTestClient(assert, server.Port())
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "request", traffic)
+
+	assert.String(readAll(assert, traffic.InboundOutput(), 7)).Equals("partial")
+	err := waitForDispatch(assert, result)
+	responseErr, ok := err.(*ResponseError)
+	assert.Bool(ok).IsTrue()
+	assert.Bool(responseErr.Reset).IsTrue()
+	assert.Int64(responseErr.Received).Equals(7)
+	assert.Bool(IsConnectionReset(err)).IsTrue()
+	traffic.InboundInput().Close()
+	server.Close()
+}
+
+func TestClientChaosReset(t *testing.T) {
+	assert := assert.On(t)
+	goroutines := runtime.NumGoroutine()
+
+	os.Setenv(internet.ChaosEnvKey, "enabled")
+	defer os.Unsetenv(internet.ChaosEnvKey)
+
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	space := app.NewSpace()
+	client, err := NewClient(&ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(listener.Addr().(*net.TCPAddr).Port),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+	}, space, &proxy.OutboundHandlerMeta{
+		Address: v2net.AnyIP,
+		StreamSettings: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+			Chaos: &internet.ChaosConfig{
+				ResetRate: 1,
+			},
+		},
+	})
+	assert.Error(err).IsNil()
+	assert.Error(space.Initialize()).IsNil()
+
+	for i := 0; i < 3; i++ {
+		traffic := ray.NewRay()
+		assert.Error(waitForDispatch(assert, dispatch(client, "request", traffic))).IsNotNil()
+	}
+	listener.Close()
+	assertNoGoroutineLeak(assert, goroutines)
+}
+
+func TestClientCloseStopsSubscription(t *testing.T) {
+	assert := assert.On(t)
+
+	// Subscription fetches never finish by themselves.
+	subscriptionServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		<-request.Context().Done()
+	}))
+	defer subscriptionServer.Close()
+
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		space := app.NewSpace()
+		client, err := NewClient(&ClientConfig{
+			Server: []*protocol.ServerEndpoint{
+				{
+					Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+					Port:    8388,
+					User:    []*protocol.User{newTestUser()},
+				},
+			},
+			Subscription: &Subscription{
+				Url: subscriptionServer.URL,
+			},
+		}, space, &proxy.OutboundHandlerMeta{
+			Address: v2net.AnyIP,
+		})
+		assert.Error(err).IsNil()
+		assert.Error(space.Initialize()).IsNil()
+
+		client.Close()
+		client.Close()
+	}
+	assertNoGoroutineLeak(assert, goroutines)
+}
+
+func TestClientServerSource(t *testing.T) {
+	assert := assert.On(t)
+
+	source := protocol.StaticServerSource{
+		protocol.NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, 8388), protocol.AlwaysValid(), newTestUser()),
+	}
+	meta := &proxy.OutboundHandlerMeta{
+		Address: v2net.AnyIP,
+	}
+	_, err := NewClientWithSource(&ClientConfig{
+		Subscription: &Subscription{
+			Url: "http://127.0.0.1/subscription",
+		},
+	}, app.NewSpace(), meta, source, 0)
+	assert.Error(err).Equals(ErrSubscriptionWithSource)
+
+	space := app.NewSpace()
+	client, err := NewClientWithSource(&ClientConfig{}, space, meta, source, 0)
+	assert.Error(err).IsNil()
+	assert.Error(space.Initialize()).IsNil()
+	defer client.Close()
+
+	servers := 0
+	for i := 0; i < 100 && servers == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		servers = len(client.Describe().(*ClientDescription).Servers)
+	}
+	assert.Int(servers).Equals(1)
+}
+
+func TestClientDeferredHandshake(t *testing.T) {
+	assert := assert.On(t)
+
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		// The first payload arrives with the request header.
+		payload, err := reader.Read()
+		assert.Error(err).IsNil()
+		assert.String(payload.String()).Equals("delayed")
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+	})
+	defer server.Close()
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(server.Port()),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		HandshakeDelay: 5000,
+	})
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "", traffic)
+	time.Sleep(time.Millisecond * 100)
+	assert.Error(traffic.InboundInput().Write(alloc.NewLocalBuffer(2048).Clear().AppendString("delayed"))).IsNil()
+
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+}
+
+func TestClientDeferredHandshakeWithoutPayload(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(listener.Addr().(*net.TCPAddr).Port),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		HandshakeDelay: 5000,
+	})
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "", traffic)
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+
+	// No connection is made to the server.
+	listener.SetDeadline(time.Now().Add(time.Millisecond * 100))
+	_, err = listener.Accept()
+	assert.Error(err).IsNotNil()
+}
+
+func TestClientDomainServer(t *testing.T) {
+	assert := assert.On(t)
+
+	other, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer other.Close()
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		payload, err := reader.Read()
+		assert.Error(err).IsNil()
+		assert.String(payload.String()).Equals("request")
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+	})
+	defer server.Close()
+
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(other.Addr().(*net.TCPAddr).Port),
+				User:    []*protocol.User{newTestUser()},
+			},
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(server.Port()),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		DomainServer: []*DomainServerRule{
+			{
+				Domain:  []string{"*.v2ray.com"},
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(server.Port()),
+			},
+		},
+	})
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+
+	other.SetDeadline(time.Now().Add(time.Millisecond * 100))
+	_, err = other.Accept()
+	assert.Error(err).IsNotNil()
+}
+
+func TestAdaptiveTimeout(t *testing.T) {
+	assert := assert.On(t)
+
+	var config *AdaptiveTimeoutConfig
+	assert.Int64(int64(config.GetTimeout(time.Second))).Equals(0)
+
+	config = &AdaptiveTimeoutConfig{}
+	assert.Int64(int64(config.GetTimeout(0))).Equals(int64(30 * time.Second))
+	assert.Int64(int64(config.GetTimeout(100 * time.Millisecond))).Equals(int64(2 * time.Second))
+	assert.Int64(int64(config.GetTimeout(time.Second))).Equals(int64(4 * time.Second))
+	assert.Int64(int64(config.GetTimeout(time.Minute))).Equals(int64(30 * time.Second))
+}
+
+func TestClientAdaptiveTimeout(t *testing.T) {
+	assert := assert.On(t)
+
+	// The destination takes longer to respond than the timeout, which only bounds connecting.
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readAll(assert, reader, 7)).Equals("request")
+		time.Sleep(500 * time.Millisecond)
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+	})
+	defer server.Close()
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(server.Port()),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		AdaptiveTimeout: &AdaptiveTimeoutConfig{
+			MinTimeout: 100,
+			MaxTimeout: 200,
+		},
+	}).(*Client)
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+
+	servers := client.ProbeServers()
+	assert.Int(len(servers)).Equals(1)
+	assert.Bool(servers[0].ConnectLatency().Average() > 0).IsTrue()
+	assert.Bool(servers[0].ConnectLatency().Average() < 200*time.Millisecond).IsTrue()
+}
+
+func TestClientMaxConnectionLifetime(t *testing.T) {
+	assert := assert.On(t)
+	goroutines := runtime.NumGoroutine()
+
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readAll(assert, reader, 7)).Equals("request")
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+		// Keeps the connection open until the client closes it.
+		_, err := reader.Read()
+		assert.Error(err).IsNotNil()
+	})
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(server.Port()),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		MaxConnectionLifetime: 1,
+	})
+
+	traffic := ray.NewRay()
+	start := time.Now()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+
+	// The client never closes its side, but the connection ends cleanly at its lifetime.
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+	assert.Bool(time.Since(start) >= time.Second).IsTrue()
+	_, err := traffic.InboundOutput().Read()
+	assert.Error(err).IsNotNil()
+	server.Close()
+	assertNoGoroutineLeak(assert, goroutines)
+}
+
+func TestClientPipeWatchdog(t *testing.T) {
+	assert := assert.On(t)
+
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readAll(assert, reader, 7)).Equals("request")
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+		// Stalls until the client closes the connection.
+		_, err := reader.Read()
+		assert.Error(err).IsNotNil()
+	})
+	defer server.Close()
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(server.Port()),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		PipeWatchdog: &PipeWatchdogConfig{
+			StallThreshold: 1,
+			CloseStalled:   true,
+		},
+	})
+	defer client.(*Client).Close()
+
+	traffic := ray.NewRay()
+	start := time.Now()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	assert.Error(waitForDispatch(assert, result)).IsNotNil()
+	assert.Bool(time.Since(start) >= time.Second).IsTrue()
+}
+
+const (
+	otaServerWithout = iota
+	otaServerWith
+	otaServerBroken
+)
+
+// listenOTA accepts requests by a server of mode: one without one-time auth closes requests with it, and
+// a broken one closes all requests. The one-time auth option of each request with payload is sent to
+// the channel, and the request is answered.
+func listenOTA(assert *assert.Assert, mode *int32) (*net.TCPListener, chan bool) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	user := newOTATestUser()
+	otaRequests := make(chan bool, 16)
+	go func() {
+		for {
+			conn, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				request, reader, err := ReadTCPSession(user, conn)
+				assert.Error(err).IsNil()
+				switch atomic.LoadInt32(mode) {
+				case otaServerBroken:
+					return
+				case otaServerWithout:
+					if request.Option.Has(RequestOptionOneTimeAuth) {
+						return
+					}
+				}
+				payload, err := reader.Read()
+				if err != nil {
+					// A probe without payload.
+					return
+				}
+				payload.Release()
+				otaRequests <- request.Option.Has(RequestOptionOneTimeAuth)
+				writer, err := WriteTCPResponse(request, conn)
+				assert.Error(err).IsNil()
+				assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+			}()
+		}
+	}()
+	return listener, otaRequests
+}
+
+func newOTATestUser() *protocol.User {
+	return &protocol.User{
+		Account: loader.NewTypedSettings(&Account{
+			Password:   "v2ray-password",
+			CipherType: CipherType_AES_128_CFB,
+			Ota:        Account_Auto,
+		}),
+	}
+}
+
+func newOTATestClient(assert *assert.Assert, listener *net.TCPListener) proxy.OutboundHandler {
+	return newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(listener.Addr().(*net.TCPAddr).Port),
+				User:    []*protocol.User{newOTATestUser()},
+			},
+		},
+	})
+}
+
+func TestClientOTAFallback(t *testing.T) {
+	assert := assert.On(t)
+
+	mode := int32(otaServerWithout)
+	listener, otaRequests := listenOTA(assert, &mode)
+	defer listener.Close()
+	client := newOTATestClient(assert, listener)
+
+	// The request can't be sent again, but a probe finds that the server rejects one-time auth.
+	traffic := ray.NewRay()
+	assert.Error(waitForDispatch(assert, dispatch(client, "request", traffic))).IsNotNil()
+
+	// The server is remembered to not support one-time auth.
+	traffic = ray.NewRay()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+	assert.Bool(<-otaRequests).IsFalse()
+}
+
+func TestClientOTAFallbackSendsIdempotentRequestAgain(t *testing.T) {
+	assert := assert.On(t)
+
+	mode := int32(otaServerWithout)
+	listener, otaRequests := listenOTA(assert, &mode)
+	defer listener.Close()
+	client := newOTATestClient(assert, listener)
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "GET / HTTP/1.1\r\n\r\n", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+	assert.Bool(<-otaRequests).IsFalse()
+}
+
+func TestClientOTAFallbackIgnoresBrokenConnections(t *testing.T) {
+	assert := assert.On(t)
+
+	mode := int32(otaServerBroken)
+	listener, otaRequests := listenOTA(assert, &mode)
+	defer listener.Close()
+	client := newOTATestClient(assert, listener)
+
+	traffic := ray.NewRay()
+	assert.Error(waitForDispatch(assert, dispatch(client, "request", traffic))).IsNotNil()
+
+	// Closing requests with or without one-time auth is no sign of rejecting it.
+	atomic.StoreInt32(&mode, otaServerWith)
+	traffic = ray.NewRay()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+	assert.Bool(<-otaRequests).IsTrue()
+}
+
+func TestClientProbe(t *testing.T) {
+	assert := assert.On(t)
+
+	request := "GET /generate_204 HTTP/1.1\r\nHost: www.v2ray.com\r\n\r\n"
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readAll(assert, reader, len(request))).Equals(request)
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("HTTP/1.1 204 No Content\r\n\r\n"))).IsNil()
+	})
+	defer server.Close()
+	client := newTestClient(assert, server.Port()).(*Client)
+
+	servers := client.ProbeServers()
+	assert.Int(len(servers)).Equals(1)
+	assert.Error(client.Probe(servers[0], testDestination, []byte(request), time.Second*5)).IsNil()
+}
+
+func TestClientConnectRetryPerServer(t *testing.T) {
+	assert := assert.On(t)
+
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readAll(assert, reader, 7)).Equals("request")
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+	})
+	defer server.Close()
+
+	// The first dial is lost, as if its SYN is dropped.
+	rawTCPDialer := internet.RawTCPDialer
+	defer func() {
+		internet.RawTCPDialer = rawTCPDialer
+	}()
+	var dials int32
+	internet.RawTCPDialer = func(src v2net.Address, dest v2net.Destination, options internet.DialerOptions) (internet.Connection, error) {
+		if atomic.AddInt32(&dials, 1) == 1 {
+			return nil, errors.New("dropped")
+		}
+		return rawTCPDialer(src, dest, options)
+	}
+
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(server.Port()),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		ConnectRetry: &ConnectRetryConfig{
+			ServerAttempts: 1,
+			PerServer:      2,
+		},
+	})
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+	assert.Int(int(atomic.LoadInt32(&dials))).Equals(2)
+
+	// Dials of each server are capped.
+	assert.Int((&ConnectRetryConfig{PerServer: 100}).GetEffectivePerServer()).Equals(5)
+}
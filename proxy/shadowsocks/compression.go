@@ -0,0 +1,69 @@
+package shadowsocks
+
+import (
+	"compress/flate"
+	"errors"
+	"io"
+	"time"
+
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/protocol"
+)
+
+const (
+	// RequestOptionCompression is set on requests whose payload is compressed in both directions. It is
+	// sent as bit 0x20 of the address type.
+	RequestOptionCompression = protocol.RequestOption(0x80)
+
+	// Time that a server is used without compression, after it rejects a compressed request.
+	compressionFallbackTimeout = 10 * time.Minute
+)
+
+var (
+	ErrCompressionDisabled = errors.New("Shadowsocks|Server: Compression is not enabled.")
+)
+
+// CompressedWriter is a Writer of compressed payload. The payload must be ended with End, for the peer to
+// tell its end from a truncated connection.
+type CompressedWriter struct {
+	v2io.Writer
+	compressor *compressionWriter
+}
+
+// End ends the compressed stream, after all payload is written.
+func (this *CompressedWriter) End() error {
+	return this.compressor.End()
+}
+
+// compressionWriter compresses data before writing it to the underlying writer. Each write is flushed, so
+// that the peer receives it without waiting for more data.
+type compressionWriter struct {
+	writer *flate.Writer
+}
+
+func newCompressionWriter(writer io.Writer) *compressionWriter {
+	// BestSpeed never fails with a valid level.
+	flateWriter, _ := flate.NewWriter(writer, flate.BestSpeed)
+	return &compressionWriter{
+		writer: flateWriter,
+	}
+}
+
+func (this *compressionWriter) Write(b []byte) (int, error) {
+	nBytes, err := this.writer.Write(b)
+	if err != nil {
+		return nBytes, err
+	}
+	return nBytes, this.writer.Flush()
+}
+
+// End writes the final block of the stream.
+func (this *compressionWriter) End() error {
+	return this.writer.Close()
+}
+
+// newDecompressionReader returns a reader that decompresses data from reader. A stream that ends without its
+// final block fails with io.ErrUnexpectedEOF, as the connection was closed before all payload was sent.
+func newDecompressionReader(reader io.Reader) io.Reader {
+	return flate.NewReader(reader)
+}
@@ -0,0 +1,231 @@
+package shadowsocks_test
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
+)
+
+func newCompressedRequest(option protocol.RequestOption) *protocol.RequestHeader {
+	return &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: v2net.DomainAddress("www.v2ray.com"),
+		Option:  option | RequestOptionCompression,
+		Port:    80,
+		User: &protocol.User{
+			Email: "love@v2ray.com",
+			Account: loader.NewTypedSettings(&Account{
+				Password:   "tcp-password",
+				CipherType: CipherType_AES_256_CFB,
+			}),
+		},
+	}
+}
+
+func TestTCPCompression(t *testing.T) {
+	assert := assert.On(t)
+
+	for _, option := range []protocol.RequestOption{0, RequestOptionOneTimeAuth} {
+		request := newCompressedRequest(option)
+		payload := strings.Repeat("compressible payload ", 100)
+		cache := alloc.NewLargeBuffer().Clear()
+
+		writer, err := WriteTCPRequest(request, cache)
+		assert.Error(err).IsNil()
+		assert.Error(writer.Write(alloc.NewLocalBuffer(4096).Clear().AppendString(payload))).IsNil()
+		assert.Bool(cache.Len() < len(payload)/2).IsTrue()
+
+		decodedRequest, reader, err := ReadTCPSession(request.User, cache)
+		assert.Error(err).IsNil()
+		assert.Bool(decodedRequest.Option.Has(RequestOptionCompression)).IsTrue()
+		assert.Bool(decodedRequest.Option.Has(RequestOptionOneTimeAuth)).Equals(option.Has(RequestOptionOneTimeAuth))
+		assert.String(readAll(assert, reader, len(payload))).Equals(payload)
+
+		cache.Clear()
+		responseWriter, err := WriteTCPResponse(decodedRequest, cache)
+		assert.Error(err).IsNil()
+		assert.Error(responseWriter.Write(alloc.NewLocalBuffer(4096).Clear().AppendString(payload))).IsNil()
+		assert.Bool(cache.Len() < len(payload)/2).IsTrue()
+
+		responseReader, err := ReadTCPResponseWithConfig(request, cache, 0, nil)
+		assert.Error(err).IsNil()
+		assert.String(readAll(assert, responseReader, len(payload))).Equals(payload)
+	}
+}
+
+func TestTCPCompressionTruncated(t *testing.T) {
+	assert := assert.On(t)
+
+	for _, ended := range []bool{false, true} {
+		request := newCompressedRequest(0)
+		cache := alloc.NewLargeBuffer().Clear()
+		writer, err := WriteTCPResponse(request, cache)
+		assert.Error(err).IsNil()
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+		if ended {
+			assert.Error(writer.(*CompressedWriter).End()).IsNil()
+		}
+
+		// A response without the end of its compressed stream is truncated.
+		reader, err := ReadTCPResponseWithConfig(request, cache, 0, nil)
+		assert.Error(err).IsNil()
+		assert.String(readAll(assert, reader, 8)).Equals("response")
+		_, err = reader.Read()
+		if ended {
+			assert.Error(err).Equals(io.EOF)
+		} else {
+			_, truncated := err.(*ResponseError)
+			assert.Bool(truncated).IsTrue()
+		}
+	}
+}
+
+// serveCompressionTest answers a request read from conn. A compressed request is read to its end, before
+// the response is ended.
+func serveCompressionTest(assert *assert.Assert, conn net.Conn, request *protocol.RequestHeader, reader v2io.Reader) {
+	defer conn.Close()
+	assert.String(readAll(assert, reader, 7)).Equals("request")
+	writer, err := WriteTCPResponse(request, conn)
+	assert.Error(err).IsNil()
+	assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+	if compressed, ok := writer.(*CompressedWriter); ok {
+		_, err = reader.Read()
+		assert.Error(err).Equals(io.EOF)
+		assert.Error(compressed.End()).IsNil()
+	}
+}
+
+func TestClientCompressionPerServer(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	compressed := make(chan bool, 2)
+	go func() {
+		for {
+			conn, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			request, reader, err := ReadTCPSession(newTestUser(), conn)
+			assert.Error(err).IsNil()
+			compressed <- request.Option.Has(RequestOptionCompression)
+			serveCompressionTest(assert, conn, request, reader)
+		}
+	}()
+
+	// Only servers configured to accept compression get compressed requests.
+	for _, compression := range []bool{false, true} {
+		client := newTestClientWithConfig(assert, &ClientConfig{
+			Server: []*protocol.ServerEndpoint{
+				{
+					Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+					Port:    uint32(listener.Addr().(*net.TCPAddr).Port),
+					User: []*protocol.User{
+						{
+							Account: loader.NewTypedSettings(&Account{
+								Password:    testAccount.Password,
+								CipherType:  testAccount.CipherType,
+								Ota:         Account_Disabled,
+								Compression: compression,
+							}),
+						},
+					},
+				},
+			},
+		})
+		traffic := ray.NewRay()
+		result := dispatch(client, "request", traffic)
+		assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+		traffic.InboundInput().Close()
+		assert.Error(waitForDispatch(assert, result)).IsNil()
+		assert.Bool(<-compressed).Equals(compression)
+	}
+}
+
+func TestClientCompressionFallback(t *testing.T) {
+	assert := assert.On(t)
+
+	// Acts like a server without compression, which closes compressed requests.
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	compressed := make(chan bool, 2)
+	go func() {
+		for {
+			conn, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			request, reader, err := ReadTCPSession(newTestUser(), conn)
+			assert.Error(err).IsNil()
+			compressed <- request.Option.Has(RequestOptionCompression)
+			if request.Option.Has(RequestOptionCompression) {
+				conn.Close()
+				continue
+			}
+			serveCompressionTest(assert, conn, request, reader)
+		}
+	}()
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(listener.Addr().(*net.TCPAddr).Port),
+				User: []*protocol.User{
+					{
+						Account: loader.NewTypedSettings(&Account{
+							Password:    testAccount.Password,
+							CipherType:  testAccount.CipherType,
+							Ota:         Account_Disabled,
+							Compression: true,
+						}),
+					},
+				},
+			},
+		},
+	})
+
+	traffic := ray.NewRay()
+	assert.Error(waitForDispatch(assert, dispatch(client, "request", traffic))).IsNotNil()
+	assert.Bool(<-compressed).IsTrue()
+
+	// The server is used without compression after it rejects a compressed request.
+	traffic = ray.NewRay()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+	assert.Bool(<-compressed).IsFalse()
+}
+
+// BenchmarkTCPCompression measures throughput and ratio of compressing text payload, with a new
+// connection in each iteration.
+func BenchmarkTCPCompression(b *testing.B) {
+	request := newCompressedRequest(0)
+	payload := []byte(strings.Repeat("GET /index.html HTTP/1.1\r\nHost: www.v2ray.com\r\nAccept: text/html\r\n\r\n", 100))
+	cache := alloc.NewLargeBuffer().Clear()
+
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+	compressed := 0
+	for i := 0; i < b.N; i++ {
+		cache.Clear()
+		writer, _ := WriteTCPRequest(request, cache)
+		writer.Write(alloc.NewLocalBuffer(8192).Clear().Append(payload))
+		compressed += cache.Len()
+	}
+	b.ReportMetric(float64(compressed)/float64(len(payload)*b.N), "ratio")
+}
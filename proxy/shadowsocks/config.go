@@ -5,15 +5,43 @@ import (
 	"crypto/cipher"
 	"crypto/md5"
 	"errors"
+	"time"
 
 	"v2ray.com/core/common/crypto"
 	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/transport/internet/shadowtls"
 )
 
 type ShadowsocksAccount struct {
+	CipherType  CipherType
 	Cipher      Cipher
 	Key         []byte
 	OneTimeAuth Account_OneTimeAuth
+	Obfs        *ObfsConfig
+	ShadowTLS   *shadowtls.Config
+	// Whether the server accepts compressed TCP requests.
+	Compression bool
+	// Whether the server accepts connection reuse of TCP requests.
+	ConnectionReuse Account_ConnectionReuse
+	// Account for UDP packets, or nil if it is the same as TCP.
+	UDP *ShadowsocksAccount
+}
+
+// ForCommand returns the account to use for requests of the given command.
+func (this *ShadowsocksAccount) ForCommand(command protocol.RequestCommand) *ShadowsocksAccount {
+	if command == protocol.RequestCommandUDP && this.UDP != nil {
+		return this.UDP
+	}
+	return this
+}
+
+// getAccount returns the account of user for requests of the given command.
+func getAccount(user *protocol.User, command protocol.RequestCommand) (*ShadowsocksAccount, error) {
+	rawAccount, err := user.GetTypedAccount()
+	if err != nil {
+		return nil, err
+	}
+	return rawAccount.(*ShadowsocksAccount).ForCommand(command), nil
 }
 
 func (this *ShadowsocksAccount) Equals(another protocol.Account) bool {
@@ -23,6 +51,120 @@ func (this *ShadowsocksAccount) Equals(another protocol.Account) bool {
 	return false
 }
 
+func (this *ServerConfig) GetEffectiveMaxDomainLength() int {
+	if this.MaxDomainLength == 0 || this.MaxDomainLength > MaxDomainLength {
+		return MaxDomainLength
+	}
+	return int(this.MaxDomainLength)
+}
+
+// GetEffectiveMaxSize returns bytes of trailing data that are discarded, which is 0 in strict mode. A nil
+// TrailingDataConfig has the default size.
+func (this *TrailingDataConfig) GetEffectiveMaxSize() int {
+	if this == nil {
+		return defaultMaxTrailingData
+	}
+	if this.Strict {
+		return 0
+	}
+	if this.MaxSize == 0 {
+		return defaultMaxTrailingData
+	}
+	return int(this.MaxSize)
+}
+
+const (
+	defaultStallThreshold = 300 * time.Second
+)
+
+func (this *PipeWatchdogConfig) GetEffectiveStallThreshold() time.Duration {
+	if this.StallThreshold == 0 {
+		return defaultStallThreshold
+	}
+	return time.Duration(this.StallThreshold) * time.Second
+}
+
+const (
+	defaultConnectServerAttempts = 5
+	defaultConnectPerServer      = 1
+	// Dials of each server are capped, as each of them may take the whole connect timeout.
+	maxConnectPerServer = 5
+)
+
+func (this *ConnectRetryConfig) GetEffectiveServerAttempts() int {
+	if this == nil || this.ServerAttempts == 0 {
+		return defaultConnectServerAttempts
+	}
+	return int(this.ServerAttempts)
+}
+
+// GetEffectivePerServer returns dials of each picked server, which are at most maxConnectPerServer.
+func (this *ConnectRetryConfig) GetEffectivePerServer() int {
+	if this == nil || this.PerServer == 0 {
+		return defaultConnectPerServer
+	}
+	if this.PerServer > maxConnectPerServer {
+		return maxConnectPerServer
+	}
+	return int(this.PerServer)
+}
+
+const (
+	defaultHandshakeQueueTimeout  = time.Second
+	defaultHandshakeHeaderTimeout = 16 * time.Second
+)
+
+func (this *HandshakeLimitConfig) GetEffectiveHeaderTimeout() time.Duration {
+	if this == nil || this.HeaderTimeout == 0 {
+		return defaultHandshakeHeaderTimeout
+	}
+	return time.Duration(this.HeaderTimeout) * time.Second
+}
+
+func (this *HandshakeLimitConfig) GetEffectiveQueueTimeout() time.Duration {
+	if this.QueueTimeout == 0 {
+		return defaultHandshakeQueueTimeout
+	}
+	return time.Duration(this.QueueTimeout) * time.Millisecond
+}
+
+const (
+	defaultTimeoutMultiplier = 4
+	defaultMinTimeout        = 2 * time.Second
+	defaultMaxTimeout        = 30 * time.Second
+)
+
+// GetTimeout returns the connect timeout for a server with the given average connect latency, where 0
+// means the server has no latency sample. A nil AdaptiveTimeoutConfig returns 0, i.e., the timeout is not adaptive.
+func (this *AdaptiveTimeoutConfig) GetTimeout(latency time.Duration) time.Duration {
+	if this == nil {
+		return 0
+	}
+	multiplier := time.Duration(this.Multiplier)
+	if multiplier == 0 {
+		multiplier = defaultTimeoutMultiplier
+	}
+	minTimeout := time.Duration(this.MinTimeout) * time.Millisecond
+	if minTimeout == 0 {
+		minTimeout = defaultMinTimeout
+	}
+	maxTimeout := time.Duration(this.MaxTimeout) * time.Millisecond
+	if maxTimeout == 0 {
+		maxTimeout = defaultMaxTimeout
+	}
+	if latency == 0 {
+		return maxTimeout
+	}
+	timeout := latency * multiplier
+	if timeout < minTimeout {
+		return minTimeout
+	}
+	if timeout > maxTimeout {
+		return maxTimeout
+	}
+	return timeout
+}
+
 func (this *Account) GetCipher() (Cipher, error) {
 	switch this.CipherType {
 	case CipherType_AES_128_CFB:
@@ -30,9 +172,9 @@ func (this *Account) GetCipher() (Cipher, error) {
 	case CipherType_AES_256_CFB:
 		return &AesCfb{KeyBytes: 32}, nil
 	case CipherType_CHACHA20:
-		return &ChaCha20{IVBytes: 8}, nil
+		return &ChaCha20{IVBytes: 8, Workers: int(this.CipherWorkers)}, nil
 	case CipherType_CHACHA20_IEFT:
-		return &ChaCha20{IVBytes: 12}, nil
+		return &ChaCha20{IVBytes: 12, Workers: int(this.CipherWorkers)}, nil
 	default:
 		return nil, errors.New("Unsupported cipher.")
 	}
@@ -43,11 +185,54 @@ func (this *Account) AsAccount() (protocol.Account, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ShadowsocksAccount{
-		Cipher:      cipher,
-		Key:         this.GetCipherKey(),
-		OneTimeAuth: this.Ota,
-	}, nil
+	obfs, err := this.GetObfsConfig()
+	if err != nil {
+		return nil, err
+	}
+	shadowTLS, err := this.GetShadowTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	account := &ShadowsocksAccount{
+		CipherType:      this.CipherType,
+		Cipher:          cipher,
+		Key:             this.GetCipherKey(),
+		OneTimeAuth:     this.Ota,
+		Obfs:            obfs,
+		ShadowTLS:       shadowTLS,
+		Compression:     this.Compression,
+		ConnectionReuse: this.ConnectionReuse,
+	}
+	if this.Udp != nil {
+		udpAccount, err := this.GetUDPAccount().AsAccount()
+		if err != nil {
+			return nil, errors.New("Shadowsocks: Invalid UDP account: " + err.Error())
+		}
+		account.UDP = udpAccount.(*ShadowsocksAccount)
+	}
+	return account, nil
+}
+
+// GetUDPAccount returns the account for UDP relay, with unset fields filled from this account.
+func (this *Account) GetUDPAccount() *Account {
+	if this.Udp == nil {
+		return this
+	}
+	account := &Account{
+		Password:   this.Udp.Password,
+		CipherType: this.Udp.CipherType,
+		Ota:        this.Udp.Ota,
+	}
+	if len(account.Password) == 0 {
+		account.Password = this.Password
+	}
+	if account.CipherType == CipherType_UNKNOWN {
+		account.CipherType = this.CipherType
+	}
+	if account.Ota == Account_Auto {
+		account.Ota = this.Ota
+	}
+	return account
 }
 
 func (this *Account) GetCipherKey() []byte {
@@ -89,6 +274,8 @@ func (this *AesCfb) NewDecodingStream(key []byte, iv []byte) (cipher.Stream, err
 
 type ChaCha20 struct {
 	IVBytes int
+	// Number of goroutines for each stream.
+	Workers int
 }
 
 func (this *ChaCha20) KeySize() int {
@@ -100,11 +287,18 @@ func (this *ChaCha20) IVSize() int {
 }
 
 func (this *ChaCha20) NewEncodingStream(key []byte, iv []byte) (cipher.Stream, error) {
-	return crypto.NewChaCha20Stream(key, iv), nil
+	return this.newStream(key, iv), nil
 }
 
 func (this *ChaCha20) NewDecodingStream(key []byte, iv []byte) (cipher.Stream, error) {
-	return crypto.NewChaCha20Stream(key, iv), nil
+	return this.newStream(key, iv), nil
+}
+
+func (this *ChaCha20) newStream(key []byte, iv []byte) cipher.Stream {
+	if this.Workers > 1 {
+		return crypto.NewParallelChaCha20Stream(key, iv, this.Workers)
+	}
+	return crypto.NewChaCha20Stream(key, iv)
 }
 
 func PasswordToCipherKey(password string, keySize int) []byte {
@@ -10,8 +10,28 @@ It is generated from these files:
 
 It has these top-level messages:
 	Account
+	UDPAccount
 	ServerConfig
+	HandshakeLimitConfig
+	QuotaConfig
+	Subscription
+	DispatchLogConfig
+	RedundancyConfig
+	ResponseFailoverConfig
+	ConnectionReuseConfig
+	WarmupConfig
+	TrailingDataConfig
+	HealthCheckConfig
+	AdaptiveTimeoutConfig
 	ClientConfig
+	SpeedTestConfig
+	ConnectRetryConfig
+	BandwidthLimitConfig
+	GeoRegion
+	GeoProximityConfig
+	PipeWatchdogConfig
+	UDPResponseConfig
+	DomainServerRule
 */
 package shadowsocks
 
@@ -20,6 +40,10 @@ import fmt "fmt"
 import math "math"
 import v2ray_core_common_protocol "v2ray.com/core/common/protocol"
 import v2ray_core_common_protocol1 "v2ray.com/core/common/protocol"
+import v2ray_core_common_protocol2 "v2ray.com/core/common/protocol"
+import v2ray_core_common_log "v2ray.com/core/common/log"
+import v2ray_core_common_net "v2ray.com/core/common/net"
+import v2ray_core_app_router "v2ray.com/core/app/router"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -62,9 +86,41 @@ func (x CipherType) String() string {
 }
 func (CipherType) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+// Requests to domains are always sent with the domains, for servers to resolve them in their own networks.
+// Policies only apply to requests whose destinations are IPv6 addresses.
+type IPv6DestinationPolicy int32
+
+const (
+	// Sent to servers as is.
+	IPv6DestinationPolicy_AsIs IPv6DestinationPolicy = 0
+	// Rejected, so that clients fall back to IPv4, e.g., by Happy Eyeballs.
+	IPv6DestinationPolicy_Reject IPv6DestinationPolicy = 1
+	// Sent with the domain sniffed from the first payload, i.e., the HTTP Host header or the TLS SNI, for
+	// servers to connect to the domain by IPv4 or IPv6. Rejected if no domain is found, e.g., for UDP.
+	IPv6DestinationPolicy_SniffDomain IPv6DestinationPolicy = 2
+)
+
+var IPv6DestinationPolicy_name = map[int32]string{
+	0: "AsIs",
+	1: "Reject",
+	2: "SniffDomain",
+}
+var IPv6DestinationPolicy_value = map[string]int32{
+	"AsIs":        0,
+	"Reject":      1,
+	"SniffDomain": 2,
+}
+
+func (x IPv6DestinationPolicy) String() string {
+	return proto.EnumName(IPv6DestinationPolicy_name, int32(x))
+}
+func (IPv6DestinationPolicy) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
 type Account_OneTimeAuth int32
 
 const (
+	// Clients use one-time auth with a server, until the server rejects a request with it. Servers
+	// accept requests with or without it.
 	Account_Auto     Account_OneTimeAuth = 0
 	Account_Disabled Account_OneTimeAuth = 1
 	Account_Enabled  Account_OneTimeAuth = 2
@@ -86,10 +142,83 @@ func (x Account_OneTimeAuth) String() string {
 }
 func (Account_OneTimeAuth) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 0} }
 
+type Account_ConnectionReuse int32
+
+const (
+	// Clients with connection reuse ask the server for it, until the server closes the first such request
+	// before responding. It is then asked again after a while.
+	Account_ReuseAuto     Account_ConnectionReuse = 0
+	Account_ReuseDisabled Account_ConnectionReuse = 1
+	// Clients with connection reuse always ask the server for it.
+	Account_ReuseEnabled Account_ConnectionReuse = 2
+)
+
+var Account_ConnectionReuse_name = map[int32]string{
+	0: "ReuseAuto",
+	1: "ReuseDisabled",
+	2: "ReuseEnabled",
+}
+var Account_ConnectionReuse_value = map[string]int32{
+	"ReuseAuto":     0,
+	"ReuseDisabled": 1,
+	"ReuseEnabled":  2,
+}
+
+func (x Account_ConnectionReuse) String() string {
+	return proto.EnumName(Account_ConnectionReuse_name, int32(x))
+}
+func (Account_ConnectionReuse) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0, 1} }
+
+type HealthCheckConfig_Mode int32
+
+const (
+	// Sends a request through servers, and waits for the response.
+	HealthCheckConfig_RoundTrip HealthCheckConfig_Mode = 0
+	// Only connects to servers, including handshakes of stream settings and plugins, and closes the
+	// connection without sending a request.
+	HealthCheckConfig_ConnectOnly HealthCheckConfig_Mode = 1
+)
+
+var HealthCheckConfig_Mode_name = map[int32]string{
+	0: "RoundTrip",
+	1: "ConnectOnly",
+}
+var HealthCheckConfig_Mode_value = map[string]int32{
+	"RoundTrip":   0,
+	"ConnectOnly": 1,
+}
+
+func (x HealthCheckConfig_Mode) String() string {
+	return proto.EnumName(HealthCheckConfig_Mode_name, int32(x))
+}
+func (HealthCheckConfig_Mode) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{12, 0} }
+
 type Account struct {
 	Password   string              `protobuf:"bytes,1,opt,name=password" json:"password,omitempty"`
 	CipherType CipherType          `protobuf:"varint,2,opt,name=cipher_type,json=cipherType,enum=v2ray.core.proxy.shadowsocks.CipherType" json:"cipher_type,omitempty"`
 	Ota        Account_OneTimeAuth `protobuf:"varint,3,opt,name=ota,enum=v2ray.core.proxy.shadowsocks.Account_OneTimeAuth" json:"ota,omitempty"`
+	// Name of a SIP003 plugin, e.g. "obfs-local". Empty for no plugin.
+	Plugin string `protobuf:"bytes,4,opt,name=plugin" json:"plugin,omitempty"`
+	// Options of the plugin, in the form of "key1=value1;key2=value2".
+	PluginOpts string `protobuf:"bytes,5,opt,name=plugin_opts,json=pluginOpts" json:"plugin_opts,omitempty"`
+	// Settings for UDP relay, if they are different from TCP. Unset fields are the same as TCP. Plugins
+	// don't apply to UDP.
+	Udp *UDPAccount `protobuf:"bytes,6,opt,name=udp" json:"udp,omitempty"`
+	// Number of goroutines that encrypt and decrypt each TCP connection, for bulk transfers on multi-core
+	// machines. Only large reads and writes are split among them, and only with ChaCha20 ciphers, as AES-CFB
+	// encryption is serial by design. 0 or 1 to use a single goroutine.
+	CipherWorkers uint32 `protobuf:"varint,7,opt,name=cipher_workers,json=cipherWorkers" json:"cipher_workers,omitempty"`
+	// Whether the server accepts compressed TCP requests, i.e., it is a V2Ray server with compression
+	// enabled. Clients compress payload in both directions before encryption, except for requests that start
+	// with a TLS handshake, as encrypted data doesn't compress. Other servers may take the compression bit
+	// as part of the address type and misread the request, so it is never set unless configured here. A
+	// server that closes a compressed request before responding is used without compression for a while.
+	// Compression takes several hundred KB of memory for each connection.
+	Compression bool `protobuf:"varint,8,opt,name=compression" json:"compression,omitempty"`
+	// Whether the server accepts connection reuse, i.e., it is a V2Ray server with connection reuse
+	// enabled. Only clients with connection reuse enabled use it. Other servers take the connection reuse
+	// bit as part of the address type and close the request, which is how clients find out in auto mode.
+	ConnectionReuse Account_ConnectionReuse `protobuf:"varint,9,opt,name=connection_reuse,json=connectionReuse,enum=v2ray.core.proxy.shadowsocks.Account_ConnectionReuse" json:"connection_reuse,omitempty"`
 }
 
 func (m *Account) Reset()                    { *m = Account{} }
@@ -97,15 +226,47 @@ func (m *Account) String() string            { return proto.CompactTextString(m)
 func (*Account) ProtoMessage()               {}
 func (*Account) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+func (m *Account) GetUdp() *UDPAccount {
+	if m != nil {
+		return m.Udp
+	}
+	return nil
+}
+
+type UDPAccount struct {
+	Password   string              `protobuf:"bytes,1,opt,name=password" json:"password,omitempty"`
+	CipherType CipherType          `protobuf:"varint,2,opt,name=cipher_type,json=cipherType,enum=v2ray.core.proxy.shadowsocks.CipherType" json:"cipher_type,omitempty"`
+	Ota        Account_OneTimeAuth `protobuf:"varint,3,opt,name=ota,enum=v2ray.core.proxy.shadowsocks.Account_OneTimeAuth" json:"ota,omitempty"`
+}
+
+func (m *UDPAccount) Reset()                    { *m = UDPAccount{} }
+func (m *UDPAccount) String() string            { return proto.CompactTextString(m) }
+func (*UDPAccount) ProtoMessage()               {}
+func (*UDPAccount) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
 type ServerConfig struct {
 	UdpEnabled bool                             `protobuf:"varint,1,opt,name=udp_enabled,json=udpEnabled" json:"udp_enabled,omitempty"`
 	User       *v2ray_core_common_protocol.User `protobuf:"bytes,2,opt,name=user" json:"user,omitempty"`
+	// Maximum length of domain in requests. Requests with longer domains are rejected. 0 for the protocol
+	// maximum of 255.
+	MaxDomainLength uint32 `protobuf:"varint,3,opt,name=max_domain_length,json=maxDomainLength" json:"max_domain_length,omitempty"`
+	// Data quota and connection limit of each user. Unlimited if not set.
+	Quota *QuotaConfig `protobuf:"bytes,4,opt,name=quota" json:"quota,omitempty"`
+	// Whether TCP requests may ask for compression of their payload. Such requests are rejected if not
+	// enabled. Clients only ask for it if their accounts of this server have compression.
+	Compression bool `protobuf:"varint,5,opt,name=compression" json:"compression,omitempty"`
+	// Limit of TCP handshakes in progress. Unlimited if not set.
+	HandshakeLimit *HandshakeLimitConfig `protobuf:"bytes,6,opt,name=handshake_limit,json=handshakeLimit" json:"handshake_limit,omitempty"`
+	// Whether TCP connections may carry sequential requests from clients with connection reuse. Requests
+	// with connection reuse are rejected if disabled. Clients ask for it unless their accounts of this
+	// server disable it.
+	ConnectionReuse bool `protobuf:"varint,7,opt,name=connection_reuse,json=connectionReuse" json:"connection_reuse,omitempty"`
 }
 
 func (m *ServerConfig) Reset()                    { *m = ServerConfig{} }
 func (m *ServerConfig) String() string            { return proto.CompactTextString(m) }
 func (*ServerConfig) ProtoMessage()               {}
-func (*ServerConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*ServerConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
 
 func (m *ServerConfig) GetUser() *v2ray_core_common_protocol.User {
 	if m != nil {
@@ -114,14 +275,278 @@ func (m *ServerConfig) GetUser() *v2ray_core_common_protocol.User {
 	return nil
 }
 
+func (m *ServerConfig) GetQuota() *QuotaConfig {
+	if m != nil {
+		return m.Quota
+	}
+	return nil
+}
+
+func (m *ServerConfig) GetHandshakeLimit() *HandshakeLimitConfig {
+	if m != nil {
+		return m.HandshakeLimit
+	}
+	return nil
+}
+
+type HandshakeLimitConfig struct {
+	// Maximum number of TCP handshakes in progress at the same time. Other connections wait in a queue.
+	// Established connections are not limited.
+	Concurrency uint32 `protobuf:"varint,1,opt,name=concurrency" json:"concurrency,omitempty"`
+	// Milliseconds that a connection waits in the queue before it is dropped. Default to 1000.
+	QueueTimeout uint32 `protobuf:"varint,2,opt,name=queue_timeout,json=queueTimeout" json:"queue_timeout,omitempty"`
+	// Seconds that a server waits for the whole request header, from the first byte of a connection, so
+	// that clients sending the header slowly don't hold a handshake for long. Default to 16. Only applies
+	// to servers.
+	HeaderTimeout uint32 `protobuf:"varint,3,opt,name=header_timeout,json=headerTimeout" json:"header_timeout,omitempty"`
+}
+
+func (m *HandshakeLimitConfig) Reset()                    { *m = HandshakeLimitConfig{} }
+func (m *HandshakeLimitConfig) String() string            { return proto.CompactTextString(m) }
+func (*HandshakeLimitConfig) ProtoMessage()               {}
+func (*HandshakeLimitConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+type QuotaConfig struct {
+	// Bytes of payload that a user may transfer in both directions in each calendar month in UTC, without
+	// headers of the protocol. Usage is added to the store in batches, so a user may go over it by up to
+	// 256KB on each server. 0 for unlimited.
+	MonthlyBytes uint64 `protobuf:"varint,1,opt,name=monthly_bytes,json=monthlyBytes" json:"monthly_bytes,omitempty"`
+	// Maximum number of TCP connections of a user at the same time. 0 for unlimited.
+	MaxConnections uint32 `protobuf:"varint,2,opt,name=max_connections,json=maxConnections" json:"max_connections,omitempty"`
+}
+
+func (m *QuotaConfig) Reset()                    { *m = QuotaConfig{} }
+func (m *QuotaConfig) String() string            { return proto.CompactTextString(m) }
+func (*QuotaConfig) ProtoMessage()               {}
+func (*QuotaConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type Subscription struct {
+	// URL of the subscription, which serves a list of ss:// URIs, optionally encoded in base64.
+	Url string `protobuf:"bytes,1,opt,name=url" json:"url,omitempty"`
+	// Refresh interval in seconds. Default to 3600.
+	RefreshInterval uint32 `protobuf:"varint,2,opt,name=refresh_interval,json=refreshInterval" json:"refresh_interval,omitempty"`
+	// Maximum number of servers loaded from the subscription. Servers beyond it are dropped with a
+	// warning. Default to 1000.
+	MaxServers uint32 `protobuf:"varint,3,opt,name=max_servers,json=maxServers" json:"max_servers,omitempty"`
+	// Maximum bytes of the subscription. A larger one fails to refresh, and servers are left untouched.
+	// Default to 4194304, i.e., 4MB.
+	MaxSize uint32 `protobuf:"varint,4,opt,name=max_size,json=maxSize" json:"max_size,omitempty"`
+}
+
+func (m *Subscription) Reset()                    { *m = Subscription{} }
+func (m *Subscription) String() string            { return proto.CompactTextString(m) }
+func (*Subscription) ProtoMessage()               {}
+func (*Subscription) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+// Log levels of dispatch events. Disabled turns an event off. If not set, start is logged as Info,
+// failure as Warning, and success is not logged.
+type DispatchLogConfig struct {
+	Start   v2ray_core_common_log.LogLevel `protobuf:"varint,1,opt,name=start,enum=v2ray.core.common.log.LogLevel" json:"start,omitempty"`
+	Success v2ray_core_common_log.LogLevel `protobuf:"varint,2,opt,name=success,enum=v2ray.core.common.log.LogLevel" json:"success,omitempty"`
+	Failure v2ray_core_common_log.LogLevel `protobuf:"varint,3,opt,name=failure,enum=v2ray.core.common.log.LogLevel" json:"failure,omitempty"`
+	// Milliseconds for connections to be established, i.e., connected to a server and handshaken for TCP,
+	// above which they are logged at the slow level with the time of each step: waiting for other dials to
+	// the destination, failed dials, the successful dial of transport layers, sending the request, and
+	// waiting for the first byte of response. Disabled if 0.
+	SlowThreshold uint32                         `protobuf:"varint,4,opt,name=slow_threshold,json=slowThreshold" json:"slow_threshold,omitempty"`
+	Slow          v2ray_core_common_log.LogLevel `protobuf:"varint,5,opt,name=slow,enum=v2ray.core.common.log.LogLevel" json:"slow,omitempty"`
+}
+
+func (m *DispatchLogConfig) Reset()                    { *m = DispatchLogConfig{} }
+func (m *DispatchLogConfig) String() string            { return proto.CompactTextString(m) }
+func (*DispatchLogConfig) ProtoMessage()               {}
+func (*DispatchLogConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+// Sends each UDP request to multiple servers at the same time, and uses the first response. Requests
+// must be idempotent, so it only applies to the listed destination ports.
+type RedundancyConfig struct {
+	// Number of servers to send to. Redundancy is disabled if it is less than 2.
+	Copies uint32 `protobuf:"varint,1,opt,name=copies" json:"copies,omitempty"`
+	// Destination ports that redundancy applies to. If empty, only DNS (port 53) is applied.
+	Port []uint32 `protobuf:"varint,2,rep,packed,name=port" json:"port,omitempty"`
+}
+
+func (m *RedundancyConfig) Reset()                    { *m = RedundancyConfig{} }
+func (m *RedundancyConfig) String() string            { return proto.CompactTextString(m) }
+func (*RedundancyConfig) ProtoMessage()               {}
+func (*RedundancyConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+// Sends a TCP request to another server, if the server doesn't start responding in time. Requests must
+// be idempotent, so it only applies to the listed destination ports. The whole request must be in the
+// first payload, because later data from the client waits until a server starts responding.
+type ResponseFailoverConfig struct {
+	// Milliseconds to wait for a server to start responding. Failover is disabled if 0.
+	Timeout uint32 `protobuf:"varint,1,opt,name=timeout" json:"timeout,omitempty"`
+	// Destination ports of idempotent requests. Failover never applies to other ports.
+	Port []uint32 `protobuf:"varint,2,rep,packed,name=port" json:"port,omitempty"`
+	// Number of servers that a request may be sent to, including the first one. Default to 2.
+	MaxAttempts uint32 `protobuf:"varint,3,opt,name=max_attempts,json=maxAttempts" json:"max_attempts,omitempty"`
+}
+
+func (m *ResponseFailoverConfig) Reset()                    { *m = ResponseFailoverConfig{} }
+func (m *ResponseFailoverConfig) String() string            { return proto.CompactTextString(m) }
+func (*ResponseFailoverConfig) ProtoMessage()               {}
+func (*ResponseFailoverConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+// Reuse of TCP connections to servers for sequential requests, which saves the connection and IV of each
+// request. Servers are asked to keep connections unless their accounts disable connection reuse, or they
+// reject it in auto mode. Requests with compression or one-time auth don't reuse connections, and
+// requests on reused connections only apply timeouts of routing policies.
+type ConnectionReuseConfig struct {
+	// Seconds that an idle connection is kept for the next request. Default to 30, and at most 50, as
+	// servers close connections idle for 60 seconds.
+	IdleTimeout uint32 `protobuf:"varint,1,opt,name=idle_timeout,json=idleTimeout" json:"idle_timeout,omitempty"`
+	// Maximum number of idle connections to each server. Default to 4.
+	MaxIdle uint32 `protobuf:"varint,2,opt,name=max_idle,json=maxIdle" json:"max_idle,omitempty"`
+}
+
+func (m *ConnectionReuseConfig) Reset()                    { *m = ConnectionReuseConfig{} }
+func (m *ConnectionReuseConfig) String() string            { return proto.CompactTextString(m) }
+func (*ConnectionReuseConfig) ProtoMessage()               {}
+func (*ConnectionReuseConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+// Connections opened to servers in advance, so that requests don't wait for connections, including TLS
+// handshakes of the stream settings.
+type WarmupConfig struct {
+	// Number of connections kept open to each server of the highest weight. 0 to disable.
+	Connections uint32 `protobuf:"varint,1,opt,name=connections" json:"connections,omitempty"`
+	// Seconds an unused connection is kept open before it is replaced by a new one. It must be shorter than
+	// the idle timeout of servers. Default to 30.
+	IdleTimeout uint32 `protobuf:"varint,2,opt,name=idle_timeout,json=idleTimeout" json:"idle_timeout,omitempty"`
+}
+
+func (m *WarmupConfig) Reset()                    { *m = WarmupConfig{} }
+func (m *WarmupConfig) String() string            { return proto.CompactTextString(m) }
+func (*WarmupConfig) ProtoMessage()               {}
+func (*WarmupConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+// Handling of data that servers send after a response is finished, i.e., after the client closes the
+// request. Some servers append padding to responses.
+type TrailingDataConfig struct {
+	// Whether any trailing data is treated as a corrupted response.
+	Strict bool `protobuf:"varint,1,opt,name=strict" json:"strict,omitempty"`
+	// Bytes of trailing data discarded before the response is treated as corrupted, if not strict. Default
+	// to 4096.
+	MaxSize uint32 `protobuf:"varint,2,opt,name=max_size,json=maxSize" json:"max_size,omitempty"`
+}
+
+func (m *TrailingDataConfig) Reset()                    { *m = TrailingDataConfig{} }
+func (m *TrailingDataConfig) String() string            { return proto.CompactTextString(m) }
+func (*TrailingDataConfig) ProtoMessage()               {}
+func (*TrailingDataConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+// Periodic checks of servers. Results are reported to circuit breakers of servers, so servers that fail
+// checks are skipped by requests.
+type HealthCheckConfig struct {
+	Mode HealthCheckConfig_Mode `protobuf:"varint,1,opt,name=mode,enum=v2ray.core.proxy.shadowsocks.HealthCheckConfig_Mode" json:"mode,omitempty"`
+	// Seconds between two checks of each server. Default to 60.
+	Interval uint32 `protobuf:"varint,2,opt,name=interval" json:"interval,omitempty"`
+	// Seconds to wait for each check. Default to 10.
+	Timeout uint32 `protobuf:"varint,3,opt,name=timeout" json:"timeout,omitempty"`
+	// Destination of requests in RoundTrip mode, and in the request header of ConnectOnly mode with
+	// handshake. Default to www.gstatic.com:80.
+	Address *v2ray_core_common_net.IPOrDomain `protobuf:"bytes,4,opt,name=address" json:"address,omitempty"`
+	Port    uint32                            `protobuf:"varint,5,opt,name=port" json:"port,omitempty"`
+	// Whether ConnectOnly mode also sends a request header without payload. A server that closes the
+	// connection before timeout, e.g., because of a wrong password, fails the check. The wait for it is at
+	// most 8 seconds, as servers close connections without payload after their header timeout.
+	Handshake bool `protobuf:"varint,6,opt,name=handshake" json:"handshake,omitempty"`
+}
+
+func (m *HealthCheckConfig) Reset()                    { *m = HealthCheckConfig{} }
+func (m *HealthCheckConfig) String() string            { return proto.CompactTextString(m) }
+func (*HealthCheckConfig) ProtoMessage()               {}
+func (*HealthCheckConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *HealthCheckConfig) GetAddress() *v2ray_core_common_net.IPOrDomain {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+// Timeout of connecting to servers over TCP that adapts to the recent time each server takes to connect,
+// including the handshakes of its transport, such as TLS. It overrides the connect timeout of routing
+// policies. Responses are never timed by it, as they wait for destinations.
+type AdaptiveTimeoutConfig struct {
+	// Multiple of the average connect time that a connection may take. Default to 4.
+	Multiplier uint32 `protobuf:"varint,1,opt,name=multiplier" json:"multiplier,omitempty"`
+	// Bounds of the timeout in milliseconds. Connections to servers without samples take the maximum.
+	// Default to 2000 and 30000.
+	MinTimeout uint32 `protobuf:"varint,2,opt,name=min_timeout,json=minTimeout" json:"min_timeout,omitempty"`
+	MaxTimeout uint32 `protobuf:"varint,3,opt,name=max_timeout,json=maxTimeout" json:"max_timeout,omitempty"`
+}
+
+func (m *AdaptiveTimeoutConfig) Reset()                    { *m = AdaptiveTimeoutConfig{} }
+func (m *AdaptiveTimeoutConfig) String() string            { return proto.CompactTextString(m) }
+func (*AdaptiveTimeoutConfig) ProtoMessage()               {}
+func (*AdaptiveTimeoutConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
 type ClientConfig struct {
-	Server []*v2ray_core_common_protocol1.ServerEndpoint `protobuf:"bytes,1,rep,name=server" json:"server,omitempty"`
+	Server       []*v2ray_core_common_protocol1.ServerEndpoint `protobuf:"bytes,1,rep,name=server" json:"server,omitempty"`
+	Subscription *Subscription                                 `protobuf:"bytes,2,opt,name=subscription" json:"subscription,omitempty"`
+	DispatchLog  *DispatchLogConfig                            `protobuf:"bytes,3,opt,name=dispatch_log,json=dispatchLog" json:"dispatch_log,omitempty"`
+	Redundancy   *RedundancyConfig                             `protobuf:"bytes,4,opt,name=redundancy" json:"redundancy,omitempty"`
+	// Circuit breaker on each server. Disabled if not set.
+	CircuitBreaker *v2ray_core_common_protocol2.CircuitBreakerConfig `protobuf:"bytes,5,opt,name=circuit_breaker,json=circuitBreaker" json:"circuit_breaker,omitempty"`
+	// Maximum number of connections being established to the same destination at the same time. Other
+	// requests to the destination wait for their turn. 0 for unlimited.
+	DialConcurrency uint32 `protobuf:"varint,6,opt,name=dial_concurrency,json=dialConcurrency" json:"dial_concurrency,omitempty"`
+	// Version of PROXY protocol header sent to servers before each TCP request, with the source of the
+	// request. 0 for no header, 1 or 2 for PROXY protocol v1 or v2.
+	ProxyProtocol uint32 `protobuf:"varint,7,opt,name=proxy_protocol,json=proxyProtocol" json:"proxy_protocol,omitempty"`
+	// Seconds that requests from the same source address keep using the server of the previous request,
+	// until the server fails. 0 to pick a server for every request.
+	StickyTimeout uint32 `protobuf:"varint,8,opt,name=sticky_timeout,json=stickyTimeout" json:"sticky_timeout,omitempty"`
+	// Size in bytes of each buffer read from servers. Larger buffers reduce overhead of bulk transfers,
+	// and smaller ones reduce latency of small flows. Adjusted automatically between 8K and 64K if 0.
+	BufferSize uint32 `protobuf:"varint,9,opt,name=buffer_size,json=bufferSize" json:"buffer_size,omitempty"`
+	// Milliseconds to wait for the first payload before connecting to a server, if a TCP request starts
+	// without payload. The request is sent without payload after the wait. No connection is made if the
+	// client closes before sending anything. 0 to connect immediately.
+	HandshakeDelay uint32 `protobuf:"varint,10,opt,name=handshake_delay,json=handshakeDelay" json:"handshake_delay,omitempty"`
+	// Servers for specific domains. Requests to a domain go to the server of the rule with the longest
+	// matching domain, if the server is in the list and available.
+	DomainServer []*DomainServerRule `protobuf:"bytes,11,rep,name=domain_server,json=domainServer" json:"domain_server,omitempty"`
+	// Warm connections for TCP requests. Disabled if not set.
+	Warmup *WarmupConfig `protobuf:"bytes,12,opt,name=warmup" json:"warmup,omitempty"`
+	// Handling of trailing data of TCP responses. Trailing data is discarded within the default size if
+	// not set.
+	TrailingData *TrailingDataConfig `protobuf:"bytes,13,opt,name=trailing_data,json=trailingData" json:"trailing_data,omitempty"`
+	// Health checks of servers. Disabled if not set.
+	HealthCheck *HealthCheckConfig `protobuf:"bytes,14,opt,name=health_check,json=healthCheck" json:"health_check,omitempty"`
+	// Adaptive timeout of connecting to servers. Connect timeout of routing policies is used if not set.
+	AdaptiveTimeout *AdaptiveTimeoutConfig `protobuf:"bytes,15,opt,name=adaptive_timeout,json=adaptiveTimeout" json:"adaptive_timeout,omitempty"`
+	// Seconds that a TCP request may use the same server connection. The connection is closed when it
+	// reaches the lifetime, even if still active, so that the next request picks a server again. 0 for
+	// unlimited.
+	MaxConnectionLifetime uint32 `protobuf:"varint,17,opt,name=max_connection_lifetime,json=maxConnectionLifetime" json:"max_connection_lifetime,omitempty"`
+	// Watchdog of TCP requests that stop moving data. Disabled if not set.
+	PipeWatchdog *PipeWatchdogConfig `protobuf:"bytes,18,opt,name=pipe_watchdog,json=pipeWatchdog" json:"pipe_watchdog,omitempty"`
+	// Picking servers by geographic proximity to destinations. Disabled if not set.
+	GeoProximity *GeoProximityConfig `protobuf:"bytes,19,opt,name=geo_proximity,json=geoProximity" json:"geo_proximity,omitempty"`
+	// Handling of UDP associations that end without any response. They are always counted by server.
+	UdpResponse *UDPResponseConfig `protobuf:"bytes,20,opt,name=udp_response,json=udpResponse" json:"udp_response,omitempty"`
+	// Limit of TCP handshakes in progress. Unlimited if not set.
+	HandshakeLimit *HandshakeLimitConfig `protobuf:"bytes,21,opt,name=handshake_limit,json=handshakeLimit" json:"handshake_limit,omitempty"`
+	// Limit of bandwidth from this outbound to servers, shared by all its connections. Unlimited if not set.
+	BandwidthLimit *BandwidthLimitConfig `protobuf:"bytes,22,opt,name=bandwidth_limit,json=bandwidthLimit" json:"bandwidth_limit,omitempty"`
+	// Failover of idempotent TCP requests whose servers don't start responding. Disabled if not set.
+	ResponseFailover *ResponseFailoverConfig `protobuf:"bytes,23,opt,name=response_failover,json=responseFailover" json:"response_failover,omitempty"`
+	// Reuse of TCP connections for sequential requests. Disabled if not set.
+	ConnectionReuse *ConnectionReuseConfig `protobuf:"bytes,24,opt,name=connection_reuse,json=connectionReuse" json:"connection_reuse,omitempty"`
+	// Retries of connecting to servers.
+	ConnectRetry *ConnectRetryConfig `protobuf:"bytes,25,opt,name=connect_retry,json=connectRetry" json:"connect_retry,omitempty"`
+	// Endpoints of speed tests through servers by the admin API. Disabled if not set.
+	SpeedTest *SpeedTestConfig `protobuf:"bytes,26,opt,name=speed_test,json=speedTest" json:"speed_test,omitempty"`
+	// Handling of requests to IPv6 destinations, for servers that can't reach IPv6.
+	Ipv6Destination IPv6DestinationPolicy `protobuf:"varint,27,opt,name=ipv6_destination,json=ipv6Destination,enum=v2ray.core.proxy.shadowsocks.IPv6DestinationPolicy" json:"ipv6_destination,omitempty"`
 }
 
 func (m *ClientConfig) Reset()                    { *m = ClientConfig{} }
 func (m *ClientConfig) String() string            { return proto.CompactTextString(m) }
 func (*ClientConfig) ProtoMessage()               {}
-func (*ClientConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*ClientConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
 
 func (m *ClientConfig) GetServer() []*v2ray_core_common_protocol1.ServerEndpoint {
 	if m != nil {
@@ -130,43 +555,431 @@ func (m *ClientConfig) GetServer() []*v2ray_core_common_protocol1.ServerEndpoint
 	return nil
 }
 
+func (m *ClientConfig) GetSubscription() *Subscription {
+	if m != nil {
+		return m.Subscription
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetDispatchLog() *DispatchLogConfig {
+	if m != nil {
+		return m.DispatchLog
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetRedundancy() *RedundancyConfig {
+	if m != nil {
+		return m.Redundancy
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetCircuitBreaker() *v2ray_core_common_protocol2.CircuitBreakerConfig {
+	if m != nil {
+		return m.CircuitBreaker
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetDomainServer() []*DomainServerRule {
+	if m != nil {
+		return m.DomainServer
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetWarmup() *WarmupConfig {
+	if m != nil {
+		return m.Warmup
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetTrailingData() *TrailingDataConfig {
+	if m != nil {
+		return m.TrailingData
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetHealthCheck() *HealthCheckConfig {
+	if m != nil {
+		return m.HealthCheck
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetAdaptiveTimeout() *AdaptiveTimeoutConfig {
+	if m != nil {
+		return m.AdaptiveTimeout
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetPipeWatchdog() *PipeWatchdogConfig {
+	if m != nil {
+		return m.PipeWatchdog
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetGeoProximity() *GeoProximityConfig {
+	if m != nil {
+		return m.GeoProximity
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetUdpResponse() *UDPResponseConfig {
+	if m != nil {
+		return m.UdpResponse
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetHandshakeLimit() *HandshakeLimitConfig {
+	if m != nil {
+		return m.HandshakeLimit
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetBandwidthLimit() *BandwidthLimitConfig {
+	if m != nil {
+		return m.BandwidthLimit
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetResponseFailover() *ResponseFailoverConfig {
+	if m != nil {
+		return m.ResponseFailover
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetConnectionReuse() *ConnectionReuseConfig {
+	if m != nil {
+		return m.ConnectionReuse
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetConnectRetry() *ConnectRetryConfig {
+	if m != nil {
+		return m.ConnectRetry
+	}
+	return nil
+}
+
+func (m *ClientConfig) GetSpeedTest() *SpeedTestConfig {
+	if m != nil {
+		return m.SpeedTest
+	}
+	return nil
+}
+
+// Speed tests send and receive data through a server by the client, as requests do. Downloads are GET
+// requests to download_url, and uploads are POST requests to upload_url. Only HTTP URLs are supported.
+type SpeedTestConfig struct {
+	// URL whose response is at least as large as downloads, e.g., "http://speed.example.com/down?bytes=1e9".
+	DownloadUrl string `protobuf:"bytes,1,opt,name=download_url,json=downloadUrl" json:"download_url,omitempty"`
+	// URL that accepts uploads. Uploads are disabled if not set.
+	UploadUrl string `protobuf:"bytes,2,opt,name=upload_url,json=uploadUrl" json:"upload_url,omitempty"`
+}
+
+func (m *SpeedTestConfig) Reset()                    { *m = SpeedTestConfig{} }
+func (m *SpeedTestConfig) String() string            { return proto.CompactTextString(m) }
+func (*SpeedTestConfig) ProtoMessage()               {}
+func (*SpeedTestConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+// Each server picked for a request is dialed up to per_server times in a row, before the next server is
+// picked, up to server_attempts servers. Only the last failure of a server counts for its circuit breaker.
+type ConnectRetryConfig struct {
+	// Servers to pick for a request. Default to 5.
+	ServerAttempts uint32 `protobuf:"varint,1,opt,name=server_attempts,json=serverAttempts" json:"server_attempts,omitempty"`
+	// Dials of each picked server, for servers that occasionally drop the first SYN. Default to 1, and at
+	// most 5. Each dial has its own connect timeout, of the routing policy or adaptive, so a request may
+	// wait up to server_attempts * per_server connect timeouts before it fails.
+	PerServer uint32 `protobuf:"varint,2,opt,name=per_server,json=perServer" json:"per_server,omitempty"`
+}
+
+func (m *ConnectRetryConfig) Reset()                    { *m = ConnectRetryConfig{} }
+func (m *ConnectRetryConfig) String() string            { return proto.CompactTextString(m) }
+func (*ConnectRetryConfig) ProtoMessage()               {}
+func (*ConnectRetryConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+type BandwidthLimitConfig struct {
+	// Bytes per second. 0 for unlimited.
+	Rate uint64 `protobuf:"varint,1,opt,name=rate" json:"rate,omitempty"`
+	// Bytes that may be sent at once after idle time. Default to 1/10 of rate, and at least 16 KB.
+	Burst uint64 `protobuf:"varint,2,opt,name=burst" json:"burst,omitempty"`
+}
+
+func (m *BandwidthLimitConfig) Reset()                    { *m = BandwidthLimitConfig{} }
+func (m *BandwidthLimitConfig) String() string            { return proto.CompactTextString(m) }
+func (*BandwidthLimitConfig) ProtoMessage()               {}
+func (*BandwidthLimitConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+type GeoRegion struct {
+	// Name of the region, as in the region of servers.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// IP ranges of the region.
+	Cidr []*v2ray_core_app_router.CIDR `protobuf:"bytes,2,rep,name=cidr" json:"cidr,omitempty"`
+	// Location of the region in degrees, to find the nearest region of servers.
+	Latitude  float64 `protobuf:"fixed64,3,opt,name=latitude" json:"latitude,omitempty"`
+	Longitude float64 `protobuf:"fixed64,4,opt,name=longitude" json:"longitude,omitempty"`
+}
+
+func (m *GeoRegion) Reset()                    { *m = GeoRegion{} }
+func (m *GeoRegion) String() string            { return proto.CompactTextString(m) }
+func (*GeoRegion) ProtoMessage()               {}
+func (*GeoRegion) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *GeoRegion) GetCidr() []*v2ray_core_app_router.CIDR {
+	if m != nil {
+		return m.Cidr
+	}
+	return nil
+}
+
+// Requests go to the available server nearest to the region of their destination, so that the onward path
+// from the server is short. Domains are resolved by the DNS app to find their regions. Requests to
+// destinations outside all regions, and requests when no server has a known region, use the normal picker.
+// Domain server rules take precedence.
+type GeoProximityConfig struct {
+	Region []*GeoRegion `protobuf:"bytes,1,rep,name=region" json:"region,omitempty"`
+}
+
+func (m *GeoProximityConfig) Reset()                    { *m = GeoProximityConfig{} }
+func (m *GeoProximityConfig) String() string            { return proto.CompactTextString(m) }
+func (*GeoProximityConfig) ProtoMessage()               {}
+func (*GeoProximityConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *GeoProximityConfig) GetRegion() []*GeoRegion {
+	if m != nil {
+		return m.Region
+	}
+	return nil
+}
+
+// A watchdog of TCP requests that move no bytes on their connections in either direction for a long
+// time, while the connections are still open, e.g., when the client stops reading. Idle timeouts don't
+// catch these if the server keeps sending. Any byte counts as progress, so servers trickling data are not
+// stalled. The number of stalled requests is exposed as counter "stalled" by the API.
+type PipeWatchdogConfig struct {
+	// Seconds without progress before a request is stalled. Default to 300.
+	StallThreshold uint32 `protobuf:"varint,1,opt,name=stall_threshold,json=stallThreshold" json:"stall_threshold,omitempty"`
+	// Whether to close stalled requests.
+	CloseStalled bool `protobuf:"varint,2,opt,name=close_stalled,json=closeStalled" json:"close_stalled,omitempty"`
+}
+
+func (m *PipeWatchdogConfig) Reset()                    { *m = PipeWatchdogConfig{} }
+func (m *PipeWatchdogConfig) String() string            { return proto.CompactTextString(m) }
+func (*PipeWatchdogConfig) ProtoMessage()               {}
+func (*PipeWatchdogConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+type UDPResponseConfig struct {
+	// Whether destinations are expected to respond to UDP requests, e.g., DNS. If so, an association that
+	// times out without any response is logged as a warning, as the requests or responses are likely lost.
+	Expected bool `protobuf:"varint,1,opt,name=expected" json:"expected,omitempty"`
+}
+
+func (m *UDPResponseConfig) Reset()                    { *m = UDPResponseConfig{} }
+func (m *UDPResponseConfig) String() string            { return proto.CompactTextString(m) }
+func (*UDPResponseConfig) ProtoMessage()               {}
+func (*UDPResponseConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+type DomainServerRule struct {
+	// Domain suffixes. A suffix matches the domain itself and all its subdomains. Leading "*." is ignored.
+	Domain []string `protobuf:"bytes,1,rep,name=domain" json:"domain,omitempty"`
+	// Destination of a server in the server list.
+	Address *v2ray_core_common_net.IPOrDomain `protobuf:"bytes,2,opt,name=address" json:"address,omitempty"`
+	Port    uint32                            `protobuf:"varint,3,opt,name=port" json:"port,omitempty"`
+}
+
+func (m *DomainServerRule) Reset()                    { *m = DomainServerRule{} }
+func (m *DomainServerRule) String() string            { return proto.CompactTextString(m) }
+func (*DomainServerRule) ProtoMessage()               {}
+func (*DomainServerRule) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *DomainServerRule) GetAddress() *v2ray_core_common_net.IPOrDomain {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Account)(nil), "v2ray.core.proxy.shadowsocks.Account")
+	proto.RegisterType((*UDPAccount)(nil), "v2ray.core.proxy.shadowsocks.UDPAccount")
 	proto.RegisterType((*ServerConfig)(nil), "v2ray.core.proxy.shadowsocks.ServerConfig")
+	proto.RegisterType((*HandshakeLimitConfig)(nil), "v2ray.core.proxy.shadowsocks.HandshakeLimitConfig")
+	proto.RegisterType((*QuotaConfig)(nil), "v2ray.core.proxy.shadowsocks.QuotaConfig")
+	proto.RegisterType((*Subscription)(nil), "v2ray.core.proxy.shadowsocks.Subscription")
+	proto.RegisterType((*DispatchLogConfig)(nil), "v2ray.core.proxy.shadowsocks.DispatchLogConfig")
+	proto.RegisterType((*RedundancyConfig)(nil), "v2ray.core.proxy.shadowsocks.RedundancyConfig")
+	proto.RegisterType((*ResponseFailoverConfig)(nil), "v2ray.core.proxy.shadowsocks.ResponseFailoverConfig")
+	proto.RegisterType((*ConnectionReuseConfig)(nil), "v2ray.core.proxy.shadowsocks.ConnectionReuseConfig")
+	proto.RegisterType((*WarmupConfig)(nil), "v2ray.core.proxy.shadowsocks.WarmupConfig")
+	proto.RegisterType((*TrailingDataConfig)(nil), "v2ray.core.proxy.shadowsocks.TrailingDataConfig")
+	proto.RegisterType((*HealthCheckConfig)(nil), "v2ray.core.proxy.shadowsocks.HealthCheckConfig")
+	proto.RegisterType((*AdaptiveTimeoutConfig)(nil), "v2ray.core.proxy.shadowsocks.AdaptiveTimeoutConfig")
 	proto.RegisterType((*ClientConfig)(nil), "v2ray.core.proxy.shadowsocks.ClientConfig")
+	proto.RegisterType((*SpeedTestConfig)(nil), "v2ray.core.proxy.shadowsocks.SpeedTestConfig")
+	proto.RegisterType((*ConnectRetryConfig)(nil), "v2ray.core.proxy.shadowsocks.ConnectRetryConfig")
+	proto.RegisterType((*BandwidthLimitConfig)(nil), "v2ray.core.proxy.shadowsocks.BandwidthLimitConfig")
+	proto.RegisterType((*GeoRegion)(nil), "v2ray.core.proxy.shadowsocks.GeoRegion")
+	proto.RegisterType((*GeoProximityConfig)(nil), "v2ray.core.proxy.shadowsocks.GeoProximityConfig")
+	proto.RegisterType((*PipeWatchdogConfig)(nil), "v2ray.core.proxy.shadowsocks.PipeWatchdogConfig")
+	proto.RegisterType((*UDPResponseConfig)(nil), "v2ray.core.proxy.shadowsocks.UDPResponseConfig")
+	proto.RegisterType((*DomainServerRule)(nil), "v2ray.core.proxy.shadowsocks.DomainServerRule")
 	proto.RegisterEnum("v2ray.core.proxy.shadowsocks.CipherType", CipherType_name, CipherType_value)
+	proto.RegisterEnum("v2ray.core.proxy.shadowsocks.IPv6DestinationPolicy", IPv6DestinationPolicy_name, IPv6DestinationPolicy_value)
 	proto.RegisterEnum("v2ray.core.proxy.shadowsocks.Account_OneTimeAuth", Account_OneTimeAuth_name, Account_OneTimeAuth_value)
+	proto.RegisterEnum("v2ray.core.proxy.shadowsocks.Account_ConnectionReuse", Account_ConnectionReuse_name, Account_ConnectionReuse_value)
+	proto.RegisterEnum("v2ray.core.proxy.shadowsocks.HealthCheckConfig_Mode", HealthCheckConfig_Mode_name, HealthCheckConfig_Mode_value)
 }
 
 func init() { proto.RegisterFile("v2ray.com/core/proxy/shadowsocks/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 431 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x91, 0x4f, 0x6f, 0xd3, 0x40,
-	0x10, 0xc5, 0xeb, 0x24, 0x6a, 0xc3, 0x6c, 0x00, 0xb3, 0xa7, 0x28, 0x42, 0xc2, 0xca, 0x29, 0x54,
-	0x62, 0xdd, 0x9a, 0x3f, 0xe2, 0xc0, 0xc5, 0x31, 0xa9, 0x5a, 0x21, 0xa5, 0xc8, 0x4d, 0x85, 0x84,
-	0x90, 0x2c, 0x77, 0x3d, 0x10, 0x8b, 0xd8, 0xb3, 0xda, 0xb5, 0x5b, 0xf2, 0x91, 0xf9, 0x16, 0xc8,
-	0xeb, 0x24, 0x44, 0x1c, 0xc2, 0xcd, 0x33, 0x7e, 0xef, 0xf9, 0xcd, 0xcf, 0xf0, 0xea, 0x3e, 0xd0,
-	0xe9, 0x5a, 0x48, 0x2a, 0x7c, 0x49, 0x1a, 0x7d, 0xa5, 0xe9, 0xd7, 0xda, 0x37, 0xcb, 0x34, 0xa3,
-	0x07, 0x43, 0xf2, 0xa7, 0xf1, 0x25, 0x95, 0xdf, 0xf3, 0x1f, 0x42, 0x69, 0xaa, 0x88, 0x3f, 0xdf,
-	0xca, 0x35, 0x0a, 0x2b, 0x15, 0x7b, 0xd2, 0xd1, 0xcb, 0x7f, 0xc2, 0x24, 0x15, 0x05, 0x95, 0xbe,
-	0xb5, 0x4a, 0x5a, 0xf9, 0xb5, 0x41, 0xdd, 0x06, 0x8d, 0xce, 0xfe, 0x23, 0x35, 0xa8, 0xef, 0x51,
-	0x27, 0x46, 0xa1, 0x6c, 0x1d, 0xe3, 0xdf, 0x0e, 0x9c, 0x84, 0x52, 0x52, 0x5d, 0x56, 0x7c, 0x04,
-	0x7d, 0x95, 0x1a, 0xf3, 0x40, 0x3a, 0x1b, 0x3a, 0x9e, 0x33, 0x79, 0x14, 0xef, 0x66, 0x7e, 0x05,
-	0x4c, 0xe6, 0x6a, 0x89, 0x3a, 0xa9, 0xd6, 0x0a, 0x87, 0x1d, 0xcf, 0x99, 0x3c, 0x09, 0x26, 0xe2,
-	0x50, 0x71, 0x11, 0x59, 0xc3, 0x62, 0xad, 0x30, 0x06, 0xb9, 0x7b, 0xe6, 0x11, 0x74, 0xa9, 0x4a,
-	0x87, 0x5d, 0x1b, 0x71, 0x7e, 0x38, 0x62, 0x53, 0x4d, 0x5c, 0x97, 0xb8, 0xc8, 0x0b, 0x0c, 0xeb,
-	0x6a, 0x19, 0x37, 0xee, 0x71, 0x00, 0x6c, 0x6f, 0xc7, 0xfb, 0xd0, 0x0b, 0xeb, 0x8a, 0xdc, 0x23,
-	0x3e, 0x80, 0xfe, 0xc7, 0xdc, 0xa4, 0x77, 0x2b, 0xcc, 0x5c, 0x87, 0x33, 0x38, 0x99, 0x95, 0xed,
-	0xd0, 0x19, 0x23, 0x0c, 0x6e, 0x2c, 0x80, 0xc8, 0xc2, 0xe7, 0x2f, 0x80, 0xd5, 0x99, 0x4a, 0xb0,
-	0x15, 0xd8, 0x93, 0xfb, 0x31, 0xd4, 0x99, 0xda, 0x58, 0xf8, 0x1b, 0xe8, 0x35, 0x70, 0xed, 0xb5,
-	0x2c, 0xf0, 0xf6, 0xab, 0xb6, 0x64, 0xc5, 0x96, 0xac, 0xb8, 0x35, 0xa8, 0x63, 0xab, 0x1e, 0xc7,
-	0x30, 0x88, 0x56, 0x39, 0x96, 0xd5, 0xe6, 0x33, 0x53, 0x38, 0x6e, 0xb9, 0x0f, 0x1d, 0xaf, 0x3b,
-	0x61, 0xc1, 0xe9, 0xa1, 0x9c, 0xb6, 0xe0, 0xac, 0xcc, 0x14, 0xe5, 0x65, 0x15, 0x6f, 0x9c, 0xa7,
-	0xdf, 0x00, 0xfe, 0xd2, 0x6c, 0xae, 0xba, 0x9d, 0x7f, 0x9a, 0x5f, 0x7f, 0x99, 0xbb, 0x47, 0xfc,
-	0x29, 0xb0, 0x70, 0x76, 0x93, 0x9c, 0x07, 0xef, 0x93, 0xe8, 0x62, 0xea, 0x3a, 0xdb, 0x45, 0xf0,
-	0xf6, 0x9d, 0x5d, 0x74, 0x1a, 0x24, 0xd1, 0x65, 0x18, 0x5d, 0x86, 0xc1, 0x99, 0xdb, 0xe5, 0xcf,
-	0xe0, 0xf1, 0x76, 0x4a, 0xae, 0x66, 0x17, 0x0b, 0xb7, 0x37, 0xfd, 0x00, 0x9e, 0xa4, 0xe2, 0xe0,
-	0x9f, 0x98, 0xb2, 0xf6, 0x9a, 0xcf, 0x4d, 0xd1, 0xaf, 0x6c, 0xef, 0xcd, 0xdd, 0xb1, 0x2d, 0xff,
-	0xfa, 0x4f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x59, 0xf6, 0xcd, 0xed, 0xf5, 0x02, 0x00, 0x00,
+	// 2155 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xd4, 0x58, 0xeb, 0x6e, 0x23, 0xb7,
+	0xf5, 0x8f, 0x2e, 0xbe, 0x1d, 0x49, 0x96, 0xcc, 0xff, 0xee, 0x66, 0xb2, 0xc9, 0xbf, 0x71, 0x26,
+	0x68, 0xd6, 0xbb, 0x40, 0xa4, 0x8d, 0xf6, 0x82, 0xde, 0x90, 0xd4, 0x96, 0xf7, 0x62, 0xd4, 0xdd,
+	0x75, 0x69, 0x1b, 0x8b, 0x76, 0x83, 0x4e, 0xe9, 0x19, 0x5a, 0x62, 0x3d, 0x1a, 0x4e, 0x48, 0x8e,
+	0x6d, 0xa5, 0x5f, 0x0b, 0x14, 0xe8, 0x33, 0xf4, 0x09, 0xfa, 0x04, 0x7d, 0x80, 0x3e, 0x40, 0x1f,
+	0xa8, 0x1f, 0x0a, 0x5e, 0x46, 0x1a, 0x5d, 0x6a, 0x0b, 0x45, 0xbf, 0xf4, 0xdb, 0xf0, 0x37, 0xe7,
+	0x1c, 0x92, 0xe7, 0xfc, 0xf8, 0xe3, 0x99, 0x81, 0x2f, 0x2f, 0xbb, 0x82, 0x8c, 0xda, 0x21, 0x1f,
+	0x76, 0x42, 0x2e, 0x68, 0x27, 0x15, 0xfc, 0x7a, 0xd4, 0x91, 0x03, 0x12, 0xf1, 0x2b, 0xc9, 0xc3,
+	0x0b, 0xd9, 0x09, 0x79, 0x72, 0xce, 0xfa, 0xed, 0x54, 0x70, 0xc5, 0xd1, 0x27, 0xb9, 0xb9, 0xa0,
+	0x6d, 0x63, 0xda, 0x2e, 0x98, 0xde, 0x7f, 0x38, 0x13, 0x2c, 0xe4, 0xc3, 0x21, 0x4f, 0x3a, 0xc6,
+	0x35, 0xe4, 0x71, 0x27, 0x93, 0x54, 0xd8, 0x40, 0xf7, 0x1f, 0xdf, 0x62, 0x2a, 0xa9, 0xb8, 0xa4,
+	0x22, 0x90, 0x29, 0x0d, 0x9d, 0xc7, 0xd3, 0x5b, 0x3c, 0x42, 0x26, 0xc2, 0x8c, 0xa9, 0xe0, 0x4c,
+	0x50, 0x72, 0x31, 0x9e, 0xe7, 0x8b, 0xc5, 0x5e, 0x31, 0xef, 0x4f, 0x6d, 0xec, 0xfe, 0x83, 0xc5,
+	0x76, 0x09, 0x55, 0x1d, 0x12, 0x45, 0x82, 0x4a, 0xf9, 0x6f, 0x02, 0x92, 0x34, 0xed, 0x08, 0x9e,
+	0x29, 0x2a, 0xa6, 0x02, 0xfa, 0xff, 0xa8, 0xc2, 0xda, 0x6e, 0x18, 0xf2, 0x2c, 0x51, 0xe8, 0x3e,
+	0xac, 0xa7, 0x44, 0xca, 0x2b, 0x2e, 0x22, 0xaf, 0xb4, 0x5d, 0xda, 0xd9, 0xc0, 0xe3, 0x31, 0x3a,
+	0x80, 0x5a, 0xc8, 0xd2, 0x01, 0x15, 0x81, 0x1a, 0xa5, 0xd4, 0x2b, 0x6f, 0x97, 0x76, 0x36, 0xbb,
+	0x3b, 0xed, 0x9b, 0xf2, 0xdc, 0xee, 0x19, 0x87, 0x93, 0x51, 0x4a, 0x31, 0x84, 0xe3, 0x67, 0xd4,
+	0x83, 0x0a, 0x57, 0xc4, 0xab, 0x98, 0x10, 0x5f, 0xdd, 0x1c, 0xc2, 0x2d, 0xad, 0xfd, 0x36, 0xa1,
+	0x27, 0x6c, 0x48, 0x77, 0x33, 0x35, 0xc0, 0xda, 0x1b, 0xdd, 0x83, 0xd5, 0x34, 0xce, 0xfa, 0x2c,
+	0xf1, 0xaa, 0x66, 0xa5, 0x6e, 0x84, 0x3e, 0x85, 0x9a, 0x7d, 0x0a, 0x78, 0xaa, 0xa4, 0xb7, 0x62,
+	0x5e, 0x82, 0x85, 0xde, 0xa6, 0x4a, 0xa2, 0x9f, 0x40, 0x25, 0x8b, 0x52, 0x6f, 0x75, 0xbb, 0xb4,
+	0x53, 0xbb, 0x6d, 0x03, 0xa7, 0xfb, 0x47, 0x6e, 0x01, 0x58, 0x3b, 0xa1, 0x1f, 0xc2, 0xa6, 0x4b,
+	0xc2, 0x15, 0x17, 0x17, 0x54, 0x48, 0x6f, 0x6d, 0xbb, 0xb4, 0xd3, 0xc0, 0x0d, 0x8b, 0xbe, 0xb3,
+	0x20, 0xda, 0x86, 0x5a, 0xc8, 0x87, 0xa9, 0xae, 0x06, 0xe3, 0x89, 0xb7, 0xbe, 0x5d, 0xda, 0x59,
+	0xc7, 0x45, 0x08, 0xfd, 0x0e, 0x5a, 0x21, 0x4f, 0x12, 0x1a, 0x2a, 0xc6, 0x93, 0x40, 0xd0, 0x4c,
+	0x52, 0x6f, 0xc3, 0xe4, 0xe3, 0xd9, 0x72, 0xf9, 0xe8, 0x8d, 0xbd, 0xb1, 0x76, 0xc6, 0xcd, 0x70,
+	0x1a, 0xf0, 0xbb, 0x50, 0x2b, 0xe4, 0x0c, 0xad, 0x43, 0x75, 0x37, 0x53, 0xbc, 0xf5, 0x01, 0xaa,
+	0xc3, 0xfa, 0x3e, 0x93, 0xe4, 0x2c, 0xa6, 0x51, 0xab, 0x84, 0x6a, 0xb0, 0xf6, 0x22, 0xb1, 0x83,
+	0xb2, 0xff, 0x02, 0x9a, 0x33, 0x71, 0x51, 0x03, 0x36, 0xcc, 0x83, 0x73, 0xde, 0x82, 0x86, 0x19,
+	0x16, 0x22, 0xb4, 0xa0, 0x6e, 0xa0, 0x49, 0x98, 0xbf, 0x95, 0x00, 0x26, 0x99, 0xfb, 0x5f, 0x62,
+	0x95, 0xff, 0xcf, 0x32, 0xd4, 0x8f, 0xcd, 0x91, 0xee, 0x99, 0x43, 0xa2, 0xe9, 0x94, 0x45, 0x69,
+	0x40, 0xed, 0xe6, 0xcc, 0xfa, 0xd7, 0x31, 0x64, 0x51, 0xea, 0xb6, 0x8b, 0x9e, 0x42, 0x55, 0xcb,
+	0x85, 0x59, 0x7a, 0xad, 0xbb, 0x5d, 0x9c, 0xd7, 0x9e, 0xcd, 0x76, 0x7e, 0xf2, 0xdb, 0xa7, 0x92,
+	0x0a, 0x6c, 0xac, 0xd1, 0x23, 0xd8, 0x1a, 0x92, 0xeb, 0x20, 0xe2, 0x43, 0xc2, 0x92, 0x20, 0xa6,
+	0x49, 0x5f, 0x0d, 0xcc, 0xd2, 0x1b, 0xb8, 0x39, 0x24, 0xd7, 0xfb, 0x06, 0x3f, 0x34, 0x30, 0xfa,
+	0x06, 0x56, 0xbe, 0xcb, 0xf4, 0xd6, 0xaa, 0x66, 0x8a, 0x87, 0x37, 0x6f, 0xed, 0x57, 0xda, 0xd4,
+	0x2e, 0x1e, 0x5b, 0xbf, 0x59, 0x3a, 0xae, 0xcc, 0xd3, 0xf1, 0x3d, 0x34, 0x07, 0x24, 0x89, 0xe4,
+	0x80, 0x5c, 0xd0, 0x20, 0x66, 0x43, 0xa6, 0xdc, 0xf9, 0xe8, 0xde, 0x3c, 0xd9, 0xeb, 0xdc, 0xe9,
+	0x50, 0xfb, 0xb8, 0x59, 0x37, 0x07, 0x53, 0x28, 0x7a, 0xb8, 0x80, 0xeb, 0x6b, 0x66, 0x0d, 0x73,
+	0xa4, 0xfd, 0x63, 0x09, 0xee, 0x2c, 0x8a, 0x69, 0xb7, 0x90, 0x84, 0x99, 0x10, 0x34, 0x09, 0x47,
+	0xa6, 0x0c, 0x0d, 0x5c, 0x84, 0xd0, 0xe7, 0xd0, 0xf8, 0x2e, 0xa3, 0x19, 0x0d, 0x14, 0x1b, 0x52,
+	0x9e, 0x29, 0x53, 0x90, 0x06, 0xae, 0x1b, 0xf0, 0xc4, 0x62, 0xfa, 0xfc, 0x0e, 0x28, 0x89, 0x34,
+	0xdd, 0x9c, 0x95, 0xcd, 0x79, 0xc3, 0xa2, 0xce, 0xcc, 0x7f, 0x0f, 0xb5, 0x42, 0x1a, 0x75, 0xe8,
+	0x21, 0x4f, 0xd4, 0x20, 0x1e, 0x05, 0x67, 0x23, 0x45, 0xa5, 0x99, 0xbe, 0x8a, 0xeb, 0x0e, 0xdc,
+	0xd3, 0x18, 0x7a, 0x00, 0xba, 0x70, 0xc1, 0x64, 0x47, 0xd2, 0xad, 0x60, 0x73, 0x48, 0xae, 0x27,
+	0xa7, 0x4a, 0xfa, 0x7f, 0x2a, 0x41, 0xfd, 0x38, 0x3b, 0x93, 0xa1, 0x60, 0xa9, 0x46, 0x50, 0x0b,
+	0x2a, 0x99, 0x88, 0xdd, 0xd1, 0xd0, 0x8f, 0x3a, 0x63, 0x82, 0x9e, 0x0b, 0x2a, 0x07, 0x01, 0x4b,
+	0x14, 0x15, 0x97, 0x24, 0x76, 0xc1, 0x9a, 0x0e, 0x3f, 0x70, 0xb0, 0xe6, 0xa7, 0x9e, 0xd6, 0x5e,
+	0x43, 0xd2, 0x6d, 0x07, 0x86, 0xe4, 0xda, 0xb2, 0x58, 0xa2, 0x8f, 0x60, 0xdd, 0x18, 0xb0, 0xef,
+	0xa9, 0x21, 0x50, 0x03, 0xaf, 0xe9, 0xb7, 0xec, 0x7b, 0xea, 0xff, 0xa5, 0x0c, 0x5b, 0xfb, 0x4c,
+	0xa6, 0x44, 0x85, 0x83, 0x43, 0xde, 0x77, 0xbb, 0x7d, 0x06, 0x2b, 0x52, 0x11, 0xa1, 0xcc, 0x82,
+	0x36, 0xbb, 0x9f, 0x2e, 0x60, 0x74, 0xcc, 0xfb, 0xed, 0x43, 0xde, 0x3f, 0xa4, 0x97, 0x34, 0xc6,
+	0xd6, 0x1a, 0xfd, 0x18, 0xd6, 0x64, 0x16, 0x86, 0x54, 0x4a, 0xaf, 0xbc, 0x9c, 0x63, 0x6e, 0xaf,
+	0x5d, 0xcf, 0x09, 0x8b, 0x33, 0x41, 0xbd, 0xca, 0x92, 0xae, 0xce, 0x5e, 0x17, 0x54, 0xc6, 0xfc,
+	0x2a, 0x50, 0x03, 0x9d, 0x15, 0x1e, 0x47, 0x6e, 0x8f, 0x0d, 0x8d, 0x9e, 0xe4, 0x20, 0x7a, 0x02,
+	0x55, 0x0d, 0x78, 0x2b, 0xcb, 0x85, 0x37, 0xc6, 0xfe, 0xd7, 0xd0, 0xc2, 0x34, 0xca, 0x92, 0x88,
+	0x24, 0xe1, 0xc8, 0x25, 0xe7, 0x1e, 0xac, 0x86, 0x3c, 0x65, 0x8e, 0x03, 0x0d, 0xec, 0x46, 0x08,
+	0x41, 0x35, 0xe5, 0x42, 0x93, 0xae, 0xb2, 0xd3, 0xc0, 0xe6, 0xd9, 0x67, 0x70, 0x0f, 0x53, 0x99,
+	0xf2, 0x44, 0xd2, 0x97, 0x84, 0xc5, 0x7c, 0x22, 0x2a, 0x1e, 0xac, 0xe5, 0xfc, 0xb3, 0x61, 0xf2,
+	0xe1, 0xa2, 0x38, 0xe8, 0x33, 0xa8, 0xeb, 0x0a, 0x12, 0xa5, 0xe8, 0x50, 0x5f, 0x69, 0xb6, 0xc6,
+	0xba, 0xec, 0xbb, 0x0e, 0xf2, 0x4f, 0xe1, 0xee, 0x8c, 0x70, 0xbb, 0x99, 0x3e, 0x83, 0x3a, 0x8b,
+	0x62, 0x1a, 0x4c, 0x4f, 0x57, 0xd3, 0x58, 0x7e, 0x26, 0x1c, 0x41, 0x34, 0xe4, 0x95, 0xc7, 0x04,
+	0x39, 0x88, 0x62, 0xea, 0x1f, 0x43, 0xfd, 0x1d, 0x11, 0xc3, 0x2c, 0x9d, 0x3a, 0x85, 0x63, 0x7e,
+	0x4f, 0x4e, 0x61, 0x0e, 0xcd, 0xcd, 0x57, 0x9e, 0x9b, 0xcf, 0x7f, 0x05, 0xe8, 0x44, 0x10, 0x16,
+	0xb3, 0xa4, 0xbf, 0x4f, 0xc6, 0x67, 0xec, 0x1e, 0xac, 0x4a, 0x25, 0x58, 0xa8, 0x9c, 0xc4, 0xba,
+	0xd1, 0x14, 0x7d, 0xcb, 0xd3, 0xf4, 0xfd, 0x6b, 0x19, 0xb6, 0x5e, 0x53, 0x12, 0xab, 0x41, 0x6f,
+	0x40, 0xc3, 0x0b, 0x17, 0xe8, 0x35, 0x54, 0x87, 0x3c, 0xa2, 0x8e, 0xbd, 0x4f, 0x6f, 0xd1, 0xaf,
+	0x59, 0xf7, 0xf6, 0x2f, 0x79, 0x44, 0xb1, 0x89, 0xa0, 0xef, 0xad, 0x99, 0xd3, 0x37, 0x1e, 0x17,
+	0x2b, 0x58, 0x99, 0xae, 0xe0, 0x4f, 0x61, 0xcd, 0x35, 0x62, 0x4e, 0xaf, 0x3f, 0x5b, 0xc0, 0xb6,
+	0x84, 0xaa, 0xf6, 0xc1, 0xd1, 0x5b, 0x61, 0x75, 0x1e, 0xe7, 0x1e, 0xe3, 0xf2, 0xaf, 0x98, 0x98,
+	0xe6, 0x19, 0x7d, 0x02, 0x1b, 0x63, 0x41, 0x35, 0xaa, 0xbc, 0x8e, 0x27, 0x80, 0xff, 0x05, 0x54,
+	0xf5, 0x92, 0xcd, 0x3d, 0xcd, 0xb3, 0x24, 0x3a, 0x11, 0x2c, 0x6d, 0x7d, 0x80, 0x9a, 0x50, 0x73,
+	0x84, 0x78, 0x9b, 0xc4, 0xa3, 0x56, 0xc9, 0x1f, 0xc1, 0xdd, 0xdd, 0x88, 0xa4, 0x8a, 0x5d, 0xe6,
+	0x85, 0x70, 0xf9, 0xfa, 0x01, 0xc0, 0x30, 0x8b, 0x15, 0x4b, 0x63, 0x46, 0x85, 0x2b, 0x69, 0x01,
+	0x31, 0x02, 0xc3, 0x92, 0x99, 0x82, 0xc2, 0x90, 0x25, 0x39, 0x7f, 0x9c, 0x02, 0x4d, 0xa7, 0x43,
+	0x2b, 0x50, 0x5e, 0xf0, 0xbf, 0x37, 0xa1, 0xde, 0x8b, 0x19, 0x4d, 0xf2, 0x29, 0xf7, 0x60, 0xd5,
+	0xea, 0x95, 0x57, 0xda, 0xae, 0xec, 0xd4, 0xba, 0x8f, 0x6e, 0xba, 0x34, 0xad, 0x8e, 0xbd, 0x48,
+	0xa2, 0x94, 0xb3, 0x44, 0x61, 0xe7, 0x89, 0xde, 0x40, 0x5d, 0x16, 0x44, 0xd4, 0x5d, 0xbf, 0x8f,
+	0x6e, 0x2e, 0x77, 0x51, 0x76, 0xf1, 0x94, 0x3f, 0xc2, 0x50, 0x8f, 0x9c, 0x14, 0x06, 0x31, 0xef,
+	0x9b, 0x6d, 0xd4, 0xba, 0x9d, 0x9b, 0xe3, 0xcd, 0x89, 0x27, 0xae, 0x45, 0x13, 0x08, 0xbd, 0x01,
+	0x10, 0x63, 0x01, 0x71, 0x6c, 0x68, 0xdf, 0x1c, 0x71, 0x56, 0x70, 0x70, 0x21, 0x02, 0xfa, 0x35,
+	0x34, 0x67, 0x3e, 0x1e, 0x0c, 0x51, 0x6a, 0xdd, 0xc7, 0x37, 0x25, 0xb0, 0x67, 0x5d, 0xf6, 0xac,
+	0x47, 0x7e, 0x47, 0x87, 0x53, 0xa8, 0xbe, 0x71, 0x22, 0x46, 0xe2, 0xa0, 0x78, 0xc9, 0xae, 0xda,
+	0x1b, 0x47, 0xe3, 0xbd, 0x09, 0xac, 0x25, 0xd7, 0xac, 0x3b, 0xc8, 0x67, 0xc8, 0x7b, 0x60, 0x83,
+	0x1e, 0x39, 0x50, 0x9b, 0x49, 0xc5, 0xc2, 0x8b, 0xd1, 0x98, 0x19, 0xeb, 0xd6, 0xcc, 0xa2, 0x05,
+	0xf6, 0x9c, 0x65, 0xe7, 0xe7, 0x54, 0xd8, 0x23, 0xbe, 0x61, 0xd9, 0x63, 0x21, 0x7d, 0xca, 0xf5,
+	0xbd, 0x3a, 0x69, 0x4d, 0x22, 0x1a, 0x93, 0x91, 0x07, 0xf6, 0x5e, 0x1d, 0xc3, 0xfb, 0x1a, 0x45,
+	0xc7, 0xd0, 0x70, 0xed, 0x94, 0x23, 0x57, 0x6d, 0xbb, 0x72, 0x7b, 0xc2, 0xed, 0x09, 0xb4, 0x24,
+	0xc3, 0x59, 0x4c, 0x71, 0x3d, 0x2a, 0x20, 0x9a, 0xaa, 0x57, 0x46, 0x01, 0xbd, 0xfa, 0x32, 0x04,
+	0x2b, 0xaa, 0x25, 0x76, 0x9e, 0xe8, 0x14, 0x1a, 0xca, 0x09, 0x5e, 0x10, 0x11, 0x45, 0xbc, 0xc6,
+	0x7c, 0xd1, 0xe6, 0x43, 0xcd, 0x6b, 0x24, 0xae, 0xab, 0x02, 0xa6, 0x19, 0x3b, 0x30, 0xf2, 0x15,
+	0x84, 0x5a, 0xbf, 0xbc, 0xcd, 0x65, 0x18, 0x3b, 0x27, 0x78, 0xb8, 0x36, 0x98, 0x40, 0xe8, 0xb7,
+	0xd0, 0x22, 0x4e, 0x25, 0xc6, 0x65, 0x6b, 0x9a, 0xb8, 0x4f, 0x6e, 0x69, 0xa8, 0x17, 0x69, 0x0b,
+	0x6e, 0x92, 0x69, 0x18, 0x3d, 0x87, 0x0f, 0xa7, 0x9b, 0xa4, 0x20, 0x66, 0xe7, 0x54, 0xcf, 0xe4,
+	0x6d, 0x99, 0xa2, 0xde, 0x9d, 0x6a, 0x96, 0x0e, 0xdd, 0x4b, 0x9d, 0xc2, 0x94, 0xa5, 0x34, 0xb8,
+	0xd2, 0x47, 0x2b, 0xe2, 0x7d, 0x0f, 0x2d, 0x93, 0xc2, 0x23, 0x96, 0xd2, 0x77, 0xce, 0x23, 0x4f,
+	0x61, 0x5a, 0xc0, 0x74, 0xd8, 0x3e, 0xe5, 0x9a, 0xc8, 0xd7, 0xba, 0xd7, 0x1c, 0x79, 0xff, 0xb7,
+	0x4c, 0xd8, 0x57, 0x94, 0x1f, 0xe5, 0x1e, 0x79, 0xd8, 0x7e, 0x01, 0xd3, 0x95, 0xd1, 0xdf, 0x0c,
+	0xc2, 0x5d, 0xfe, 0xde, 0x9d, 0x65, 0x2a, 0x73, 0xba, 0x7f, 0x94, 0x77, 0x0b, 0x79, 0x65, 0xb2,
+	0x28, 0xcd, 0xa1, 0x45, 0x1d, 0xfa, 0xdd, 0xff, 0x5a, 0x87, 0xfe, 0x1e, 0x9a, 0x67, 0x24, 0x89,
+	0xae, 0x58, 0xa4, 0x06, 0x2e, 0xf8, 0xbd, 0x65, 0x82, 0xef, 0xe5, 0x4e, 0x53, 0xc1, 0xcf, 0xa6,
+	0x50, 0x44, 0x60, 0x2b, 0xcf, 0x44, 0x70, 0xee, 0xfa, 0x20, 0xef, 0x43, 0x13, 0xfe, 0xe9, 0x6d,
+	0x62, 0xb8, 0xa8, 0x7b, 0xc2, 0x2d, 0x31, 0x83, 0x6b, 0xda, 0xce, 0x7d, 0x61, 0x78, 0xcb, 0xd0,
+	0x76, 0x61, 0xd3, 0x34, 0xf7, 0x59, 0xa2, 0x79, 0xe2, 0xa0, 0x40, 0x50, 0x25, 0x46, 0xde, 0x47,
+	0xcb, 0xf0, 0xc4, 0x05, 0xc7, 0xda, 0x23, 0xe7, 0x49, 0x58, 0xc0, 0xd0, 0x21, 0x80, 0x4c, 0x29,
+	0x8d, 0x02, 0x45, 0xa5, 0xf2, 0xee, 0x9b, 0x98, 0x5f, 0xde, 0x72, 0x83, 0x69, 0xfb, 0x13, 0x2a,
+	0xf3, 0x64, 0x6f, 0xc8, 0x1c, 0xd0, 0x49, 0x60, 0xe9, 0xe5, 0xf3, 0x20, 0xa2, 0x52, 0xb1, 0x84,
+	0x98, 0x5b, 0xf1, 0x63, 0xd3, 0x04, 0xdd, 0x92, 0x84, 0x83, 0xa3, 0xcb, 0xe7, 0xfb, 0x13, 0xa7,
+	0x23, 0x1e, 0xb3, 0x70, 0x84, 0x9b, 0x2c, 0x9d, 0x82, 0xfd, 0x63, 0x68, 0xce, 0xcc, 0xae, 0xbb,
+	0xbd, 0x88, 0x5f, 0x25, 0x31, 0x27, 0x51, 0x30, 0xf9, 0x84, 0xa9, 0xe5, 0xd8, 0xa9, 0x88, 0xd1,
+	0xff, 0x03, 0x64, 0xe9, 0xd8, 0xa0, 0x6c, 0x0c, 0x36, 0x2c, 0x72, 0x2a, 0x62, 0xff, 0x5b, 0x40,
+	0xf3, 0x69, 0xd2, 0x9a, 0xef, 0xfe, 0xab, 0x8d, 0x9b, 0x5e, 0xdb, 0x98, 0x6c, 0x5a, 0x38, 0xef,
+	0x7b, 0x75, 0xf4, 0x54, 0x5f, 0x1d, 0x06, 0x75, 0xbd, 0xc9, 0x46, 0x4a, 0x85, 0x55, 0x6f, 0xff,
+	0xe7, 0x70, 0x67, 0x11, 0x45, 0x75, 0x9b, 0x25, 0x88, 0xa2, 0xee, 0x3b, 0xce, 0x3c, 0xa3, 0x3b,
+	0xb0, 0x72, 0x96, 0x09, 0x69, 0x3b, 0x9c, 0x2a, 0xb6, 0x03, 0xff, 0xcf, 0x25, 0xd8, 0x78, 0x45,
+	0x39, 0xa6, 0x7d, 0xdd, 0x24, 0x20, 0xa8, 0x26, 0x64, 0x48, 0xdd, 0x3e, 0xcd, 0x33, 0xea, 0x40,
+	0x35, 0x64, 0x91, 0x30, 0x1d, 0x7b, 0xad, 0xfb, 0x71, 0x31, 0xd5, 0x24, 0x4d, 0xdb, 0xf6, 0x97,
+	0x5b, 0xbb, 0x77, 0xb0, 0x8f, 0xb1, 0x31, 0xd4, 0x6d, 0x65, 0x4c, 0x14, 0x53, 0x59, 0x64, 0x3f,
+	0x77, 0x4a, 0x78, 0x3c, 0xd6, 0xbd, 0x5e, 0xcc, 0x93, 0xbe, 0x7d, 0x59, 0x35, 0x2f, 0x27, 0x80,
+	0x7f, 0x0a, 0x68, 0x5e, 0x7b, 0xd0, 0x37, 0xb0, 0x2a, 0xcc, 0xf2, 0x5c, 0x37, 0xf5, 0xe0, 0x56,
+	0xf5, 0xb2, 0xbb, 0xc1, 0xce, 0xcd, 0x3f, 0x03, 0x34, 0xaf, 0x94, 0xa6, 0x06, 0x8a, 0xc4, 0x71,
+	0xe1, 0xd3, 0x2a, 0xaf, 0x81, 0x86, 0x27, 0xdf, 0x56, 0x9f, 0x43, 0x23, 0x8c, 0xb9, 0xa4, 0x81,
+	0xc1, 0x69, 0x64, 0x12, 0xb8, 0x8e, 0xeb, 0x06, 0x3c, 0xb6, 0x98, 0xdf, 0x81, 0xad, 0x39, 0x81,
+	0xd3, 0x99, 0xa0, 0xd7, 0x29, 0x0d, 0xd5, 0xf8, 0xc7, 0xca, 0x78, 0xec, 0xff, 0x01, 0x5a, 0xb3,
+	0x57, 0xb3, 0xfe, 0x46, 0xb0, 0x97, 0xb3, 0xd9, 0xe9, 0x06, 0x76, 0xa3, 0x62, 0xcb, 0x5d, 0xfe,
+	0x8f, 0x5b, 0xee, 0xca, 0xa4, 0xe5, 0x7e, 0xf4, 0x2d, 0xc0, 0xe4, 0x27, 0x93, 0xfe, 0x45, 0x76,
+	0xfa, 0xe6, 0x17, 0x6f, 0xde, 0xbe, 0x7b, 0x63, 0x1b, 0xeb, 0xdd, 0x17, 0xc7, 0xc1, 0x57, 0xdd,
+	0x1f, 0x05, 0xbd, 0x97, 0x7b, 0xad, 0x52, 0x0e, 0x74, 0x9f, 0x3d, 0x37, 0x40, 0x59, 0xff, 0x5f,
+	0xeb, 0xbd, 0xde, 0xed, 0xbd, 0xde, 0xed, 0x3e, 0x6e, 0x55, 0xf4, 0x0f, 0xb3, 0x7c, 0x14, 0x1c,
+	0xbc, 0x78, 0x79, 0xd2, 0xaa, 0x3e, 0xfa, 0x1a, 0xee, 0x2e, 0x3c, 0x72, 0xe6, 0x1f, 0x9d, 0x3c,
+	0x90, 0xad, 0x0f, 0x10, 0xc0, 0x2a, 0xa6, 0xbf, 0xa7, 0xa1, 0xb2, 0x13, 0x1c, 0x27, 0xec, 0xfc,
+	0xdc, 0x2e, 0xbc, 0x55, 0xde, 0xfb, 0x19, 0x6c, 0x87, 0x7c, 0x78, 0x63, 0x95, 0xf7, 0x6a, 0x36,
+	0xc5, 0xa6, 0x1b, 0xfb, 0x4d, 0xad, 0xf0, 0xe6, 0x6c, 0xd5, 0xb4, 0x6d, 0x4f, 0xfe, 0x15, 0x00,
+	0x00, 0xff, 0xff, 0x72, 0x2d, 0x0e, 0x72, 0x51, 0x17, 0x00, 0x00,
 }
@@ -0,0 +1,77 @@
+package shadowsocks
+
+import (
+	"sync"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+type dialSlots struct {
+	slots chan bool
+	users int
+}
+
+// DialLimiter limits the number of concurrent dials for each destination. A nil DialLimiter doesn't
+// limit anything.
+type DialLimiter struct {
+	sync.Mutex
+	limit   int
+	pending map[string]*dialSlots
+}
+
+func NewDialLimiter(limit uint32) *DialLimiter {
+	if limit == 0 {
+		return nil
+	}
+	return &DialLimiter{
+		limit:   int(limit),
+		pending: make(map[string]*dialSlots),
+	}
+}
+
+// Acquire blocks until a dial to destination is allowed. The returned function must be called once the
+// dial finishes.
+func (this *DialLimiter) Acquire(destination v2net.Destination) func() {
+	if this == nil {
+		return func() {}
+	}
+
+	key := destination.String()
+	this.Lock()
+	entry, found := this.pending[key]
+	if !found {
+		entry = &dialSlots{
+			slots: make(chan bool, this.limit),
+		}
+		this.pending[key] = entry
+	}
+	entry.users++
+	this.Unlock()
+
+	entry.slots <- true
+	return func() {
+		<-entry.slots
+
+		this.Lock()
+		entry.users--
+		if entry.users == 0 {
+			delete(this.pending, key)
+		}
+		this.Unlock()
+	}
+}
+
+// Pending returns the number of dials to destination that are in progress or waiting.
+func (this *DialLimiter) Pending(destination v2net.Destination) int {
+	if this == nil {
+		return 0
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	if entry, found := this.pending[destination.String()]; found {
+		return entry.users
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package shadowsocks_test
+
+import (
+	"testing"
+	"time"
+
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestDialLimiter(t *testing.T) {
+	assert := assert.On(t)
+
+	limiter := NewDialLimiter(2)
+	dest := v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 443)
+	other := v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), 80)
+
+	release1 := limiter.Acquire(dest)
+	release2 := limiter.Acquire(dest)
+
+	acquired := make(chan bool, 1)
+	go func() {
+		release := limiter.Acquire(dest)
+		acquired <- true
+		release()
+	}()
+
+	// Other destinations are not affected.
+	limiter.Acquire(other)()
+
+	select {
+	case <-acquired:
+		t.Fatal("Third dial should wait.")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Int(limiter.Pending(dest)).Equals(3)
+
+	release1()
+	<-acquired
+	release2()
+	assert.Int(limiter.Pending(dest)).Equals(0)
+	assert.Int(limiter.Pending(other)).Equals(0)
+}
+
+func TestNilDialLimiter(t *testing.T) {
+	assert := assert.On(t)
+
+	limiter := NewDialLimiter(0)
+	assert.Pointer(limiter).IsNil()
+	limiter.Acquire(v2net.TCPDestination(v2net.LocalHostIP, 80))()
+}
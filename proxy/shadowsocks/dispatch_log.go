@@ -0,0 +1,277 @@
+package shadowsocks
+
+import (
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/stats"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/internet"
+)
+
+var (
+	defaultDispatchLogConfig = &DispatchLogConfig{
+		Start:   log.LogLevel_Info,
+		Success: log.LogLevel_Disabled,
+		Failure: log.LogLevel_Warning,
+	}
+)
+
+// dispatchLogger logs start, success and failure of a dispatch, each in its own level. It also calls
+// hooks of the outbound, if any.
+type dispatchLogger struct {
+	config      *DispatchLogConfig
+	hooks       proxy.ConnectionHooks
+	outboundTag string
+	destination v2net.Destination
+	server      v2net.Destination
+	start       time.Time
+	// Time that the first and the last dials start, that a server is connected and that the request is
+	// sent. Each is zero if it doesn't happen yet.
+	firstDial time.Time
+	dialed    time.Time
+	connected time.Time
+	requested time.Time
+	timing    proxy.ConnectionTiming
+	tags      map[string]string
+	// Layers of connections to servers, and the layer that the last dial failed at, if any.
+	layers      []string
+	dialFailure string
+	handshaken  bool
+	// Tags as a JSON object, with a leading space, or empty if there is no tag.
+	tagString string
+	// Routing decision of the session, with a leading space, or empty if the session is not routed.
+	routeString string
+}
+
+func newDispatchLogger(config *DispatchLogConfig, meta *proxy.OutboundHandlerMeta, destination v2net.Destination, tags map[string]string, route *proxy.Route) *dispatchLogger {
+	if config == nil {
+		config = defaultDispatchLogConfig
+	}
+	logger := &dispatchLogger{
+		config:      config,
+		hooks:       meta.Hooks,
+		outboundTag: meta.Tag,
+		destination: destination,
+		start:       time.Now(),
+		tags:        tags,
+		layers:      append(meta.StreamSettings.TransportLayers(destination.Network), LayerShadowsocks),
+	}
+	if len(tags) > 0 {
+		if tagString, err := json.Marshal(tags); err == nil {
+			logger.tagString = " " + string(tagString)
+		}
+	}
+	if route != nil {
+		logger.routeString = " by " + route.String()
+	}
+	return logger
+}
+
+func (this *dispatchLogger) newEvent() *proxy.ConnectionEvent {
+	return &proxy.ConnectionEvent{
+		Tag:         this.outboundTag,
+		Destination: this.destination,
+		Server:      this.server,
+		Layers:      this.layers,
+		Timing:      this.timing,
+	}
+}
+
+// OnDialAllowed is called when the dial limiter allows dials to the destination, after waiting for queued.
+func (this *dispatchLogger) OnDialAllowed(queued time.Duration) {
+	this.firstDial = time.Time{}
+	this.dialed = time.Time{}
+	this.timing = proxy.ConnectionTiming{
+		Queued: queued,
+	}
+}
+
+// OnDial is called when a dial to a server starts.
+func (this *dispatchLogger) OnDial() {
+	this.dialed = time.Now()
+	if this.firstDial.IsZero() {
+		this.firstDial = this.dialed
+	}
+}
+
+// OnDialFailure is called when a server fails to connect with err.
+func (this *dispatchLogger) OnDialFailure(err error) {
+	this.dialFailure = internet.FailedLayer(err)
+}
+
+// failedLayer returns the layer that a dispatch failed at. TCP requests that fail after connecting and
+// before any response fail at the Shadowsocks handshake.
+func (this *dispatchLogger) failedLayer() string {
+	if this.server.Address == nil {
+		return this.dialFailure
+	}
+	if this.destination.Network == v2net.Network_TCP && !this.handshaken {
+		return LayerShadowsocks
+	}
+	return ""
+}
+
+// logIfSlow logs the time to establish the connection at established, if it is over the threshold, with
+// the time of each step. Waiting and retries under a millisecond are left out. Time of transport layers is
+// not broken down, as they are established in one dial.
+func (this *dispatchLogger) logIfSlow(established time.Time) {
+	threshold := time.Duration(this.config.SlowThreshold) * time.Millisecond
+	elapsed := established.Sub(this.start)
+	if threshold == 0 || elapsed < threshold {
+		return
+	}
+	var steps []string
+	if this.timing.Queued >= time.Millisecond {
+		steps = append(steps, "queued "+this.timing.Queued.Round(time.Millisecond).String())
+	}
+	if this.timing.Retried >= time.Millisecond {
+		steps = append(steps, "retries "+this.timing.Retried.Round(time.Millisecond).String())
+	}
+	transport := strings.Join(this.layers[:len(this.layers)-1], "+")
+	if this.dialed.IsZero() {
+		steps = append(steps, transport+" not dialed")
+	} else {
+		steps = append(steps, transport+" "+this.timing.Dial.Round(time.Millisecond).String())
+	}
+	if this.destination.Network == v2net.Network_TCP {
+		steps = append(steps, LayerShadowsocks+" request "+this.timing.Request.Round(time.Millisecond).String(),
+			"response "+this.timing.Response.Round(time.Millisecond).String())
+	}
+	log.Print(this.config.Slow, "Shadowsocks|Client: Slow connection to ", this.destination, " via ", this.server,
+		": established in ", elapsed.Round(time.Millisecond), " (", strings.Join(steps, ", "), ").", this.routeString, this.tagString)
+}
+
+// OnStart is called when server is connected, either by the last dial, or by a warm or reused connection.
+func (this *dispatchLogger) OnStart(server v2net.Destination) {
+	this.server = server
+	this.connected = time.Now()
+	if !this.dialed.IsZero() {
+		this.timing.Dial = this.connected.Sub(this.dialed)
+		this.timing.Retried = this.dialed.Sub(this.firstDial)
+	}
+	if this.destination.Network != v2net.Network_TCP {
+		this.logIfSlow(this.connected)
+	}
+	log.Print(this.config.Start, "Shadowsocks|Client: Tunneling request to ", this.destination, " via ", server, " over ", strings.Join(this.layers, "+"), this.routeString, this.tagString)
+	if this.hooks != nil {
+		this.hooks.OnOpen(this.newEvent())
+	}
+}
+
+// OnRequest is called when a TCP request is sent to the server.
+func (this *dispatchLogger) OnRequest() {
+	this.requested = time.Now()
+	this.timing.Request = this.requested.Sub(this.connected)
+}
+
+// OnHandshake is called when the server responds to a TCP request.
+func (this *dispatchLogger) OnHandshake() {
+	this.handshaken = true
+	established := time.Now()
+	this.timing.Response = established.Sub(this.requested)
+	this.logIfSlow(established)
+	if this.hooks != nil {
+		this.hooks.OnHandshake(this.newEvent())
+	}
+}
+
+func (this *dispatchLogger) OnFinish(conn *countingConn, err error) {
+	if this.hooks != nil {
+		event := this.newEvent()
+		if conn != nil {
+			event.Sent, event.Received = conn.Sent(), conn.Received()
+		}
+		event.Error = err
+		if err != nil {
+			event.FailedLayer = this.failedLayer()
+		}
+		this.hooks.OnClose(event)
+	}
+	if err != nil {
+		if responseErr, ok := err.(*ResponseError); ok && responseErr.Received > 0 {
+			log.Print(this.config.Failure, "Shadowsocks|Client: Response from ", this.server, " for ", this.destination, " is truncated: ", err, this.routeString, this.tagString)
+			return
+		}
+		var layerString string
+		if layer := this.failedLayer(); len(layer) > 0 {
+			layerString = " at " + layer
+		}
+		if this.server.Address == nil {
+			log.Print(this.config.Failure, "Shadowsocks|Client: Failed to dispatch request to ", this.destination, layerString, ": ", err, this.routeString, this.tagString)
+		} else {
+			log.Print(this.config.Failure, "Shadowsocks|Client: Failed to dispatch request to ", this.destination, " via ", this.server, layerString, ": ", err, this.routeString, this.tagString)
+		}
+		return
+	}
+	var sent, received int64
+	if conn != nil {
+		sent, received = conn.Sent(), conn.Received()
+	}
+	log.Print(this.config.Success, "Shadowsocks|Client: Finished request to ", this.destination, " via ", this.server,
+		" in ", time.Since(this.start), ", ", sent, " bytes sent, ", received, " bytes received.", this.routeString, this.tagString)
+}
+
+// countingConn counts bytes sent and received on the underlying connection. Bytes are also added to
+// uplink and downlink counters of the server and of each tag, if any.
+type countingConn struct {
+	internet.Connection
+	sent      int64
+	received  int64
+	uplink    *stats.Counter
+	downlink  *stats.Counter
+	uplinks   []*stats.Counter
+	downlinks []*stats.Counter
+}
+
+func newCountingConn(conn internet.Connection, server v2net.Destination, counters *stats.CounterSet) *countingConn {
+	counter := &countingConn{
+		Connection: conn,
+	}
+	if counters != nil {
+		counter.uplink = counters.Get(server.NetAddr() + ">>>uplink")
+		counter.downlink = counters.Get(server.NetAddr() + ">>>downlink")
+	}
+	return counter
+}
+
+// countTags adds bytes to counters named by "key=value" of each tag. It must be called before the
+// connection is used.
+func (this *countingConn) countTags(counters *stats.CounterSet, tags map[string]string) {
+	for key, value := range tags {
+		this.uplinks = append(this.uplinks, counters.Get(key+"="+value+">>>uplink"))
+		this.downlinks = append(this.downlinks, counters.Get(key+"="+value+">>>downlink"))
+	}
+}
+
+func (this *countingConn) Write(b []byte) (int, error) {
+	nBytes, err := this.Connection.Write(b)
+	atomic.AddInt64(&this.sent, int64(nBytes))
+	this.uplink.Add(int64(nBytes))
+	for _, counter := range this.uplinks {
+		counter.Add(int64(nBytes))
+	}
+	return nBytes, err
+}
+
+func (this *countingConn) Read(b []byte) (int, error) {
+	nBytes, err := this.Connection.Read(b)
+	atomic.AddInt64(&this.received, int64(nBytes))
+	this.downlink.Add(int64(nBytes))
+	for _, counter := range this.downlinks {
+		counter.Add(int64(nBytes))
+	}
+	return nBytes, err
+}
+
+func (this *countingConn) Sent() int64 {
+	return atomic.LoadInt64(&this.sent)
+}
+
+func (this *countingConn) Received() int64 {
+	return atomic.LoadInt64(&this.received)
+}
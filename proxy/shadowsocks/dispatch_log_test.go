@@ -0,0 +1,96 @@
+package shadowsocks_test
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
+)
+
+// timingHooks records the timing of the handshake event.
+type timingHooks struct {
+	sync.Mutex
+	handshake *proxy.ConnectionTiming
+}
+
+func (this *timingHooks) OnOpen(event *proxy.ConnectionEvent) {}
+
+func (this *timingHooks) OnHandshake(event *proxy.ConnectionEvent) {
+	this.Lock()
+	defer this.Unlock()
+	timing := event.Timing
+	this.handshake = &timing
+}
+
+func (this *timingHooks) OnClose(event *proxy.ConnectionEvent) {}
+
+func TestDispatchTimingOfSlowResponse(t *testing.T) {
+	assert := assert.On(t)
+
+	// The server takes its time to reach the destination, before responding.
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go func() {
+		conn, err := listener.AcceptTCP()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request, reader, err := ReadTCPSession(newTestUser(), conn)
+		assert.Error(err).IsNil()
+		readAll(assert, reader, 7)
+		time.Sleep(300 * time.Millisecond)
+		writer, err := WriteTCPResponse(request, conn)
+		assert.Error(err).IsNil()
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+	}()
+
+	hooks := new(timingHooks)
+	space := app.NewSpace()
+	client, err := NewClient(&ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(listener.Addr().(*net.TCPAddr).Port),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		DispatchLog: &DispatchLogConfig{
+			SlowThreshold: 100,
+		},
+	}, space, &proxy.OutboundHandlerMeta{
+		Address: v2net.AnyIP,
+		StreamSettings: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+		Hooks: hooks,
+	})
+	assert.Error(err).IsNil()
+	assert.Error(space.Initialize()).IsNil()
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+
+	// The wait is for the response, and not for the dial.
+	hooks.Lock()
+	defer hooks.Unlock()
+	assert.Bool(hooks.handshake.Response >= 300*time.Millisecond).IsTrue()
+	assert.Bool(hooks.handshake.Dial < 100*time.Millisecond).IsTrue()
+	assert.Bool(hooks.handshake.Request < 100*time.Millisecond).IsTrue()
+	assert.Int64(int64(hooks.handshake.Retried)).Equals(0)
+	assert.Bool(hooks.handshake.Queued < 100*time.Millisecond).IsTrue()
+}
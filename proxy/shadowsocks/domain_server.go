@@ -0,0 +1,49 @@
+package shadowsocks
+
+import (
+	"strings"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+// DomainServerTable maps domain suffixes to destinations of servers.
+type DomainServerTable map[string]v2net.Destination
+
+func NewDomainServerTable(rules []*DomainServerRule) DomainServerTable {
+	if len(rules) == 0 {
+		return nil
+	}
+	table := make(DomainServerTable)
+	for _, rule := range rules {
+		dest := v2net.TCPDestination(rule.Address.AsAddress(), v2net.Port(rule.Port))
+		for _, domain := range rule.Domain {
+			table[normalizeDomainSuffix(domain)] = dest
+		}
+	}
+	return table
+}
+
+func normalizeDomainSuffix(domain string) string {
+	domain = strings.TrimPrefix(strings.ToLower(domain), "*.")
+	return strings.TrimSuffix(domain, ".")
+}
+
+// Lookup returns the server for destination, by the longest matching domain suffix. It returns false if
+// destination is not a domain or no suffix matches.
+func (this DomainServerTable) Lookup(destination v2net.Destination) (v2net.Destination, bool) {
+	if len(this) == 0 || !destination.Address.Family().IsDomain() {
+		return v2net.Destination{}, false
+	}
+	domain := normalizeDomainSuffix(destination.Address.Domain())
+	for len(domain) > 0 {
+		if server, found := this[domain]; found {
+			return server, true
+		}
+		idx := strings.IndexByte(domain, '.')
+		if idx < 0 {
+			break
+		}
+		domain = domain[idx+1:]
+	}
+	return v2net.Destination{}, false
+}
@@ -0,0 +1,48 @@
+package shadowsocks_test
+
+import (
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestDomainServerTable(t *testing.T) {
+	assert := assert.On(t)
+
+	first := v2net.TCPDestination(v2net.LocalHostIP, 1001)
+	second := v2net.TCPDestination(v2net.LocalHostIP, 1002)
+	table := NewDomainServerTable([]*DomainServerRule{
+		{
+			Domain:  []string{"*.example.com", "v2ray.com"},
+			Address: v2net.NewIPOrDomain(first.Address),
+			Port:    uint32(first.Port),
+		},
+		{
+			Domain:  []string{"api.Example.com."},
+			Address: v2net.NewIPOrDomain(second.Address),
+			Port:    uint32(second.Port),
+		},
+	})
+
+	server, found := table.Lookup(v2net.TCPDestination(v2net.DomainAddress("example.com"), 443))
+	assert.Bool(found).IsTrue()
+	assert.Destination(server).EqualsString(first.String())
+
+	server, found = table.Lookup(v2net.TCPDestination(v2net.DomainAddress("www.v2ray.com"), 443))
+	assert.Bool(found).IsTrue()
+	assert.Destination(server).EqualsString(first.String())
+
+	// The longest suffix wins.
+	server, found = table.Lookup(v2net.TCPDestination(v2net.DomainAddress("v1.API.example.com"), 443))
+	assert.Bool(found).IsTrue()
+	assert.Destination(server).EqualsString(second.String())
+
+	_, found = table.Lookup(v2net.TCPDestination(v2net.DomainAddress("notexample.com"), 443))
+	assert.Bool(found).IsFalse()
+	_, found = table.Lookup(v2net.TCPDestination(v2net.LocalHostIP, 443))
+	assert.Bool(found).IsFalse()
+	_, found = NewDomainServerTable(nil).Lookup(v2net.TCPDestination(v2net.DomainAddress("example.com"), 443))
+	assert.Bool(found).IsFalse()
+}
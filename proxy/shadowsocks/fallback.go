@@ -0,0 +1,196 @@
+package shadowsocks
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"time"
+
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+)
+
+// featureFallback tracks servers that reject requests with an optional feature, such as one-time auth, so
+// that the feature is not used with them for a while. Servers known to accept the feature are tracked as
+// well, for the same time.
+type featureFallback struct {
+	sync.Mutex
+	timeout  time.Duration
+	until    map[v2net.Destination]time.Time
+	accepted map[v2net.Destination]time.Time
+	probing  map[v2net.Destination]bool
+}
+
+func newFeatureFallback(timeout time.Duration) *featureFallback {
+	return &featureFallback{
+		timeout:  timeout,
+		until:    make(map[v2net.Destination]time.Time),
+		accepted: make(map[v2net.Destination]time.Time),
+		probing:  make(map[v2net.Destination]bool),
+	}
+}
+
+// Allowed returns true if requests to server may use the feature.
+func (this *featureFallback) Allowed(server v2net.Destination) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	until, found := this.until[server]
+	if !found {
+		return true
+	}
+	if time.Now().After(until) {
+		delete(this.until, server)
+		return true
+	}
+	return false
+}
+
+// OnRejected makes requests to server go without the feature until the timeout.
+func (this *featureFallback) OnRejected(server v2net.Destination) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.until[server] = time.Now().Add(this.timeout)
+	delete(this.accepted, server)
+}
+
+// Confirmed returns true if server has accepted a request with the feature, in the timeout.
+func (this *featureFallback) Confirmed(server v2net.Destination) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	until, found := this.accepted[server]
+	if !found {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(this.accepted, server)
+		return false
+	}
+	return true
+}
+
+// OnAccepted records that server accepts requests with the feature, until the timeout.
+func (this *featureFallback) OnAccepted(server v2net.Destination) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.accepted[server] = time.Now().Add(this.timeout)
+}
+
+// startProbe returns true if server is not being probed already. The caller then probes the server, and
+// calls endProbe when it finishes.
+func (this *featureFallback) startProbe(server v2net.Destination) bool {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.probing[server] {
+		return false
+	}
+	this.probing[server] = true
+	return true
+}
+
+func (this *featureFallback) endProbe(server v2net.Destination) {
+	this.Lock()
+	defer this.Unlock()
+
+	delete(this.probing, server)
+}
+
+var (
+	idempotentMethods = []string{"GET ", "HEAD ", "OPTIONS ", "TRACE "}
+)
+
+// isIdempotentRequest returns true if payload starts a HTTP request that is safe to send again.
+func isIdempotentRequest(payload []byte) bool {
+	for _, method := range idempotentMethods {
+		if bytes.HasPrefix(payload, []byte(method)) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeOneTimeAuth decides whether server rejects one-time auth, after a request with it failed. Two
+// requests to destination are sent without payload, one with one-time auth and one without. A server
+// waits for the payload of a request it accepts, and closes right away otherwise. Requests to the server
+// go without one-time auth only if it closes the first request but not the second, so that connections
+// broken for any other reason never turn it off. It returns true if the server rejects one-time auth.
+func (this *Client) probeOneTimeAuth(server *protocol.ServerSpec, destination v2net.Destination) bool {
+	dest := server.Destination()
+	if !this.ota.startProbe(dest) {
+		return false
+	}
+	defer this.ota.endProbe(dest)
+
+	withoutOTA := make(chan bool, 1)
+	go func() {
+		rejected, err := this.probeRequest(server, destination, false)
+		withoutOTA <- err == nil && !rejected
+	}()
+	rejected, err := this.probeRequest(server, destination, true)
+	accepted := <-withoutOTA
+	if err != nil {
+		log.Info("Shadowsocks|Client: Failed to probe one-time auth of ", dest, ": ", err)
+		return false
+	}
+	if !rejected {
+		this.ota.OnAccepted(dest)
+		return false
+	}
+	if !accepted {
+		log.Info("Shadowsocks|Client: Server ", dest, " closes requests to ", destination, " with or without one-time auth.")
+		return false
+	}
+	log.Warning("Shadowsocks|Client: Server ", dest, " rejects one-time auth, falling back to requests without it.")
+	this.ota.OnRejected(dest)
+	return true
+}
+
+// probeRequest sends a request to destination through server without payload, and returns true if the
+// server closes the connection before otaRejectionTimeout.
+func (this *Client) probeRequest(server *protocol.ServerSpec, destination v2net.Destination, ota bool) (bool, error) {
+	dest := server.Destination()
+	dest.Network = v2net.Network_TCP
+	conn, err := dialWithTimeout(this.meta.Address, dest, this.meta.GetDialerOptions(), otaProbeTimeout)
+	if err != nil {
+		return false, err
+	}
+	conn.SetReusable(false)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(otaProbeTimeout))
+
+	destination.Network = v2net.Network_TCP
+	request, account, err := newRequest(destination, server)
+	if err != nil {
+		return false, err
+	}
+	if !ota {
+		request.Option.Clear(RequestOptionOneTimeAuth)
+	}
+	conn, err = this.wrapTCPConn(conn, account, server, v2net.DestinationFromAddr(conn.LocalAddr()))
+	if err != nil {
+		return false, err
+	}
+	bufferedWriter := v2io.NewBufferedWriter(conn)
+	defer bufferedWriter.Release()
+	bodyWriter, err := WriteTCPRequest(request, bufferedWriter)
+	if err != nil {
+		return false, err
+	}
+	defer bodyWriter.Release()
+	if err := bufferedWriter.Flush(); err != nil {
+		return false, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(otaRejectionTimeout))
+	_, err = conn.Read(make([]byte, 1))
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return false, nil
+	}
+	return err != nil, nil
+}
@@ -0,0 +1,143 @@
+package shadowsocks
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+)
+
+const (
+	// Time that the region of a domain is cached, and the shorter time if the domain has no known region,
+	// e.g., because it failed to resolve.
+	domainRegionTTL        = 10 * time.Minute
+	unknownDomainRegionTTL = time.Minute
+	// Maximum number of domains whose regions are cached.
+	maxCachedDomainRegions = 4096
+)
+
+type cachedRegion struct {
+	region string
+	expire time.Time
+}
+
+type geoRegion struct {
+	name string
+	ipv4 *v2net.IPNet
+	ipv6 []*net.IPNet
+}
+
+// GeoRegionTable finds the regions of IPs, and caches the regions of domains.
+type GeoRegionTable struct {
+	regions   []*geoRegion
+	locations map[string]protocol.GeoLocation
+
+	domainLock sync.Mutex
+	domains    map[string]cachedRegion
+}
+
+func NewGeoRegionTable(config *GeoProximityConfig) (*GeoRegionTable, error) {
+	table := &GeoRegionTable{
+		locations: make(map[string]protocol.GeoLocation, len(config.Region)),
+		domains:   make(map[string]cachedRegion),
+	}
+	for _, regionConfig := range config.Region {
+		if len(regionConfig.Name) == 0 {
+			return nil, errors.New("Shadowsocks|Client: Region without name.")
+		}
+		region := &geoRegion{
+			name: regionConfig.Name,
+			ipv4: v2net.NewIPNetWithCapacity(len(regionConfig.Cidr)),
+		}
+		for _, cidr := range regionConfig.Cidr {
+			switch len(cidr.Ip) {
+			case net.IPv4len:
+				region.ipv4.AddIP(cidr.Ip, byte(cidr.Prefix))
+			case net.IPv6len:
+				region.ipv6 = append(region.ipv6, &net.IPNet{
+					IP:   net.IP(cidr.Ip),
+					Mask: net.CIDRMask(int(cidr.Prefix), 8*net.IPv6len),
+				})
+			default:
+				return nil, errors.New("Shadowsocks|Client: Invalid IP length in region " + region.name + ".")
+			}
+		}
+		table.regions = append(table.regions, region)
+		table.locations[region.name] = protocol.GeoLocation{
+			Latitude:  regionConfig.Latitude,
+			Longitude: regionConfig.Longitude,
+		}
+	}
+	return table, nil
+}
+
+// Region returns the region of the first matching range of ip, or empty if none matches.
+func (this *GeoRegionTable) Region(ip net.IP) string {
+	for _, region := range this.regions {
+		if ip.To4() != nil {
+			if region.ipv4.Contains(ip) {
+				return region.name
+			}
+			continue
+		}
+		for _, ipNet := range region.ipv6 {
+			if ipNet.Contains(ip) {
+				return region.name
+			}
+		}
+	}
+	return ""
+}
+
+// DomainRegion returns the region of the first IP of domain that has one, or empty if none has. IPs are
+// resolved by resolve, unless the region of domain is cached.
+func (this *GeoRegionTable) DomainRegion(domain string, resolve func(domain string) []net.IP) string {
+	now := time.Now()
+	this.domainLock.Lock()
+	cached, found := this.domains[domain]
+	this.domainLock.Unlock()
+	if found && now.Before(cached.expire) {
+		return cached.region
+	}
+
+	region := ""
+	for _, ip := range resolve(domain) {
+		if region = this.Region(ip); len(region) > 0 {
+			break
+		}
+	}
+	ttl := domainRegionTTL
+	if len(region) == 0 {
+		ttl = unknownDomainRegionTTL
+	}
+
+	this.domainLock.Lock()
+	defer this.domainLock.Unlock()
+	if len(this.domains) >= maxCachedDomainRegions {
+		for key, cached := range this.domains {
+			if !now.Before(cached.expire) {
+				delete(this.domains, key)
+			}
+		}
+		if len(this.domains) >= maxCachedDomainRegions {
+			// Drops an arbitrary domain, when none has expired.
+			for key := range this.domains {
+				delete(this.domains, key)
+				break
+			}
+		}
+	}
+	this.domains[domain] = cachedRegion{
+		region: region,
+		expire: now.Add(ttl),
+	}
+	return region
+}
+
+// Locations returns the locations of all regions by their names.
+func (this *GeoRegionTable) Locations() map[string]protocol.GeoLocation {
+	return this.locations
+}
@@ -0,0 +1,62 @@
+package shadowsocks_test
+
+import (
+	"net"
+	"testing"
+
+	"v2ray.com/core/app/router"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestGeoRegionTable(t *testing.T) {
+	assert := assert.On(t)
+
+	table, err := NewGeoRegionTable(&GeoProximityConfig{
+		Region: []*GeoRegion{
+			{
+				Name: "jp",
+				Cidr: []*router.CIDR{
+					{Ip: []byte{10, 1, 0, 0}, Prefix: 16},
+					{Ip: net.ParseIP("2001:db8::"), Prefix: 32},
+				},
+				Latitude:  35.7,
+				Longitude: 139.7,
+			},
+			{
+				Name: "us",
+				Cidr: []*router.CIDR{
+					{Ip: []byte{10, 0, 0, 0}, Prefix: 8},
+				},
+			},
+		},
+	})
+	assert.Error(err).IsNil()
+	assert.String(table.Region(net.ParseIP("10.1.2.3"))).Equals("jp")
+	assert.String(table.Region(net.ParseIP("10.2.2.3"))).Equals("us")
+	assert.String(table.Region(net.ParseIP("2001:db8::1"))).Equals("jp")
+	assert.String(table.Region(net.ParseIP("192.168.1.1"))).Equals("")
+	assert.Bool(table.Locations()["jp"].Latitude == 35.7).IsTrue()
+
+	// Regions of domains are cached.
+	resolutions := 0
+	resolve := func(domain string) []net.IP {
+		resolutions++
+		return []net.IP{net.ParseIP("192.168.1.1"), net.ParseIP("10.1.2.3")}
+	}
+	assert.String(table.DomainRegion("www.v2ray.com", resolve)).Equals("jp")
+	assert.String(table.DomainRegion("www.v2ray.com", resolve)).Equals("jp")
+	assert.Int(resolutions).Equals(1)
+
+	_, err = NewGeoRegionTable(&GeoProximityConfig{
+		Region: []*GeoRegion{
+			{
+				Name: "bad",
+				Cidr: []*router.CIDR{
+					{Ip: []byte{10, 0, 0}, Prefix: 8},
+				},
+			},
+		},
+	})
+	assert.Error(err).IsNotNil()
+}
@@ -0,0 +1,78 @@
+package shadowsocks
+
+import (
+	"errors"
+	"time"
+
+	"v2ray.com/core/common/stats"
+)
+
+var (
+	ErrHandshakeQueueTimeout = errors.New("Shadowsocks: Too many handshakes in progress.")
+)
+
+// HandshakeLimiter limits the number of TCP handshakes in progress at the same time, so that a burst of
+// new connections doesn't take all CPU from established ones. A nil HandshakeLimiter doesn't limit
+// anything.
+type HandshakeLimiter struct {
+	slots    chan bool
+	timeout  time.Duration
+	counters *stats.CounterSet
+	active   *stats.Counter
+	queued   *stats.Counter
+	rejected *stats.Counter
+}
+
+// NewHandshakeLimiter creates a limiter by config, or returns nil if config doesn't limit handshakes.
+func NewHandshakeLimiter(config *HandshakeLimitConfig) *HandshakeLimiter {
+	if config == nil || config.Concurrency == 0 {
+		return nil
+	}
+	counters := stats.NewCounterSet()
+	counters.Get("limit").Add(int64(config.Concurrency))
+	return &HandshakeLimiter{
+		slots:    make(chan bool, config.Concurrency),
+		timeout:  config.GetEffectiveQueueTimeout(),
+		counters: counters,
+		active:   counters.Get("active"),
+		queued:   counters.Get("queued"),
+		rejected: counters.Get("rejected"),
+	}
+}
+
+// Acquire waits for a handshake to start, and returns a function that must be called once the handshake
+// finishes. It returns ErrHandshakeQueueTimeout if no handshake finishes in time.
+func (this *HandshakeLimiter) Acquire() (func(), error) {
+	if this == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case this.slots <- true:
+	default:
+		this.queued.Add(1)
+		timer := time.NewTimer(this.timeout)
+		defer timer.Stop()
+		select {
+		case this.slots <- true:
+			this.queued.Add(-1)
+		case <-timer.C:
+			this.queued.Add(-1)
+			this.rejected.Add(1)
+			return nil, ErrHandshakeQueueTimeout
+		}
+	}
+	this.active.Add(1)
+	return func() {
+		this.active.Add(-1)
+		<-this.slots
+	}, nil
+}
+
+// Counters returns the limit, and the numbers of handshakes in progress, waiting and rejected.
+func (this *HandshakeLimiter) Counters() *stats.CounterSet {
+	if this == nil {
+		return nil
+	}
+	return this.counters
+}
@@ -0,0 +1,51 @@
+package shadowsocks_test
+
+import (
+	"testing"
+	"time"
+
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestHandshakeLimiter(t *testing.T) {
+	assert := assert.On(t)
+
+	limiter := NewHandshakeLimiter(&HandshakeLimitConfig{
+		Concurrency:  1,
+		QueueTimeout: 100,
+	})
+	counters := limiter.Counters()
+	assert.Int64(counters.Get("limit").Value()).Equals(1)
+
+	release, err := limiter.Acquire()
+	assert.Error(err).IsNil()
+	assert.Int64(counters.Get("active").Value()).Equals(1)
+
+	// The second handshake waits until the first one finishes.
+	acquired := make(chan func())
+	go func() {
+		release, err := limiter.Acquire()
+		assert.Error(err).IsNil()
+		acquired <- release
+	}()
+	for counters.Get("queued").Value() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	release()
+	(<-acquired)()
+	assert.Int64(counters.Get("queued").Value()).Equals(0)
+	assert.Int64(counters.Get("active").Value()).Equals(0)
+
+	// Handshakes that wait too long are rejected.
+	release, err = limiter.Acquire()
+	assert.Error(err).IsNil()
+	_, err = limiter.Acquire()
+	assert.Error(err).Equals(ErrHandshakeQueueTimeout)
+	assert.Int64(counters.Get("rejected").Value()).Equals(1)
+	release()
+
+	release, err = NewHandshakeLimiter(nil).Acquire()
+	assert.Error(err).IsNil()
+	release()
+}
@@ -0,0 +1,89 @@
+package shadowsocks_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/api"
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
+)
+
+func TestHandshakeCounters(t *testing.T) {
+	assert := assert.On(t)
+
+	// The server responds to the first request, and closes the second one without a response.
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go func() {
+		for i := 0; i < 2; i++ {
+			conn, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			request, reader, err := ReadTCPSession(newTestUser(), conn)
+			assert.Error(err).IsNil()
+			readAll(assert, reader, 7)
+			if i == 0 {
+				writer, err := WriteTCPResponse(request, conn)
+				assert.Error(err).IsNil()
+				assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+			}
+			conn.Close()
+		}
+	}()
+
+	apiPort := pickPort(assert)
+	space := app.NewSpace()
+	space.BindApp(api.APP_ID, api.NewApiServer(space, &api.Config{
+		Port: uint32(apiPort),
+	}))
+	client, err := NewClient(&ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(listener.Addr().(*net.TCPAddr).Port),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+	}, space, &proxy.OutboundHandlerMeta{
+		Tag:     "shadowsocks",
+		Address: v2net.AnyIP,
+		StreamSettings: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+	})
+	assert.Error(err).IsNil()
+	assert.Error(space.Initialize()).IsNil()
+	defer space.GetApp(api.APP_ID).(*api.ApiServer).Release()
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+
+	traffic = ray.NewRay()
+	result = dispatch(client, "request", traffic)
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNotNil()
+
+	response, err := http.Get("http://" + v2net.TCPDestination(v2net.LocalHostIP, apiPort).NetAddr() + "/outbound/shadowsocks/handshakes")
+	assert.Error(err).IsNil()
+	defer response.Body.Close()
+	var handshakes map[string]int64
+	assert.Error(json.NewDecoder(response.Body).Decode(&handshakes)).IsNil()
+	method := testAccount.CipherType.Name()
+	assert.Int64(handshakes[method+">>>success"]).Equals(1)
+	assert.Int64(handshakes[method+">>>failure"]).Equals(1)
+}
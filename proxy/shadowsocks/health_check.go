@@ -0,0 +1,242 @@
+package shadowsocks
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"v2ray.com/core/app/observatory"
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/transport/internet"
+)
+
+const (
+	defaultHealthCheckInterval = 60 * time.Second
+	defaultHealthCheckTimeout  = 10 * time.Second
+	// Longest wait for a server to reject the request header in ConnectOnly mode, which is within the
+	// default header timeout of servers, after which they close healthy connections without payload.
+	maxHandshakeCheckWait = defaultHandshakeHeaderTimeout / 2
+)
+
+func (this *HealthCheckConfig) GetEffectiveInterval() time.Duration {
+	if this.Interval == 0 {
+		return defaultHealthCheckInterval
+	}
+	return time.Duration(this.Interval) * time.Second
+}
+
+func (this *HealthCheckConfig) GetEffectiveTimeout() time.Duration {
+	if this.Timeout == 0 {
+		return defaultHealthCheckTimeout
+	}
+	return time.Duration(this.Timeout) * time.Second
+}
+
+// GetEffectiveDestination returns the destination requested through servers by checks.
+func (this *HealthCheckConfig) GetEffectiveDestination() v2net.Destination {
+	address := this.Address.AsAddress()
+	if address == nil {
+		address = v2net.DomainAddress("www.gstatic.com")
+	}
+	port := v2net.Port(this.Port)
+	if port == 0 {
+		port = v2net.Port(80)
+	}
+	return v2net.TCPDestination(address, port)
+}
+
+// HealthChecker checks all servers in a list periodically, and reports the results to their circuit
+// breakers. A nil HealthChecker checks nothing.
+type HealthChecker struct {
+	config    *HealthCheckConfig
+	servers   *protocol.ServerList
+	check     func(server *protocol.ServerSpec) error
+	done      chan bool
+	closeOnce sync.Once
+}
+
+// NewHealthChecker creates a HealthChecker that checks each server by check. It returns nil if config is
+// nil.
+func NewHealthChecker(config *HealthCheckConfig, servers *protocol.ServerList, check func(server *protocol.ServerSpec) error) *HealthChecker {
+	if config == nil {
+		return nil
+	}
+	return &HealthChecker{
+		config:  config,
+		servers: servers,
+		check:   check,
+		done:    make(chan bool),
+	}
+}
+
+// Start checks all servers now, and then in every interval. It must be called only once.
+func (this *HealthChecker) Start() {
+	if this == nil {
+		return
+	}
+	go this.run()
+}
+
+// Close stops checking servers. Checks in progress are not affected.
+func (this *HealthChecker) Close() {
+	if this == nil {
+		return
+	}
+	this.closeOnce.Do(func() {
+		close(this.done)
+	})
+}
+
+func (this *HealthChecker) run() {
+	ticker := time.NewTicker(this.config.GetEffectiveInterval())
+	defer ticker.Stop()
+
+	for {
+		this.CheckAll()
+		select {
+		case <-this.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll checks all servers whose circuit breakers allow a dial, and waits for the results.
+func (this *HealthChecker) CheckAll() {
+	var wg sync.WaitGroup
+	for _, server := range this.servers.Servers() {
+		breaker := server.CircuitBreaker()
+		if !breaker.Allow() {
+			continue
+		}
+		wg.Add(1)
+		go func(server *protocol.ServerSpec) {
+			defer wg.Done()
+
+			if err := this.check(server); err != nil {
+				log.Warning("Shadowsocks|Client: Server ", server.Destination(), " failed health check: ", err)
+				breaker.OnFailure()
+				return
+			}
+			log.Debug("Shadowsocks|Client: Server ", server.Destination(), " passed health check.")
+			breaker.OnSuccess()
+		}(server)
+	}
+	wg.Wait()
+}
+
+// checkServer checks server by the mode in config. Requests in RoundTrip mode send payload, or a HEAD
+// request if payload is empty.
+func (this *Client) checkServer(config *HealthCheckConfig, server *protocol.ServerSpec, payload []byte) error {
+	timeout := config.GetEffectiveTimeout()
+	dest := server.Destination()
+	dest.Network = v2net.Network_TCP
+	conn, err := dialWithTimeout(this.meta.Address, dest, this.meta.GetDialerOptions(), timeout)
+	if err != nil {
+		return err
+	}
+	conn.SetReusable(false)
+	defer conn.Close()
+	deadline := time.Now().Add(timeout)
+	conn.SetDeadline(deadline)
+
+	if handshaker, ok := internet.UnwrapConnection(conn).(interface {
+		Handshake() error
+	}); ok {
+		if err := handshaker.Handshake(); err != nil {
+			return err
+		}
+	}
+
+	target := config.GetEffectiveDestination()
+	request, account, err := newRequest(target, server)
+	if err != nil {
+		return err
+	}
+	conn, err = this.wrapTCPConn(conn, account, server, v2net.DestinationFromAddr(conn.LocalAddr()))
+	if err != nil {
+		return err
+	}
+	if config.Mode == HealthCheckConfig_ConnectOnly && !config.Handshake {
+		return nil
+	}
+
+	bufferedWriter := v2io.NewBufferedWriter(conn)
+	defer bufferedWriter.Release()
+	bodyWriter, err := WriteTCPRequest(request, bufferedWriter)
+	if err != nil {
+		return err
+	}
+	defer bodyWriter.Release()
+
+	if config.Mode == HealthCheckConfig_ConnectOnly {
+		if err := bufferedWriter.Flush(); err != nil {
+			return err
+		}
+		// The server waits for payload, unless it rejects the request.
+		if wait := time.Now().Add(maxHandshakeCheckWait); wait.Before(deadline) {
+			conn.SetReadDeadline(wait)
+		}
+		_, err := conn.Read(make([]byte, 1))
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return nil
+		}
+		if err == nil {
+			return nil
+		}
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	if len(payload) == 0 {
+		payload = []byte("HEAD / HTTP/1.1\r\nHost: " + target.Address.String() + "\r\nConnection: close\r\n\r\n")
+	}
+	if err := bodyWriter.Write(alloc.NewLocalBuffer(len(payload)).Clear().Append(payload)); err != nil {
+		return err
+	}
+	bufferedWriter.SetCached(false)
+
+	responseReader, err := ReadTCPResponse(request.User, conn)
+	if err != nil {
+		return err
+	}
+	defer responseReader.Release()
+	response, err := responseReader.Read()
+	if err != nil {
+		return err
+	}
+	response.Release()
+	return nil
+}
+
+// ProbeServers implements observatory.Prober.ProbeServers().
+func (this *Client) ProbeServers() []*protocol.ServerSpec {
+	return this.serverList.Servers()
+}
+
+// Probe implements observatory.Prober.Probe(). It checks server in RoundTrip mode with request as payload.
+func (this *Client) Probe(server *protocol.ServerSpec, dest v2net.Destination, request []byte, timeout time.Duration) error {
+	return this.checkServer(&HealthCheckConfig{
+		Timeout: uint32(timeout / time.Second),
+		Address: v2net.NewIPOrDomain(dest.Address),
+		Port:    uint32(dest.Port),
+	}, server, request)
+}
+
+// onProbe reports results of probes by the observatory to circuit breakers and probe latencies of servers.
+// Probes wait for their destinations, so they don't count in the handshake latency.
+func (this *Client) onProbe(result *observatory.ProbeResult) {
+	if result.Err != nil {
+		result.Server.CircuitBreaker().OnFailure()
+		return
+	}
+	result.Server.CircuitBreaker().OnSuccess()
+	result.Server.ProbeLatency().Update(result.Latency)
+}
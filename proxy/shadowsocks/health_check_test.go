@@ -0,0 +1,63 @@
+package shadowsocks_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestHealthCheckerReportsToCircuitBreaker(t *testing.T) {
+	assert := assert.On(t)
+
+	serverList := protocol.NewServerList()
+	serverList.SetCircuitBreaker(&protocol.CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: 3600})
+	good := protocol.NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, 10001), protocol.AlwaysValid())
+	bad := protocol.NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, 10002), protocol.AlwaysValid())
+	serverList.AddServer(good)
+	serverList.AddServer(bad)
+
+	var checks int32
+	checker := NewHealthChecker(&HealthCheckConfig{Mode: HealthCheckConfig_ConnectOnly}, serverList, func(server *protocol.ServerSpec) error {
+		atomic.AddInt32(&checks, 1)
+		if server == bad {
+			return errors.New("unreachable")
+		}
+		return nil
+	})
+
+	checker.CheckAll()
+	assert.Int(int(atomic.LoadInt32(&checks))).Equals(2)
+	assert.Bool(good.CircuitBreaker().State() == protocol.CircuitClosed).IsTrue()
+	assert.Bool(bad.CircuitBreaker().State() == protocol.CircuitOpen).IsTrue()
+
+	// Open circuits are not checked until they time out.
+	checker.CheckAll()
+	assert.Int(int(atomic.LoadInt32(&checks))).Equals(3)
+	checker.Close()
+	checker.Close()
+}
+
+func TestHealthCheckDisabled(t *testing.T) {
+	assert := assert.On(t)
+
+	checker := NewHealthChecker(nil, protocol.NewServerList(), nil)
+	assert.Pointer(checker).IsNil()
+	checker.Start()
+	checker.Close()
+}
+
+func TestHealthCheckDestination(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &HealthCheckConfig{}
+	assert.Destination(config.GetEffectiveDestination()).EqualsString("tcp:www.gstatic.com:80")
+
+	config.Address = v2net.NewIPOrDomain(v2net.LocalHostIP)
+	config.Port = 8080
+	assert.Destination(config.GetEffectiveDestination()).EqualsString("tcp:127.0.0.1:8080")
+}
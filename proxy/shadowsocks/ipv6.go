@@ -0,0 +1,44 @@
+package shadowsocks
+
+import (
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/ray"
+)
+
+const (
+	// Time to wait for the first payload to sniff the domain of an IPv6 destination.
+	ipv6SniffTimeout = 2 * time.Second
+)
+
+// applyIPv6Policy returns the session to dispatch and its first payload by the IPv6 destination policy.
+// The returned payload replaces payload, which is released if it isn't returned. The error is
+// proxy.ErrIPv6Unreachable if the request is rejected.
+func (this *Client) applyIPv6Policy(session *proxy.SessionInfo, payload *alloc.Buffer, input ray.InputStream) (*proxy.SessionInfo, *alloc.Buffer, error) {
+	dest := session.Destination
+	if this.ipv6Policy == IPv6DestinationPolicy_AsIs || !dest.Address.Family().IsIPv6() {
+		return session, payload, nil
+	}
+	if this.ipv6Policy == IPv6DestinationPolicy_SniffDomain && dest.Network == v2net.Network_TCP {
+		if payload.IsEmpty() {
+			if first, err := waitForPayload(input, ipv6SniffTimeout); err == nil && first != nil {
+				payload.Release()
+				payload = first
+			}
+		}
+		if domain, found := proxy.SniffDomain(payload.Value); found {
+			log.Info("Shadowsocks|Client: Sending domain ", domain, " instead of IPv6 destination ", dest, ".")
+			sniffed := *session
+			sniffed.Destination = v2net.TCPDestination(v2net.DomainAddress(domain), dest.Port)
+			return &sniffed, payload, nil
+		}
+		log.Info("Shadowsocks|Client: Rejecting IPv6 destination ", dest, ", as no domain is found in its first payload.")
+		return session, payload, proxy.ErrIPv6Unreachable
+	}
+	log.Info("Shadowsocks|Client: Rejecting IPv6 destination ", dest, " by policy ", this.ipv6Policy, ".")
+	return session, payload, proxy.ErrIPv6Unreachable
+}
@@ -0,0 +1,66 @@
+package shadowsocks_test
+
+import (
+	"net"
+	"testing"
+
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
+)
+
+func newIPv6TestClient(assert *assert.Assert, port v2net.Port, policy IPv6DestinationPolicy) proxy.OutboundHandler {
+	return newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(port),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		Ipv6Destination: policy,
+	})
+}
+
+func TestIPv6DestinationSniffDomain(t *testing.T) {
+	assert := assert.On(t)
+
+	request := "GET / HTTP/1.1\r\nHost: www.v2ray.com\r\n\r\n"
+	// The test server only accepts requests to www.v2ray.com.
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readAll(assert, reader, len(request))).Equals(request)
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+	})
+	defer server.Close()
+	client := newIPv6TestClient(assert, server.Port(), IPv6DestinationPolicy_SniffDomain)
+
+	traffic := ray.NewRay()
+	dest := v2net.TCPDestination(v2net.ParseAddress("2001:db8::1"), 80)
+	result := make(chan error, 1)
+	go func() {
+		result <- client.Dispatch(dest, alloc.NewLocalBuffer(2048).Clear().AppendString(request), traffic)
+	}()
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+
+	// Nothing to sniff in the payload.
+	traffic = ray.NewRay()
+	err := client.Dispatch(dest, alloc.NewLocalBuffer(2048).Clear().AppendString("SSH-2.0-OpenSSH_7.2\r\n"), traffic)
+	assert.Error(err).Equals(proxy.ErrIPv6Unreachable)
+}
+
+func TestIPv6DestinationReject(t *testing.T) {
+	assert := assert.On(t)
+
+	client := newIPv6TestClient(assert, v2net.Port(1), IPv6DestinationPolicy_Reject)
+	traffic := ray.NewRay()
+	err := client.Dispatch(v2net.TCPDestination(v2net.ParseAddress("2001:db8::1"), 80), alloc.NewLocalBuffer(2048).Clear().AppendString("request"), traffic)
+	assert.Error(err).Equals(proxy.ErrIPv6Unreachable)
+	assert.Bool(proxy.GetFailureReason(traffic.InboundOutput().Err()) == proxy.FailureNetworkUnreachable).IsTrue()
+}
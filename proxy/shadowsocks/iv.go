@@ -0,0 +1,69 @@
+package shadowsocks
+
+import (
+	"crypto/rand"
+	"errors"
+	"sync"
+
+	"v2ray.com/core/common/log"
+)
+
+const (
+	// Number of recent IVs to check new IVs against.
+	ivHistorySize = 4096
+)
+
+var (
+	ErrIVReused = errors.New("Shadowsocks: IV is reused. Random source may be broken.")
+
+	globalIVHistory = NewIVHistory(ivHistorySize)
+)
+
+// IVHistory remembers recently generated IVs. Reusing an IV with the same key breaks stream ciphers, but
+// it never happens with a working random source, so an IV found in the history means the source is
+// broken.
+type IVHistory struct {
+	sync.Mutex
+	seen  map[string]bool
+	order []string
+	next  int
+}
+
+func NewIVHistory(size int) *IVHistory {
+	return &IVHistory{
+		seen:  make(map[string]bool, size),
+		order: make([]string, size),
+	}
+}
+
+// Add adds iv to the history. It returns false if iv is already in the history.
+func (this *IVHistory) Add(iv []byte) bool {
+	key := string(iv)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.seen[key] {
+		return false
+	}
+	if oldest := this.order[this.next]; len(oldest) > 0 {
+		delete(this.seen, oldest)
+	}
+	this.seen[key] = true
+	this.order[this.next] = key
+	this.next = (this.next + 1) % len(this.order)
+	return true
+}
+
+// generateIV fills iv with random bytes, and checks it against recently generated IVs.
+func generateIV(iv []byte) error {
+	if _, err := rand.Read(iv); err != nil {
+		log.Error("Shadowsocks: Failed to generate IV: ", err)
+		return err
+	}
+	if !globalIVHistory.Add(iv) {
+		log.Error("Shadowsocks: Generated an IV that is recently used.")
+		return ErrIVReused
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package shadowsocks_test
+
+import (
+	"bytes"
+	"testing"
+
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestIVHistory(t *testing.T) {
+	assert := assert.On(t)
+
+	history := NewIVHistory(2)
+	assert.Bool(history.Add([]byte{1})).IsTrue()
+	assert.Bool(history.Add([]byte{2})).IsTrue()
+	assert.Bool(history.Add([]byte{1})).IsFalse()
+
+	// The oldest IV is forgotten when the history is full.
+	assert.Bool(history.Add([]byte{3})).IsTrue()
+	assert.Bool(history.Add([]byte{1})).IsTrue()
+	assert.Bool(history.Add([]byte{3})).IsFalse()
+}
+
+func TestUniqueRequestIVs(t *testing.T) {
+	assert := assert.On(t)
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: v2net.LocalHostIP,
+		Port:    1234,
+		User: &protocol.User{
+			Email: "love@v2ray.com",
+			Account: loader.NewTypedSettings(&Account{
+				Password:   "shadowsocks-password",
+				CipherType: CipherType_AES_128_CFB,
+				Ota:        Account_Disabled,
+			}),
+		},
+	}
+
+	ivs := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		buffer := new(bytes.Buffer)
+		_, err := WriteTCPRequest(request, buffer)
+		assert.Error(err).IsNil()
+		iv := string(buffer.Bytes()[:16])
+		assert.Bool(ivs[iv]).IsFalse()
+		ivs[iv] = true
+	}
+}
@@ -0,0 +1,142 @@
+package shadowsocks
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/dice"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+	v2http "v2ray.com/core/transport/internet/authenticators/http"
+)
+
+const (
+	ObfsModeHTTP = "http"
+)
+
+// ObfsConfig is the client side settings of simple-obfs.
+type ObfsConfig struct {
+	Mode string
+	// Host header of the fake request. Server address is used if empty.
+	Host string
+	Uri  string
+	// User-Agent headers of the fake request. A random one is chosen on every connection.
+	UserAgents []string
+}
+
+// NewObfsConfig creates an ObfsConfig from plugin options, i.e., "obfs", "obfs-host", "obfs-uri"
+// and "obfs-ua". Multiple User-Agents in "obfs-ua" are separated by '|'.
+func NewObfsConfig(options PluginOptions) (*ObfsConfig, error) {
+	config := &ObfsConfig{
+		Mode: options["obfs"],
+		Host: options["obfs-host"],
+		Uri:  options["obfs-uri"],
+	}
+	if config.Mode != ObfsModeHTTP {
+		return nil, errors.New("Shadowsocks|Obfs: Unsupported obfs mode: " + config.Mode)
+	}
+	if len(config.Uri) == 0 {
+		config.Uri = "/"
+	}
+	if ua := options["obfs-ua"]; len(ua) > 0 {
+		for _, agent := range strings.Split(ua, "|") {
+			if agent = strings.TrimSpace(agent); len(agent) > 0 {
+				config.UserAgents = append(config.UserAgents, agent)
+			}
+		}
+	}
+	return config, nil
+}
+
+func (this *ObfsConfig) PickUserAgent() string {
+	if len(this.UserAgents) == 0 {
+		return "curl/7." + strconv.Itoa(dice.Roll(51)) + "." + strconv.Itoa(dice.Roll(2))
+	}
+	return this.UserAgents[dice.Roll(len(this.UserAgents))]
+}
+
+// GetHost returns the Host header for connections to the given server.
+func (this *ObfsConfig) GetHost(server v2net.Destination) string {
+	if len(this.Host) > 0 {
+		return this.Host
+	}
+	host := server.Address.String()
+	if server.Port != 80 {
+		host += ":" + server.Port.String()
+	}
+	return host
+}
+
+// ObfsHTTPConn wraps a connection with simple-obfs HTTP mode. The first write is sent as the body
+// of a fake websocket upgrade request, and the response header of the server is skipped on the
+// first read.
+type ObfsHTTPConn struct {
+	internet.Connection
+
+	config       *ObfsConfig
+	host         string
+	requestSent  bool
+	responseRead bool
+	readBuffer   *alloc.Buffer
+}
+
+func NewObfsHTTPConn(conn internet.Connection, config *ObfsConfig, server v2net.Destination) *ObfsHTTPConn {
+	return &ObfsHTTPConn{
+		Connection: conn,
+		config:     config,
+		host:       config.GetHost(server),
+	}
+}
+
+func (this *ObfsHTTPConn) Write(b []byte) (int, error) {
+	if this.requestSent {
+		return this.Connection.Write(b)
+	}
+	this.requestSent = true
+
+	key := make([]byte, 16)
+	rand.Read(key)
+
+	header := alloc.NewLocalBuffer(2048).Clear()
+	defer header.Release()
+
+	header.AppendString("GET ").AppendString(this.config.Uri).AppendString(" HTTP/1.1").AppendString(v2http.CRLF)
+	header.AppendString("Host: ").AppendString(this.host).AppendString(v2http.CRLF)
+	header.AppendString("User-Agent: ").AppendString(this.config.PickUserAgent()).AppendString(v2http.CRLF)
+	header.AppendString("Upgrade: websocket").AppendString(v2http.CRLF)
+	header.AppendString("Connection: Upgrade").AppendString(v2http.CRLF)
+	header.AppendString("Sec-WebSocket-Key: ").AppendString(base64.StdEncoding.EncodeToString(key)).AppendString(v2http.CRLF)
+	header.AppendString("Content-Length: ").AppendString(strconv.Itoa(len(b))).AppendString(v2http.ENDING)
+	header.Append(b)
+
+	if _, err := this.Connection.Write(header.Value); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (this *ObfsHTTPConn) Read(b []byte) (int, error) {
+	if !this.responseRead {
+		buffer, err := new(v2http.HeaderReader).Read(this.Connection)
+		if err != nil {
+			return 0, err
+		}
+		this.readBuffer = buffer
+		this.responseRead = true
+	}
+
+	if this.readBuffer != nil {
+		nBytes, err := this.readBuffer.Read(b)
+		if this.readBuffer.IsEmpty() {
+			this.readBuffer.Release()
+			this.readBuffer = nil
+		}
+		return nBytes, err
+	}
+
+	return this.Connection.Read(b)
+}
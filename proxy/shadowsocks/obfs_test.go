@@ -0,0 +1,67 @@
+package shadowsocks_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net"
+	"regexp"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestObfsHTTPConnOnWire(t *testing.T) {
+	assert := assert.On(t)
+
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := NewObfsHTTPConn(&warmConn{Conn: client, closed: new(int32)}, &ObfsConfig{
+		Mode:       ObfsModeHTTP,
+		Uri:        "/obfs",
+		UserAgents: []string{"curl/7.50.0"},
+	}, v2net.TCPDestination(v2net.DomainAddress("ss.v2ray.com"), v2net.Port(8388)))
+	defer conn.Close()
+
+	go func() {
+		conn.Write([]byte("payload"))
+		conn.Write([]byte("more"))
+	}()
+	request := "GET /obfs HTTP/1.1\r\n" +
+		"Host: ss.v2ray.com:8388\r\n" +
+		"User-Agent: curl/7.50.0\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: ________________________\r\n" +
+		"Content-Length: 7\r\n" +
+		"\r\n" +
+		"payload"
+	wire := make([]byte, len(request))
+	_, err := io.ReadFull(server, wire)
+	assert.Error(err).IsNil()
+
+	// The key is random on every connection.
+	keyPattern := regexp.MustCompile("Sec-WebSocket-Key: ([A-Za-z0-9+/=]{24})\r\n")
+	match := keyPattern.FindSubmatch(wire)
+	assert.Int(len(match)).Equals(2)
+	key, err := base64.StdEncoding.DecodeString(string(match[1]))
+	assert.Error(err).IsNil()
+	assert.Int(len(key)).Equals(16)
+	wire = bytes.Replace(wire, match[1], []byte("________________________"), 1)
+	assert.String(string(wire)).Equals(request)
+
+	// Later writes go on the wire as they are.
+	more := make([]byte, 4)
+	_, err = io.ReadFull(server, more)
+	assert.Error(err).IsNil()
+	assert.String(string(more)).Equals("more")
+
+	// The response header is skipped.
+	go server.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\nresponse"))
+	response := make([]byte, 8)
+	_, err = io.ReadFull(conn, response)
+	assert.Error(err).IsNil()
+	assert.String(string(response)).Equals("response")
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha1"
+	"errors"
 	"io"
 
 	"v2ray.com/core/common/alloc"
@@ -16,6 +17,10 @@ const (
 	AuthSize = 10
 )
 
+var (
+	ErrChunkTooLarge = errors.New("Shadowsocks|OTA: Chunk too large.")
+)
+
 type KeyGenerator func() []byte
 
 type Authenticator struct {
@@ -78,14 +83,17 @@ func (this *ChunkReader) Read() (*alloc.Buffer, error) {
 		buffer.Release()
 		return nil, err
 	}
-	// There is a potential buffer overflow here. Large buffer is 64K bytes,
-	// while uin16 + 10 will be more than that
-	length := serial.BytesToUint16(buffer.Value[:2]) + AuthSize
+	// Large buffer is 64K bytes, while uint16 + 10 may be more than that.
+	length := int(serial.BytesToUint16(buffer.Value[:2])) + AuthSize
+	if length > len(buffer.Value) {
+		buffer.Release()
+		return nil, ErrChunkTooLarge
+	}
 	if _, err := io.ReadFull(this.reader, buffer.Value[:length]); err != nil {
 		buffer.Release()
 		return nil, err
 	}
-	buffer.Slice(0, int(length))
+	buffer.Slice(0, length)
 
 	authBytes := buffer.Value[:AuthSize]
 	payload := buffer.Value[AuthSize:]
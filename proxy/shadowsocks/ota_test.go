@@ -34,3 +34,13 @@ func TestNormalChunkWriting(t *testing.T) {
 	assert.Error(err).IsNil()
 	assert.Bytes(buffer.Value).Equals([]byte{0, 8, 39, 228, 69, 96, 133, 39, 254, 26, 201, 70, 11, 12, 13, 14, 15, 16, 17, 18})
 }
+
+func TestOversizedChunkReading(t *testing.T) {
+	assert := assert.On(t)
+
+	buffer := alloc.NewBuffer().Clear().AppendBytes(0xFF, 0xFF)
+	reader := NewChunkReader(buffer, NewAuthenticator(ChunkKeyGenerator(
+		[]byte{21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36})))
+	_, err := reader.Read()
+	assert.Error(err).Equals(ErrChunkTooLarge)
+}
@@ -0,0 +1,153 @@
+package shadowsocks
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+
+	"v2ray.com/core/transport/internet/shadowtls"
+)
+
+const (
+	PluginShadowTLS = "shadow-tls"
+)
+
+var (
+	ErrUnsupportedPlugin = errors.New("Shadowsocks|Plugin: Unsupported plugin.")
+)
+
+// PluginOptions are the options of a SIP003 plugin, such as "obfs=http;obfs-host=www.bing.com".
+type PluginOptions map[string]string
+
+// ParsePluginOptions parses plugin options in SIP003 format. Semicolon, equal sign and backslash
+// can be escaped by a backslash.
+func ParsePluginOptions(opts string) (PluginOptions, error) {
+	options := make(PluginOptions)
+	var key, value bytes.Buffer
+	current := &key
+	escaped := false
+	flush := func() error {
+		if key.Len() == 0 && value.Len() == 0 && current == &key {
+			return nil
+		}
+		if key.Len() == 0 {
+			return errors.New("Shadowsocks|Plugin: Empty option name in: " + opts)
+		}
+		options[key.String()] = value.String()
+		key.Reset()
+		value.Reset()
+		current = &key
+		return nil
+	}
+	for _, c := range opts {
+		if escaped {
+			current.WriteRune(c)
+			escaped = false
+			continue
+		}
+		switch {
+		case c == '\\':
+			escaped = true
+		case c == '=' && current == &key:
+			current = &value
+		case c == ';':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			current.WriteRune(c)
+		}
+	}
+	if escaped {
+		return nil, errors.New("Shadowsocks|Plugin: Unterminated escape in: " + opts)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+func escapePluginOption(s string) string {
+	var b bytes.Buffer
+	for _, c := range s {
+		if c == '\\' || c == ';' || c == '=' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// String returns the options in SIP003 format, sorted by name.
+func (this PluginOptions) String() string {
+	keys := make([]string, 0, len(this))
+	for key := range this {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	opts := make([]string, len(keys))
+	for idx, key := range keys {
+		opts[idx] = escapePluginOption(key)
+		if value := this[key]; len(value) > 0 {
+			opts[idx] += "=" + escapePluginOption(value)
+		}
+	}
+	return strings.Join(opts, ";")
+}
+
+func (this *Account) GetPluginOptions() (PluginOptions, error) {
+	return ParsePluginOptions(this.PluginOpts)
+}
+
+// GetObfsConfig returns the simple-obfs settings of this account, or nil if obfs is not used.
+func (this *Account) GetObfsConfig() (*ObfsConfig, error) {
+	switch this.Plugin {
+	case "", PluginShadowTLS:
+		return nil, nil
+	case "obfs-local", "simple-obfs":
+		options, err := this.GetPluginOptions()
+		if err != nil {
+			return nil, err
+		}
+		return NewObfsConfig(options)
+	default:
+		return nil, ErrUnsupportedPlugin
+	}
+}
+
+// GetShadowTLSConfig returns the ShadowTLS settings of this account, or nil if ShadowTLS is not used.
+// Options are the same as the shadow-tls plugin, i.e., "v3;host=www.bing.com;passwd=password", with
+// an optional "alpn" separated by ','. Only v3 is supported.
+func (this *Account) GetShadowTLSConfig() (*shadowtls.Config, error) {
+	if this.Plugin != PluginShadowTLS {
+		return nil, nil
+	}
+	options, err := this.GetPluginOptions()
+	if err != nil {
+		return nil, err
+	}
+	if _, found := options["v3"]; !found {
+		return nil, errors.New("Shadowsocks|Plugin: Only ShadowTLS v3 is supported.")
+	}
+	config := &shadowtls.Config{
+		ServerName: options["host"],
+		Password:   options["passwd"],
+	}
+	if alpn := options["alpn"]; len(alpn) > 0 {
+		config.ALPN = strings.Split(alpn, ",")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ValidatePlugin returns an error if the plugin of this account is unsupported or misconfigured.
+func (this *Account) ValidatePlugin() error {
+	if _, err := this.GetObfsConfig(); err != nil {
+		return err
+	}
+	_, err := this.GetShadowTLSConfig()
+	return err
+}
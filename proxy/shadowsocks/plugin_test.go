@@ -0,0 +1,64 @@
+package shadowsocks_test
+
+import (
+	"testing"
+
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestPluginOptionsParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	options, err := ParsePluginOptions(`obfs=http;obfs-host=www.bing.com;obfs-ua=Mozilla/5.0 (X11\; Linux x86_64)|curl/7.50.0`)
+	assert.Error(err).IsNil()
+	assert.Int(len(options)).Equals(3)
+	assert.String(options["obfs"]).Equals("http")
+	assert.String(options["obfs-host"]).Equals("www.bing.com")
+
+	config, err := NewObfsConfig(options)
+	assert.Error(err).IsNil()
+	assert.Int(len(config.UserAgents)).Equals(2)
+	assert.String(config.UserAgents[0]).Equals("Mozilla/5.0 (X11; Linux x86_64)")
+
+	reparsed, err := ParsePluginOptions(options.String())
+	assert.Error(err).IsNil()
+	assert.String(reparsed["obfs-ua"]).Equals(options["obfs-ua"])
+}
+
+func TestUnsupportedObfsMode(t *testing.T) {
+	assert := assert.On(t)
+
+	account := &Account{
+		Password:   "password",
+		CipherType: CipherType_AES_128_CFB,
+		Plugin:     "obfs-local",
+		PluginOpts: "obfs=tls",
+	}
+	_, err := account.AsAccount()
+	assert.Error(err).IsNotNil()
+
+	account.Plugin = "v2ray-plugin"
+	_, err = account.AsAccount()
+	assert.Error(err).Equals(ErrUnsupportedPlugin)
+}
+
+func TestShadowTLSPluginOptions(t *testing.T) {
+	assert := assert.On(t)
+
+	account := &Account{
+		Password:   "password",
+		CipherType: CipherType_AES_128_CFB,
+		Plugin:     PluginShadowTLS,
+		PluginOpts: "v3;host=www.bing.com;passwd=tls-password;alpn=h2,http/1.1",
+	}
+	config, err := account.GetShadowTLSConfig()
+	assert.Error(err).IsNil()
+	assert.String(config.ServerName).Equals("www.bing.com")
+	assert.String(config.Password).Equals("tls-password")
+	assert.Int(len(config.ALPN)).Equals(2)
+	assert.Error(account.ValidatePlugin()).IsNil()
+
+	account.PluginOpts = "host=www.bing.com;passwd=tls-password"
+	assert.Error(account.ValidatePlugin()).IsNotNil()
+}
@@ -2,15 +2,16 @@ package shadowsocks
 
 import (
 	"bytes"
-	"crypto/rand"
 	"errors"
 	"io"
 
 	"v2ray.com/core/common/alloc"
 	"v2ray.com/core/common/crypto"
 	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/common/stats"
 )
 
 const (
@@ -20,14 +21,31 @@ const (
 	AddrTypeIPv4   = 1
 	AddrTypeIPv6   = 4
 	AddrTypeDomain = 3
+
+	// Domain length is a single byte in the protocol.
+	MaxDomainLength = 255
+
+	// Layer of Shadowsocks on top of transport layers of connections.
+	LayerShadowsocks = "shadowsocks"
+)
+
+var (
+	ErrDomainTooLong  = errors.New("Shadowsocks: Domain too long.")
+	ErrEmptyDomain    = errors.New("Shadowsocks: Empty domain.")
+	ErrPacketTooShort = errors.New("Shadowsocks|UDP: Packet too short.")
 )
 
 func ReadTCPSession(user *protocol.User, reader io.Reader) (*protocol.RequestHeader, v2io.Reader, error) {
-	rawAccount, err := user.GetTypedAccount()
+	return ReadTCPSessionWithLimit(user, reader, MaxDomainLength)
+}
+
+// ReadTCPSessionWithLimit reads a request header, and rejects it if its domain is longer than
+// maxDomainLength, before reading the domain.
+func ReadTCPSessionWithLimit(user *protocol.User, reader io.Reader, maxDomainLength int) (*protocol.RequestHeader, v2io.Reader, error) {
+	account, err := getAccount(user, protocol.RequestCommandTCP)
 	if err != nil {
 		return nil, nil, errors.New("Shadowsocks|TCP: Failed to parse account: " + err.Error())
 	}
-	account := rawAccount.(*ShadowsocksAccount)
 
 	buffer := alloc.NewLocalBuffer(512)
 	defer buffer.Release()
@@ -53,90 +71,124 @@ func ReadTCPSession(user *protocol.User, reader io.Reader) (*protocol.RequestHea
 		Command: protocol.RequestCommandTCP,
 	}
 
+	lenBuffer, err := readTCPHeader(reader, request, buffer, maxDomainLength)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if request.Option.Has(RequestOptionOneTimeAuth) {
+		authBytes := buffer.Value[lenBuffer : lenBuffer+AuthSize]
+		_, err = io.ReadFull(reader, authBytes)
+		if err != nil {
+			return nil, nil, errors.New("Shadowsocks|TCP: Failed to read OTA: " + err.Error())
+		}
+
+		actualAuth := authenticator.Authenticate(nil, buffer.Value[0:lenBuffer])
+		if !bytes.Equal(actualAuth, authBytes) {
+			return nil, nil, errors.New("Shadowsocks|TCP: Invalid OTA")
+		}
+	}
+
+	if request.Option.Has(protocol.RequestOptionConnectionReuse) {
+		if request.Option.Has(RequestOptionOneTimeAuth) || request.Option.Has(RequestOptionCompression) {
+			return nil, nil, ErrReuseNotSupported
+		}
+		return request, NewFrameReader(reader), nil
+	}
+
+	if request.Option.Has(RequestOptionCompression) {
+		reader = newDecompressionReader(reader)
+	}
+
+	var chunkReader v2io.Reader
+	if request.Option.Has(RequestOptionOneTimeAuth) {
+		chunkReader = NewChunkReader(reader, NewAuthenticator(ChunkKeyGenerator(iv)))
+	} else {
+		chunkReader = v2io.NewAdaptiveReader(reader)
+	}
+
+	return request, chunkReader, nil
+}
+
+// readTCPHeader reads the address of a request header from the decrypted reader into request, using
+// buffer. It returns the length of the header in buffer.
+func readTCPHeader(reader io.Reader, request *protocol.RequestHeader, buffer *alloc.Buffer, maxDomainLength int) (int, error) {
 	lenBuffer := 1
-	_, err = io.ReadFull(reader, buffer.Value[:1])
+	_, err := io.ReadFull(reader, buffer.Value[:1])
 	if err != nil {
-		return nil, nil, errors.New("Sahdowsocks|TCP: Failed to read address type: " + err.Error())
+		return 0, errors.New("Sahdowsocks|TCP: Failed to read address type: " + err.Error())
 	}
 
 	addrType := (buffer.Value[0] & 0x0F)
 	if (buffer.Value[0] & 0x10) == 0x10 {
 		request.Option |= RequestOptionOneTimeAuth
 	}
+	if (buffer.Value[0] & 0x20) == 0x20 {
+		request.Option |= RequestOptionCompression
+	}
+	if (buffer.Value[0] & 0x40) == 0x40 {
+		request.Option |= protocol.RequestOptionConnectionReuse
+	}
 
 	switch addrType {
 	case AddrTypeIPv4:
 		_, err := io.ReadFull(reader, buffer.Value[lenBuffer:lenBuffer+4])
 		if err != nil {
-			return nil, nil, errors.New("Shadowsocks|TCP: Failed to read IPv4 address: " + err.Error())
+			return 0, errors.New("Shadowsocks|TCP: Failed to read IPv4 address: " + err.Error())
 		}
 		request.Address = v2net.IPAddress(buffer.Value[lenBuffer : lenBuffer+4])
 		lenBuffer += 4
 	case AddrTypeIPv6:
 		_, err := io.ReadFull(reader, buffer.Value[lenBuffer:lenBuffer+16])
 		if err != nil {
-			return nil, nil, errors.New("Shadowsocks|TCP: Failed to read IPv6 address: " + err.Error())
+			return 0, errors.New("Shadowsocks|TCP: Failed to read IPv6 address: " + err.Error())
 		}
 		request.Address = v2net.IPAddress(buffer.Value[lenBuffer : lenBuffer+16])
 		lenBuffer += 16
 	case AddrTypeDomain:
 		_, err := io.ReadFull(reader, buffer.Value[lenBuffer:lenBuffer+1])
 		if err != nil {
-			return nil, nil, errors.New("Shadowsocks|TCP: Failed to read domain lenth: " + err.Error())
+			return 0, errors.New("Shadowsocks|TCP: Failed to read domain lenth: " + err.Error())
 		}
 		domainLength := int(buffer.Value[lenBuffer])
+		if domainLength == 0 {
+			return 0, ErrEmptyDomain
+		}
+		if domainLength > maxDomainLength {
+			return 0, ErrDomainTooLong
+		}
 		lenBuffer++
 		_, err = io.ReadFull(reader, buffer.Value[lenBuffer:lenBuffer+domainLength])
 		if err != nil {
-			return nil, nil, errors.New("Shadowsocks|TCP: Failed to read domain: " + err.Error())
+			return 0, errors.New("Shadowsocks|TCP: Failed to read domain: " + err.Error())
 		}
 		request.Address = v2net.DomainAddress(string(buffer.Value[lenBuffer : lenBuffer+domainLength]))
 		lenBuffer += domainLength
 	default:
-		return nil, nil, errors.New("Shadowsocks|TCP: Unknown address type.")
+		return 0, errors.New("Shadowsocks|TCP: Unknown address type.")
 	}
 
 	_, err = io.ReadFull(reader, buffer.Value[lenBuffer:lenBuffer+2])
 	if err != nil {
-		return nil, nil, errors.New("Shadowsocks|TCP: Failed to read port: " + err.Error())
+		return 0, errors.New("Shadowsocks|TCP: Failed to read port: " + err.Error())
 	}
 
 	request.Port = v2net.PortFromBytes(buffer.Value[lenBuffer : lenBuffer+2])
 	lenBuffer += 2
-
-	if request.Option.Has(RequestOptionOneTimeAuth) {
-		authBytes := buffer.Value[lenBuffer : lenBuffer+AuthSize]
-		_, err = io.ReadFull(reader, authBytes)
-		if err != nil {
-			return nil, nil, errors.New("Shadowsocks|TCP: Failed to read OTA: " + err.Error())
-		}
-
-		actualAuth := authenticator.Authenticate(nil, buffer.Value[0:lenBuffer])
-		if !bytes.Equal(actualAuth, authBytes) {
-			return nil, nil, errors.New("Shadowsocks|TCP: Invalid OTA")
-		}
-	}
-
-	var chunkReader v2io.Reader
-	if request.Option.Has(RequestOptionOneTimeAuth) {
-		chunkReader = NewChunkReader(reader, NewAuthenticator(ChunkKeyGenerator(iv)))
-	} else {
-		chunkReader = v2io.NewAdaptiveReader(reader)
-	}
-
-	return request, chunkReader, nil
+	return lenBuffer, nil
 }
 
 func WriteTCPRequest(request *protocol.RequestHeader, writer io.Writer) (v2io.Writer, error) {
 	user := request.User
-	rawAccount, err := user.GetTypedAccount()
+	account, err := getAccount(user, protocol.RequestCommandTCP)
 	if err != nil {
 		return nil, errors.New("Shadowsocks|TCP: Failed to parse account: " + err.Error())
 	}
-	account := rawAccount.(*ShadowsocksAccount)
 
 	iv := make([]byte, account.Cipher.IVSize())
-	rand.Read(iv)
+	if err := generateIV(iv); err != nil {
+		return nil, errors.New("Shadowsocks|TCP: Failed to generate IV: " + err.Error())
+	}
 	_, err = writer.Write(iv)
 	if err != nil {
 		return nil, errors.New("Shadowsocks|TCP: Failed to write IV: " + err.Error())
@@ -149,6 +201,47 @@ func WriteTCPRequest(request *protocol.RequestHeader, writer io.Writer) (v2io.Wr
 
 	writer = crypto.NewCryptionWriter(stream, writer)
 
+	header, err := encodeTCPHeader(request)
+	if err != nil {
+		return nil, err
+	}
+	if request.Option.Has(RequestOptionOneTimeAuth) {
+		authenticator := NewAuthenticator(HeaderKeyGenerator(account.Key, iv))
+		header.Value = authenticator.Authenticate(header.Value, header.Value)
+	}
+
+	_, err = writer.Write(header.Value)
+	if err != nil {
+		return nil, errors.New("Shadowsocks|TCP: Failed to write header: " + err.Error())
+	}
+	if request.Option.Has(protocol.RequestOptionConnectionReuse) {
+		return NewFrameWriter(writer), nil
+	}
+	var compressor *compressionWriter
+	if request.Option.Has(RequestOptionCompression) {
+		compressor = newCompressionWriter(writer)
+		writer = compressor
+	}
+
+	var chunkWriter v2io.Writer
+	if request.Option.Has(RequestOptionOneTimeAuth) {
+		chunkWriter = NewChunkWriter(writer, NewAuthenticator(ChunkKeyGenerator(iv)))
+	} else {
+		chunkWriter = v2io.NewAdaptiveWriter(writer)
+	}
+	if compressor != nil {
+		return &CompressedWriter{Writer: chunkWriter, compressor: compressor}, nil
+	}
+
+	return chunkWriter, nil
+}
+
+// encodeTCPHeader returns the address of request, with its options in the address type.
+func encodeTCPHeader(request *protocol.RequestHeader) (*alloc.Buffer, error) {
+	if request.Option.Has(protocol.RequestOptionConnectionReuse) && (request.Option.Has(RequestOptionOneTimeAuth) || request.Option.Has(RequestOptionCompression)) {
+		return nil, ErrReuseNotSupported
+	}
+
 	header := alloc.NewLocalBuffer(512).Clear()
 
 	switch request.Address.Family() {
@@ -156,6 +249,7 @@ func WriteTCPRequest(request *protocol.RequestHeader, writer io.Writer) (v2io.Wr
 		header.AppendBytes(AddrTypeIPv4)
 		header.Append([]byte(request.Address.IP()))
 	case v2net.AddressFamilyIPv6:
+		// Zone of IPv6 addresses is not meaningful on the server, so it is dropped.
 		header.AppendBytes(AddrTypeIPv6)
 		header.Append([]byte(request.Address.IP()))
 	case v2net.AddressFamilyDomain:
@@ -167,38 +261,46 @@ func WriteTCPRequest(request *protocol.RequestHeader, writer io.Writer) (v2io.Wr
 
 	header.AppendUint16(uint16(request.Port))
 
+	if request.Option.Has(RequestOptionCompression) {
+		header.Value[0] |= 0x20
+	}
+	if request.Option.Has(protocol.RequestOptionConnectionReuse) {
+		header.Value[0] |= 0x40
+	}
 	if request.Option.Has(RequestOptionOneTimeAuth) {
 		header.Value[0] |= 0x10
-
-		authenticator := NewAuthenticator(HeaderKeyGenerator(account.Key, iv))
-		header.Value = authenticator.Authenticate(header.Value, header.Value)
 	}
+	return header, nil
+}
 
-	_, err = writer.Write(header.Value)
-	if err != nil {
-		return nil, errors.New("Shadowsocks|TCP: Failed to write header: " + err.Error())
-	}
+func ReadTCPResponse(user *protocol.User, reader io.Reader) (v2io.Reader, error) {
+	return ReadTCPResponseWithBufferSize(user, reader, 0)
+}
 
-	var chunkWriter v2io.Writer
-	if request.Option.Has(RequestOptionOneTimeAuth) {
-		chunkWriter = NewChunkWriter(writer, NewAuthenticator(ChunkKeyGenerator(iv)))
-	} else {
-		chunkWriter = v2io.NewAdaptiveWriter(writer)
+// ReadTCPResponseWithBufferSize reads the response header, and returns a reader of the response body that
+// reads at most bufferSize bytes at a time. Buffer size is adjusted automatically if bufferSize is 0.
+func ReadTCPResponseWithBufferSize(user *protocol.User, reader io.Reader, bufferSize int) (v2io.Reader, error) {
+	responseReader, err := ReadTCPResponseWithConfig(&protocol.RequestHeader{User: user}, reader, bufferSize, nil)
+	if err != nil {
+		return nil, err
 	}
-
-	return chunkWriter, nil
+	return responseReader, nil
 }
 
-func ReadTCPResponse(user *protocol.User, reader io.Reader) (v2io.Reader, error) {
-	rawAccount, err := user.GetTypedAccount()
+// ReadTCPResponseWithConfig is ReadTCPResponseWithBufferSize for the response to request, with trailing
+// data handled by trailing.
+func ReadTCPResponseWithConfig(request *protocol.RequestHeader, reader io.Reader, bufferSize int, trailing *TrailingDataConfig) (*ResponseReader, error) {
+	account, err := getAccount(request.User, protocol.RequestCommandTCP)
 	if err != nil {
 		return nil, errors.New("Shadowsocks|TCP: Failed to parse account: " + err.Error())
 	}
-	account := rawAccount.(*ShadowsocksAccount)
 
 	iv := make([]byte, account.Cipher.IVSize())
 	_, err = io.ReadFull(reader, iv)
 	if err != nil {
+		if IsConnectionReset(err) {
+			return nil, &ResponseError{Reset: true, Err: err}
+		}
 		return nil, errors.New("Shadowsocks|TCP: Failed to read IV: " + err.Error())
 	}
 
@@ -206,19 +308,33 @@ func ReadTCPResponse(user *protocol.User, reader io.Reader) (v2io.Reader, error)
 	if err != nil {
 		return nil, errors.New("Shadowsocks|TCP: Failed to initialize decoding stream: " + err.Error())
 	}
-	return v2io.NewAdaptiveReader(crypto.NewCryptionReader(stream, reader)), nil
+	reader = crypto.NewCryptionReader(stream, reader)
+	if request.Option.Has(RequestOptionCompression) {
+		reader = newDecompressionReader(reader)
+	}
+	var bodyReader v2io.Reader
+	if request.Option.Has(protocol.RequestOptionConnectionReuse) {
+		bodyReader = NewFrameReader(reader)
+	} else {
+		bodyReader = v2io.NewSizedReader(reader, bufferSize)
+	}
+	// Errors other than EOF are returned as ResponseError, to tell a truncated response from a complete one.
+	responseReader := NewResponseReader(bodyReader)
+	responseReader.SetMaxTrailingData(trailing.GetEffectiveMaxSize())
+	return responseReader, nil
 }
 
 func WriteTCPResponse(request *protocol.RequestHeader, writer io.Writer) (v2io.Writer, error) {
 	user := request.User
-	rawAccount, err := user.GetTypedAccount()
+	account, err := getAccount(user, protocol.RequestCommandTCP)
 	if err != nil {
 		return nil, errors.New("Shadowsocks|TCP: Failed to parse account: " + err.Error())
 	}
-	account := rawAccount.(*ShadowsocksAccount)
 
 	iv := make([]byte, account.Cipher.IVSize())
-	rand.Read(iv)
+	if err := generateIV(iv); err != nil {
+		return nil, errors.New("Shadowsocks|TCP: Failed to generate IV: " + err.Error())
+	}
 	_, err = writer.Write(iv)
 	if err != nil {
 		return nil, errors.New("Shadowsocks|TCP: Failed to write IV: " + err.Error())
@@ -229,21 +345,31 @@ func WriteTCPResponse(request *protocol.RequestHeader, writer io.Writer) (v2io.W
 		return nil, errors.New("Shadowsocks|TCP: Failed to create encoding stream: " + err.Error())
 	}
 
-	return v2io.NewAdaptiveWriter(crypto.NewCryptionWriter(stream, writer)), nil
+	writer = crypto.NewCryptionWriter(stream, writer)
+	if request.Option.Has(protocol.RequestOptionConnectionReuse) {
+		return NewFrameWriter(writer), nil
+	}
+	if request.Option.Has(RequestOptionCompression) {
+		compressor := newCompressionWriter(writer)
+		return &CompressedWriter{Writer: v2io.NewAdaptiveWriter(compressor), compressor: compressor}, nil
+	}
+	return v2io.NewAdaptiveWriter(writer), nil
 }
 
 func EncodeUDPPacket(request *protocol.RequestHeader, payload *alloc.Buffer) (*alloc.Buffer, error) {
 	user := request.User
-	rawAccount, err := user.GetTypedAccount()
+	account, err := getAccount(user, protocol.RequestCommandUDP)
 	if err != nil {
 		return nil, errors.New("Shadowsocks|UDP: Failed to parse account: " + err.Error())
 	}
-	account := rawAccount.(*ShadowsocksAccount)
 
 	buffer := alloc.NewLocalBuffer(2048)
 	ivLen := account.Cipher.IVSize()
 	buffer.Slice(0, ivLen)
-	rand.Read(buffer.Value)
+	if err := generateIV(buffer.Value); err != nil {
+		buffer.Release()
+		return nil, errors.New("Shadowsocks|UDP: Failed to generate IV: " + err.Error())
+	}
 	iv := buffer.Value
 
 	switch request.Address.Family() {
@@ -251,6 +377,7 @@ func EncodeUDPPacket(request *protocol.RequestHeader, payload *alloc.Buffer) (*a
 		buffer.AppendBytes(AddrTypeIPv4)
 		buffer.Append([]byte(request.Address.IP()))
 	case v2net.AddressFamilyIPv6:
+		// Zone is dropped, as for TCP requests.
 		buffer.AppendBytes(AddrTypeIPv6)
 		buffer.Append([]byte(request.Address.IP()))
 	case v2net.AddressFamilyDomain:
@@ -280,13 +407,16 @@ func EncodeUDPPacket(request *protocol.RequestHeader, payload *alloc.Buffer) (*a
 }
 
 func DecodeUDPPacket(user *protocol.User, payload *alloc.Buffer) (*protocol.RequestHeader, *alloc.Buffer, error) {
-	rawAccount, err := user.GetTypedAccount()
+	account, err := getAccount(user, protocol.RequestCommandUDP)
 	if err != nil {
 		return nil, nil, errors.New("Shadowsocks|UDP: Failed to parse account: " + err.Error())
 	}
-	account := rawAccount.(*ShadowsocksAccount)
 
 	ivLen := account.Cipher.IVSize()
+	// IV, address type and at least an IPv4 address with port.
+	if payload.Len() < ivLen+1+4+2 {
+		return nil, nil, ErrPacketTooShort
+	}
 	iv := payload.Value[:ivLen]
 	payload.SliceFrom(ivLen)
 
@@ -310,6 +440,9 @@ func DecodeUDPPacket(user *protocol.User, payload *alloc.Buffer) (*protocol.Requ
 
 	if request.Option.Has(RequestOptionOneTimeAuth) {
 		payloadLen := payload.Len() - AuthSize
+		if payloadLen < 1+4+2 {
+			return nil, nil, ErrPacketTooShort
+		}
 		authBytes := payload.Value[payloadLen:]
 
 		actualAuth := authenticator.Authenticate(nil, payload.Value[0:payloadLen])
@@ -327,10 +460,19 @@ func DecodeUDPPacket(user *protocol.User, payload *alloc.Buffer) (*protocol.Requ
 		request.Address = v2net.IPAddress(payload.Value[:4])
 		payload.SliceFrom(4)
 	case AddrTypeIPv6:
+		if payload.Len() < 16+2 {
+			return nil, nil, ErrPacketTooShort
+		}
 		request.Address = v2net.IPAddress(payload.Value[:16])
 		payload.SliceFrom(16)
 	case AddrTypeDomain:
 		domainLength := int(payload.Value[0])
+		if domainLength == 0 {
+			return nil, nil, ErrEmptyDomain
+		}
+		if payload.Len() < 1+domainLength+2 {
+			return nil, nil, ErrPacketTooShort
+		}
 		request.Address = v2net.DomainAddress(string(payload.Value[1 : 1+domainLength]))
 		payload.SliceFrom(1 + domainLength)
 	default:
@@ -346,22 +488,37 @@ func DecodeUDPPacket(user *protocol.User, payload *alloc.Buffer) (*protocol.Requ
 type UDPReader struct {
 	Reader io.Reader
 	User   *protocol.User
+	// Number of malformed datagrams skipped, if not nil.
+	Malformed *stats.Counter
 }
 
 func (this *UDPReader) Read() (*alloc.Buffer, error) {
-	buffer := alloc.NewLocalBuffer(2048)
-	nBytes, err := this.Reader.Read(buffer.Value)
-	if err != nil {
-		buffer.Release()
-		return nil, err
-	}
-	buffer.Slice(0, nBytes)
-	_, payload, err := DecodeUDPPacket(this.User, buffer)
-	if err != nil {
+	payload, _, err := this.ReadFrom()
+	return payload, err
+}
+
+// ReadFrom reads a UDP response, and returns its payload with the source destination embedded in the
+// response by the server. Malformed datagrams are skipped, so that noise doesn't end the association.
+func (this *UDPReader) ReadFrom() (*alloc.Buffer, v2net.Destination, error) {
+	for {
+		buffer := alloc.NewLocalBuffer(2048)
+		nBytes, err := this.Reader.Read(buffer.Value)
+		if err != nil {
+			buffer.Release()
+			return nil, v2net.Destination{}, err
+		}
+		buffer.Slice(0, nBytes)
+		request, payload, err := DecodeUDPPacket(this.User, buffer)
+		if err == nil {
+			return payload, v2net.UDPDestination(request.Address, request.Port), nil
+		}
 		buffer.Release()
-		return nil, err
+		if _, accountErr := getAccount(this.User, protocol.RequestCommandUDP); accountErr != nil {
+			return nil, v2net.Destination{}, err
+		}
+		this.Malformed.Add(1)
+		log.Debug("Shadowsocks|UDP: Skipping malformed datagram: ", err)
 	}
-	return payload, nil
 }
 
 func (this *UDPReader) Release() {
@@ -1,12 +1,15 @@
 package shadowsocks_test
 
 import (
+	"io"
+	"strings"
 	"testing"
 
 	"v2ray.com/core/common/alloc"
 	"v2ray.com/core/common/loader"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/common/stats"
 	. "v2ray.com/core/proxy/shadowsocks"
 	"v2ray.com/core/testing/assert"
 )
@@ -40,6 +43,58 @@ func TestUDPEncoding(t *testing.T) {
 	assert.Port(decodedRequest.Port).Equals(request.Port)
 }
 
+func TestSeparateUDPAccount(t *testing.T) {
+	assert := assert.On(t)
+
+	user := &protocol.User{
+		Email: "love@v2ray.com",
+		Account: loader.NewTypedSettings(&Account{
+			Password:   "shadowsocks-password",
+			CipherType: CipherType_AES_256_CFB,
+			Ota:        Account_Disabled,
+			Udp: &UDPAccount{
+				Password:   "udp-password",
+				CipherType: CipherType_CHACHA20,
+			},
+		}),
+	}
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandUDP,
+		Address: v2net.LocalHostIP,
+		Port:    1234,
+		User:    user,
+	}
+
+	data := alloc.NewLocalBuffer(256).Clear().AppendString("test string")
+	encodedData, err := EncodeUDPPacket(request, data)
+	assert.Error(err).IsNil()
+	encoded := append([]byte(nil), encodedData.Value...)
+	// 8 bytes of IV for CHACHA20, then 7 bytes of address header and the payload.
+	assert.Int(len(encoded)).Equals(8 + 7 + len("test string"))
+
+	_, decodedData, err := DecodeUDPPacket(user, encodedData)
+	assert.Error(err).IsNil()
+	assert.Bytes(decodedData.Value).Equals([]byte("test string"))
+
+	// TCP account doesn't decode UDP packets.
+	tcpOnly := &protocol.User{
+		Account: loader.NewTypedSettings(&Account{
+			Password:   "shadowsocks-password",
+			CipherType: CipherType_AES_256_CFB,
+			Ota:        Account_Disabled,
+		}),
+	}
+	_, decodedData, err = DecodeUDPPacket(tcpOnly, alloc.NewLocalBuffer(256).Clear().Append(encoded))
+	assert.Bool(err == nil && string(decodedData.Value) == "test string").IsFalse()
+
+	rawAccount, err := user.GetTypedAccount()
+	assert.Error(err).IsNil()
+	account := rawAccount.(*ShadowsocksAccount)
+	assert.Pointer(account.ForCommand(protocol.RequestCommandTCP)).Equals(account)
+	assert.Bytes(account.ForCommand(protocol.RequestCommandUDP).Key).Equals(PasswordToCipherKey("udp-password", 32))
+}
+
 func TestTCPRequest(t *testing.T) {
 	assert := assert.On(t)
 
@@ -116,3 +171,254 @@ func TestUDPReaderWriter(t *testing.T) {
 	assert.Error(err).IsNil()
 	assert.String(payload.String()).Equals("test payload 2")
 }
+
+type countingReader struct {
+	reader io.Reader
+	count  int
+}
+
+func (this *countingReader) Read(b []byte) (int, error) {
+	nBytes, err := this.reader.Read(b)
+	this.count += nBytes
+	return nBytes, err
+}
+
+func TestTCPRequestDomainTooLong(t *testing.T) {
+	assert := assert.On(t)
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: v2net.DomainAddress(strings.Repeat("a", 200) + ".com"),
+		Port:    1234,
+		User: &protocol.User{
+			Account: loader.NewTypedSettings(&Account{
+				Password:   "tcp-password",
+				CipherType: CipherType_AES_128_CFB,
+				Ota:        Account_Disabled,
+			}),
+		},
+	}
+
+	cache := alloc.NewLargeBuffer().Clear()
+	_, err := WriteTCPRequest(request, cache)
+	assert.Error(err).IsNil()
+
+	reader := &countingReader{reader: cache}
+	_, _, err = ReadTCPSessionWithLimit(request.User, reader, 64)
+	assert.Error(err).Equals(ErrDomainTooLong)
+	// IV, address type and domain length only. The domain is not read.
+	assert.Int(reader.count).Equals(16 + 1 + 1)
+}
+
+func TestTruncatedUDPPacket(t *testing.T) {
+	assert := assert.On(t)
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandUDP,
+		Address: v2net.DomainAddress("www.v2ray.com"),
+		Port:    1234,
+		User: &protocol.User{
+			Account: loader.NewTypedSettings(&Account{
+				Password:   "udp-password",
+				CipherType: CipherType_AES_128_CFB,
+				Ota:        Account_Disabled,
+			}),
+		},
+	}
+
+	encodedData, err := EncodeUDPPacket(request, alloc.NewLocalBuffer(256).Clear())
+	assert.Error(err).IsNil()
+	for size := 0; size < encodedData.Len(); size++ {
+		packet := alloc.NewLocalBuffer(256).Clear().Append(encodedData.Value[:size])
+		_, _, err := DecodeUDPPacket(request.User, packet)
+		assert.Error(err).IsNotNil()
+	}
+}
+
+func TestUDPReaderFromMultipleDestinations(t *testing.T) {
+	assert := assert.On(t)
+
+	user := &protocol.User{
+		Account: loader.NewTypedSettings(&Account{
+			Password:   "test-password",
+			CipherType: CipherType_AES_128_CFB,
+		}),
+	}
+	cache := alloc.NewBuffer().Clear()
+	reader := &UDPReader{
+		Reader: cache,
+		User:   user,
+	}
+
+	destinations := []v2net.Destination{
+		v2net.UDPDestination(v2net.IPAddress([]byte{8, 8, 8, 8}), 53),
+		v2net.UDPDestination(v2net.DomainAddress("v2ray.com"), 123),
+		v2net.UDPDestination(v2net.IPAddress([]byte{0x20, 0x01, 0x48, 0x60, 0x48, 0x60, 0, 0, 0, 0, 0, 0, 0, 0, 0x88, 0x88}), 53),
+	}
+	for _, dest := range destinations {
+		writer := &UDPWriter{
+			Writer: cache,
+			Request: &protocol.RequestHeader{
+				Version: Version,
+				Address: dest.Address,
+				Port:    dest.Port,
+				User:    user,
+			},
+		}
+		assert.Error(writer.Write(alloc.NewBuffer().Clear().AppendString("response from " + dest.String()))).IsNil()
+
+		payload, source, err := reader.ReadFrom()
+		assert.Error(err).IsNil()
+		assert.Destination(source).EqualsString(dest.String())
+		assert.String(payload.String()).Equals("response from " + dest.String())
+	}
+}
+
+// datagramReader returns one datagram on each read.
+type datagramReader struct {
+	datagrams [][]byte
+}
+
+func (this *datagramReader) Read(b []byte) (int, error) {
+	if len(this.datagrams) == 0 {
+		return 0, io.EOF
+	}
+	nBytes := copy(b, this.datagrams[0])
+	this.datagrams = this.datagrams[1:]
+	return nBytes, nil
+}
+
+func TestUDPReaderSkipsMalformedDatagram(t *testing.T) {
+	assert := assert.On(t)
+
+	user := &protocol.User{
+		Account: loader.NewTypedSettings(&Account{
+			Password:   "test-password",
+			CipherType: CipherType_AES_128_CFB,
+		}),
+	}
+	datagrams := new(datagramReader)
+	for _, text := range []string{"first", "malformed", "last"} {
+		packet, err := EncodeUDPPacket(&protocol.RequestHeader{
+			Version: Version,
+			Address: v2net.IPAddress([]byte{8, 8, 8, 8}),
+			Port:    53,
+			User:    user,
+		}, alloc.NewBuffer().Clear().AppendString(text))
+		assert.Error(err).IsNil()
+		datagrams.datagrams = append(datagrams.datagrams, append([]byte(nil), packet.Value...))
+	}
+	// The byte after the IV is the address type. An unknown type comes out of the stream cipher, as IPv4
+	// is 1.
+	datagrams.datagrams[1][16] ^= 0x0E
+
+	malformed := new(stats.Counter)
+	reader := &UDPReader{
+		Reader:    datagrams,
+		User:      user,
+		Malformed: malformed,
+	}
+	payload, source, err := reader.ReadFrom()
+	assert.Error(err).IsNil()
+	assert.String(payload.String()).Equals("first")
+	assert.Destination(source).EqualsString("udp:8.8.8.8:53")
+
+	payload, err = reader.Read()
+	assert.Error(err).IsNil()
+	assert.String(payload.String()).Equals("last")
+	assert.Int64(malformed.Value()).Equals(1)
+
+	_, err = reader.Read()
+	assert.Error(err).Equals(io.EOF)
+}
+
+func TestTCPResponseBufferSize(t *testing.T) {
+	assert := assert.On(t)
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: v2net.LocalHostIP,
+		Port:    1234,
+		User: &protocol.User{
+			Email: "love@v2ray.com",
+			Account: loader.NewTypedSettings(&Account{
+				Password:   "tcp-password",
+				CipherType: CipherType_AES_128_CFB,
+			}),
+		},
+	}
+
+	cache := alloc.NewLargeBuffer().Clear()
+	writer, err := WriteTCPResponse(request, cache)
+	assert.Error(err).IsNil()
+	assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString(strings.Repeat("a", 1000)))).IsNil()
+
+	reader, err := ReadTCPResponseWithBufferSize(request.User, cache, 256)
+	assert.Error(err).IsNil()
+
+	total := 0
+	for total < 1000 {
+		payload, err := reader.Read()
+		assert.Error(err).IsNil()
+		assert.Bool(payload.Len() <= 256).IsTrue()
+		total += payload.Len()
+	}
+	assert.Int(total).Equals(1000)
+}
+
+func TestTCPResponseTrailingData(t *testing.T) {
+	assert := assert.On(t)
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: v2net.LocalHostIP,
+		Port:    1234,
+		User: &protocol.User{
+			Email: "love@v2ray.com",
+			Account: loader.NewTypedSettings(&Account{
+				Password:   "tcp-password",
+				CipherType: CipherType_AES_128_CFB,
+			}),
+		},
+	}
+
+	readWithTrailing := func(trailing *TrailingDataConfig, size int) error {
+		cache := alloc.NewLargeBuffer().Clear()
+		writer, err := WriteTCPResponse(request, cache)
+		assert.Error(err).IsNil()
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+
+		reader, err := ReadTCPResponseWithConfig(request, cache, 0, trailing)
+		assert.Error(err).IsNil()
+		payload, err := reader.Read()
+		assert.Error(err).IsNil()
+		assert.String(payload.String()).Equals("response")
+
+		// Anything after the response is finished is trailing data, and never returned.
+		reader.Finish()
+		if size > 0 {
+			assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString(strings.Repeat("p", size)))).IsNil()
+		}
+		_, err = reader.Read()
+		assert.Bool(err != nil).IsTrue()
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	assert.Error(readWithTrailing(nil, 0)).IsNil()
+	assert.Error(readWithTrailing(nil, 1000)).IsNil()
+	assert.Error(readWithTrailing(&TrailingDataConfig{MaxSize: 100}, 100)).IsNil()
+	assert.Error(readWithTrailing(&TrailingDataConfig{MaxSize: 100}, 101)).IsNotNil()
+
+	assert.Error(readWithTrailing(&TrailingDataConfig{Strict: true}, 0)).IsNil()
+	err := readWithTrailing(&TrailingDataConfig{Strict: true}, 1)
+	assert.Error(err).IsNotNil()
+	assert.Error(err.(*ResponseError).Err).Equals(ErrTrailingData)
+	assert.Int(int(err.(*ResponseError).Received)).Equals(len("response"))
+}
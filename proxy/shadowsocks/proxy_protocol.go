@@ -0,0 +1,108 @@
+package shadowsocks
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+)
+
+var (
+	ErrUnknownProxyProtocolVersion = errors.New("Shadowsocks|ProxyProtocol: Unknown version.")
+
+	proxyProtocolV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+)
+
+// EncodeProxyProtocolHeader returns a PROXY protocol header of the given version, for a TCP connection
+// from source to destination. If source is not an IP address, the header tells no address.
+func EncodeProxyProtocolHeader(version uint32, source v2net.Destination, destination v2net.Destination) ([]byte, error) {
+	srcIP, dstIP := proxyProtocolAddresses(source, destination)
+	switch version {
+	case 1:
+		return encodeProxyProtocolV1(srcIP, source.Port, dstIP, destination.Port), nil
+	case 2:
+		return encodeProxyProtocolV2(srcIP, source.Port, dstIP, destination.Port), nil
+	default:
+		return nil, ErrUnknownProxyProtocolVersion
+	}
+}
+
+// proxyProtocolAddresses returns IPs of source and destination in the same length, or nil if either of
+// them is not an IP address.
+func proxyProtocolAddresses(source v2net.Destination, destination v2net.Destination) (net.IP, net.IP) {
+	if source.Address == nil || destination.Address == nil ||
+		source.Address.Family().IsDomain() || destination.Address.Family().IsDomain() {
+		return nil, nil
+	}
+	srcIP := source.Address.IP()
+	dstIP := destination.Address.IP()
+	if srcIP4, dstIP4 := srcIP.To4(), dstIP.To4(); srcIP4 != nil && dstIP4 != nil {
+		return srcIP4, dstIP4
+	}
+	return srcIP.To16(), dstIP.To16()
+}
+
+func encodeProxyProtocolV1(srcIP net.IP, srcPort v2net.Port, dstIP net.IP, dstPort v2net.Port) []byte {
+	if srcIP == nil {
+		return []byte("PROXY UNKNOWN\r\n")
+	}
+	family := "TCP4"
+	if len(srcIP) == net.IPv6len {
+		family = "TCP6"
+	}
+	return []byte("PROXY " + family + " " + formatProxyProtocolIP(srcIP) + " " + formatProxyProtocolIP(dstIP) + " " + srcPort.String() + " " + dstPort.String() + "\r\n")
+}
+
+// formatProxyProtocolIP formats ip in its own family. net.IP.String() prints IPv4-mapped IPv6 addresses
+// as IPv4, which is not valid in a TCP6 header.
+func formatProxyProtocolIP(ip net.IP) string {
+	if len(ip) == net.IPv6len && ip.To4() != nil {
+		return "::ffff:" + ip.To4().String()
+	}
+	return ip.String()
+}
+
+func encodeProxyProtocolV2(srcIP net.IP, srcPort v2net.Port, dstIP net.IP, dstPort v2net.Port) []byte {
+	buffer := alloc.NewLocalBuffer(64).Clear()
+	buffer.Append(proxyProtocolV2Signature)
+	if srcIP == nil {
+		// LOCAL command with no address.
+		buffer.AppendBytes(0x20, 0x00).AppendUint16(0)
+		return buffer.Bytes()
+	}
+	family := byte(0x11)
+	if len(srcIP) == net.IPv6len {
+		family = 0x21
+	}
+	buffer.AppendBytes(0x21, family).AppendUint16(uint16(2*len(srcIP) + 4))
+	buffer.Append(srcIP).Append(dstIP)
+	buffer.AppendUint16(srcPort.Value()).AppendUint16(dstPort.Value())
+	return buffer.Bytes()
+}
+
+// proxyProtocolConn sends a PROXY protocol header together with the first write on the connection.
+type proxyProtocolConn struct {
+	internet.Connection
+	sync.Mutex
+	header []byte
+}
+
+func (this *proxyProtocolConn) Write(b []byte) (int, error) {
+	this.Lock()
+	header := this.header
+	this.header = nil
+	this.Unlock()
+
+	if header == nil {
+		return this.Connection.Write(b)
+	}
+	nBytes, err := this.Connection.Write(append(header, b...))
+	nBytes -= len(header)
+	if nBytes < 0 {
+		nBytes = 0
+	}
+	return nBytes, err
+}
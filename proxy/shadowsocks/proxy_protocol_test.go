@@ -0,0 +1,127 @@
+package shadowsocks_test
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
+)
+
+func TestProxyProtocolV1Header(t *testing.T) {
+	assert := assert.On(t)
+
+	header, err := EncodeProxyProtocolHeader(1,
+		v2net.TCPDestination(v2net.IPAddress([]byte{192, 168, 0, 1}), 56324),
+		v2net.TCPDestination(v2net.IPAddress([]byte{192, 168, 0, 11}), 443))
+	assert.Error(err).IsNil()
+	assert.String(string(header)).Equals("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n")
+
+	header, err = EncodeProxyProtocolHeader(1,
+		v2net.TCPDestination(v2net.IPAddress([]byte{192, 168, 0, 1}), 56324),
+		v2net.TCPDestination(v2net.IPAddress(net.ParseIP("2001:db8::1")), 443))
+	assert.Error(err).IsNil()
+	assert.String(string(header)).Equals("PROXY TCP6 ::ffff:192.168.0.1 2001:db8::1 56324 443\r\n")
+
+	header, err = EncodeProxyProtocolHeader(1, v2net.Destination{},
+		v2net.TCPDestination(v2net.IPAddress([]byte{192, 168, 0, 11}), 443))
+	assert.Error(err).IsNil()
+	assert.String(string(header)).Equals("PROXY UNKNOWN\r\n")
+
+	_, err = EncodeProxyProtocolHeader(3, v2net.Destination{}, v2net.Destination{})
+	assert.Error(err).Equals(ErrUnknownProxyProtocolVersion)
+}
+
+func TestProxyProtocolV2Header(t *testing.T) {
+	assert := assert.On(t)
+
+	header, err := EncodeProxyProtocolHeader(2,
+		v2net.TCPDestination(v2net.IPAddress([]byte{192, 168, 0, 1}), 56324),
+		v2net.TCPDestination(v2net.IPAddress([]byte{192, 168, 0, 11}), 443))
+	assert.Error(err).IsNil()
+	assert.Bytes(header).Equals([]byte{
+		0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A,
+		0x21, 0x11, 0x00, 0x0C,
+		192, 168, 0, 1, 192, 168, 0, 11,
+		0xDC, 0x04, 0x01, 0xBB,
+	})
+
+	header, err = EncodeProxyProtocolHeader(2, v2net.Destination{}, v2net.Destination{})
+	assert.Error(err).IsNil()
+	assert.Bytes(header).Equals([]byte{
+		0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A,
+		0x20, 0x00, 0x00, 0x00,
+	})
+}
+
+func TestClientSendsProxyProtocolHeader(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer listener.Close()
+
+	headers := make(chan string, 1)
+	go func() {
+		conn, err := listener.AcceptTCP()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		header, err := reader.ReadString('\n')
+		assert.Error(err).IsNil()
+		headers <- header
+
+		request, bodyReader, err := ReadTCPSession(newTestUser(), reader)
+		assert.Error(err).IsNil()
+		assert.Address(request.Address).Equals(testDestination.Address)
+		assert.String(readAll(assert, bodyReader, 7)).Equals("request")
+		writer, err := WriteTCPResponse(request, conn)
+		assert.Error(err).IsNil()
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+	}()
+
+	port := v2net.Port(listener.Addr().(*net.TCPAddr).Port)
+	space := app.NewSpace()
+	client, err := NewClient(&ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(port),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		ProxyProtocol: 1,
+	}, space, &proxy.OutboundHandlerMeta{
+		Address: v2net.AnyIP,
+		StreamSettings: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+	})
+	assert.Error(err).IsNil()
+	assert.Error(space.Initialize()).IsNil()
+
+	traffic := ray.NewRay()
+	result := make(chan error, 1)
+	go func() {
+		result <- client.DispatchSession(&proxy.SessionInfo{
+			Source:      v2net.TCPDestination(v2net.IPAddress([]byte{10, 0, 0, 1}), 12345),
+			Destination: testDestination,
+		}, alloc.NewLocalBuffer(2048).Clear().AppendString("request"), traffic)
+	}()
+
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+	assert.String(<-headers).Equals("PROXY TCP4 10.0.0.1 127.0.0.1 12345 " + port.String() + "\r\n")
+}
@@ -0,0 +1,364 @@
+package shadowsocks
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/log"
+)
+
+var (
+	ErrDataQuotaExceeded  = errors.New("Shadowsocks|Quota: Monthly data quota exceeded.")
+	ErrTooManyConnections = errors.New("Shadowsocks|Quota: Too many connections.")
+)
+
+// QuotaStore keeps data usage of users. Implementations that share usage among servers must be safe for
+// concurrent use.
+type QuotaStore interface {
+	// Add adds bytes to the usage of user in period, and returns the total usage.
+	Add(user string, period string, bytes int64) (int64, error)
+	// Get returns the usage of user in period.
+	Get(user string, period string) (int64, error)
+}
+
+// expiringQuotaStore is a QuotaStore that can drop usage of past periods.
+type expiringQuotaStore interface {
+	// Expire removes usage of all periods before period.
+	Expire(period string) error
+}
+
+// MemoryQuotaStore is a QuotaStore in memory, whose usage is lost on restart. Usage of past periods is
+// dropped once the manager moves on to a new period.
+type MemoryQuotaStore struct {
+	sync.Mutex
+	// Usage of users by period.
+	usage map[string]map[string]int64
+}
+
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{
+		usage: make(map[string]map[string]int64),
+	}
+}
+
+func (this *MemoryQuotaStore) Add(user string, period string, bytes int64) (int64, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	users, found := this.usage[period]
+	if !found {
+		users = make(map[string]int64)
+		this.usage[period] = users
+	}
+	users[user] += bytes
+	return users[user], nil
+}
+
+func (this *MemoryQuotaStore) Get(user string, period string) (int64, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	return this.usage[period][user], nil
+}
+
+func (this *MemoryQuotaStore) Expire(period string) error {
+	this.Lock()
+	defer this.Unlock()
+
+	// Periods are formatted as "2006-01", so they sort by time.
+	for past := range this.usage {
+		if past < period {
+			delete(this.usage, past)
+		}
+	}
+	return nil
+}
+
+// QuotaEvent is emitted when a user exceeds the quota.
+type QuotaEvent struct {
+	User string
+	// ErrDataQuotaExceeded or ErrTooManyConnections.
+	Reason error
+	// Data usage of the user in the current month.
+	Usage int64
+}
+
+const (
+	// Usage of a user is added to the store once this many bytes are pending, or once quotaFlushInterval
+	// passes since the last addition.
+	quotaFlushBytes    = 256 * 1024
+	quotaFlushInterval = 10 * time.Second
+)
+
+// QuotaManager enforces data quota and connection limit of each user. A nil QuotaManager doesn't limit
+// anything.
+type QuotaManager struct {
+	sync.Mutex
+	monthlyBytes   int64
+	maxConnections int
+	store          QuotaStore
+	connections    map[string]int
+	listener       func(QuotaEvent)
+	// Period of the usage below. Usage of other periods is not kept.
+	currentPeriod string
+	// Bytes of users not added to the store yet.
+	pending map[string]int64
+	// Usage of users returned by the store at their last addition, and the time of it.
+	usage     map[string]int64
+	flushedAt map[string]time.Time
+	// Returns the current time, for tests.
+	now func() time.Time
+}
+
+// NewQuotaManager creates a QuotaManager with usage in memory. It returns nil if config has no limit.
+func NewQuotaManager(config *QuotaConfig) *QuotaManager {
+	if config == nil || (config.MonthlyBytes == 0 && config.MaxConnections == 0) {
+		return nil
+	}
+	return &QuotaManager{
+		monthlyBytes:   int64(config.MonthlyBytes),
+		maxConnections: int(config.MaxConnections),
+		store:          NewMemoryQuotaStore(),
+		connections:    make(map[string]int),
+		pending:        make(map[string]int64),
+		usage:          make(map[string]int64),
+		flushedAt:      make(map[string]time.Time),
+		now:            time.Now,
+	}
+}
+
+// SetStore replaces the store of data usage. It must be called before the manager is used.
+func (this *QuotaManager) SetStore(store QuotaStore) {
+	if this != nil {
+		this.store = store
+	}
+}
+
+// SetListener sets the function that receives QuotaEvents. It must be called before the manager is used.
+func (this *QuotaManager) SetListener(listener func(QuotaEvent)) {
+	if this != nil {
+		this.listener = listener
+	}
+}
+
+// period returns the month that usage is counted in, in UTC.
+func (this *QuotaManager) period() string {
+	return this.now().UTC().Format("2006-01")
+}
+
+// quotaRotation is the move of a QuotaManager from one period to the next.
+type quotaRotation struct {
+	previous string
+	// Bytes of users pending in the previous period.
+	pending map[string]int64
+	current string
+}
+
+// rotate moves the manager on to the current period, and returns it, with the rotation if the period has
+// changed. Caller must hold the lock, and finish the rotation outside of it.
+func (this *QuotaManager) rotate() (string, *quotaRotation) {
+	period := this.period()
+	if period == this.currentPeriod {
+		return period, nil
+	}
+	rotation := &quotaRotation{
+		previous: this.currentPeriod,
+		pending:  this.pending,
+		current:  period,
+	}
+	this.currentPeriod = period
+	this.pending = make(map[string]int64)
+	this.usage = make(map[string]int64)
+	this.flushedAt = make(map[string]time.Time)
+	return period, rotation
+}
+
+// finish adds bytes pending in the previous period to the store, and drops usage of periods before the
+// current one from it. It does nothing if rotation is nil.
+func (this *QuotaManager) finish(rotation *quotaRotation) {
+	if rotation == nil {
+		return
+	}
+	this.add(rotation.previous, rotation.pending)
+	if store, ok := this.store.(expiringQuotaStore); ok {
+		if err := store.Expire(rotation.current); err != nil {
+			log.Warning("Shadowsocks|Quota: Failed to expire usage before ", rotation.current, ": ", err)
+		}
+	}
+}
+
+// add adds bytes of users to the store in period.
+func (this *QuotaManager) add(period string, pending map[string]int64) {
+	for user, bytes := range pending {
+		if _, err := this.store.Add(user, period, bytes); err != nil {
+			log.Warning("Shadowsocks|Quota: Failed to add usage of ", user, ": ", err)
+		}
+	}
+}
+
+// Flush adds all pending bytes to the store, e.g., before the server closes.
+func (this *QuotaManager) Flush() {
+	if this == nil || this.monthlyBytes == 0 {
+		return
+	}
+	this.Lock()
+	period, rotation := this.rotate()
+	pending := this.pending
+	this.pending = make(map[string]int64)
+	this.Unlock()
+
+	this.finish(rotation)
+	this.add(period, pending)
+}
+
+func (this *QuotaManager) emit(user string, reason error, usage int64) {
+	log.Warning("Shadowsocks|Quota: User ", user, " is rejected: ", reason)
+	if this.listener != nil {
+		this.listener(QuotaEvent{
+			User:   user,
+			Reason: reason,
+			Usage:  usage,
+		})
+	}
+}
+
+// Acquire takes a connection slot of user, if the user is within limits. The returned function must be
+// called once the connection is closed.
+func (this *QuotaManager) Acquire(user string) (func(), error) {
+	if this == nil {
+		return func() {}, nil
+	}
+	if err := this.Check(user); err != nil {
+		return nil, err
+	}
+
+	this.Lock()
+	if this.maxConnections > 0 && this.connections[user] >= this.maxConnections {
+		count := this.connections[user]
+		this.Unlock()
+		this.emit(user, ErrTooManyConnections, int64(count))
+		return nil, ErrTooManyConnections
+	}
+	this.connections[user]++
+	this.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			this.Lock()
+			defer this.Unlock()
+
+			this.connections[user]--
+			if this.connections[user] <= 0 {
+				delete(this.connections, user)
+			}
+		})
+	}, nil
+}
+
+// Check returns ErrDataQuotaExceeded if user has used up the data quota of this month.
+func (this *QuotaManager) Check(user string) error {
+	if this == nil || this.monthlyBytes == 0 {
+		return nil
+	}
+	this.Lock()
+	period, rotation := this.rotate()
+	pending := this.pending[user]
+	this.Unlock()
+	this.finish(rotation)
+
+	usage, err := this.store.Get(user, period)
+	if err != nil {
+		// Usage is unknown, so the user is not blocked.
+		log.Warning("Shadowsocks|Quota: Failed to get usage of ", user, ": ", err)
+		return nil
+	}
+	if usage+pending >= this.monthlyBytes {
+		this.emit(user, ErrDataQuotaExceeded, usage+pending)
+		return ErrDataQuotaExceeded
+	}
+	return nil
+}
+
+// Consume adds bytes to the usage of user, and returns ErrDataQuotaExceeded if the quota is used up. An
+// event is emitted only when the usage crosses the quota. Bytes are added to the store in batches, so a
+// user may go over the quota by up to quotaFlushBytes on each server before being cut.
+func (this *QuotaManager) Consume(user string, bytes int) error {
+	if this == nil || this.monthlyBytes == 0 || bytes == 0 {
+		return nil
+	}
+	this.Lock()
+	period, rotation := this.rotate()
+	if this.usage[user] > this.monthlyBytes {
+		// Usage only grows in a period, so the store is not asked again.
+		this.Unlock()
+		this.finish(rotation)
+		return ErrDataQuotaExceeded
+	}
+	now := this.now()
+	pending := this.pending[user] + int64(bytes)
+	if pending < quotaFlushBytes && this.usage[user]+pending <= this.monthlyBytes && now.Sub(this.flushedAt[user]) < quotaFlushInterval {
+		this.pending[user] = pending
+		this.Unlock()
+		this.finish(rotation)
+		return nil
+	}
+	delete(this.pending, user)
+	this.flushedAt[user] = now
+	this.Unlock()
+	this.finish(rotation)
+
+	usage, err := this.store.Add(user, period, pending)
+	if err != nil {
+		log.Warning("Shadowsocks|Quota: Failed to add usage of ", user, ": ", err)
+		return nil
+	}
+	this.Lock()
+	if period == this.currentPeriod && usage > this.usage[user] {
+		this.usage[user] = usage
+	}
+	this.Unlock()
+	if usage > this.monthlyBytes {
+		if usage-pending <= this.monthlyBytes {
+			this.emit(user, ErrDataQuotaExceeded, usage)
+		}
+		return ErrDataQuotaExceeded
+	}
+	return nil
+}
+
+// quotaReader counts payload read from Reader to the quota of user, and fails once the quota is used up.
+type quotaReader struct {
+	v2io.Reader
+	quota *QuotaManager
+	user  string
+}
+
+func (this *quotaReader) Read() (*alloc.Buffer, error) {
+	buffer, err := this.Reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if err := this.quota.Consume(this.user, buffer.Len()); err != nil {
+		buffer.Release()
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// quotaWriter counts payload written to Writer to the quota of user, and fails once the quota is used up.
+type quotaWriter struct {
+	v2io.Writer
+	quota *QuotaManager
+	user  string
+}
+
+func (this *quotaWriter) Write(buffer *alloc.Buffer) error {
+	if err := this.quota.Consume(this.user, buffer.Len()); err != nil {
+		return err
+	}
+	return this.Writer.Write(buffer)
+}
@@ -0,0 +1,187 @@
+package shadowsocks_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestQuotaConnections(t *testing.T) {
+	assert := assert.On(t)
+
+	quota := NewQuotaManager(&QuotaConfig{MaxConnections: 2})
+	var events []QuotaEvent
+	quota.SetListener(func(event QuotaEvent) {
+		events = append(events, event)
+	})
+
+	release1, err := quota.Acquire("a@v2ray.com")
+	assert.Error(err).IsNil()
+	release2, err := quota.Acquire("a@v2ray.com")
+	assert.Error(err).IsNil()
+	_, err = quota.Acquire("a@v2ray.com")
+	assert.Error(err).Equals(ErrTooManyConnections)
+	assert.Int(len(events)).Equals(1)
+	assert.String(events[0].User).Equals("a@v2ray.com")
+
+	// Limits are per user.
+	release3, err := quota.Acquire("b@v2ray.com")
+	assert.Error(err).IsNil()
+	release3()
+
+	release1()
+	release1()
+	release4, err := quota.Acquire("a@v2ray.com")
+	assert.Error(err).IsNil()
+	_, err = quota.Acquire("a@v2ray.com")
+	assert.Error(err).Equals(ErrTooManyConnections)
+	release2()
+	release4()
+}
+
+func TestQuotaData(t *testing.T) {
+	assert := assert.On(t)
+
+	quota := NewQuotaManager(&QuotaConfig{MonthlyBytes: 100})
+	store := NewMemoryQuotaStore()
+	quota.SetStore(store)
+	var events []QuotaEvent
+	quota.SetListener(func(event QuotaEvent) {
+		events = append(events, event)
+	})
+
+	assert.Error(quota.Consume("a@v2ray.com", 60)).IsNil()
+	assert.Error(quota.Consume("a@v2ray.com", 40)).IsNil()
+	assert.Int(len(events)).Equals(0)
+	assert.Error(quota.Consume("a@v2ray.com", 1)).Equals(ErrDataQuotaExceeded)
+	assert.Error(quota.Consume("a@v2ray.com", 1)).Equals(ErrDataQuotaExceeded)
+	// Only crossing the quota is an event.
+	assert.Int(len(events)).Equals(1)
+	assert.Error(events[0].Reason).Equals(ErrDataQuotaExceeded)
+	assert.Int64(events[0].Usage).Equals(101)
+
+	_, err := quota.Acquire("a@v2ray.com")
+	assert.Error(err).Equals(ErrDataQuotaExceeded)
+	assert.Int(len(events)).Equals(2)
+
+	release, err := quota.Acquire("b@v2ray.com")
+	assert.Error(err).IsNil()
+	release()
+}
+
+func TestNilQuota(t *testing.T) {
+	assert := assert.On(t)
+
+	quota := NewQuotaManager(&QuotaConfig{})
+	assert.Pointer(quota).IsNil()
+
+	release, err := quota.Acquire("a@v2ray.com")
+	assert.Error(err).IsNil()
+	release()
+	assert.Error(quota.Consume("a@v2ray.com", 1<<40)).IsNil()
+}
+
+// countingQuotaStore counts additions to its usage, and records periods it expires.
+type countingQuotaStore struct {
+	*MemoryQuotaStore
+	adds    int
+	expired []string
+}
+
+func (this *countingQuotaStore) Add(user string, period string, bytes int64) (int64, error) {
+	this.adds++
+	return this.MemoryQuotaStore.Add(user, period, bytes)
+}
+
+func (this *countingQuotaStore) Expire(period string) error {
+	this.expired = append(this.expired, period)
+	return this.MemoryQuotaStore.Expire(period)
+}
+
+func TestQuotaBatchesStore(t *testing.T) {
+	assert := assert.On(t)
+
+	quota := NewQuotaManager(&QuotaConfig{MonthlyBytes: 1 << 30})
+	store := &countingQuotaStore{MemoryQuotaStore: NewMemoryQuotaStore()}
+	quota.SetStore(store)
+	period := time.Now().UTC().Format("2006-01")
+
+	for i := 0; i < 100; i++ {
+		assert.Error(quota.Consume("a@v2ray.com", 1000)).IsNil()
+	}
+	// The first bytes are added at once, and the rest wait for more.
+	assert.Int(store.adds).Equals(1)
+	usage, err := store.Get("a@v2ray.com", period)
+	assert.Error(err).IsNil()
+	assert.Int64(usage).Equals(1000)
+
+	quota.Flush()
+	assert.Int(store.adds).Equals(2)
+	usage, err = store.Get("a@v2ray.com", period)
+	assert.Error(err).IsNil()
+	assert.Int64(usage).Equals(100000)
+
+	// Periods before the current one are dropped from the store.
+	assert.Int(len(store.expired)).Equals(1)
+	assert.String(store.expired[0]).Equals(period)
+}
+
+func TestMemoryQuotaStoreExpire(t *testing.T) {
+	assert := assert.On(t)
+
+	store := NewMemoryQuotaStore()
+	store.Add("a@v2ray.com", "2026-09", 100)
+	store.Add("a@v2ray.com", "2026-10", 200)
+	assert.Error(store.Expire("2026-10")).IsNil()
+
+	usage, _ := store.Get("a@v2ray.com", "2026-09")
+	assert.Int64(usage).Equals(0)
+	usage, _ = store.Get("a@v2ray.com", "2026-10")
+	assert.Int64(usage).Equals(200)
+}
+
+func TestQuotaCountsPayloadOnly(t *testing.T) {
+	assert := assert.On(t)
+
+	sessions := &sessionDispatcher{
+		destinations: make(chan v2net.Destination, 1),
+	}
+	server, port := startServer(assert, &ServerConfig{
+		User:  newTestUser(),
+		Quota: &QuotaConfig{MonthlyBytes: 1 << 20},
+	}, sessions)
+	defer server.Close()
+	store := NewMemoryQuotaStore()
+	server.Quota().SetStore(store)
+
+	conn, err := net.Dial("tcp", (&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(port)}).String())
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	writer, err := WriteTCPRequest(&protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: testDestination.Address,
+		Port:    testDestination.Port,
+		User:    newTestUser(),
+	}, conn)
+	assert.Error(err).IsNil()
+	assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("payload"))).IsNil()
+	writer.Release()
+	<-sessions.destinations
+
+	// The IV and the request header are not charged to the user.
+	period := time.Now().UTC().Format("2006-01")
+	deadline := time.Now().Add(5 * time.Second)
+	usage, _ := store.Get("", period)
+	for usage == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		usage, _ = store.Get("", period)
+	}
+	assert.Int64(usage).Equals(int64(len("payload")))
+}
@@ -0,0 +1,207 @@
+package shadowsocks
+
+import (
+	"errors"
+	"strconv"
+
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
+)
+
+const (
+	// Maximum number of servers a request is sent to.
+	maxRedundantCopies = 3
+)
+
+// AppliesTo returns true if requests to destination should be sent to multiple servers.
+func (this *RedundancyConfig) AppliesTo(destination v2net.Destination) bool {
+	if this == nil || this.Copies < 2 || destination.Network != v2net.Network_UDP {
+		return false
+	}
+	if len(this.Port) == 0 {
+		return destination.Port == v2net.Port(53)
+	}
+	for _, port := range this.Port {
+		if destination.Port == v2net.Port(port) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *RedundancyConfig) GetEffectiveCopies() int {
+	if this.Copies > maxRedundantCopies {
+		return maxRedundantCopies
+	}
+	return int(this.Copies)
+}
+
+// redundantSession is a UDP session to one of the servers that a request is sent to.
+type redundantSession struct {
+	server      *protocol.ServerSpec
+	conn        *countingConn
+	timedReader *v2net.TimeOutReader
+	reader      *UDPReader
+	writer      *UDPWriter
+}
+
+type redundantResponse struct {
+	session *redundantSession
+	payload *alloc.Buffer
+	err     error
+}
+
+// pickServers picks up to count servers with distinct destinations.
+func (this *Client) pickServers(count int) []*protocol.ServerSpec {
+	servers := make([]*protocol.ServerSpec, 0, count)
+	picked := make(map[string]bool)
+	for i := 0; i < count*2 && len(servers) < count; i++ {
+		server := this.serverPicker.PickServer()
+		if server == nil {
+			break
+		}
+		dest := server.Destination().NetAddr()
+		if picked[dest] {
+			continue
+		}
+		picked[dest] = true
+		servers = append(servers, server)
+	}
+	return servers
+}
+
+// dispatchRedundant sends the payload to multiple servers, and continues the session with the server
+// that responds first. Connections to other servers are closed once the first response arrives.
+func (this *Client) dispatchRedundant(destination v2net.Destination, chain internet.OutboundChain, payload *alloc.Buffer, ray ray.OutboundRay, logger *dispatchLogger) (*countingConn, error) {
+	servers := this.pickServers(this.redundancy.GetEffectiveCopies())
+	if len(servers) == 0 {
+		return nil, errors.New("Shadowsocks|Client: Failed to find an available destination:" + protocol.ErrNoServerAvailable.Error())
+	}
+
+	sessions := make([]*redundantSession, 0, len(servers))
+	release := this.dialLimiter.Acquire(destination)
+	for _, server := range servers {
+		session, err := this.newRedundantSession(destination, server, chain, logger.tags)
+		if err != nil {
+			log.Warning("Shadowsocks|Client: Failed to send request to ", server.Destination(), ": ", err)
+			continue
+		}
+		defer session.conn.Close()
+
+		if err := session.writer.Write(payload); err != nil {
+			log.Warning("Shadowsocks|Client: Failed to send request to ", server.Destination(), ": ", err)
+			session.conn.Close()
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	release()
+	if len(sessions) == 0 {
+		return nil, errors.New("Shadowsocks|Client: Failed to send request to any server.")
+	}
+	logger.OnStart(sessions[0].server.Destination())
+
+	responses := make(chan *redundantResponse, len(sessions))
+	for _, session := range sessions {
+		go func(session *redundantSession) {
+			payload, err := session.reader.Read()
+			responses <- &redundantResponse{
+				session: session,
+				payload: payload,
+				err:     err,
+			}
+		}(session)
+	}
+
+	var winner *redundantResponse
+	received := 0
+	for received < len(sessions) {
+		response := <-responses
+		received++
+		if response.err == nil {
+			winner = response
+			break
+		}
+	}
+	if winner == nil {
+		return nil, errors.New("Shadowsocks|Client: No response from " + strconv.Itoa(len(sessions)) + " servers.")
+	}
+
+	if len(sessions) > 1 {
+		log.Debug("Shadowsocks|Client: Using response from ", winner.session.server.Destination(), " for ", destination)
+		for _, session := range sessions {
+			if session != winner.session {
+				session.conn.Close()
+			}
+		}
+		go func(remaining int) {
+			for i := 0; i < remaining; i++ {
+				if response := <-responses; response.payload != nil {
+					response.payload.Release()
+				}
+			}
+		}(len(sessions) - received)
+	}
+
+	// Further requests in this session only go to the winner.
+	session := winner.session
+	return session.conn, this.transfer(session.timedReader, session.writer, ray, func() error {
+		if err := ray.OutboundOutput().Write(winner.payload); err != nil {
+			winner.payload.Release()
+			return nil
+		}
+		v2io.Pipe(session.reader, ray.OutboundOutput())
+		return nil
+	}, nil)
+}
+
+func (this *Client) newRedundantSession(destination v2net.Destination, server *protocol.ServerSpec, chain internet.OutboundChain, tags map[string]string) (*redundantSession, error) {
+	breaker := server.CircuitBreaker()
+	if !breaker.Allow() {
+		return nil, protocol.ErrCircuitOpen
+	}
+	dest := server.Destination()
+	dest.Network = v2net.Network_UDP
+	options := this.meta.GetDialerOptions()
+	options.Chain = chain
+	conn, err := internet.Dial(this.meta.Address, dest, options)
+	if err != nil {
+		breaker.OnFailure()
+		return nil, err
+	}
+	breaker.OnSuccess()
+	conn.SetReusable(false)
+
+	request, _, err := newRequest(destination, server)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	counter := newCountingConn(conn, server.Destination(), this.counters)
+	counter.countTags(this.tagCounters, tags)
+	monitored := &udpMonitoredConn{
+		Connection: this.shaper.Wrap(counter, v2net.Network_UDP),
+		monitor:    NewUDPSizeMonitor(server.Destination()),
+	}
+	timedReader := v2net.NewTimeOutReader(udpTimeout, monitored)
+	return &redundantSession{
+		server:      server,
+		conn:        counter,
+		timedReader: timedReader,
+		reader: &UDPReader{
+			Reader:    timedReader,
+			User:      request.User,
+			Malformed: this.counters.Get(server.Destination().NetAddr() + ">>>malformed"),
+		},
+		writer: &UDPWriter{
+			Writer:  monitored,
+			Request: request,
+		},
+	}, nil
+}
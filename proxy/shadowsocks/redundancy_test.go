@@ -0,0 +1,113 @@
+package shadowsocks_test
+
+import (
+	"net"
+	"testing"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	_ "v2ray.com/core/transport/internet/udp"
+	"v2ray.com/core/transport/ray"
+)
+
+// startUDPTestServer receives Shadowsocks UDP requests, and responds to them if respond is true.
+func startUDPTestServer(assert *assert.Assert, respond bool, received chan string) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	go func() {
+		for {
+			buffer := alloc.NewLocalBuffer(2048)
+			nBytes, addr, err := conn.ReadFromUDP(buffer.Value)
+			if err != nil {
+				return
+			}
+			buffer.Slice(0, nBytes)
+			request, payload, err := DecodeUDPPacket(newTestUser(), buffer)
+			assert.Error(err).IsNil()
+			request.User = newTestUser()
+			received <- payload.String()
+			if !respond {
+				continue
+			}
+			response, err := EncodeUDPPacket(request, alloc.NewLocalBuffer(2048).Clear().AppendString("response"))
+			assert.Error(err).IsNil()
+			conn.WriteToUDP(response.Value, addr)
+		}
+	}()
+	return conn
+}
+
+func TestRedundantUDPRequest(t *testing.T) {
+	assert := assert.On(t)
+
+	received := make(chan string, 4)
+	silentServer := startUDPTestServer(assert, false, received)
+	defer silentServer.Close()
+	server := startUDPTestServer(assert, true, received)
+	defer server.Close()
+
+	var servers []*protocol.ServerEndpoint
+	for _, conn := range []*net.UDPConn{silentServer, server} {
+		servers = append(servers, &protocol.ServerEndpoint{
+			Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+			Port:    uint32(conn.LocalAddr().(*net.UDPAddr).Port),
+			User:    []*protocol.User{newTestUser()},
+		})
+	}
+	space := app.NewSpace()
+	client, err := NewClient(&ClientConfig{
+		Server: servers,
+		Redundancy: &RedundancyConfig{
+			Copies: 2,
+		},
+	}, space, &proxy.OutboundHandlerMeta{
+		Address: v2net.AnyIP,
+		StreamSettings: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+	})
+	assert.Error(err).IsNil()
+	assert.Error(space.Initialize()).IsNil()
+
+	traffic := ray.NewRay()
+	destination := v2net.UDPDestination(v2net.LocalHostIP, v2net.Port(53))
+	go client.Dispatch(destination, alloc.NewLocalBuffer(2048).Clear().AppendString("request"), traffic)
+
+	// Both servers get the request.
+	assert.String(<-received).Equals("request")
+	assert.String(<-received).Equals("request")
+
+	response, err := traffic.InboundOutput().Read()
+	assert.Error(err).IsNil()
+	assert.String(response.String()).Equals("response")
+
+	// Further requests only go to the server that responded.
+	assert.Error(traffic.InboundInput().Write(alloc.NewLocalBuffer(2048).Clear().AppendString("second"))).IsNil()
+	assert.String(<-received).Equals("second")
+	response, err = traffic.InboundOutput().Read()
+	assert.Error(err).IsNil()
+	assert.String(response.String()).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Int(len(received)).Equals(0)
+}
+
+func TestRedundancyAppliesTo(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &RedundancyConfig{Copies: 2}
+	assert.Bool(config.AppliesTo(v2net.UDPDestination(v2net.LocalHostIP, v2net.Port(53)))).IsTrue()
+	assert.Bool(config.AppliesTo(v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(53)))).IsFalse()
+	assert.Bool(config.AppliesTo(v2net.UDPDestination(v2net.LocalHostIP, v2net.Port(443)))).IsFalse()
+
+	config.Port = []uint32{443}
+	assert.Bool(config.AppliesTo(v2net.UDPDestination(v2net.LocalHostIP, v2net.Port(443)))).IsTrue()
+
+	config.Copies = 1
+	assert.Bool(config.AppliesTo(v2net.UDPDestination(v2net.LocalHostIP, v2net.Port(443)))).IsFalse()
+}
@@ -0,0 +1,147 @@
+package shadowsocks
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+)
+
+const (
+	defaultMaxTrailingData = 4096
+)
+
+var (
+	ErrTrailingData = errors.New("Shadowsocks|TCP: Too much data after response is finished.")
+)
+
+// ResponseError is returned when a TCP response from server ends abnormally, i.e., not with a clean EOF.
+type ResponseError struct {
+	// Number of payload bytes received before the error.
+	Received int64
+	// True if the connection is reset by server.
+	Reset bool
+	Err   error
+}
+
+func (this *ResponseError) Error() string {
+	if this.Reset {
+		return "Shadowsocks|TCP: Connection reset by server after " + strconv.FormatInt(this.Received, 10) + " bytes of response: " + this.Err.Error()
+	}
+	return "Shadowsocks|TCP: Response terminated after " + strconv.FormatInt(this.Received, 10) + " bytes: " + this.Err.Error()
+}
+
+// IsConnectionReset returns true if err is caused by a connection reset by peer.
+func IsConnectionReset(err error) bool {
+	if responseErr, ok := err.(*ResponseError); ok {
+		return responseErr.Reset
+	}
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if syscallErr, ok := err.(*os.SyscallError); ok {
+		err = syscallErr.Err
+	}
+	return err == syscall.ECONNRESET
+}
+
+// ResponseReader reads TCP response payload, and turns any error other than io.EOF into a ResponseError.
+// Once the response is finished, i.e., the client no longer takes it, data that the server sends is
+// trailing data. It is discarded, until the server closes the connection or the read fails, e.g., by a
+// deadline. The response fails with a ResponseError once there are more than the maximum bytes of it.
+type ResponseReader struct {
+	reader   v2io.Reader
+	received int64
+	// Set by Finish, which is called on the goroutine that reads.
+	finished    bool
+	trailing    int
+	maxTrailing int
+}
+
+func NewResponseReader(reader v2io.Reader) *ResponseReader {
+	return &ResponseReader{
+		reader:      reader,
+		maxTrailing: defaultMaxTrailingData,
+	}
+}
+
+// SetMaxTrailingData sets bytes of trailing data discarded. Any trailing data is an error if it is 0.
+func (this *ResponseReader) SetMaxTrailingData(size int) {
+	this.maxTrailing = size
+}
+
+// Finish finishes the response, so that further reads return io.EOF once the server closes, or
+// ResponseError with ErrTrailingData if it sends too much data before that.
+func (this *ResponseReader) Finish() {
+	this.finished = true
+}
+
+// discard counts buffer that is read but not taken by the client as trailing data.
+func (this *ResponseReader) discard(buffer *alloc.Buffer) {
+	this.received -= int64(buffer.Len())
+	this.trailing += buffer.Len()
+	buffer.Release()
+}
+
+func (this *ResponseReader) Read() (*alloc.Buffer, error) {
+	for {
+		if this.finished && this.trailing > this.maxTrailing {
+			return nil, &ResponseError{
+				Received: this.received,
+				Err:      ErrTrailingData,
+			}
+		}
+		buffer, err := this.reader.Read()
+		if err != nil {
+			if err == io.EOF || this.finished {
+				// The response is complete anyway, if the read fails after it is finished.
+				return nil, io.EOF
+			}
+			return nil, &ResponseError{
+				Received: this.received,
+				Reset:    IsConnectionReset(err),
+				Err:      err,
+			}
+		}
+		if !this.finished {
+			this.received += int64(buffer.Len())
+			return buffer, nil
+		}
+		this.trailing += buffer.Len()
+		buffer.Release()
+	}
+}
+
+func (this *ResponseReader) Release() {
+	this.reader.Release()
+}
+
+// finishingWriter writes the response of reader to writer. Once a write fails, the response is finished,
+// and onFinish is called. The rest of it is discarded as trailing data, including the failed write.
+type finishingWriter struct {
+	reader   *ResponseReader
+	writer   v2io.Writer
+	onFinish func()
+}
+
+func (this *finishingWriter) Write(buffer *alloc.Buffer) error {
+	if !this.reader.finished {
+		if err := this.writer.Write(buffer); err == nil {
+			return nil
+		}
+		this.reader.Finish()
+		this.onFinish()
+	}
+	this.reader.discard(buffer)
+	return nil
+}
+
+// Release implements Releasable.Release(). The underlying writer is released by its owner.
+func (this *finishingWriter) Release() {
+	this.writer = nil
+}
@@ -0,0 +1,133 @@
+package shadowsocks
+
+import (
+	"errors"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
+)
+
+const (
+	// Maximum number of servers a request is sent to, after the server doesn't start responding.
+	maxFailoverAttempts = 5
+)
+
+var (
+	ErrResponseTimeout = errors.New("Shadowsocks|Client: Server did not start responding in time.")
+)
+
+// AppliesTo returns true if TCP requests to destination may be sent to another server, if the server
+// doesn't start responding in time.
+func (this *ResponseFailoverConfig) AppliesTo(destination v2net.Destination) bool {
+	if this == nil || this.Timeout == 0 || destination.Network != v2net.Network_TCP {
+		return false
+	}
+	for _, port := range this.Port {
+		if destination.Port == v2net.Port(port) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *ResponseFailoverConfig) GetEffectiveTimeout() time.Duration {
+	return time.Duration(this.Timeout) * time.Millisecond
+}
+
+func (this *ResponseFailoverConfig) GetEffectiveMaxAttempts() int {
+	if this.MaxAttempts == 0 {
+		return 2
+	}
+	if this.MaxAttempts > maxFailoverAttempts {
+		return maxFailoverAttempts
+	}
+	return int(this.MaxAttempts)
+}
+
+// failoverAttempt is the state of a request that is sent to another server, if the server doesn't start
+// responding in time.
+type failoverAttempt struct {
+	// Time to wait for the response to start. The last attempt waits as long as other requests, so it is 0.
+	timeout time.Duration
+	// Servers that didn't start responding, by their addresses.
+	excluded map[string]bool
+	// Whether the server of the current attempt didn't start responding.
+	failed bool
+}
+
+// avoid returns server, or another server if server is excluded. It returns nil if all servers are
+// excluded.
+func (this *failoverAttempt) avoid(server *protocol.ServerSpec, picker protocol.ServerPicker) *protocol.ServerSpec {
+	for i := 0; i < len(this.excluded)*2+1; i++ {
+		if server == nil || !this.excluded[server.Destination().NetAddr()] {
+			return server
+		}
+		server = picker.PickServer()
+	}
+	return nil
+}
+
+func (this *failoverAttempt) onFailure(server *protocol.ServerSpec) {
+	this.failed = true
+	this.excluded[server.Destination().NetAddr()] = true
+}
+
+// waitForResponseStart waits up to timeout for the first byte of a response on conn. Any error is
+// returned before the response starts, including the server closing the connection.
+func waitForResponseStart(conn internet.Connection, timeout time.Duration) ([]byte, error) {
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	first := make([]byte, 1)
+	if _, err := conn.Read(first); err != nil {
+		if netErr, ok := err.(interface {
+			Timeout() bool
+		}); ok && netErr.Timeout() {
+			return nil, ErrResponseTimeout
+		}
+		return nil, err
+	}
+	return first, nil
+}
+
+// dispatchWithFailover sends the request to another server, each time the server doesn't start
+// responding in time. The first payload is kept for the next server, and nothing else is sent before a
+// server starts responding.
+func (this *Client) dispatchWithFailover(session *proxy.SessionInfo, payload *alloc.Buffer, ray ray.OutboundRay, logger *dispatchLogger) (*countingConn, error) {
+	if payload.IsEmpty() {
+		firstPayload, err := waitForPayload(ray.OutboundInput(), this.failover.GetEffectiveTimeout())
+		if err != nil {
+			log.Info("Shadowsocks|Client: Request to ", session.Destination, " closed before sending any data.")
+			return nil, nil
+		}
+		if firstPayload == nil {
+			// Without a request to send again, the request is dispatched as usual.
+			return this.dispatch(session, payload, ray, logger, nil, nil)
+		}
+		defer firstPayload.Release()
+		payload = firstPayload
+	}
+
+	attempt := &failoverAttempt{
+		timeout:  this.failover.GetEffectiveTimeout(),
+		excluded: make(map[string]bool),
+	}
+	maxAttempts := this.failover.GetEffectiveMaxAttempts()
+	for i := 1; ; i++ {
+		if i == maxAttempts {
+			attempt.timeout = 0
+		}
+		attempt.failed = false
+		conn, err := this.dispatch(session, payload, ray, logger, attempt, nil)
+		if !attempt.failed {
+			return conn, err
+		}
+		log.Info("Shadowsocks|Client: Sending request to ", session.Destination, " through another server: ", err)
+	}
+}
@@ -0,0 +1,83 @@
+package shadowsocks_test
+
+import (
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
+)
+
+func TestResponseFailoverAppliesTo(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &ResponseFailoverConfig{
+		Timeout: 100,
+		Port:    []uint32{80},
+	}
+	assert.Bool(config.AppliesTo(v2net.TCPDestination(v2net.LocalHostIP, 80))).IsTrue()
+	assert.Bool(config.AppliesTo(v2net.TCPDestination(v2net.LocalHostIP, 443))).IsFalse()
+	assert.Bool(config.AppliesTo(v2net.UDPDestination(v2net.LocalHostIP, 80))).IsFalse()
+	assert.Bool((&ResponseFailoverConfig{Port: []uint32{80}}).AppliesTo(v2net.TCPDestination(v2net.LocalHostIP, 80))).IsFalse()
+	assert.Bool((*ResponseFailoverConfig)(nil).AppliesTo(v2net.TCPDestination(v2net.LocalHostIP, 80))).IsFalse()
+	assert.Int(config.GetEffectiveMaxAttempts()).Equals(2)
+}
+
+func TestClientResponseFailover(t *testing.T) {
+	assert := assert.On(t)
+
+	// The first server receives the request but never responds.
+	stalled, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer stalled.Close()
+	received := make(chan int64, 1)
+	go func() {
+		conn, err := stalled.Accept()
+		if err != nil {
+			return
+		}
+		nBytes, _ := ioutil.ReadAll(conn)
+		received <- int64(len(nBytes))
+		conn.Close()
+	}()
+
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readAll(assert, reader, 7)).Equals("request")
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+	})
+	defer server.Close()
+
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(stalled.Addr().(*net.TCPAddr).Port),
+				User:    []*protocol.User{newTestUser()},
+			},
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(server.Port()),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		ResponseFailover: &ResponseFailoverConfig{
+			Timeout: 200,
+			Port:    []uint32{80},
+		},
+	})
+
+	traffic := ray.NewRay()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+
+	// The stalled server got the request before the failover.
+	assert.Bool(<-received > 0).IsTrue()
+}
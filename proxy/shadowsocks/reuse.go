@@ -0,0 +1,151 @@
+package shadowsocks
+
+import (
+	"errors"
+	"io"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/common/serial"
+)
+
+// A TCP connection with connection reuse carries sequential requests. After the IV and the first request
+// header, data in each direction is sent in frames of a 2-byte length and the data, and a frame of length
+// 0 ends the request or the response. Once both ends are received, the client may send the header of
+// the next request on the same encrypted stream, without a new IV. Requests with connection reuse are
+// marked by bit 0x40 of the address type, and can't have one-time auth or compression.
+
+const (
+	// Maximum size of data in a frame.
+	maxFrameSize = alloc.BufferSize - 2
+
+	// Seconds that servers wait for the next request on a reused connection.
+	reuseIdleTimeout = 60
+)
+
+var (
+	ErrReuseNotSupported = errors.New("Shadowsocks|TCP: Connection reuse doesn't support one-time auth or compression.")
+	ErrReuseDisabled     = errors.New("Shadowsocks|Server: Connection reuse is not enabled.")
+	ErrFrameTooLarge     = errors.New("Shadowsocks|TCP: Frame too large.")
+)
+
+// FrameWriter writes data of a request or a response on a reusable connection in frames.
+type FrameWriter struct {
+	writer io.Writer
+}
+
+func NewFrameWriter(writer io.Writer) *FrameWriter {
+	return &FrameWriter{
+		writer: writer,
+	}
+}
+
+func (this *FrameWriter) Write(buffer *alloc.Buffer) error {
+	defer buffer.Release()
+
+	for !buffer.IsEmpty() {
+		size := buffer.Len()
+		if size > maxFrameSize {
+			size = maxFrameSize
+		}
+		frame := alloc.NewBuffer().Clear().AppendUint16(uint16(size)).Append(buffer.Value[:size])
+		_, err := this.writer.Write(frame.Value)
+		frame.Release()
+		if err != nil {
+			return err
+		}
+		buffer.SliceFrom(size)
+	}
+	return nil
+}
+
+// End writes the frame that ends the request or the response.
+func (this *FrameWriter) End() error {
+	_, err := this.writer.Write([]byte{0, 0})
+	return err
+}
+
+// WriteHeader writes the header of the next request on the connection.
+func (this *FrameWriter) WriteHeader(request *protocol.RequestHeader) error {
+	header, err := encodeTCPHeader(request)
+	if err != nil {
+		return err
+	}
+	if _, err := this.writer.Write(header.Value); err != nil {
+		return errors.New("Shadowsocks|TCP: Failed to write header: " + err.Error())
+	}
+	return nil
+}
+
+func (this *FrameWriter) Release() {}
+
+// FrameReader reads data of a request or a response on a reusable connection from frames. It returns
+// io.EOF after the frame that ends the data, until Next or ReadNextTCPSession is called.
+type FrameReader struct {
+	reader io.Reader
+	ended  bool
+}
+
+func NewFrameReader(reader io.Reader) *FrameReader {
+	return &FrameReader{
+		reader: reader,
+	}
+}
+
+func (this *FrameReader) Read() (*alloc.Buffer, error) {
+	if this.ended {
+		return nil, io.EOF
+	}
+	var header [2]byte
+	if _, err := io.ReadFull(this.reader, header[:]); err != nil {
+		return nil, err
+	}
+	size := int(serial.BytesToUint16(header[:]))
+	if size == 0 {
+		this.ended = true
+		return nil, io.EOF
+	}
+	if size > maxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+	buffer := alloc.NewBuffer().Clear()
+	buffer.Value = buffer.Value[:size]
+	if _, err := io.ReadFull(this.reader, buffer.Value); err != nil {
+		buffer.Release()
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// Ended returns true if the frame that ends the data has been read.
+func (this *FrameReader) Ended() bool {
+	return this.ended
+}
+
+// Next starts reading data of the next response on the connection.
+func (this *FrameReader) Next() {
+	this.ended = false
+}
+
+func (this *FrameReader) Release() {}
+
+// ReadNextTCPSession reads the header of the next request on a reusable connection, after the data of
+// the previous request has ended in reader.
+func ReadNextTCPSession(user *protocol.User, reader *FrameReader, maxDomainLength int) (*protocol.RequestHeader, error) {
+	buffer := alloc.NewLocalBuffer(512)
+	defer buffer.Release()
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		User:    user,
+		Command: protocol.RequestCommandTCP,
+	}
+	if _, err := readTCPHeader(reader.reader, request, buffer, maxDomainLength); err != nil {
+		return nil, err
+	}
+	if !request.Option.Has(protocol.RequestOptionConnectionReuse) || request.Option.Has(RequestOptionOneTimeAuth) || request.Option.Has(RequestOptionCompression) {
+		return nil, ErrReuseNotSupported
+	}
+	reader.Next()
+	return request, nil
+}
@@ -0,0 +1,281 @@
+package shadowsocks
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
+)
+
+const (
+	defaultReuseIdleTimeout = 30 * time.Second
+	// Below the idle timeout of servers, so that clients don't send requests on connections being closed.
+	maxReuseIdleTimeout = 50 * time.Second
+	defaultReuseMaxIdle = 4
+)
+
+func (this *ConnectionReuseConfig) GetEffectiveIdleTimeout() time.Duration {
+	if this.IdleTimeout == 0 {
+		return defaultReuseIdleTimeout
+	}
+	timeout := time.Duration(this.IdleTimeout) * time.Second
+	if timeout > maxReuseIdleTimeout {
+		return maxReuseIdleTimeout
+	}
+	return timeout
+}
+
+func (this *ConnectionReuseConfig) GetEffectiveMaxIdle() int {
+	if this.MaxIdle == 0 {
+		return defaultReuseMaxIdle
+	}
+	return int(this.MaxIdle)
+}
+
+// reusedSession is an idle TCP connection to a server, whose last request and response have ended.
+type reusedSession struct {
+	conn           internet.Connection
+	counter        *countingConn
+	user           *protocol.User
+	bufferedWriter *v2io.BufferedWriter
+	writer         *FrameWriter
+	timedReader    *v2net.TimeOutReader
+	reader         *FrameReader
+	created        time.Time
+	idleSince      time.Time
+}
+
+// alive returns true if the connection is neither closed by the server, nor has anything unexpected to
+// read.
+func (this *reusedSession) alive() bool {
+	this.counter.SetReadDeadline(time.Now().Add(time.Millisecond))
+	defer this.counter.SetReadDeadline(time.Time{})
+
+	var b [1]byte
+	_, err := this.counter.Read(b[:])
+	if netErr, ok := err.(interface {
+		Timeout() bool
+	}); ok && netErr.Timeout() {
+		return true
+	}
+	return false
+}
+
+func (this *reusedSession) Close() {
+	this.counter.Close()
+	this.bufferedWriter.Release()
+}
+
+// since returns a countingConn with the bytes counted after sent and received.
+func (this *countingConn) since(sent, received int64) *countingConn {
+	return &countingConn{
+		sent:     this.Sent() - sent,
+		received: this.Received() - received,
+	}
+}
+
+// ReusePool keeps idle connections to servers for sequential requests. A nil ReusePool has no
+// connection.
+type ReusePool struct {
+	sync.Mutex
+	idleTimeout time.Duration
+	maxLifetime time.Duration
+	maxIdle     int
+	sessions    map[string][]*reusedSession
+	closed      bool
+}
+
+// NewReusePool creates a ReusePool. It returns nil if connection reuse is not enabled in config.
+// Connections are not reused after maxLifetime, unless it is 0.
+func NewReusePool(config *ConnectionReuseConfig, maxLifetime time.Duration) *ReusePool {
+	if config == nil {
+		return nil
+	}
+	return &ReusePool{
+		idleTimeout: config.GetEffectiveIdleTimeout(),
+		maxLifetime: maxLifetime,
+		maxIdle:     config.GetEffectiveMaxIdle(),
+		sessions:    make(map[string][]*reusedSession),
+	}
+}
+
+// get takes the most recently used idle connection to server that is still alive, or returns nil if
+// there is none.
+func (this *ReusePool) get(server v2net.Destination) *reusedSession {
+	if this == nil {
+		return nil
+	}
+	for {
+		this.Lock()
+		this.expireWithoutLock(time.Now())
+		key := server.NetAddr()
+		list := this.sessions[key]
+		if len(list) == 0 {
+			this.Unlock()
+			return nil
+		}
+		session := list[len(list)-1]
+		list[len(list)-1] = nil
+		this.sessions[key] = list[:len(list)-1]
+		this.Unlock()
+
+		if session.alive() {
+			return session
+		}
+		log.Debug("Shadowsocks|Client: Idle connection to ", server, " is closed.")
+		session.Close()
+	}
+}
+
+// put keeps session as an idle connection to server. It returns false if the session is not kept, and
+// should be closed.
+func (this *ReusePool) put(server v2net.Destination, session *reusedSession) bool {
+	if this == nil {
+		return false
+	}
+	now := time.Now()
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.closed {
+		return false
+	}
+	this.expireWithoutLock(now)
+	key := server.NetAddr()
+	if len(this.sessions[key]) >= this.maxIdle {
+		return false
+	}
+	session.idleSince = now
+	this.sessions[key] = append(this.sessions[key], session)
+	return true
+}
+
+func (this *ReusePool) expired(session *reusedSession, now time.Time) bool {
+	if now.Sub(session.idleSince) >= this.idleTimeout {
+		return true
+	}
+	return this.maxLifetime > 0 && now.Sub(session.created) >= this.maxLifetime
+}
+
+// expireWithoutLock closes connections that are idle for longer than the idle timeout, or older than the
+// maximum lifetime.
+func (this *ReusePool) expireWithoutLock(now time.Time) {
+	for key, list := range this.sessions {
+		valid := list[:0]
+		for _, session := range list {
+			if !this.expired(session, now) {
+				valid = append(valid, session)
+			} else {
+				session.Close()
+			}
+		}
+		for i := len(valid); i < len(list); i++ {
+			list[i] = nil
+		}
+		if len(valid) == 0 {
+			delete(this.sessions, key)
+		} else {
+			this.sessions[key] = valid
+		}
+	}
+}
+
+// Close closes all idle connections and stops keeping new ones.
+func (this *ReusePool) Close() {
+	if this == nil {
+		return
+	}
+	this.Lock()
+	defer this.Unlock()
+
+	this.closed = true
+	for key, list := range this.sessions {
+		for _, session := range list {
+			session.Close()
+		}
+		delete(this.sessions, key)
+	}
+}
+
+// shouldReuse returns true if the TCP request to server with account should ask the server to keep the
+// connection for sequential requests.
+func (this *Client) shouldReuse(request *protocol.RequestHeader, account *ShadowsocksAccount, server *protocol.ServerSpec) bool {
+	if this.reuse == nil || account.ConnectionReuse == Account_ReuseDisabled {
+		return false
+	}
+	if account.ConnectionReuse == Account_ReuseAuto && !this.reuseFallback.Allowed(server.Destination()) {
+		return false
+	}
+	return !request.Option.Has(RequestOptionOneTimeAuth) && !request.Option.Has(RequestOptionCompression)
+}
+
+// dispatchReused sends the request of session on an idle connection to server. The connection is kept
+// for the next request, if both the request and the response end with their frames.
+func (this *Client) dispatchReused(session *proxy.SessionInfo, reused *reusedSession, server *protocol.ServerSpec, payload *alloc.Buffer, ray ray.OutboundRay, logger *dispatchLogger) (*countingConn, error) {
+	destination := session.Destination
+	policy := session.Route.GetPolicy()
+	logger.OnStart(server.Destination())
+	ray.OutboundOutput().Established()
+
+	kept := false
+	defer func() {
+		if !kept {
+			reused.Close()
+		}
+	}()
+	reused.counter.uplinks = nil
+	reused.counter.downlinks = nil
+	reused.counter.countTags(this.tagCounters, logger.tags)
+	sent, received := reused.counter.Sent(), reused.counter.Received()
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: destination.Address,
+		Port:    destination.Port,
+		Option:  protocol.RequestOptionConnectionReuse,
+		User:    reused.user,
+	}
+	// The header and the first payload are sent together.
+	reused.bufferedWriter.SetCached(true)
+	err := reused.writer.WriteHeader(request)
+	if err == nil {
+		err = reused.writer.Write(payload)
+	}
+	reused.bufferedWriter.SetCached(false)
+	if err != nil {
+		return reused.counter.since(sent, received), errors.New("Shadowsocks|Client: Failed to write request on reused connection: " + err.Error())
+	}
+	logger.OnRequest()
+
+	reused.reader.Next()
+	reused.timedReader.SetTimeOut(timeoutSeconds(policy.HandshakeTimeout))
+	err = this.transfer(reused.timedReader, reused.writer, ray, func() error {
+		responseReader := NewResponseReader(reused.reader)
+		first, err := responseReader.Read()
+		if err != nil {
+			return err
+		}
+		logger.OnHandshake()
+		reused.timedReader.SetTimeOut(timeoutSeconds(policy.IdleTimeout))
+		if err := ray.OutboundOutput().Write(first); err != nil {
+			return err
+		}
+		return v2io.Pipe(responseReader, ray.OutboundOutput())
+	}, reused.writer.End)
+	// Counted before the connection may be used by the next request.
+	counter := reused.counter.since(sent, received)
+	if err == nil && reused.reader.Ended() {
+		kept = this.reuse.put(server.Destination(), reused)
+	}
+	return counter, err
+}
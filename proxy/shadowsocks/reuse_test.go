@@ -0,0 +1,306 @@
+package shadowsocks_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/ray"
+)
+
+// newReuseTestUser returns the test user, on a server that accepts connection reuse.
+func newReuseTestUser() *protocol.User {
+	return &protocol.User{
+		Account: loader.NewTypedSettings(&Account{
+			Password:        testAccount.Password,
+			CipherType:      testAccount.CipherType,
+			Ota:             testAccount.Ota,
+			ConnectionReuse: Account_ReuseEnabled,
+		}),
+	}
+}
+
+// newReuseTestClient returns a client with connection reuse, of a server on port that accepts it.
+func newReuseTestClient(assert *assert.Assert, port v2net.Port, breaker *protocol.CircuitBreakerConfig) proxy.OutboundHandler {
+	return newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(port),
+				User:    []*protocol.User{newReuseTestUser()},
+			},
+		},
+		ConnectionReuse: &ConnectionReuseConfig{},
+		CircuitBreaker:  breaker,
+	})
+}
+
+func TestFrameReaderWriter(t *testing.T) {
+	assert := assert.On(t)
+
+	cache := new(bytes.Buffer)
+	writer := NewFrameWriter(cache)
+	assert.Error(writer.Write(alloc.NewBuffer().Clear().AppendString("first"))).IsNil()
+	assert.Error(writer.End()).IsNil()
+	assert.Error(writer.Write(alloc.NewBuffer().Clear().AppendString("second"))).IsNil()
+
+	reader := NewFrameReader(cache)
+	buffer, err := reader.Read()
+	assert.Error(err).IsNil()
+	assert.String(string(buffer.Value)).Equals("first")
+	_, err = reader.Read()
+	assert.Error(err).Equals(io.EOF)
+	assert.Bool(reader.Ended()).IsTrue()
+	_, err = reader.Read()
+	assert.Error(err).Equals(io.EOF)
+
+	reader.Next()
+	buffer, err = reader.Read()
+	assert.Error(err).IsNil()
+	assert.String(string(buffer.Value)).Equals("second")
+}
+
+func TestReuseNotSupportedWithCompression(t *testing.T) {
+	assert := assert.On(t)
+
+	request := &protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: v2net.LocalHostIP,
+		Port:    v2net.Port(80),
+		Option:  protocol.RequestOptionConnectionReuse | RequestOptionCompression,
+		User:    newTestUser(),
+	}
+	_, err := WriteTCPRequest(request, new(bytes.Buffer))
+	assert.Error(err).Equals(ErrReuseNotSupported)
+}
+
+func TestClientConnectionReuse(t *testing.T) {
+	assert := assert.On(t)
+
+	// The test server only accepts one connection, so the second request fails unless sent on it.
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		frames := reader.(*FrameReader)
+		for _, name := range []string{"first", "second"} {
+			if name != "first" {
+				request, err := ReadNextTCPSession(newTestUser(), frames, MaxDomainLength)
+				assert.Error(err).IsNil()
+				assert.Address(request.Address).Equals(testDestination.Address)
+			}
+			assert.String(readAll(assert, frames, len(name))).Equals(name)
+			assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString(name + " response"))).IsNil()
+			assert.Error(writer.(*FrameWriter).End()).IsNil()
+			_, err := frames.Read()
+			assert.Error(err).Equals(io.EOF)
+		}
+	})
+	defer server.Close()
+
+	client := newReuseTestClient(assert, server.Port(), nil)
+
+	for _, name := range []string{"first", "second"} {
+		traffic := ray.NewRay()
+		result := dispatch(client, name, traffic)
+		response := name + " response"
+		assert.String(readAll(assert, traffic.InboundOutput(), len(response))).Equals(response)
+		traffic.InboundInput().Close()
+		assert.Error(waitForDispatch(assert, result)).IsNil()
+	}
+}
+
+func TestClientConnectionReuseDisabledByAccount(t *testing.T) {
+	assert := assert.On(t)
+
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		_, reused := reader.(*FrameReader)
+		assert.Bool(reused).IsFalse()
+		assert.String(readAll(assert, reader, 7)).Equals("request")
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+	})
+	defer server.Close()
+
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(server.Port()),
+				User: []*protocol.User{{
+					Account: loader.NewTypedSettings(&Account{
+						Password:        testAccount.Password,
+						CipherType:      testAccount.CipherType,
+						Ota:             testAccount.Ota,
+						ConnectionReuse: Account_ReuseDisabled,
+					}),
+				}},
+			},
+		},
+		ConnectionReuse: &ConnectionReuseConfig{},
+	})
+	traffic := ray.NewRay()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+}
+
+func TestClientConnectionReuseFallback(t *testing.T) {
+	assert := assert.On(t)
+
+	// Acts like a server without connection reuse, which closes requests for it.
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	reuseRequests := make(chan bool, 4)
+	go func() {
+		for {
+			conn, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			request, reader, err := ReadTCPSession(newTestUser(), conn)
+			assert.Error(err).IsNil()
+			_, reused := reader.(*FrameReader)
+			reuseRequests <- reused
+			if !reused {
+				readAll(assert, reader, 7)
+				writer, err := WriteTCPResponse(request, conn)
+				assert.Error(err).IsNil()
+				assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("response"))).IsNil()
+			}
+			conn.Close()
+		}
+	}()
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(listener.Addr().(*net.TCPAddr).Port),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		ConnectionReuse: &ConnectionReuseConfig{},
+	})
+
+	traffic := ray.NewRay()
+	assert.Error(waitForDispatch(assert, dispatch(client, "request", traffic))).IsNotNil()
+	assert.Bool(<-reuseRequests).IsTrue()
+
+	// The server is remembered to not support connection reuse.
+	traffic = ray.NewRay()
+	result := dispatch(client, "request", traffic)
+	assert.String(readAll(assert, traffic.InboundOutput(), 8)).Equals("response")
+	traffic.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, result)).IsNil()
+	assert.Bool(<-reuseRequests).IsFalse()
+}
+
+func TestClientConnectionReuseEnabledByAccount(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	reuseRequests := make(chan bool, 4)
+	go func() {
+		for {
+			conn, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			_, reader, err := ReadTCPSession(newTestUser(), conn)
+			assert.Error(err).IsNil()
+			_, reused := reader.(*FrameReader)
+			reuseRequests <- reused
+			conn.Close()
+		}
+	}()
+	client := newReuseTestClient(assert, v2net.Port(listener.Addr().(*net.TCPAddr).Port), nil)
+
+	// Failures are not blamed on connection reuse, when the account enables it.
+	for i := 0; i < 2; i++ {
+		traffic := ray.NewRay()
+		assert.Error(waitForDispatch(assert, dispatch(client, "request", traffic))).IsNotNil()
+		assert.Bool(<-reuseRequests).IsTrue()
+	}
+}
+
+// listenFramedEcho accepts connections with connection reuse, and echoes the first payload of each request
+// on them.
+func listenFramedEcho(assert *assert.Assert) *net.TCPListener {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	go func() {
+		for {
+			conn, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				request, reader, err := ReadTCPSession(newTestUser(), conn)
+				if err != nil {
+					return
+				}
+				writer, err := WriteTCPResponse(request, conn)
+				if err != nil {
+					return
+				}
+				frames := reader.(*FrameReader)
+				for {
+					payload, err := frames.Read()
+					if err != nil {
+						return
+					}
+					writer.Write(payload)
+					writer.(*FrameWriter).End()
+					if _, err := frames.Read(); err != io.EOF {
+						return
+					}
+					if _, err := ReadNextTCPSession(newTestUser(), frames, MaxDomainLength); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestClientConnectionReuseWithCircuitBreaker(t *testing.T) {
+	assert := assert.On(t)
+
+	listener := listenFramedEcho(assert)
+	client := newReuseTestClient(assert, v2net.Port(listener.Addr().(*net.TCPAddr).Port), &protocol.CircuitBreakerConfig{
+		FailureThreshold: 1,
+		OpenTimeout:      1,
+	})
+	defer proxy.CloseOutboundHandler(client)
+	assert.Error(echoThrough(assert, client, "first")).IsNil()
+
+	// The idle connection is busy, so the next request dials, fails and opens the circuit.
+	busy := ray.NewRay()
+	busyResult := dispatch(client, "busy", busy)
+	buffer, err := busy.InboundOutput().Read()
+	assert.Error(err).IsNil()
+	assert.String(string(buffer.Value)).Equals("busy")
+	buffer.Release()
+	listener.Close()
+	assert.Error(echoThrough(assert, client, "refused")).IsNotNil()
+	busy.InboundInput().Close()
+	assert.Error(waitForDispatch(assert, busyResult)).IsNil()
+
+	// The idle connection takes the probe of the half-open circuit, and closes it.
+	time.Sleep(1100 * time.Millisecond)
+	assert.Error(echoThrough(assert, client, "second")).IsNil()
+	assert.Error(echoThrough(assert, client, "third")).IsNil()
+}
@@ -2,10 +2,14 @@
 package shadowsocks
 
 import (
+	"bytes"
+	"io"
 	"sync"
+	"time"
 
 	"errors"
 	"v2ray.com/core/app"
+	"v2ray.com/core/app/api"
 	"v2ray.com/core/app/dispatcher"
 	"v2ray.com/core/common"
 	"v2ray.com/core/common/alloc"
@@ -16,6 +20,7 @@ import (
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/internet/udp"
+	"v2ray.com/core/transport/ray"
 )
 
 type Server struct {
@@ -28,6 +33,8 @@ type Server struct {
 	tcpHub           *internet.TCPHub
 	udpHub           *udp.UDPHub
 	udpServer        *udp.UDPServer
+	quota            *QuotaManager
+	handshakes       *HandshakeLimiter
 }
 
 func NewServer(config *ServerConfig, space app.Space, meta *proxy.InboundHandlerMeta) (*Server, error) {
@@ -42,10 +49,12 @@ func NewServer(config *ServerConfig, space app.Space, meta *proxy.InboundHandler
 	account := rawAccount.(*ShadowsocksAccount)
 
 	s := &Server{
-		config:  config,
-		meta:    meta,
-		user:    config.GetUser(),
-		account: account,
+		config:     config,
+		meta:       meta,
+		user:       config.GetUser(),
+		account:    account,
+		quota:      NewQuotaManager(config.Quota),
+		handshakes: NewHandshakeLimiter(config.HandshakeLimit),
 	}
 
 	space.InitializeApplication(func() error {
@@ -53,12 +62,22 @@ func NewServer(config *ServerConfig, space app.Space, meta *proxy.InboundHandler
 			return app.ErrMissingApplication
 		}
 		s.packetDispatcher = space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher)
+		if s.handshakes != nil && space.HasApp(api.APP_ID) {
+			apiServer := space.GetApp(api.APP_ID).(*api.ApiServer)
+			apiServer.Handle("/inbound/"+meta.Tag+"/handshake-queue", api.NewCounterHandler(s.handshakes.Counters()))
+		}
 		return nil
 	})
 
 	return s, nil
 }
 
+// Quota returns the quota manager of users, or nil if there is no quota. Its store and listener may be
+// replaced before the server starts.
+func (this *Server) Quota() *QuotaManager {
+	return this.quota
+}
+
 func (this *Server) Port() v2net.Port {
 	return this.meta.Port
 }
@@ -75,6 +94,7 @@ func (this *Server) Close() {
 		this.udpHub.Close()
 		this.udpHub = nil
 	}
+	this.quota.Flush()
 }
 
 func (this *Server) Start() error {
@@ -109,30 +129,46 @@ func (this *Server) handlerUDPPayload(payload *alloc.Buffer, session *proxy.Sess
 	request, data, err := DecodeUDPPacket(this.user, payload)
 	if err != nil {
 		log.Info("Shadowsocks|Server: Skipping invalid UDP packet from: ", source, ": ", err)
-		log.Access(source, "", log.AccessRejected, err)
+		log.AccessWithTags(source, "", log.AccessRejected, err, this.meta.ConnectionTags)
+		payload.Release()
+		return
+	}
+	if request.Address.Family().IsDomain() && len(request.Address.Domain()) > this.config.GetEffectiveMaxDomainLength() {
+		log.Info("Shadowsocks|Server: Skipping UDP packet from: ", source, ": ", ErrDomainTooLong)
+		log.AccessWithTags(source, "", log.AccessRejected, ErrDomainTooLong, this.meta.ConnectionTags)
 		payload.Release()
 		return
 	}
 
-	if request.Option.Has(RequestOptionOneTimeAuth) && this.account.OneTimeAuth == Account_Disabled {
+	udpAccount := this.account.ForCommand(protocol.RequestCommandUDP)
+	if request.Option.Has(RequestOptionOneTimeAuth) && udpAccount.OneTimeAuth == Account_Disabled {
 		log.Info("Shadowsocks|Server: Client payload enables OTA but server doesn't allow it.")
 		payload.Release()
 		return
 	}
 
-	if !request.Option.Has(RequestOptionOneTimeAuth) && this.account.OneTimeAuth == Account_Enabled {
+	if !request.Option.Has(RequestOptionOneTimeAuth) && udpAccount.OneTimeAuth == Account_Enabled {
 		log.Info("Shadowsocks|Server: Client payload disables OTA but server forces it.")
 		payload.Release()
 		return
 	}
 
 	dest := request.Destination()
-	log.Access(source, dest, log.AccessAccepted, "")
+	if err := this.quota.Consume(request.User.Email, data.Len()); err != nil {
+		log.AccessWithTags(source, dest, log.AccessRejected, err, this.meta.ConnectionTags)
+		payload.Release()
+		return
+	}
+	log.AccessWithTags(source, dest, log.AccessAccepted, "", this.meta.ConnectionTags)
 	log.Info("Shadowsocks|Server: Tunnelling request to ", dest)
 
 	this.udpServer.Dispatch(&proxy.SessionInfo{Source: source, Destination: dest, User: request.User, Inbound: this.meta}, data, func(destination v2net.Destination, payload *alloc.Buffer) {
 		defer payload.Release()
 
+		if err := this.quota.Consume(request.User.Email, payload.Len()); err != nil {
+			return
+		}
+
 		data, err := EncodeUDPPacket(request, payload)
 		if err != nil {
 			log.Warning("Shadowsocks|Server: Failed to encode UDP packet: ", err)
@@ -154,21 +190,66 @@ func (this *Server) handleConnection(conn internet.Connection) {
 	bufferedReader := v2io.NewBufferedReader(timedReader)
 	defer bufferedReader.Release()
 
-	request, bodyReader, err := ReadTCPSession(this.user, bufferedReader)
+	// Idle connections don't take a handshake slot until they send something.
+	first := make([]byte, 1)
+	if _, err := io.ReadFull(bufferedReader, first); err != nil {
+		log.AccessWithTags(conn.RemoteAddr(), "", log.AccessRejected, err, this.meta.ConnectionTags)
+		log.Info("Shadowsocks|Server: Failed to create request from: ", conn.RemoteAddr(), ": ", err)
+		return
+	}
+	// The rest of the header has a single deadline, instead of one for each read, so that a client that
+	// trickles bytes can't hold the handshake.
+	timedReader.SetTimeOut(0)
+	conn.SetReadDeadline(time.Now().Add(this.config.HandshakeLimit.GetEffectiveHeaderTimeout()))
+	releaseHandshake, err := this.handshakes.Acquire()
+	if err != nil {
+		log.AccessWithTags(conn.RemoteAddr(), "", log.AccessRejected, err, this.meta.ConnectionTags)
+		log.Info("Shadowsocks|Server: Rejecting connection from ", conn.RemoteAddr(), ": ", err)
+		return
+	}
+	request, bodyReader, err := ReadTCPSessionWithLimit(this.user, io.MultiReader(bytes.NewReader(first), bufferedReader), this.config.GetEffectiveMaxDomainLength())
+	releaseHandshake()
+	conn.SetReadDeadline(time.Time{})
+	timedReader.SetTimeOut(16)
 	if err != nil {
-		log.Access(conn.RemoteAddr(), "", log.AccessRejected, err)
+		log.AccessWithTags(conn.RemoteAddr(), "", log.AccessRejected, err, this.meta.ConnectionTags)
 		log.Info("Shadowsocks|Server: Failed to create request from: ", conn.RemoteAddr(), ": ", err)
 		return
 	}
 	defer bodyReader.Release()
 
+	if request.Option.Has(RequestOptionCompression) && !this.config.Compression {
+		log.AccessWithTags(conn.RemoteAddr(), request.Destination(), log.AccessRejected, ErrCompressionDisabled, this.meta.ConnectionTags)
+		log.Info("Shadowsocks|Server: Rejecting compressed request from ", conn.RemoteAddr())
+		return
+	}
+
+	if request.Option.Has(protocol.RequestOptionConnectionReuse) {
+		if !this.config.ConnectionReuse {
+			log.AccessWithTags(conn.RemoteAddr(), request.Destination(), log.AccessRejected, ErrReuseDisabled, this.meta.ConnectionTags)
+			log.Info("Shadowsocks|Server: Rejecting request with connection reuse from ", conn.RemoteAddr())
+			return
+		}
+		bufferedReader.SetCached(false)
+		this.serveReused(conn, timedReader, request, bodyReader.(*FrameReader))
+		return
+	}
+
+	release, err := this.quota.Acquire(request.User.Email)
+	if err != nil {
+		log.AccessWithTags(conn.RemoteAddr(), request.Destination(), log.AccessRejected, err, this.meta.ConnectionTags)
+		log.Info("Shadowsocks|Server: Rejecting request from ", conn.RemoteAddr(), ": ", err)
+		return
+	}
+	defer release()
+
 	bufferedReader.SetCached(false)
 
 	userSettings := this.user.GetSettings()
 	timedReader.SetTimeOut(userSettings.PayloadReadTimeout)
 
 	dest := request.Destination()
-	log.Access(conn.RemoteAddr(), dest, log.AccessAccepted, "")
+	log.AccessWithTags(conn.RemoteAddr(), dest, log.AccessAccepted, "", this.meta.ConnectionTags)
 	log.Info("Shadowsocks|Server: Tunnelling request to ", dest)
 
 	ray := this.packetDispatcher.DispatchToOutbound(&proxy.SessionInfo{
@@ -178,6 +259,7 @@ func (this *Server) handleConnection(conn internet.Connection) {
 		Inbound:     this.meta,
 	})
 	defer ray.InboundOutput().Release()
+	input, output := this.countPayload(request.User.Email, ray)
 
 	var writeFinish sync.Mutex
 	writeFinish.Lock()
@@ -194,25 +276,126 @@ func (this *Server) handleConnection(conn internet.Connection) {
 		}
 		defer responseWriter.Release()
 
-		if payload, err := ray.InboundOutput().Read(); err == nil {
+		if payload, err := output.Read(); err == nil {
 			responseWriter.Write(payload)
 			bufferedWriter.SetCached(false)
 
-			v2io.Pipe(ray.InboundOutput(), responseWriter)
+			err := v2io.Pipe(output, responseWriter)
+			if compressed, ok := responseWriter.(*CompressedWriter); ok && err == io.EOF {
+				if err := compressed.End(); err != nil {
+					log.Info("Shadowsocks|Server: Failed to end compressed response: ", err)
+				}
+			}
 		}
 	}()
 
-	v2io.Pipe(bodyReader, ray.InboundInput())
+	if err := v2io.Pipe(bodyReader, input); err != nil && err != io.EOF {
+		log.Info("Shadowsocks|Server: Failed to read request from ", conn.RemoteAddr(), ": ", err)
+	}
 	ray.InboundInput().Close()
 
 	writeFinish.Lock()
 }
 
+// serveReused serves sequential requests on conn, starting with request, until one of them doesn't end
+// with its frames, or the client sends no more requests.
+func (this *Server) serveReused(conn internet.Connection, timedReader *v2net.TimeOutReader, request *protocol.RequestHeader, reader *FrameReader) {
+	bufferedWriter := v2io.NewBufferedWriter(conn)
+	defer bufferedWriter.Release()
+
+	var writer *FrameWriter
+	userSettings := this.user.GetSettings()
+	for {
+		release, err := this.quota.Acquire(request.User.Email)
+		if err != nil {
+			log.AccessWithTags(conn.RemoteAddr(), request.Destination(), log.AccessRejected, err, this.meta.ConnectionTags)
+			log.Info("Shadowsocks|Server: Rejecting request from ", conn.RemoteAddr(), ": ", err)
+			return
+		}
+		if writer == nil {
+			responseWriter, err := WriteTCPResponse(request, bufferedWriter)
+			if err != nil {
+				release()
+				log.Warning("Shadowsocks|Server: Failed to write response: ", err)
+				return
+			}
+			writer = responseWriter.(*FrameWriter)
+		}
+		timedReader.SetTimeOut(userSettings.PayloadReadTimeout)
+		ended := this.serveFrames(conn, request, reader, writer, bufferedWriter)
+		release()
+		if !ended {
+			return
+		}
+
+		timedReader.SetTimeOut(reuseIdleTimeout)
+		request, err = ReadNextTCPSession(this.user, reader, this.config.GetEffectiveMaxDomainLength())
+		if err != nil {
+			log.Debug("Shadowsocks|Server: Closing reused connection from ", conn.RemoteAddr(), ": ", err)
+			return
+		}
+	}
+}
+
+// serveFrames dispatches a request on a reusable connection. It returns true if both the request and the
+// response end with their frames.
+func (this *Server) serveFrames(conn internet.Connection, request *protocol.RequestHeader, reader *FrameReader, writer *FrameWriter, bufferedWriter *v2io.BufferedWriter) bool {
+	dest := request.Destination()
+	log.AccessWithTags(conn.RemoteAddr(), dest, log.AccessAccepted, "", this.meta.ConnectionTags)
+	log.Info("Shadowsocks|Server: Tunnelling request to ", dest)
+
+	ray := this.packetDispatcher.DispatchToOutbound(&proxy.SessionInfo{
+		Source:      v2net.DestinationFromAddr(conn.RemoteAddr()),
+		Destination: dest,
+		User:        request.User,
+		Inbound:     this.meta,
+	})
+	defer ray.InboundOutput().Release()
+	input, output := this.countPayload(request.User.Email, ray)
+
+	responseEnded := make(chan bool, 1)
+	go func() {
+		payload, err := output.Read()
+		if err == nil {
+			// The IV of the first response is sent with its first payload.
+			err = writer.Write(payload)
+			bufferedWriter.SetCached(false)
+		}
+		if err == nil {
+			err = v2io.Pipe(output, writer)
+		}
+		if err == io.EOF {
+			// A response that the outbound fails is not ended, so the client sees it as truncated.
+			err = ray.InboundOutput().Err()
+		}
+		if err == nil {
+			err = writer.End()
+		}
+		bufferedWriter.SetCached(false)
+		responseEnded <- err == nil
+	}()
+
+	err := v2io.Pipe(reader, input)
+	ray.InboundInput().Close()
+
+	return <-responseEnded && err == io.EOF && reader.Ended()
+}
+
+// countPayload returns the input and output of link, which count payload in both directions to the quota
+// of user. Headers of the protocol are not counted.
+func (this *Server) countPayload(user string, link ray.InboundRay) (v2io.Writer, v2io.Reader) {
+	if this.quota == nil {
+		return link.InboundInput(), link.InboundOutput()
+	}
+	return &quotaWriter{Writer: link.InboundInput(), quota: this.quota, user: user},
+		&quotaReader{Reader: link.InboundOutput(), quota: this.quota, user: user}
+}
+
 type ServerFactory struct{}
 
 func (this *ServerFactory) StreamCapability() v2net.NetworkList {
 	return v2net.NetworkList{
-		Network: []v2net.Network{v2net.Network_TCP, v2net.Network_RawTCP},
+		Network: []v2net.Network{v2net.Network_TCP, v2net.Network_RawTCP, v2net.Network_GRPC},
 	}
 }
 
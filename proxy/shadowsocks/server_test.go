@@ -0,0 +1,113 @@
+package shadowsocks_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/dispatcher"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
+)
+
+// sessionDispatcher sends the destination of each session, and closes it.
+type sessionDispatcher struct {
+	destinations chan v2net.Destination
+}
+
+func (this *sessionDispatcher) DispatchToOutbound(session *proxy.SessionInfo) ray.InboundRay {
+	traffic := ray.NewRay()
+	this.destinations <- session.Destination
+	traffic.OutboundOutput().Close()
+	return traffic
+}
+
+func (this *sessionDispatcher) Release() {}
+
+func pickPort(assert *assert.Assert) v2net.Port {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	return v2net.Port(listener.Addr().(*net.TCPAddr).Port)
+}
+
+// startServer starts a server of config on a free port, with sessions sent to sessions.
+func startServer(assert *assert.Assert, config *ServerConfig, sessions *sessionDispatcher) (*Server, v2net.Port) {
+	port := pickPort(assert)
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, sessions)
+	server, err := NewServer(config, space, &proxy.InboundHandlerMeta{
+		Address:                v2net.LocalHostIP,
+		Port:                   port,
+		AllowPassiveConnection: true,
+		StreamSettings: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+	})
+	assert.Error(err).IsNil()
+	assert.Error(space.Initialize()).IsNil()
+	assert.Error(server.Start()).IsNil()
+	return server, port
+}
+
+func TestServerHeaderTimeoutOfTricklingClient(t *testing.T) {
+	assert := assert.On(t)
+
+	sessions := &sessionDispatcher{
+		destinations: make(chan v2net.Destination, 1),
+	}
+	server, port := startServer(assert, &ServerConfig{
+		User: newTestUser(),
+		HandshakeLimit: &HandshakeLimitConfig{
+			Concurrency:   1,
+			QueueTimeout:  10000,
+			HeaderTimeout: 1,
+		},
+	}, sessions)
+	defer server.Close()
+	address := (&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(port)}).String()
+
+	// A byte every 200ms keeps each read in time, but not the whole header.
+	trickling, err := net.Dial("tcp", address)
+	assert.Error(err).IsNil()
+	defer trickling.Close()
+	start := time.Now()
+	go func() {
+		for {
+			if _, err := trickling.Write([]byte{0}); err != nil {
+				return
+			}
+			time.Sleep(200 * time.Millisecond)
+		}
+	}()
+	trickling.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = trickling.Read(make([]byte, 1))
+	assert.Error(err).IsNotNil()
+	assert.Bool(time.Since(start) < 3*time.Second).IsTrue()
+
+	// The handshake is free for other clients.
+	conn, err := net.Dial("tcp", address)
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	writer, err := WriteTCPRequest(&protocol.RequestHeader{
+		Version: Version,
+		Command: protocol.RequestCommandTCP,
+		Address: testDestination.Address,
+		Port:    testDestination.Port,
+		User:    newTestUser(),
+	}, conn)
+	assert.Error(err).IsNil()
+	writer.Release()
+	select {
+	case destination := <-sessions.destinations:
+		assert.Destination(destination).EqualsString(testDestination.String())
+	case <-time.After(5 * time.Second):
+		assert.Fail("Request is not dispatched.")
+	}
+}
@@ -0,0 +1,406 @@
+package shadowsocks
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"v2ray.com/core/app/api"
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	"v2ray.com/core/transport/ray"
+)
+
+const (
+	defaultSpeedTestSize     = 10 << 20
+	maxSpeedTestSize         = 1 << 30
+	defaultSpeedTestDuration = 10 * time.Second
+	maxSpeedTestDuration     = 60 * time.Second
+	// Time to wait for the response of an upload, after all of it is sent.
+	speedTestResponseTimeout = 10 * time.Second
+)
+
+var (
+	ErrSpeedTestUploadDisabled = errors.New("Shadowsocks|SpeedTest: Upload URL is not configured.")
+	ErrSpeedTestNoResponse     = errors.New("Shadowsocks|SpeedTest: No response.")
+
+	// Data of uploads. Buffers are not sent as is, as they may hold data of other connections.
+	speedTestData = make([]byte, alloc.BufferSize)
+)
+
+// speedTestTarget is the destination and the HTTP request line of one direction of speed tests.
+type speedTestTarget struct {
+	dest v2net.Destination
+	host string
+	uri  string
+}
+
+func parseSpeedTestURL(rawURL string) (*speedTestTarget, error) {
+	targetURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.New("Shadowsocks|SpeedTest: Invalid URL: " + err.Error())
+	}
+	if targetURL.Scheme != "http" {
+		return nil, errors.New("Shadowsocks|SpeedTest: Only HTTP URLs are supported: " + rawURL)
+	}
+	port := v2net.Port(80)
+	if len(targetURL.Port()) > 0 {
+		portValue, err := strconv.ParseUint(targetURL.Port(), 10, 16)
+		if err != nil || portValue == 0 {
+			return nil, errors.New("Shadowsocks|SpeedTest: Invalid port in URL: " + rawURL)
+		}
+		port = v2net.Port(portValue)
+	}
+	return &speedTestTarget{
+		dest: v2net.TCPDestination(v2net.ParseAddress(targetURL.Hostname()), port),
+		host: targetURL.Host,
+		uri:  targetURL.RequestURI(),
+	}, nil
+}
+
+// SpeedTestRequest is a speed test through a server.
+type SpeedTestRequest struct {
+	// Server in the form of "host:port". A server is picked as for requests if empty.
+	Server string `json:"server"`
+	// "download", "upload", or "both" if empty.
+	Direction string `json:"direction"`
+	// Maximum bytes of each direction. Default to 10 MB.
+	Size int64 `json:"size"`
+	// Maximum seconds of each direction. Default to 10.
+	Duration uint32 `json:"duration"`
+}
+
+func (this *SpeedTestRequest) GetEffectiveSize() int64 {
+	if this.Size <= 0 {
+		return defaultSpeedTestSize
+	}
+	if this.Size > maxSpeedTestSize {
+		return maxSpeedTestSize
+	}
+	return this.Size
+}
+
+func (this *SpeedTestRequest) GetEffectiveDuration() time.Duration {
+	if this.Duration == 0 {
+		return defaultSpeedTestDuration
+	}
+	duration := time.Duration(this.Duration) * time.Second
+	if duration > maxSpeedTestDuration {
+		return maxSpeedTestDuration
+	}
+	return duration
+}
+
+// SpeedTestResult is the throughput of one direction. Time includes connecting to the server and the
+// handshake, as in requests.
+type SpeedTestResult struct {
+	Bytes         int64   `json:"bytes"`
+	Seconds       float64 `json:"seconds"`
+	BitsPerSecond float64 `json:"bitsPerSecond"`
+	Error         string  `json:"error,omitempty"`
+}
+
+func newSpeedTestResult(bytes int64, elapsed time.Duration, err error) *SpeedTestResult {
+	result := &SpeedTestResult{
+		Bytes:   bytes,
+		Seconds: elapsed.Seconds(),
+	}
+	if result.Seconds > 0 {
+		result.BitsPerSecond = float64(bytes*8) / result.Seconds
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+type SpeedTestReport struct {
+	Server   string           `json:"server"`
+	Download *SpeedTestResult `json:"download,omitempty"`
+	Upload   *SpeedTestResult `json:"upload,omitempty"`
+}
+
+// SpeedTester runs speed tests through servers of a client. On POST with a SpeedTestRequest body, it
+// returns a SpeedTestReport.
+type SpeedTester struct {
+	client   *Client
+	download *speedTestTarget
+	// Target of uploads, or nil if uploads are disabled.
+	upload *speedTestTarget
+}
+
+func NewSpeedTester(config *SpeedTestConfig, client *Client) (*SpeedTester, error) {
+	download, err := parseSpeedTestURL(config.DownloadUrl)
+	if err != nil {
+		return nil, err
+	}
+	tester := &SpeedTester{
+		client:   client,
+		download: download,
+	}
+	if len(config.UploadUrl) > 0 {
+		if tester.upload, err = parseSpeedTestURL(config.UploadUrl); err != nil {
+			return nil, err
+		}
+	}
+	return tester, nil
+}
+
+func (this *SpeedTester) findServer(address string) (*protocol.ServerSpec, error) {
+	if len(address) == 0 {
+		if server := this.client.serverPicker.PickServer(); server != nil {
+			return server, nil
+		}
+		return nil, protocol.ErrNoServerAvailable
+	}
+	host, rawPort, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errors.New("Shadowsocks|SpeedTest: Invalid server address: " + address)
+	}
+	port, err := v2net.PortFromString(rawPort)
+	if err != nil {
+		return nil, errors.New("Shadowsocks|SpeedTest: Invalid server port: " + rawPort)
+	}
+	server := this.client.serverList.FindServer(v2net.TCPDestination(v2net.ParseAddress(host), port))
+	if server == nil {
+		return nil, errors.New("Shadowsocks|SpeedTest: Server not found: " + address)
+	}
+	return server, nil
+}
+
+// Run runs the speed test of request, one direction after another.
+func (this *SpeedTester) Run(request *SpeedTestRequest) (*SpeedTestReport, error) {
+	direction := strings.ToLower(request.Direction)
+	switch direction {
+	case "", "both", "download", "upload":
+	default:
+		return nil, errors.New("Shadowsocks|SpeedTest: Unknown direction: " + request.Direction)
+	}
+	server, err := this.findServer(request.Server)
+	if err != nil {
+		return nil, err
+	}
+	size := request.GetEffectiveSize()
+	duration := request.GetEffectiveDuration()
+
+	report := &SpeedTestReport{
+		Server: server.Destination().NetAddr(),
+	}
+	if direction != "upload" {
+		report.Download = this.runDownload(server, size, duration)
+		log.Info("Shadowsocks|SpeedTest: Downloaded ", report.Download.Bytes, " bytes through ", server.Destination(), " in ", report.Download.Seconds, " seconds.")
+	}
+	if direction != "download" {
+		if this.upload == nil {
+			report.Upload = newSpeedTestResult(0, 0, ErrSpeedTestUploadDisabled)
+		} else {
+			report.Upload = this.runUpload(server, size, duration)
+			log.Info("Shadowsocks|SpeedTest: Uploaded ", report.Upload.Bytes, " bytes through ", server.Destination(), " in ", report.Upload.Seconds, " seconds.")
+		}
+	}
+	return report, nil
+}
+
+// takenInput is the input of a speed test request, which counts the bytes that the client takes from it.
+// The client takes a buffer only after writing the one before to its connection, so these are the bytes
+// sent, and not the ones still waiting in the link.
+type takenInput struct {
+	ray.InputStream
+	taken int64
+	// Unix time in nanoseconds of the last take.
+	lastTaken int64
+}
+
+func (this *takenInput) take(buffer *alloc.Buffer) {
+	atomic.AddInt64(&this.taken, int64(buffer.Len()))
+	atomic.StoreInt64(&this.lastTaken, time.Now().UnixNano())
+}
+
+func (this *takenInput) Read() (*alloc.Buffer, error) {
+	buffer, err := this.InputStream.Read()
+	if err == nil {
+		this.take(buffer)
+	}
+	return buffer, err
+}
+
+func (this *takenInput) ReadTimeout(timeout time.Duration) (*alloc.Buffer, error) {
+	buffer, err := this.InputStream.ReadTimeout(timeout)
+	if err == nil {
+		this.take(buffer)
+	}
+	return buffer, err
+}
+
+func (this *takenInput) Taken() int64 {
+	return atomic.LoadInt64(&this.taken)
+}
+
+// Since returns the time from start to the last take, or 0 if nothing is taken.
+func (this *takenInput) Since(start time.Time) time.Duration {
+	last := atomic.LoadInt64(&this.lastTaken)
+	if last == 0 {
+		return 0
+	}
+	return time.Unix(0, last).Sub(start)
+}
+
+// speedTestLink is the link of a speed test request, as seen by the client.
+type speedTestLink struct {
+	ray.OutboundRay
+	input *takenInput
+}
+
+func (this *speedTestLink) OutboundInput() ray.InputStream {
+	return this.input
+}
+
+// open starts a request with header to target through server, and returns its link, with the input that
+// counts the bytes taken by the client.
+func (this *SpeedTester) open(server *protocol.ServerSpec, target *speedTestTarget, header string) (ray.InboundRay, *takenInput) {
+	link := ray.NewRay()
+	input := &takenInput{InputStream: link.OutboundInput()}
+	go this.client.dispatchThrough(server, target.dest, &speedTestLink{OutboundRay: link, input: input})
+	link.InboundInput().Write(alloc.NewLocalBuffer(len(header)).Clear().AppendString(header))
+	return link, input
+}
+
+// streamError returns the error that stream is closed with, or ErrSpeedTestNoResponse if there is none.
+func streamError(stream ray.InputStream) error {
+	if err := stream.Err(); err != nil {
+		return err
+	}
+	return ErrSpeedTestNoResponse
+}
+
+func (this *SpeedTester) runDownload(server *protocol.ServerSpec, size int64, duration time.Duration) *SpeedTestResult {
+	start := time.Now()
+	link, _ := this.open(server, this.download, "GET "+this.download.uri+" HTTP/1.1\r\nHost: "+this.download.host+"\r\nConnection: close\r\n\r\n")
+	defer link.InboundOutput().Release()
+	defer link.InboundInput().Close()
+
+	deadline := start.Add(duration)
+	var received int64
+	var err error
+	for received < size {
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			break
+		}
+		buffer, readErr := link.InboundOutput().ReadTimeout(remaining)
+		if readErr == ray.ErrIOTimeout {
+			break
+		}
+		if readErr != nil {
+			if received == 0 {
+				err = streamError(link.InboundOutput())
+			}
+			break
+		}
+		received += int64(buffer.Len())
+		buffer.Release()
+	}
+	return newSpeedTestResult(received, time.Since(start), err)
+}
+
+func (this *SpeedTester) runUpload(server *protocol.ServerSpec, size int64, duration time.Duration) *SpeedTestResult {
+	start := time.Now()
+	header := "POST " + this.upload.uri + " HTTP/1.1\r\nHost: " + this.upload.host + "\r\nContent-Type: application/octet-stream\r\nContent-Length: " + strconv.FormatInt(size, 10) + "\r\nConnection: close\r\n\r\n"
+	link, input := this.open(server, this.upload, header)
+	defer link.InboundOutput().Release()
+
+	deadline := start.Add(duration)
+	// Writes block while the link is full, so the link is closed to end them at the deadline.
+	timer := time.AfterFunc(duration, link.InboundInput().Close)
+	defer timer.Stop()
+	var sent int64
+	for sent < size && time.Now().Before(deadline) {
+		length := int64(len(speedTestData))
+		if length > size-sent {
+			length = size - sent
+		}
+		if err := link.InboundInput().Write(alloc.NewBuffer().Clear().Append(speedTestData[:length])); err != nil {
+			break
+		}
+		sent += length
+	}
+	link.InboundInput().Close()
+
+	// The upload is fully sent once the server responds to it. Until then, only the bytes taken by the
+	// client are sent, as the rest may still be queued in the link, so the upload is timed until the last
+	// bytes are taken.
+	if sent < size {
+		return newSpeedTestResult(uploaded(input, len(header), sent), input.Since(start), nil)
+	}
+	buffer, err := link.InboundOutput().ReadTimeout(speedTestResponseTimeout)
+	if err == ray.ErrIOTimeout {
+		return newSpeedTestResult(uploaded(input, len(header), sent), input.Since(start), nil)
+	}
+	if err != nil {
+		return newSpeedTestResult(uploaded(input, len(header), sent), input.Since(start), streamError(link.InboundOutput()))
+	}
+	buffer.Release()
+	return newSpeedTestResult(sent, time.Since(start), nil)
+}
+
+// uploaded returns the bytes of an upload of at most sent bytes that input has given to the client, without
+// the header.
+func uploaded(input *takenInput, header int, sent int64) int64 {
+	taken := input.Taken() - int64(header)
+	if taken < 0 {
+		return 0
+	}
+	if taken > sent {
+		return sent
+	}
+	return taken
+}
+
+func (this *SpeedTester) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		api.WriteError(writer, http.StatusMethodNotAllowed, api.ErrInvalidRequest)
+		return
+	}
+	speedTest := new(SpeedTestRequest)
+	if err := json.NewDecoder(request.Body).Decode(speedTest); err != nil {
+		api.WriteError(writer, http.StatusBadRequest, api.ErrInvalidRequest)
+		return
+	}
+	report, err := this.Run(speedTest)
+	if err != nil {
+		api.WriteError(writer, http.StatusBadRequest, err)
+		return
+	}
+	api.WriteJSON(writer, report)
+}
+
+// dispatchThrough sends a request to destination through server, as a request from an inbound. The first
+// payload is read from link.
+func (this *Client) dispatchThrough(server *protocol.ServerSpec, destination v2net.Destination, link ray.OutboundRay) error {
+	payload, err := link.OutboundInput().Read()
+	if err != nil {
+		link.OutboundInput().Release()
+		link.OutboundOutput().Close()
+		return err
+	}
+	defer payload.Release()
+	defer link.OutboundInput().Release()
+	defer link.OutboundOutput().Close()
+
+	logger := newDispatchLogger(this.dispatchLog, this.meta, destination, nil, nil)
+	conn, err := this.dispatch(&proxy.SessionInfo{Destination: destination}, payload, link, logger, nil, server)
+	logger.OnFinish(conn, err)
+	if err != nil {
+		link.OutboundOutput().CloseError(err)
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+package shadowsocks_test
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	v2io "v2ray.com/core/common/io"
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func newSpeedTester(assert *assert.Assert, port v2net.Port) *SpeedTester {
+	client := newTestClient(assert, port).(*Client)
+	tester, err := NewSpeedTester(&SpeedTestConfig{
+		DownloadUrl: "http://www.v2ray.com/down",
+		UploadUrl:   "http://www.v2ray.com/up",
+	}, client)
+	assert.Error(err).IsNil()
+	return tester
+}
+
+// readHeader reads from reader until the end of an HTTP request header.
+func readHeader(assert *assert.Assert, reader v2io.Reader) string {
+	var data []byte
+	for !strings.Contains(string(data), "\r\n\r\n") {
+		buffer, err := reader.Read()
+		assert.Error(err).IsNil()
+		data = append(data, buffer.Value...)
+		buffer.Release()
+	}
+	return string(data)
+}
+
+func TestSpeedTestDownload(t *testing.T) {
+	assert := assert.On(t)
+
+	const size = 256 << 10
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		assert.String(readHeader(assert, reader)).Contains("GET /down HTTP/1.1\r\n")
+		// More than requested, so the client stops at the size.
+		for sent := 0; sent < size*2; sent += 8192 {
+			if err := writer.Write(alloc.NewBuffer().Clear().Append(make([]byte, 8192))); err != nil {
+				return
+			}
+		}
+	})
+	defer server.Close()
+	tester := newSpeedTester(assert, server.Port())
+
+	report, err := tester.Run(&SpeedTestRequest{
+		Server:    "127.0.0.1:" + server.Port().String(),
+		Direction: "download",
+		Size:      size,
+	})
+	assert.Error(err).IsNil()
+	assert.Pointer(report.Upload).IsNil()
+	assert.String(report.Download.Error).Equals("")
+	assert.Bool(report.Download.Bytes >= size).IsTrue()
+	assert.Bool(report.Download.BitsPerSecond > 0).IsTrue()
+}
+
+func TestSpeedTestUpload(t *testing.T) {
+	assert := assert.On(t)
+
+	const size = 256 << 10
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		header := readHeader(assert, reader)
+		assert.String(header).Contains("POST /up HTTP/1.1\r\n")
+		assert.String(header).Contains("Content-Length: " + strconv.Itoa(size) + "\r\n")
+		received := len(header) - strings.Index(header, "\r\n\r\n") - 4
+		for received < size {
+			buffer, err := reader.Read()
+			assert.Error(err).IsNil()
+			received += buffer.Len()
+			buffer.Release()
+		}
+		assert.Error(writer.Write(alloc.NewLocalBuffer(2048).Clear().AppendString("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))).IsNil()
+	})
+	defer server.Close()
+	tester := newSpeedTester(assert, server.Port())
+
+	report, err := tester.Run(&SpeedTestRequest{
+		Direction: "upload",
+		Size:      size,
+	})
+	assert.Error(err).IsNil()
+	assert.Pointer(report.Download).IsNil()
+	assert.String(report.Upload.Error).Equals("")
+	assert.Int64(report.Upload.Bytes).Equals(size)
+
+	_, err = tester.Run(&SpeedTestRequest{
+		Server: "127.0.0.1:1",
+	})
+	assert.Error(err).IsNotNil()
+	_, err = tester.Run(&SpeedTestRequest{
+		Direction: "sideways",
+	})
+	assert.Error(err).IsNotNil()
+}
+
+func TestSpeedTestUploadOfStalledServer(t *testing.T) {
+	assert := assert.On(t)
+
+	received := make(chan int, 1)
+	server := startTestServer(assert, func(conn *net.TCPConn, reader v2io.Reader, writer v2io.Writer) {
+		header := readHeader(assert, reader)
+		total := len(header) - strings.Index(header, "\r\n\r\n") - 4
+		// Nothing is read for a while, so the upload fills the link of the client.
+		time.Sleep(4 * time.Second)
+		for {
+			conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+			buffer, err := reader.Read()
+			if err != nil {
+				break
+			}
+			total += buffer.Len()
+			buffer.Release()
+		}
+		received <- total
+	})
+	defer server.Close()
+	tester := newSpeedTester(assert, server.Port())
+
+	start := time.Now()
+	report, err := tester.Run(&SpeedTestRequest{
+		Direction: "upload",
+		Size:      1 << 30,
+		Duration:  1,
+	})
+	assert.Error(err).IsNil()
+	assert.Bool(time.Since(start) < 3500*time.Millisecond).IsTrue()
+
+	// Bytes still queued in the link at the end of the test are not counted, as they never reach the server.
+	total := <-received
+	assert.Bool(report.Upload.Bytes > 0).IsTrue()
+	assert.Bool(report.Upload.Bytes <= int64(total)).IsTrue()
+	assert.Bool(report.Upload.Seconds <= 1.5).IsTrue()
+}
+
+func TestSpeedTestConfig(t *testing.T) {
+	assert := assert.On(t)
+
+	_, err := NewSpeedTester(&SpeedTestConfig{
+		DownloadUrl: "https://www.v2ray.com/down",
+	}, nil)
+	assert.Error(err).IsNotNil()
+	_, err = NewSpeedTester(&SpeedTestConfig{
+		DownloadUrl: "http://www.v2ray.com/down",
+		UploadUrl:   "http://www.v2ray.com:0/up",
+	}, nil)
+	assert.Error(err).IsNotNil()
+}
@@ -0,0 +1,204 @@
+package shadowsocks
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+	"v2ray.com/core/common/protocol"
+)
+
+const (
+	DefaultRefreshInterval = 3600
+	DefaultMaxServers      = 1000
+	DefaultMaxSize         = 4 * 1024 * 1024
+)
+
+var (
+	ErrEmptySubscription    = errors.New("Shadowsocks|Subscription: No server in subscription.")
+	ErrSubscriptionTooLarge = errors.New("Shadowsocks|Subscription: Subscription is too large.")
+)
+
+func (this *Subscription) GetRefreshInterval() time.Duration {
+	if this.RefreshInterval == 0 {
+		return time.Second * DefaultRefreshInterval
+	}
+	return time.Second * time.Duration(this.RefreshInterval)
+}
+
+func (this *Subscription) GetEffectiveMaxServers() int {
+	if this.MaxServers == 0 {
+		return DefaultMaxServers
+	}
+	return int(this.MaxServers)
+}
+
+func (this *Subscription) GetEffectiveMaxSize() int64 {
+	if this.MaxSize == 0 {
+		return DefaultMaxSize
+	}
+	return int64(this.MaxSize)
+}
+
+// ParseSubscription parses the content of a subscription, which is a list of ss:// URIs, one per line,
+// optionally encoded in base64 as a whole. Lines that are not valid ss:// URIs are skipped.
+func ParseSubscription(content []byte) ([]*URI, error) {
+	text := strings.TrimSpace(string(content))
+	if !strings.Contains(text, "://") {
+		decoded, err := decodeBase64(strings.Replace(strings.Replace(text, "\n", "", -1), "\r", "", -1))
+		if err != nil {
+			return nil, errors.New("Shadowsocks|Subscription: Invalid content: " + err.Error())
+		}
+		text = string(decoded)
+	}
+
+	var uris []*URI
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		uri, err := ParseURI(line)
+		if err != nil {
+			log.Warning("Shadowsocks|Subscription: Skipping invalid entry: ", err)
+			continue
+		}
+		uris = append(uris, uri)
+	}
+	if len(uris) == 0 {
+		return nil, ErrEmptySubscription
+	}
+	return uris, nil
+}
+
+// SubscriptionFetcher refreshes a ServerList from a subscription periodically. Servers configured
+// statically are always kept in the list. If a refresh fails, the last known good list stays in use.
+type SubscriptionFetcher struct {
+	sync.Mutex
+	config       *Subscription
+	serverList   *protocol.ServerList
+	static       []*protocol.ServerSpec
+	client       *http.Client
+	etag         string
+	lastModified string
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// NewSubscriptionFetcher creates a fetcher that refreshes serverList. The list is limited to its current
+// servers and the maximum from the subscription.
+func NewSubscriptionFetcher(config *Subscription, serverList *protocol.ServerList) *SubscriptionFetcher {
+	static := serverList.Servers()
+	serverList.SetLimit(uint32(len(static) + config.GetEffectiveMaxServers()))
+	return &SubscriptionFetcher{
+		config:     config,
+		serverList: serverList,
+		static:     static,
+		client: &http.Client{
+			// Own transport, so that its idle connections can be closed with the fetcher.
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+			},
+			Timeout: time.Second * 30,
+		},
+		done: make(chan struct{}),
+	}
+}
+
+// Refresh fetches the subscription once and updates the ServerList. The list is left untouched if
+// the subscription is not modified or the fetch fails.
+func (this *SubscriptionFetcher) Refresh() error {
+	this.Lock()
+	defer this.Unlock()
+
+	request, err := http.NewRequest(http.MethodGet, this.config.Url, nil)
+	if err != nil {
+		return errors.New("Shadowsocks|Subscription: Invalid URL: " + err.Error())
+	}
+	// Closing the fetcher cancels the request in progress.
+	request.Cancel = this.done
+	if len(this.etag) > 0 {
+		request.Header.Set("If-None-Match", this.etag)
+	}
+	if len(this.lastModified) > 0 {
+		request.Header.Set("If-Modified-Since", this.lastModified)
+	}
+
+	response, err := this.client.Do(request)
+	if err != nil {
+		return errors.New("Shadowsocks|Subscription: Failed to fetch subscription: " + err.Error())
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified {
+		log.Debug("Shadowsocks|Subscription: Subscription not modified.")
+		return nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return errors.New("Shadowsocks|Subscription: Unexpected status: " + response.Status)
+	}
+
+	// One more byte tells a subscription of the maximum size from a larger one.
+	maxSize := this.config.GetEffectiveMaxSize()
+	content, err := ioutil.ReadAll(io.LimitReader(response.Body, maxSize+1))
+	if err != nil {
+		return errors.New("Shadowsocks|Subscription: Failed to read subscription: " + err.Error())
+	}
+	if int64(len(content)) > maxSize {
+		log.Warning("Shadowsocks|Subscription: Subscription is larger than the limit of ", maxSize, " bytes.")
+		return ErrSubscriptionTooLarge
+	}
+	uris, err := ParseSubscription(content)
+	if err != nil {
+		return err
+	}
+	if maxServers := this.config.GetEffectiveMaxServers(); len(uris) > maxServers {
+		log.Warning("Shadowsocks|Subscription: Dropping ", len(uris)-maxServers, " servers beyond the limit of ", maxServers, ".")
+		uris = uris[:maxServers]
+	}
+
+	servers := make([]*protocol.ServerSpec, 0, len(this.static)+len(uris))
+	servers = append(servers, this.static...)
+	for _, uri := range uris {
+		if err := uri.Account.ValidatePlugin(); err != nil {
+			log.Warning("Shadowsocks|Subscription: Skipping ", uri, ": ", err)
+			continue
+		}
+		servers = append(servers, protocol.NewServerSpecFromPB(*uri.ServerEndpoint()))
+	}
+	this.serverList.ReplaceServers(servers)
+
+	this.etag = response.Header.Get("ETag")
+	this.lastModified = response.Header.Get("Last-Modified")
+	log.Info("Shadowsocks|Subscription: ", len(uris), " servers loaded from subscription.")
+	return nil
+}
+
+// Start refreshes the subscription immediately and then on every refresh interval, until Close() is called.
+func (this *SubscriptionFetcher) Start() {
+	go func() {
+		defer this.client.Transport.(*http.Transport).CloseIdleConnections()
+		for {
+			if err := this.Refresh(); err != nil {
+				log.Warning(err, ". Using last known servers.")
+			}
+			select {
+			case <-this.done:
+				return
+			case <-time.After(this.config.GetRefreshInterval()):
+			}
+		}
+	}()
+}
+
+// Close stops refreshing and cancels the fetch in progress. It is safe to call Close more than once.
+func (this *SubscriptionFetcher) Close() {
+	this.closeOnce.Do(func() {
+		close(this.done)
+	})
+}
@@ -0,0 +1,94 @@
+package shadowsocks_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestSubscriptionRefresh(t *testing.T) {
+	assert := assert.On(t)
+
+	content := base64.StdEncoding.EncodeToString([]byte("ss://YWVzLTEyOC1jZmI6dGVzdA@192.168.100.1:8888#A\nvmess://invalid\nss://YWVzLTI1Ni1jZmI6dGVzdA@192.168.100.2:8888#B\n"))
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			writer.Header().Set("ETag", "v1")
+			writer.Write([]byte(content))
+		case 2:
+			assert.String(request.Header.Get("If-None-Match")).Equals("v1")
+			writer.WriteHeader(http.StatusNotModified)
+		default:
+			writer.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	serverList := protocol.NewServerList()
+	fetcher := NewSubscriptionFetcher(&Subscription{Url: server.URL}, serverList)
+
+	assert.Error(fetcher.Refresh()).IsNil()
+	assert.Uint32(serverList.Size()).Equals(2)
+	serverList.GetServer(0).SetWeight(5)
+
+	assert.Error(fetcher.Refresh()).IsNil()
+	assert.Uint32(serverList.Size()).Equals(2)
+
+	assert.Error(fetcher.Refresh()).IsNotNil()
+	assert.Uint32(serverList.Size()).Equals(2)
+	assert.Uint32(serverList.GetServer(0).Weight()).Equals(5)
+}
+
+func TestEmptySubscription(t *testing.T) {
+	assert := assert.On(t)
+
+	_, err := ParseSubscription([]byte("vmess://invalid\n"))
+	assert.Error(err).Equals(ErrEmptySubscription)
+}
+
+func TestSubscriptionMaxServers(t *testing.T) {
+	assert := assert.On(t)
+
+	content := "ss://YWVzLTEyOC1jZmI6dGVzdA@192.168.100.1:8888#A\nss://YWVzLTEyOC1jZmI6dGVzdA@192.168.100.2:8888#B\nss://YWVzLTEyOC1jZmI6dGVzdA@192.168.100.3:8888#C\n"
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	serverList := protocol.NewServerList()
+	serverList.AddServer(protocol.NewServerSpec(v2net.TCPDestination(v2net.LocalHostIP, 8388), protocol.AlwaysValid()))
+	fetcher := NewSubscriptionFetcher(&Subscription{Url: server.URL, MaxServers: 2}, serverList)
+
+	assert.Error(fetcher.Refresh()).IsNil()
+	assert.Uint32(serverList.Size()).Equals(3)
+	assert.Port(serverList.GetServer(0).Destination().Port).Equals(8388)
+	assert.String(serverList.GetServer(2).Destination().Address.String()).Equals("192.168.100.2")
+}
+
+func TestSubscriptionMaxSize(t *testing.T) {
+	assert := assert.On(t)
+
+	content := "ss://YWVzLTEyOC1jZmI6dGVzdA@192.168.100.1:8888#A\n"
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	serverList := protocol.NewServerList()
+	fetcher := NewSubscriptionFetcher(&Subscription{Url: server.URL, MaxSize: uint32(len(content))}, serverList)
+	assert.Error(fetcher.Refresh()).IsNil()
+	assert.Uint32(serverList.Size()).Equals(1)
+
+	// A larger subscription leaves servers untouched.
+	content += "ss://YWVzLTEyOC1jZmI6dGVzdA@192.168.100.2:8888#B\n"
+	assert.Error(fetcher.Refresh()).Equals(ErrSubscriptionTooLarge)
+	assert.Uint32(serverList.Size()).Equals(1)
+}
@@ -0,0 +1,147 @@
+package shadowsocks
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+)
+
+const (
+	// Datagrams up to this size are unlikely to be dropped because of MTU on any path.
+	safeUDPPayloadSize = 1232
+)
+
+// UDPSizeMonitor correlates sizes of datagrams sent to a server with responses from it. When a read
+// times out, it tells whether the failure is likely caused by MTU rather than connectivity.
+type UDPSizeMonitor struct {
+	sync.Mutex
+	server v2net.Destination
+	// Largest datagram that was followed by a response.
+	largestAnswered int
+	// Largest datagram sent since last response.
+	largestPending int
+	pending        int
+	responses      int
+}
+
+func NewUDPSizeMonitor(server v2net.Destination) *UDPSizeMonitor {
+	return &UDPSizeMonitor{
+		server: server,
+	}
+}
+
+func (this *UDPSizeMonitor) headerSize() int {
+	if this.server.Address.Family().IsIPv6() {
+		return 48
+	}
+	return 28
+}
+
+func (this *UDPSizeMonitor) OnSent(size int) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.pending++
+	if size > this.largestPending {
+		this.largestPending = size
+	}
+}
+
+func (this *UDPSizeMonitor) OnReceived() {
+	this.Lock()
+	defer this.Unlock()
+
+	this.responses++
+	if this.largestPending > this.largestAnswered {
+		this.largestAnswered = this.largestPending
+	}
+	this.pending = 0
+	this.largestPending = 0
+}
+
+// Diagnose returns a description of the likely MTU problem, or empty string if the pending datagrams
+// don't indicate one.
+func (this *UDPSizeMonitor) Diagnose() string {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.pending == 0 || this.largestPending <= this.largestAnswered {
+		return ""
+	}
+	header := this.headerSize()
+	if this.responses > 0 {
+		return "datagrams up to " + strconv.Itoa(this.largestAnswered) + " bytes got responses but " + strconv.Itoa(this.pending) +
+			" datagrams up to " + strconv.Itoa(this.largestPending) + " bytes did not. Estimated working MTU is " +
+			strconv.Itoa(this.largestAnswered+header) + "."
+	}
+	if this.largestPending > safeUDPPayloadSize {
+		return "no response to " + strconv.Itoa(this.pending) + " datagrams up to " + strconv.Itoa(this.largestPending) +
+			" bytes. They may exceed path MTU; MTU of " + strconv.Itoa(safeUDPPayloadSize+header) + " is known to work on most paths."
+	}
+	return ""
+}
+
+// Responses returns the number of datagrams received.
+func (this *UDPSizeMonitor) Responses() int {
+	this.Lock()
+	defer this.Unlock()
+
+	return this.responses
+}
+
+func (this *UDPSizeMonitor) OnTimeout() {
+	if diagnosis := this.Diagnose(); len(diagnosis) > 0 {
+		log.Warning("Shadowsocks|Client: UDP to ", this.server, " timed out, likely MTU related: ", diagnosis)
+	}
+}
+
+func (this *UDPSizeMonitor) OnWriteError(size int, err error) {
+	if isMessageTooLong(err) {
+		// Kernel returns EMSGSIZE once it learns a smaller path MTU, e.g., from ICMP "fragmentation needed".
+		log.Warning("Shadowsocks|Client: UDP datagram of ", size, " bytes to ", this.server, " exceeds path MTU: ", err)
+	}
+}
+
+func isMessageTooLong(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == syscall.EMSGSIZE
+}
+
+// udpMonitoredConn reports datagrams sent and received on a UDP connection to a UDPSizeMonitor.
+type udpMonitoredConn struct {
+	internet.Connection
+	monitor *UDPSizeMonitor
+}
+
+func (this *udpMonitoredConn) Write(b []byte) (int, error) {
+	nBytes, err := this.Connection.Write(b)
+	if err != nil {
+		this.monitor.OnWriteError(len(b), err)
+		return nBytes, err
+	}
+	this.monitor.OnSent(len(b))
+	return nBytes, nil
+}
+
+func (this *udpMonitoredConn) Read(b []byte) (int, error) {
+	nBytes, err := this.Connection.Read(b)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			this.monitor.OnTimeout()
+		}
+		return nBytes, err
+	}
+	this.monitor.OnReceived()
+	return nBytes, nil
+}
@@ -0,0 +1,28 @@
+package shadowsocks_test
+
+import (
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestUDPSizeMonitor(t *testing.T) {
+	assert := assert.On(t)
+
+	monitor := NewUDPSizeMonitor(v2net.UDPDestination(v2net.LocalHostIP, v2net.Port(8388)))
+	monitor.OnSent(500)
+	assert.String(monitor.Diagnose()).Equals("")
+	monitor.OnReceived()
+
+	monitor.OnSent(400)
+	assert.String(monitor.Diagnose()).Equals("")
+
+	monitor.OnSent(1400)
+	assert.String(monitor.Diagnose()).Equals("datagrams up to 500 bytes got responses but 2 datagrams up to 1400 bytes did not. Estimated working MTU is 528.")
+
+	monitor.OnReceived()
+	assert.String(monitor.Diagnose()).Equals("")
+	assert.Int(monitor.Responses()).Equals(2)
+}
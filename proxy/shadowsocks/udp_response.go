@@ -0,0 +1,37 @@
+package shadowsocks
+
+import (
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/stats"
+)
+
+// UDPResponseTracker counts UDP associations by whether they received any response, so that silent
+// destinations can be told apart from lost datagrams on lossy relays.
+type UDPResponseTracker struct {
+	config   *UDPResponseConfig
+	counters *stats.CounterSet
+}
+
+// NewUDPResponseTracker creates a tracker that adds to counters named "<server>>>>answered" and
+// "<server>>>>silent". config may be nil.
+func NewUDPResponseTracker(config *UDPResponseConfig, counters *stats.CounterSet) *UDPResponseTracker {
+	return &UDPResponseTracker{
+		config:   config,
+		counters: counters,
+	}
+}
+
+// OnFinish records an association to destination via server, which received the given number of responses.
+func (this *UDPResponseTracker) OnFinish(server v2net.Destination, destination v2net.Destination, responses int) {
+	if responses > 0 {
+		this.counters.Get(server.NetAddr() + ">>>answered").Add(1)
+		return
+	}
+	this.counters.Get(server.NetAddr() + ">>>silent").Add(1)
+	if this.config != nil && this.config.Expected {
+		log.Warning("Shadowsocks|Client: No UDP response from ", destination, " via ", server, ", datagrams may be lost.")
+		return
+	}
+	log.Debug("Shadowsocks|Client: No UDP response from ", destination, " via ", server, ".")
+}
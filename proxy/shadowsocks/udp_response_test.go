@@ -0,0 +1,29 @@
+package shadowsocks_test
+
+import (
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/stats"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestUDPResponseTracker(t *testing.T) {
+	assert := assert.On(t)
+
+	counters := stats.NewCounterSet()
+	tracker := NewUDPResponseTracker(&UDPResponseConfig{Expected: true}, counters)
+	server := v2net.UDPDestination(v2net.LocalHostIP, v2net.Port(8388))
+	destination := v2net.UDPDestination(v2net.IPAddress([]byte{8, 8, 8, 8}), v2net.Port(53))
+
+	tracker.OnFinish(server, destination, 2)
+	tracker.OnFinish(server, destination, 0)
+	tracker.OnFinish(server, destination, 0)
+	assert.Int64(counters.Get("127.0.0.1:8388>>>answered").Value()).Equals(1)
+	assert.Int64(counters.Get("127.0.0.1:8388>>>silent").Value()).Equals(2)
+
+	// Silent associations are counted even if no response is expected.
+	NewUDPResponseTracker(nil, counters).OnFinish(server, destination, 0)
+	assert.Int64(counters.Get("127.0.0.1:8388>>>silent").Value()).Equals(3)
+}
@@ -0,0 +1,206 @@
+package shadowsocks
+
+import (
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+)
+
+const (
+	URIScheme = "ss"
+)
+
+var (
+	ErrInvalidURI = errors.New("Shadowsocks|URI: Invalid URI.")
+)
+
+// URI is a Shadowsocks server in the form of ss:// URI.
+type URI struct {
+	Address v2net.Address
+	Port    v2net.Port
+	Account *Account
+	Tag     string
+	// Region of the server, for pickers by geographic proximity, or empty if unknown.
+	Region string
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+func parseMethodPassword(userInfo string) (*Account, error) {
+	idx := strings.Index(userInfo, ":")
+	if idx == -1 {
+		return nil, ErrInvalidURI
+	}
+	cipherType, err := CipherTypeFromName(userInfo[:idx])
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		CipherType: cipherType,
+		Password:   userInfo[idx+1:],
+		Ota:        Account_Disabled,
+	}, nil
+}
+
+func parseHostPort(hostPort string) (v2net.Address, v2net.Port, error) {
+	host, rawPort, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return nil, 0, ErrInvalidURI
+	}
+	port, err := v2net.PortFromString(rawPort)
+	if err != nil || port == 0 {
+		return nil, 0, ErrInvalidURI
+	}
+	return v2net.ParseAddress(host), port, nil
+}
+
+// ParseURI parses a ss:// URI, in either SIP002 form (ss://base64(method:password)@host:port/?plugin=...#tag)
+// or legacy form (ss://base64(method:password@host:port)#tag). SIP002 form may also have "compression=1" in
+// its query, for a server that accepts compressed requests, "reuse=1" or "reuse=0" to always or never ask
+// the server for connection reuse, and "region=<name>" for the region of the server.
+func ParseURI(rawURI string) (*URI, error) {
+	rawURI = strings.TrimSpace(rawURI)
+	if !strings.HasPrefix(rawURI, URIScheme+"://") {
+		return nil, ErrInvalidURI
+	}
+
+	var tag string
+	if idx := strings.Index(rawURI, "#"); idx != -1 {
+		fragment, err := url.QueryUnescape(rawURI[idx+1:])
+		if err != nil {
+			return nil, ErrInvalidURI
+		}
+		tag = fragment
+		rawURI = rawURI[:idx]
+	}
+
+	body := rawURI[len(URIScheme)+3:]
+	if !strings.Contains(body, "@") {
+		decoded, err := decodeBase64(body)
+		if err != nil {
+			return nil, ErrInvalidURI
+		}
+		body = string(decoded)
+		idx := strings.LastIndex(body, "@")
+		if idx == -1 {
+			return nil, ErrInvalidURI
+		}
+		account, err := parseMethodPassword(body[:idx])
+		if err != nil {
+			return nil, err
+		}
+		address, port, err := parseHostPort(body[idx+1:])
+		if err != nil {
+			return nil, err
+		}
+		return &URI{
+			Address: address,
+			Port:    port,
+			Account: account,
+			Tag:     tag,
+		}, nil
+	}
+
+	u, err := url.Parse(rawURI)
+	if err != nil || u.User == nil {
+		return nil, ErrInvalidURI
+	}
+	userInfo := u.User.String()
+	if decoded, err := decodeBase64(userInfo); err == nil && strings.Contains(string(decoded), ":") {
+		userInfo = string(decoded)
+	} else if password, found := u.User.Password(); found {
+		userInfo = u.User.Username() + ":" + password
+	}
+	account, err := parseMethodPassword(userInfo)
+	if err != nil {
+		return nil, err
+	}
+	address, port, err := parseHostPort(u.Host)
+	if err != nil {
+		return nil, err
+	}
+	if plugin := u.Query().Get("plugin"); len(plugin) > 0 {
+		parts := strings.SplitN(plugin, ";", 2)
+		account.Plugin = parts[0]
+		if len(parts) > 1 {
+			account.PluginOpts = parts[1]
+		}
+	}
+	account.Compression = u.Query().Get("compression") == "1"
+	switch u.Query().Get("reuse") {
+	case "1":
+		account.ConnectionReuse = Account_ReuseEnabled
+	case "0":
+		account.ConnectionReuse = Account_ReuseDisabled
+	}
+	return &URI{
+		Address: address,
+		Port:    port,
+		Account: account,
+		Tag:     tag,
+		Region:  u.Query().Get("region"),
+	}, nil
+}
+
+// String returns this URI in SIP002 form.
+func (this *URI) String() string {
+	userInfo := this.Account.CipherType.Name() + ":" + this.Account.Password
+	u := &url.URL{
+		Scheme:   URIScheme,
+		User:     url.User(base64.RawURLEncoding.EncodeToString([]byte(userInfo))),
+		Host:     v2net.TCPDestination(this.Address, this.Port).NetAddr(),
+		Fragment: this.Tag,
+	}
+	query := url.Values{}
+	if len(this.Account.Plugin) > 0 {
+		plugin := this.Account.Plugin
+		if len(this.Account.PluginOpts) > 0 {
+			plugin += ";" + this.Account.PluginOpts
+		}
+		query.Set("plugin", plugin)
+	}
+	if this.Account.Compression {
+		query.Set("compression", "1")
+	}
+	switch this.Account.ConnectionReuse {
+	case Account_ReuseEnabled:
+		query.Set("reuse", "1")
+	case Account_ReuseDisabled:
+		query.Set("reuse", "0")
+	}
+	if len(this.Region) > 0 {
+		query.Set("region", this.Region)
+	}
+	if len(query) > 0 {
+		u.Path = "/"
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// ServerEndpoint converts this URI into a server record for ClientConfig.
+func (this *URI) ServerEndpoint() *protocol.ServerEndpoint {
+	return &protocol.ServerEndpoint{
+		Address: v2net.NewIPOrDomain(this.Address),
+		Port:    uint32(this.Port),
+		Region:  this.Region,
+		User: []*protocol.User{
+			{
+				Email:   this.Tag,
+				Account: loader.NewTypedSettings(this.Account),
+			},
+		},
+	}
+}
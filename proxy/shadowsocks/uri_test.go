@@ -0,0 +1,69 @@
+package shadowsocks_test
+
+import (
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestSIP002URIParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	uri, err := ParseURI("ss://YWVzLTEyOC1jZmI6dGVzdA@192.168.100.1:8888/?plugin=obfs-local%3Bobfs%3Dhttp%3Bobfs-host%3Dwww.bing.com#Example1")
+	assert.Error(err).IsNil()
+	assert.Address(uri.Address).Equals(v2net.ParseAddress("192.168.100.1"))
+	assert.Port(uri.Port).Equals(v2net.Port(8888))
+	assert.Bool(uri.Account.CipherType == CipherType_AES_128_CFB).IsTrue()
+	assert.String(uri.Account.Password).Equals("test")
+	assert.String(uri.Account.Plugin).Equals("obfs-local")
+	assert.String(uri.Account.PluginOpts).Equals("obfs=http;obfs-host=www.bing.com")
+	assert.String(uri.Tag).Equals("Example1")
+
+	reparsed, err := ParseURI(uri.String())
+	assert.Error(err).IsNil()
+	assert.String(reparsed.String()).Equals(uri.String())
+	assert.String(reparsed.Account.PluginOpts).Equals(uri.Account.PluginOpts)
+	assert.String(reparsed.Tag).Equals(uri.Tag)
+
+	uri, err = ParseURI("ss://YWVzLTEyOC1jZmI6dGVzdA@192.168.100.1:8888/?compression=1&reuse=1")
+	assert.Error(err).IsNil()
+	assert.Bool(uri.Account.Compression).IsTrue()
+	assert.Bool(uri.Account.ConnectionReuse == Account_ReuseEnabled).IsTrue()
+	reparsed, err = ParseURI(uri.String())
+	assert.Error(err).IsNil()
+	assert.Bool(reparsed.Account.Compression).IsTrue()
+	assert.Bool(reparsed.Account.ConnectionReuse == Account_ReuseEnabled).IsTrue()
+
+	uri, err = ParseURI("ss://YWVzLTEyOC1jZmI6dGVzdA@192.168.100.1:8888/?region=jp")
+	assert.Error(err).IsNil()
+	assert.String(uri.Region).Equals("jp")
+	assert.String(uri.ServerEndpoint().Region).Equals("jp")
+	reparsed, err = ParseURI(uri.String())
+	assert.Error(err).IsNil()
+	assert.String(reparsed.Region).Equals("jp")
+
+	uri, err = ParseURI("ss://YWVzLTEyOC1jZmI6dGVzdA@192.168.100.1:8888/?reuse=0")
+	assert.Error(err).IsNil()
+	assert.Bool(uri.Account.ConnectionReuse == Account_ReuseDisabled).IsTrue()
+	reparsed, err = ParseURI(uri.String())
+	assert.Error(err).IsNil()
+	assert.Bool(reparsed.Account.ConnectionReuse == Account_ReuseDisabled).IsTrue()
+}
+
+func TestLegacyURIParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	uri, err := ParseURI("ss://YWVzLTI1Ni1jZmI6cGFzczp3b3JkQDEyNy4wLjAuMTo4Mzg4#Legacy%20Server")
+	assert.Error(err).IsNil()
+	assert.Address(uri.Address).Equals(v2net.LocalHostIP)
+	assert.Port(uri.Port).Equals(v2net.Port(8388))
+	assert.Bool(uri.Account.CipherType == CipherType_AES_256_CFB).IsTrue()
+	assert.String(uri.Account.Password).Equals("pass:word")
+	assert.String(uri.Tag).Equals("Legacy Server")
+	assert.String(uri.String()).Equals("ss://YWVzLTI1Ni1jZmI6cGFzczp3b3Jk@127.0.0.1:8388#Legacy%20Server")
+
+	_, err = ParseURI("ss://YWVzLTI1Ni1jZmI6cGFzcw@127.0.0.1")
+	assert.Error(err).IsNotNil()
+}
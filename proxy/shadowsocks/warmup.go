@@ -0,0 +1,256 @@
+package shadowsocks
+
+import (
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/transport/internet"
+)
+
+const (
+	defaultWarmupIdleTimeout = 30 * time.Second
+)
+
+type warmConnection struct {
+	conn   internet.Connection
+	expire time.Time
+}
+
+// WarmupPool keeps connections open to a set of servers, to be taken by requests. Taken and expired
+// connections are replaced in background. A nil WarmupPool has no connection.
+type WarmupPool struct {
+	sync.Mutex
+	dial        func(server v2net.Destination) (internet.Connection, error)
+	size        int
+	idleTimeout time.Duration
+	servers     map[string]*protocol.ServerSpec
+	conns       map[string][]*warmConnection
+	filling     map[string]bool
+	closed      bool
+	done        chan bool
+}
+
+// NewWarmupPool creates a WarmupPool that opens connections by dial. It returns nil if warmup is not
+// enabled in config.
+func NewWarmupPool(config *WarmupConfig, dial func(server v2net.Destination) (internet.Connection, error)) *WarmupPool {
+	if config == nil || config.Connections == 0 {
+		return nil
+	}
+	pool := &WarmupPool{
+		dial:        dial,
+		size:        int(config.Connections),
+		idleTimeout: time.Duration(config.IdleTimeout) * time.Second,
+		servers:     make(map[string]*protocol.ServerSpec),
+		conns:       make(map[string][]*warmConnection),
+		filling:     make(map[string]bool),
+		done:        make(chan bool),
+	}
+	if pool.idleTimeout == 0 {
+		pool.idleTimeout = defaultWarmupIdleTimeout
+	}
+	return pool
+}
+
+// Start opens connections to each of servers, as far as their circuit breakers allow. It must be called only
+// once.
+func (this *WarmupPool) Start(servers []*protocol.ServerSpec) {
+	if this == nil {
+		return
+	}
+	this.Update(servers)
+	go this.refresh()
+}
+
+// Update replaces the servers to keep connections open to, e.g., after the server list is refreshed.
+// Connections to servers no longer in servers are closed.
+func (this *WarmupPool) Update(servers []*protocol.ServerSpec) {
+	if this == nil {
+		return
+	}
+	this.Lock()
+	this.servers = make(map[string]*protocol.ServerSpec, len(servers))
+	for _, server := range servers {
+		this.servers[server.Destination().NetAddr()] = server
+	}
+	for key, list := range this.conns {
+		if _, found := this.servers[key]; found {
+			continue
+		}
+		for _, warm := range list {
+			warm.conn.Close()
+		}
+		delete(this.conns, key)
+	}
+	this.Unlock()
+
+	this.fillAll()
+}
+
+// Get takes an open connection to server, or returns nil if there is none.
+func (this *WarmupPool) Get(server v2net.Destination) internet.Connection {
+	if this == nil {
+		return nil
+	}
+	key := server.NetAddr()
+	now := time.Now()
+
+	this.Lock()
+	defer this.Unlock()
+
+	if _, found := this.servers[key]; !found {
+		return nil
+	}
+	var conn internet.Connection
+	list := this.conns[key]
+	for len(list) > 0 && conn == nil {
+		if list[0].expire.After(now) {
+			conn = list[0].conn
+		} else {
+			list[0].conn.Close()
+		}
+		list[0] = nil
+		list = list[1:]
+	}
+	this.conns[key] = list
+	go this.fill(key)
+	return conn
+}
+
+// Close closes all open connections and stops opening new ones.
+func (this *WarmupPool) Close() {
+	if this == nil {
+		return
+	}
+	this.Lock()
+	defer this.Unlock()
+
+	if this.closed {
+		return
+	}
+	this.closed = true
+	close(this.done)
+	for key, list := range this.conns {
+		for _, warm := range list {
+			warm.conn.Close()
+		}
+		delete(this.conns, key)
+	}
+}
+
+func (this *WarmupPool) fillAll() {
+	this.Lock()
+	defer this.Unlock()
+
+	for key := range this.servers {
+		go this.fill(key)
+	}
+}
+
+// fill opens connections to the server of key, until there are enough of them, a dial fails or its circuit
+// breaker doesn't allow more. Results of dials are reported to the breaker.
+func (this *WarmupPool) fill(key string) {
+	this.Lock()
+	if this.closed || this.filling[key] {
+		this.Unlock()
+		return
+	}
+	this.filling[key] = true
+	this.Unlock()
+
+	defer func() {
+		this.Lock()
+		delete(this.filling, key)
+		this.Unlock()
+	}()
+
+	for {
+		this.Lock()
+		server, found := this.servers[key]
+		full := this.closed || !found || len(this.conns[key]) >= this.size
+		this.Unlock()
+		if full {
+			return
+		}
+
+		breaker := server.CircuitBreaker()
+		if !breaker.Allow() {
+			// Retried on next refresh.
+			return
+		}
+		conn, err := this.dial(server.Destination())
+		if err != nil {
+			// Retried on next refresh.
+			breaker.OnFailure()
+			log.Info("Shadowsocks|Client: Failed to warm up connection to ", server.Destination(), ": ", err)
+			return
+		}
+		breaker.OnSuccess()
+		conn.SetReusable(false)
+
+		this.Lock()
+		if _, found := this.servers[key]; this.closed || !found {
+			this.Unlock()
+			conn.Close()
+			return
+		}
+		this.conns[key] = append(this.conns[key], &warmConnection{
+			conn:   conn,
+			expire: time.Now().Add(this.idleTimeout),
+		})
+		this.Unlock()
+	}
+}
+
+// refresh replaces expired connections periodically, until the pool is closed.
+func (this *WarmupPool) refresh() {
+	ticker := time.NewTicker(this.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-this.done:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		this.Lock()
+		for key, list := range this.conns {
+			valid := list[:0]
+			for _, warm := range list {
+				if warm.expire.After(now) {
+					valid = append(valid, warm)
+				} else {
+					warm.conn.Close()
+				}
+			}
+			for i := len(valid); i < len(list); i++ {
+				list[i] = nil
+			}
+			this.conns[key] = valid
+		}
+		this.Unlock()
+		this.fillAll()
+	}
+}
+
+// warmupServers returns servers of the highest weight in serverList.
+func warmupServers(serverList *protocol.ServerList) []*protocol.ServerSpec {
+	var maxWeight uint32
+	var servers []*protocol.ServerSpec
+	for _, server := range serverList.Servers() {
+		weight := server.Weight()
+		if weight == 0 || weight < maxWeight {
+			continue
+		}
+		if weight > maxWeight {
+			maxWeight = weight
+			servers = servers[:0]
+		}
+		servers = append(servers, server)
+	}
+	return servers
+}
@@ -0,0 +1,175 @@
+package shadowsocks_test
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/shadowsocks"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
+)
+
+type warmConn struct {
+	net.Conn
+	closed *int32
+}
+
+func (this *warmConn) Close() error {
+	atomic.AddInt32(this.closed, 1)
+	return this.Conn.Close()
+}
+
+func (this *warmConn) Reusable() bool {
+	return false
+}
+
+func (this *warmConn) SetReusable(bool) {}
+
+func waitForCount(count *int32, expected int32) int {
+	for i := 0; i < 100 && atomic.LoadInt32(count) < expected; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return int(atomic.LoadInt32(count))
+}
+
+func TestWarmupPool(t *testing.T) {
+	assert := assert.On(t)
+
+	var dialed, closed int32
+	pool := NewWarmupPool(&WarmupConfig{Connections: 2}, func(server v2net.Destination) (internet.Connection, error) {
+		atomic.AddInt32(&dialed, 1)
+		conn, _ := net.Pipe()
+		return &warmConn{Conn: conn, closed: &closed}, nil
+	})
+	server := v2net.TCPDestination(v2net.LocalHostIP, 8388)
+	pool.Start([]*protocol.ServerSpec{protocol.NewServerSpec(server, protocol.AlwaysValid())})
+	assert.Int(waitForCount(&dialed, 2)).Equals(2)
+
+	// Taken connections are replaced.
+	conn := pool.Get(server)
+	assert.Pointer(conn).IsNotNil()
+	assert.Int(waitForCount(&dialed, 3)).Equals(3)
+
+	assert.Pointer(pool.Get(v2net.TCPDestination(v2net.LocalHostIP, 8389))).IsNil()
+
+	pool.Close()
+	assert.Int(int(atomic.LoadInt32(&closed))).Equals(2)
+	conn.Close()
+}
+
+func TestWarmupPoolUpdate(t *testing.T) {
+	assert := assert.On(t)
+
+	var dialed, closed int32
+	pool := NewWarmupPool(&WarmupConfig{Connections: 1}, func(server v2net.Destination) (internet.Connection, error) {
+		atomic.AddInt32(&dialed, 1)
+		conn, _ := net.Pipe()
+		return &warmConn{Conn: conn, closed: &closed}, nil
+	})
+	defer pool.Close()
+	removed := v2net.TCPDestination(v2net.LocalHostIP, 8388)
+	pool.Start([]*protocol.ServerSpec{protocol.NewServerSpec(removed, protocol.AlwaysValid())})
+	assert.Int(waitForCount(&dialed, 1)).Equals(1)
+
+	// Servers from a refresh are warmed up, and connections to servers gone with it are closed.
+	added := v2net.TCPDestination(v2net.LocalHostIP, 8389)
+	pool.Update([]*protocol.ServerSpec{protocol.NewServerSpec(added, protocol.AlwaysValid())})
+	assert.Int(waitForCount(&dialed, 2)).Equals(2)
+	assert.Int(int(atomic.LoadInt32(&closed))).Equals(1)
+	assert.Pointer(pool.Get(removed)).IsNil()
+	conn := pool.Get(added)
+	assert.Pointer(conn).IsNotNil()
+	conn.Close()
+}
+
+func TestWarmupPoolDisabled(t *testing.T) {
+	assert := assert.On(t)
+
+	pool := NewWarmupPool(&WarmupConfig{}, nil)
+	assert.Pointer(pool.Get(v2net.TCPDestination(v2net.LocalHostIP, 8388))).IsNil()
+	pool.Close()
+}
+
+// listenEcho accepts Shadowsocks connections on port until the listener is closed, and echoes the first
+// payload of each of them. Accepted connections are counted in accepted.
+func listenEcho(assert *assert.Assert, port int, accepted *int32) *net.TCPListener {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	assert.Error(err).IsNil()
+	go func() {
+		for {
+			conn, err := listener.AcceptTCP()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(accepted, 1)
+			go func() {
+				defer conn.Close()
+				request, reader, err := ReadTCPSession(newTestUser(), conn)
+				if err != nil {
+					return
+				}
+				payload, err := reader.Read()
+				if err != nil {
+					return
+				}
+				writer, err := WriteTCPResponse(request, conn)
+				if err != nil {
+					return
+				}
+				writer.Write(payload)
+			}()
+		}
+	}()
+	return listener
+}
+
+// echoThrough sends payload through client, and returns the error of the dispatch once the echo is back.
+func echoThrough(assert *assert.Assert, client proxy.OutboundHandler, payload string) error {
+	traffic := ray.NewRay()
+	result := dispatch(client, payload, traffic)
+	buffer, err := traffic.InboundOutput().Read()
+	if err == nil {
+		assert.String(string(buffer.Value)).Equals(payload)
+		buffer.Release()
+	}
+	traffic.InboundInput().Close()
+	return waitForDispatch(assert, result)
+}
+
+func TestClientWarmupWithCircuitBreaker(t *testing.T) {
+	assert := assert.On(t)
+
+	var accepted int32
+	listener := listenEcho(assert, 0, &accepted)
+	port := listener.Addr().(*net.TCPAddr).Port
+	client := newTestClientWithConfig(assert, &ClientConfig{
+		Server: []*protocol.ServerEndpoint{
+			{
+				Address: v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:    uint32(port),
+				User:    []*protocol.User{newTestUser()},
+			},
+		},
+		Warmup:         &WarmupConfig{Connections: 2},
+		CircuitBreaker: &protocol.CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: 1},
+	})
+	defer proxy.CloseOutboundHandler(client)
+	assert.Int(waitForCount(&accepted, 2)).Equals(2)
+
+	// Replacing the taken connection fails, which opens the circuit.
+	listener.Close()
+	assert.Error(echoThrough(assert, client, "first")).IsNil()
+
+	// The remaining warm connection takes the probe of the half-open circuit, and closes it.
+	listener = listenEcho(assert, port, &accepted)
+	defer listener.Close()
+	time.Sleep(1100 * time.Millisecond)
+	assert.Error(echoThrough(assert, client, "second")).IsNil()
+	assert.Error(echoThrough(assert, client, "third")).IsNil()
+}
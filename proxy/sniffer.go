@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"bytes"
+	"net"
+	"strings"
+
+	"v2ray.com/core/common/serial"
+)
+
+const (
+	// Protocol of sessions that start with a TLS ClientHello.
+	ProtocolTLS = "tls"
+)
+
+// SniffProtocol returns the application protocol of a session by its first payload, or empty if the
+// protocol is unknown.
+func SniffProtocol(payload []byte) string {
+	// Record of handshake, in TLS 1.0 to 1.3, with a ClientHello.
+	if len(payload) >= 6 && payload[0] == 0x16 && payload[1] == 0x03 && payload[2] <= 0x04 && payload[5] == 0x01 {
+		return ProtocolTLS
+	}
+	return ""
+}
+
+var (
+	httpMethods = []string{"GET ", "POST ", "HEAD ", "PUT ", "DELETE ", "OPTIONS ", "PATCH ", "CONNECT ", "TRACE "}
+)
+
+// SniffDomain returns the domain in HTTP Host header or TLS SNI of the first payload of a connection. It
+// returns false if the payload is neither a HTTP request nor a TLS ClientHello with a domain.
+func SniffDomain(payload []byte) (string, bool) {
+	domain, found := sniffHTTPHost(payload)
+	if !found {
+		domain, found = sniffTLSServerName(payload)
+	}
+	if !found || len(domain) == 0 || net.ParseIP(domain) != nil {
+		return "", false
+	}
+	return strings.ToLower(domain), true
+}
+
+func sniffHTTPHost(payload []byte) (string, bool) {
+	isHTTP := false
+	for _, method := range httpMethods {
+		if bytes.HasPrefix(payload, []byte(method)) {
+			isHTTP = true
+			break
+		}
+	}
+	if !isHTTP {
+		return "", false
+	}
+
+	lines := strings.Split(string(payload), "\r\n")
+	// The first line is the request line.
+	for _, line := range lines[1:] {
+		if len(line) == 0 {
+			break
+		}
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 || !strings.EqualFold(line[:colon], "Host") {
+			continue
+		}
+		host := strings.TrimSpace(line[colon+1:])
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		return host, true
+	}
+	return "", false
+}
+
+func sniffTLSServerName(payload []byte) (string, bool) {
+	// Record header: type, version and length.
+	if len(payload) < 5 || payload[0] != 0x16 || payload[1] != 0x03 {
+		return "", false
+	}
+	record := payload[5:]
+	if length := int(serial.BytesToUint16(payload[3:5])); length < len(record) {
+		record = record[:length]
+	}
+
+	// Handshake header: type and length, then version and random of ClientHello.
+	if len(record) < 38 || record[0] != 0x01 {
+		return "", false
+	}
+	hello := record[38:]
+
+	// Session ID, cipher suites and compression methods.
+	for _, lengthSize := range []int{1, 2, 1} {
+		if len(hello) < lengthSize {
+			return "", false
+		}
+		var length int
+		if lengthSize == 1 {
+			length = int(hello[0])
+		} else {
+			length = int(serial.BytesToUint16(hello[:2]))
+		}
+		if len(hello) < lengthSize+length {
+			return "", false
+		}
+		hello = hello[lengthSize+length:]
+	}
+
+	if len(hello) < 2 {
+		return "", false
+	}
+	extensions := hello[2:]
+	if length := int(serial.BytesToUint16(hello[:2])); length < len(extensions) {
+		extensions = extensions[:length]
+	}
+	for len(extensions) >= 4 {
+		extType := serial.BytesToUint16(extensions[:2])
+		length := int(serial.BytesToUint16(extensions[2:4]))
+		if len(extensions) < 4+length {
+			return "", false
+		}
+		data := extensions[4 : 4+length]
+		extensions = extensions[4+length:]
+		if extType != 0x0000 {
+			continue
+		}
+
+		// Server name list, in which only host names are defined.
+		if len(data) < 2 {
+			return "", false
+		}
+		data = data[2:]
+		for len(data) >= 3 {
+			nameType := data[0]
+			nameLength := int(serial.BytesToUint16(data[1:3]))
+			if len(data) < 3+nameLength {
+				return "", false
+			}
+			if nameType == 0x00 {
+				return string(data[3 : 3+nameLength]), true
+			}
+			data = data[3+nameLength:]
+		}
+		return "", false
+	}
+	return "", false
+}
@@ -0,0 +1,58 @@
+package proxy_test
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+
+	. "v2ray.com/core/proxy"
+	"v2ray.com/core/testing/assert"
+)
+
+func TestSniffProtocol(t *testing.T) {
+	assert := assert.On(t)
+
+	clientHello := []byte{0x16, 0x03, 0x01, 0x02, 0x00, 0x01, 0x00, 0x01, 0xfc, 0x03, 0x03}
+	assert.String(SniffProtocol(clientHello)).Equals(ProtocolTLS)
+
+	serverHello := []byte{0x16, 0x03, 0x03, 0x00, 0x5a, 0x02, 0x00, 0x00, 0x56, 0x03, 0x03}
+	assert.String(SniffProtocol(serverHello)).Equals("")
+	assert.String(SniffProtocol([]byte("GET / HTTP/1.1\r\n"))).Equals("")
+	assert.String(SniffProtocol([]byte{0x16, 0x03})).Equals("")
+}
+
+func TestSniffHTTPHost(t *testing.T) {
+	assert := assert.On(t)
+
+	domain, found := SniffDomain([]byte("GET / HTTP/1.1\r\nUser-Agent: test\r\nhost: www.V2Ray.com:8080\r\n\r\n"))
+	assert.Bool(found).IsTrue()
+	assert.String(domain).Equals("www.v2ray.com")
+
+	_, found = SniffDomain([]byte("GET / HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n"))
+	assert.Bool(found).IsFalse()
+
+	_, found = SniffDomain([]byte("SSH-2.0-OpenSSH_7.2\r\n"))
+	assert.Bool(found).IsFalse()
+}
+
+func TestSniffTLSServerName(t *testing.T) {
+	assert := assert.On(t)
+
+	client, server := net.Pipe()
+	go func() {
+		tls.Client(client, &tls.Config{ServerName: "www.v2ray.com"}).Handshake()
+	}()
+	defer client.Close()
+
+	payload := make([]byte, 8192)
+	nBytes, err := server.Read(payload)
+	assert.Error(err).IsNil()
+	server.Close()
+
+	domain, found := SniffDomain(payload[:nBytes])
+	assert.Bool(found).IsTrue()
+	assert.String(domain).Equals("www.v2ray.com")
+
+	_, found = SniffDomain(payload[:40])
+	assert.Bool(found).IsFalse()
+}
@@ -3,6 +3,7 @@ package socks
 import (
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
 
 	"github.com/golang/protobuf/ptypes"
 	google_protobuf "github.com/golang/protobuf/ptypes/any"
@@ -38,6 +39,26 @@ func (this *ServerConfig) HasAccount(username, password string) bool {
 	return storedPassed == password
 }
 
+// BuildAuthenticator returns the authenticator for password authentication. It is the registered
+// authenticator if one is named, or the list of accounts otherwise.
+func (this *ServerConfig) BuildAuthenticator() (proxy.Authenticator, error) {
+	if len(this.Authenticator) > 0 {
+		return proxy.GetAuthenticator(this.Authenticator)
+	}
+	return proxy.NewStaticAuthenticator(this.Accounts), nil
+}
+
+const (
+	defaultMaxUDPAssociations = 64
+)
+
+func (this *ServerConfig) GetEffectiveMaxUDPAssociations() int {
+	if this.MaxUdpAssociations == 0 {
+		return defaultMaxUDPAssociations
+	}
+	return int(this.MaxUdpAssociations)
+}
+
 func (this *ServerConfig) GetNetAddress() v2net.Address {
 	if this.Address == nil {
 		return v2net.LocalHostIP
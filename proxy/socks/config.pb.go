@@ -20,6 +20,7 @@ import fmt "fmt"
 import math "math"
 import v2ray_core_common_net "v2ray.com/core/common/net"
 import v2ray_core_common_protocol1 "v2ray.com/core/common/protocol"
+import v2ray_core_proxy "v2ray.com/core/proxy"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -69,6 +70,18 @@ type ServerConfig struct {
 	Address    *v2ray_core_common_net.IPOrDomain `protobuf:"bytes,3,opt,name=address" json:"address,omitempty"`
 	UdpEnabled bool                              `protobuf:"varint,4,opt,name=udp_enabled,json=udpEnabled" json:"udp_enabled,omitempty"`
 	Timeout    uint32                            `protobuf:"varint,5,opt,name=timeout" json:"timeout,omitempty"`
+	// Name of a registered authenticator for password authentication. Accounts are used if not set.
+	Authenticator string `protobuf:"bytes,6,opt,name=authenticator" json:"authenticator,omitempty"`
+	// Seconds to keep the UDP relay for a client after its last UDP ASSOCIATE connection closes. A new
+	// association from the same client within the period keeps using the relay. The relay is torn down
+	// immediately if it is 0.
+	UdpGracePeriod uint32 `protobuf:"varint,7,opt,name=udp_grace_period,json=udpGracePeriod" json:"udp_grace_period,omitempty"`
+	// Maximum number of UDP ASSOCIATE connections from the same client IP at the same time. Requests over
+	// the limit are rejected. 64 if 0.
+	MaxUdpAssociations uint32 `protobuf:"varint,8,opt,name=max_udp_associations,json=maxUdpAssociations" json:"max_udp_associations,omitempty"`
+	// Outbound selection by trusted clients, or nil if clients can't select the outbound. Clients of
+	// inbounds without authentication select it through password authentication, whose password is ignored.
+	OutboundSelection *v2ray_core_proxy.OutboundSelectionConfig `protobuf:"bytes,9,opt,name=outbound_selection,json=outboundSelection" json:"outbound_selection,omitempty"`
 }
 
 func (m *ServerConfig) Reset()                    { *m = ServerConfig{} }
@@ -90,6 +103,13 @@ func (m *ServerConfig) GetAddress() *v2ray_core_common_net.IPOrDomain {
 	return nil
 }
 
+func (m *ServerConfig) GetOutboundSelection() *v2ray_core_proxy.OutboundSelectionConfig {
+	if m != nil {
+		return m.OutboundSelection
+	}
+	return nil
+}
+
 type ClientConfig struct {
 	Server []*v2ray_core_common_protocol1.ServerEndpoint `protobuf:"bytes,1,rep,name=server" json:"server,omitempty"`
 }
@@ -116,33 +136,40 @@ func init() {
 func init() { proto.RegisterFile("v2ray.com/core/proxy/socks/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 435 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x91, 0x51, 0x6f, 0xd3, 0x30,
-	0x10, 0xc7, 0x49, 0x4b, 0xd7, 0xec, 0xd2, 0xa1, 0xca, 0x42, 0x28, 0xca, 0x0b, 0xa1, 0x12, 0x22,
-	0xda, 0x83, 0x33, 0x85, 0x17, 0xc4, 0xc4, 0x43, 0xba, 0x55, 0x82, 0x97, 0xb5, 0x72, 0x87, 0x90,
-	0x78, 0xa9, 0x3c, 0xc7, 0xb0, 0x68, 0x8d, 0x6d, 0xd9, 0x4e, 0x21, 0x5f, 0x93, 0x4f, 0x84, 0x1a,
-	0x27, 0xd3, 0x40, 0xed, 0x9b, 0xcf, 0x77, 0xf7, 0xbf, 0xfb, 0xff, 0x0e, 0xde, 0xed, 0x32, 0x4d,
-	0x1b, 0xcc, 0x64, 0x95, 0x32, 0xa9, 0x79, 0xaa, 0xb4, 0xfc, 0xdd, 0xa4, 0x46, 0xb2, 0x07, 0x93,
-	0x32, 0x29, 0x7e, 0x94, 0x3f, 0xb1, 0xd2, 0xd2, 0x4a, 0xf4, 0xaa, 0x2f, 0xd4, 0x1c, 0xb7, 0x45,
-	0xb8, 0x2d, 0x8a, 0xfe, 0x17, 0x60, 0xb2, 0xaa, 0xa4, 0x48, 0x05, 0xb7, 0x29, 0x2d, 0x0a, 0xcd,
-	0x8d, 0x71, 0x02, 0xd1, 0xc5, 0xe1, 0xc2, 0x36, 0xc9, 0xe4, 0x36, 0x35, 0x5c, 0xef, 0xb8, 0xde,
-	0x18, 0xc5, 0x99, 0xeb, 0x98, 0xe5, 0x30, 0xce, 0x19, 0x93, 0xb5, 0xb0, 0x28, 0x02, 0xbf, 0x36,
-	0x5c, 0x0b, 0x5a, 0xf1, 0xd0, 0x8b, 0xbd, 0xe4, 0x94, 0x3c, 0xc6, 0xfb, 0x9c, 0xa2, 0xc6, 0xfc,
-	0x92, 0xba, 0x08, 0x07, 0x2e, 0xd7, 0xc7, 0xb3, 0x3f, 0x03, 0x98, 0xac, 0x5b, 0xe1, 0xab, 0xd6,
-	0x0c, 0xfa, 0x04, 0xa7, 0xb4, 0xb6, 0xf7, 0x1b, 0xdb, 0x28, 0xa7, 0xf4, 0x22, 0x8b, 0xf1, 0x61,
-	0x6b, 0x38, 0xaf, 0xed, 0xfd, 0x6d, 0xa3, 0x38, 0xf1, 0x69, 0xf7, 0x42, 0x37, 0xe0, 0x53, 0xb7,
-	0x92, 0x09, 0x07, 0xf1, 0x30, 0x09, 0xb2, 0xec, 0x58, 0xf7, 0xd3, 0xb1, 0xb8, 0xf3, 0x61, 0x16,
-	0xc2, 0xea, 0x86, 0x3c, 0x6a, 0xa0, 0x4b, 0x18, 0x77, 0x94, 0xc2, 0x61, 0xec, 0x25, 0x41, 0xf6,
-	0xe6, 0xa9, 0x9c, 0x43, 0x84, 0x05, 0xb7, 0xf8, 0xcb, 0x6a, 0xa9, 0xaf, 0x65, 0x45, 0x4b, 0x41,
-	0xfa, 0x0e, 0xf4, 0x1a, 0x82, 0xba, 0x50, 0x1b, 0x2e, 0xe8, 0xdd, 0x96, 0x17, 0xe1, 0xf3, 0xd8,
-	0x4b, 0x7c, 0x02, 0x75, 0xa1, 0x16, 0xee, 0x07, 0x85, 0x30, 0xb6, 0x65, 0xc5, 0x65, 0x6d, 0xc3,
-	0x51, 0xec, 0x25, 0x67, 0xa4, 0x0f, 0xa3, 0x4b, 0x38, 0xfb, 0x67, 0x25, 0x34, 0x85, 0xe1, 0x03,
-	0x6f, 0x3a, 0xb6, 0xfb, 0x27, 0x7a, 0x09, 0xa3, 0x1d, 0xdd, 0xd6, 0xbc, 0x63, 0xea, 0x82, 0x8f,
-	0x83, 0x0f, 0xde, 0x8c, 0xc0, 0xe4, 0x6a, 0x5b, 0x72, 0x61, 0x3b, 0xa6, 0x73, 0x38, 0x71, 0xc7,
-	0x0b, 0xbd, 0x16, 0xc9, 0xf9, 0x01, 0x0f, 0xfd, 0x99, 0x3b, 0x2c, 0x0b, 0x51, 0x28, 0x59, 0x0a,
-	0x4b, 0xba, 0xce, 0xf3, 0xb7, 0xe0, 0xf7, 0xb8, 0x51, 0x00, 0xe3, 0x9b, 0xe5, 0x26, 0xff, 0x7a,
-	0xfb, 0x79, 0xfa, 0x0c, 0x4d, 0xc0, 0x5f, 0xe5, 0xeb, 0xf5, 0xb7, 0x25, 0xb9, 0x9e, 0x7a, 0xf3,
-	0x0b, 0x88, 0x98, 0xac, 0x8e, 0x20, 0x9f, 0x07, 0x6e, 0xa1, 0xd5, 0x7e, 0xd6, 0xf7, 0x51, 0xfb,
-	0x77, 0x77, 0xd2, 0x4e, 0x7e, 0xff, 0x37, 0x00, 0x00, 0xff, 0xff, 0x3f, 0xcd, 0xac, 0xc7, 0xe9,
-	0x02, 0x00, 0x00,
+	// 545 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x52, 0x41, 0x6f, 0xda, 0x30,
+	0x14, 0x5e, 0xa0, 0x40, 0x30, 0x50, 0x31, 0xab, 0x9a, 0x2c, 0x2e, 0x4b, 0xd1, 0xa6, 0x65, 0x3d,
+	0x24, 0x88, 0x5d, 0xa6, 0x55, 0x3b, 0x84, 0x16, 0x6d, 0xbb, 0x14, 0x14, 0x5a, 0x6d, 0xda, 0x25,
+	0x32, 0x8e, 0xd7, 0x46, 0x25, 0xb6, 0x65, 0x3b, 0x8c, 0xfc, 0xa9, 0xfd, 0xc6, 0x29, 0x4e, 0x52,
+	0xd1, 0x96, 0xde, 0xfc, 0xde, 0xfb, 0xde, 0xf7, 0x9e, 0xbf, 0xf7, 0x81, 0x0f, 0xdb, 0xa9, 0xc4,
+	0xb9, 0x47, 0x78, 0xea, 0x13, 0x2e, 0xa9, 0x2f, 0x24, 0xdf, 0xe5, 0xbe, 0xe2, 0xe4, 0x5e, 0xf9,
+	0x84, 0xb3, 0x3f, 0xc9, 0xad, 0x27, 0x24, 0xd7, 0x1c, 0xbe, 0xa9, 0x81, 0x92, 0x7a, 0x06, 0xe4,
+	0x19, 0xd0, 0xe8, 0x29, 0x01, 0xe1, 0x69, 0xca, 0x99, 0xcf, 0xa8, 0xf6, 0x71, 0x1c, 0x4b, 0xaa,
+	0x54, 0x49, 0x30, 0x9a, 0x1c, 0x06, 0x9a, 0x22, 0xe1, 0x1b, 0x5f, 0x51, 0xb9, 0xa5, 0x32, 0x52,
+	0x82, 0x92, 0xaa, 0xe3, 0xf4, 0xe0, 0x6e, 0xfb, 0x5b, 0x8d, 0x03, 0xd0, 0x09, 0x08, 0xe1, 0x19,
+	0xd3, 0x70, 0x04, 0xec, 0x4c, 0x51, 0xc9, 0x70, 0x4a, 0x91, 0xe5, 0x58, 0x6e, 0x37, 0x7c, 0x88,
+	0x8b, 0x9a, 0xc0, 0x4a, 0xfd, 0xe5, 0x32, 0x46, 0x8d, 0xb2, 0x56, 0xc7, 0xe3, 0x7f, 0x47, 0xa0,
+	0xbf, 0x32, 0xb3, 0x2f, 0x0c, 0x33, 0xfc, 0x0a, 0xba, 0x38, 0xd3, 0x77, 0x91, 0xce, 0x45, 0xc9,
+	0x74, 0x3c, 0x75, 0xbc, 0xc3, 0xbf, 0xf7, 0x82, 0x4c, 0xdf, 0x5d, 0xe7, 0x82, 0x86, 0x36, 0xae,
+	0x5e, 0xf0, 0x0a, 0xd8, 0xb8, 0x5c, 0x49, 0xa1, 0x86, 0xd3, 0x74, 0x7b, 0xd3, 0xe9, 0x4b, 0xdd,
+	0xfb, 0x63, 0xbd, 0xea, 0x1f, 0x6a, 0xce, 0xb4, 0xcc, 0xc3, 0x07, 0x0e, 0x78, 0x0e, 0x3a, 0x95,
+	0x90, 0xa8, 0xe9, 0x58, 0x6e, 0x6f, 0x7a, 0xba, 0x4f, 0x57, 0xaa, 0xe8, 0x31, 0xaa, 0xbd, 0x1f,
+	0xcb, 0x85, 0xbc, 0xe4, 0x29, 0x4e, 0x58, 0x58, 0x77, 0xc0, 0xb7, 0xa0, 0x97, 0xc5, 0x22, 0xa2,
+	0x0c, 0xaf, 0x37, 0x34, 0x46, 0x47, 0x8e, 0xe5, 0xda, 0x21, 0xc8, 0x62, 0x31, 0x2f, 0x33, 0x10,
+	0x81, 0x8e, 0x4e, 0x52, 0xca, 0x33, 0x8d, 0x5a, 0x8e, 0xe5, 0x0e, 0xc2, 0x3a, 0x84, 0xef, 0xc0,
+	0xa0, 0xf8, 0x13, 0x65, 0x3a, 0x21, 0x58, 0x73, 0x89, 0xda, 0x46, 0xb8, 0xc7, 0x49, 0xe8, 0x82,
+	0x61, 0x31, 0xe0, 0x56, 0x62, 0x42, 0x23, 0x41, 0x65, 0xc2, 0x63, 0xd4, 0x31, 0x44, 0xc7, 0x59,
+	0x2c, 0xbe, 0x15, 0xe9, 0xa5, 0xc9, 0xc2, 0x09, 0x38, 0x49, 0xf1, 0x2e, 0x2a, 0xd0, 0x58, 0x29,
+	0x4e, 0x12, 0xac, 0x13, 0xce, 0x14, 0xb2, 0x0d, 0x1a, 0xa6, 0x78, 0x77, 0x13, 0x8b, 0x60, 0xaf,
+	0x02, 0x7f, 0x01, 0xc8, 0x33, 0xbd, 0xe6, 0x19, 0x8b, 0x23, 0x45, 0x37, 0x94, 0x14, 0x69, 0xd4,
+	0x35, 0x22, 0x7c, 0x7c, 0xae, 0xe9, 0xa2, 0xc2, 0xae, 0x6a, 0x68, 0x29, 0x6c, 0xf8, 0x9a, 0x3f,
+	0x2d, 0x8c, 0xce, 0xc1, 0xe0, 0x91, 0xdc, 0x70, 0x08, 0x9a, 0xf7, 0x34, 0xaf, 0x7c, 0x53, 0x3c,
+	0xe1, 0x09, 0x68, 0x6d, 0xf1, 0x26, 0xa3, 0x95, 0x5f, 0xca, 0xe0, 0x4b, 0xe3, 0xb3, 0x35, 0x0e,
+	0x41, 0xff, 0x62, 0x93, 0x50, 0xa6, 0x2b, 0xbf, 0xcc, 0x40, 0xbb, 0xf4, 0x2e, 0xb2, 0xcc, 0xb9,
+	0xcf, 0x0e, 0xdc, 0xa7, 0x76, 0x79, 0x75, 0xf2, 0x39, 0x8b, 0x05, 0x4f, 0x98, 0x0e, 0xab, 0xce,
+	0xb3, 0xf7, 0xc0, 0xae, 0xad, 0x04, 0x7b, 0xa0, 0x73, 0xb5, 0x88, 0x82, 0x9b, 0xeb, 0xef, 0xc3,
+	0x57, 0xb0, 0x0f, 0xec, 0x65, 0xb0, 0x5a, 0xfd, 0x5c, 0x84, 0x97, 0x43, 0x6b, 0x36, 0x01, 0x23,
+	0xc2, 0xd3, 0x17, 0xec, 0x34, 0xeb, 0x95, 0x0b, 0x2d, 0x8b, 0x59, 0xbf, 0x5b, 0x26, 0xb7, 0x6e,
+	0x9b, 0xc9, 0x9f, 0xfe, 0x07, 0x00, 0x00, 0xff, 0xff, 0x4c, 0xf9, 0x07, 0x03, 0xe8, 0x03, 0x00,
+	0x00,
 }
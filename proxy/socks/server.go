@@ -3,25 +3,29 @@ package socks
 import (
 	"errors"
 	"io"
+	"net"
 	"sync"
-	"time"
 
 	"v2ray.com/core/app"
+	"v2ray.com/core/app/api"
 	"v2ray.com/core/app/dispatcher"
 	v2io "v2ray.com/core/common/io"
 	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/common/stats"
 	"v2ray.com/core/proxy"
 	"v2ray.com/core/proxy/registry"
 	"v2ray.com/core/proxy/socks/protocol"
 	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/internet/udp"
+	"v2ray.com/core/transport/ray"
 )
 
 var (
 	ErrUnsupportedSocksCommand = errors.New("Unsupported socks command.")
 	ErrUnsupportedAuthMethod   = errors.New("Unsupported auth method.")
+	ErrTooManyUDPAssociations  = errors.New("Too many UDP associations.")
 )
 
 // Server is a SOCKS 5 proxy server
@@ -30,19 +34,29 @@ type Server struct {
 	udpMutex         sync.RWMutex
 	accepting        bool
 	packetDispatcher dispatcher.PacketDispatcher
+	authenticator    proxy.Authenticator
+	selector         *proxy.OutboundSelector
 	config           *ServerConfig
 	tcpListener      *internet.TCPHub
 	udpHub           *udp.UDPHub
 	udpAddress       v2net.Destination
 	udpServer        *udp.UDPServer
 	meta             *proxy.InboundHandlerMeta
+
+	associationMutex sync.Mutex
+	associations     map[string]*udpAssociation
+	// Number of UDP ASSOCIATE requests rejected for the limit, by client IP, for a bounded number of clients.
+	rejectedAssociations *stats.CounterSet
 }
 
 // NewServer creates a new Server object.
 func NewServer(config *ServerConfig, space app.Space, meta *proxy.InboundHandlerMeta) *Server {
 	s := &Server{
-		config: config,
-		meta:   meta,
+		config:               config,
+		meta:                 meta,
+		selector:             proxy.NewOutboundSelector(config.OutboundSelection),
+		associations:         make(map[string]*udpAssociation),
+		rejectedAssociations: stats.NewCounterSet(),
 	}
 	space.InitializeApplication(func() error {
 		if !space.HasApp(dispatcher.APP_ID) {
@@ -50,6 +64,18 @@ func NewServer(config *ServerConfig, space app.Space, meta *proxy.InboundHandler
 			return app.ErrMissingApplication
 		}
 		s.packetDispatcher = space.GetApp(dispatcher.APP_ID).(dispatcher.PacketDispatcher)
+		if config.AuthType == AuthType_PASSWORD {
+			authenticator, err := config.BuildAuthenticator()
+			if err != nil {
+				log.Error("Socks|Server: Failed to find authenticator ", config.Authenticator, ": ", err)
+				return err
+			}
+			s.authenticator = authenticator
+		}
+		if len(meta.Tag) > 0 && space.HasApp(api.APP_ID) {
+			apiServer := space.GetApp(api.APP_ID).(*api.ApiServer)
+			apiServer.Handle("/inbound/"+meta.Tag+"/udp-rejected", api.NewCounterHandler(s.rejectedAssociations))
+		}
 		return nil
 	})
 	return s
@@ -132,6 +158,9 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 	expectedAuthMethod := protocol.AuthNotRequired
 	if this.config.AuthType == AuthType_PASSWORD {
 		expectedAuthMethod = protocol.AuthUserPass
+	} else if this.selector != nil && auth.HasAuthMethod(protocol.AuthUserPass) {
+		// Clients select the outbound through usernames.
+		expectedAuthMethod = protocol.AuthUserPass
 	}
 
 	if !auth.HasAuthMethod(expectedAuthMethod) {
@@ -153,15 +182,28 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 		log.Error("Socks: failed to write authentication: ", err)
 		return err
 	}
-	if this.config.AuthType == AuthType_PASSWORD {
+	session := &proxy.SessionInfo{
+		Source:  clientAddr,
+		Inbound: this.meta,
+	}
+	if expectedAuthMethod == protocol.AuthUserPass {
 		upRequest, err := protocol.ReadUserPassRequest(reader)
 		if err != nil {
 			log.Warning("Socks: failed to read username and password: ", err)
 			return err
 		}
 		status := byte(0)
-		if !this.config.HasAccount(upRequest.Username(), upRequest.Password()) {
-			status = byte(0xFF)
+		tag, username := this.selector.Select(clientAddr, upRequest.Username())
+		session.OutboundTag = tag
+		if this.config.AuthType == AuthType_PASSWORD {
+			user, err := this.authenticator.Authenticate(username, upRequest.Password())
+			if err != nil {
+				if err != proxy.ErrInvalidAuthentication {
+					log.Warning("Socks: Failed to authenticate user: ", err)
+				}
+				status = byte(0xFF)
+			}
+			session.User = user
 		}
 		upResponse := protocol.NewSocks5UserPassResponse(status)
 		err = protocol.WriteUserPassResponse(writer, upResponse)
@@ -172,7 +214,7 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 		}
 		if status != byte(0) {
 			log.Warning("Socks: Invalid user account: ", upRequest.AuthDetail())
-			log.Access(clientAddr, "", log.AccessRejected, proxy.ErrInvalidAuthentication)
+			log.AccessWithTags(clientAddr, "", log.AccessRejected, proxy.ErrInvalidAuthentication, this.meta.ConnectionTags)
 			return proxy.ErrInvalidAuthentication
 		}
 	}
@@ -184,7 +226,7 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 	}
 
 	if request.Command == protocol.CmdUdpAssociate && this.config.UdpEnabled {
-		return this.handleUDP(reader, writer)
+		return this.handleUDP(clientAddr, reader, writer)
 	}
 
 	if request.Command == protocol.CmdBind || request.Command == protocol.CmdUdpAssociate {
@@ -203,6 +245,10 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 		return ErrUnsupportedSocksCommand
 	}
 
+	dest := request.Destination()
+	session.Destination = dest
+	log.Info("Socks: TCP Connect request to ", dest)
+
 	response := protocol.NewSocks5Response()
 	response.Error = protocol.ErrorSuccess
 
@@ -210,29 +256,61 @@ func (this *Server) handleSocks5(clientAddr v2net.Destination, reader *v2io.Buff
 	response.Port = v2net.Port(1717)
 	response.SetIPv4([]byte{0, 0, 0, 0})
 
-	response.Write(writer)
+	ray, err := this.dispatch(session)
 	if err != nil {
-		log.Error("Socks: failed to write response: ", err)
+		response.Error = socks5ReplyCode(proxy.GetFailureReason(err))
+		response.Write(writer)
+		writer.Flush()
+		log.Info("Socks: Failed to connect to ", dest, ": ", err)
+		log.AccessWithTags(clientAddr, dest, log.AccessRejected, err, this.meta.ConnectionTags)
 		return err
 	}
 
+	response.Write(writer)
+
 	reader.SetCached(false)
 	writer.SetCached(false)
 
-	dest := request.Destination()
-	session := &proxy.SessionInfo{
-		Source:      clientAddr,
-		Destination: dest,
-		Inbound:     this.meta,
-	}
-	log.Info("Socks: TCP Connect request to ", dest)
-	log.Access(clientAddr, dest, log.AccessAccepted, "")
+	log.AccessWithTags(clientAddr, dest, log.AccessAccepted, "", this.meta.ConnectionTags)
 
-	this.transport(reader, writer, session)
+	this.transport(reader, writer, ray)
 	return nil
 }
 
-func (this *Server) handleUDP(reader io.Reader, writer *v2io.BufferedWriter) error {
+// dispatch dispatches session to outbound. If passive connections are allowed, the outbound connects
+// before the client sends anything, and dispatch waits for it, so that its failure can be reported in the
+// reply. Otherwise the outbound connects on first payload, after the reply.
+func (this *Server) dispatch(session *proxy.SessionInfo) (ray.InboundRay, error) {
+	link := this.packetDispatcher.DispatchToOutbound(session)
+	if !this.meta.AllowPassiveConnection {
+		return link, nil
+	}
+	if err := link.InboundOutput().WaitEstablished(); err != nil {
+		link.InboundInput().Close()
+		link.InboundOutput().Release()
+		return nil, err
+	}
+	return link, nil
+}
+
+func socks5ReplyCode(reason proxy.FailureReason) byte {
+	switch reason {
+	case proxy.FailureRejected:
+		return protocol.ErrorConnectionNotAllowed
+	case proxy.FailureNetworkUnreachable:
+		return protocol.ErrorNetworkUnreachable
+	case proxy.FailureHostUnreachable:
+		return protocol.ErrorHostUnUnreachable
+	case proxy.FailureConnectionRefused:
+		return protocol.ErrorConnectionRefused
+	case proxy.FailureTimeout:
+		return protocol.ErrorTTLExpired
+	default:
+		return protocol.ErrorGeneralFailure
+	}
+}
+
+func (this *Server) handleUDP(clientAddr v2net.Destination, reader io.Reader, writer *v2io.BufferedWriter) error {
 	response := protocol.NewSocks5Response()
 	response.Error = protocol.ErrorSuccess
 
@@ -248,6 +326,18 @@ func (this *Server) handleUDP(reader io.Reader, writer *v2io.BufferedWriter) err
 		response.SetDomain(udpAddr.Address.Domain())
 	}
 
+	if !this.openUDPAssociation(clientAddr.Address) {
+		log.Warning("Socks: Rejecting UDP association from ", clientAddr, ": ", ErrTooManyUDPAssociations)
+		log.AccessWithTags(clientAddr, "", log.AccessRejected, ErrTooManyUDPAssociations, this.meta.ConnectionTags)
+		response = protocol.NewSocks5Response()
+		response.Error = protocol.ErrorConnectionNotAllowed
+		response.SetIPv4([]byte{0, 0, 0, 0})
+		response.Write(writer)
+		writer.Flush()
+		return ErrTooManyUDPAssociations
+	}
+	defer this.closeUDPAssociation(clientAddr.Address)
+
 	response.Write(writer)
 	err := writer.Flush()
 
@@ -256,45 +346,59 @@ func (this *Server) handleUDP(reader io.Reader, writer *v2io.BufferedWriter) err
 		return err
 	}
 
-	// The TCP connection closes after this method returns. We need to wait until
-	// the client closes it.
-	// TODO: get notified from UDP part
-	<-time.After(5 * time.Minute)
-
+	// The association lasts until the client closes the TCP connection. Nothing is expected on it.
+	buffer := make([]byte, 256)
+	for {
+		_, err := reader.Read(buffer)
+		if err == nil {
+			continue
+		}
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			continue
+		}
+		break
+	}
 	return nil
 }
 
 func (this *Server) handleSocks4(clientAddr v2net.Destination, reader *v2io.BufferedReader, writer *v2io.BufferedWriter, auth protocol.Socks4AuthenticationRequest) error {
-	result := protocol.Socks4RequestGranted
 	if auth.Command == protocol.CmdBind {
-		result = protocol.Socks4RequestRejected
-	}
-	socks4Response := protocol.NewSocks4AuthenticationResponse(result, auth.Port, auth.IP[:])
-
-	socks4Response.Write(writer)
-
-	if result == protocol.Socks4RequestRejected {
+		socks4Response := protocol.NewSocks4AuthenticationResponse(protocol.Socks4RequestRejected, auth.Port, auth.IP[:])
+		socks4Response.Write(writer)
 		log.Warning("Socks: Unsupported socks 4 command ", auth.Command)
-		log.Access(clientAddr, "", log.AccessRejected, ErrUnsupportedSocksCommand)
+		log.AccessWithTags(clientAddr, "", log.AccessRejected, ErrUnsupportedSocksCommand, this.meta.ConnectionTags)
 		return ErrUnsupportedSocksCommand
 	}
 
-	reader.SetCached(false)
-	writer.SetCached(false)
-
 	dest := v2net.TCPDestination(v2net.IPAddress(auth.IP[:]), auth.Port)
 	session := &proxy.SessionInfo{
 		Source:      clientAddr,
 		Destination: dest,
 		Inbound:     this.meta,
 	}
-	log.Access(clientAddr, dest, log.AccessAccepted, "")
-	this.transport(reader, writer, session)
+	ray, err := this.dispatch(session)
+	if err != nil {
+		// SOCKS 4 has no reason for failures.
+		socks4Response := protocol.NewSocks4AuthenticationResponse(protocol.Socks4RequestRejected, auth.Port, auth.IP[:])
+		socks4Response.Write(writer)
+		writer.Flush()
+		log.Info("Socks: Failed to connect to ", dest, ": ", err)
+		log.AccessWithTags(clientAddr, dest, log.AccessRejected, err, this.meta.ConnectionTags)
+		return err
+	}
+
+	socks4Response := protocol.NewSocks4AuthenticationResponse(protocol.Socks4RequestGranted, auth.Port, auth.IP[:])
+	socks4Response.Write(writer)
+
+	reader.SetCached(false)
+	writer.SetCached(false)
+
+	log.AccessWithTags(clientAddr, dest, log.AccessAccepted, "", this.meta.ConnectionTags)
+	this.transport(reader, writer, ray)
 	return nil
 }
 
-func (this *Server) transport(reader io.Reader, writer io.Writer, session *proxy.SessionInfo) {
-	ray := this.packetDispatcher.DispatchToOutbound(session)
+func (this *Server) transport(reader io.Reader, writer io.Writer, ray ray.InboundRay) {
 	input := ray.InboundInput()
 	output := ray.InboundOutput()
 
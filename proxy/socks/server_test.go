@@ -0,0 +1,140 @@
+package socks_test
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/dispatcher"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/socks"
+	"v2ray.com/core/proxy/socks/protocol"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/ray"
+)
+
+// dialDispatcher connects to the destination of each session, and fails the session if it can't.
+type dialDispatcher struct{}
+
+func (this *dialDispatcher) DispatchToOutbound(session *proxy.SessionInfo) ray.InboundRay {
+	traffic := ray.NewRay()
+	go func() {
+		conn, err := net.Dial("tcp", session.Destination.NetAddr())
+		if err != nil {
+			traffic.OutboundOutput().CloseError(err)
+			return
+		}
+		conn.Close()
+		traffic.OutboundOutput().Close()
+	}()
+	return traffic
+}
+
+func (this *dialDispatcher) Release() {}
+
+// selectionDispatcher records the outbound selected in each session, and closes it.
+type selectionDispatcher struct {
+	selected chan string
+}
+
+func (this *selectionDispatcher) DispatchToOutbound(session *proxy.SessionInfo) ray.InboundRay {
+	traffic := ray.NewRay()
+	this.selected <- session.OutboundTag
+	traffic.OutboundOutput().Close()
+	return traffic
+}
+
+func (this *selectionDispatcher) Release() {}
+
+func TestConnectFailureReply(t *testing.T) {
+	assert := assert.On(t)
+
+	port := pickPort(assert)
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, new(dialDispatcher))
+	server := NewServer(&ServerConfig{
+		AuthType: AuthType_NO_AUTH,
+	}, space, &proxy.InboundHandlerMeta{
+		Address:                v2net.LocalHostIP,
+		Port:                   port,
+		AllowPassiveConnection: true,
+		StreamSettings: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+	})
+	assert.Error(space.Initialize()).IsNil()
+	assert.Error(server.Start()).IsNil()
+	defer server.Close()
+
+	closedPort := pickPort(assert)
+	conn, err := net.Dial("tcp", (&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(port)}).String())
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = conn.Write([]byte{5, 1, 0})
+	assert.Error(err).IsNil()
+	authResponse := make([]byte, 2)
+	_, err = io.ReadFull(conn, authResponse)
+	assert.Error(err).IsNil()
+
+	_, err = conn.Write([]byte{5, 1, 0, 1, 127, 0, 0, 1, byte(closedPort >> 8), byte(closedPort)})
+	assert.Error(err).IsNil()
+	response := make([]byte, 10)
+	_, err = io.ReadFull(conn, response)
+	assert.Error(err).IsNil()
+	assert.Byte(response[1]).Equals(protocol.ErrorConnectionRefused)
+}
+
+func TestOutboundSelection(t *testing.T) {
+	assert := assert.On(t)
+
+	port := pickPort(assert)
+	space := app.NewSpace()
+	selections := &selectionDispatcher{
+		selected: make(chan string, 1),
+	}
+	space.BindApp(dispatcher.APP_ID, selections)
+	server := NewServer(&ServerConfig{
+		AuthType:          AuthType_NO_AUTH,
+		OutboundSelection: &proxy.OutboundSelectionConfig{},
+	}, space, &proxy.InboundHandlerMeta{
+		Address: v2net.LocalHostIP,
+		Port:    port,
+		StreamSettings: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+	})
+	assert.Error(space.Initialize()).IsNil()
+	assert.Error(server.Start()).IsNil()
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", (&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(port)}).String())
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Password authentication is accepted for the selection, though the server requires none.
+	_, err = conn.Write([]byte{5, 1, protocol.AuthUserPass})
+	assert.Error(err).IsNil()
+	authResponse := make([]byte, 2)
+	_, err = io.ReadFull(conn, authResponse)
+	assert.Error(err).IsNil()
+	assert.Byte(authResponse[1]).Equals(protocol.AuthUserPass)
+
+	username := "outbound:ss-jp"
+	_, err = conn.Write(append(append([]byte{1, byte(len(username))}, username...), 1, 'x'))
+	assert.Error(err).IsNil()
+	upResponse := make([]byte, 2)
+	_, err = io.ReadFull(conn, upResponse)
+	assert.Error(err).IsNil()
+	assert.Byte(upResponse[1]).Equals(0)
+
+	_, err = conn.Write([]byte{5, 1, 0, 1, 127, 0, 0, 1, 0, 80})
+	assert.Error(err).IsNil()
+	assert.String(<-selections.selected).Equals("ss-jp")
+}
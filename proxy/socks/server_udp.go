@@ -1,6 +1,8 @@
 package socks
 
 import (
+	"time"
+
 	"v2ray.com/core/common/alloc"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
@@ -9,6 +11,79 @@ import (
 	"v2ray.com/core/transport/internet/udp"
 )
 
+const (
+	// Clients with UDP ASSOCIATE requests rejected are counted by IP, up to this many. Others are
+	// counted together.
+	maxRejectedAssociationClients   = 1024
+	otherRejectedAssociationClients = "others"
+)
+
+// udpAssociation is the UDP relay of a client. It lives as long as any UDP ASSOCIATE connection from the
+// client is open, and for the grace period after the last one closes.
+type udpAssociation struct {
+	controls int
+	timer    *time.Timer
+}
+
+// openUDPAssociation returns false if client has too many UDP associations already.
+func (this *Server) openUDPAssociation(client v2net.Address) bool {
+	this.associationMutex.Lock()
+	defer this.associationMutex.Unlock()
+
+	key := client.String()
+	association, found := this.associations[key]
+	if !found {
+		association = new(udpAssociation)
+		this.associations[key] = association
+	}
+	if association.controls >= this.config.GetEffectiveMaxUDPAssociations() {
+		this.rejectedAssociations.GetBounded(key, maxRejectedAssociationClients, otherRejectedAssociationClients).Add(1)
+		return false
+	}
+	if association.timer != nil {
+		association.timer.Stop()
+		association.timer = nil
+		log.Debug("Socks: Reusing UDP relay for ", client)
+	}
+	association.controls++
+	return true
+}
+
+func (this *Server) closeUDPAssociation(client v2net.Address) {
+	this.associationMutex.Lock()
+	defer this.associationMutex.Unlock()
+
+	key := client.String()
+	association, found := this.associations[key]
+	if !found {
+		return
+	}
+	association.controls--
+	if association.controls > 0 {
+		return
+	}
+	if this.config.UdpGracePeriod == 0 {
+		delete(this.associations, key)
+		return
+	}
+	association.timer = time.AfterFunc(time.Duration(this.config.UdpGracePeriod)*time.Second, func() {
+		this.associationMutex.Lock()
+		defer this.associationMutex.Unlock()
+		if this.associations[key] == association && association.controls == 0 {
+			log.Debug("Socks: UDP relay for ", client, " expired.")
+			delete(this.associations, key)
+		}
+	})
+}
+
+func (this *Server) hasUDPAssociation(client v2net.Address) bool {
+	this.associationMutex.Lock()
+	defer this.associationMutex.Unlock()
+
+	_, found := this.associations[client.String()]
+	return found
+}
+
 func (this *Server) listenUDP() error {
 	this.udpServer = udp.NewUDPServer(this.packetDispatcher)
 	udpHub, err := udp.ListenUDP(this.meta.Address, this.meta.Port, udp.ListenOption{Callback: this.handleUDPPayload})
@@ -26,6 +101,11 @@ func (this *Server) listenUDP() error {
 func (this *Server) handleUDPPayload(payload *alloc.Buffer, session *proxy.SessionInfo) {
 	source := session.Source
 	log.Info("Socks: Client UDP connection from ", source)
+	if !this.hasUDPAssociation(source.Address) {
+		log.Info("Socks: Dropping UDP packet from ", source, " without association.")
+		payload.Release()
+		return
+	}
 	request, err := protocol.ReadUDPRequest(payload.Value)
 	payload.Release()
 
@@ -44,16 +124,23 @@ func (this *Server) handleUDPPayload(payload *alloc.Buffer, session *proxy.Sessi
 		return
 	}
 
-	log.Info("Socks: Send packet to ", request.Destination(), " with ", request.Data.Len(), " bytes")
-	log.Access(source, request.Destination, log.AccessAccepted, "")
-	this.udpServer.Dispatch(&proxy.SessionInfo{Source: source, Destination: request.Destination(), Inbound: this.meta}, request.Data, func(destination v2net.Destination, payload *alloc.Buffer) {
+	destination := request.Destination()
+	log.Info("Socks: Send packet to ", destination, " with ", request.Data.Len(), " bytes")
+	log.AccessWithTags(source, destination, log.AccessAccepted, "", this.meta.ConnectionTags)
+	// UDP server keeps one session per source and destination, so every response in this session comes
+	// from destination. It is written in the SOCKS header for the client to tell responses apart.
+	this.udpServer.Dispatch(&proxy.SessionInfo{Source: source, Destination: destination, Inbound: this.meta}, request.Data, func(client v2net.Destination, payload *alloc.Buffer) {
 		response := &protocol.Socks5UDPRequest{
 			Fragment: 0,
-			Address:  request.Destination().Address,
-			Port:     request.Destination().Port,
+			Address:  destination.Address,
+			Port:     destination.Port,
 			Data:     payload,
 		}
-		log.Info("Socks: Writing back UDP response with ", payload.Len(), " bytes to ", destination)
+		if !this.hasUDPAssociation(client.Address) {
+			payload.Release()
+			return
+		}
+		log.Info("Socks: Writing back UDP response with ", payload.Len(), " bytes to ", client)
 
 		udpMessage := alloc.NewLocalBuffer(2048).Clear()
 		response.Write(udpMessage)
@@ -63,12 +150,12 @@ func (this *Server) handleUDPPayload(payload *alloc.Buffer, session *proxy.Sessi
 			this.udpMutex.RUnlock()
 			return
 		}
-		nBytes, err := this.udpHub.WriteTo(udpMessage.Value, destination)
+		nBytes, err := this.udpHub.WriteTo(udpMessage.Value, client)
 		this.udpMutex.RUnlock()
 		udpMessage.Release()
 		response.Data.Release()
 		if err != nil {
-			log.Error("Socks: failed to write UDP message (", nBytes, " bytes) to ", destination, ": ", err)
+			log.Error("Socks: failed to write UDP message (", nBytes, " bytes) to ", client, ": ", err)
 		}
 	})
 }
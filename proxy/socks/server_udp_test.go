@@ -0,0 +1,235 @@
+package socks_test
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/dispatcher"
+	"v2ray.com/core/common/alloc"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy"
+	. "v2ray.com/core/proxy/socks"
+	"v2ray.com/core/proxy/socks/protocol"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	_ "v2ray.com/core/transport/internet/tcp"
+	"v2ray.com/core/transport/ray"
+)
+
+// echoDispatcher responds to every packet with the destination of its session.
+type echoDispatcher struct{}
+
+func (this *echoDispatcher) DispatchToOutbound(session *proxy.SessionInfo) ray.InboundRay {
+	traffic := ray.NewRay()
+	destination := session.Destination
+	go func() {
+		for {
+			payload, err := traffic.OutboundInput().Read()
+			if err != nil {
+				break
+			}
+			payload.Release()
+			traffic.OutboundOutput().Write(alloc.NewLocalBuffer(2048).Clear().AppendString("from " + destination.String()))
+		}
+		traffic.OutboundOutput().Close()
+	}()
+	return traffic
+}
+
+func (this *echoDispatcher) Release() {}
+
+func pickPort(assert *assert.Assert) v2net.Port {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	return v2net.Port(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func startUDPServer(assert *assert.Assert, gracePeriod uint32) (*Server, v2net.Port) {
+	return startUDPServerWithConfig(assert, &ServerConfig{
+		AuthType:       AuthType_NO_AUTH,
+		UdpEnabled:     true,
+		Address:        v2net.NewIPOrDomain(v2net.LocalHostIP),
+		UdpGracePeriod: gracePeriod,
+	})
+}
+
+func startUDPServerWithConfig(assert *assert.Assert, config *ServerConfig) (*Server, v2net.Port) {
+	port := pickPort(assert)
+	space := app.NewSpace()
+	space.BindApp(dispatcher.APP_ID, new(echoDispatcher))
+	server := NewServer(config, space, &proxy.InboundHandlerMeta{
+		Address: v2net.LocalHostIP,
+		Port:    port,
+		StreamSettings: &internet.StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+	})
+	assert.Error(space.Initialize()).IsNil()
+	assert.Error(server.Start()).IsNil()
+	return server, port
+}
+
+// associate opens a UDP ASSOCIATE control connection to the server on port.
+func associate(assert *assert.Assert, port v2net.Port) net.Conn {
+	conn, reply := requestAssociate(assert, port)
+	assert.Byte(reply).Equals(0)
+	return conn
+}
+
+// requestAssociate sends a UDP ASSOCIATE request to the server on port, and returns the reply code.
+func requestAssociate(assert *assert.Assert, port v2net.Port) (net.Conn, byte) {
+	conn, err := net.Dial("tcp", (&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(port)}).String())
+	assert.Error(err).IsNil()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = conn.Write([]byte{5, 1, 0})
+	assert.Error(err).IsNil()
+	authResponse := make([]byte, 2)
+	_, err = io.ReadFull(conn, authResponse)
+	assert.Error(err).IsNil()
+	assert.Bytes(authResponse).Equals([]byte{5, 0})
+
+	_, err = conn.Write([]byte{5, 3, 0, 1, 0, 0, 0, 0, 0, 0})
+	assert.Error(err).IsNil()
+	response := make([]byte, 10)
+	_, err = io.ReadFull(conn, response)
+	assert.Error(err).IsNil()
+	conn.SetDeadline(time.Time{})
+	return conn, response[1]
+}
+
+// exchangeUDP sends a request to dest through the relay, and returns whether a response arrives.
+func exchangeUDP(assert *assert.Assert, conn *net.UDPConn, dest v2net.Destination) bool {
+	request := &protocol.Socks5UDPRequest{
+		Address: dest.Address,
+		Port:    dest.Port,
+		Data:    alloc.NewLocalBuffer(2048).Clear().AppendString("request"),
+	}
+	packet := alloc.NewLocalBuffer(2048).Clear()
+	request.Write(packet)
+	_, err := conn.Write(packet.Value)
+	assert.Error(err).IsNil()
+
+	buffer := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	_, err = conn.Read(buffer)
+	return err == nil
+}
+
+func TestUDPResponseSourceAddress(t *testing.T) {
+	assert := assert.On(t)
+
+	server, port := startUDPServer(assert, 0)
+	defer server.Close()
+	control := associate(assert, port)
+	defer control.Close()
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(port)})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	destinations := []v2net.Destination{
+		v2net.UDPDestination(v2net.IPAddress([]byte{8, 8, 8, 8}), 53),
+		v2net.UDPDestination(v2net.IPAddress([]byte{8, 8, 4, 4}), 53),
+		v2net.UDPDestination(v2net.DomainAddress("v2ray.com"), 123),
+	}
+	// Requests to different destinations are interleaved on the same association.
+	for round := 0; round < 2; round++ {
+		for _, dest := range destinations {
+			request := &protocol.Socks5UDPRequest{
+				Address: dest.Address,
+				Port:    dest.Port,
+				Data:    alloc.NewLocalBuffer(2048).Clear().AppendString("request"),
+			}
+			packet := alloc.NewLocalBuffer(2048).Clear()
+			request.Write(packet)
+			_, err := conn.Write(packet.Value)
+			assert.Error(err).IsNil()
+		}
+
+		for range destinations {
+			buffer := make([]byte, 2048)
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			nBytes, err := conn.Read(buffer)
+			assert.Error(err).IsNil()
+
+			response, err := protocol.ReadUDPRequest(buffer[:nBytes])
+			assert.Error(err).IsNil()
+			assert.String(response.Data.String()).Equals("from " + response.Destination().String())
+		}
+	}
+}
+
+func TestUDPAssociationTeardown(t *testing.T) {
+	assert := assert.On(t)
+
+	server, port := startUDPServer(assert, 0)
+	defer server.Close()
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(port)})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	dest := v2net.UDPDestination(v2net.IPAddress([]byte{8, 8, 8, 8}), 53)
+
+	assert.Bool(exchangeUDP(assert, conn, dest)).IsFalse()
+
+	control := associate(assert, port)
+	assert.Bool(exchangeUDP(assert, conn, dest)).IsTrue()
+
+	control.Close()
+	time.Sleep(100 * time.Millisecond)
+	assert.Bool(exchangeUDP(assert, conn, dest)).IsFalse()
+}
+
+func TestUDPAssociationGracePeriod(t *testing.T) {
+	assert := assert.On(t)
+
+	server, port := startUDPServer(assert, 2)
+	defer server.Close()
+
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: int(port)})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	dest := v2net.UDPDestination(v2net.IPAddress([]byte{8, 8, 8, 8}), 53)
+
+	control := associate(assert, port)
+	assert.Bool(exchangeUDP(assert, conn, dest)).IsTrue()
+	control.Close()
+	time.Sleep(100 * time.Millisecond)
+	assert.Bool(exchangeUDP(assert, conn, dest)).IsTrue()
+
+	// A new association within the grace period keeps the relay after the period.
+	control = associate(assert, port)
+	time.Sleep(2 * time.Second)
+	assert.Bool(exchangeUDP(assert, conn, dest)).IsTrue()
+
+	control.Close()
+	time.Sleep(2500 * time.Millisecond)
+	assert.Bool(exchangeUDP(assert, conn, dest)).IsFalse()
+}
+
+func TestUDPAssociationLimit(t *testing.T) {
+	assert := assert.On(t)
+
+	server, port := startUDPServerWithConfig(assert, &ServerConfig{
+		AuthType:           AuthType_NO_AUTH,
+		UdpEnabled:         true,
+		Address:            v2net.NewIPOrDomain(v2net.LocalHostIP),
+		MaxUdpAssociations: 1,
+	})
+	defer server.Close()
+
+	control := associate(assert, port)
+	rejected, reply := requestAssociate(assert, port)
+	rejected.Close()
+	assert.Byte(reply).Equals(protocol.ErrorConnectionNotAllowed)
+
+	control.Close()
+	time.Sleep(100 * time.Millisecond)
+	control = associate(assert, port)
+	control.Close()
+}
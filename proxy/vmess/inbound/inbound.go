@@ -155,13 +155,13 @@ func (this *VMessInboundHandler) HandleConnection(connection internet.Connection
 
 	if err != nil {
 		if err != io.EOF {
-			log.Access(connection.RemoteAddr(), "", log.AccessRejected, err)
+			log.AccessWithTags(connection.RemoteAddr(), "", log.AccessRejected, err, this.meta.ConnectionTags)
 			log.Warning("VMessIn: Invalid request from ", connection.RemoteAddr(), ": ", err)
 		}
 		connection.SetReusable(false)
 		return
 	}
-	log.Access(connection.RemoteAddr(), request.Destination(), log.AccessAccepted, "")
+	log.AccessWithTags(connection.RemoteAddr(), request.Destination(), log.AccessAccepted, "", this.meta.ConnectionTags)
 	log.Info("VMessIn: Received request for ", request.Destination())
 
 	connection.SetReusable(request.Option.Has(protocol.RequestOptionConnectionReuse))
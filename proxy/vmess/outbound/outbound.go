@@ -27,18 +27,33 @@ type VMessOutboundHandler struct {
 }
 
 func (this *VMessOutboundHandler) Dispatch(target v2net.Destination, payload *alloc.Buffer, ray ray.OutboundRay) error {
+	return this.dispatch(target, nil, payload, ray)
+}
+
+// DispatchSession implements SessionOutboundHandler.DispatchSession().
+func (this *VMessOutboundHandler) DispatchSession(session *proxy.SessionInfo, payload *alloc.Buffer, ray ray.OutboundRay) error {
+	return this.dispatch(session.Destination, session.Chain, payload, ray)
+}
+
+// dispatch tunnels the connection to target, with chain as the outbounds it has gone through.
+func (this *VMessOutboundHandler) dispatch(target v2net.Destination, chain internet.OutboundChain, payload *alloc.Buffer, ray ray.OutboundRay) error {
 	defer ray.OutboundInput().Release()
 	defer ray.OutboundOutput().Close()
 
 	var rec *protocol.ServerSpec
 	var conn internet.Connection
+	var lastErr error
 
 	err := retry.Timed(5, 100).On(func() error {
 		rec = this.serverPicker.PickServer()
 		rawConn, err := internet.Dial(this.meta.Address, rec.Destination(), internet.DialerOptions{
-			Stream: this.meta.StreamSettings,
+			Stream:          this.meta.StreamSettings,
+			Tag:             this.meta.Tag,
+			Chain:           chain,
+			SourcePortRange: this.meta.StreamSettings.GetSourcePortRange(),
 		})
 		if err != nil {
+			lastErr = err
 			return err
 		}
 		conn = rawConn
@@ -46,9 +61,11 @@ func (this *VMessOutboundHandler) Dispatch(target v2net.Destination, payload *al
 		return nil
 	})
 	if err != nil {
-		log.Error("VMess|Outbound: Failed to find an available destination:", err)
+		log.Error("VMess|Outbound: Failed to find an available destination:", lastErr)
+		ray.OutboundOutput().CloseError(lastErr)
 		return err
 	}
+	ray.OutboundOutput().Established()
 	log.Info("VMess|Outbound: Tunneling request to ", target, " via ", rec.Destination())
 
 	command := protocol.RequestCommandTCP
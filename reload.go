@@ -0,0 +1,128 @@
+package core
+
+import (
+	"errors"
+
+	"v2ray.com/core/app"
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/app/proxyman"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common"
+	"v2ray.com/core/common/loader"
+	"v2ray.com/core/common/log"
+)
+
+var (
+	ErrReloadInProgress = errors.New("Point: Another reload is in progress.")
+	ErrPointClosed      = errors.New("Point: Point is closed.")
+)
+
+// reloadSpace shares apps with a running space, but keeps its own initializers, so that handlers created
+// on reload are initialized without initializing the running apps again.
+type reloadSpace struct {
+	app.Space
+	appInit []app.ApplicationInitializer
+}
+
+func (this *reloadSpace) InitializeApplication(f app.ApplicationInitializer) {
+	this.appInit = append(this.appInit, f)
+}
+
+func (this *reloadSpace) Initialize() error {
+	for _, f := range this.appInit {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reload replaces outbound handlers, routing rules and DNS settings of a running Point with the ones in
+// config. Connections in progress keep using the handlers they started with. Everything is built before
+// any replacement, so the Point is unchanged if config is invalid. Inbounds and other settings are not
+// reloaded. Reloads don't interleave: a reload while another is in progress is rejected with
+// ErrReloadInProgress, as its config would be replaced or replace the other one anyway. A closed Point is
+// never reloaded, and returns ErrPointClosed.
+func (this *Point) Reload(config *Config) error {
+	select {
+	case this.reloadSlot <- true:
+		defer func() {
+			<-this.reloadSlot
+		}()
+	default:
+		log.Warning("Point: Rejecting reload as another one is in progress.")
+		return ErrReloadInProgress
+	}
+	if this.closed {
+		log.Warning("Point: Rejecting reload as the point is closed.")
+		return ErrPointClosed
+	}
+
+	if len(config.Outbound) == 0 {
+		log.Error("Point: No outbound in reloaded config.")
+		return common.ErrBadConfiguration
+	}
+	var routerConfig *router.Config
+	var dnsConfig *dns.Config
+	for _, appSettings := range config.App {
+		instance, err := appSettings.GetInstance()
+		if err != nil {
+			return err
+		}
+		switch appSettings.Type {
+		case loader.GetType(new(router.Config)):
+			routerConfig = instance.(*router.Config)
+		case loader.GetType(new(dns.Config)):
+			dnsConfig = instance.(*dns.Config)
+		}
+	}
+
+	// Router can't be added or removed at runtime, as the dispatcher picks it up on initialization.
+	var ruleSet *router.RuleSet
+	var err error
+	currentRouter, hasRouter := this.space.GetApp(router.APP_ID).(*router.Router)
+	if hasRouter {
+		if routerConfig == nil {
+			routerConfig = new(router.Config)
+		}
+		ruleSet, err = router.CompileRules(routerConfig)
+		if err != nil {
+			return err
+		}
+	} else if routerConfig != nil {
+		log.Warning("Point: Routing is not reloaded as it is not configured at start.")
+	}
+	if dnsConfig == nil {
+		dnsConfig = defaultDNSConfig()
+	}
+
+	// Handlers are created last, as they have to be closed if anything fails from here on.
+	space := &reloadSpace{Space: this.space}
+	defaultHandler, outboundHandlers, taggedOutboundHandlers, err := createOutboundHandlers(space, config.Outbound, this.hooks)
+	if err != nil {
+		return err
+	}
+	if err := space.Initialize(); err != nil {
+		closeOutboundHandlers(outboundHandlers)
+		return err
+	}
+
+	outboundManager := this.space.GetApp(proxyman.APP_ID_OUTBOUND_MANAGER).(*proxyman.DefaultOutboundHandlerManager)
+	outboundManager.ReplaceHandlers(defaultHandler, taggedOutboundHandlers)
+	if ruleSet != nil {
+		currentRouter.SetRuleSet(ruleSet)
+	}
+	if dnsServer, ok := this.space.GetApp(dns.APP_ID).(*dns.CacheServer); ok {
+		dnsServer.Reload(dnsConfig)
+	}
+
+	previousHandlers := this.outboundHandlers
+	this.outboundHandlers = outboundHandlers
+	this.taggedOutboundHandlers = taggedOutboundHandlers
+	this.setLoadedConfig(config, outboundHandlers)
+	// Stops background work of previous handlers, e.g., subscription refreshing.
+	closeOutboundHandlers(previousHandlers)
+
+	log.Warning("Point: Config reloaded with ", len(outboundHandlers), " outbounds.")
+	return nil
+}
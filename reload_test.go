@@ -0,0 +1,187 @@
+package core_test
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "v2ray.com/core"
+	"v2ray.com/core/app/router"
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/proxy/blackhole"
+	"v2ray.com/core/proxy/dokodemo"
+	"v2ray.com/core/proxy/freedom"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/testing/servers/tcp"
+)
+
+func newReloadConfig(port v2net.Port, dest v2net.Destination, routerConfig *router.Config) *Config {
+	return &Config{
+		Inbound: []*InboundConnectionConfig{
+			{
+				PortRange: &v2net.PortRange{
+					From: uint32(port),
+					To:   uint32(port),
+				},
+				ListenOn:  v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Settings: loader.NewTypedSettings(&dokodemo.Config{
+					Address: v2net.NewIPOrDomain(dest.Address),
+					Port:    uint32(dest.Port),
+					NetworkList: &v2net.NetworkList{
+						Network: []v2net.Network{v2net.Network_TCP},
+					},
+				}),
+			},
+		},
+		Outbound: []*OutboundConnectionConfig{
+			{
+				Tag:      "direct",
+				Settings: loader.NewTypedSettings(&freedom.Config{}),
+			},
+			{
+				Tag:      "blocked",
+				Settings: loader.NewTypedSettings(&blackhole.Config{}),
+			},
+		},
+		App: []*loader.TypedSettings{
+			loader.NewTypedSettings(routerConfig),
+		},
+	}
+}
+
+func echo(assert *assert.Assert, conn net.Conn, payload string) bool {
+	_, err := conn.Write([]byte(payload))
+	assert.Error(err).IsNil()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	response := make([]byte, 1024)
+	nBytes, err := conn.Read(response)
+	if err != nil {
+		return false
+	}
+	assert.String(string(response[:nBytes])).Equals(payload)
+	return true
+}
+
+func TestReload(t *testing.T) {
+	assert := assert.On(t)
+
+	tcpServer := &tcp.Server{
+		MsgProcessor: func(data []byte) []byte { return data },
+	}
+	dest, err := tcpServer.Start()
+	assert.Error(err).IsNil()
+	defer tcpServer.Close()
+
+	port := v2net.Port(dice.Roll(20000) + 10000)
+	point, err := NewPoint(newReloadConfig(port, dest, &router.Config{}))
+	assert.Error(err).IsNil()
+	assert.Error(point.Start()).IsNil()
+	defer point.Close()
+
+	inbound := &net.TCPAddr{IP: net.IP{127, 0, 0, 1}, Port: int(port)}
+	activeConn, err := net.DialTCP("tcp", nil, inbound)
+	assert.Error(err).IsNil()
+	defer activeConn.Close()
+	assert.Bool(echo(assert, activeConn, "before reload")).IsTrue()
+
+	assert.Error(point.Reload(newReloadConfig(port, dest, &router.Config{
+		Rule: []*router.RoutingRule{
+			{
+				Tag: "blocked",
+				NetworkList: &v2net.NetworkList{
+					Network: []v2net.Network{v2net.Network_TCP},
+				},
+			},
+		},
+	}))).IsNil()
+
+	// Connections in progress are not affected.
+	assert.Bool(echo(assert, activeConn, "active connection")).IsTrue()
+
+	conn, err := net.DialTCP("tcp", nil, inbound)
+	assert.Error(err).IsNil()
+	assert.Bool(echo(assert, conn, "after reload")).IsFalse()
+	conn.Close()
+
+	// Invalid config is rejected, and the current config is kept.
+	invalidConfig := newReloadConfig(port, dest, &router.Config{})
+	invalidConfig.Outbound = nil
+	assert.Error(point.Reload(invalidConfig)).IsNotNil()
+
+	conn, err = net.DialTCP("tcp", nil, inbound)
+	assert.Error(err).IsNil()
+	assert.Bool(echo(assert, conn, "after invalid reload")).IsFalse()
+	conn.Close()
+}
+
+func TestConcurrentReload(t *testing.T) {
+	assert := assert.On(t)
+
+	tcpServer := &tcp.Server{
+		MsgProcessor: func(data []byte) []byte { return data },
+	}
+	dest, err := tcpServer.Start()
+	assert.Error(err).IsNil()
+	defer tcpServer.Close()
+
+	port := v2net.Port(dice.Roll(20000) + 10000)
+	point, err := NewPoint(newReloadConfig(port, dest, &router.Config{}))
+	assert.Error(err).IsNil()
+	assert.Error(point.Start()).IsNil()
+	defer point.Close()
+
+	blocked := &router.Config{
+		Rule: []*router.RoutingRule{
+			{
+				Tag: "blocked",
+				NetworkList: &v2net.NetworkList{
+					Network: []v2net.Network{v2net.Network_TCP},
+				},
+			},
+		},
+	}
+	const reloads = 50
+	var wg sync.WaitGroup
+	var succeeded int32
+	for i := 0; i < reloads; i++ {
+		routerConfig := &router.Config{}
+		if i%2 == 1 {
+			routerConfig = blocked
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := point.Reload(newReloadConfig(port, dest, routerConfig))
+			if err == nil {
+				atomic.AddInt32(&succeeded, 1)
+			} else {
+				assert.Error(err).Equals(ErrReloadInProgress)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Bool(atomic.LoadInt32(&succeeded) > 0).IsTrue()
+
+	// Routing matches the config that the Point reports as running.
+	state, err := point.ConfigState()
+	assert.Error(err).IsNil()
+	assert.Int(len(state.Outbounds)).Equals(2)
+	isBlocked := strings.Contains(string(state.Routing), "blocked")
+
+	conn, err := net.DialTCP("tcp", nil, &net.TCPAddr{IP: net.IP{127, 0, 0, 1}, Port: int(port)})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	assert.Bool(echo(assert, conn, "after reloads")).Equals(!isBlocked)
+
+	// Reloads are accepted again once others finish.
+	assert.Error(point.Reload(newReloadConfig(port, dest, &router.Config{}))).IsNil()
+
+	// But never after the Point is closed.
+	point.Close()
+	assert.Error(point.Reload(newReloadConfig(port, dest, &router.Config{}))).Equals(ErrPointClosed)
+}
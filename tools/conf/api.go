@@ -0,0 +1,28 @@
+package conf
+
+import (
+	"errors"
+
+	"v2ray.com/core/app/api"
+)
+
+type ApiConfig struct {
+	Listen *Address `json:"listen"`
+	Port   uint16   `json:"port"`
+}
+
+func (this *ApiConfig) Build() (*api.Config, error) {
+	if this.Port == 0 {
+		return nil, errors.New("Api: Port is not specified.")
+	}
+	config := &api.Config{
+		Port: uint32(this.Port),
+	}
+	if this.Listen != nil {
+		if this.Listen.Family().IsDomain() {
+			return nil, errors.New("Api: Unable to listen on domain address: " + this.Listen.Domain())
+		}
+		config.Listen = this.Listen.Build()
+	}
+	return config, nil
+}
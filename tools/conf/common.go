@@ -88,7 +88,7 @@ func (this *NetworkList) UnmarshalJSON(data []byte) error {
 		strlist := strings.Split(string(rawstr), ",")
 		nl := make([]Network, len(strlist))
 		for idx, network := range strlist {
-			nl[idx] = Network(network)
+			nl[idx] = Network(strings.TrimSpace(network))
 		}
 		*this = nl
 		return nil
@@ -119,7 +119,11 @@ func parseStringPort(data []byte) (v2net.Port, v2net.Port, error) {
 	if err != nil {
 		return v2net.Port(0), v2net.Port(0), err
 	}
-	pair := strings.SplitN(s, "-", 2)
+	return parsePortString(s)
+}
+
+func parsePortString(s string) (v2net.Port, v2net.Port, error) {
+	pair := strings.SplitN(strings.TrimSpace(s), "-", 2)
 	if len(pair) == 0 {
 		return v2net.Port(0), v2net.Port(0), v2net.ErrInvalidPortRange
 	}
@@ -175,6 +179,64 @@ func (this *PortRange) UnmarshalJSON(data []byte) error {
 	return v2net.ErrInvalidPortRange
 }
 
+// PortList is a list of ports or port ranges, in the form of a number, a string like "80,443,1000-2000",
+// or an array of both.
+type PortList []*PortRange
+
+func (this *PortList) Build() *v2net.PortList {
+	list := new(v2net.PortList)
+	for _, portRange := range *this {
+		list.Range = append(list.Range, portRange.Build())
+	}
+	return list
+}
+
+// UnmarshalJSON implements encoding/json.Unmarshaler.UnmarshalJSON
+func (this *PortList) UnmarshalJSON(data []byte) error {
+	var rawList []json.RawMessage
+	if err := json.Unmarshal(data, &rawList); err == nil {
+		list := make([]*PortRange, 0, len(rawList))
+		for _, raw := range rawList {
+			ranges, err := parsePortList(raw)
+			if err != nil {
+				return err
+			}
+			list = append(list, ranges...)
+		}
+		*this = PortList(list)
+		return nil
+	}
+
+	list, err := parsePortList(data)
+	if err != nil {
+		return err
+	}
+	*this = PortList(list)
+	return nil
+}
+
+func parsePortList(data []byte) ([]*PortRange, error) {
+	if port, err := parseIntPort(data); err == nil {
+		return []*PortRange{{From: uint32(port), To: uint32(port)}}, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		log.Error("Invalid port list: ", string(data))
+		return nil, v2net.ErrInvalidPortRange
+	}
+	var list []*PortRange
+	for _, part := range strings.Split(s, ",") {
+		from, to, err := parsePortString(part)
+		if err != nil || from > to {
+			log.Error("Invalid port range: ", part)
+			return nil, v2net.ErrInvalidPortRange
+		}
+		list = append(list, &PortRange{From: uint32(from), To: uint32(to)})
+	}
+	return list, nil
+}
+
 type User struct {
 	EmailString string `json:"email"`
 	LevelByte   byte   `json:"level"`
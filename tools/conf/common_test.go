@@ -183,3 +183,24 @@ func TestInvalidUserJson(t *testing.T) {
 	err := json.Unmarshal([]byte(`{"email": 1234}`), user)
 	assert.Error(err).IsNotNil()
 }
+
+func TestPortList(t *testing.T) {
+	assert := assert.On(t)
+
+	var list PortList
+	err := json.Unmarshal([]byte("\"80, 443,1000-2000\""), &list)
+	assert.Error(err).IsNil()
+	assert.Int(len(list)).Equals(3)
+	assert.Uint32(list[1].From).Equals(443)
+	assert.Uint32(list[2].From).Equals(1000)
+	assert.Uint32(list[2].To).Equals(2000)
+
+	err = json.Unmarshal([]byte("[53, \"8000-9000\"]"), &list)
+	assert.Error(err).IsNil()
+	assert.Int(len(list)).Equals(2)
+	assert.Bool(list.Build().Contains(v2net.Port(8500))).IsTrue()
+	assert.Bool(list.Build().Contains(v2net.Port(54))).IsFalse()
+
+	err = json.Unmarshal([]byte("\"80,2000-1000\""), &list)
+	assert.Error(err).Equals(v2net.ErrInvalidPortRange)
+}
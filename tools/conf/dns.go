@@ -1,32 +1,164 @@
 package conf
 
 import (
+	"errors"
+	"sort"
+	"strings"
+
 	"v2ray.com/core/app/dns"
+	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 )
 
-type DnsConfig struct {
-	Servers []*Address          `json:"servers"`
-	Hosts   map[string]*Address `json:"hosts"`
+type DnsTLSServerConfig struct {
+	Address     *Address `json:"address"`
+	Port        uint16   `json:"port"`
+	ServerName  string   `json:"serverName"`
+	OutboundTag string   `json:"outboundTag"`
 }
 
-func (this *DnsConfig) Build() *dns.Config {
-	config := new(dns.Config)
-	config.NameServers = make([]*v2net.Endpoint, len(this.Servers))
-	for idx, server := range this.Servers {
-		config.NameServers[idx] = &v2net.Endpoint{
+func (this *DnsTLSServerConfig) Build() (*dns.TLSNameServerConfig, error) {
+	if this.Address == nil {
+		return nil, errors.New("DNS: Address of TLS server is not specified.")
+	}
+	return &dns.TLSNameServerConfig{
+		Address: &v2net.Endpoint{
+			Network: v2net.Network_TCP,
+			Address: this.Address.Build(),
+			Port:    uint32(this.Port),
+		},
+		ServerName:  this.ServerName,
+		OutboundTag: this.OutboundTag,
+	}, nil
+}
+
+type DnsDomainServerConfig struct {
+	Domains     []string              `json:"domains"`
+	Servers     []*Address            `json:"servers"`
+	TLSServers  []*DnsTLSServerConfig `json:"tlsServers"`
+	OutboundTag string                `json:"outboundTag"`
+}
+
+func (this *DnsDomainServerConfig) Build() (*dns.DomainNameServer, error) {
+	if len(this.Domains) == 0 {
+		return nil, errors.New("DNS: No domain in domain servers.")
+	}
+	if len(this.Servers) == 0 && len(this.TLSServers) == 0 {
+		return nil, errors.New("DNS: No name server for domains: " + strings.Join(this.Domains, ","))
+	}
+	rule := &dns.DomainNameServer{
+		Domain:      this.Domains,
+		NameServers: buildUDPNameServers(this.Servers),
+		OutboundTag: this.OutboundTag,
+	}
+	for _, server := range this.TLSServers {
+		tlsServer, err := server.Build()
+		if err != nil {
+			return nil, err
+		}
+		rule.TlsNameServers = append(rule.TlsNameServers, tlsServer)
+	}
+	return rule, nil
+}
+
+func buildUDPNameServers(servers []*Address) []*v2net.Endpoint {
+	endpoints := make([]*v2net.Endpoint, len(servers))
+	for idx, server := range servers {
+		endpoints[idx] = &v2net.Endpoint{
 			Network: v2net.Network_UDP,
 			Address: server.Build(),
 			Port:    53,
 		}
 	}
+	return endpoints
+}
+
+type DnsConfig struct {
+	Servers         []*Address               `json:"servers"`
+	TLSServers      []*DnsTLSServerConfig    `json:"tlsServers"`
+	DomainServers   []*DnsDomainServerConfig `json:"domainServers"`
+	Hosts           map[string]*StringList   `json:"hosts"`
+	Order           []string                 `json:"order"`
+	HostsFile       string                   `json:"hostsFile"`
+	ResolveOutbound bool                     `json:"resolveOutbound"`
+	QueryLog        string                   `json:"queryLog"`
+	OutboundTag     string                   `json:"outboundTag"`
+	MinTTL          uint32                   `json:"minTtl"`
+	MaxTTL          uint32                   `json:"maxTtl"`
+}
+
+func (this *DnsConfig) Build() (*dns.Config, error) {
+	config := new(dns.Config)
+	config.NameServers = buildUDPNameServers(this.Servers)
+
+	for _, server := range this.TLSServers {
+		tlsServer, err := server.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.TlsNameServers = append(config.TlsNameServers, tlsServer)
+	}
+	for _, server := range this.DomainServers {
+		rule, err := server.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.DomainNameServers = append(config.DomainNameServers, rule)
+	}
 
-	if this.Hosts != nil {
-		config.Hosts = make(map[string]*v2net.IPOrDomain)
-		for k, v := range this.Hosts {
-			config.Hosts[k] = v.Build()
+	domains := make([]string, 0, len(this.Hosts))
+	for domain := range this.Hosts {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	for _, domain := range domains {
+		mapping := &dns.HostMapping{
+			Domain: domain,
+		}
+		for _, ip := range *this.Hosts[domain] {
+			address := v2net.ParseAddress(strings.TrimSpace(ip))
+			if address.Family().IsDomain() {
+				return nil, errors.New("Invalid IP in DNS hosts: " + ip)
+			}
+			mapping.Ip = append(mapping.Ip, v2net.NewIPOrDomain(address))
+		}
+		config.HostMapping = append(config.HostMapping, mapping)
+	}
+
+	for _, stage := range this.Order {
+		switch strings.ToLower(stage) {
+		case "static":
+			config.ResolveOrder = append(config.ResolveOrder, dns.ResolveStage_Static)
+		case "hosts":
+			config.ResolveOrder = append(config.ResolveOrder, dns.ResolveStage_SystemHosts)
+		case "servers":
+			config.ResolveOrder = append(config.ResolveOrder, dns.ResolveStage_NameServer)
+		default:
+			return nil, errors.New("Unknown DNS resolve stage: " + stage)
 		}
 	}
+	config.HostsFile = this.HostsFile
+	config.ResolveOutbound = this.ResolveOutbound
+	config.OutboundTag = this.OutboundTag
+	if this.MaxTTL > 0 && this.MaxTTL < this.MinTTL {
+		return nil, errors.New("DNS: Maximum TTL is less than minimum TTL.")
+	}
+	config.MinTtl = this.MinTTL
+	config.MaxTtl = this.MaxTTL
+
+	switch strings.ToLower(this.QueryLog) {
+	case "", "none":
+	case "debug":
+		config.QueryLogLevel = log.LogLevel_Debug
+	case "info":
+		config.QueryLogLevel = log.LogLevel_Info
+	case "warning":
+		config.QueryLogLevel = log.LogLevel_Warning
+	case "error":
+		config.QueryLogLevel = log.LogLevel_Error
+	default:
+		return nil, errors.New("DNS: Unknown query log level: " + this.QueryLog)
+	}
 
-	return config
+	return config, nil
 }
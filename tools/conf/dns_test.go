@@ -2,8 +2,11 @@ package conf_test
 
 import (
 	"encoding/json"
+	"net"
 	"testing"
 
+	"v2ray.com/core/app/dns"
+	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/testing/assert"
 	. "v2ray.com/core/tools/conf"
@@ -20,10 +23,119 @@ func TestDnsConfigParsing(t *testing.T) {
 	err := json.Unmarshal([]byte(rawJson), jsonConfig)
 	assert.Error(err).IsNil()
 
-	config := jsonConfig.Build()
+	config, err := jsonConfig.Build()
+	assert.Error(err).IsNil()
 	assert.Int(len(config.NameServers)).Equals(1)
 	dest := config.NameServers[0].AsDestination()
 	assert.Destination(dest).IsUDP()
 	assert.Address(dest.Address).Equals(v2net.IPAddress([]byte{8, 8, 8, 8}))
 	assert.Port(dest.Port).Equals(v2net.Port(53))
+	assert.Bool(config.QueryLogLevel == log.LogLevel_Disabled).IsTrue()
+}
+
+func TestDnsQueryLogParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	jsonConfig := new(DnsConfig)
+	assert.Error(json.Unmarshal([]byte(`{"queryLog": "Info"}`), jsonConfig)).IsNil()
+	config, err := jsonConfig.Build()
+	assert.Error(err).IsNil()
+	assert.Bool(config.QueryLogLevel == log.LogLevel_Info).IsTrue()
+
+	jsonConfig.QueryLog = "verbose"
+	_, err = jsonConfig.Build()
+	assert.Error(err).IsNotNil()
+}
+
+func TestDnsResolveOrderParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "servers": ["8.8.8.8"],
+    "hosts": {
+      "www.v2ray.com": ["127.0.0.1", "::1"],
+      "v2ray.com": "10.0.0.1"
+    },
+    "order": ["hosts", "static", "servers"]
+  }`
+
+	jsonConfig := new(DnsConfig)
+	err := json.Unmarshal([]byte(rawJson), jsonConfig)
+	assert.Error(err).IsNil()
+
+	config, err := jsonConfig.Build()
+	assert.Error(err).IsNil()
+	assert.Int(len(config.ResolveOrder)).Equals(3)
+	assert.Bool(config.ResolveOrder[0] == dns.ResolveStage_SystemHosts).IsTrue()
+	assert.Bool(config.ResolveOrder[2] == dns.ResolveStage_NameServer).IsTrue()
+
+	hosts := config.GetInternalHosts()
+	assert.Int(len(hosts["www.v2ray.com"])).Equals(2)
+	assert.IP(hosts["www.v2ray.com"][1]).Equals(net.IPv6loopback)
+	assert.IP(hosts["v2ray.com"][0]).Equals(net.IP{10, 0, 0, 1})
+
+	jsonConfig.Order = []string{"upstream"}
+	_, err = jsonConfig.Build()
+	assert.Error(err).IsNotNil()
+}
+
+func TestDnsTLSServerParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "tlsServers": [
+      {"address": "1.1.1.1", "serverName": "cloudflare-dns.com", "outboundTag": "proxy"},
+      {"address": "dns.google", "port": 8853}
+    ]
+  }`
+
+	jsonConfig := new(DnsConfig)
+	err := json.Unmarshal([]byte(rawJson), jsonConfig)
+	assert.Error(err).IsNil()
+
+	config, err := jsonConfig.Build()
+	assert.Error(err).IsNil()
+	assert.Int(len(config.TlsNameServers)).Equals(2)
+	server := config.TlsNameServers[0]
+	assert.Port(server.GetDestination().Port).Equals(dns.DefaultTLSPort)
+	assert.String(server.GetServerName()).Equals("cloudflare-dns.com")
+	assert.String(server.OutboundTag).Equals("proxy")
+	server = config.TlsNameServers[1]
+	assert.Port(server.GetDestination().Port).Equals(v2net.Port(8853))
+	assert.String(server.GetServerName()).Equals("dns.google")
+}
+
+func TestDnsDomainServersParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "servers": ["8.8.8.8"],
+    "outboundTag": "proxy",
+    "domainServers": [{
+      "domains": ["v2ray.com", "cn"],
+      "servers": ["114.114.114.114"],
+      "outboundTag": "direct"
+    }]
+  }`
+
+	jsonConfig := new(DnsConfig)
+	err := json.Unmarshal([]byte(rawJson), jsonConfig)
+	assert.Error(err).IsNil()
+
+	config, err := jsonConfig.Build()
+	assert.Error(err).IsNil()
+	assert.String(config.OutboundTag).Equals("proxy")
+	assert.Int(len(config.DomainNameServers)).Equals(1)
+	rule := config.DomainNameServers[0]
+	assert.String(rule.OutboundTag).Equals("direct")
+	assert.Int(len(rule.Domain)).Equals(2)
+	assert.String(rule.Domain[1]).Equals("cn")
+	assert.Int(len(rule.NameServers)).Equals(1)
+	assert.Address(rule.NameServers[0].AsDestination().Address).Equals(v2net.IPAddress([]byte{114, 114, 114, 114}))
+
+	jsonConfig = new(DnsConfig)
+	err = json.Unmarshal([]byte(`{"domainServers": [{"domains": ["cn"]}]}`), jsonConfig)
+	assert.Error(err).IsNil()
+	_, err = jsonConfig.Build()
+	assert.Error(err).IsNotNil()
 }
@@ -0,0 +1,20 @@
+package conf
+
+import (
+	"v2ray.com/core/common/loader"
+	"v2ray.com/core/proxy/dnsserver"
+)
+
+type DnsServerConfig struct {
+	NetworkList *NetworkList `json:"network"`
+	TTL         uint32       `json:"ttl"`
+}
+
+func (this *DnsServerConfig) Build() (*loader.TypedSettings, error) {
+	config := new(dnsserver.Config)
+	if this.NetworkList != nil {
+		config.NetworkList = this.NetworkList.Build()
+	}
+	config.Ttl = this.TTL
+	return loader.NewTypedSettings(config), nil
+}
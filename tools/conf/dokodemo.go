@@ -1,6 +1,9 @@
 package conf
 
 import (
+	"errors"
+	"strings"
+
 	"v2ray.com/core/common/loader"
 	"v2ray.com/core/proxy/dokodemo"
 )
@@ -11,6 +14,7 @@ type DokodemoConfig struct {
 	NetworkList  *NetworkList `json:"network"`
 	TimeoutValue uint32       `json:"timeout"`
 	Redirect     bool         `json:"followRedirect"`
+	DestOverride string       `json:"destOverride"`
 }
 
 func (this *DokodemoConfig) Build() (*loader.TypedSettings, error) {
@@ -22,5 +26,13 @@ func (this *DokodemoConfig) Build() (*loader.TypedSettings, error) {
 	config.NetworkList = this.NetworkList.Build()
 	config.Timeout = this.TimeoutValue
 	config.FollowRedirect = this.Redirect
+	switch strings.ToLower(this.DestOverride) {
+	case "", "none":
+		config.DestinationOverride = dokodemo.DestinationOverride_None
+	case "sniffeddomain":
+		config.DestinationOverride = dokodemo.DestinationOverride_SniffedDomain
+	default:
+		return nil, errors.New("Dokodemo: Unknown destination override: " + this.DestOverride)
+	}
 	return loader.NewTypedSettings(config), nil
 }
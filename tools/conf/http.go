@@ -1,17 +1,92 @@
 package conf
 
 import (
+	"errors"
+	"strings"
+
 	"v2ray.com/core/common/loader"
 	"v2ray.com/core/proxy/http"
 )
 
+type HttpAccount struct {
+	Username string `json:"user"`
+	Password string `json:"pass"`
+}
+
+type HttpHeaderRule struct {
+	Operation string `json:"operation"`
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+}
+
+func (this *HttpHeaderRule) Build() (*http.HeaderRule, error) {
+	if len(this.Name) == 0 {
+		return nil, errors.New("HTTP: Header name is not specified.")
+	}
+	rule := &http.HeaderRule{
+		Name:  this.Name,
+		Value: this.Value,
+	}
+	switch strings.ToLower(this.Operation) {
+	case "add":
+		rule.Operation = http.HeaderRule_Add
+	case "remove":
+		rule.Operation = http.HeaderRule_Remove
+	case "replace":
+		rule.Operation = http.HeaderRule_Replace
+	default:
+		return nil, errors.New("HTTP: Unknown header operation: " + this.Operation)
+	}
+	return rule, nil
+}
+
+func buildHttpHeaderRules(rawRules []*HttpHeaderRule) ([]*http.HeaderRule, error) {
+	rules := make([]*http.HeaderRule, 0, len(rawRules))
+	for _, rawRule := range rawRules {
+		rule, err := rawRule.Build()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
 type HttpServerConfig struct {
-	Timeout uint32 `json:"timeout"`
+	Timeout         uint32                   `json:"timeout"`
+	Accounts        []*HttpAccount           `json:"accounts"`
+	Auth            string                   `json:"authenticator"`
+	RequestHeaders  []*HttpHeaderRule        `json:"requestHeaders"`
+	ResponseHeaders []*HttpHeaderRule        `json:"responseHeaders"`
+	StripResponse   bool                     `json:"stripResponseHopByHop"`
+	Selection       *OutboundSelectionConfig `json:"outboundSelection"`
 }
 
 func (this *HttpServerConfig) Build() (*loader.TypedSettings, error) {
 	config := &http.ServerConfig{
-		Timeout: this.Timeout,
+		Timeout:               this.Timeout,
+		Authenticator:         this.Auth,
+		StripResponseHopByHop: this.StripResponse,
+	}
+
+	if len(this.Accounts) > 0 {
+		config.Accounts = make(map[string]string, len(this.Accounts))
+		for _, account := range this.Accounts {
+			config.Accounts[account.Username] = account.Password
+		}
+	}
+
+	var err error
+	if config.RequestHeader, err = buildHttpHeaderRules(this.RequestHeaders); err != nil {
+		return nil, err
+	}
+	if config.ResponseHeader, err = buildHttpHeaderRules(this.ResponseHeaders); err != nil {
+		return nil, err
+	}
+	if this.Selection != nil {
+		if config.OutboundSelection, err = this.Selection.Build(); err != nil {
+			return nil, err
+		}
 	}
 
 	return loader.NewTypedSettings(config), nil
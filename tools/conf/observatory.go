@@ -0,0 +1,27 @@
+package conf
+
+import (
+	"v2ray.com/core/app/observatory"
+)
+
+type ObservatoryConfig struct {
+	OutboundTags []string `json:"outboundTags"`
+	Interval     uint32   `json:"interval"`
+	URL          string   `json:"url"`
+	Concurrency  uint32   `json:"concurrency"`
+	Timeout      uint32   `json:"timeout"`
+}
+
+func (this *ObservatoryConfig) Build() (*observatory.Config, error) {
+	config := &observatory.Config{
+		OutboundTag: this.OutboundTags,
+		Interval:    this.Interval,
+		Url:         this.URL,
+		Concurrency: this.Concurrency,
+		Timeout:     this.Timeout,
+	}
+	if _, _, err := config.BuildProbe(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
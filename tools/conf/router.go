@@ -15,8 +15,16 @@ import (
 )
 
 type RouterRulesConfig struct {
-	RuleList       []json.RawMessage `json:"rules"`
-	DomainStrategy string            `json:"domainStrategy"`
+	RuleList       []json.RawMessage              `json:"rules"`
+	DomainStrategy string                         `json:"domainStrategy"`
+	Policies       map[string]*RouterPolicyConfig `json:"policies"`
+}
+
+// RouterPolicyConfig is the connection policy of rules, with timeouts in seconds.
+type RouterPolicyConfig struct {
+	ConnectTimeout   uint32 `json:"connectTimeout"`
+	HandshakeTimeout uint32 `json:"handshakeTimeout"`
+	IdleTimeout      uint32 `json:"idleTimeout"`
 }
 
 type RouterConfig struct {
@@ -42,6 +50,19 @@ func (this *RouterConfig) Build() (*router.Config, error) {
 		rule := ParseRule(rawRule)
 		config.Rule[idx] = rule
 	}
+	if len(settings.Policies) > 0 {
+		config.Policy = make(map[string]*router.ConnectionPolicy, len(settings.Policies))
+		for name, policy := range settings.Policies {
+			if policy == nil {
+				return nil, errors.New("Router policy " + name + " is empty.")
+			}
+			config.Policy[name] = &router.ConnectionPolicy{
+				ConnectTimeout:   policy.ConnectTimeout,
+				HandshakeTimeout: policy.HandshakeTimeout,
+				IdleTimeout:      policy.IdleTimeout,
+			}
+		}
+	}
 	return config, nil
 }
 
@@ -104,13 +125,16 @@ func parseIP(s string) *router.CIDR {
 func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
 	type RawFieldRule struct {
 		RouterRule
-		Domain     *StringList  `json:"domain"`
-		IP         *StringList  `json:"ip"`
-		Port       *PortRange   `json:"port"`
-		Network    *NetworkList `json:"network"`
-		SourceIP   *StringList  `json:"source"`
-		User       *StringList  `json:"user"`
-		InboundTag *StringList  `json:"inboundTag"`
+		Domain     *StringList       `json:"domain"`
+		IP         *StringList       `json:"ip"`
+		Port       *PortList         `json:"port"`
+		Network    *NetworkList      `json:"network"`
+		SourceIP   *StringList       `json:"source"`
+		User       *StringList       `json:"user"`
+		InboundTag *StringList       `json:"inboundTag"`
+		Protocol   *StringList       `json:"protocol"`
+		Policy     string            `json:"policy"`
+		ConnTag    map[string]string `json:"connectionTag"`
 	}
 	rawFieldRule := new(RawFieldRule)
 	err := json.Unmarshal(msg, rawFieldRule)
@@ -120,6 +144,8 @@ func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
 
 	rule := new(router.RoutingRule)
 	rule.Tag = rawFieldRule.OutboundTag
+	rule.Policy = rawFieldRule.Policy
+	rule.ConnectionTag = rawFieldRule.ConnTag
 
 	if rawFieldRule.Domain != nil {
 		for _, domain := range *rawFieldRule.Domain {
@@ -145,7 +171,7 @@ func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
 	}
 
 	if rawFieldRule.Port != nil {
-		rule.PortRange = rawFieldRule.Port.Build()
+		rule.PortList = rawFieldRule.Port.Build()
 	}
 
 	if rawFieldRule.Network != nil {
@@ -173,6 +199,12 @@ func parseFieldRule(msg json.RawMessage) (*router.RoutingRule, error) {
 		}
 	}
 
+	if rawFieldRule.Protocol != nil {
+		for _, s := range *rawFieldRule.Protocol {
+			rule.Protocol = append(rule.Protocol, s)
+		}
+	}
+
 	return rule, nil
 }
 
@@ -219,16 +251,25 @@ func parseChinaIPRule(data []byte) (*router.RoutingRule, error) {
 		log.Error("Router: Invalid router rule: ", err)
 		return nil, err
 	}
-	var chinaIPs geoip.CountryIPRange
-	if err := proto.Unmarshal(geoip.ChinaIPs, &chinaIPs); err != nil {
+	chinaIPs, err := loadChinaIPs()
+	if err != nil {
 		return nil, err
 	}
 	return &router.RoutingRule{
 		Tag:  rawRule.OutboundTag,
-		Cidr: chinaIPs.Ips,
+		Cidr: chinaIPs,
 	}, nil
 }
 
+// loadChinaIPs returns IP ranges of China from the GeoIP data.
+func loadChinaIPs() ([]*router.CIDR, error) {
+	var chinaIPs geoip.CountryIPRange
+	if err := proto.Unmarshal(geoip.ChinaIPs, &chinaIPs); err != nil {
+		return nil, err
+	}
+	return chinaIPs.Ips, nil
+}
+
 func parseChinaSitesRule(data []byte) (*router.RoutingRule, error) {
 	rawRule := new(RouterRule)
 	err := json.Unmarshal(data, rawRule)
@@ -241,3 +282,14 @@ func parseChinaSitesRule(data []byte) (*router.RoutingRule, error) {
 		Domain: chinaSitesDomains,
 	}, nil
 }
+
+func init() {
+	// Routing settings pushed at runtime are in the same format as "routing" object in config file.
+	router.RulesLoader = func(data []byte) (*router.Config, error) {
+		config := new(RouterConfig)
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, errors.New("Router: Invalid routing settings: " + err.Error())
+		}
+		return config.Build()
+	}
+}
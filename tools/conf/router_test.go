@@ -163,3 +163,86 @@ func TestSourceIPRule(t *testing.T) {
 		Source: v2net.TCPDestination(v2net.IPAddress([]byte{192, 0, 0, 1}), 80),
 	})).IsTrue()
 }
+
+func TestPortListRule(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "domain": [
+      "v2ray.com"
+    ],
+    "port": "80,443,1000-2000",
+    "outboundTag": "direct"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	cond, err := rule.BuildCondition()
+	assert.Error(err).IsNil()
+	assert.Bool(cond.Apply(&proxy.SessionInfo{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("www.v2ray.com"), 443),
+	})).IsTrue()
+	assert.Bool(cond.Apply(&proxy.SessionInfo{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("www.v2ray.com"), 1500),
+	})).IsTrue()
+	assert.Bool(cond.Apply(&proxy.SessionInfo{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("www.v2ray.com"), 8080),
+	})).IsFalse()
+	assert.Bool(cond.Apply(&proxy.SessionInfo{
+		Destination: v2net.TCPDestination(v2net.DomainAddress("www.ooxx.com"), 80),
+	})).IsFalse()
+}
+
+func TestNetworkRule(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "network": "udp",
+    "outboundTag": "gaming"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	cond, err := rule.BuildCondition()
+	assert.Error(err).IsNil()
+	assert.Bool(cond.Apply(&proxy.SessionInfo{
+		Destination: v2net.UDPDestination(v2net.IPAddress([]byte{8, 8, 8, 8}), 53),
+	})).IsTrue()
+	assert.Bool(cond.Apply(&proxy.SessionInfo{
+		Destination: v2net.TCPDestination(v2net.IPAddress([]byte{8, 8, 8, 8}), 53),
+	})).IsFalse()
+
+	rule = ParseRule([]byte(`{
+    "type": "field",
+    "network": "tcp, udp",
+    "outboundTag": "direct"
+  }`))
+	cond, err = rule.BuildCondition()
+	assert.Error(err).IsNil()
+	assert.Bool(cond.Apply(&proxy.SessionInfo{
+		Destination: v2net.UDPDestination(v2net.IPAddress([]byte{8, 8, 8, 8}), 53),
+	})).IsTrue()
+	assert.Bool(cond.Apply(&proxy.SessionInfo{
+		Destination: v2net.TCPDestination(v2net.IPAddress([]byte{8, 8, 8, 8}), 53),
+	})).IsTrue()
+}
+
+func TestConnectionTagRule(t *testing.T) {
+	assert := assert.On(t)
+
+	rule := ParseRule([]byte(`{
+    "type": "field",
+    "connectionTag": {
+      "tenant": "x"
+    },
+    "outboundTag": "group-b"
+  }`))
+	assert.Pointer(rule).IsNotNil()
+	cond, err := rule.BuildCondition()
+	assert.Error(err).IsNil()
+	assert.Bool(cond.Apply(&proxy.SessionInfo{
+		Tags: map[string]string{"tenant": "x"},
+	})).IsTrue()
+	assert.Bool(cond.Apply(&proxy.SessionInfo{
+		Tags: map[string]string{"tenant": "y"},
+	})).IsFalse()
+	assert.Bool(cond.Apply(&proxy.SessionInfo{})).IsFalse()
+}
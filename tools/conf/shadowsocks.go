@@ -2,44 +2,111 @@ package conf
 
 import (
 	"errors"
+	"net/url"
 	"strings"
 
 	"v2ray.com/core/common/loader"
+	"v2ray.com/core/common/log"
 	"v2ray.com/core/common/protocol"
 	"v2ray.com/core/proxy/shadowsocks"
 )
 
-type ShadowsocksServerConfig struct {
+// ShadowsocksUDPAccount overrides cipher and password for UDP relay. Unset fields are the same as TCP.
+type ShadowsocksUDPAccount struct {
 	Cipher   string `json:"method"`
 	Password string `json:"password"`
-	UDP      bool   `json:"udp"`
-	Level    byte   `json:"level"`
-	Email    string `json:"email"`
+}
+
+func (this *ShadowsocksUDPAccount) Build() (*shadowsocks.UDPAccount, error) {
+	account := &shadowsocks.UDPAccount{
+		Password: this.Password,
+	}
+	if len(this.Cipher) > 0 {
+		cipherType, err := shadowsocks.CipherTypeFromName(this.Cipher)
+		if err != nil {
+			return nil, err
+		}
+		account.CipherType = cipherType
+	}
+	return account, nil
+}
+
+type ShadowsocksServerConfig struct {
+	Cipher     string                 `json:"method"`
+	Password   string                 `json:"password"`
+	UDP        bool                   `json:"udp"`
+	UDPAccount *ShadowsocksUDPAccount `json:"udpAccount"`
+	Level      byte                   `json:"level"`
+	Email      string                 `json:"email"`
+	Workers    uint32                 `json:"cipherWorkers"`
+
+	MaxDomainLength uint32                           `json:"maxDomainLength"`
+	Quota           *ShadowsocksQuotaConfig          `json:"quota"`
+	Compression     bool                             `json:"compression"`
+	HandshakeLimit  *ShadowsocksHandshakeLimitConfig `json:"handshakeLimit"`
+	ConnectionReuse bool                             `json:"connectionReuse"`
+}
+
+type ShadowsocksQuotaConfig struct {
+	MonthlyData    uint64 `json:"monthlyData"`
+	MaxConnections uint32 `json:"maxConnections"`
+}
+
+type ShadowsocksHandshakeLimitConfig struct {
+	Concurrency   uint32 `json:"concurrency"`
+	QueueTimeout  uint32 `json:"queueTimeout"`
+	HeaderTimeout uint32 `json:"headerTimeout"`
+}
+
+func (this *ShadowsocksHandshakeLimitConfig) Build() *shadowsocks.HandshakeLimitConfig {
+	if this == nil {
+		return nil
+	}
+	return &shadowsocks.HandshakeLimitConfig{
+		Concurrency:   this.Concurrency,
+		QueueTimeout:  this.QueueTimeout,
+		HeaderTimeout: this.HeaderTimeout,
+	}
 }
 
 func (this *ShadowsocksServerConfig) Build() (*loader.TypedSettings, error) {
 	config := new(shadowsocks.ServerConfig)
 	config.UdpEnabled = this.UDP
 
+	if this.MaxDomainLength > shadowsocks.MaxDomainLength {
+		return nil, errors.New("Shadowsocks maxDomainLength must not exceed 255.")
+	}
+	config.MaxDomainLength = this.MaxDomainLength
+	config.Compression = this.Compression
+	config.ConnectionReuse = this.ConnectionReuse
+	config.HandshakeLimit = this.HandshakeLimit.Build()
+	if this.Quota != nil {
+		config.Quota = &shadowsocks.QuotaConfig{
+			// Monthly data is configured in MB.
+			MonthlyBytes:   this.Quota.MonthlyData * 1024 * 1024,
+			MaxConnections: this.Quota.MaxConnections,
+		}
+	}
+
 	if len(this.Password) == 0 {
 		return nil, errors.New("Shadowsocks password is not specified.")
 	}
 	account := &shadowsocks.Account{
-		Password: this.Password,
-		Ota:      shadowsocks.Account_Auto,
-	}
-	cipher := strings.ToLower(this.Cipher)
-	switch cipher {
-	case "aes-256-cfb":
-		account.CipherType = shadowsocks.CipherType_AES_256_CFB
-	case "aes-128-cfb":
-		account.CipherType = shadowsocks.CipherType_AES_128_CFB
-	case "chacha20":
-		account.CipherType = shadowsocks.CipherType_CHACHA20
-	case "chacha20-ietf":
-		account.CipherType = shadowsocks.CipherType_CHACHA20_IEFT
-	default:
-		return nil, errors.New("Unknown cipher method: " + cipher)
+		Password:      this.Password,
+		Ota:           shadowsocks.Account_Auto,
+		CipherWorkers: this.Workers,
+	}
+	cipherType, err := shadowsocks.CipherTypeFromName(this.Cipher)
+	if err != nil {
+		return nil, err
+	}
+	account.CipherType = cipherType
+	if this.UDPAccount != nil {
+		udpAccount, err := this.UDPAccount.Build()
+		if err != nil {
+			return nil, err
+		}
+		account.Udp = udpAccount
 	}
 
 	config.User = &protocol.User{
@@ -52,27 +119,496 @@ func (this *ShadowsocksServerConfig) Build() (*loader.TypedSettings, error) {
 }
 
 type ShadowsocksServerTarget struct {
-	Address  *Address `json:"address"`
-	Port     uint16   `json:"port"`
-	Cipher   string   `json:"method"`
-	Password string   `json:"password"`
-	Email    string   `json:"email"`
-	Ota      bool     `json:"ota"`
+	Address     *Address `json:"address"`
+	Port        uint16   `json:"port"`
+	Cipher      string   `json:"method"`
+	Password    string   `json:"password"`
+	Email       string   `json:"email"`
+	Ota         bool     `json:"ota"`
+	Plugin      string   `json:"plugin"`
+	PluginOpts  string   `json:"pluginOpts"`
+	Weight      uint32   `json:"weight"`
+	URI         string   `json:"uri"`
+	Workers     uint32   `json:"cipherWorkers"`
+	Region      string   `json:"region"`
+	Compression bool     `json:"compression"`
+	Reuse       *bool    `json:"connectionReuse"`
+
+	UDPAccount *ShadowsocksUDPAccount `json:"udpAccount"`
+}
+
+// applyURI fills in the fields of this target from its ss:// URI. Fields set explicitly take precedence.
+func (this *ShadowsocksServerTarget) applyURI() error {
+	uri, err := shadowsocks.ParseURI(this.URI)
+	if err != nil {
+		return errors.New("Invalid Shadowsocks URI: " + err.Error())
+	}
+	if this.Address == nil {
+		this.Address = &Address{Address: uri.Address}
+	}
+	if this.Port == 0 {
+		this.Port = uint16(uri.Port)
+	}
+	if len(this.Cipher) == 0 {
+		this.Cipher = uri.Account.CipherType.Name()
+	}
+	if len(this.Password) == 0 {
+		this.Password = uri.Account.Password
+	}
+	if len(this.Plugin) == 0 {
+		this.Plugin = uri.Account.Plugin
+		this.PluginOpts = uri.Account.PluginOpts
+	}
+	if len(this.Email) == 0 {
+		this.Email = uri.Tag
+	}
+	if uri.Account.Compression {
+		this.Compression = true
+	}
+	if this.Reuse == nil && uri.Account.ConnectionReuse != shadowsocks.Account_ReuseAuto {
+		reuse := uri.Account.ConnectionReuse == shadowsocks.Account_ReuseEnabled
+		this.Reuse = &reuse
+	}
+	return nil
+}
+
+type ShadowsocksSubscriptionConfig struct {
+	URL        string `json:"url"`
+	Interval   uint32 `json:"interval"`
+	MaxServers uint32 `json:"maxServers"`
+	MaxSize    uint32 `json:"maxSize"`
+}
+
+func (this *ShadowsocksSubscriptionConfig) Build() (*shadowsocks.Subscription, error) {
+	u, err := url.Parse(this.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.New("Invalid Shadowsocks subscription URL: " + this.URL)
+	}
+	return &shadowsocks.Subscription{
+		Url:             this.URL,
+		RefreshInterval: this.Interval,
+		MaxServers:      this.MaxServers,
+		MaxSize:         this.MaxSize,
+	}, nil
 }
 
 type ShadowsocksClientConfig struct {
-	Servers []*ShadowsocksServerTarget `json:"servers"`
+	Servers      []*ShadowsocksServerTarget         `json:"servers"`
+	Subscription *ShadowsocksSubscriptionConfig     `json:"subscription"`
+	Log          *ShadowsocksDispatchLogConfig      `json:"log"`
+	Redundancy   *ShadowsocksRedundancyConfig       `json:"redundancy"`
+	Breaker      *ShadowsocksBreakerConfig          `json:"circuitBreaker"`
+	DialLimit    uint32                             `json:"dialConcurrency"`
+	ProxyHeader  uint32                             `json:"proxyProtocol"`
+	StickyTime   uint32                             `json:"stickyTimeout"`
+	BufferSize   uint32                             `json:"bufferSize"`
+	Delay        uint32                             `json:"handshakeDelay"`
+	DomainServer []*ShadowsocksDomainServerConfig   `json:"domainServers"`
+	Warmup       *ShadowsocksWarmupConfig           `json:"warmup"`
+	TrailingData *ShadowsocksTrailingDataConfig     `json:"trailingData"`
+	HealthCheck  *ShadowsocksHealthCheckConfig      `json:"healthCheck"`
+	Adaptive     *ShadowsocksAdaptiveTimeoutConfig  `json:"adaptiveTimeout"`
+	MaxLifetime  uint32                             `json:"maxConnectionLifetime"`
+	Watchdog     *ShadowsocksPipeWatchdogConfig     `json:"pipeWatchdog"`
+	Regions      []*ShadowsocksGeoRegionConfig      `json:"geoRegions"`
+	ExpectUDP    bool                               `json:"expectUdpResponse"`
+	Handshakes   *ShadowsocksHandshakeLimitConfig   `json:"handshakeLimit"`
+	Bandwidth    *ShadowsocksBandwidthLimitConfig   `json:"bandwidthLimit"`
+	Failover     *ShadowsocksResponseFailoverConfig `json:"responseFailover"`
+	Reuse        *ShadowsocksConnectionReuseConfig  `json:"connectionReuse"`
+	ConnectRetry *ShadowsocksConnectRetryConfig     `json:"connectRetry"`
+	SpeedTest    *ShadowsocksSpeedTestConfig        `json:"speedTest"`
+	IPv6Dest     string                             `json:"ipv6Destination"`
+}
+
+type ShadowsocksBandwidthLimitConfig struct {
+	Rate  uint64 `json:"rate"`
+	Burst uint64 `json:"burst"`
+}
+
+type ShadowsocksGeoRegionConfig struct {
+	Name      string   `json:"name"`
+	IP        []string `json:"ip"`
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+}
+
+func (this *ShadowsocksGeoRegionConfig) Build() (*shadowsocks.GeoRegion, error) {
+	if len(this.Name) == 0 {
+		return nil, errors.New("Shadowsocks region name is not specified.")
+	}
+	region := &shadowsocks.GeoRegion{
+		Name:      this.Name,
+		Latitude:  this.Latitude,
+		Longitude: this.Longitude,
+	}
+	for _, ip := range this.IP {
+		if ip == "geoip:cn" {
+			chinaIPs, err := loadChinaIPs()
+			if err != nil {
+				return nil, err
+			}
+			region.Cidr = append(region.Cidr, chinaIPs...)
+			continue
+		}
+		cidr := parseIP(ip)
+		if cidr == nil {
+			return nil, errors.New("Invalid IP range in Shadowsocks region " + this.Name + ": " + ip)
+		}
+		region.Cidr = append(region.Cidr, cidr)
+	}
+	return region, nil
+}
+
+type ShadowsocksPipeWatchdogConfig struct {
+	StallThreshold uint32 `json:"stallThreshold"`
+	CloseStalled   bool   `json:"closeStalled"`
+}
+
+type ShadowsocksHealthCheckConfig struct {
+	Mode      string   `json:"mode"`
+	Interval  uint32   `json:"interval"`
+	Timeout   uint32   `json:"timeout"`
+	Address   *Address `json:"address"`
+	Port      uint16   `json:"port"`
+	Handshake bool     `json:"handshake"`
+}
+
+func (this *ShadowsocksHealthCheckConfig) Build() (*shadowsocks.HealthCheckConfig, error) {
+	config := &shadowsocks.HealthCheckConfig{
+		Interval:  this.Interval,
+		Timeout:   this.Timeout,
+		Port:      uint32(this.Port),
+		Handshake: this.Handshake,
+	}
+	switch strings.ToLower(this.Mode) {
+	case "", "roundtrip":
+		config.Mode = shadowsocks.HealthCheckConfig_RoundTrip
+	case "connectonly":
+		config.Mode = shadowsocks.HealthCheckConfig_ConnectOnly
+	default:
+		return nil, errors.New("Unknown Shadowsocks health check mode: " + this.Mode)
+	}
+	if this.Address != nil {
+		config.Address = this.Address.Build()
+	}
+	return config, nil
+}
+
+type ShadowsocksWarmupConfig struct {
+	Connections uint32 `json:"connections"`
+	IdleTimeout uint32 `json:"idleTimeout"`
+}
+
+type ShadowsocksTrailingDataConfig struct {
+	Strict  bool   `json:"strict"`
+	MaxSize uint32 `json:"maxSize"`
+}
+
+type ShadowsocksAdaptiveTimeoutConfig struct {
+	Multiplier uint32 `json:"multiplier"`
+	MinTimeout uint32 `json:"minTimeout"`
+	MaxTimeout uint32 `json:"maxTimeout"`
+}
+
+type ShadowsocksDomainServerConfig struct {
+	Domains []string `json:"domains"`
+	Address *Address `json:"address"`
+	Port    uint16   `json:"port"`
+}
+
+func (this *ShadowsocksDomainServerConfig) Build() (*shadowsocks.DomainServerRule, error) {
+	if len(this.Domains) == 0 {
+		return nil, errors.New("No domain in Shadowsocks domain servers.")
+	}
+	if this.Address == nil || this.Port == 0 {
+		return nil, errors.New("Invalid Shadowsocks server for domains: " + strings.Join(this.Domains, ","))
+	}
+	return &shadowsocks.DomainServerRule{
+		Domain:  this.Domains,
+		Address: this.Address.Build(),
+		Port:    uint32(this.Port),
+	}, nil
+}
+
+type ShadowsocksBreakerConfig struct {
+	Failures    uint32 `json:"failures"`
+	OpenTimeout uint32 `json:"openTimeout"`
+	Successes   uint32 `json:"successes"`
+}
+
+func (this *ShadowsocksBreakerConfig) Build() *protocol.CircuitBreakerConfig {
+	return &protocol.CircuitBreakerConfig{
+		FailureThreshold: this.Failures,
+		OpenTimeout:      this.OpenTimeout,
+		SuccessThreshold: this.Successes,
+	}
+}
+
+type ShadowsocksRedundancyConfig struct {
+	Copies uint32   `json:"copies"`
+	Ports  []uint16 `json:"ports"`
+}
+
+func (this *ShadowsocksRedundancyConfig) Build() (*shadowsocks.RedundancyConfig, error) {
+	if this.Copies < 2 || this.Copies > 3 {
+		return nil, errors.New("Shadowsocks redundancy copies must be 2 or 3.")
+	}
+	config := &shadowsocks.RedundancyConfig{
+		Copies: this.Copies,
+	}
+	for _, port := range this.Ports {
+		if port == 0 {
+			return nil, errors.New("Invalid Shadowsocks redundancy port.")
+		}
+		config.Port = append(config.Port, uint32(port))
+	}
+	return config, nil
+}
+
+type ShadowsocksResponseFailoverConfig struct {
+	Timeout     uint32   `json:"timeout"`
+	Ports       []uint16 `json:"ports"`
+	MaxAttempts uint32   `json:"maxAttempts"`
+}
+
+func (this *ShadowsocksResponseFailoverConfig) Build() (*shadowsocks.ResponseFailoverConfig, error) {
+	if this.Timeout == 0 {
+		return nil, errors.New("Shadowsocks response failover timeout must be positive.")
+	}
+	if len(this.Ports) == 0 {
+		return nil, errors.New("Shadowsocks response failover requires ports of idempotent requests.")
+	}
+	config := &shadowsocks.ResponseFailoverConfig{
+		Timeout:     this.Timeout,
+		MaxAttempts: this.MaxAttempts,
+	}
+	for _, port := range this.Ports {
+		if port == 0 {
+			return nil, errors.New("Invalid Shadowsocks response failover port.")
+		}
+		config.Port = append(config.Port, uint32(port))
+	}
+	return config, nil
+}
+
+type ShadowsocksConnectionReuseConfig struct {
+	IdleTimeout uint32 `json:"idleTimeout"`
+	MaxIdle     uint32 `json:"maxIdle"`
+}
+
+type ShadowsocksConnectRetryConfig struct {
+	ServerAttempts uint32 `json:"serverAttempts"`
+	PerServer      uint32 `json:"perServer"`
+}
+
+type ShadowsocksSpeedTestConfig struct {
+	DownloadURL string `json:"downloadUrl"`
+	UploadURL   string `json:"uploadUrl"`
+}
+
+type ShadowsocksDispatchLogConfig struct {
+	Start         string `json:"start"`
+	Success       string `json:"success"`
+	Failure       string `json:"failure"`
+	Slow          string `json:"slow"`
+	SlowThreshold uint32 `json:"slowThreshold"`
+}
+
+func parseDispatchLogLevel(level string, defaultLevel log.LogLevel) (log.LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "":
+		return defaultLevel, nil
+	case "debug":
+		return log.LogLevel_Debug, nil
+	case "info":
+		return log.LogLevel_Info, nil
+	case "warning":
+		return log.LogLevel_Warning, nil
+	case "error":
+		return log.LogLevel_Error, nil
+	case "none":
+		return log.LogLevel_Disabled, nil
+	default:
+		return log.LogLevel_Disabled, errors.New("Unknown log level: " + level)
+	}
+}
+
+func (this *ShadowsocksDispatchLogConfig) Build() (*shadowsocks.DispatchLogConfig, error) {
+	config := new(shadowsocks.DispatchLogConfig)
+	var err error
+	if config.Start, err = parseDispatchLogLevel(this.Start, log.LogLevel_Info); err != nil {
+		return nil, err
+	}
+	if config.Success, err = parseDispatchLogLevel(this.Success, log.LogLevel_Disabled); err != nil {
+		return nil, err
+	}
+	if config.Failure, err = parseDispatchLogLevel(this.Failure, log.LogLevel_Warning); err != nil {
+		return nil, err
+	}
+	if config.Slow, err = parseDispatchLogLevel(this.Slow, log.LogLevel_Warning); err != nil {
+		return nil, err
+	}
+	config.SlowThreshold = this.SlowThreshold
+	return config, nil
 }
 
 func (this *ShadowsocksClientConfig) Build() (*loader.TypedSettings, error) {
 	config := new(shadowsocks.ClientConfig)
 
-	if len(this.Servers) == 0 {
+	if this.Subscription != nil {
+		subscription, err := this.Subscription.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Subscription = subscription
+	} else if len(this.Servers) == 0 {
 		return nil, errors.New("0 Shadowsocks server configured.")
 	}
 
+	if this.Log != nil {
+		dispatchLog, err := this.Log.Build()
+		if err != nil {
+			return nil, errors.New("Invalid Shadowsocks log settings: " + err.Error())
+		}
+		config.DispatchLog = dispatchLog
+	}
+
+	if this.Redundancy != nil {
+		redundancy, err := this.Redundancy.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Redundancy = redundancy
+	}
+
+	if this.Failover != nil {
+		failover, err := this.Failover.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.ResponseFailover = failover
+	}
+
+	if this.ConnectRetry != nil {
+		config.ConnectRetry = &shadowsocks.ConnectRetryConfig{
+			ServerAttempts: this.ConnectRetry.ServerAttempts,
+			PerServer:      this.ConnectRetry.PerServer,
+		}
+	}
+
+	switch strings.ToLower(this.IPv6Dest) {
+	case "", "asis":
+		config.Ipv6Destination = shadowsocks.IPv6DestinationPolicy_AsIs
+	case "reject":
+		config.Ipv6Destination = shadowsocks.IPv6DestinationPolicy_Reject
+	case "sniffdomain":
+		config.Ipv6Destination = shadowsocks.IPv6DestinationPolicy_SniffDomain
+	default:
+		return nil, errors.New("Unknown Shadowsocks IPv6 destination policy: " + this.IPv6Dest)
+	}
+
+	if this.SpeedTest != nil {
+		config.SpeedTest = &shadowsocks.SpeedTestConfig{
+			DownloadUrl: this.SpeedTest.DownloadURL,
+			UploadUrl:   this.SpeedTest.UploadURL,
+		}
+	}
+
+	if this.Reuse != nil {
+		config.ConnectionReuse = &shadowsocks.ConnectionReuseConfig{
+			IdleTimeout: this.Reuse.IdleTimeout,
+			MaxIdle:     this.Reuse.MaxIdle,
+		}
+	}
+
+	if this.Breaker != nil {
+		config.CircuitBreaker = this.Breaker.Build()
+	}
+	config.DialConcurrency = this.DialLimit
+
+	if this.ProxyHeader > 2 {
+		return nil, errors.New("Unknown PROXY protocol version for Shadowsocks.")
+	}
+	config.ProxyProtocol = this.ProxyHeader
+	config.StickyTimeout = this.StickyTime
+	// Buffer size is configured in KB.
+	config.BufferSize = this.BufferSize * 1024
+	config.HandshakeDelay = this.Delay
+	config.MaxConnectionLifetime = this.MaxLifetime
+	if len(this.Regions) > 0 {
+		config.GeoProximity = new(shadowsocks.GeoProximityConfig)
+		for _, regionConfig := range this.Regions {
+			region, err := regionConfig.Build()
+			if err != nil {
+				return nil, err
+			}
+			config.GeoProximity.Region = append(config.GeoProximity.Region, region)
+		}
+	}
+	config.HandshakeLimit = this.Handshakes.Build()
+	if this.Bandwidth != nil {
+		config.BandwidthLimit = &shadowsocks.BandwidthLimitConfig{
+			// Rate is configured in KB/s, and burst in KB.
+			Rate:  this.Bandwidth.Rate * 1024,
+			Burst: this.Bandwidth.Burst * 1024,
+		}
+	}
+	if this.ExpectUDP {
+		config.UdpResponse = &shadowsocks.UDPResponseConfig{
+			Expected: true,
+		}
+	}
+	if this.Watchdog != nil {
+		config.PipeWatchdog = &shadowsocks.PipeWatchdogConfig{
+			StallThreshold: this.Watchdog.StallThreshold,
+			CloseStalled:   this.Watchdog.CloseStalled,
+		}
+	}
+	for _, server := range this.DomainServer {
+		rule, err := server.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.DomainServer = append(config.DomainServer, rule)
+	}
+	if this.Warmup != nil {
+		config.Warmup = &shadowsocks.WarmupConfig{
+			Connections: this.Warmup.Connections,
+			IdleTimeout: this.Warmup.IdleTimeout,
+		}
+	}
+	if this.HealthCheck != nil {
+		healthCheck, err := this.HealthCheck.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.HealthCheck = healthCheck
+	}
+	if this.TrailingData != nil {
+		config.TrailingData = &shadowsocks.TrailingDataConfig{
+			Strict:  this.TrailingData.Strict,
+			MaxSize: this.TrailingData.MaxSize,
+		}
+	}
+	if this.Adaptive != nil {
+		if this.Adaptive.MaxTimeout > 0 && this.Adaptive.MaxTimeout < this.Adaptive.MinTimeout {
+			return nil, errors.New("Shadowsocks adaptive timeout has maximum below minimum.")
+		}
+		config.AdaptiveTimeout = &shadowsocks.AdaptiveTimeoutConfig{
+			Multiplier: this.Adaptive.Multiplier,
+			MinTimeout: this.Adaptive.MinTimeout,
+			MaxTimeout: this.Adaptive.MaxTimeout,
+		}
+	}
+
 	serverSpecs := make([]*protocol.ServerEndpoint, len(this.Servers))
 	for idx, server := range this.Servers {
+		if len(server.URI) > 0 {
+			if err := server.applyURI(); err != nil {
+				return nil, err
+			}
+		}
 		if server.Address == nil {
 			return nil, errors.New("Shadowsocks server address is not set.")
 		}
@@ -83,29 +619,45 @@ func (this *ShadowsocksClientConfig) Build() (*loader.TypedSettings, error) {
 			return nil, errors.New("Shadowsocks password is not specified.")
 		}
 		account := &shadowsocks.Account{
-			Password: server.Password,
-			Ota:      shadowsocks.Account_Enabled,
+			Password:      server.Password,
+			Ota:           shadowsocks.Account_Enabled,
+			CipherWorkers: server.Workers,
+			Compression:   server.Compression,
 		}
 		if !server.Ota {
 			account.Ota = shadowsocks.Account_Disabled
 		}
-		cipher := strings.ToLower(server.Cipher)
-		switch cipher {
-		case "aes-256-cfb":
-			account.CipherType = shadowsocks.CipherType_AES_256_CFB
-		case "aes-128-cfb":
-			account.CipherType = shadowsocks.CipherType_AES_128_CFB
-		case "chacha20":
-			account.CipherType = shadowsocks.CipherType_CHACHA20
-		case "chacha20-ietf":
-			account.CipherType = shadowsocks.CipherType_CHACHA20_IEFT
-		default:
-			return nil, errors.New("Unknown cipher method: " + cipher)
+		if server.Reuse != nil {
+			account.ConnectionReuse = shadowsocks.Account_ReuseDisabled
+			if *server.Reuse {
+				account.ConnectionReuse = shadowsocks.Account_ReuseEnabled
+			}
+		}
+		if len(server.Plugin) > 0 {
+			account.Plugin = server.Plugin
+			account.PluginOpts = server.PluginOpts
+			if err := account.ValidatePlugin(); err != nil {
+				return nil, errors.New("Invalid Shadowsocks plugin: " + err.Error())
+			}
+		}
+		cipherType, err := shadowsocks.CipherTypeFromName(server.Cipher)
+		if err != nil {
+			return nil, err
+		}
+		account.CipherType = cipherType
+		if server.UDPAccount != nil {
+			udpAccount, err := server.UDPAccount.Build()
+			if err != nil {
+				return nil, err
+			}
+			account.Udp = udpAccount
 		}
 
 		ss := &protocol.ServerEndpoint{
 			Address: server.Address.Build(),
 			Port:    uint32(server.Port),
+			Weight:  server.Weight,
+			Region:  server.Region,
 			User: []*protocol.User{
 				{
 					Email:   server.Email,
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"v2ray.com/core/common/log"
 	"v2ray.com/core/proxy/shadowsocks"
 	"v2ray.com/core/testing/assert"
 	. "v2ray.com/core/tools/conf"
@@ -36,3 +37,77 @@ func TestShadowsocksServerConfigParsing(t *testing.T) {
 	assert.Int(account.Cipher.KeySize()).Equals(16)
 	assert.Bytes(account.Key).Equals([]byte{160, 224, 26, 2, 22, 110, 9, 80, 65, 52, 80, 20, 38, 243, 224, 241})
 }
+
+func TestShadowsocksClientDispatchLogParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "servers": [{
+      "address": "127.0.0.1",
+      "port": 8388,
+      "method": "aes-128-cfb",
+      "password": "v2ray-password"
+    }],
+    "log": {
+      "success": "info",
+      "failure": "error",
+      "slowThreshold": 500
+    }
+  }`
+
+	rawConfig := new(ShadowsocksClientConfig)
+	err := json.Unmarshal([]byte(rawJson), rawConfig)
+	assert.Error(err).IsNil()
+
+	ts, err := rawConfig.Build()
+	assert.Error(err).IsNil()
+	iConfig, err := ts.GetInstance()
+	assert.Error(err).IsNil()
+	config := iConfig.(*shadowsocks.ClientConfig)
+
+	assert.Bool(config.DispatchLog.Start == log.LogLevel_Info).IsTrue()
+	assert.Bool(config.DispatchLog.Success == log.LogLevel_Info).IsTrue()
+	assert.Bool(config.DispatchLog.Failure == log.LogLevel_Error).IsTrue()
+	assert.Bool(config.DispatchLog.Slow == log.LogLevel_Warning).IsTrue()
+	assert.Uint32(config.DispatchLog.SlowThreshold).Equals(500)
+
+	rawConfig.Log.Start = "verbose"
+	_, err = rawConfig.Build()
+	assert.Error(err).IsNotNil()
+}
+
+func TestShadowsocksClientDomainServersParsing(t *testing.T) {
+	assert := assert.On(t)
+
+	rawJson := `{
+    "servers": [{
+      "address": "127.0.0.1",
+      "port": 8388,
+      "method": "aes-128-cfb",
+      "password": "v2ray-password"
+    }],
+    "domainServers": [{
+      "domains": ["*.example.com"],
+      "address": "127.0.0.1",
+      "port": 8388
+    }]
+  }`
+
+	rawConfig := new(ShadowsocksClientConfig)
+	err := json.Unmarshal([]byte(rawJson), rawConfig)
+	assert.Error(err).IsNil()
+
+	ts, err := rawConfig.Build()
+	assert.Error(err).IsNil()
+	iConfig, err := ts.GetInstance()
+	assert.Error(err).IsNil()
+	config := iConfig.(*shadowsocks.ClientConfig)
+
+	assert.Int(len(config.DomainServer)).Equals(1)
+	assert.String(config.DomainServer[0].Domain[0]).Equals("*.example.com")
+	assert.Uint32(config.DomainServer[0].Port).Equals(8388)
+
+	rawConfig.DomainServer[0].Port = 0
+	_, err = rawConfig.Build()
+	assert.Error(err).IsNotNil()
+}
@@ -3,9 +3,11 @@ package conf
 import (
 	"encoding/json"
 	"errors"
+	"net"
 
 	"v2ray.com/core/common/loader"
 	"v2ray.com/core/common/protocol"
+	"v2ray.com/core/proxy"
 	"v2ray.com/core/proxy/socks"
 )
 
@@ -21,17 +23,37 @@ func (this *SocksAccount) Build() *socks.Account {
 	}
 }
 
+// OutboundSelectionConfig is the outbound selection of SOCKS and HTTP servers.
+type OutboundSelectionConfig struct {
+	Trusted []string `json:"trusted"`
+}
+
+func (this *OutboundSelectionConfig) Build() (*proxy.OutboundSelectionConfig, error) {
+	for _, network := range this.Trusted {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			return nil, errors.New("Invalid trusted network of outbound selection: " + network)
+		}
+	}
+	return &proxy.OutboundSelectionConfig{
+		TrustedNetwork: this.Trusted,
+	}, nil
+}
+
 const (
 	AuthMethodNoAuth   = "noauth"
 	AuthMethodUserPass = "password"
 )
 
 type SocksServerConfig struct {
-	AuthMethod string          `json:"auth"`
-	Accounts   []*SocksAccount `json:"accounts"`
-	UDP        bool            `json:"udp"`
-	Host       *Address        `json:"ip"`
-	Timeout    uint32          `json:"timeout"`
+	AuthMethod string                   `json:"auth"`
+	Accounts   []*SocksAccount          `json:"accounts"`
+	UDP        bool                     `json:"udp"`
+	Host       *Address                 `json:"ip"`
+	Timeout    uint32                   `json:"timeout"`
+	Auth       string                   `json:"authenticator"`
+	UDPGrace   uint32                   `json:"udpGracePeriod"`
+	MaxUDP     uint32                   `json:"maxUdpAssociations"`
+	Selection  *OutboundSelectionConfig `json:"outboundSelection"`
 }
 
 func (this *SocksServerConfig) Build() (*loader.TypedSettings, error) {
@@ -51,11 +73,28 @@ func (this *SocksServerConfig) Build() (*loader.TypedSettings, error) {
 		}
 	}
 
+	if len(this.Auth) > 0 {
+		if config.AuthType != socks.AuthType_PASSWORD {
+			return nil, errors.New("Socks authenticator requires password auth.")
+		}
+		config.Authenticator = this.Auth
+	}
+
 	config.UdpEnabled = this.UDP
+	config.UdpGracePeriod = this.UDPGrace
+	config.MaxUdpAssociations = this.MaxUDP
 	if this.Host != nil {
 		config.Address = this.Host.Build()
 	}
 
+	if this.Selection != nil {
+		selection, err := this.Selection.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.OutboundSelection = selection
+	}
+
 	config.Timeout = this.Timeout
 	return loader.NewTypedSettings(config), nil
 }
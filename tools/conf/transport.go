@@ -9,9 +9,11 @@ import (
 )
 
 type TransportConfig struct {
-	TCPConfig *TCPConfig       `json:"tcpSettings"`
-	KCPConfig *KCPConfig       `json:"kcpSettings"`
-	WSConfig  *WebSocketConfig `json:"wsSettings"`
+	TCPConfig            *TCPConfig       `json:"tcpSettings"`
+	KCPConfig            *KCPConfig       `json:"kcpSettings"`
+	WSConfig             *WebSocketConfig `json:"wsSettings"`
+	GRPCConfig           *GRPCConfig      `json:"grpcSettings"`
+	BufferedWriterMemory uint32           `json:"bufferedWriterMemory"`
 }
 
 func (this *TransportConfig) Build() (*transport.Config, error) {
@@ -49,5 +51,18 @@ func (this *TransportConfig) Build() (*transport.Config, error) {
 			Settings: ts,
 		})
 	}
+
+	if this.GRPCConfig != nil {
+		ts, err := this.GRPCConfig.Build()
+		if err != nil {
+			return nil, errors.New("Failed to build gRPC config: " + err.Error())
+		}
+		config.NetworkSettings = append(config.NetworkSettings, &internet.NetworkSettings{
+			Network:  v2net.Network_GRPC,
+			Settings: ts,
+		})
+	}
+	// Memory limit is configured in KB.
+	config.BufferedWriterMemory = this.BufferedWriterMemory * 1024
 	return config, nil
 }
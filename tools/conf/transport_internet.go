@@ -5,11 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net"
+	"strconv"
 
 	"strings"
 	"v2ray.com/core/common/loader"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/transport/internet"
+	"v2ray.com/core/transport/internet/grpc"
 	"v2ray.com/core/transport/internet/kcp"
 	"v2ray.com/core/transport/internet/tcp"
 	"v2ray.com/core/transport/internet/tls"
@@ -127,11 +131,13 @@ func (this *TCPConfig) Build() (*loader.TypedSettings, error) {
 type WebSocketConfig struct {
 	ConnectionReuse *bool  `json:"connectionReuse"`
 	Path            string `json:"Path"`
+	Host            string `json:"host"`
 }
 
 func (this *WebSocketConfig) Build() (*loader.TypedSettings, error) {
 	config := &ws.Config{
 		Path: this.Path,
+		Host: this.Host,
 	}
 	if this.ConnectionReuse != nil {
 		config.ConnectionReuse = &ws.ConnectionReuse{
@@ -141,13 +147,39 @@ func (this *WebSocketConfig) Build() (*loader.TypedSettings, error) {
 	return loader.NewTypedSettings(config), nil
 }
 
+type GRPCConfig struct {
+	ServiceName        string `json:"serviceName"`
+	Path               string `json:"path"`
+	IdleTimeout        uint32 `json:"idleTimeout"`
+	HealthCheckTimeout uint32 `json:"healthCheckTimeout"`
+}
+
+func (this *GRPCConfig) Build() (*loader.TypedSettings, error) {
+	if len(this.Path) > 0 && !strings.HasPrefix(this.Path, "/") {
+		return nil, errors.New("gRPC path must start with '/': " + this.Path)
+	}
+	return loader.NewTypedSettings(&grpc.Config{
+		ServiceName:        this.ServiceName,
+		Path:               this.Path,
+		IdleTimeout:        this.IdleTimeout,
+		HealthCheckTimeout: this.HealthCheckTimeout,
+	}), nil
+}
+
 type TLSCertConfig struct {
 	CertFile string `json:"certificateFile"`
 	KeyFile  string `json:"keyFile"`
 }
 type TLSConfig struct {
-	Insecure bool             `json:"allowInsecure"`
-	Certs    []*TLSCertConfig `json:"certificates"`
+	Insecure          bool             `json:"allowInsecure"`
+	Certs             []*TLSCertConfig `json:"certificates"`
+	SessionResumption *bool            `json:"sessionResumption"`
+	SessionCacheSize  uint32           `json:"sessionCacheSize"`
+	ServerName        string           `json:"serverName"`
+	MinVersion        string           `json:"minVersion"`
+	MaxVersion        string           `json:"maxVersion"`
+	CipherSuites      []string         `json:"cipherSuites"`
+	Curves            []string         `json:"curvePreferences"`
 }
 
 func (this *TLSConfig) Build() (*loader.TypedSettings, error) {
@@ -168,21 +200,158 @@ func (this *TLSConfig) Build() (*loader.TypedSettings, error) {
 		}
 	}
 	config.AllowInsecure = this.Insecure
+	if this.SessionResumption != nil {
+		config.DisableSessionResumption = !*this.SessionResumption
+	}
+	config.SessionCacheSize = this.SessionCacheSize
+	config.ServerName = this.ServerName
+	if len(this.MinVersion) > 0 {
+		version, err := tls.ParseVersion(this.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		config.MinVersion = version
+	}
+	if len(this.MaxVersion) > 0 {
+		version, err := tls.ParseVersion(this.MaxVersion)
+		if err != nil {
+			return nil, err
+		}
+		config.MaxVersion = version
+	}
+	if config.MinVersion != tls.Version_Default && config.MaxVersion != tls.Version_Default && config.MinVersion > config.MaxVersion {
+		return nil, errors.New("TLS: Min version " + this.MinVersion + " is above max version " + this.MaxVersion + ".")
+	}
+	for _, name := range this.CipherSuites {
+		suite, err := tls.ParseCipherSuite(name)
+		if err != nil {
+			return nil, err
+		}
+		config.CipherSuites = append(config.CipherSuites, suite)
+	}
+	for _, name := range this.Curves {
+		curve, err := tls.ParseCurve(name)
+		if err != nil {
+			return nil, err
+		}
+		config.CurvePreferences = append(config.CurvePreferences, curve)
+	}
 	return loader.NewTypedSettings(config), nil
 }
 
+type SocketConfig struct {
+	SendBuffer        uint32  `json:"sendBuffer"`
+	ReceiveBuffer     uint32  `json:"receiveBuffer"`
+	DisableAutotuning bool    `json:"disableAutotuning"`
+	NoDelay           *bool   `json:"noDelay"`
+	Linger            *uint32 `json:"linger"`
+	Congestion        string  `json:"congestion"`
+}
+
+func (this *SocketConfig) Build() (*internet.SocketConfig, error) {
+	// Kernel stores buffer sizes as doubled int.
+	const maxBufferSize = math.MaxInt32 / 2
+	if this.SendBuffer > maxBufferSize {
+		return nil, errors.New("Socket send buffer must not be larger than " + strconv.Itoa(maxBufferSize) + ".")
+	}
+	if this.ReceiveBuffer > maxBufferSize {
+		return nil, errors.New("Socket receive buffer must not be larger than " + strconv.Itoa(maxBufferSize) + ".")
+	}
+	config := &internet.SocketConfig{
+		SendBufferSize:    this.SendBuffer,
+		ReceiveBufferSize: this.ReceiveBuffer,
+		DisableAutotuning: this.DisableAutotuning,
+		CongestionControl: this.Congestion,
+	}
+	if this.NoDelay != nil {
+		config.DisableNoDelay = !*this.NoDelay
+	}
+	if this.Linger != nil {
+		config.Linger = &internet.LingerConfig{
+			Timeout: *this.Linger,
+		}
+	}
+	return config, nil
+}
+
 type StreamConfig struct {
-	Network     *Network         `json:"network"`
-	Security    string           `json:"security"`
-	TLSSettings *TLSConfig       `json:"tlsSettings"`
-	TCPSettings *TCPConfig       `json:"tcpSettings"`
-	KCPSettings *KCPConfig       `json:"kcpSettings"`
-	WSSettings  *WebSocketConfig `json:"wsSettings"`
+	Network        *Network         `json:"network"`
+	Security       string           `json:"security"`
+	TLSSettings    *TLSConfig       `json:"tlsSettings"`
+	TCPSettings    *TCPConfig       `json:"tcpSettings"`
+	KCPSettings    *KCPConfig       `json:"kcpSettings"`
+	WSSettings     *WebSocketConfig `json:"wsSettings"`
+	GRPCSettings   *GRPCConfig      `json:"grpcSettings"`
+	SocketSettings *SocketConfig    `json:"socketSettings"`
+	ProxyProtocol  bool             `json:"acceptProxyProtocol"`
+	RateLimit      *RateLimitConfig `json:"connectionRateLimit"`
+	DialAddress    *Address         `json:"dialAddress"`
+	SourcePort     *PortRange       `json:"sourcePortRange"`
+	Listener       *ListenerConfig  `json:"listenerSettings"`
+	Chaos          *ChaosConfig     `json:"chaos"`
+}
+
+type ChaosConfig struct {
+	Latency    uint32  `json:"latency"`
+	Jitter     uint32  `json:"jitter"`
+	PacketLoss float64 `json:"packetLoss"`
+	ResetRate  float64 `json:"resetRate"`
+}
+
+func (this *ChaosConfig) Build() (*internet.ChaosConfig, error) {
+	if this.PacketLoss < 0 || this.PacketLoss > 1 || this.ResetRate < 0 || this.ResetRate > 1 {
+		return nil, errors.New("Chaos probabilities must be between 0 and 1.")
+	}
+	return &internet.ChaosConfig{
+		Latency:    this.Latency,
+		Jitter:     this.Jitter,
+		PacketLoss: this.PacketLoss,
+		ResetRate:  this.ResetRate,
+	}, nil
+}
+
+type ListenerConfig struct {
+	Backlog      uint32 `json:"backlog"`
+	ReuseAddress bool   `json:"reuseAddress"`
+	ReusePort    bool   `json:"reusePort"`
+	Acceptors    uint32 `json:"acceptors"`
+}
+
+func (this *ListenerConfig) Build() (*internet.ListenerConfig, error) {
+	if this.Acceptors > 1 && !this.ReusePort {
+		return nil, errors.New("Multiple acceptors require reusePort.")
+	}
+	return &internet.ListenerConfig{
+		Backlog:      this.Backlog,
+		ReuseAddress: this.ReuseAddress,
+		ReusePort:    this.ReusePort,
+		Acceptors:    this.Acceptors,
+	}, nil
+}
+
+type RateLimitConfig struct {
+	Rate    uint32   `json:"rate"`
+	Burst   uint32   `json:"burst"`
+	Trusted []string `json:"trusted"`
+}
+
+func (this *RateLimitConfig) Build() (*internet.ConnectionRateLimit, error) {
+	for _, network := range this.Trusted {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			return nil, errors.New("Invalid trusted network: " + network)
+		}
+	}
+	return &internet.ConnectionRateLimit{
+		Rate:           this.Rate,
+		Burst:          this.Burst,
+		TrustedNetwork: this.Trusted,
+	}, nil
 }
 
 func (this *StreamConfig) Build() (*internet.StreamConfig, error) {
 	config := &internet.StreamConfig{
-		Network: v2net.Network_RawTCP,
+		Network:             v2net.Network_RawTCP,
+		AcceptProxyProtocol: this.ProxyProtocol,
 	}
 	if this.Network != nil {
 		config.Network = (*this.Network).Build()
@@ -228,18 +397,119 @@ func (this *StreamConfig) Build() (*internet.StreamConfig, error) {
 			Settings: ts,
 		})
 	}
+	if this.GRPCSettings != nil {
+		ts, err := this.GRPCSettings.Build()
+		if err != nil {
+			return nil, errors.New("Failed to build gRPC config: " + err.Error())
+		}
+		config.NetworkSettings = append(config.NetworkSettings, &internet.NetworkSettings{
+			Network:  v2net.Network_GRPC,
+			Settings: ts,
+		})
+	}
+	if this.SocketSettings != nil {
+		ss, err := this.SocketSettings.Build()
+		if err != nil {
+			return nil, errors.New("Failed to build socket config: " + err.Error())
+		}
+		config.SocketSettings = ss
+	}
+	if this.RateLimit != nil {
+		rl, err := this.RateLimit.Build()
+		if err != nil {
+			return nil, errors.New("Failed to build connection rate limit: " + err.Error())
+		}
+		config.ConnectionRateLimit = rl
+	}
+	if this.DialAddress != nil {
+		if this.DialAddress.Family().IsDomain() {
+			return nil, errors.New("Dial address must be an IP: " + this.DialAddress.String())
+		}
+		config.DialAddress = this.DialAddress.Build()
+	}
+	if this.SourcePort != nil {
+		if this.SourcePort.From == 0 {
+			return nil, errors.New("Source port range must not contain port 0.")
+		}
+		config.SourcePortRange = this.SourcePort.Build()
+	}
+	if this.Listener != nil {
+		ls, err := this.Listener.Build()
+		if err != nil {
+			return nil, errors.New("Failed to build listener config: " + err.Error())
+		}
+		config.ListenerSettings = ls
+	}
+	if this.Chaos != nil {
+		chaos, err := this.Chaos.Build()
+		if err != nil {
+			return nil, errors.New("Failed to build chaos config: " + err.Error())
+		}
+		config.Chaos = chaos
+	}
 	return config, nil
 }
 
+type UpstreamProxyConfig struct {
+	Address  *Address `json:"address"`
+	Port     uint16   `json:"port"`
+	Username string   `json:"user"`
+	Password string   `json:"pass"`
+	Protocol string   `json:"protocol"`
+}
+
+func (this *UpstreamProxyConfig) Build() (*internet.UpstreamProxy, error) {
+	if this.Address == nil {
+		return nil, errors.New("Upstream proxy address is not set.")
+	}
+	if this.Port == 0 {
+		return nil, errors.New("Invalid upstream proxy port.")
+	}
+	var protocol internet.UpstreamProxy_Protocol
+	switch strings.ToLower(this.Protocol) {
+	case "", "socks", "socks5":
+		protocol = internet.UpstreamProxy_SOCKS5
+	case "http":
+		protocol = internet.UpstreamProxy_HTTP
+	default:
+		return nil, errors.New("Unknown upstream proxy protocol: " + this.Protocol)
+	}
+	if protocol == internet.UpstreamProxy_SOCKS5 && (len(this.Username) > 255 || len(this.Password) > 255) {
+		return nil, errors.New("Upstream proxy username or password is too long.")
+	}
+	if len(this.Username) == 0 && len(this.Password) > 0 {
+		return nil, errors.New("Upstream proxy password is set without username.")
+	}
+	return &internet.UpstreamProxy{
+		Address:  this.Address.Build(),
+		Port:     uint32(this.Port),
+		Username: this.Username,
+		Password: this.Password,
+		Protocol: protocol,
+	}, nil
+}
+
 type ProxyConfig struct {
-	Tag string `json:"tag"`
+	Tag      string               `json:"tag"`
+	Upstream *UpstreamProxyConfig `json:"upstream"`
 }
 
 func (this *ProxyConfig) Build() (*internet.ProxyConfig, error) {
-	if len(this.Tag) == 0 {
+	if len(this.Tag) == 0 && this.Upstream == nil {
 		return nil, errors.New("Proxy tag is not set.")
 	}
-	return &internet.ProxyConfig{
+	if len(this.Tag) > 0 && this.Upstream != nil {
+		return nil, errors.New("Proxy tag and upstream proxy can't be used together.")
+	}
+	config := &internet.ProxyConfig{
 		Tag: this.Tag,
-	}, nil
+	}
+	if this.Upstream != nil {
+		upstream, err := this.Upstream.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.Upstream = upstream
+	}
+	return config, nil
 }
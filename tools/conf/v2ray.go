@@ -13,6 +13,7 @@ import (
 
 var (
 	inboundConfigLoader = NewJSONConfigLoader(ConfigCreatorCache{
+		"dns":           func() interface{} { return new(DnsServerConfig) },
 		"dokodemo-door": func() interface{} { return new(DokodemoConfig) },
 		"http":          func() interface{} { return new(HttpServerConfig) },
 		"shadowsocks":   func() interface{} { return new(ShadowsocksServerConfig) },
@@ -29,12 +30,13 @@ var (
 )
 
 type InboundConnectionConfig struct {
-	Port          uint16          `json:"port"`
-	Listen        *Address        `json:"listen"`
-	Protocol      string          `json:"protocol"`
-	StreamSetting *StreamConfig   `json:"streamSettings"`
-	Settings      json.RawMessage `json:"settings"`
-	AllowPassive  bool            `json:"allowPassive"`
+	Port          uint16            `json:"port"`
+	Listen        *Address          `json:"listen"`
+	Protocol      string            `json:"protocol"`
+	StreamSetting *StreamConfig     `json:"streamSettings"`
+	Settings      json.RawMessage   `json:"settings"`
+	AllowPassive  bool              `json:"allowPassive"`
+	Tags          map[string]string `json:"connectionTags"`
 }
 
 func (this *InboundConnectionConfig) Build() (*core.InboundConnectionConfig, error) {
@@ -57,6 +59,7 @@ func (this *InboundConnectionConfig) Build() (*core.InboundConnectionConfig, err
 		config.StreamSettings = ts
 	}
 	config.AllowPassiveConnection = this.AllowPassive
+	config.ConnectionTags = this.Tags
 
 	jsonConfig, err := inboundConfigLoader.LoadWithID(this.Settings, this.Protocol)
 	if err != nil {
@@ -76,6 +79,8 @@ type OutboundConnectionConfig struct {
 	StreamSetting *StreamConfig   `json:"streamSettings"`
 	ProxySettings *ProxyConfig    `json:"proxySettings"`
 	Settings      json.RawMessage `json:"settings"`
+	// Maximum KB in flight in connections of the outbound.
+	MaxInFlight uint32 `json:"maxInFlightSize"`
 }
 
 func (this *OutboundConnectionConfig) Build() (*core.OutboundConnectionConfig, error) {
@@ -89,6 +94,7 @@ func (this *OutboundConnectionConfig) Build() (*core.OutboundConnectionConfig, e
 		return nil, err
 	}
 	config.Settings = ts
+	config.MaxInFlightBytes = uint64(this.MaxInFlight) * 1024
 
 	if this.SendThrough != nil {
 		address := this.SendThrough
@@ -156,6 +162,7 @@ type InboundDetourConfig struct {
 	Allocation    *InboundDetourAllocationConfig `json:"allocate"`
 	StreamSetting *StreamConfig                  `json:"streamSettings"`
 	AllowPassive  bool                           `json:"allowPassive"`
+	Tags          map[string]string              `json:"connectionTags"`
 }
 
 func (this *InboundDetourConfig) Build() (*core.InboundConnectionConfig, error) {
@@ -187,6 +194,7 @@ func (this *InboundDetourConfig) Build() (*core.InboundConnectionConfig, error)
 		config.StreamSettings = ss
 	}
 	config.AllowPassiveConnection = this.AllowPassive
+	config.ConnectionTags = this.Tags
 
 	rawConfig, err := inboundConfigLoader.LoadWithID(this.Settings, this.Protocol)
 	if err != nil {
@@ -207,11 +215,14 @@ type OutboundDetourConfig struct {
 	Settings      json.RawMessage `json:"settings"`
 	StreamSetting *StreamConfig   `json:"streamSettings"`
 	ProxySettings *ProxyConfig    `json:"proxySettings"`
+	// Maximum KB in flight in connections of the outbound.
+	MaxInFlight uint32 `json:"maxInFlightSize"`
 }
 
 func (this *OutboundDetourConfig) Build() (*core.OutboundConnectionConfig, error) {
 	config := new(core.OutboundConnectionConfig)
 	config.Tag = this.Tag
+	config.MaxInFlightBytes = uint64(this.MaxInFlight) * 1024
 
 	if this.SendThrough != nil {
 		address := this.SendThrough
@@ -259,10 +270,14 @@ type Config struct {
 	InboundDetours  []InboundDetourConfig     `json:"inboundDetour"`
 	OutboundDetours []OutboundDetourConfig    `json:"outboundDetour"`
 	Transport       *TransportConfig          `json:"transport"`
+	ApiConfig       *ApiConfig                `json:"api"`
+	Observatory     *ObservatoryConfig        `json:"observatory"`
+	FailClosed      bool                      `json:"failClosed"`
 }
 
 func (this *Config) Build() (*core.Config, error) {
 	config := new(core.Config)
+	config.FailClosed = this.FailClosed
 
 	if this.LogConfig != nil {
 		config.Log = this.LogConfig.Build()
@@ -285,7 +300,27 @@ func (this *Config) Build() (*core.Config, error) {
 	}
 
 	if this.DNSConfig != nil {
-		config.App = append(config.App, loader.NewTypedSettings(this.DNSConfig.Build()))
+		dnsConfig, err := this.DNSConfig.Build()
+		if err != nil {
+			return nil, errors.New("Failed to build DNS config: " + err.Error())
+		}
+		config.App = append(config.App, loader.NewTypedSettings(dnsConfig))
+	}
+
+	if this.ApiConfig != nil {
+		apiConfig, err := this.ApiConfig.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.App = append(config.App, loader.NewTypedSettings(apiConfig))
+	}
+
+	if this.Observatory != nil {
+		observatoryConfig, err := this.Observatory.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.App = append(config.App, loader.NewTypedSettings(observatoryConfig))
 	}
 
 	if this.InboundConfig == nil {
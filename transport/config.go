@@ -1,6 +1,7 @@
 package transport
 
 import (
+	v2io "v2ray.com/core/common/io"
 	"v2ray.com/core/transport/internet"
 )
 
@@ -12,5 +13,6 @@ func (this *Config) Apply() error {
 	if err := internet.ApplyGlobalNetworkSettings(this.NetworkSettings); err != nil {
 		return err
 	}
+	v2io.SetBufferedWriterMemoryLimit(int64(this.BufferedWriterMemory))
 	return nil
 }
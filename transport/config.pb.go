@@ -32,6 +32,10 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 // Global transport settings. This affects all type of connections that go through V2Ray.
 type Config struct {
 	NetworkSettings []*v2ray_core_transport_internet.NetworkSettings `protobuf:"bytes,1,rep,name=network_settings,json=networkSettings" json:"network_settings,omitempty"`
+	// Maximum bytes of buffers that all connections allocate to cache first payloads, before their requests
+	// are sent. Each caching connection takes a buffer of 8 KB. Beyond the limit, connections wait until
+	// others send their requests, though one connection can always cache. 0 for unlimited.
+	BufferedWriterMemory uint32 `protobuf:"varint,2,opt,name=buffered_writer_memory,json=bufferedWriterMemory" json:"buffered_writer_memory,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
@@ -53,16 +57,18 @@ func init() {
 func init() { proto.RegisterFile("v2ray.com/core/transport/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 165 bytes of a gzipped FileDescriptorProto
+	// 208 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0x52, 0x2d, 0x33, 0x2a, 0x4a,
 	0xac, 0xd4, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xce, 0x2f, 0x4a, 0xd5, 0x2f, 0x29, 0x4a, 0xcc, 0x2b,
 	0x2e, 0xc8, 0x2f, 0x2a, 0xd1, 0x4f, 0xce, 0xcf, 0x4b, 0xcb, 0x4c, 0xd7, 0x2b, 0x28, 0xca, 0x2f,
 	0xc9, 0x17, 0x12, 0x81, 0x29, 0x2b, 0x4a, 0xd5, 0x83, 0x2b, 0x91, 0xd2, 0xc3, 0xa9, 0x39, 0x33,
-	0xaf, 0x24, 0xb5, 0x28, 0x2f, 0x15, 0xd5, 0x14, 0xa5, 0x64, 0x2e, 0x36, 0x67, 0x30, 0x5f, 0x28,
-	0x92, 0x4b, 0x20, 0x2f, 0xb5, 0xa4, 0x3c, 0xbf, 0x28, 0x3b, 0xbe, 0x38, 0xb5, 0xa4, 0x24, 0x33,
-	0x2f, 0xbd, 0x58, 0x82, 0x51, 0x81, 0x59, 0x83, 0xdb, 0x48, 0x4f, 0x0f, 0x9b, 0x55, 0x7a, 0x30,
-	0x03, 0xf5, 0xfc, 0x20, 0xda, 0x82, 0xa1, 0xba, 0x82, 0xf8, 0xf3, 0x50, 0x05, 0x9c, 0x8c, 0xb8,
-	0x24, 0x92, 0xf3, 0x73, 0xb1, 0x9a, 0xe2, 0xc4, 0x0d, 0xb1, 0x3e, 0x00, 0xe4, 0x9a, 0x28, 0x4e,
-	0xb8, 0x78, 0x12, 0x1b, 0xd8, 0x7d, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0x0c, 0xfb, 0x2d,
-	0x01, 0x0e, 0x01, 0x00, 0x00,
+	0xaf, 0x24, 0xb5, 0x28, 0x2f, 0x15, 0xd5, 0x14, 0xa5, 0x99, 0x8c, 0x5c, 0x6c, 0xce, 0x60, 0x01,
+	0xa1, 0x48, 0x2e, 0x81, 0xbc, 0xd4, 0x92, 0xf2, 0xfc, 0xa2, 0xec, 0xf8, 0xe2, 0xd4, 0x92, 0x92,
+	0xcc, 0xbc, 0xf4, 0x62, 0x09, 0x46, 0x05, 0x66, 0x0d, 0x6e, 0x23, 0x3d, 0x3d, 0x6c, 0x76, 0xe9,
+	0xc1, 0x4c, 0xd4, 0xf3, 0x83, 0x68, 0x0b, 0x86, 0xea, 0x0a, 0xe2, 0xcf, 0x43, 0x15, 0x10, 0x32,
+	0xe1, 0x12, 0x4b, 0x2a, 0x4d, 0x4b, 0x4b, 0x2d, 0x4a, 0x4d, 0x89, 0x2f, 0x2f, 0xca, 0x2c, 0x49,
+	0x2d, 0x8a, 0xcf, 0x4d, 0xcd, 0xcd, 0x2f, 0xaa, 0x94, 0x60, 0x52, 0x60, 0xd4, 0xe0, 0x0d, 0x12,
+	0x81, 0xc9, 0x86, 0x83, 0x25, 0x7d, 0xc1, 0x72, 0x4e, 0x46, 0x5c, 0x12, 0xc9, 0xf9, 0xb9, 0x58,
+	0xed, 0x76, 0xe2, 0x86, 0x38, 0x3a, 0x00, 0xe4, 0x89, 0x28, 0x4e, 0xb8, 0x78, 0x12, 0x1b, 0xd8,
+	0x5b, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0xe8, 0x12, 0x30, 0x83, 0x45, 0x01, 0x00, 0x00,
 }
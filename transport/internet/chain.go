@@ -0,0 +1,129 @@
+package internet
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+// OutboundChain is the list of outbound tags a connection has gone through. Untagged outbound is
+// recorded as an empty tag.
+type OutboundChain []string
+
+func (this OutboundChain) Has(tag string) bool {
+	for _, t := range this {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Append returns a new chain with the given tag appended, if it is not in the chain already.
+func (this OutboundChain) Append(tags ...string) OutboundChain {
+	chain := make(OutboundChain, len(this), len(this)+len(tags))
+	copy(chain, this)
+	for _, tag := range tags {
+		if !chain.Has(tag) {
+			chain = append(chain, tag)
+		}
+	}
+	return chain
+}
+
+func (this OutboundChain) String() string {
+	tags := make([]string, len(this))
+	for idx, tag := range this {
+		if len(tag) == 0 {
+			tag = "<default>"
+		}
+		tags[idx] = tag
+	}
+	return strings.Join(tags, " -> ")
+}
+
+// chainTracker remembers outbound connections by their local addresses. If such a connection comes
+// back to an inbound of this instance, its source address is one of them.
+type chainTracker struct {
+	sync.RWMutex
+	conns map[string]OutboundChain
+}
+
+var (
+	globalChainTracker = &chainTracker{
+		conns: make(map[string]OutboundChain),
+	}
+)
+
+// track remembers conn as dialed by the outbound with options.Tag, after the outbounds in options.Chain.
+func (this *chainTracker) track(conn Connection, options DialerOptions) Connection {
+	var local v2net.Destination
+	switch addr := conn.LocalAddr().(type) {
+	case *net.TCPAddr, *net.UDPAddr:
+		local = v2net.DestinationFromAddr(addr)
+	default:
+		return conn
+	}
+	if local.Port == 0 {
+		// Connections through proxy dialer don't have a real local address.
+		return conn
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	key := local.NetAddr()
+	this.conns[key] = options.Chain.Append(options.Tag)
+	return &trackedConnection{
+		Connection: conn,
+		key:        key,
+	}
+}
+
+// TrackOutboundChain records conn, dialed by Dial(), as dialed by the outbound with options.Tag after the
+// outbounds in options.Chain, e.g., if conn is dialed ahead of the session that uses it.
+func TrackOutboundChain(conn Connection, options DialerOptions) {
+	tracked, ok := conn.(*trackedConnection)
+	if !ok {
+		return
+	}
+
+	globalChainTracker.Lock()
+	defer globalChainTracker.Unlock()
+
+	if _, found := globalChainTracker.conns[tracked.key]; found {
+		globalChainTracker.conns[tracked.key] = options.Chain.Append(options.Tag)
+	}
+}
+
+func (this *chainTracker) untrack(key string) {
+	this.Lock()
+	defer this.Unlock()
+
+	delete(this.conns, key)
+}
+
+// FindOutboundChain returns the outbound chain of a connection from the given source, or nil if the
+// connection was not made by an outbound of this instance.
+func FindOutboundChain(src v2net.Destination) OutboundChain {
+	globalChainTracker.RLock()
+	defer globalChainTracker.RUnlock()
+
+	return globalChainTracker.conns[src.NetAddr()]
+}
+
+type trackedConnection struct {
+	Connection
+	key string
+}
+
+func (this *trackedConnection) Close() error {
+	globalChainTracker.untrack(this.key)
+	return this.Connection.Close()
+}
+
+func (this *trackedConnection) Unwrap() Connection {
+	return this.Connection
+}
@@ -0,0 +1,48 @@
+package internet_test
+
+import (
+	"net"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet"
+	_ "v2ray.com/core/transport/internet/tcp"
+)
+
+func TestOutboundChain(t *testing.T) {
+	assert := assert.On(t)
+
+	chain := OutboundChain{"ss"}.Append("freedom", "ss", "")
+	assert.Int(len(chain)).Equals(3)
+	assert.Bool(chain.Has("freedom")).IsTrue()
+	assert.Bool(chain.Has("vmess")).IsFalse()
+	assert.String(chain.String()).Equals("ss -> freedom -> <default>")
+}
+
+func TestTrackOutboundChain(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+
+	options := DialerOptions{
+		Stream: &StreamConfig{
+			Network: v2net.Network_RawTCP,
+		},
+		Tag: "ss",
+	}
+	conn, err := Dial(nil, v2net.DestinationFromAddr(listener.Addr()), options)
+	assert.Error(err).IsNil()
+	source := v2net.DestinationFromAddr(conn.LocalAddr())
+	assert.String(FindOutboundChain(source).String()).Equals("ss")
+
+	// The connection is taken by a session that has gone through another outbound.
+	options.Chain = OutboundChain{"freedom"}
+	TrackOutboundChain(conn, options)
+	assert.String(FindOutboundChain(source).String()).Equals("freedom -> ss")
+
+	conn.Close()
+	assert.Int(len(FindOutboundChain(source))).Equals(0)
+}
@@ -0,0 +1,121 @@
+package internet
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	// ChaosEnvKey is the environment variable that must be "enabled" for chaos settings to take effect.
+	ChaosEnvKey = "v2ray.chaos"
+)
+
+var (
+	ErrChaosReset = errors.New("Internet|Chaos: Connection reset by chaos.")
+
+	chaosIgnored sync.Once
+)
+
+// IsEnabled returns true if faults are injected by this config.
+func (this *ChaosConfig) IsEnabled() bool {
+	if this == nil || (this.Latency == 0 && this.Jitter == 0 && this.PacketLoss <= 0 && this.ResetRate <= 0) {
+		return false
+	}
+	if os.Getenv(ChaosEnvKey) != "enabled" {
+		chaosIgnored.Do(func() {
+			log.Warning("Internet|Chaos: Ignoring chaos settings, because ", ChaosEnvKey, " is not enabled.")
+		})
+		return false
+	}
+	return true
+}
+
+// Wrap returns conn with faults injected, or conn itself if chaos is not enabled. Packet loss only applies
+// to UDP.
+func (this *ChaosConfig) Wrap(conn Connection, network v2net.Network) Connection {
+	if !this.IsEnabled() {
+		return conn
+	}
+	log.Warning("Internet|Chaos: Injecting faults into connection to ", conn.RemoteAddr(), ".")
+	return &chaosConn{
+		Connection: conn,
+		config:     this,
+		udp:        network == v2net.Network_UDP,
+	}
+}
+
+// chaosConn is a Connection with latency, packet loss and resets injected.
+type chaosConn struct {
+	Connection
+	config *ChaosConfig
+	udp    bool
+}
+
+func (this *chaosConn) Unwrap() Connection {
+	return this.Connection
+}
+
+func (this *chaosConn) delay() {
+	latency := time.Duration(this.config.Latency) * time.Millisecond
+	if this.config.Jitter > 0 {
+		latency += time.Duration(rand.Int63n(int64(this.config.Jitter)+1)) * time.Millisecond
+	}
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+}
+
+func (this *chaosConn) lost() bool {
+	return this.udp && rand.Float64() < this.config.PacketLoss
+}
+
+// reset closes the connection at the probability of reset, and returns true if it does. TCP connections
+// are closed with SO_LINGER of 0, so that the peer sees a RST rather than a FIN.
+func (this *chaosConn) reset() bool {
+	if rand.Float64() >= this.config.ResetRate {
+		return false
+	}
+	log.Info("Internet|Chaos: Resetting connection to ", this.RemoteAddr(), ".")
+	if setter, ok := UnwrapConnection(this.Connection).(lingerSetter); ok {
+		if err := setter.SetLinger(0); err != nil {
+			log.Info("Internet|Chaos: Failed to set SO_LINGER: ", err)
+		}
+	}
+	// A reset connection is never reused.
+	this.Connection.SetReusable(false)
+	this.Connection.Close()
+	return true
+}
+
+func (this *chaosConn) Read(b []byte) (int, error) {
+	for {
+		nBytes, err := this.Connection.Read(b)
+		if err != nil {
+			return nBytes, err
+		}
+		if this.reset() {
+			return 0, ErrChaosReset
+		}
+		if this.lost() {
+			continue
+		}
+		this.delay()
+		return nBytes, nil
+	}
+}
+
+func (this *chaosConn) Write(b []byte) (int, error) {
+	if this.reset() {
+		return 0, ErrChaosReset
+	}
+	if this.lost() {
+		return len(b), nil
+	}
+	return this.Connection.Write(b)
+}
@@ -0,0 +1,113 @@
+package internet_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/testing/servers/tcp"
+	. "v2ray.com/core/transport/internet"
+)
+
+type chaosTestConnection struct {
+	net.Conn
+}
+
+func (this *chaosTestConnection) SetLinger(sec int) error {
+	return this.Conn.(*net.TCPConn).SetLinger(sec)
+}
+
+func (this *chaosTestConnection) Reusable() bool {
+	return false
+}
+
+func (this *chaosTestConnection) SetReusable(bool) {}
+
+func dialChaosTestServer(assert *assert.Assert) (Connection, func()) {
+	server := &tcp.Server{
+		MsgProcessor: func(msg []byte) []byte {
+			return msg
+		},
+	}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	conn, err := net.Dial("tcp", dest.NetAddr())
+	assert.Error(err).IsNil()
+	return &chaosTestConnection{Conn: conn}, func() {
+		conn.Close()
+		server.Close()
+	}
+}
+
+func TestChaosConnection(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &ChaosConfig{
+		Latency: 200,
+	}
+	conn, closeConn := dialChaosTestServer(assert)
+	defer closeConn()
+
+	// Settings are ignored unless enabled by the environment.
+	os.Unsetenv(ChaosEnvKey)
+	assert.Bool(config.Wrap(conn, v2net.Network_TCP) == conn).IsTrue()
+
+	os.Setenv(ChaosEnvKey, "enabled")
+	defer os.Unsetenv(ChaosEnvKey)
+	chaosConn := config.Wrap(conn, v2net.Network_TCP)
+	assert.Bool(chaosConn == conn).IsFalse()
+
+	start := time.Now()
+	_, err := chaosConn.Write([]byte("ping"))
+	assert.Error(err).IsNil()
+	buffer := make([]byte, 16)
+	nBytes, err := chaosConn.Read(buffer)
+	assert.Error(err).IsNil()
+	assert.String(string(buffer[:nBytes])).Equals("ping")
+	assert.Bool(time.Since(start) >= 200*time.Millisecond).IsTrue()
+}
+
+func TestChaosReset(t *testing.T) {
+	assert := assert.On(t)
+
+	os.Setenv(ChaosEnvKey, "enabled")
+	defer os.Unsetenv(ChaosEnvKey)
+
+	conn, closeConn := dialChaosTestServer(assert)
+	defer closeConn()
+	chaosConn := (&ChaosConfig{ResetRate: 1}).Wrap(conn, v2net.Network_TCP)
+	_, err := chaosConn.Write([]byte("ping"))
+	assert.Error(err).Equals(ErrChaosReset)
+
+	// The peer sees a RST.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	rawConn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Error(err).IsNil()
+	peer, err := listener.Accept()
+	assert.Error(err).IsNil()
+	defer peer.Close()
+	chaosConn = (&ChaosConfig{ResetRate: 1}).Wrap(&chaosTestConnection{Conn: rawConn}, v2net.Network_TCP)
+	_, err = chaosConn.Write([]byte("ping"))
+	assert.Error(err).Equals(ErrChaosReset)
+	peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = peer.Read(make([]byte, 16))
+	assert.Bool(errors.Is(err, syscall.ECONNRESET)).IsTrue()
+
+	// Packet loss doesn't apply to TCP.
+	conn, closeConn = dialChaosTestServer(assert)
+	defer closeConn()
+	chaosConn = (&ChaosConfig{PacketLoss: 1}).Wrap(conn, v2net.Network_TCP)
+	_, err = chaosConn.Write([]byte("ping"))
+	assert.Error(err).IsNil()
+	buffer := make([]byte, 16)
+	nBytes, err := chaosConn.Read(buffer)
+	assert.Error(err).IsNil()
+	assert.Int(nBytes).Equals(4)
+}
@@ -11,6 +11,12 @@ It is generated from these files:
 It has these top-level messages:
 	NetworkSettings
 	StreamConfig
+	ChaosConfig
+	ListenerConfig
+	ConnectionRateLimit
+	SocketConfig
+	LingerConfig
+	UpstreamProxy
 	ProxyConfig
 */
 package internet
@@ -20,6 +26,8 @@ import fmt "fmt"
 import math "math"
 import v2ray_core_common_net "v2ray.com/core/common/net"
 import v2ray_core_common_loader "v2ray.com/core/common/loader"
+import v2ray_core_common_net1 "v2ray.com/core/common/net"
+import v2ray_core_common_net2 "v2ray.com/core/common/net"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -32,6 +40,28 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type UpstreamProxy_Protocol int32
+
+const (
+	UpstreamProxy_SOCKS5 UpstreamProxy_Protocol = 0
+	// HTTP proxy with CONNECT method.
+	UpstreamProxy_HTTP UpstreamProxy_Protocol = 1
+)
+
+var UpstreamProxy_Protocol_name = map[int32]string{
+	0: "SOCKS5",
+	1: "HTTP",
+}
+var UpstreamProxy_Protocol_value = map[string]int32{
+	"SOCKS5": 0,
+	"HTTP":   1,
+}
+
+func (x UpstreamProxy_Protocol) String() string {
+	return proto.EnumName(UpstreamProxy_Protocol_name, int32(x))
+}
+func (UpstreamProxy_Protocol) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{7, 0} }
+
 type NetworkSettings struct {
 	// Type of network that this settings supports.
 	Network v2ray_core_common_net.Network `protobuf:"varint,1,opt,name=network,enum=v2ray.core.common.net.Network" json:"network,omitempty"`
@@ -58,6 +88,21 @@ type StreamConfig struct {
 	// Type of security. Must be a message name of the settings proto.
 	SecurityType     string                                    `protobuf:"bytes,3,opt,name=security_type,json=securityType" json:"security_type,omitempty"`
 	SecuritySettings []*v2ray_core_common_loader.TypedSettings `protobuf:"bytes,4,rep,name=security_settings,json=securitySettings" json:"security_settings,omitempty"`
+	SocketSettings   *SocketConfig                             `protobuf:"bytes,5,opt,name=socket_settings,json=socketSettings" json:"socket_settings,omitempty"`
+	// Whether inbound TCP connections start with a PROXY protocol header, v1 or v2. Only used in listeners.
+	AcceptProxyProtocol bool `protobuf:"varint,6,opt,name=accept_proxy_protocol,json=acceptProxyProtocol" json:"accept_proxy_protocol,omitempty"`
+	// Limit of new connections from each source IP. Only used in listeners.
+	ConnectionRateLimit *ConnectionRateLimit `protobuf:"bytes,7,opt,name=connection_rate_limit,json=connectionRateLimit" json:"connection_rate_limit,omitempty"`
+	// IP to connect to instead of the address of destination. The destination address is still used as
+	// TLS server name and WebSocket host, unless they are set in their own settings. Only used in dialers.
+	DialAddress *v2ray_core_common_net1.IPOrDomain `protobuf:"bytes,8,opt,name=dial_address,json=dialAddress" json:"dial_address,omitempty"`
+	// Range of local ports that outgoing connections are bound to. Any port is used if not set. Only used in
+	// dialers.
+	SourcePortRange *v2ray_core_common_net2.PortRange `protobuf:"bytes,9,opt,name=source_port_range,json=sourcePortRange" json:"source_port_range,omitempty"`
+	// Options of listening sockets of TCP and raw TCP networks. Only used in listeners.
+	ListenerSettings *ListenerConfig `protobuf:"bytes,10,opt,name=listener_settings,json=listenerSettings" json:"listener_settings,omitempty"`
+	// Faults injected into connections, for testing. Only used in dialers.
+	Chaos *ChaosConfig `protobuf:"bytes,11,opt,name=chaos" json:"chaos,omitempty"`
 }
 
 func (m *StreamConfig) Reset()                    { *m = StreamConfig{} }
@@ -79,43 +124,263 @@ func (m *StreamConfig) GetSecuritySettings() []*v2ray_core_common_loader.TypedSe
 	return nil
 }
 
+func (m *StreamConfig) GetSocketSettings() *SocketConfig {
+	if m != nil {
+		return m.SocketSettings
+	}
+	return nil
+}
+
+func (m *StreamConfig) GetConnectionRateLimit() *ConnectionRateLimit {
+	if m != nil {
+		return m.ConnectionRateLimit
+	}
+	return nil
+}
+
+func (m *StreamConfig) GetDialAddress() *v2ray_core_common_net1.IPOrDomain {
+	if m != nil {
+		return m.DialAddress
+	}
+	return nil
+}
+
+func (m *StreamConfig) GetSourcePortRange() *v2ray_core_common_net2.PortRange {
+	if m != nil {
+		return m.SourcePortRange
+	}
+	return nil
+}
+
+func (m *StreamConfig) GetListenerSettings() *ListenerConfig {
+	if m != nil {
+		return m.ListenerSettings
+	}
+	return nil
+}
+
+func (m *StreamConfig) GetChaos() *ChaosConfig {
+	if m != nil {
+		return m.Chaos
+	}
+	return nil
+}
+
+// Faults injected into outgoing connections, for testing how outbounds handle bad networks. Never use it
+// in production. The settings are ignored, unless environment variable v2ray.chaos is "enabled".
+type ChaosConfig struct {
+	// Milliseconds that each read waits before returning data.
+	Latency uint32 `protobuf:"varint,1,opt,name=latency" json:"latency,omitempty"`
+	// Maximum milliseconds added to the latency of each read at random.
+	Jitter uint32 `protobuf:"varint,2,opt,name=jitter" json:"jitter,omitempty"`
+	// Probability from 0 to 1 that a UDP packet is dropped, in either direction.
+	PacketLoss float64 `protobuf:"fixed64,3,opt,name=packet_loss,json=packetLoss" json:"packet_loss,omitempty"`
+	// Probability from 0 to 1 that a connection is reset at each read or write.
+	ResetRate float64 `protobuf:"fixed64,4,opt,name=reset_rate,json=resetRate" json:"reset_rate,omitempty"`
+}
+
+func (m *ChaosConfig) Reset()                    { *m = ChaosConfig{} }
+func (m *ChaosConfig) String() string            { return proto.CompactTextString(m) }
+func (*ChaosConfig) ProtoMessage()               {}
+func (*ChaosConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+type ListenerConfig struct {
+	// Maximum length of the queue of pending connections. Connections beyond it may be dropped silently
+	// by the system under connection storms. 0 for system default, which is net.core.somaxconn on Linux.
+	Backlog uint32 `protobuf:"varint,1,opt,name=backlog" json:"backlog,omitempty"`
+	// Whether to set SO_REUSEADDR, which allows listening on a port with connections still in TIME_WAIT.
+	// Go sets it on Unix systems by default. Only supported on Linux otherwise.
+	ReuseAddress bool `protobuf:"varint,2,opt,name=reuse_address,json=reuseAddress" json:"reuse_address,omitempty"`
+	// Whether to set SO_REUSEPORT, which allows other sockets to listen on the same port. The system
+	// spreads new connections among them. Only supported on Linux.
+	ReusePort bool `protobuf:"varint,3,opt,name=reuse_port,json=reusePort" json:"reuse_port,omitempty"`
+	// Number of sockets listening on the port, each accepting in its own goroutine. More than 1 requires
+	// reuse_port. Default to 1.
+	Acceptors uint32 `protobuf:"varint,4,opt,name=acceptors" json:"acceptors,omitempty"`
+}
+
+func (m *ListenerConfig) Reset()                    { *m = ListenerConfig{} }
+func (m *ListenerConfig) String() string            { return proto.CompactTextString(m) }
+func (*ListenerConfig) ProtoMessage()               {}
+func (*ListenerConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+// A token bucket for new connections from each source IP. Connections beyond the limit are closed
+// right after accepted.
+type ConnectionRateLimit struct {
+	// Number of new connections per second from a source IP. 0 for unlimited.
+	Rate uint32 `protobuf:"varint,1,opt,name=rate" json:"rate,omitempty"`
+	// Number of new connections a source IP can open at once. Same as rate if not set.
+	Burst uint32 `protobuf:"varint,2,opt,name=burst" json:"burst,omitempty"`
+	// Networks in CIDR notation that are not limited, e.g., "10.0.0.0/8".
+	TrustedNetwork []string `protobuf:"bytes,3,rep,name=trusted_network,json=trustedNetwork" json:"trusted_network,omitempty"`
+}
+
+func (m *ConnectionRateLimit) Reset()                    { *m = ConnectionRateLimit{} }
+func (m *ConnectionRateLimit) String() string            { return proto.CompactTextString(m) }
+func (*ConnectionRateLimit) ProtoMessage()               {}
+func (*ConnectionRateLimit) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type SocketConfig struct {
+	// Size of SO_SNDBUF in bytes. 0 for system default.
+	SendBufferSize uint32 `protobuf:"varint,1,opt,name=send_buffer_size,json=sendBufferSize" json:"send_buffer_size,omitempty"`
+	// Size of SO_RCVBUF in bytes. 0 for system default.
+	ReceiveBufferSize uint32 `protobuf:"varint,2,opt,name=receive_buffer_size,json=receiveBufferSize" json:"receive_buffer_size,omitempty"`
+	// Whether to disable buffer autotuning of the system, by fixing buffers to their current sizes.
+	// Autotuning is always disabled when a buffer size is set.
+	DisableAutotuning bool `protobuf:"varint,3,opt,name=disable_autotuning,json=disableAutotuning" json:"disable_autotuning,omitempty"`
+	// Whether to clear TCP_NODELAY, so that Nagle's algorithm coalesces small writes. This may help bulk
+	// transfer, at the cost of latency for interactive traffic. TCP_NODELAY is set by default.
+	DisableNoDelay bool `protobuf:"varint,4,opt,name=disable_no_delay,json=disableNoDelay" json:"disable_no_delay,omitempty"`
+	// SO_LINGER of TCP connections. Not set for the system default, which closes gracefully and sends
+	// unsent data in background.
+	Linger *LingerConfig `protobuf:"bytes,5,opt,name=linger" json:"linger,omitempty"`
+	// TCP congestion control algorithm of connections, e.g., "bbr". The algorithm must be available in the
+	// kernel, see net.ipv4.tcp_available_congestion_control. Only supported on Linux. Empty for the system
+	// default.
+	CongestionControl string `protobuf:"bytes,6,opt,name=congestion_control,json=congestionControl" json:"congestion_control,omitempty"`
+}
+
+func (m *SocketConfig) Reset()                    { *m = SocketConfig{} }
+func (m *SocketConfig) String() string            { return proto.CompactTextString(m) }
+func (*SocketConfig) ProtoMessage()               {}
+func (*SocketConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *SocketConfig) GetLinger() *LingerConfig {
+	if m != nil {
+		return m.Linger
+	}
+	return nil
+}
+
+type LingerConfig struct {
+	// Seconds that closing a connection blocks to send unsent data, before the connection is reset. 0 resets
+	// the connection immediately with RST, which releases resources fast but discards unsent data and
+	// doesn't tell the peer whether it received everything.
+	Timeout uint32 `protobuf:"varint,1,opt,name=timeout" json:"timeout,omitempty"`
+}
+
+func (m *LingerConfig) Reset()                    { *m = LingerConfig{} }
+func (m *LingerConfig) String() string            { return proto.CompactTextString(m) }
+func (*LingerConfig) ProtoMessage()               {}
+func (*LingerConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+// An external proxy that outgoing TCP connections go through.
+type UpstreamProxy struct {
+	Address *v2ray_core_common_net1.IPOrDomain `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Port    uint32                             `protobuf:"varint,2,opt,name=port" json:"port,omitempty"`
+	// Username and password for authentication. Authentication is not used if username is empty. HTTP
+	// proxies get them by Basic authentication, in plain text.
+	Username string                 `protobuf:"bytes,3,opt,name=username" json:"username,omitempty"`
+	Password string                 `protobuf:"bytes,4,opt,name=password" json:"password,omitempty"`
+	Protocol UpstreamProxy_Protocol `protobuf:"varint,5,opt,name=protocol,enum=v2ray.core.transport.internet.UpstreamProxy_Protocol" json:"protocol,omitempty"`
+}
+
+func (m *UpstreamProxy) Reset()                    { *m = UpstreamProxy{} }
+func (m *UpstreamProxy) String() string            { return proto.CompactTextString(m) }
+func (*UpstreamProxy) ProtoMessage()               {}
+func (*UpstreamProxy) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *UpstreamProxy) GetAddress() *v2ray_core_common_net1.IPOrDomain {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
 type ProxyConfig struct {
-	Tag string `protobuf:"bytes,1,opt,name=tag" json:"tag,omitempty"`
+	// Tag of another outbound to go through.
+	Tag      string         `protobuf:"bytes,1,opt,name=tag" json:"tag,omitempty"`
+	Upstream *UpstreamProxy `protobuf:"bytes,2,opt,name=upstream" json:"upstream,omitempty"`
 }
 
 func (m *ProxyConfig) Reset()                    { *m = ProxyConfig{} }
 func (m *ProxyConfig) String() string            { return proto.CompactTextString(m) }
 func (*ProxyConfig) ProtoMessage()               {}
-func (*ProxyConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*ProxyConfig) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *ProxyConfig) GetUpstream() *UpstreamProxy {
+	if m != nil {
+		return m.Upstream
+	}
+	return nil
+}
 
 func init() {
 	proto.RegisterType((*NetworkSettings)(nil), "v2ray.core.transport.internet.NetworkSettings")
 	proto.RegisterType((*StreamConfig)(nil), "v2ray.core.transport.internet.StreamConfig")
+	proto.RegisterType((*ChaosConfig)(nil), "v2ray.core.transport.internet.ChaosConfig")
+	proto.RegisterType((*ListenerConfig)(nil), "v2ray.core.transport.internet.ListenerConfig")
+	proto.RegisterType((*ConnectionRateLimit)(nil), "v2ray.core.transport.internet.ConnectionRateLimit")
+	proto.RegisterType((*SocketConfig)(nil), "v2ray.core.transport.internet.SocketConfig")
+	proto.RegisterType((*LingerConfig)(nil), "v2ray.core.transport.internet.LingerConfig")
+	proto.RegisterType((*UpstreamProxy)(nil), "v2ray.core.transport.internet.UpstreamProxy")
 	proto.RegisterType((*ProxyConfig)(nil), "v2ray.core.transport.internet.ProxyConfig")
+	proto.RegisterEnum("v2ray.core.transport.internet.UpstreamProxy_Protocol", UpstreamProxy_Protocol_name, UpstreamProxy_Protocol_value)
 }
 
 func init() { proto.RegisterFile("v2ray.com/core/transport/internet/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 317 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xa4, 0x92, 0xcd, 0x4a, 0xfb, 0x50,
-	0x10, 0xc5, 0x49, 0xfb, 0xe7, 0x6f, 0x7b, 0x5b, 0x6d, 0xcd, 0xaa, 0x08, 0x6a, 0xad, 0x8b, 0x66,
-	0xe3, 0x04, 0xe2, 0xc6, 0xb5, 0xdd, 0x8b, 0xa4, 0xdd, 0xe8, 0xa6, 0xc4, 0xdb, 0xb1, 0x04, 0xcd,
-	0x9d, 0x30, 0x19, 0x3f, 0xf2, 0x16, 0x3e, 0x81, 0xcf, 0x2a, 0xf9, 0xb8, 0xa1, 0x14, 0x2d, 0x82,
-	0xbb, 0x61, 0x38, 0xe7, 0xdc, 0x33, 0x3f, 0xae, 0x82, 0xd7, 0x80, 0xa3, 0x1c, 0x34, 0x25, 0xbe,
-	0x26, 0x46, 0x5f, 0x38, 0x32, 0x59, 0x4a, 0x2c, 0x7e, 0x6c, 0x04, 0xd9, 0xa0, 0xf8, 0x9a, 0xcc,
-	0x63, 0xbc, 0x86, 0x94, 0x49, 0xc8, 0x3d, 0xb6, 0x7a, 0x46, 0x68, 0xb4, 0x60, 0xb5, 0x47, 0xd3,
-	0xad, 0x38, 0x4d, 0x49, 0x42, 0xc6, 0x2f, 0x62, 0x0c, 0xca, 0x1b, 0xf1, 0x53, 0x95, 0xf3, 0x93,
-	0xf0, 0x99, 0xa2, 0x15, 0xb2, 0x2f, 0x79, 0x8a, 0x95, 0x70, 0xf2, 0xe1, 0xa8, 0xc1, 0x4d, 0x65,
-	0x9d, 0xa3, 0x48, 0x6c, 0xd6, 0x99, 0x7b, 0xa5, 0xf6, 0xea, 0xb4, 0x91, 0x33, 0x76, 0xbc, 0x83,
-	0xe0, 0x04, 0x36, 0x6a, 0x55, 0x51, 0x60, 0x50, 0xa0, 0x36, 0x86, 0x56, 0xee, 0xce, 0x54, 0x27,
-	0xab, 0x53, 0x46, 0xad, 0xb1, 0xe3, 0xf5, 0x82, 0xe9, 0x37, 0xd6, 0xaa, 0x05, 0x2c, 0xf2, 0x14,
-	0x57, 0xf6, 0xd1, 0xb0, 0x31, 0x4e, 0x3e, 0x5b, 0xaa, 0x3f, 0x17, 0xc6, 0x28, 0x99, 0x95, 0x68,
-	0xfe, 0xd0, 0xe7, 0x4e, 0x0d, 0xeb, 0x71, 0xb9, 0xd1, 0xab, 0xed, 0xf5, 0x02, 0x80, 0x9d, 0xa4,
-	0x61, 0x8b, 0x49, 0x38, 0x30, 0x5b, 0x90, 0xce, 0xd5, 0x7e, 0x86, 0xfa, 0x85, 0x63, 0xc9, 0x97,
-	0x05, 0xcf, 0x51, 0x7b, 0xec, 0x78, 0xdd, 0xb0, 0x6f, 0x97, 0xc5, 0x75, 0xee, 0x42, 0x1d, 0x36,
-	0xa2, 0xa6, 0xc0, 0xbf, 0xb2, 0xc0, 0xaf, 0xc1, 0x0c, 0x6d, 0x82, 0xdd, 0x4c, 0x4e, 0x55, 0xef,
-	0x96, 0xe9, 0x3d, 0xaf, 0xf1, 0x0c, 0x55, 0x5b, 0xa2, 0x75, 0x89, 0xa6, 0x1b, 0x16, 0xe3, 0xf5,
-	0x85, 0x3a, 0xd3, 0x94, 0xec, 0xbe, 0xf0, 0xbe, 0x63, 0xa7, 0x87, 0xff, 0xe5, 0x57, 0xb8, 0xfc,
-	0x0a, 0x00, 0x00, 0xff, 0xff, 0xa6, 0xb6, 0x2f, 0xcf, 0xad, 0x02, 0x00, 0x00,
+	// 975 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xa4, 0x55, 0xdd, 0x6e, 0x1b, 0x45,
+	0x14, 0xc6, 0x71, 0xe2, 0xac, 0x8f, 0x1d, 0xc7, 0x9e, 0x50, 0x64, 0x55, 0x14, 0x5c, 0x83, 0x14,
+	0x0b, 0xc8, 0x5a, 0x32, 0xaa, 0x84, 0xc4, 0x0d, 0xad, 0x73, 0x51, 0x44, 0xd4, 0x86, 0x71, 0xb8,
+	0xa0, 0x37, 0xab, 0xc9, 0xec, 0x89, 0xbb, 0x64, 0x3d, 0x63, 0xcd, 0xcc, 0xb6, 0xb8, 0x37, 0xbc,
+	0x00, 0x48, 0xbc, 0x0c, 0x2f, 0xc0, 0x93, 0xa1, 0x99, 0x9d, 0xd9, 0x38, 0xa1, 0x89, 0x5b, 0xf5,
+	0x6e, 0xe7, 0x9c, 0xef, 0x7c, 0xf3, 0x9d, 0x9f, 0x39, 0x0b, 0xf1, 0xab, 0x89, 0x62, 0xab, 0x98,
+	0xcb, 0xc5, 0x98, 0x4b, 0x85, 0x63, 0xa3, 0x98, 0xd0, 0x4b, 0xa9, 0xcc, 0x38, 0x13, 0x06, 0x95,
+	0x40, 0x33, 0xe6, 0x52, 0x5c, 0x64, 0xf3, 0x78, 0xa9, 0xa4, 0x91, 0xe4, 0x41, 0xc0, 0x2b, 0x8c,
+	0x2b, 0x6c, 0x1c, 0xb0, 0xf7, 0x0f, 0x6f, 0xd0, 0x71, 0xb9, 0x58, 0x48, 0x31, 0xb6, 0x34, 0x02,
+	0xcd, 0x6b, 0xa9, 0x2e, 0x4b, 0x9e, 0xdb, 0x80, 0xb9, 0x64, 0x29, 0xaa, 0xb1, 0x59, 0x2d, 0xf1,
+	0x6e, 0xa0, 0x65, 0x64, 0x69, 0xaa, 0x50, 0x6b, 0x0f, 0xfc, 0xf2, 0x76, 0xa0, 0xd3, 0xe8, 0x50,
+	0xc3, 0xbf, 0x6b, 0xb0, 0xff, 0xac, 0x54, 0x32, 0x43, 0x63, 0x32, 0x31, 0xd7, 0xe4, 0x3b, 0xd8,
+	0xf5, 0xe2, 0xfa, 0xb5, 0x41, 0x6d, 0xd4, 0x99, 0x7c, 0x16, 0xaf, 0x65, 0x59, 0xf2, 0xc4, 0x02,
+	0x4d, 0xec, 0x03, 0x69, 0x80, 0x93, 0x29, 0x44, 0xda, 0xb3, 0xf4, 0xb7, 0x06, 0xb5, 0x51, 0x6b,
+	0x72, 0xf8, 0x96, 0xd0, 0x32, 0xa9, 0xf8, 0x6c, 0xb5, 0xc4, 0x34, 0x5c, 0x4a, 0xab, 0xc0, 0xe1,
+	0xbf, 0x0d, 0x68, 0xcf, 0x8c, 0x42, 0xb6, 0x98, 0xba, 0x4a, 0x7f, 0x80, 0x9e, 0x5f, 0xa1, 0xeb,
+	0x3f, 0x93, 0x35, 0x5d, 0xf5, 0x51, 0x6b, 0x12, 0xc7, 0x77, 0x36, 0x2e, 0xbe, 0x51, 0x13, 0xba,
+	0x2f, 0x6e, 0x14, 0xe9, 0x0b, 0xd8, 0xd3, 0xc8, 0x0b, 0x95, 0x99, 0x55, 0x62, 0xdb, 0xd3, 0xaf,
+	0x0f, 0x6a, 0xa3, 0x26, 0x6d, 0x07, 0xa3, 0xcd, 0x8e, 0x9c, 0x41, 0xaf, 0x02, 0x55, 0x02, 0xb6,
+	0x07, 0xf5, 0xf7, 0x29, 0x4c, 0x37, 0x30, 0x54, 0x57, 0x9f, 0xc1, 0xbe, 0x96, 0xfc, 0x12, 0xcd,
+	0x15, 0xe7, 0x8e, 0x2b, 0xf6, 0xd7, 0x1b, 0x92, 0x9a, 0xb9, 0xa8, 0xb2, 0xaa, 0xb4, 0x53, 0x72,
+	0x54, 0xac, 0x13, 0xb8, 0xc7, 0x38, 0xc7, 0xa5, 0x49, 0x96, 0x4a, 0xfe, 0xbe, 0x4a, 0xdc, 0x7c,
+	0x70, 0x99, 0xf7, 0x1b, 0x83, 0xda, 0x28, 0xa2, 0x07, 0xa5, 0xf3, 0xd4, 0xfa, 0x4e, 0xbd, 0x8b,
+	0x5c, 0xc0, 0x3d, 0x2e, 0x85, 0x40, 0x6e, 0x32, 0x29, 0x12, 0xc5, 0x0c, 0x26, 0x79, 0xb6, 0xc8,
+	0x4c, 0x7f, 0xd7, 0xe9, 0x99, 0x6c, 0xd0, 0x33, 0xad, 0x62, 0x29, 0x33, 0x78, 0x62, 0x23, 0xe9,
+	0x01, 0xff, 0xbf, 0x91, 0x1c, 0x43, 0x3b, 0xcd, 0x58, 0x9e, 0xf8, 0x09, 0xef, 0x47, 0x8e, 0xfe,
+	0xe1, 0x2d, 0x63, 0xf0, 0xe3, 0xe9, 0x73, 0x75, 0x2c, 0x17, 0x2c, 0x13, 0xb4, 0x65, 0xc3, 0x1e,
+	0x97, 0x51, 0xe4, 0x04, 0x7a, 0x5a, 0x16, 0x8a, 0x63, 0x62, 0x65, 0x24, 0x8a, 0x89, 0x39, 0xf6,
+	0x9b, 0x8e, 0x6a, 0x70, 0x0b, 0xd5, 0xa9, 0x54, 0x86, 0x5a, 0x1c, 0xdd, 0x2f, 0x43, 0x2b, 0x03,
+	0x79, 0x01, 0xbd, 0x3c, 0xd3, 0x06, 0x05, 0xaa, 0xab, 0x3e, 0x80, 0x63, 0x3b, 0xda, 0x90, 0xf7,
+	0x89, 0x8f, 0xf3, 0x9d, 0xe8, 0x06, 0x9e, 0xaa, 0x17, 0x3f, 0xc0, 0x0e, 0x7f, 0xc9, 0xa4, 0xee,
+	0xb7, 0x1c, 0xdf, 0x57, 0x9b, 0xea, 0x68, 0xb1, 0x9e, 0xac, 0x0c, 0x1c, 0xfe, 0x01, 0xad, 0x35,
+	0x2b, 0xe9, 0xc3, 0x6e, 0xce, 0x0c, 0x0a, 0xbe, 0x72, 0x4f, 0x68, 0x8f, 0x86, 0x23, 0xf9, 0x04,
+	0x1a, 0xbf, 0x65, 0xc6, 0xa0, 0x72, 0x0f, 0x76, 0x8f, 0xfa, 0x13, 0xf9, 0x1c, 0x5a, 0x4b, 0xe6,
+	0x86, 0x2c, 0x97, 0x5a, 0xbb, 0xe9, 0xae, 0x51, 0x28, 0x4d, 0x27, 0x52, 0x6b, 0xf2, 0x00, 0x40,
+	0xa1, 0x46, 0xe3, 0xda, 0xde, 0xdf, 0x76, 0xfe, 0xa6, 0xb3, 0xd8, 0xbe, 0x0d, 0xff, 0xac, 0x41,
+	0xe7, 0x7a, 0x9e, 0x56, 0xc4, 0x39, 0xe3, 0x97, 0xb9, 0x9c, 0x07, 0x11, 0xfe, 0x68, 0x1f, 0x93,
+	0xc2, 0x42, 0x63, 0xd5, 0xe0, 0x2d, 0x37, 0x73, 0x6d, 0x67, 0x0c, 0xed, 0x73, 0x17, 0x5a, 0x90,
+	0x4d, 0xde, 0x09, 0x8a, 0xec, 0x85, 0x85, 0x76, 0x4d, 0x21, 0x9f, 0x42, 0xb3, 0x1c, 0x51, 0xa9,
+	0xb4, 0x93, 0xb3, 0x47, 0xaf, 0x0c, 0xc3, 0x97, 0x70, 0xf0, 0x96, 0x69, 0x23, 0x04, 0xb6, 0x9d,
+	0xfc, 0x52, 0x8f, 0xfb, 0x26, 0x1f, 0xc3, 0xce, 0x79, 0xa1, 0xb4, 0xf1, 0x05, 0x29, 0x0f, 0xe4,
+	0x10, 0xf6, 0x8d, 0x2a, 0xb4, 0xc1, 0x34, 0x09, 0xcb, 0xa8, 0x3e, 0xa8, 0x8f, 0x9a, 0xb4, 0xe3,
+	0xcd, 0x7e, 0x63, 0x0c, 0xff, 0xd9, 0x82, 0xf6, 0xfa, 0x43, 0x23, 0x23, 0xe8, 0x6a, 0x14, 0x69,
+	0x72, 0x5e, 0x5c, 0x5c, 0xd8, 0x59, 0xc9, 0xde, 0x84, 0xfb, 0x3a, 0xd6, 0xfe, 0xc4, 0x99, 0x67,
+	0xd9, 0x1b, 0x24, 0x31, 0x1c, 0x28, 0xe4, 0x98, 0xbd, 0xc2, 0x6b, 0xe0, 0x52, 0x47, 0xcf, 0xbb,
+	0xd6, 0xf0, 0x47, 0x40, 0xd2, 0x4c, 0xb3, 0xf3, 0x1c, 0x13, 0x56, 0x18, 0x69, 0x0a, 0x91, 0x89,
+	0xb9, 0xaf, 0x4c, 0xcf, 0x7b, 0x1e, 0x57, 0x0e, 0x2b, 0x24, 0xc0, 0x85, 0x4c, 0x52, 0xcc, 0xd9,
+	0xca, 0x15, 0x2a, 0xa2, 0x1d, 0x6f, 0x7f, 0x26, 0x8f, 0xad, 0x95, 0x4c, 0xa1, 0x91, 0x67, 0x62,
+	0x8e, 0xea, 0x1d, 0x17, 0xcb, 0x89, 0x03, 0xfb, 0x09, 0xf4, 0xa1, 0x56, 0x1d, 0x97, 0x62, 0x8e,
+	0xda, 0x2d, 0x07, 0x2e, 0x85, 0x51, 0x7e, 0x9b, 0x34, 0x69, 0xef, 0xca, 0x33, 0x2d, 0x1d, 0xc3,
+	0x11, 0xb4, 0xd7, 0x69, 0xec, 0xb4, 0x98, 0x6c, 0x81, 0xb2, 0x30, 0x61, 0x5a, 0xfc, 0x71, 0xf8,
+	0xd7, 0x16, 0xec, 0xfd, 0xb2, 0xd4, 0xee, 0x17, 0xe1, 0xf6, 0x11, 0xf9, 0x1e, 0x76, 0xc3, 0xe4,
+	0xd4, 0xde, 0x75, 0x35, 0x84, 0x08, 0x3b, 0x03, 0x6e, 0xa2, 0xca, 0x32, 0xbb, 0x6f, 0x72, 0x1f,
+	0xa2, 0x42, 0xa3, 0x12, 0x6c, 0x11, 0x16, 0x7b, 0x75, 0xb6, 0xbe, 0x25, 0xd3, 0xfa, 0xb5, 0x54,
+	0xa9, 0x2b, 0x5f, 0x93, 0x56, 0x67, 0xf2, 0x33, 0x44, 0xd5, 0xde, 0xdc, 0x71, 0xff, 0xaa, 0x47,
+	0x1b, 0x4a, 0x77, 0x2d, 0x91, 0x38, 0x6c, 0x56, 0x5a, 0xd1, 0x0c, 0x07, 0x10, 0x05, 0x2b, 0x01,
+	0x68, 0xcc, 0x9e, 0x4f, 0x7f, 0x9a, 0x3d, 0xea, 0x7e, 0x44, 0x22, 0xd8, 0x7e, 0x7a, 0x76, 0x76,
+	0xda, 0xad, 0x0d, 0x33, 0x68, 0xb9, 0x68, 0x5f, 0xb8, 0x2e, 0xd4, 0x0d, 0x2b, 0x9f, 0x58, 0x93,
+	0xda, 0x4f, 0xf2, 0x14, 0xa2, 0xc2, 0x5f, 0xe3, 0x7f, 0xcb, 0xdf, 0xbc, 0x8f, 0x2a, 0x5a, 0x45,
+	0x3f, 0x39, 0x82, 0x87, 0x5c, 0x2e, 0xee, 0x0e, 0x7e, 0x11, 0x85, 0xaf, 0xf3, 0x86, 0xcb, 0xe2,
+	0xdb, 0xff, 0x02, 0x00, 0x00, 0xff, 0xff, 0xae, 0x1e, 0xc4, 0xae, 0x56, 0x09, 0x00, 0x00,
 }
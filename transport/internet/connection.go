@@ -19,3 +19,20 @@ type Connection interface {
 type SysFd interface {
 	SysFd() (int, error)
 }
+
+// WrappedConnection is a Connection that adds behavior to another, such as tracking or fault injection.
+type WrappedConnection interface {
+	Unwrap() Connection
+}
+
+// UnwrapConnection returns the innermost connection of conn, for optional methods of the transport, such
+// as CloseWrite() of raw TCP connections. Reads and writes should still go through conn.
+func UnwrapConnection(conn Connection) Connection {
+	for {
+		wrapped, ok := conn.(WrappedConnection)
+		if !ok {
+			return conn
+		}
+		conn = wrapped.Unwrap()
+	}
+}
@@ -0,0 +1,155 @@
+package internet
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+	"v2ray.com/core/common/stats"
+)
+
+var (
+	// ThrottledConnections counts connections closed by ConnectionThrottle, by listener. Counts by source
+	// IP are kept by each throttle, as there are too many sources to keep them all.
+	ThrottledConnections = stats.NewCounterSet()
+)
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// Connections closed since the bucket was created.
+	throttled int64
+}
+
+// ConnectionThrottle limits the rate of new connections from each source IP with token buckets.
+type ConnectionThrottle struct {
+	sync.Mutex
+	name        string
+	rate        float64
+	burst       float64
+	trusted     []*net.IPNet
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+}
+
+// NewConnectionThrottle creates a ConnectionThrottle for the listener of the given name. It returns nil if
+// config has no limit.
+func NewConnectionThrottle(name string, config *ConnectionRateLimit) *ConnectionThrottle {
+	if config == nil || config.Rate == 0 {
+		return nil
+	}
+	throttle := &ConnectionThrottle{
+		name:        name,
+		rate:        float64(config.Rate),
+		burst:       float64(config.Burst),
+		buckets:     make(map[string]*tokenBucket),
+		lastCleanup: time.Now(),
+	}
+	if throttle.burst == 0 {
+		throttle.burst = throttle.rate
+	}
+	for _, network := range config.TrustedNetwork {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			log.Warning("Internet|Listener: Ignoring invalid trusted network: ", network)
+			continue
+		}
+		throttle.trusted = append(throttle.trusted, ipNet)
+	}
+	return throttle
+}
+
+func (this *ConnectionThrottle) isTrusted(ip net.IP) bool {
+	for _, network := range this.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allow returns true if a new connection from addr is within the limit, and takes a token for it.
+func (this *ConnectionThrottle) Allow(addr net.Addr) bool {
+	if this == nil {
+		return true
+	}
+	var ip net.IP
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		ip = addr.IP
+	case *net.UDPAddr:
+		ip = addr.IP
+	default:
+		return true
+	}
+	if this.isTrusted(ip) {
+		return true
+	}
+
+	key := ip.String()
+	now := time.Now()
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.cleanup(now)
+	bucket, found := this.buckets[key]
+	if !found {
+		bucket = &tokenBucket{
+			tokens: this.burst,
+			last:   now,
+		}
+		this.buckets[key] = bucket
+	}
+	bucket.tokens += now.Sub(bucket.last).Seconds() * this.rate
+	if bucket.tokens > this.burst {
+		bucket.tokens = this.burst
+	}
+	bucket.last = now
+	if bucket.tokens < 1 {
+		bucket.throttled++
+		ThrottledConnections.Get(this.name).Add(1)
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// ThrottledSources returns the number of connections closed from each source IP that is still being
+// throttled, i.e., whose bucket is not full again. Sources are forgotten along with their buckets.
+func (this *ConnectionThrottle) ThrottledSources() map[string]int64 {
+	if this == nil {
+		return nil
+	}
+	this.Lock()
+	defer this.Unlock()
+
+	now := time.Now()
+	this.cleanup(now)
+	sources := make(map[string]int64)
+	for key, bucket := range this.buckets {
+		if bucket.throttled > 0 && !this.isFull(bucket, now) {
+			sources[key] = bucket.throttled
+		}
+	}
+	return sources
+}
+
+// cleanup removes buckets that are full again, as they are the same as new ones. It must be called with
+// the lock held.
+func (this *ConnectionThrottle) cleanup(now time.Time) {
+	if now.Sub(this.lastCleanup) < time.Minute {
+		return
+	}
+	this.lastCleanup = now
+	for key, bucket := range this.buckets {
+		if this.isFull(bucket, now) {
+			delete(this.buckets, key)
+		}
+	}
+}
+
+func (this *ConnectionThrottle) isFull(bucket *tokenBucket, now time.Time) bool {
+	return bucket.tokens+now.Sub(bucket.last).Seconds()*this.rate >= this.burst
+}
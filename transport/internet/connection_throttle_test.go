@@ -0,0 +1,71 @@
+package internet_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet"
+)
+
+func TestConnectionThrottle(t *testing.T) {
+	assert := assert.On(t)
+
+	throttle := NewConnectionThrottle("test", &ConnectionRateLimit{
+		Rate:           10,
+		Burst:          3,
+		TrustedNetwork: []string{"10.0.0.0/8"},
+	})
+	source1 := &net.TCPAddr{IP: net.IP{192, 168, 0, 1}, Port: 10000}
+	source2 := &net.TCPAddr{IP: net.IP{192, 168, 0, 2}, Port: 10000}
+	trusted := &net.TCPAddr{IP: net.IP{10, 0, 0, 1}, Port: 10000}
+
+	for i := 0; i < 3; i++ {
+		assert.Bool(throttle.Allow(source1)).IsTrue()
+	}
+	assert.Bool(throttle.Allow(source1)).IsFalse()
+	assert.Bool(throttle.Allow(source2)).IsTrue()
+	for i := 0; i < 10; i++ {
+		assert.Bool(throttle.Allow(trusted)).IsTrue()
+	}
+	assert.Int64(ThrottledConnections.Get("test").Value()).Equals(1)
+	sources := throttle.ThrottledSources()
+	assert.Int(len(sources)).Equals(1)
+	assert.Int64(sources["192.168.0.1"]).Equals(1)
+
+	time.Sleep(150 * time.Millisecond)
+	assert.Bool(throttle.Allow(source1)).IsTrue()
+}
+
+func TestConnectionThrottleForgetsSources(t *testing.T) {
+	assert := assert.On(t)
+
+	throttle := NewConnectionThrottle("forget", &ConnectionRateLimit{
+		Rate:  1000,
+		Burst: 1,
+	})
+	for i := 0; i < 100; i++ {
+		source := &net.TCPAddr{IP: net.IP{192, 168, 1, byte(i)}, Port: 10000}
+		assert.Bool(throttle.Allow(source)).IsTrue()
+		assert.Bool(throttle.Allow(source)).IsFalse()
+	}
+	assert.Int64(ThrottledConnections.Get("forget").Value()).Equals(100)
+	assert.Int(len(throttle.ThrottledSources())).Equals(100)
+	for name := range ThrottledConnections.Snapshot() {
+		assert.Bool(strings.HasPrefix(name, "forget>>>")).IsFalse()
+	}
+
+	// Sources are forgotten once their buckets are full again.
+	time.Sleep(10 * time.Millisecond)
+	assert.Int(len(throttle.ThrottledSources())).Equals(0)
+}
+
+func TestConnectionThrottleDisabled(t *testing.T) {
+	assert := assert.On(t)
+
+	assert.Pointer(NewConnectionThrottle("test", &ConnectionRateLimit{})).IsNil()
+	var throttle *ConnectionThrottle
+	assert.Bool(throttle.Allow(&net.TCPAddr{IP: net.IP{192, 168, 0, 1}})).IsTrue()
+}
@@ -1,19 +1,32 @@
 package internet
 
 import (
+	"context"
 	"errors"
 	"net"
 
+	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 )
 
 var (
 	ErrUnsupportedStreamType = errors.New("Unsupported stream type.")
+	ErrInvalidDialAddress    = errors.New("Internet: Dial address must be an IP.")
 )
 
 type DialerOptions struct {
 	Stream *StreamConfig
 	Proxy  *ProxyConfig
+	// Tag of the outbound that makes this connection.
+	Tag string
+	// Outbounds the session of this connection has gone through before the outbound with Tag, or nil if
+	// the session comes from outside.
+	Chain OutboundChain
+	// Range of local ports to bind, or nil for any port.
+	SourcePortRange *v2net.PortRange
+	// Context that cancels connecting to the destination, whose deadline, if any, replaces the default
+	// timeout of system dialers. Nil for the default timeout.
+	Context context.Context
 }
 
 type Dialer func(src v2net.Address, dest v2net.Destination, options DialerOptions) (Connection, error)
@@ -24,6 +37,7 @@ var (
 	RawTCPDialer Dialer
 	UDPDialer    Dialer
 	WSDialer     Dialer
+	GRPCDialer   Dialer
 	ProxyDialer  Dialer
 )
 
@@ -42,6 +56,8 @@ func Dial(src v2net.Address, dest v2net.Destination, options DialerOptions) (Con
 			connection, err = KCPDialer(src, dest, options)
 		case v2net.Network_WebSocket:
 			connection, err = WSDialer(src, dest, options)
+		case v2net.Network_GRPC:
+			connection, err = GRPCDialer(src, dest, options)
 
 			// This check has to be the last one.
 		case v2net.Network_RawTCP:
@@ -50,15 +66,74 @@ func Dial(src v2net.Address, dest v2net.Destination, options DialerOptions) (Con
 			return nil, ErrUnsupportedStreamType
 		}
 		if err != nil {
-			return nil, err
+			// Errors that transports don't attribute to a layer are blamed on their top layer.
+			layers := options.Stream.TransportLayers(dest.Network)
+			return nil, NewLayerError(layers[len(layers)-1], err)
 		}
 
-		return connection, nil
+		connection = options.Stream.GetChaos().Wrap(connection, dest.Network)
+		return globalChainTracker.track(connection, options), nil
 	}
 
-	return UDPDialer(src, dest, options)
+	connection, err = UDPDialer(src, dest, options)
+	if err != nil {
+		return nil, NewLayerError(LayerUDP, err)
+	}
+	connection = options.Stream.GetChaos().Wrap(connection, dest.Network)
+	return globalChainTracker.track(connection, options), nil
 }
 
 func DialToDest(src v2net.Address, dest v2net.Destination) (net.Conn, error) {
-	return effectiveSystemDialer.Dial(src, dest)
+	return dialToDest(nil, src, dest, "", nil)
+}
+
+// dialToDest dials to dest by system dialer, on behalf of the outbound with tag, with socket buffers of
+// settings. Connecting is cancelled by ctx, if not nil.
+func dialToDest(ctx context.Context, src v2net.Address, dest v2net.Destination, tag string, settings *SocketConfig) (net.Conn, error) {
+	if resolver := effectiveDomainResolver; resolver != nil && dest.Address.Family().IsDomain() {
+		return dialResolved(ctx, resolver, src, dest, tag, settings)
+	}
+	return dialSystem(ctx, src, dest, settings)
+}
+
+// DialToDestWithOptions dials to dest by system dialer, or through the upstream proxy if there is one,
+// and applies socket settings in the given options. The address of dest is replaced by the dial address
+// in stream settings, if there is one. The local port is taken from the source port range in options, if
+// set, unless the connection goes through the upstream proxy.
+func DialToDestWithOptions(src v2net.Address, dest v2net.Destination, options DialerOptions) (net.Conn, error) {
+	if dialAddress := options.Stream.GetDialAddress(); dialAddress != nil {
+		address := dialAddress.AsAddress()
+		if address.Family().IsDomain() {
+			return nil, ErrInvalidDialAddress
+		}
+		dest.Address = address
+	}
+
+	settings := options.Stream.GetSocketSettings()
+	if options.Proxy.HasUpstream() {
+		conn, err := options.Proxy.Upstream.Dial(src, dest)
+		if err != nil {
+			return nil, err
+		}
+		if err := settings.Apply(conn); err != nil {
+			log.Warning("Internet: Failed to apply socket settings: ", err)
+		}
+		return conn, nil
+	}
+
+	// Socket buffers are set while dialing, before connecting.
+	var conn net.Conn
+	var err error
+	if options.SourcePortRange != nil {
+		conn, err = dialFromPortRange(options.Context, src, dest, options.SourcePortRange, options.Tag, settings)
+	} else {
+		conn, err = dialToDest(options.Context, src, dest, options.Tag, settings)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := settings.applyConnected(conn); err != nil {
+		log.Warning("Internet: Failed to apply socket settings: ", err)
+	}
+	return conn, nil
 }
@@ -1,7 +1,10 @@
 package internet_test
 
 import (
+	"context"
+	"net"
 	"testing"
+	"time"
 
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/testing/assert"
@@ -36,3 +39,161 @@ func TestDialWithLocalAddr(t *testing.T) {
 	assert.String(conn.RemoteAddr().String()).Equals("127.0.0.1:" + dest.Port.String())
 	conn.Close()
 }
+
+type staticResolver map[string][]net.IP
+
+func (this staticResolver) Get(domain string) []net.IP {
+	return this[domain]
+}
+
+func TestDialWithDomainResolver(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	UseDomainResolver(staticResolver{
+		// The first IP is not reachable.
+		"www.v2ray.com": []net.IP{net.IP{127, 0, 0, 2}, net.IP{127, 0, 0, 1}},
+	})
+	defer UseDomainResolver(nil)
+
+	conn, err := DialToDest(v2net.LocalHostIP, v2net.TCPDestination(v2net.DomainAddress("www.v2ray.com"), dest.Port))
+	assert.Error(err).IsNil()
+	assert.String(conn.RemoteAddr().String()).Equals("127.0.0.1:" + dest.Port.String())
+	conn.Close()
+
+	_, err = DialToDest(nil, v2net.TCPDestination(v2net.DomainAddress("v2ray.com"), dest.Port))
+	assert.Error(err).Equals(ErrDomainNotResolved)
+}
+
+func TestDialWithDialAddress(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	options := DialerOptions{
+		Stream: &StreamConfig{
+			DialAddress: v2net.NewIPOrDomain(v2net.LocalHostIP),
+		},
+	}
+	conn, err := DialToDestWithOptions(nil, v2net.TCPDestination(v2net.DomainAddress("www.v2ray.com"), dest.Port), options)
+	assert.Error(err).IsNil()
+	assert.String(conn.RemoteAddr().String()).Equals("127.0.0.1:" + dest.Port.String())
+	conn.Close()
+
+	options.Stream.DialAddress = v2net.NewIPOrDomain(v2net.DomainAddress("local.v2ray.com"))
+	_, err = DialToDestWithOptions(nil, v2net.TCPDestination(v2net.LocalHostIP, dest.Port), options)
+	assert.Error(err).Equals(ErrInvalidDialAddress)
+}
+
+func TestDialZonedIPv6(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 is not available: ", err)
+	}
+	defer listener.Close()
+
+	var zone string
+	interfaces, err := net.Interfaces()
+	assert.Error(err).IsNil()
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			zone = iface.Name
+		}
+	}
+	assert.String(zone).NotEquals("")
+
+	port := v2net.Port(listener.Addr().(*net.TCPAddr).Port)
+	conn, err := DialToDest(nil, v2net.TCPDestination(v2net.ParseAddress("::1%"+zone), port))
+	assert.Error(err).IsNil()
+	assert.String(conn.RemoteAddr().String()).Equals("[::1]:" + port.String())
+	conn.Close()
+}
+
+func TestDialSourcePortRange(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	options := DialerOptions{
+		SourcePortRange: &v2net.PortRange{
+			From: port,
+			To:   port,
+		},
+	}
+	conn, err := DialToDestWithOptions(v2net.LocalHostIP, dest, options)
+	assert.Error(err).IsNil()
+	assert.Int(conn.LocalAddr().(*net.TCPAddr).Port).Equals(int(port))
+	defer conn.Close()
+
+	_, err = DialToDestWithOptions(v2net.LocalHostIP, dest, options)
+	assert.Error(err).Equals(ErrSourcePortExhausted)
+}
+
+func TestDialSourcePortInTimeWait(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	// Closing first leaves the connection in TIME_WAIT at this side.
+	options := DialerOptions{
+		SourcePortRange: &v2net.PortRange{
+			From: port,
+			To:   port,
+		},
+	}
+	conn, err := DialToDestWithOptions(v2net.LocalHostIP, dest, options)
+	assert.Error(err).IsNil()
+	conn.Close()
+
+	other, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer other.Close()
+	conn, err = DialToDestWithOptions(v2net.LocalHostIP, v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(other.Addr().(*net.TCPAddr).Port)), options)
+	assert.Error(err).IsNil()
+	assert.Int(conn.LocalAddr().(*net.TCPAddr).Port).Equals(int(port))
+	conn.Close()
+}
+
+func TestDialWithContext(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	// A deadline past the default timeout of system dialers replaces it.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	conn, err := DialToDestWithOptions(v2net.LocalHostIP, dest, DialerOptions{Context: ctx})
+	assert.Error(err).IsNil()
+	conn.Close()
+
+	cancel()
+	_, err = DialToDestWithOptions(v2net.LocalHostIP, dest, DialerOptions{Context: ctx})
+	assert.Error(err).IsNotNil()
+}
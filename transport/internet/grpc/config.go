@@ -0,0 +1,60 @@
+// Package grpc implements a transport that tunnels connections in bidirectional gRPC streams over HTTP/2,
+// with or without TLS. Streams to the same server share HTTP/2 connections.
+package grpc
+
+import (
+	"net/http"
+	"time"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+)
+
+const (
+	defaultServiceName        = "GunService"
+	defaultHealthCheckTimeout = 15 * time.Second
+)
+
+// GetPath returns the URL path of the gRPC method that streams are opened on.
+func (this *Config) GetPath() string {
+	if len(this.Path) > 0 {
+		return this.Path
+	}
+	serviceName := this.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = defaultServiceName
+	}
+	return "/" + serviceName + "/Tun"
+}
+
+func (this *Config) GetIdleTimeout() time.Duration {
+	return time.Duration(this.IdleTimeout) * time.Second
+}
+
+func (this *Config) GetHealthCheckTimeout() time.Duration {
+	if this.HealthCheckTimeout == 0 {
+		return defaultHealthCheckTimeout
+	}
+	return time.Duration(this.HealthCheckTimeout) * time.Second
+}
+
+func (this *Config) getHTTP2Config() *http.HTTP2Config {
+	return &http.HTTP2Config{
+		SendPingTimeout: this.GetIdleTimeout(),
+		PingTimeout:     this.GetHealthCheckTimeout(),
+	}
+}
+
+// newProtocols returns HTTP/2 over TLS and over plain TCP, without HTTP/1.
+func newProtocols() *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
+
+func init() {
+	internet.RegisterNetworkConfigCreator(v2net.Network_GRPC, func() interface{} {
+		return new(Config)
+	})
+}
@@ -0,0 +1,71 @@
+// Code generated by protoc-gen-go.
+// source: v2ray.com/core/transport/internet/grpc/config.proto
+// DO NOT EDIT!
+
+/*
+Package grpc is a generated protocol buffer package.
+
+It is generated from these files:
+	v2ray.com/core/transport/internet/grpc/config.proto
+
+It has these top-level messages:
+	Config
+*/
+package grpc
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type Config struct {
+	// Name of the gRPC service. Streams are opened on method "/<service_name>/Tun". "GunService" if not
+	// set.
+	ServiceName string `protobuf:"bytes,1,opt,name=service_name,json=serviceName" json:"service_name,omitempty"`
+	// Full path of the gRPC method, e.g., "/my.app.Service/Stream". It overrides service_name if set.
+	Path string `protobuf:"bytes,2,opt,name=path" json:"path,omitempty"`
+	// Seconds without receiving any frame on a HTTP/2 connection, after which a ping is sent to check the
+	// connection. 0 for no ping.
+	IdleTimeout uint32 `protobuf:"varint,3,opt,name=idle_timeout,json=idleTimeout" json:"idle_timeout,omitempty"`
+	// Seconds to wait for the response of a ping, before the connection is closed. 15 if not set.
+	HealthCheckTimeout uint32 `protobuf:"varint,4,opt,name=health_check_timeout,json=healthCheckTimeout" json:"health_check_timeout,omitempty"`
+}
+
+func (m *Config) Reset()                    { *m = Config{} }
+func (m *Config) String() string            { return proto.CompactTextString(m) }
+func (*Config) ProtoMessage()               {}
+func (*Config) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func init() {
+	proto.RegisterType((*Config)(nil), "v2ray.core.transport.internet.grpc.Config")
+}
+
+func init() { proto.RegisterFile("v2ray.com/core/transport/internet/grpc/config.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 220 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x8f, 0x31, 0x4b, 0x03, 0x41,
+	0x10, 0x85, 0x39, 0x3d, 0x02, 0xee, 0x69, 0xb3, 0x58, 0x5c, 0x19, 0x53, 0x48, 0xaa, 0x5d, 0x31,
+	0xa5, 0x5d, 0xd2, 0x8b, 0x04, 0x2b, 0x9b, 0x63, 0x1d, 0xc7, 0xec, 0x62, 0x76, 0x67, 0x99, 0x8c,
+	0x01, 0xff, 0x87, 0x3f, 0x58, 0x76, 0x2f, 0xb9, 0xd6, 0x6e, 0xf8, 0xde, 0xfb, 0x60, 0x9e, 0x5a,
+	0x1d, 0x1f, 0xd9, 0xfd, 0x18, 0xa0, 0x68, 0x81, 0x18, 0xad, 0xb0, 0x4b, 0x87, 0x4c, 0x2c, 0x36,
+	0x24, 0x41, 0x4e, 0x28, 0x76, 0xc7, 0x19, 0x2c, 0x50, 0xfa, 0x0c, 0x3b, 0x93, 0x99, 0x84, 0xf4,
+	0xe2, 0x2c, 0x31, 0x9a, 0x49, 0x30, 0x67, 0xc1, 0x14, 0x61, 0xf1, 0xdb, 0xa8, 0xd9, 0xa6, 0x4a,
+	0xfa, 0x4e, 0x5d, 0x1f, 0x90, 0x8f, 0x01, 0x70, 0x48, 0x2e, 0x62, 0xdf, 0xcc, 0x9b, 0xe5, 0xd5,
+	0xb6, 0x3b, 0xb1, 0x67, 0x17, 0x51, 0x6b, 0xd5, 0x66, 0x27, 0xbe, 0xbf, 0xa8, 0x51, 0xbd, 0x8b,
+	0x16, 0x3e, 0xf6, 0x38, 0x48, 0x88, 0x48, 0xdf, 0xd2, 0x5f, 0xce, 0x9b, 0xe5, 0xcd, 0xb6, 0x2b,
+	0xec, 0x75, 0x44, 0xfa, 0x41, 0xdd, 0x7a, 0x74, 0x7b, 0xf1, 0x03, 0x78, 0x84, 0xaf, 0xa9, 0xda,
+	0xd6, 0xaa, 0x1e, 0xb3, 0x4d, 0x89, 0x4e, 0xc6, 0xfa, 0x49, 0xdd, 0x03, 0x45, 0xf3, 0xff, 0x80,
+	0x75, 0x37, 0x7e, 0xff, 0x52, 0x16, 0xbf, 0xb5, 0x05, 0xbd, 0xcf, 0xea, 0xfc, 0xd5, 0x5f, 0x00,
+	0x00, 0x00, 0xff, 0xff, 0x16, 0xbf, 0xb9, 0xcf, 0x35, 0x01, 0x00, 0x00,
+}
@@ -0,0 +1,290 @@
+package grpc
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/serial"
+)
+
+const (
+	// Size of payload in each message written. Larger writes are split into multiple messages.
+	maxChunkSize = 32 * 1024
+	// Largest message accepted, which is the default limit of gRPC implementations.
+	maxMessageSize = 4 * 1024 * 1024
+)
+
+var (
+	ErrInvalidMessage   = errors.New("gRPC: Invalid message.")
+	ErrClosedConnection = errors.New("gRPC: Connection is closed.")
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "gRPC: Read timed out." }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// connection is a bidirectional gRPC stream. Each gRPC message is a length-prefixed protobuf message of
+// "message Hunk { bytes data = 1; }", which carries a piece of the tunneled stream.
+type connection struct {
+	writeLock sync.Mutex
+	// Writer of the stream, which is flushed after each write if it is a http.Flusher.
+	writer io.Writer
+	// Closes the stream at client side.
+	closer   func()
+	local    net.Addr
+	remote   net.Addr
+	payloads chan []byte
+	// Error that ends payloads, which is set before payloads is closed.
+	readErr  error
+	leftover []byte
+	done     chan bool
+	// Whether writes are finished, which is guarded by writeLock.
+	writeFinished bool
+	closeOnce     sync.Once
+
+	deadlineLock sync.Mutex
+	readDeadline time.Time
+}
+
+func newConnection(writer io.Writer, closer func(), local net.Addr, remote net.Addr) *connection {
+	return &connection{
+		writer:   writer,
+		closer:   closer,
+		local:    local,
+		remote:   remote,
+		payloads: make(chan []byte),
+		done:     make(chan bool),
+	}
+}
+
+// readFrom reads messages from reader into payloads, until reader fails or the connection is closed. It
+// must be called only once.
+func (this *connection) readFrom(reader io.Reader) {
+	defer close(this.payloads)
+
+	for {
+		data, err := readMessage(reader)
+		if err != nil {
+			this.readErr = err
+			return
+		}
+		if len(data) == 0 {
+			continue
+		}
+		select {
+		case this.payloads <- data:
+		case <-this.done:
+			this.readErr = ErrClosedConnection
+			return
+		}
+	}
+}
+
+func (this *connection) Read(b []byte) (int, error) {
+	if len(this.leftover) == 0 {
+		var timeout <-chan time.Time
+		if deadline := this.getReadDeadline(); !deadline.IsZero() {
+			timer := time.NewTimer(deadline.Sub(time.Now()))
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case data, open := <-this.payloads:
+			if !open {
+				return 0, this.readErr
+			}
+			this.leftover = data
+		case <-this.done:
+			return 0, io.EOF
+		case <-timeout:
+			return 0, timeoutError{}
+		}
+	}
+	nBytes := copy(b, this.leftover)
+	this.leftover = this.leftover[nBytes:]
+	return nBytes, nil
+}
+
+func (this *connection) Write(b []byte) (int, error) {
+	this.writeLock.Lock()
+	defer this.writeLock.Unlock()
+
+	if this.writeFinished {
+		return 0, ErrClosedConnection
+	}
+	select {
+	case <-this.done:
+		return 0, ErrClosedConnection
+	default:
+	}
+	written := 0
+	for written < len(b) {
+		chunk := b[written:]
+		if len(chunk) > maxChunkSize {
+			chunk = chunk[:maxChunkSize]
+		}
+		if _, err := this.writer.Write(appendMessage(nil, chunk)); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+	}
+	if flusher, ok := this.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return written, nil
+}
+
+func (this *connection) Close() error {
+	this.closeOnce.Do(func() {
+		close(this.done)
+		if this.closer != nil {
+			this.closer()
+		}
+	})
+	return nil
+}
+
+// finishWrites waits for the write in progress, if any, and fails all writes after it.
+func (this *connection) finishWrites() {
+	this.writeLock.Lock()
+	defer this.writeLock.Unlock()
+
+	this.writeFinished = true
+}
+
+func (this *connection) LocalAddr() net.Addr {
+	return this.local
+}
+
+func (this *connection) RemoteAddr() net.Addr {
+	return this.remote
+}
+
+// SetDeadline sets the read deadline. Writes are not limited, as they are flow controlled by HTTP/2.
+func (this *connection) SetDeadline(t time.Time) error {
+	return this.SetReadDeadline(t)
+}
+
+func (this *connection) SetReadDeadline(t time.Time) error {
+	this.deadlineLock.Lock()
+	defer this.deadlineLock.Unlock()
+
+	this.readDeadline = t
+	return nil
+}
+
+func (this *connection) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (this *connection) getReadDeadline() time.Time {
+	this.deadlineLock.Lock()
+	defer this.deadlineLock.Unlock()
+
+	return this.readDeadline
+}
+
+func (this *connection) Reusable() bool {
+	return false
+}
+
+func (this *connection) SetReusable(reusable bool) {}
+
+// appendMessage appends a gRPC message of data to b.
+func appendMessage(b []byte, data []byte) []byte {
+	hunkSize := 1 + varintSize(uint64(len(data))) + len(data)
+	b = append(b, 0)
+	b = serial.Uint32ToBytes(uint32(hunkSize), b)
+	// Field 1, length delimited.
+	b = append(b, 0x0A)
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// readMessage reads a gRPC message from reader, and returns data in it.
+func readMessage(reader io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0] != 0 {
+		// Compression is never negotiated.
+		return nil, ErrInvalidMessage
+	}
+	size := serial.BytesToUint32(header[1:])
+	if size > maxMessageSize {
+		return nil, ErrInvalidMessage
+	}
+	message := make([]byte, size)
+	if _, err := io.ReadFull(reader, message); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	var data []byte
+	for len(message) > 0 {
+		key, n := readVarint(message)
+		if n == 0 {
+			return nil, ErrInvalidMessage
+		}
+		message = message[n:]
+		switch key & 7 {
+		case 0:
+			_, n = readVarint(message)
+			if n == 0 {
+				return nil, ErrInvalidMessage
+			}
+			message = message[n:]
+		case 2:
+			length, n := readVarint(message)
+			if n == 0 || uint64(len(message)-n) < length {
+				return nil, ErrInvalidMessage
+			}
+			if key>>3 == 1 {
+				data = message[n : n+int(length)]
+			}
+			message = message[n+int(length):]
+		default:
+			return nil, ErrInvalidMessage
+		}
+	}
+	return data, nil
+}
+
+func varintSize(v uint64) int {
+	size := 1
+	for v >= 0x80 {
+		v >>= 7
+		size++
+	}
+	return size
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// readVarint returns the varint at the beginning of b and its size, or 0 size if b doesn't start with a
+// valid varint.
+func readVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7F) << uint(7*i)
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,269 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+	v2tls "v2ray.com/core/transport/internet/tls"
+)
+
+const (
+	// Time to wait for a stream to be accepted, including dialing a connection for it, if the dial has no
+	// deadline.
+	streamOpenTimeout = 60 * time.Second
+	// Time that a HTTP/2 connection without streams is kept, and that a transport without streams is
+	// cached.
+	idleConnTimeout = 90 * time.Second
+)
+
+var (
+	ErrHTTP2NotNegotiated = errors.New("gRPC|Dialer: Server doesn't support HTTP/2.")
+
+	globalTransports = newTransportCache()
+)
+
+// transportKey identifies the settings that connections of a transport are dialed with.
+type transportKey struct {
+	src    string
+	dest   string
+	stream *internet.StreamConfig
+	proxy  *internet.ProxyConfig
+	tag    string
+}
+
+type cachedTransport struct {
+	transport *http.Transport
+	// URL scheme of requests, which is "https" for TLS.
+	scheme string
+	// Number of streams open on the transport, and the time that the last of them is closed. They are
+	// guarded by the lock of transportCache.
+	streams  int
+	idleFrom time.Time
+}
+
+// transportCache keeps a HTTP/2 transport for each server, so that streams to the same server share
+// HTTP/2 connections. Transports without streams for idleConnTimeout are evicted.
+type transportCache struct {
+	sync.Mutex
+	transports map[transportKey]*cachedTransport
+}
+
+func newTransportCache() *transportCache {
+	return &transportCache{
+		transports: make(map[transportKey]*cachedTransport),
+	}
+}
+
+func (this *transportCache) Get(src v2net.Address, dest v2net.Destination, options internet.DialerOptions, config *Config) (*cachedTransport, error) {
+	key := transportKey{
+		src:    src.String(),
+		dest:   dest.NetAddr(),
+		stream: options.Stream,
+		proxy:  options.Proxy,
+		tag:    options.Tag,
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.evictIdle()
+	if cached, found := this.transports[key]; found {
+		cached.streams++
+		return cached, nil
+	}
+
+	var tlsConfig *tls.Config
+	if options.Stream != nil && options.Stream.HasSecuritySettings() {
+		securitySettings, err := options.Stream.GetEffectiveSecuritySettings()
+		if err != nil {
+			log.Error("gRPC|Dialer: Failed to create security settings: ", err)
+			return nil, err
+		}
+		if tlsSettings, ok := securitySettings.(*v2tls.Config); ok {
+			tlsConfig = tlsSettings.GetTLSConfig()
+			tlsConfig.NextProtos = []string{"h2"}
+			if len(tlsConfig.ServerName) == 0 && dest.Address.Family().IsDomain() {
+				tlsConfig.ServerName = dest.Address.Domain()
+			}
+		}
+	}
+
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// The connection is dialed for the stream that opens it, but shared by streams of other dials.
+		dialOptions := options
+		dialOptions.Context = ctx
+		conn, err := internet.DialToDestWithOptions(src, dest, dialOptions)
+		if err != nil {
+			return nil, internet.NewLayerError(internet.LayerTCP, err)
+		}
+		return conn, nil
+	}
+	transport := &http.Transport{
+		DialContext: dial,
+		DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			tlsConn, err := v2tls.ClientHandshake(ctx, conn, tlsConfig)
+			if err != nil {
+				conn.Close()
+				return nil, internet.NewLayerError(internet.LayerTLS, err)
+			}
+			if tlsConn.ConnectionState().NegotiatedProtocol != "h2" {
+				conn.Close()
+				return nil, ErrHTTP2NotNegotiated
+			}
+			return tlsConn, nil
+		},
+		ForceAttemptHTTP2: true,
+		IdleConnTimeout:   idleConnTimeout,
+		Protocols:         newProtocols(),
+		HTTP2:             config.getHTTP2Config(),
+	}
+	cached := &cachedTransport{
+		transport: transport,
+		scheme:    "http",
+		streams:   1,
+	}
+	if tlsConfig != nil {
+		cached.scheme = "https"
+	}
+	this.transports[key] = cached
+	return cached, nil
+}
+
+// Put returns a stream of cached, after it is closed.
+func (this *transportCache) Put(cached *cachedTransport) {
+	this.Lock()
+	defer this.Unlock()
+
+	cached.streams--
+	if cached.streams == 0 {
+		cached.idleFrom = time.Now()
+	}
+}
+
+// evictIdle removes transports without streams for idleConnTimeout, and closes their connections.
+func (this *transportCache) evictIdle() {
+	now := time.Now()
+	for key, cached := range this.transports {
+		if cached.streams == 0 && now.Sub(cached.idleFrom) >= idleConnTimeout {
+			cached.transport.CloseIdleConnections()
+			delete(this.transports, key)
+		}
+	}
+}
+
+// openStream sends request on transport, and returns its response. It fails if the response doesn't
+// arrive in time, or if ctx, which is the context of the dial, is done before that. The stream isn't
+// bound to ctx after it is open. Cancelling the returned function resets the stream.
+func openStream(ctx context.Context, transport *http.Transport, request *http.Request) (*http.Response, context.CancelFunc, error) {
+	streamCtx, cancel := context.WithCancel(context.Background())
+	timeout := streamOpenTimeout
+	if ctx != nil {
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = deadline.Sub(time.Now())
+		}
+		stop := context.AfterFunc(ctx, cancel)
+		defer stop()
+	}
+	timer := time.AfterFunc(timeout, cancel)
+	defer timer.Stop()
+
+	response, err := transport.RoundTrip(request.WithContext(streamCtx))
+	if err == nil && streamCtx.Err() != nil {
+		// Cancelled as the response arrives.
+		response.Body.Close()
+		err = streamCtx.Err()
+	}
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return response, cancel, nil
+}
+
+// Dial opens a gRPC stream to dest. The stream shares the HTTP/2 connection with other streams to dest,
+// if there is one.
+func Dial(src v2net.Address, dest v2net.Destination, options internet.DialerOptions) (internet.Connection, error) {
+	log.Info("gRPC|Dialer: Creating stream to ", dest)
+	if src == nil {
+		src = v2net.AnyIP
+	}
+	networkSettings, err := options.Stream.GetEffectiveNetworkSettings()
+	if err != nil {
+		return nil, err
+	}
+	config := networkSettings.(*Config)
+
+	cached, err := globalTransports.Get(src, dest, options, config)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyReader, bodyWriter := io.Pipe()
+	request := &http.Request{
+		Method: "POST",
+		URL: &url.URL{
+			Scheme: cached.scheme,
+			Host:   dest.NetAddr(),
+			Path:   config.GetPath(),
+		},
+		Proto:      "HTTP/2",
+		ProtoMajor: 2,
+		Header: http.Header{
+			"Content-Type": []string{"application/grpc"},
+			"Te":           []string{"trailers"},
+		},
+		Body: bodyReader,
+		Host: dest.NetAddr(),
+	}
+
+	// Server sends response headers as soon as the stream is accepted.
+	response, cancel, err := openStream(options.Context, cached.transport, request)
+	if err == nil && response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		cancel()
+		err = errors.New("gRPC|Dialer: Unexpected response status: " + response.Status)
+	}
+	if err != nil {
+		log.Warning("gRPC|Dialer: Failed to open stream to ", dest, ": ", err)
+		bodyWriter.CloseWithError(err)
+		globalTransports.Put(cached)
+		// Errors of dialing are wrapped by the HTTP transport.
+		var layerErr *internet.LayerError
+		if errors.As(err, &layerErr) {
+			return nil, layerErr
+		}
+		return nil, internet.NewLayerError(internet.LayerGRPC, err)
+	}
+
+	remote := &net.TCPAddr{Port: int(dest.Port)}
+	if !dest.Address.Family().IsDomain() {
+		remote.IP = dest.Address.IP()
+	}
+	conn := newConnection(bodyWriter, func() {
+		bodyWriter.Close()
+		// Resets the stream if the server hasn't finished it.
+		response.Body.Close()
+		cancel()
+		globalTransports.Put(cached)
+	}, &net.TCPAddr{}, remote)
+	go conn.readFrom(response.Body)
+	return conn, nil
+}
+
+func init() {
+	internet.GRPCDialer = Dial
+}
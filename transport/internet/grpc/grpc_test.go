@@ -0,0 +1,261 @@
+package grpc_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	. "v2ray.com/core/transport/internet/grpc"
+)
+
+func newStreamConfig(config *Config) *internet.StreamConfig {
+	return &internet.StreamConfig{
+		Network: v2net.Network_GRPC,
+		NetworkSettings: []*internet.NetworkSettings{
+			{
+				Network:  v2net.Network_GRPC,
+				Settings: loader.NewTypedSettings(config),
+			},
+		},
+	}
+}
+
+// startEchoServer accepts gRPC streams and echoes data back, until a stream is closed by client.
+func startEchoServer(assert *assert.Assert, config *Config) (internet.Listener, v2net.Destination) {
+	listener, err := ListenGRPC(v2net.LocalHostIP, 0, internet.ListenOptions{
+		Stream: newStreamConfig(config),
+	})
+	assert.Error(err).IsNil()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	dest := v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(listener.Addr().(*net.TCPAddr).Port))
+	return listener, dest
+}
+
+func TestGRPCStream(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, dest := startEchoServer(assert, &Config{ServiceName: "test.Service"})
+	defer listener.Close()
+
+	options := internet.DialerOptions{
+		Stream: newStreamConfig(&Config{ServiceName: "test.Service"}),
+	}
+	conn, err := Dial(v2net.LocalHostIP, dest, options)
+	assert.Error(err).IsNil()
+
+	// Larger than a single message.
+	payload := make([]byte, 100*1024)
+	rand.Read(payload)
+	nBytes, err := conn.Write(payload)
+	assert.Error(err).IsNil()
+	assert.Int(nBytes).Equals(len(payload))
+
+	response := make([]byte, len(payload))
+	_, err = io.ReadFull(conn, response)
+	assert.Error(err).IsNil()
+	assert.Bool(bytes.Equal(response, payload)).IsTrue()
+
+	// Another stream on the same connection.
+	conn2, err := Dial(v2net.LocalHostIP, dest, options)
+	assert.Error(err).IsNil()
+	_, err = conn2.Write([]byte("second"))
+	assert.Error(err).IsNil()
+	_, err = io.ReadFull(conn2, response[:6])
+	assert.Error(err).IsNil()
+	assert.String(string(response[:6])).Equals("second")
+
+	assert.Error(conn.Close()).IsNil()
+	assert.Error(conn2.Close()).IsNil()
+}
+
+func TestGRPCReadDeadline(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, dest := startEchoServer(assert, &Config{})
+	defer listener.Close()
+
+	conn, err := Dial(v2net.LocalHostIP, dest, internet.DialerOptions{
+		Stream: newStreamConfig(&Config{}),
+	})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	assert.Error(conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))).IsNil()
+	_, err = conn.Read(make([]byte, 16))
+	assert.Bool(err.(net.Error).Timeout()).IsTrue()
+}
+
+func TestGRPCWrongService(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, dest := startEchoServer(assert, &Config{ServiceName: "test.Service"})
+	defer listener.Close()
+
+	_, err := Dial(v2net.LocalHostIP, dest, internet.DialerOptions{
+		Stream: newStreamConfig(&Config{Path: "/other.Service/Tun"}),
+	})
+	assert.Error(err).IsNotNil()
+}
+
+func TestGRPCStreamReset(t *testing.T) {
+	assert := assert.On(t)
+
+	// The server sends a message, and resets the stream.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "application/grpc")
+			writer.WriteHeader(http.StatusOK)
+			writer.Write([]byte{0, 0, 0, 0, 7, 0x0A, 5, 'h', 'e', 'l', 'l', 'o'})
+			writer.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}),
+		Protocols: protocols,
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	dest := v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(listener.Addr().(*net.TCPAddr).Port))
+	conn, err := Dial(v2net.LocalHostIP, dest, internet.DialerOptions{
+		Stream: newStreamConfig(&Config{}),
+	})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	data := make([]byte, 5)
+	_, err = io.ReadFull(conn, data)
+	assert.Error(err).IsNil()
+	assert.String(string(data)).Equals("hello")
+
+	// Unlike a finished stream, a reset one doesn't end with io.EOF.
+	assert.Error(conn.SetReadDeadline(time.Now().Add(5 * time.Second))).IsNil()
+	_, err = conn.Read(data)
+	assert.Error(err).IsNotNil()
+	assert.Bool(err == io.EOF).IsFalse()
+	_, isNetError := err.(net.Error)
+	assert.Bool(isNetError && err.(net.Error).Timeout()).IsFalse()
+}
+
+// startRelay relays connections to dest, until the returned function is called, after which all data
+// is dropped.
+func startRelay(assert *assert.Assert, dest v2net.Destination) (net.Listener, v2net.Destination, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	var frozen int32
+	relay := func(dst net.Conn, src net.Conn) {
+		defer dst.Close()
+		buffer := make([]byte, 32*1024)
+		for {
+			nBytes, err := src.Read(buffer)
+			if err != nil {
+				return
+			}
+			if atomic.LoadInt32(&frozen) == 0 {
+				dst.Write(buffer[:nBytes])
+			}
+		}
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			upstream, err := net.Dial("tcp", dest.NetAddr())
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			go relay(upstream, conn)
+			go relay(conn, upstream)
+		}
+	}()
+	relayDest := v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(listener.Addr().(*net.TCPAddr).Port))
+	return listener, relayDest, func() {
+		atomic.StoreInt32(&frozen, 1)
+	}
+}
+
+func TestGRPCKeepAlive(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, dest := startEchoServer(assert, &Config{})
+	defer listener.Close()
+	relay, relayDest, freeze := startRelay(assert, dest)
+	defer relay.Close()
+
+	conn, err := Dial(v2net.LocalHostIP, relayDest, internet.DialerOptions{
+		Stream: newStreamConfig(&Config{IdleTimeout: 1, HealthCheckTimeout: 1}),
+	})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	// Pings are answered while the connection is alive.
+	time.Sleep(2500 * time.Millisecond)
+	_, err = conn.Write([]byte("ping"))
+	assert.Error(err).IsNil()
+	data := make([]byte, 4)
+	_, err = io.ReadFull(conn, data)
+	assert.Error(err).IsNil()
+	assert.String(string(data)).Equals("ping")
+
+	// The connection is closed, once a ping is not answered in time.
+	freeze()
+	start := time.Now()
+	assert.Error(conn.SetReadDeadline(time.Now().Add(10 * time.Second))).IsNil()
+	_, err = conn.Read(data)
+	assert.Error(err).IsNotNil()
+	assert.Bool(time.Since(start) < 5*time.Second).IsTrue()
+}
+
+func TestGRPCOpenTimeout(t *testing.T) {
+	assert := assert.On(t)
+
+	// The server accepts connections, and never answers.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = Dial(v2net.LocalHostIP, v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(listener.Addr().(*net.TCPAddr).Port)), internet.DialerOptions{
+		Stream:  newStreamConfig(&Config{}),
+		Context: ctx,
+	})
+	assert.Error(err).IsNotNil()
+	assert.Bool(time.Since(start) < 3*time.Second).IsTrue()
+}
@@ -0,0 +1,163 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet"
+	v2tls "v2ray.com/core/transport/internet/tls"
+)
+
+var (
+	ErrClosedListener = errors.New("gRPC|Listener: Listener is closed.")
+)
+
+type rawConnKey struct{}
+
+// Listener accepts gRPC streams on HTTP/2 connections, with TLS if security settings are set.
+type Listener struct {
+	sync.Mutex
+	listener      net.Listener
+	server        *http.Server
+	config        *Config
+	accepting     bool
+	awaitingConns chan *connection
+}
+
+func ListenGRPC(address v2net.Address, port v2net.Port, options internet.ListenOptions) (internet.Listener, error) {
+	networkSettings, err := options.Stream.GetEffectiveNetworkSettings()
+	if err != nil {
+		return nil, err
+	}
+	config := networkSettings.(*Config)
+
+	l := &Listener{
+		config:        config,
+		accepting:     true,
+		awaitingConns: make(chan *connection, 32),
+	}
+	l.server = &http.Server{
+		Handler:   http.HandlerFunc(l.serveStream),
+		Protocols: newProtocols(),
+		HTTP2:     config.getHTTP2Config(),
+		// Addresses of streams are taken from their HTTP/2 connections.
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			return context.WithValue(ctx, rawConnKey{}, conn)
+		},
+	}
+	if options.Stream != nil && options.Stream.HasSecuritySettings() {
+		securitySettings, err := options.Stream.GetEffectiveSecuritySettings()
+		if err != nil {
+			log.Error("gRPC|Listener: Failed to apply TLS config: ", err)
+			return nil, err
+		}
+		if tlsSettings, ok := securitySettings.(*v2tls.Config); ok {
+			l.server.TLSConfig = tlsSettings.GetTLSConfig()
+		}
+	}
+
+	listener, err := net.Listen("tcp", address.String()+":"+port.String())
+	if err != nil {
+		return nil, err
+	}
+	l.listener = listener
+	go func() {
+		var err error
+		if l.server.TLSConfig != nil {
+			err = l.server.ServeTLS(listener, "", "")
+		} else {
+			err = l.server.Serve(listener)
+		}
+		if err != http.ErrServerClosed {
+			log.Warning("gRPC|Listener: Failed to serve: ", err)
+		}
+	}()
+	return l, nil
+}
+
+// serveStream accepts a gRPC stream, and returns when the stream is closed by either side.
+func (this *Listener) serveStream(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != this.config.GetPath() {
+		http.NotFound(writer, request)
+		return
+	}
+	if request.Method != "POST" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(request.Header.Get("Content-Type"), "application/grpc") {
+		writer.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/grpc")
+	writer.WriteHeader(http.StatusOK)
+	writer.(http.Flusher).Flush()
+
+	rawConn := request.Context().Value(rawConnKey{}).(net.Conn)
+	conn := newConnection(writer, nil, rawConn.LocalAddr(), rawConn.RemoteAddr())
+	go conn.readFrom(request.Body)
+
+	this.Lock()
+	accepted := false
+	if this.accepting {
+		select {
+		case this.awaitingConns <- conn:
+			accepted = true
+		default:
+			log.Warning("gRPC|Listener: Too many streams waiting to be accepted.")
+		}
+	}
+	this.Unlock()
+
+	if accepted {
+		select {
+		case <-conn.done:
+		case <-request.Context().Done():
+			// Stream is reset by client.
+			conn.Close()
+		}
+	}
+	conn.Close()
+	conn.finishWrites()
+	writer.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+}
+
+func (this *Listener) Accept() (internet.Connection, error) {
+	conn, open := <-this.awaitingConns
+	if !open {
+		return nil, ErrClosedListener
+	}
+	return conn, nil
+}
+
+// Close stops accepting new streams, and closes all HTTP/2 connections.
+func (this *Listener) Close() error {
+	this.Lock()
+	defer this.Unlock()
+
+	if !this.accepting {
+		return nil
+	}
+	this.accepting = false
+	err := this.server.Close()
+	close(this.awaitingConns)
+	for conn := range this.awaitingConns {
+		conn.Close()
+	}
+	return err
+}
+
+func (this *Listener) Addr() net.Addr {
+	return this.listener.Addr()
+}
+
+func init() {
+	internet.GRPCListenFunc = ListenGRPC
+}
@@ -14,7 +14,11 @@ func GetSysFd(conn net.Conn) (int, error) {
 	cv := reflect.ValueOf(conn)
 	switch ce := cv.Elem(); ce.Kind() {
 	case reflect.Struct:
-		netfd := ce.FieldByName("conn").FieldByName("fd")
+		conn := ce.FieldByName("conn")
+		if !conn.IsValid() || conn.Kind() != reflect.Struct {
+			return 0, ErrInvalidConn
+		}
+		netfd := conn.FieldByName("fd")
 		switch fe := netfd.Elem(); fe.Kind() {
 		case reflect.Struct:
 			fd := fe.FieldByName("sysfd")
@@ -19,7 +19,7 @@ var (
 func DialKCP(src v2net.Address, dest v2net.Destination, options internet.DialerOptions) (internet.Connection, error) {
 	dest.Network = v2net.Network_UDP
 	log.Info("KCP|Dialer: Dialing KCP to ", dest)
-	conn, err := internet.DialToDest(src, dest)
+	conn, err := internet.DialToDestWithOptions(src, dest, options)
 	if err != nil {
 		log.Error("KCP|Dialer: Failed to dial to dest: ", err)
 		return nil, err
@@ -53,7 +53,7 @@ func DialKCP(src v2net.Address, dest v2net.Destination, options internet.DialerO
 		switch securitySettings := securitySettings.(type) {
 		case *v2tls.Config:
 			config := securitySettings.GetTLSConfig()
-			if dest.Address.Family().IsDomain() {
+			if len(config.ServerName) == 0 && dest.Address.Family().IsDomain() {
 				config.ServerName = dest.Address.Domain()
 			}
 			tlsConn := tls.Client(conn, config)
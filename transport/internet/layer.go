@@ -0,0 +1,79 @@
+package internet
+
+import (
+	"strings"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+// Layers of transports, from the bottom. Proxies add their own layers on top, e.g., "shadowsocks".
+const (
+	LayerTCP       = "tcp"
+	LayerUDP       = "udp"
+	LayerTLS       = "tls"
+	LayerWebSocket = "websocket"
+	LayerKCP       = "kcp"
+	LayerGRPC      = "grpc"
+)
+
+// LayerError is an error at a layer of a connection, e.g., a TLS handshake that fails after TCP is
+// connected.
+type LayerError struct {
+	Layer string
+	Err   error
+}
+
+// NewLayerError returns err at layer, unless err already has its own layer.
+func NewLayerError(layer string, err error) error {
+	if _, ok := err.(*LayerError); ok {
+		return err
+	}
+	return &LayerError{
+		Layer: layer,
+		Err:   err,
+	}
+}
+
+func (this *LayerError) Error() string {
+	return "Internet: Failed at " + this.Layer + ": " + this.Err.Error()
+}
+
+// FailedLayer returns the layer that err happened at, or empty if it is not a LayerError.
+func FailedLayer(err error) string {
+	if layerErr, ok := err.(*LayerError); ok {
+		return layerErr.Layer
+	}
+	return ""
+}
+
+// securityLayer returns the layer name of the security type, e.g., "tls" for
+// "v2ray.core.transport.internet.tls.Config".
+func (this *StreamConfig) securityLayer() string {
+	parts := strings.Split(strings.TrimSuffix(this.SecurityType, ".Config"), ".")
+	return strings.ToLower(parts[len(parts)-1])
+}
+
+// TransportLayers returns layers of connections in network with this config, from the bottom, e.g.,
+// "tcp", "tls" and "websocket".
+func (this *StreamConfig) TransportLayers(network v2net.Network) []string {
+	if network == v2net.Network_UDP {
+		return []string{LayerUDP}
+	}
+	if this == nil {
+		return []string{LayerTCP}
+	}
+	if this.Network == v2net.Network_KCP {
+		return []string{LayerUDP, LayerKCP}
+	}
+	layers := []string{LayerTCP}
+	if this.Network != v2net.Network_RawTCP && this.HasSecuritySettings() {
+		layers = append(layers, this.securityLayer())
+	}
+	switch this.Network {
+	case v2net.Network_WebSocket:
+		layers = append(layers, LayerWebSocket)
+	case v2net.Network_GRPC:
+		layers = append(layers, LayerGRPC)
+	}
+	return layers
+}
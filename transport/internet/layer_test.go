@@ -0,0 +1,40 @@
+package internet_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"v2ray.com/core/common/loader"
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet"
+	v2tls "v2ray.com/core/transport/internet/tls"
+)
+
+func TestTransportLayers(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &StreamConfig{
+		Network:      v2net.Network_WebSocket,
+		SecurityType: loader.GetType(new(v2tls.Config)),
+	}
+	assert.String(strings.Join(config.TransportLayers(v2net.Network_TCP), "+")).Equals("tcp+tls+websocket")
+	assert.String(strings.Join(config.TransportLayers(v2net.Network_UDP), "+")).Equals("udp")
+
+	config = &StreamConfig{
+		Network: v2net.Network_KCP,
+	}
+	assert.String(strings.Join(config.TransportLayers(v2net.Network_TCP), "+")).Equals("udp+kcp")
+	assert.String(strings.Join((*StreamConfig)(nil).TransportLayers(v2net.Network_TCP), "+")).Equals("tcp")
+}
+
+func TestLayerError(t *testing.T) {
+	assert := assert.On(t)
+
+	err := NewLayerError(LayerTLS, errors.New("bad certificate"))
+	assert.String(FailedLayer(err)).Equals(LayerTLS)
+	// The lowest layer that fails is kept.
+	assert.String(FailedLayer(NewLayerError(LayerWebSocket, err))).Equals(LayerTLS)
+	assert.String(FailedLayer(errors.New("unknown"))).Equals("")
+}
@@ -0,0 +1,79 @@
+package internet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+
+	v2net "v2ray.com/core/common/net"
+)
+
+var (
+	ErrAcceptorsWithoutReusePort = errors.New("Internet: Multiple acceptors require SO_REUSEPORT.")
+)
+
+// GetEffectiveAcceptors returns the number of sockets to listen with, which is at least 1.
+func (this *ListenerConfig) GetEffectiveAcceptors() int {
+	if this == nil || this.Acceptors == 0 {
+		return 1
+	}
+	return int(this.Acceptors)
+}
+
+// ListenTCPSockets listens on address and port with config. It returns one socket for each acceptor, all
+// on the same port. Sockets have system defaults if config is nil.
+func ListenTCPSockets(address v2net.Address, port v2net.Port, config *ListenerConfig) ([]*net.TCPListener, error) {
+	addr := &net.TCPAddr{
+		IP:   address.IP(),
+		Port: int(port),
+	}
+	if config == nil {
+		listener, err := net.ListenTCP("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		return []*net.TCPListener{listener}, nil
+	}
+
+	acceptors := config.GetEffectiveAcceptors()
+	if acceptors > 1 && !config.ReusePort {
+		return nil, ErrAcceptorsWithoutReusePort
+	}
+	listenConfig := &net.ListenConfig{
+		Control: func(network string, address string, rawConn syscall.RawConn) error {
+			var setErr error
+			if err := rawConn.Control(func(fd uintptr) {
+				setErr = setListenerOptions(fd, config)
+			}); err != nil {
+				return err
+			}
+			return setErr
+		},
+	}
+	listeners := make([]*net.TCPListener, 0, acceptors)
+	for len(listeners) < acceptors {
+		listener, err := listenConfig.Listen(context.Background(), "tcp", addr.String())
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		tcpListener := listener.(*net.TCPListener)
+		listeners = append(listeners, tcpListener)
+		if config.Backlog > 0 {
+			if err := setBacklog(tcpListener, config.Backlog); err != nil {
+				closeListeners(listeners)
+				return nil, err
+			}
+		}
+		// Other sockets listen on the port picked by the system for the first one.
+		addr = tcpListener.Addr().(*net.TCPAddr)
+	}
+	return listeners, nil
+}
+
+func closeListeners(listeners []*net.TCPListener) {
+	for _, listener := range listeners {
+		listener.Close()
+	}
+}
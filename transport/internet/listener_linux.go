@@ -0,0 +1,46 @@
+// +build linux
+
+package internet
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+const (
+	// SO_REUSEPORT is not defined in syscall.
+	soReusePort = 0xf
+)
+
+func setListenerOptions(fd uintptr, config *ListenerConfig) error {
+	if config.ReuseAddress {
+		if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); err != nil {
+			return errors.New("Internet: Failed to set SO_REUSEADDR: " + err.Error())
+		}
+	}
+	if config.ReusePort {
+		if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1); err != nil {
+			return errors.New("Internet: Failed to set SO_REUSEPORT: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// setBacklog changes the backlog of a listening socket, by calling listen() again.
+func setBacklog(listener *net.TCPListener, backlog uint32) error {
+	rawConn, err := listener.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var listenErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		listenErr = syscall.Listen(int(fd), int(backlog))
+	}); err != nil {
+		return err
+	}
+	if listenErr != nil {
+		return errors.New("Internet: Failed to set listen backlog: " + listenErr.Error())
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// +build linux
+
+package internet_test
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet"
+)
+
+func getReusePort(assert *assert.Assert, listener *net.TCPListener) int {
+	rawConn, err := listener.SyscallConn()
+	assert.Error(err).IsNil()
+	var value int
+	assert.Error(rawConn.Control(func(fd uintptr) {
+		value, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, 0xf)
+	})).IsNil()
+	assert.Error(err).IsNil()
+	return value
+}
+
+func TestListenTCPSockets(t *testing.T) {
+	assert := assert.On(t)
+
+	listeners, err := ListenTCPSockets(v2net.LocalHostIP, 0, nil)
+	assert.Error(err).IsNil()
+	assert.Int(len(listeners)).Equals(1)
+	assert.Int(getReusePort(assert, listeners[0])).Equals(0)
+	listeners[0].Close()
+
+	_, err = ListenTCPSockets(v2net.LocalHostIP, 0, &ListenerConfig{
+		Acceptors: 2,
+	})
+	assert.Error(err).Equals(ErrAcceptorsWithoutReusePort)
+
+	listeners, err = ListenTCPSockets(v2net.LocalHostIP, 0, &ListenerConfig{
+		Backlog:      16,
+		ReuseAddress: true,
+		ReusePort:    true,
+		Acceptors:    2,
+	})
+	assert.Error(err).IsNil()
+	assert.Int(len(listeners)).Equals(2)
+	for _, listener := range listeners {
+		defer listener.Close()
+		assert.Int(getReusePort(assert, listener)).Equals(1)
+	}
+	assert.String(listeners[1].Addr().String()).Equals(listeners[0].Addr().String())
+}
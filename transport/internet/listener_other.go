@@ -0,0 +1,27 @@
+// +build !linux
+
+package internet
+
+import (
+	"errors"
+	"net"
+	"runtime"
+
+	"v2ray.com/core/common/log"
+)
+
+func setListenerOptions(fd uintptr, config *ListenerConfig) error {
+	if config.ReusePort {
+		return errors.New("Internet: SO_REUSEPORT is not supported on this platform.")
+	}
+	// Go sets SO_REUSEADDR on Unix systems anyway.
+	if config.ReuseAddress && runtime.GOOS == "windows" {
+		log.Warning("Internet: Setting SO_REUSEADDR is not supported on this platform.")
+	}
+	return nil
+}
+
+func setBacklog(listener *net.TCPListener, backlog uint32) error {
+	log.Warning("Internet: Setting listen backlog is not supported on this platform.")
+	return nil
+}
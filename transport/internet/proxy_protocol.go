@@ -0,0 +1,224 @@
+package internet
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"v2ray.com/core/common/log"
+	"v2ray.com/core/common/serial"
+	"v2ray.com/core/transport/internet/internal"
+)
+
+const (
+	// Time allowed for a client to send its PROXY protocol header.
+	proxyProtocolHeaderTimeout = 10 * time.Second
+
+	// Longest v1 header, including CRLF.
+	proxyProtocolV1MaxLength = 107
+)
+
+var (
+	ErrInvalidProxyProtocolHeader = errors.New("Internet|ProxyProtocol: Invalid header.")
+
+	proxyProtocolV1Signature = []byte("PROXY ")
+	proxyProtocolV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+)
+
+// ReadProxyProtocolHeader reads a PROXY protocol header of v1 or v2 from reader. It returns the source
+// address in the header, or nil if the header has no address, e.g., for LOCAL command in v2 or UNKNOWN
+// in v1.
+func ReadProxyProtocolHeader(reader *bufio.Reader) (net.Addr, error) {
+	signature, err := reader.Peek(len(proxyProtocolV1Signature))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(signature, proxyProtocolV1Signature) {
+		return readProxyProtocolV1(reader)
+	}
+
+	signature, err = reader.Peek(len(proxyProtocolV2Signature))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(signature, proxyProtocolV2Signature) {
+		return readProxyProtocolV2(reader)
+	}
+	return nil, ErrInvalidProxyProtocolHeader
+}
+
+func readProxyProtocolV1(reader *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < proxyProtocolV1MaxLength {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, ErrInvalidProxyProtocolHeader
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, ErrInvalidProxyProtocolHeader
+	}
+	srcIP := net.ParseIP(fields[2])
+	dstIP := net.ParseIP(fields[3])
+	if srcIP == nil || dstIP == nil || (srcIP.To4() != nil) != (fields[1] == "TCP4") {
+		return nil, ErrInvalidProxyProtocolHeader
+	}
+	srcPort, err := parseProxyProtocolPort(fields[4])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseProxyProtocolPort(fields[5]); err != nil {
+		return nil, err
+	}
+	return &net.TCPAddr{IP: srcIP, Port: srcPort}, nil
+}
+
+func parseProxyProtocolPort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > 65535 {
+		return 0, ErrInvalidProxyProtocolHeader
+	}
+	return port, nil
+}
+
+func readProxyProtocolV2(reader *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	if header[12]>>4 != 2 {
+		return nil, ErrInvalidProxyProtocolHeader
+	}
+	command := header[12] & 0x0F
+	family := header[13] >> 4
+	body := make([]byte, serial.BytesToUint16(header[14:16]))
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+
+	switch command {
+	case 0x00:
+		// LOCAL command, e.g., health checks from the proxy itself.
+		return nil, nil
+	case 0x01:
+	default:
+		return nil, ErrInvalidProxyProtocolHeader
+	}
+
+	switch family {
+	case 0x01:
+		if len(body) < 12 {
+			return nil, ErrInvalidProxyProtocolHeader
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:4]), Port: int(serial.BytesToUint16(body[8:10]))}, nil
+	case 0x02:
+		if len(body) < 36 {
+			return nil, ErrInvalidProxyProtocolHeader
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(serial.BytesToUint16(body[32:34]))}, nil
+	default:
+		// Unspecified or Unix socket addresses.
+		return nil, nil
+	}
+}
+
+// ProxyProtocolConn is a connection that starts with a PROXY protocol header. The header is read on first
+// use of the connection, and RemoteAddr() returns the source in the header. The connection is closed if
+// the header is malformed.
+type ProxyProtocolConn struct {
+	net.Conn
+	sync.Mutex
+	once     sync.Once
+	reader   *bufio.Reader
+	source   net.Addr
+	err      error
+	deadline time.Time
+}
+
+func NewProxyProtocolConn(conn net.Conn) *ProxyProtocolConn {
+	return &ProxyProtocolConn{
+		Conn:   conn,
+		reader: bufio.NewReaderSize(conn, 256),
+	}
+}
+
+func (this *ProxyProtocolConn) readHeader() {
+	this.once.Do(func() {
+		this.Conn.SetReadDeadline(time.Now().Add(proxyProtocolHeaderTimeout))
+		source, err := ReadProxyProtocolHeader(this.reader)
+		this.Lock()
+		this.Conn.SetReadDeadline(this.deadline)
+		this.Unlock()
+		if err != nil {
+			log.Warning("Internet|ProxyProtocol: Failed to read header from ", this.Conn.RemoteAddr(), ": ", err)
+			this.err = ErrInvalidProxyProtocolHeader
+			this.Conn.Close()
+			return
+		}
+		this.source = source
+	})
+}
+
+func (this *ProxyProtocolConn) Read(b []byte) (int, error) {
+	this.readHeader()
+	if this.err != nil {
+		return 0, this.err
+	}
+	return this.reader.Read(b)
+}
+
+// RemoteAddr returns the source in PROXY protocol header, or address of the peer if the header has none.
+func (this *ProxyProtocolConn) RemoteAddr() net.Addr {
+	this.readHeader()
+	if this.source != nil {
+		return this.source
+	}
+	return this.Conn.RemoteAddr()
+}
+
+func (this *ProxyProtocolConn) SetDeadline(t time.Time) error {
+	this.Lock()
+	this.deadline = t
+	this.Unlock()
+	return this.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets read deadline of the connection. The deadline is kept while reading the header.
+func (this *ProxyProtocolConn) SetReadDeadline(t time.Time) error {
+	this.Lock()
+	this.deadline = t
+	this.Unlock()
+	return this.Conn.SetReadDeadline(t)
+}
+
+// SysFd returns the file descriptor of the underlying connection, for socket options of the inbound.
+func (this *ProxyProtocolConn) SysFd() (int, error) {
+	if conn, ok := this.Conn.(SysFd); ok {
+		return conn.SysFd()
+	}
+	return internal.GetSysFd(this.Conn)
+}
+
+func (this *ProxyProtocolConn) Reusable() bool {
+	return false
+}
+
+func (this *ProxyProtocolConn) SetReusable(reusable bool) {}
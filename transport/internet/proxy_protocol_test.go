@@ -0,0 +1,132 @@
+package internet_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet"
+)
+
+var proxyProtocolV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+func readProxyProtocolHeader(header []byte) (net.Addr, error) {
+	return ReadProxyProtocolHeader(bufio.NewReader(bytes.NewReader(header)))
+}
+
+func TestProxyProtocolV1(t *testing.T) {
+	assert := assert.On(t)
+
+	addr, err := readProxyProtocolHeader([]byte("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n"))
+	assert.Error(err).IsNil()
+	assert.String(addr.String()).Equals("192.168.0.1:56324")
+
+	addr, err = readProxyProtocolHeader([]byte("PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n"))
+	assert.Error(err).IsNil()
+	assert.String(addr.String()).Equals("[2001:db8::1]:56324")
+
+	addr, err = readProxyProtocolHeader([]byte("PROXY UNKNOWN\r\n"))
+	assert.Error(err).IsNil()
+	assert.Pointer(addr).IsNil()
+
+	for _, header := range []string{
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324\r\n",
+		"PROXY TCP4 2001:db8::1 192.168.0.11 56324 443\r\n",
+		"PROXY TCP4 192.168.0.1 192.168.0.11 70000 443\r\n",
+		"PROXY UDP4 192.168.0.1 192.168.0.11 56324 443\r\n",
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\n",
+		"PROXY TCP4 " + string(bytes.Repeat([]byte{'1'}, 120)) + "\r\n",
+		"GET / HTTP/1.1\r\n",
+	} {
+		_, err := readProxyProtocolHeader([]byte(header))
+		assert.Error(err).Equals(ErrInvalidProxyProtocolHeader)
+	}
+}
+
+func TestProxyProtocolV2(t *testing.T) {
+	assert := assert.On(t)
+
+	header := append(append([]byte{}, proxyProtocolV2Signature...),
+		0x21, 0x11, 0x00, 0x0C,
+		192, 168, 0, 1, 192, 168, 0, 11,
+		0xDC, 0x04, 0x01, 0xBB)
+	addr, err := readProxyProtocolHeader(header)
+	assert.Error(err).IsNil()
+	assert.String(addr.String()).Equals("192.168.0.1:56324")
+
+	local := append(append([]byte{}, proxyProtocolV2Signature...), 0x20, 0x00, 0x00, 0x00)
+	addr, err = readProxyProtocolHeader(local)
+	assert.Error(err).IsNil()
+	assert.Pointer(addr).IsNil()
+
+	badVersion := append(append([]byte{}, proxyProtocolV2Signature...), 0x11, 0x11, 0x00, 0x00)
+	_, err = readProxyProtocolHeader(badVersion)
+	assert.Error(err).Equals(ErrInvalidProxyProtocolHeader)
+
+	shortAddress := append(append([]byte{}, proxyProtocolV2Signature...), 0x21, 0x11, 0x00, 0x04, 192, 168, 0, 1)
+	_, err = readProxyProtocolHeader(shortAddress)
+	assert.Error(err).Equals(ErrInvalidProxyProtocolHeader)
+
+	truncated := append(append([]byte{}, proxyProtocolV2Signature...), 0x21, 0x11, 0x00, 0x0C, 192, 168)
+	_, err = readProxyProtocolHeader(truncated)
+	assert.Error(err).IsNotNil()
+}
+
+func TestProxyProtocolConn(t *testing.T) {
+	assert := assert.On(t)
+
+	client, server := net.Pipe()
+	go func() {
+		client.Write([]byte("PROXY TCP4 10.0.0.1 10.0.0.2 12345 443\r\npayload"))
+		client.Close()
+	}()
+
+	conn := NewProxyProtocolConn(server)
+	assert.String(conn.RemoteAddr().String()).Equals("10.0.0.1:12345")
+	data := make([]byte, 7)
+	_, err := io.ReadFull(conn, data)
+	assert.Error(err).IsNil()
+	assert.String(string(data)).Equals("payload")
+}
+
+// sysFdConn is a connection with the given file descriptor.
+type sysFdConn struct {
+	net.Conn
+	fd int
+}
+
+func (this *sysFdConn) SysFd() (int, error) {
+	return this.fd, nil
+}
+
+func TestProxyProtocolConnSysFd(t *testing.T) {
+	assert := assert.On(t)
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	var conn net.Conn = NewProxyProtocolConn(&sysFdConn{Conn: server, fd: 42})
+	sysFd, ok := conn.(SysFd)
+	assert.Bool(ok).IsTrue()
+	fd, err := sysFd.SysFd()
+	assert.Error(err).IsNil()
+	assert.Int(fd).Equals(42)
+}
+
+func TestProxyProtocolConnRejectsMalformedHeader(t *testing.T) {
+	assert := assert.On(t)
+
+	client, server := net.Pipe()
+	go func() {
+		client.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+	}()
+
+	conn := NewProxyProtocolConn(server)
+	_, err := conn.Read(make([]byte, 16))
+	assert.Error(err).Equals(ErrInvalidProxyProtocolHeader)
+	_, err = client.Write([]byte("more"))
+	assert.Error(err).IsNotNil()
+}
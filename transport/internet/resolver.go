@@ -0,0 +1,64 @@
+package internet
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+var (
+	ErrDomainNotResolved = errors.New("Internet: Failed to resolve domain.")
+
+	effectiveDomainResolver DomainResolver
+)
+
+// DomainResolver resolves domains of outbound connections, instead of system resolver.
+type DomainResolver interface {
+	Get(domain string) []net.IP
+}
+
+// requesterResolver is a DomainResolver that resolves domains on behalf of outbounds, so that it can
+// avoid sending queries through the outbound that is waiting for the resolution.
+type requesterResolver interface {
+	GetFor(domain string, requester string) []net.IP
+}
+
+// resolveDomain resolves domain by resolver, on behalf of the outbound with tag if the resolver supports
+// it.
+func resolveDomain(resolver DomainResolver, domain string, tag string) []net.IP {
+	if requester, ok := resolver.(requesterResolver); ok {
+		return requester.GetFor(domain, tag)
+	}
+	return resolver.Get(domain)
+}
+
+// UseDomainResolver sets the resolver for domains in outbound connections.
+// Caller must ensure there is no race condition.
+func UseDomainResolver(resolver DomainResolver) {
+	effectiveDomainResolver = resolver
+}
+
+// dialResolved resolves domain of dest by the effective DomainResolver on behalf of the outbound with tag,
+// and tries each IP in order, with socket buffers of settings.
+func dialResolved(ctx context.Context, resolver DomainResolver, src v2net.Address, dest v2net.Destination, tag string, settings *SocketConfig) (net.Conn, error) {
+	ips := resolveDomain(resolver, dest.Address.Domain(), tag)
+	if len(ips) == 0 {
+		log.Warning("Internet: No IP found for domain ", dest.Address.Domain())
+		return nil, ErrDomainNotResolved
+	}
+	var lastErr error
+	for _, ip := range ips {
+		ipDest := dest
+		ipDest.Address = v2net.IPAddress(ip)
+		conn, err := dialSystem(ctx, src, ipDest, settings)
+		if err == nil {
+			return conn, nil
+		}
+		log.Debug("Internet: Failed to dial ", ipDest, " for ", dest.Address.Domain(), ": ", err)
+		lastErr = err
+	}
+	return nil, lastErr
+}
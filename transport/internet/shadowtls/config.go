@@ -0,0 +1,48 @@
+package shadowtls
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"hash"
+)
+
+var (
+	ErrUnauthorized = errors.New("ShadowTLS: Server is not authenticated.")
+)
+
+// Config is the client side settings of ShadowTLS v3.
+type Config struct {
+	// ServerName is the SNI of the handshake server that the ShadowTLS server relays to.
+	ServerName string
+	Password   string
+	// ALPN protocols in ClientHello.
+	ALPN []string
+}
+
+func (this *Config) Validate() error {
+	if len(this.ServerName) == 0 {
+		return errors.New("ShadowTLS: Server name is not specified.")
+	}
+	if len(this.Password) == 0 {
+		return errors.New("ShadowTLS: Password is not specified.")
+	}
+	return nil
+}
+
+func (this *Config) newHMAC(parts ...[]byte) hash.Hash {
+	h := hmac.New(sha1.New, []byte(this.Password))
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h
+}
+
+// xorKey returns the key that the server uses to mask handshake records, i.e., SHA256(password + ServerRandom).
+func (this *Config) xorKey(serverRandom []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(this.Password))
+	h.Write(serverRandom)
+	return h.Sum(nil)
+}
@@ -0,0 +1,109 @@
+package shadowtls
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+
+	"v2ray.com/core/transport/internet"
+)
+
+const (
+	maxDataSize = 16384 - hmacSize
+)
+
+// Conn carries data in application data records after the handshake. Each record is prefixed by
+// the first 4 bytes of a running HMAC over all data in its direction.
+type Conn struct {
+	internet.Connection
+
+	writeHMAC  hash.Hash
+	verifyHMAC hash.Hash
+	// HMAC of records still relayed from the handshake server, which are dropped.
+	ignoreHMAC hash.Hash
+	pending    []byte
+}
+
+// Client performs a ShadowTLS v3 handshake on the given connection, and returns a connection for the
+// data afterwards.
+func Client(conn internet.Connection, config *Config) (*Conn, error) {
+	hs := &handshake{
+		config: config,
+		conn:   conn,
+	}
+	if err := hs.Run(); err != nil {
+		return nil, err
+	}
+	return &Conn{
+		Connection: conn,
+		writeHMAC:  config.newHMAC(hs.serverRandom, []byte("C")),
+		verifyHMAC: config.newHMAC(hs.serverRandom, []byte("S")),
+		ignoreHMAC: hs.readHMAC,
+	}, nil
+}
+
+// sign returns the HMAC of the given data, and updates h with it.
+func sign(h hash.Hash, data []byte) []byte {
+	h.Write(data)
+	mac := h.Sum(nil)[:hmacSize]
+	h.Write(mac)
+	return mac
+}
+
+func (this *Conn) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		size := len(b)
+		if size > maxDataSize {
+			size = maxDataSize
+		}
+		record := make([]byte, recordHeaderSize+hmacSize+size)
+		record[0] = recordTypeApplicationData
+		record[1] = 0x03
+		record[2] = 0x03
+		binary.BigEndian.PutUint16(record[3:], uint16(hmacSize+size))
+		copy(record[recordHeaderSize:], sign(this.writeHMAC, b[:size]))
+		copy(record[recordHeaderSize+hmacSize:], b[:size])
+		if _, err := this.Connection.Write(record); err != nil {
+			return written, err
+		}
+		written += size
+		b = b[size:]
+	}
+	return written, nil
+}
+
+func (this *Conn) Read(b []byte) (int, error) {
+	for len(this.pending) == 0 {
+		header, payload, err := readRecord(this.Connection)
+		if err != nil {
+			return 0, err
+		}
+		switch header[0] {
+		case recordTypeApplicationData:
+		case recordTypeAlert:
+			return 0, io.EOF
+		default:
+			return 0, errors.New("ShadowTLS: Unexpected record type.")
+		}
+		if len(payload) < hmacSize {
+			return 0, errors.New("ShadowTLS: Record too short.")
+		}
+		if this.ignoreHMAC != nil {
+			this.ignoreHMAC.Write(payload[hmacSize:])
+			if string(this.ignoreHMAC.Sum(nil)[:hmacSize]) == string(payload[:hmacSize]) {
+				continue
+			}
+			this.ignoreHMAC = nil
+		}
+		if string(sign(this.verifyHMAC, payload[hmacSize:])) != string(payload[:hmacSize]) {
+			return 0, errors.New("ShadowTLS: Failed to verify record from server.")
+		}
+		this.pending = payload[hmacSize:]
+	}
+
+	n := copy(b, this.pending)
+	this.pending = this.pending[n:]
+	return n, nil
+}
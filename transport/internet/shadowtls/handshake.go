@@ -0,0 +1,534 @@
+package shadowtls
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+const (
+	recordTypeChangeCipherSpec = 20
+	recordTypeAlert            = 21
+	recordTypeHandshake        = 22
+	recordTypeApplicationData  = 23
+
+	handshakeTypeClientHello         = 1
+	handshakeTypeServerHello         = 2
+	handshakeTypeEncryptedExtensions = 8
+	handshakeTypeCertificate         = 11
+	handshakeTypeCertificateVerify   = 15
+	handshakeTypeFinished            = 20
+
+	extensionServerName          = 0
+	extensionSupportedGroups     = 10
+	extensionSignatureAlgorithms = 13
+	extensionALPN                = 16
+	extensionSupportedVersions   = 43
+	extensionPSKModes            = 45
+	extensionKeyShare            = 51
+
+	versionTLS12 = 0x0303
+	versionTLS13 = 0x0304
+
+	groupX25519 = 0x001d
+
+	recordHeaderSize = 5
+	maxRecordPayload = 16384 + 256
+	randomSize       = 32
+	sessionIDSize    = 32
+	hmacSize         = 4
+
+	// Offset of random in a ServerHello message: type(1), length(3) and version(2).
+	serverRandomOffset = 6
+	// Offset of session id in a ClientHello message: random and length of session id follow the version.
+	sessionIDOffset = serverRandomOffset + randomSize + 1
+)
+
+var (
+	errUnexpectedMessage = errors.New("ShadowTLS: Unexpected handshake message.")
+
+	// Random of a HelloRetryRequest, defined in RFC 8446.
+	helloRetryRequestRandom = []byte{
+		0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11, 0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+		0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E, 0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+	}
+
+	cipherSuites = []uint16{
+		0x1301, // TLS_AES_128_GCM_SHA256
+		0x1302, // TLS_AES_256_GCM_SHA384
+	}
+
+	signatureAlgorithms = []uint16{
+		0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601, 0x0807,
+	}
+)
+
+type bytesBuilder struct {
+	bytes.Buffer
+}
+
+func (this *bytesBuilder) uint8(v uint8) *bytesBuilder {
+	this.WriteByte(v)
+	return this
+}
+
+func (this *bytesBuilder) uint16(v uint16) *bytesBuilder {
+	this.WriteByte(byte(v >> 8))
+	this.WriteByte(byte(v))
+	return this
+}
+
+// prefixed writes the content produced by f, prefixed by its length in the given number of bytes.
+func (this *bytesBuilder) prefixed(size int, f func(*bytesBuilder)) *bytesBuilder {
+	content := new(bytesBuilder)
+	f(content)
+	n := content.Len()
+	for i := size - 1; i >= 0; i-- {
+		this.WriteByte(byte(n >> uint(8*i)))
+	}
+	this.Write(content.Bytes())
+	return this
+}
+
+func (this *bytesBuilder) extension(typ uint16, f func(*bytesBuilder)) *bytesBuilder {
+	return this.uint16(typ).prefixed(2, f)
+}
+
+// hkdfExtract and hkdfExpandLabel implement the key schedule of RFC 8446, section 7.1.
+func hkdfExtract(newHash func() hash.Hash, salt, secret []byte) []byte {
+	if salt == nil {
+		salt = make([]byte, newHash().Size())
+	}
+	if secret == nil {
+		secret = make([]byte, newHash().Size())
+	}
+	h := hmac.New(newHash, salt)
+	h.Write(secret)
+	return h.Sum(nil)
+}
+
+func hkdfExpandLabel(newHash func() hash.Hash, secret []byte, label string, context []byte, length int) []byte {
+	info := new(bytesBuilder)
+	info.uint16(uint16(length))
+	info.prefixed(1, func(b *bytesBuilder) { b.WriteString("tls13 " + label) })
+	info.prefixed(1, func(b *bytesBuilder) { b.Write(context) })
+
+	var out, prev []byte
+	for counter := byte(1); len(out) < length; counter++ {
+		h := hmac.New(newHash, secret)
+		h.Write(prev)
+		h.Write(info.Bytes())
+		h.Write([]byte{counter})
+		prev = h.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:length]
+}
+
+type trafficKey struct {
+	aead cipher.AEAD
+	iv   []byte
+	seq  uint64
+}
+
+func newTrafficKey(newHash func() hash.Hash, secret []byte, keySize int) (*trafficKey, error) {
+	block, err := aes.NewCipher(hkdfExpandLabel(newHash, secret, "key", nil, keySize))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &trafficKey{
+		aead: aead,
+		iv:   hkdfExpandLabel(newHash, secret, "iv", nil, aead.NonceSize()),
+	}, nil
+}
+
+func (this *trafficKey) nonce() []byte {
+	nonce := make([]byte, len(this.iv))
+	copy(nonce, this.iv)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(this.seq >> uint(8*i))
+	}
+	this.seq++
+	return nonce
+}
+
+// open decrypts a record and returns its real content type and content.
+func (this *trafficKey) open(header []byte, payload []byte) (byte, []byte, error) {
+	plain, err := this.aead.Open(nil, this.nonce(), payload, header)
+	if err != nil {
+		return 0, nil, errors.New("ShadowTLS: Failed to decrypt handshake record: " + err.Error())
+	}
+	idx := len(plain) - 1
+	for idx >= 0 && plain[idx] == 0 {
+		idx--
+	}
+	if idx < 0 {
+		return 0, nil, errors.New("ShadowTLS: Empty inner record.")
+	}
+	return plain[idx], plain[:idx], nil
+}
+
+func (this *trafficKey) seal(contentType byte, content []byte) []byte {
+	plain := append(append([]byte{}, content...), contentType)
+	header := []byte{recordTypeApplicationData, 0x03, 0x03, 0, 0}
+	binary.BigEndian.PutUint16(header[3:], uint16(len(plain)+this.aead.Overhead()))
+	return this.aead.Seal(header, this.nonce(), plain, header)
+}
+
+func readRecord(reader io.Reader) ([]byte, []byte, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[3:]))
+	if length > maxRecordPayload {
+		return nil, nil, errors.New("ShadowTLS: Record too large.")
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, nil, err
+	}
+	return header, payload, nil
+}
+
+// handshake performs a TLS 1.3 handshake with the handshake server behind a ShadowTLS server. Session
+// id of ClientHello carries an HMAC for the ShadowTLS server to identify the client. Certificate of the
+// handshake server is not verified, as the ShadowTLS server is authenticated by the HMAC of the records
+// it modifies.
+type handshake struct {
+	config     *Config
+	conn       io.ReadWriter
+	privateKey *ecdh.PrivateKey
+	transcript hash.Hash
+	newHash    func() hash.Hash
+	keySize    int
+
+	serverRandom []byte
+	readHMAC     hash.Hash
+	xorKey       []byte
+	authorized   bool
+
+	serverKey      *trafficKey
+	serverSecret   []byte
+	clientSecret   []byte
+	messages       []byte
+	clientHelloMsg []byte
+}
+
+func (this *handshake) buildClientHello() ([]byte, error) {
+	random := make([]byte, randomSize)
+	sessionID := make([]byte, sessionIDSize)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(sessionID[:sessionIDSize-hmacSize]); err != nil {
+		return nil, err
+	}
+
+	body := new(bytesBuilder)
+	body.uint16(versionTLS12)
+	body.Write(random)
+	body.prefixed(1, func(b *bytesBuilder) { b.Write(sessionID) })
+	body.prefixed(2, func(b *bytesBuilder) {
+		for _, suite := range cipherSuites {
+			b.uint16(suite)
+		}
+	})
+	body.prefixed(1, func(b *bytesBuilder) { b.uint8(0) })
+	body.prefixed(2, func(b *bytesBuilder) {
+		b.extension(extensionServerName, func(b *bytesBuilder) {
+			b.prefixed(2, func(b *bytesBuilder) {
+				b.uint8(0).prefixed(2, func(b *bytesBuilder) { b.WriteString(this.config.ServerName) })
+			})
+		})
+		b.extension(extensionSupportedGroups, func(b *bytesBuilder) {
+			b.prefixed(2, func(b *bytesBuilder) { b.uint16(groupX25519) })
+		})
+		b.extension(extensionSignatureAlgorithms, func(b *bytesBuilder) {
+			b.prefixed(2, func(b *bytesBuilder) {
+				for _, algorithm := range signatureAlgorithms {
+					b.uint16(algorithm)
+				}
+			})
+		})
+		if len(this.config.ALPN) > 0 {
+			b.extension(extensionALPN, func(b *bytesBuilder) {
+				b.prefixed(2, func(b *bytesBuilder) {
+					for _, proto := range this.config.ALPN {
+						b.prefixed(1, func(b *bytesBuilder) { b.WriteString(proto) })
+					}
+				})
+			})
+		}
+		b.extension(extensionSupportedVersions, func(b *bytesBuilder) {
+			b.prefixed(1, func(b *bytesBuilder) { b.uint16(versionTLS13) })
+		})
+		b.extension(extensionPSKModes, func(b *bytesBuilder) {
+			b.prefixed(1, func(b *bytesBuilder) { b.uint8(1) })
+		})
+		b.extension(extensionKeyShare, func(b *bytesBuilder) {
+			b.prefixed(2, func(b *bytesBuilder) {
+				b.uint16(groupX25519).prefixed(2, func(b *bytesBuilder) { b.Write(this.privateKey.PublicKey().Bytes()) })
+			})
+		})
+	})
+
+	msg := new(bytesBuilder)
+	msg.uint8(handshakeTypeClientHello).prefixed(3, func(b *bytesBuilder) { b.Write(body.Bytes()) })
+	hello := msg.Bytes()
+
+	// HMAC is calculated with the last 4 bytes of session id being zero.
+	mac := this.config.newHMAC(hello).Sum(nil)
+	copy(hello[sessionIDOffset+sessionIDSize-hmacSize:sessionIDOffset+sessionIDSize], mac[:hmacSize])
+	return hello, nil
+}
+
+func parseExtensions(data []byte) (map[uint16][]byte, error) {
+	extensions := make(map[uint16][]byte)
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, errUnexpectedMessage
+		}
+		typ := binary.BigEndian.Uint16(data)
+		length := int(binary.BigEndian.Uint16(data[2:]))
+		if len(data) < 4+length {
+			return nil, errUnexpectedMessage
+		}
+		extensions[typ] = data[4 : 4+length]
+		data = data[4+length:]
+	}
+	return extensions, nil
+}
+
+func (this *handshake) processServerHello(msg []byte) error {
+	if len(msg) < sessionIDOffset || msg[0] != handshakeTypeServerHello {
+		return errUnexpectedMessage
+	}
+	random := msg[serverRandomOffset : serverRandomOffset+randomSize]
+	if bytes.Equal(random, helloRetryRequestRandom) {
+		return errors.New("ShadowTLS: HelloRetryRequest is not supported.")
+	}
+
+	data := msg[sessionIDOffset-1:]
+	sessionIDLen := int(data[0])
+	if len(data) < 1+sessionIDLen+5 {
+		return errUnexpectedMessage
+	}
+	data = data[1+sessionIDLen:]
+	suite := binary.BigEndian.Uint16(data)
+	data = data[3:]
+	extLen := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+extLen {
+		return errUnexpectedMessage
+	}
+	extensions, err := parseExtensions(data[2 : 2+extLen])
+	if err != nil {
+		return err
+	}
+	if version := extensions[extensionSupportedVersions]; len(version) != 2 || binary.BigEndian.Uint16(version) != versionTLS13 {
+		return errors.New("ShadowTLS: Handshake server doesn't support TLS 1.3.")
+	}
+	keyShare := extensions[extensionKeyShare]
+	if len(keyShare) < 4 || binary.BigEndian.Uint16(keyShare) != groupX25519 || int(binary.BigEndian.Uint16(keyShare[2:])) != len(keyShare)-4 {
+		return errors.New("ShadowTLS: Invalid key share from server.")
+	}
+	peerKey, err := ecdh.X25519().NewPublicKey(keyShare[4:])
+	if err != nil {
+		return err
+	}
+	shared, err := this.privateKey.ECDH(peerKey)
+	if err != nil {
+		return err
+	}
+
+	switch suite {
+	case 0x1301:
+		this.newHash = sha256.New
+		this.keySize = 16
+	case 0x1302:
+		this.newHash = sha512.New384
+		this.keySize = 32
+	default:
+		return errors.New("ShadowTLS: Unsupported cipher suite.")
+	}
+
+	this.serverRandom = append([]byte{}, random...)
+	this.readHMAC = this.config.newHMAC(this.serverRandom)
+	this.xorKey = this.config.xorKey(this.serverRandom)
+
+	this.transcript = this.newHash()
+	this.transcript.Write(this.clientHelloMsg)
+	this.transcript.Write(msg)
+
+	emptyHash := this.newHash().Sum(nil)
+	earlySecret := hkdfExtract(this.newHash, nil, nil)
+	derived := hkdfExpandLabel(this.newHash, earlySecret, "derived", emptyHash, len(emptyHash))
+	handshakeSecret := hkdfExtract(this.newHash, derived, shared)
+	transcriptHash := this.transcript.Sum(nil)
+	this.clientSecret = hkdfExpandLabel(this.newHash, handshakeSecret, "c hs traffic", transcriptHash, len(emptyHash))
+	this.serverSecret = hkdfExpandLabel(this.newHash, handshakeSecret, "s hs traffic", transcriptHash, len(emptyHash))
+
+	this.serverKey, err = newTrafficKey(this.newHash, this.serverSecret, this.keySize)
+	return err
+}
+
+// unmask restores an application data record modified by the ShadowTLS server. It returns false if
+// the record is not modified.
+func (this *handshake) unmask(payload []byte) ([]byte, bool) {
+	if len(payload) <= hmacSize {
+		return payload, false
+	}
+	this.readHMAC.Write(payload[hmacSize:])
+	if !hmac.Equal(this.readHMAC.Sum(nil)[:hmacSize], payload[:hmacSize]) {
+		return payload, false
+	}
+	content := payload[hmacSize:]
+	for idx := range content {
+		content[idx] ^= this.xorKey[idx%len(this.xorKey)]
+	}
+	return content, true
+}
+
+// nextMessage returns the next complete handshake message from server, reading more records if needed.
+func (this *handshake) nextMessage() ([]byte, error) {
+	for {
+		if len(this.messages) >= 4 {
+			length := 4 + (int(this.messages[1])<<16 | int(this.messages[2])<<8 | int(this.messages[3]))
+			if len(this.messages) >= length {
+				msg := this.messages[:length]
+				this.messages = this.messages[length:]
+				return msg, nil
+			}
+		}
+
+		header, payload, err := readRecord(this.conn)
+		if err != nil {
+			return nil, err
+		}
+		switch header[0] {
+		case recordTypeHandshake:
+			if this.serverKey != nil {
+				return nil, errUnexpectedMessage
+			}
+			this.messages = append(this.messages, payload...)
+		case recordTypeChangeCipherSpec:
+		case recordTypeApplicationData:
+			if this.serverKey == nil {
+				return nil, errUnexpectedMessage
+			}
+			content, modified := this.unmask(payload)
+			if modified {
+				this.authorized = true
+				binary.BigEndian.PutUint16(header[3:], uint16(len(content)))
+			}
+			contentType, plain, err := this.serverKey.open(header, content)
+			if err != nil {
+				return nil, err
+			}
+			if contentType == recordTypeAlert {
+				return nil, errors.New("ShadowTLS: Alert from handshake server.")
+			}
+			if contentType != recordTypeHandshake {
+				return nil, errUnexpectedMessage
+			}
+			this.messages = append(this.messages, plain...)
+		case recordTypeAlert:
+			return nil, errors.New("ShadowTLS: Alert from handshake server.")
+		default:
+			return nil, errUnexpectedMessage
+		}
+	}
+}
+
+func (this *handshake) finishedMAC(secret []byte) []byte {
+	finishedKey := hkdfExpandLabel(this.newHash, secret, "finished", nil, this.newHash().Size())
+	h := hmac.New(this.newHash, finishedKey)
+	h.Write(this.transcript.Sum(nil))
+	return h.Sum(nil)
+}
+
+func (this *handshake) writeRecord(contentType byte, content []byte) error {
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(content))
+	record[0] = contentType
+	record[1] = 0x03
+	record[2] = 0x01
+	binary.BigEndian.PutUint16(record[3:], uint16(len(content)))
+	_, err := this.conn.Write(append(record, content...))
+	return err
+}
+
+// Run performs the handshake. Records from server are authenticated when it returns without error.
+func (this *handshake) Run() error {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	this.privateKey = privateKey
+
+	hello, err := this.buildClientHello()
+	if err != nil {
+		return err
+	}
+	this.clientHelloMsg = hello
+	if err := this.writeRecord(recordTypeHandshake, hello); err != nil {
+		return err
+	}
+
+	msg, err := this.nextMessage()
+	if err != nil {
+		return err
+	}
+	if err := this.processServerHello(msg); err != nil {
+		return err
+	}
+
+	for _, expected := range []byte{handshakeTypeEncryptedExtensions, handshakeTypeCertificate, handshakeTypeCertificateVerify} {
+		msg, err := this.nextMessage()
+		if err != nil {
+			return err
+		}
+		if msg[0] != expected {
+			return errUnexpectedMessage
+		}
+		this.transcript.Write(msg)
+	}
+
+	msg, err = this.nextMessage()
+	if err != nil {
+		return err
+	}
+	if msg[0] != handshakeTypeFinished || !hmac.Equal(msg[4:], this.finishedMAC(this.serverSecret)) {
+		return errors.New("ShadowTLS: Invalid Finished from handshake server.")
+	}
+	this.transcript.Write(msg)
+
+	if !this.authorized {
+		return ErrUnauthorized
+	}
+
+	clientKey, err := newTrafficKey(this.newHash, this.clientSecret, this.keySize)
+	if err != nil {
+		return err
+	}
+	finished := new(bytesBuilder)
+	finished.uint8(handshakeTypeFinished).prefixed(3, func(b *bytesBuilder) { b.Write(this.finishedMAC(this.clientSecret)) })
+
+	// ChangeCipherSpec for middlebox compatibility, followed by encrypted Finished.
+	records := []byte{recordTypeChangeCipherSpec, 0x03, 0x03, 0x00, 0x01, 0x01}
+	records = append(records, clientKey.seal(recordTypeHandshake, finished.Bytes())...)
+	_, err = this.conn.Write(records)
+	return err
+}
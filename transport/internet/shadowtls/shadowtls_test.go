@@ -0,0 +1,233 @@
+package shadowtls_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"hash"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	. "v2ray.com/core/transport/internet/shadowtls"
+)
+
+const (
+	testPassword = "v2ray-password"
+)
+
+type rawConnection struct {
+	net.Conn
+}
+
+func (this *rawConnection) Reusable() bool {
+	return false
+}
+
+func (this *rawConnection) SetReusable(bool) {}
+
+func newHMAC(parts ...[]byte) hash.Hash {
+	h := hmac.New(sha1.New, []byte(testPassword))
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h
+}
+
+func readRecord(reader io.Reader) ([]byte, error) {
+	record := make([]byte, 5)
+	if _, err := io.ReadFull(reader, record); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(record[3:]))
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+	return append(record, payload...), nil
+}
+
+func newRecord(payload []byte) []byte {
+	record := []byte{23, 3, 3, 0, 0}
+	binary.BigEndian.PutUint16(record[3:], uint16(len(payload)))
+	return append(record, payload...)
+}
+
+func startHandshakeServer(assert *assert.Assert) (net.Listener, chan error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Error(err).IsNil()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "www.v2ray.com"},
+		DNSNames:     []string{"www.v2ray.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Error(err).IsNil()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS13,
+	})
+	assert.Error(err).IsNil()
+
+	result := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			result <- err
+			return
+		}
+		result <- conn.(*tls.Conn).Handshake()
+		io.Copy(io.Discard, conn)
+	}()
+	return listener, result
+}
+
+// serveShadowTLS is a minimal ShadowTLS v3 server which echoes data after handshake.
+func serveShadowTLS(assert *assert.Assert, listener net.Listener, handshakeAddr string) {
+	conn, err := listener.Accept()
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	hsConn, err := net.Dial("tcp", handshakeAddr)
+	assert.Error(err).IsNil()
+
+	clientHello, err := readRecord(conn)
+	assert.Error(err).IsNil()
+	hello := append([]byte{}, clientHello[5:]...)
+	mac := append([]byte{}, hello[39+28:39+32]...)
+	copy(hello[39+28:39+32], []byte{0, 0, 0, 0})
+	assert.Bool(hmac.Equal(newHMAC(hello).Sum(nil)[:4], mac)).IsTrue()
+	hsConn.Write(clientHello)
+
+	serverHello, err := readRecord(hsConn)
+	assert.Error(err).IsNil()
+	serverRandom := serverHello[5+6 : 5+6+32]
+	conn.Write(serverHello)
+
+	xorKey := sha256.Sum256(append([]byte(testPassword), serverRandom...))
+	readHMAC := newHMAC(serverRandom)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			record, err := readRecord(hsConn)
+			if err != nil {
+				return
+			}
+			if record[0] == 23 {
+				payload := record[5:]
+				for idx := range payload {
+					payload[idx] ^= xorKey[idx%len(xorKey)]
+				}
+				readHMAC.Write(payload)
+				record = newRecord(append(readHMAC.Sum(nil)[:4], payload...))
+			}
+			conn.Write(record)
+		}
+	}()
+
+	var clientHMAC hash.Hash
+	var frame []byte
+	for {
+		record, err := readRecord(conn)
+		assert.Error(err).IsNil()
+		if record[0] == 23 && len(record) > 9 {
+			candidate := newHMAC(serverRandom, []byte("C"))
+			candidate.Write(record[9:])
+			if sum := candidate.Sum(nil)[:4]; hmac.Equal(sum, record[5:9]) {
+				candidate.Write(sum)
+				clientHMAC = candidate
+				frame = record[9:]
+				break
+			}
+		}
+		hsConn.Write(record)
+	}
+	hsConn.Close()
+	wg.Wait()
+
+	serverHMAC := newHMAC(serverRandom, []byte("S"))
+	for {
+		serverHMAC.Write(frame)
+		sum := serverHMAC.Sum(nil)[:4]
+		serverHMAC.Write(sum)
+		conn.Write(newRecord(append(sum, frame...)))
+
+		record, err := readRecord(conn)
+		if err != nil {
+			return
+		}
+		frame = record[9:]
+		clientHMAC.Write(frame)
+		sum = clientHMAC.Sum(nil)[:4]
+		clientHMAC.Write(sum)
+		assert.Bool(hmac.Equal(sum, record[5:9])).IsTrue()
+	}
+}
+
+func TestShadowTLSHandshake(t *testing.T) {
+	assert := assert.On(t)
+
+	hsListener, hsResult := startHandshakeServer(assert)
+	defer hsListener.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go serveShadowTLS(assert, listener, hsListener.Addr().String())
+
+	rawConn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Error(err).IsNil()
+	var conn internet.Connection = &rawConnection{Conn: rawConn}
+	conn, err = Client(conn, &Config{
+		ServerName: "www.v2ray.com",
+		Password:   testPassword,
+		ALPN:       []string{"h2", "http/1.1"},
+	})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	for _, payload := range []string{"first payload", "second payload"} {
+		nBytes, err := conn.Write([]byte(payload))
+		assert.Error(err).IsNil()
+		assert.Int(nBytes).Equals(len(payload))
+
+		buffer := make([]byte, len(payload))
+		_, err = io.ReadFull(conn, buffer)
+		assert.Error(err).IsNil()
+		assert.String(string(buffer)).Equals(payload)
+	}
+	assert.Error(<-hsResult).IsNil()
+}
+
+func TestShadowTLSUnauthorized(t *testing.T) {
+	assert := assert.On(t)
+
+	hsListener, _ := startHandshakeServer(assert)
+	defer hsListener.Close()
+
+	// Without a ShadowTLS server, records from the handshake server are not signed.
+	rawConn, err := net.Dial("tcp", hsListener.Addr().String())
+	assert.Error(err).IsNil()
+	defer rawConn.Close()
+	_, err = Client(&rawConnection{Conn: rawConn}, &Config{
+		ServerName: "www.v2ray.com",
+		Password:   testPassword,
+	})
+	assert.Error(err).Equals(ErrUnauthorized)
+}
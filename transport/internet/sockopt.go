@@ -0,0 +1,95 @@
+package internet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+type noDelaySetter interface {
+	SetNoDelay(noDelay bool) error
+}
+
+type lingerSetter interface {
+	SetLinger(sec int) error
+}
+
+// Apply applies the socket settings to the given connection, which must be a system connection. Socket
+// buffers set this way don't change the window scale that TCP has negotiated, so dialers of this package set
+// them before connecting instead.
+func (this *SocketConfig) Apply(conn net.Conn) error {
+	if err := this.applyConnected(conn); err != nil {
+		return err
+	}
+	return this.applyBuffers(conn)
+}
+
+// applyConnected applies the settings other than socket buffers.
+func (this *SocketConfig) applyConnected(conn net.Conn) error {
+	if this == nil {
+		return nil
+	}
+	// Connections other than TCP have no Nagle's algorithm to configure.
+	if setter, ok := conn.(noDelaySetter); ok && this.DisableNoDelay {
+		if err := setter.SetNoDelay(false); err != nil {
+			return errors.New("Internet: Failed to clear TCP_NODELAY: " + err.Error())
+		}
+	}
+	if setter, ok := conn.(lingerSetter); ok && this.Linger != nil {
+		if err := setter.SetLinger(int(this.Linger.Timeout)); err != nil {
+			return errors.New("Internet: Failed to set SO_LINGER: " + err.Error())
+		}
+	}
+	if len(this.CongestionControl) > 0 {
+		if err := setCongestionControl(conn, this.CongestionControl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *SocketConfig) hasBuffers() bool {
+	return this != nil && (this.SendBufferSize > 0 || this.ReceiveBufferSize > 0 || this.DisableAutotuning)
+}
+
+func (this *SocketConfig) applyBuffers(conn net.Conn) error {
+	if !this.hasBuffers() {
+		return nil
+	}
+	return applySocketConfig(conn, this)
+}
+
+// dialWithSocket dials to dest by dialer. Socket buffers in settings are set before connecting if the
+// platform supports it, or right after connecting otherwise. The Control function of dialer, if any, runs
+// before that.
+func dialWithSocket(ctx context.Context, dialer *net.Dialer, dest v2net.Destination, settings *SocketConfig) (net.Conn, error) {
+	control := settings.dialControl()
+	if previous := dialer.Control; previous != nil && control != nil {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			if err := previous(network, address, c); err != nil {
+				return err
+			}
+			return control(network, address, c)
+		}
+	} else if control != nil {
+		dialer.Control = control
+	}
+	// NetAddr() keeps zone of IPv6 addresses, e.g., "[fe80::1%eth0]:80".
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	conn, err := dialer.DialContext(ctx, dest.Network.SystemString(), dest.NetAddr())
+	if err != nil {
+		return nil, err
+	}
+	if control == nil {
+		if err := settings.applyBuffers(conn); err != nil {
+			log.Warning("Internet: Failed to apply socket settings: ", err)
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,164 @@
+// +build linux
+
+package internet
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"v2ray.com/core/common/log"
+)
+
+type socketBuffer struct {
+	name      string
+	option    int
+	size      uint32
+	sysctl    string
+	forceName string
+}
+
+// readSysctl returns the value of a sysctl under /proc/sys, or 0 if unavailable.
+func readSysctl(name string) uint32 {
+	content, err := ioutil.ReadFile("/proc/sys/" + strings.Replace(name, ".", "/", -1))
+	if err != nil {
+		return 0
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(value)
+}
+
+func (this *socketBuffer) apply(fd int, disableAutotuning bool) error {
+	size := this.size
+	if size == 0 {
+		if !disableAutotuning {
+			return nil
+		}
+		// Setting the buffer to its current size locks it, which disables autotuning.
+		current, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, this.option)
+		if err != nil {
+			return errors.New("Internet: Failed to get " + this.name + ": " + err.Error())
+		}
+		// Kernel reports the doubled value of what was set.
+		size = uint32(current / 2)
+	} else if max := readSysctl(this.sysctl); max > 0 && size > max {
+		log.Warning("Internet: ", this.name, " of ", size, " exceeds ", this.sysctl, " (", max, "). Raise ", this.sysctl, ", or use ", this.forceName, " which requires CAP_NET_ADMIN.")
+	}
+
+	if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, this.option, int(size)); err != nil {
+		return errors.New("Internet: Failed to set " + this.name + ": " + err.Error())
+	}
+
+	if actual, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, this.option); err == nil && uint32(actual/2) < size {
+		log.Warning("Internet: ", this.name, " is clamped by kernel from ", size, " to ", actual/2, ".")
+	}
+	return nil
+}
+
+// dialControl returns the Control function of net.Dialer that sets socket buffers before connecting, when
+// the receive buffer still decides the window scale of TCP. It returns nil if there are no buffers to set.
+func (this *SocketConfig) dialControl() func(network, address string, c syscall.RawConn) error {
+	if !this.hasBuffers() {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		if err := setSocketBuffers(c, this); err != nil {
+			log.Warning("Internet: Failed to apply socket settings: ", err)
+		}
+		return nil
+	}
+}
+
+func applySocketConfig(conn net.Conn, config *SocketConfig) error {
+	sysConn, ok := conn.(syscall.Conn)
+	if !ok {
+		return errors.New("Internet: Socket buffers are not supported by this connection.")
+	}
+	rawConn, err := sysConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return setSocketBuffers(rawConn, config)
+}
+
+func setSocketBuffers(rawConn syscall.RawConn, config *SocketConfig) error {
+	buffers := []*socketBuffer{
+		{
+			name:      "SO_SNDBUF",
+			option:    syscall.SO_SNDBUF,
+			size:      config.SendBufferSize,
+			sysctl:    "net.core.wmem_max",
+			forceName: "SO_SNDBUFFORCE",
+		},
+		{
+			name:      "SO_RCVBUF",
+			option:    syscall.SO_RCVBUF,
+			size:      config.ReceiveBufferSize,
+			sysctl:    "net.core.rmem_max",
+			forceName: "SO_RCVBUFFORCE",
+		},
+	}
+
+	var applyErr error
+	err := rawConn.Control(func(fd uintptr) {
+		for _, buffer := range buffers {
+			if err := buffer.apply(int(fd), config.DisableAutotuning); err != nil {
+				applyErr = err
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return applyErr
+}
+
+func setCongestionControl(conn net.Conn, algorithm string) error {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		// Only TCP has congestion control.
+		return nil
+	}
+	rawConn, err := tcpConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var setErr error
+	err = rawConn.Control(func(fd uintptr) {
+		setErr = syscall.SetsockoptString(int(fd), syscall.IPPROTO_TCP, syscall.TCP_CONGESTION, algorithm)
+	})
+	if err != nil {
+		return err
+	}
+	switch setErr {
+	case nil:
+		return nil
+	case syscall.ENOENT:
+		return errors.New("Internet: TCP congestion control " + algorithm + " is not available in kernel. Load its module, e.g., tcp_" + algorithm + ", and check net.ipv4.tcp_available_congestion_control.")
+	case syscall.EPERM:
+		return errors.New("Internet: TCP congestion control " + algorithm + " is not allowed. Add it to net.ipv4.tcp_allowed_congestion_control, or run with CAP_NET_ADMIN.")
+	default:
+		return errors.New("Internet: Failed to set TCP_CONGESTION: " + setErr.Error())
+	}
+}
+
+// reuseAddressControl is the Control function of net.Dialer that sets SO_REUSEADDR.
+func reuseAddressControl(network, address string, c syscall.RawConn) error {
+	var setErr error
+	if err := c.Control(func(fd uintptr) {
+		setErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+	}); err != nil {
+		return err
+	}
+	if setErr != nil {
+		return errors.New("Internet: Failed to set SO_REUSEADDR: " + setErr.Error())
+	}
+	return nil
+}
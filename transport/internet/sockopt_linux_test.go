@@ -0,0 +1,175 @@
+// +build linux
+
+package internet_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/testing/servers/tcp"
+	. "v2ray.com/core/transport/internet"
+)
+
+func getSockopt(assert *assert.Assert, conn net.Conn, level int, option int) int {
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	assert.Error(err).IsNil()
+	var value int
+	assert.Error(rawConn.Control(func(fd uintptr) {
+		value, err = syscall.GetsockoptInt(int(fd), level, option)
+	})).IsNil()
+	assert.Error(err).IsNil()
+	return value
+}
+
+func getNoDelay(assert *assert.Assert, conn net.Conn) int {
+	return getSockopt(assert, conn, syscall.IPPROTO_TCP, syscall.TCP_NODELAY)
+}
+
+func TestSocketNoDelaySettings(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	conn, err := DialToDestWithOptions(v2net.LocalHostIP, v2net.TCPDestination(v2net.LocalHostIP, dest.Port), DialerOptions{})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	assert.Int(getNoDelay(assert, conn)).Equals(1)
+
+	conn, err = DialToDestWithOptions(v2net.LocalHostIP, v2net.TCPDestination(v2net.LocalHostIP, dest.Port), DialerOptions{
+		Stream: &StreamConfig{
+			SocketSettings: &SocketConfig{
+				DisableNoDelay: true,
+			},
+		},
+	})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	assert.Int(getNoDelay(assert, conn)).Equals(0)
+}
+
+// closeAndRead closes the connection dialed with socket settings, and returns the error of reading its peer.
+func closeAndRead(assert *assert.Assert, settings *SocketConfig) error {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer listener.Close()
+
+	conn, err := DialToDestWithOptions(v2net.LocalHostIP, v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(listener.Addr().(*net.TCPAddr).Port)), DialerOptions{
+		Stream: &StreamConfig{
+			SocketSettings: settings,
+		},
+	})
+	assert.Error(err).IsNil()
+	peer, err := listener.Accept()
+	assert.Error(err).IsNil()
+	defer peer.Close()
+
+	assert.Error(conn.Close()).IsNil()
+	_, err = peer.Read(make([]byte, 1))
+	return err
+}
+
+func TestSocketLingerSettings(t *testing.T) {
+	assert := assert.On(t)
+
+	assert.Error(closeAndRead(assert, nil)).Equals(io.EOF)
+	assert.Error(closeAndRead(assert, &SocketConfig{Linger: &LingerConfig{Timeout: 5}})).Equals(io.EOF)
+
+	// Connection is reset without linger time.
+	err := closeAndRead(assert, &SocketConfig{Linger: &LingerConfig{Timeout: 0}})
+	assert.Error(err.(*net.OpError).Err.(*os.SyscallError).Err).Equals(syscall.ECONNRESET)
+}
+
+func TestSocketCongestionControl(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	conn, err := DialToDestWithOptions(v2net.LocalHostIP, v2net.TCPDestination(v2net.LocalHostIP, dest.Port), DialerOptions{})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	// Reno is built into kernel.
+	assert.Error((&SocketConfig{CongestionControl: "reno"}).Apply(conn)).IsNil()
+	err = (&SocketConfig{CongestionControl: "v2ray"}).Apply(conn)
+	assert.String(err.Error()).Equals("Internet: TCP congestion control v2ray is not available in kernel. Load its module, e.g., tcp_v2ray, and check net.ipv4.tcp_available_congestion_control.")
+}
+
+func TestSocketBufferSettings(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	conn, err := DialToDestWithOptions(v2net.LocalHostIP, v2net.TCPDestination(v2net.LocalHostIP, dest.Port), DialerOptions{
+		Stream: &StreamConfig{
+			SocketSettings: &SocketConfig{
+				SendBufferSize:    32 * 1024,
+				ReceiveBufferSize: 64 * 1024,
+			},
+		},
+	})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	// Kernel reports the doubled value of what was set.
+	assert.Int(getSockopt(assert, conn, syscall.SOL_SOCKET, syscall.SO_SNDBUF)).Equals(2 * 32 * 1024)
+	assert.Int(getSockopt(assert, conn, syscall.SOL_SOCKET, syscall.SO_RCVBUF)).Equals(2 * 64 * 1024)
+}
+
+// receiveBufferAfterTransfer returns the receive buffer of a connection dialed with socket settings, right
+// after dialing and after receiving a lot of data.
+func receiveBufferAfterTransfer(assert *assert.Assert, settings *SocketConfig) (int, int) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Error(err).IsNil()
+	defer listener.Close()
+
+	conn, err := DialToDestWithOptions(v2net.LocalHostIP, v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(listener.Addr().(*net.TCPAddr).Port)), DialerOptions{
+		Stream: &StreamConfig{
+			SocketSettings: settings,
+		},
+	})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+	peer, err := listener.Accept()
+	assert.Error(err).IsNil()
+
+	before := getSockopt(assert, conn, syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+	go func() {
+		payload := make([]byte, 64*1024)
+		for i := 0; i < 512; i++ {
+			if _, err := peer.Write(payload); err != nil {
+				break
+			}
+		}
+		peer.Close()
+	}()
+	_, err = io.Copy(ioutil.Discard, conn)
+	assert.Error(err).IsNil()
+	return before, getSockopt(assert, conn, syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+}
+
+func TestSocketBufferAutotuning(t *testing.T) {
+	assert := assert.On(t)
+
+	// Receive buffer grows with autotuning.
+	before, after := receiveBufferAfterTransfer(assert, nil)
+	assert.Bool(after > before).IsTrue()
+
+	// Locked buffer keeps its size.
+	before, after = receiveBufferAfterTransfer(assert, &SocketConfig{DisableAutotuning: true})
+	assert.Int(after).Equals(before)
+}
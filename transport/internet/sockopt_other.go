@@ -0,0 +1,53 @@
+// +build !linux
+
+package internet
+
+import (
+	"errors"
+	"net"
+	"syscall"
+
+	"v2ray.com/core/common/log"
+)
+
+type bufferSetter interface {
+	SetReadBuffer(bytes int) error
+	SetWriteBuffer(bytes int) error
+}
+
+// dialControl returns nil, as socket buffers are set after connecting on this platform.
+func (this *SocketConfig) dialControl() func(network, address string, c syscall.RawConn) error {
+	return nil
+}
+
+func applySocketConfig(conn net.Conn, config *SocketConfig) error {
+	setter, ok := conn.(bufferSetter)
+	if !ok {
+		return errors.New("Internet: Socket buffers are not supported by this connection.")
+	}
+	if config.SendBufferSize > 0 {
+		if err := setter.SetWriteBuffer(int(config.SendBufferSize)); err != nil {
+			return errors.New("Internet: Failed to set SO_SNDBUF: " + err.Error())
+		}
+	}
+	if config.ReceiveBufferSize > 0 {
+		if err := setter.SetReadBuffer(int(config.ReceiveBufferSize)); err != nil {
+			return errors.New("Internet: Failed to set SO_RCVBUF: " + err.Error())
+		}
+	}
+	if config.DisableAutotuning && (config.SendBufferSize == 0 || config.ReceiveBufferSize == 0) {
+		log.Warning("Internet: Disabling buffer autotuning without buffer sizes is not supported on this platform.")
+	}
+	return nil
+}
+
+func setCongestionControl(conn net.Conn, algorithm string) error {
+	log.Warning("Internet: TCP congestion control is not supported on this platform. Using system default instead of ", algorithm, ".")
+	return nil
+}
+
+// reuseAddressControl does nothing, as SO_REUSEADDR differs on other platforms, e.g., it lets sockets take
+// ports in use on Windows.
+func reuseAddressControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
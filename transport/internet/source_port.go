@@ -0,0 +1,69 @@
+package internet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"v2ray.com/core/common/dice"
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+var (
+	ErrSourcePortExhausted = errors.New("Internet: All ports in source port range are in use.")
+)
+
+// dialFromPortRange dials to dest from a local port in ports, on behalf of the outbound with tag. Ports are
+// tried in order from a random one, until one of them is not in use. The range is ignored if the effective
+// system dialer can't dial from a given port.
+func dialFromPortRange(ctx context.Context, src v2net.Address, dest v2net.Destination, ports *v2net.PortRange, tag string, settings *SocketConfig) (net.Conn, error) {
+	dialer, ok := effectiveSystemDialer.(sourcePortSystemDialer)
+	if !ok {
+		log.Warning("Internet: Source port range is not supported by the system dialer.")
+		return dialSystem(ctx, src, dest, settings)
+	}
+	if resolver := effectiveDomainResolver; resolver != nil && dest.Address.Family().IsDomain() {
+		// Only the first IP is used, as a failed IP would go through the whole range.
+		ips := resolveDomain(resolver, dest.Address.Domain(), tag)
+		if len(ips) == 0 {
+			log.Warning("Internet: No IP found for domain ", dest.Address.Domain())
+			return nil, ErrDomainNotResolved
+		}
+		dest.Address = v2net.IPAddress(ips[0])
+	}
+
+	from := int(ports.FromPort())
+	size := int(ports.ToPort()) - from + 1
+	if size <= 0 {
+		return nil, ErrSourcePortExhausted
+	}
+	start := dice.Roll(size)
+	for i := 0; i < size; i++ {
+		port := v2net.Port(from + (start+i)%size)
+		conn, err := dialer.DialFromPort(ctx, src, port, dest, settings)
+		if err == nil {
+			return conn, nil
+		}
+		if !isAddressInUse(err) {
+			return nil, err
+		}
+		log.Debug("Internet: Source port ", port, " is in use.")
+	}
+	log.Warning("Internet: No source port available in ", ports.FromPort(), "-", ports.ToPort(), " for ", dest)
+	return nil, ErrSourcePortExhausted
+}
+
+// isAddressInUse returns whether err is of a local port in use. With SO_REUSEADDR, a port of another
+// connection to the same destination fails at connecting, rather than at binding.
+func isAddressInUse(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == syscall.EADDRINUSE || err == syscall.EADDRNOTAVAIL
+}
@@ -1,12 +1,19 @@
 package internet
 
 import (
+	"context"
 	"net"
 	"time"
 
+	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 )
 
+const (
+	// Timeout of system dialers when the dial has no deadline.
+	defaultDialTimeout = time.Second * 60
+)
+
 var (
 	effectiveSystemDialer SystemDialer
 )
@@ -15,30 +22,91 @@ type SystemDialer interface {
 	Dial(source v2net.Address, destination v2net.Destination) (net.Conn, error)
 }
 
+// socketSystemDialer is a SystemDialer that applies socket settings while dialing, so that socket buffers
+// are set before connecting.
+type socketSystemDialer interface {
+	DialWithSocket(ctx context.Context, source v2net.Address, destination v2net.Destination, settings *SocketConfig) (net.Conn, error)
+}
+
+// sourcePortSystemDialer is a SystemDialer that dials from a given local port.
+type sourcePortSystemDialer interface {
+	DialFromPort(ctx context.Context, source v2net.Address, port v2net.Port, destination v2net.Destination, settings *SocketConfig) (net.Conn, error)
+}
+
+// dialSystem dials to dest by the effective system dialer, with socket buffers of settings. Buffers are
+// set right after connecting if the dialer can't set them before. Connecting is cancelled by ctx if not
+// nil, unless the dialer doesn't support it.
+func dialSystem(ctx context.Context, src v2net.Address, dest v2net.Destination, settings *SocketConfig) (net.Conn, error) {
+	if dialer, ok := effectiveSystemDialer.(socketSystemDialer); ok {
+		return dialer.DialWithSocket(ctx, src, dest, settings)
+	}
+	conn, err := effectiveSystemDialer.Dial(src, dest)
+	if err != nil {
+		return nil, err
+	}
+	if err := settings.applyBuffers(conn); err != nil {
+		log.Warning("Internet: Failed to apply socket settings: ", err)
+	}
+	return conn, nil
+}
+
 type DefaultSystemDialer struct {
 }
 
 func (this *DefaultSystemDialer) Dial(src v2net.Address, dest v2net.Destination) (net.Conn, error) {
-	dialer := &net.Dialer{
-		Timeout:   time.Second * 60,
-		DualStack: true,
-	}
+	return this.DialWithSocket(nil, src, dest, nil)
+}
+
+// DialWithSocket dials like Dial(), and sets socket buffers of settings before connecting. Connecting is
+// cancelled by ctx, if not nil.
+func (this *DefaultSystemDialer) DialWithSocket(ctx context.Context, src v2net.Address, dest v2net.Destination, settings *SocketConfig) (net.Conn, error) {
+	return this.DialFromPort(ctx, src, 0, dest, settings)
+}
+
+// DialFromPort dials like DialWithSocket(), from local port, or from any port if it is 0. SO_REUSEADDR is
+// set on sockets of a given port, so that the port is available while its last connection is in TIME_WAIT.
+func (this *DefaultSystemDialer) DialFromPort(ctx context.Context, src v2net.Address, port v2net.Port, dest v2net.Destination, settings *SocketConfig) (net.Conn, error) {
+	dialer := newNetDialer(ctx)
+	var ip net.IP
+	var zone string
 	if src != nil && src != v2net.AnyIP {
-		var addr net.Addr
+		ip = src.IP()
+		zone = v2net.AddressZone(src)
+	}
+	if ip != nil || port != 0 {
 		if dest.Network == v2net.Network_TCP {
-			addr = &net.TCPAddr{
-				IP:   src.IP(),
-				Port: 0,
+			dialer.LocalAddr = &net.TCPAddr{
+				IP:   ip,
+				Port: int(port),
+				Zone: zone,
 			}
 		} else {
-			addr = &net.UDPAddr{
-				IP:   src.IP(),
-				Port: 0,
+			dialer.LocalAddr = &net.UDPAddr{
+				IP:   ip,
+				Port: int(port),
+				Zone: zone,
 			}
 		}
-		dialer.LocalAddr = addr
 	}
-	return dialer.Dial(dest.Network.SystemString(), dest.NetAddr())
+	if port != 0 {
+		dialer.Control = reuseAddressControl
+	}
+	return dialWithSocket(ctx, dialer, dest, settings)
+}
+
+// newNetDialer returns a dialer that times out by the deadline of ctx if it has one, or by defaultDialTimeout
+// otherwise.
+func newNetDialer(ctx context.Context) *net.Dialer {
+	dialer := &net.Dialer{
+		Timeout:   defaultDialTimeout,
+		DualStack: true,
+	}
+	if ctx != nil {
+		if _, found := ctx.Deadline(); found {
+			dialer.Timeout = 0
+		}
+	}
+	return dialer
 }
 
 type SystemDialerAdapter interface {
@@ -1,19 +1,28 @@
 package tcp
 
 import (
+	"crypto/tls"
+	"errors"
 	"io"
 	"net"
 	"time"
 
+	"v2ray.com/core/transport/internet"
 	"v2ray.com/core/transport/internet/internal"
 )
 
+var (
+	ErrLingerNotSupported = errors.New("TCP: SO_LINGER is not supported by this connection.")
+)
+
 type ConnectionManager interface {
 	Recycle(string, net.Conn)
 }
 
+// RawConnection is a TCP connection without transport headers. It is usually a *net.TCPConn, but may be a
+// tunnel through an upstream proxy.
 type RawConnection struct {
-	net.TCPConn
+	net.Conn
 }
 
 func (this *RawConnection) Reusable() bool {
@@ -23,7 +32,40 @@ func (this *RawConnection) Reusable() bool {
 func (this *RawConnection) SetReusable(b bool) {}
 
 func (this *RawConnection) SysFd() (int, error) {
-	return internal.GetSysFd(&this.TCPConn)
+	if conn, ok := this.Conn.(internet.SysFd); ok {
+		return conn.SysFd()
+	}
+	return internal.GetSysFd(this.Conn)
+}
+
+// CloseWrite shuts down the writing side of the connection. It does nothing if the underlying connection
+// can't be half closed.
+func (this *RawConnection) CloseWrite() error {
+	if conn, ok := this.Conn.(interface {
+		CloseWrite() error
+	}); ok {
+		return conn.CloseWrite()
+	}
+	return nil
+}
+
+// SetLinger sets SO_LINGER of the underlying TCP connection, if there is one.
+func (this *RawConnection) SetLinger(sec int) error {
+	return setLinger(this.Conn, sec)
+}
+
+// setLinger sets SO_LINGER of conn, or of the TCP connection under its TLS.
+func setLinger(conn net.Conn, sec int) error {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	setter, ok := conn.(interface {
+		SetLinger(sec int) error
+	})
+	if !ok {
+		return ErrLingerNotSupported
+	}
+	return setter.SetLinger(sec)
 }
 
 type Connection struct {
@@ -59,6 +101,17 @@ func (this *Connection) Write(b []byte) (int, error) {
 	return this.conn.Write(b)
 }
 
+// Handshake completes the TLS handshake now, if the connection is TLS. Otherwise it does nothing.
+func (this *Connection) Handshake() error {
+	if this == nil || this.conn == nil {
+		return io.ErrClosedPipe
+	}
+	if tlsConn, ok := this.conn.(*tls.Conn); ok {
+		return tlsConn.Handshake()
+	}
+	return nil
+}
+
 func (this *Connection) Close() error {
 	if this == nil || this.conn == nil {
 		return io.ErrClosedPipe
@@ -106,3 +159,11 @@ func (this *Connection) Reusable() bool {
 func (this *Connection) SysFd() (int, error) {
 	return internal.GetSysFd(this.conn)
 }
+
+// SetLinger sets SO_LINGER of the underlying TCP connection, if there is one.
+func (this *Connection) SetLinger(sec int) error {
+	if this == nil || this.conn == nil {
+		return io.ErrClosedPipe
+	}
+	return setLinger(this.conn, sec)
+}
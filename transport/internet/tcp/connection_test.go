@@ -11,7 +11,7 @@ import (
 func TestRawConnection(t *testing.T) {
 	assert := assert.On(t)
 
-	rawConn := RawConnection{net.TCPConn{}}
+	rawConn := RawConnection{&net.TCPConn{}}
 	assert.Bool(rawConn.Reusable()).IsFalse()
 
 	rawConn.SetReusable(true)
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"net"
 
-	"crypto/tls"
 	"v2ray.com/core/common/log"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/transport/internet"
@@ -27,15 +26,18 @@ func Dial(src v2net.Address, dest v2net.Destination, options internet.DialerOpti
 	tcpSettings := networkSettings.(*Config)
 
 	id := src.String() + "-" + dest.NetAddr()
+	if options.Proxy.HasUpstream() {
+		id += "-" + options.Proxy.Upstream.Destination().NetAddr()
+	}
 	var conn net.Conn
 	if dest.Network == v2net.Network_TCP && tcpSettings.ConnectionReuse.IsEnabled() {
 		conn = globalCache.Get(id)
 	}
 	if conn == nil {
 		var err error
-		conn, err = internet.DialToDest(src, dest)
+		conn, err = internet.DialToDestWithOptions(src, dest, options)
 		if err != nil {
-			return nil, err
+			return nil, internet.NewLayerError(internet.LayerTCP, err)
 		}
 		if options.Stream != nil && options.Stream.HasSecuritySettings() {
 			securitySettings, err := options.Stream.GetEffectiveSecuritySettings()
@@ -46,10 +48,16 @@ func Dial(src v2net.Address, dest v2net.Destination, options internet.DialerOpti
 			tlsConfig, ok := securitySettings.(*v2tls.Config)
 			if ok {
 				config := tlsConfig.GetTLSConfig()
-				if dest.Address.Family().IsDomain() {
+				if len(config.ServerName) == 0 && dest.Address.Family().IsDomain() {
 					config.ServerName = dest.Address.Domain()
 				}
-				conn = tls.Client(conn, config)
+				// Handshakes now, so that a failure is reported at TLS, instead of when the proxy writes.
+				tlsConn, err := v2tls.ClientHandshake(options.Context, conn, config)
+				if err != nil {
+					conn.Close()
+					return nil, internet.NewLayerError(internet.LayerTLS, err)
+				}
+				conn = tlsConn
 			}
 		}
 		if tcpSettings.HeaderSettings != nil {
@@ -69,13 +77,13 @@ func Dial(src v2net.Address, dest v2net.Destination, options internet.DialerOpti
 
 func DialRaw(src v2net.Address, dest v2net.Destination, options internet.DialerOptions) (internet.Connection, error) {
 	log.Info("Dailing Raw TCP to ", dest)
-	conn, err := internet.DialToDest(src, dest)
+	conn, err := internet.DialToDestWithOptions(src, dest, options)
 	if err != nil {
 		return nil, err
 	}
 	// TODO: handle dialer options
 	return &RawConnection{
-		TCPConn: *conn.(*net.TCPConn),
+		Conn: conn,
 	}, nil
 }
 
@@ -0,0 +1,52 @@
+package tcp_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	. "v2ray.com/core/transport/internet/tcp"
+)
+
+func TestDialRawThroughHTTPProxyWithEarlyData(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := http.ReadRequest(bufio.NewReader(conn)); err != nil {
+			return
+		}
+		// Data from the destination arrives along with the response, and is buffered by the dialer.
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\nearly data"))
+	}()
+
+	conn, err := DialRaw(nil, v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(80)), internet.DialerOptions{
+		Proxy: &internet.ProxyConfig{
+			Upstream: &internet.UpstreamProxy{
+				Address:  v2net.NewIPOrDomain(v2net.LocalHostIP),
+				Port:     uint32(listener.Addr().(*net.TCPAddr).Port),
+				Protocol: internet.UpstreamProxy_HTTP,
+			},
+		},
+	})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	data := make([]byte, len("early data"))
+	_, err = io.ReadFull(conn, data)
+	assert.Error(err).IsNil()
+	assert.String(string(data)).Equals("early data")
+	assert.Error(conn.(*RawConnection).CloseWrite()).IsNil()
+}
@@ -25,18 +25,16 @@ type ConnectionWithError struct {
 type TCPListener struct {
 	sync.Mutex
 	acccepting    bool
-	listener      *net.TCPListener
+	listeners     []*net.TCPListener
 	awaitingConns chan *ConnectionWithError
 	tlsConfig     *tls.Config
 	authConfig    internet.ConnectionAuthenticator
 	config        *Config
+	proxyProtocol bool
 }
 
 func ListenTCP(address v2net.Address, port v2net.Port, options internet.ListenOptions) (internet.Listener, error) {
-	listener, err := net.ListenTCP("tcp", &net.TCPAddr{
-		IP:   address.IP(),
-		Port: int(port),
-	})
+	listeners, err := internet.ListenTCPSockets(address, port, options.Stream.GetListenerSettings())
 	if err != nil {
 		return nil, err
 	}
@@ -48,9 +46,10 @@ func ListenTCP(address v2net.Address, port v2net.Port, options internet.ListenOp
 
 	l := &TCPListener{
 		acccepting:    true,
-		listener:      listener,
+		listeners:     listeners,
 		awaitingConns: make(chan *ConnectionWithError, 32),
 		config:        tcpSettings,
+		proxyProtocol: options.Stream != nil && options.Stream.AcceptProxyProtocol,
 	}
 	if options.Stream != nil && options.Stream.HasSecuritySettings() {
 		securitySettings, err := options.Stream.GetEffectiveSecuritySettings()
@@ -74,7 +73,9 @@ func ListenTCP(address v2net.Address, port v2net.Port, options internet.ListenOp
 		}
 		l.authConfig = auth
 	}
-	go l.KeepAccepting()
+	for _, listener := range listeners {
+		go l.KeepAccepting(listener)
+	}
 	return l, nil
 }
 
@@ -96,14 +97,18 @@ func (this *TCPListener) Accept() (internet.Connection, error) {
 	return nil, ErrClosedListener
 }
 
-func (this *TCPListener) KeepAccepting() {
+func (this *TCPListener) KeepAccepting(listener *net.TCPListener) {
 	for this.acccepting {
-		conn, err := this.listener.Accept()
+		conn, err := listener.Accept()
 		this.Lock()
 		if !this.acccepting {
 			this.Unlock()
 			break
 		}
+		if conn != nil && this.proxyProtocol {
+			// PROXY protocol header comes before anything else, including TLS handshake.
+			conn = internet.NewProxyProtocolConn(conn)
+		}
 		if this.tlsConfig != nil {
 			conn = tls.Server(conn, this.tlsConfig)
 		}
@@ -139,14 +144,16 @@ func (this *TCPListener) Recycle(dest string, conn net.Conn) {
 }
 
 func (this *TCPListener) Addr() net.Addr {
-	return this.listener.Addr()
+	return this.listeners[0].Addr()
 }
 
 func (this *TCPListener) Close() error {
 	this.Lock()
 	defer this.Unlock()
 	this.acccepting = false
-	this.listener.Close()
+	for _, listener := range this.listeners {
+		listener.Close()
+	}
 	close(this.awaitingConns)
 	for connErr := range this.awaitingConns {
 		if connErr.conn != nil {
@@ -156,44 +163,92 @@ func (this *TCPListener) Close() error {
 	return nil
 }
 
+type rawAcceptResult struct {
+	conn *net.TCPConn
+	err  error
+}
+
 type RawTCPListener struct {
-	accepting bool
-	listener  *net.TCPListener
+	accepting     bool
+	listeners     []*net.TCPListener
+	proxyProtocol bool
+	// Connections from all listeners, if there are more than one.
+	accepted chan rawAcceptResult
+	closed   chan bool
+}
+
+func (this *RawTCPListener) keepAccepting(listener *net.TCPListener) {
+	for {
+		conn, err := listener.AcceptTCP()
+		select {
+		case this.accepted <- rawAcceptResult{conn: conn, err: err}:
+		case <-this.closed:
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+	}
+}
+
+func (this *RawTCPListener) acceptTCP() (*net.TCPConn, error) {
+	if len(this.listeners) == 1 {
+		return this.listeners[0].AcceptTCP()
+	}
+	select {
+	case result := <-this.accepted:
+		return result.conn, result.err
+	case <-this.closed:
+		return nil, ErrClosedListener
+	}
 }
 
 func (this *RawTCPListener) Accept() (internet.Connection, error) {
-	conn, err := this.listener.AcceptTCP()
+	conn, err := this.acceptTCP()
 	if err != nil {
 		return nil, err
 	}
+	if this.proxyProtocol {
+		return internet.NewProxyProtocolConn(conn), nil
+	}
 	return &RawConnection{
-		TCPConn: *conn,
+		Conn: conn,
 	}, nil
 }
 
 func (this *RawTCPListener) Addr() net.Addr {
-	return this.listener.Addr()
+	return this.listeners[0].Addr()
 }
 
 func (this *RawTCPListener) Close() error {
 	this.accepting = false
-	this.listener.Close()
+	for _, listener := range this.listeners {
+		listener.Close()
+	}
+	if this.closed != nil {
+		close(this.closed)
+	}
 	return nil
 }
 
 func ListenRawTCP(address v2net.Address, port v2net.Port, options internet.ListenOptions) (internet.Listener, error) {
-	listener, err := net.ListenTCP("tcp", &net.TCPAddr{
-		IP:   address.IP(),
-		Port: int(port),
-	})
+	listeners, err := internet.ListenTCPSockets(address, port, options.Stream.GetListenerSettings())
 	if err != nil {
 		return nil, err
 	}
-	// TODO: handle listen options
-	return &RawTCPListener{
-		accepting: true,
-		listener:  listener,
-	}, nil
+	l := &RawTCPListener{
+		accepting:     true,
+		listeners:     listeners,
+		proxyProtocol: options.Stream != nil && options.Stream.AcceptProxyProtocol,
+	}
+	if len(listeners) > 1 {
+		l.accepted = make(chan rawAcceptResult)
+		l.closed = make(chan bool)
+		for _, listener := range listeners {
+			go l.keepAccepting(listener)
+		}
+	}
+	return l, nil
 }
 
 func init() {
@@ -0,0 +1,90 @@
+package tcp_test
+
+import (
+	"net"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/transport/internet"
+	. "v2ray.com/core/transport/internet/tcp"
+)
+
+func TestRawTCPListenerAcceptors(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := ListenRawTCP(v2net.LocalHostIP, 0, internet.ListenOptions{
+		Stream: &internet.StreamConfig{
+			ListenerSettings: &internet.ListenerConfig{
+				ReusePort: true,
+				Acceptors: 4,
+			},
+		},
+	})
+	assert.Error(err).IsNil()
+
+	for i := 0; i < 16; i++ {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		assert.Error(err).IsNil()
+		accepted, err := listener.Accept()
+		assert.Error(err).IsNil()
+		accepted.Close()
+		conn.Close()
+	}
+
+	assert.Error(listener.Close()).IsNil()
+	_, err = listener.Accept()
+	assert.Error(err).IsNotNil()
+}
+
+func benchmarkAccept(b *testing.B, config *internet.ListenerConfig) {
+	listener, err := ListenRawTCP(v2net.LocalHostIP, 0, internet.ListenOptions{
+		Stream: &internet.StreamConfig{
+			ListenerSettings: config,
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	address := listener.Addr().String()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		buffer := make([]byte, 1)
+		for pb.Next() {
+			conn, err := net.Dial("tcp", address)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			// Waits for the server to accept and close the connection.
+			conn.Read(buffer)
+			conn.Close()
+		}
+	})
+}
+
+// BenchmarkAccept measures connections accepted per second under concurrent dials, with a single
+// listening socket or with SO_REUSEPORT and multiple acceptors.
+func BenchmarkAccept(b *testing.B) {
+	b.Run("Single", func(b *testing.B) {
+		benchmarkAccept(b, nil)
+	})
+	b.Run("ReusePort4", func(b *testing.B) {
+		benchmarkAccept(b, &internet.ListenerConfig{
+			Backlog:   4096,
+			ReusePort: true,
+			Acceptors: 4,
+		})
+	})
+}
@@ -16,6 +16,7 @@ var (
 	TCPListenFunc    ListenFunc
 	RawTCPListenFunc ListenFunc
 	WSListenFunc     ListenFunc
+	GRPCListenFunc   ListenFunc
 )
 
 type ListenFunc func(address v2net.Address, port v2net.Port, options ListenOptions) (Listener, error)
@@ -33,6 +34,7 @@ type TCPHub struct {
 	sync.Mutex
 	listener     Listener
 	connCallback ConnectionHandler
+	throttle     *ConnectionThrottle
 	accepting    bool
 }
 
@@ -49,6 +51,8 @@ func ListenTCP(address v2net.Address, port v2net.Port, callback ConnectionHandle
 		listener, err = KCPListenFunc(address, port, options)
 	case v2net.Network_WebSocket:
 		listener, err = WSListenFunc(address, port, options)
+	case v2net.Network_GRPC:
+		listener, err = GRPCListenFunc(address, port, options)
 	case v2net.Network_RawTCP:
 		listener, err = RawTCPListenFunc(address, port, options)
 	default:
@@ -64,6 +68,7 @@ func ListenTCP(address v2net.Address, port v2net.Port, callback ConnectionHandle
 	hub := &TCPHub{
 		listener:     listener,
 		connCallback: callback,
+		throttle:     NewConnectionThrottle(listener.Addr().String(), settings.ConnectionRateLimit),
 	}
 
 	go hub.start()
@@ -86,6 +91,16 @@ func (this *TCPHub) start() {
 			}
 			continue
 		}
-		go this.connCallback(conn)
+		go this.handleConnection(conn)
 	}
 }
+
+func (this *TCPHub) handleConnection(conn Connection) {
+	// Source is checked here instead of the accept loop, as it may be read from a PROXY protocol header.
+	if source := conn.RemoteAddr(); !this.throttle.Allow(source) {
+		log.Debug("Internet|Listener: Too many connections from ", source, ". Closing.")
+		conn.Close()
+		return
+	}
+	this.connCallback(conn)
+}
@@ -2,14 +2,102 @@ package tls
 
 import (
 	"crypto/tls"
+	"errors"
+	"strings"
+	"sync"
 
 	"v2ray.com/core/common/log"
 )
 
+const (
+	defaultSessionCacheSize = 128
+)
+
+var (
+	// Session caches by size. Dialers create a tls.Config for every connection, so caches are kept
+	// globally to survive across connections.
+	sessionCaches      = make(map[uint32]tls.ClientSessionCache)
+	sessionCachesMutex sync.Mutex
+)
+
+func getSessionCache(size uint32) tls.ClientSessionCache {
+	if size == 0 {
+		size = defaultSessionCacheSize
+	}
+	sessionCachesMutex.Lock()
+	defer sessionCachesMutex.Unlock()
+
+	cache, found := sessionCaches[size]
+	if !found {
+		cache = tls.NewLRUClientSessionCache(int(size))
+		sessionCaches[size] = cache
+	}
+	return cache
+}
+
 var (
-	globalSessionCache = tls.NewLRUClientSessionCache(128)
+	versions = map[Version]uint16{
+		Version_TLS10: tls.VersionTLS10,
+		Version_TLS11: tls.VersionTLS11,
+		Version_TLS12: tls.VersionTLS12,
+		Version_TLS13: tls.VersionTLS13,
+	}
+
+	curves = map[string]tls.CurveID{
+		"x25519":         tls.X25519,
+		"p256":           tls.CurveP256,
+		"p384":           tls.CurveP384,
+		"p521":           tls.CurveP521,
+		"x25519mlkem768": tls.X25519MLKEM768,
+	}
 )
 
+// ParseVersion returns the TLS version of name, such as "1.2" or "TLS1.3".
+func ParseVersion(name string) (Version, error) {
+	switch strings.TrimPrefix(strings.ToLower(name), "tls") {
+	case "1.0":
+		return Version_TLS10, nil
+	case "1.1":
+		return Version_TLS11, nil
+	case "1.2":
+		return Version_TLS12, nil
+	case "1.3":
+		return Version_TLS13, nil
+	default:
+		return Version_Default, errors.New("TLS: Unknown version: " + name)
+	}
+}
+
+// ParseCipherSuite returns the ID of the cipher suite of name, such as
+// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256". Insecure cipher suites are accepted with a warning, as they may
+// be needed to match other clients.
+func ParseCipherSuite(name string) (uint32, error) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			if len(suite.SupportedVersions) == 1 && suite.SupportedVersions[0] == tls.VersionTLS13 {
+				return 0, errors.New("TLS: Cipher suites of TLS 1.3 are not configurable: " + name)
+			}
+			return uint32(suite.ID), nil
+		}
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.Name == name {
+			log.Warning("TLS: Cipher suite ", name, " is insecure.")
+			return uint32(suite.ID), nil
+		}
+	}
+	return 0, errors.New("TLS: Unknown cipher suite: " + name)
+}
+
+// ParseCurve returns the ID of the elliptic curve of name, such as "X25519" or "P256".
+func ParseCurve(name string) (uint32, error) {
+	curve, found := curves[strings.TrimPrefix(strings.ToLower(name), "curve")]
+	if !found {
+		return 0, errors.New("TLS: Unknown curve: " + name)
+	}
+	return uint32(curve), nil
+}
+
 func (this *Config) BuildCertificates() []tls.Certificate {
 	certs := make([]tls.Certificate, 0, len(this.Certificate))
 	for _, entry := range this.Certificate {
@@ -24,16 +112,36 @@ func (this *Config) BuildCertificates() []tls.Certificate {
 }
 
 func (this *Config) GetTLSConfig() *tls.Config {
-	config := &tls.Config{
-		ClientSessionCache: globalSessionCache,
-	}
+	config := new(tls.Config)
 	if this == nil {
+		config.ClientSessionCache = getSessionCache(0)
 		return config
 	}
 
+	if this.DisableSessionResumption {
+		config.SessionTicketsDisabled = true
+	} else {
+		config.ClientSessionCache = getSessionCache(this.SessionCacheSize)
+	}
 	config.InsecureSkipVerify = this.AllowInsecure
+	config.ServerName = this.ServerName
 	config.Certificates = this.BuildCertificates()
 	config.BuildNameToCertificate()
 
+	config.MinVersion = versions[this.MinVersion]
+	config.MaxVersion = versions[this.MaxVersion]
+	if len(this.CipherSuites) > 0 {
+		config.CipherSuites = make([]uint16, len(this.CipherSuites))
+		for idx, suite := range this.CipherSuites {
+			config.CipherSuites[idx] = uint16(suite)
+		}
+	}
+	if len(this.CurvePreferences) > 0 {
+		config.CurvePreferences = make([]tls.CurveID, len(this.CurvePreferences))
+		for idx, curve := range this.CurvePreferences {
+			config.CurvePreferences[idx] = tls.CurveID(curve)
+		}
+	}
+
 	return config
 }
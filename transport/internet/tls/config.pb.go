@@ -29,6 +29,36 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type Version int32
+
+const (
+	Version_Default Version = 0
+	Version_TLS10   Version = 1
+	Version_TLS11   Version = 2
+	Version_TLS12   Version = 3
+	Version_TLS13   Version = 4
+)
+
+var Version_name = map[int32]string{
+	0: "Default",
+	1: "TLS10",
+	2: "TLS11",
+	3: "TLS12",
+	4: "TLS13",
+}
+var Version_value = map[string]int32{
+	"Default": 0,
+	"TLS10":   1,
+	"TLS11":   2,
+	"TLS12":   3,
+	"TLS13":   4,
+}
+
+func (x Version) String() string {
+	return proto.EnumName(Version_name, int32(x))
+}
+func (Version) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
 type Certificate struct {
 	// TLS certificate in x509 format.
 	Certificate []byte `protobuf:"bytes,1,opt,name=Certificate,proto3" json:"Certificate,omitempty"`
@@ -46,6 +76,22 @@ type Config struct {
 	AllowInsecure bool `protobuf:"varint,1,opt,name=allow_insecure,json=allowInsecure" json:"allow_insecure,omitempty"`
 	// List of certificates to be served on server.
 	Certificate []*Certificate `protobuf:"bytes,2,rep,name=certificate" json:"certificate,omitempty"`
+	// Whether or not to disable TLS session resumption, i.e., session cache on client and session tickets
+	// on server.
+	DisableSessionResumption bool `protobuf:"varint,3,opt,name=disable_session_resumption,json=disableSessionResumption" json:"disable_session_resumption,omitempty"`
+	// Number of sessions cached on client for resumption. 128 if not set.
+	SessionCacheSize uint32 `protobuf:"varint,4,opt,name=session_cache_size,json=sessionCacheSize" json:"session_cache_size,omitempty"`
+	// Server name sent in SNI and verified against the certificate of server. Address of destination is
+	// used if it is a domain and this is not set.
+	ServerName string `protobuf:"bytes,5,opt,name=server_name,json=serverName" json:"server_name,omitempty"`
+	// Range of TLS versions, on both client and server. Defaults of Go are used if not set.
+	MinVersion Version `protobuf:"varint,6,opt,name=min_version,json=minVersion,enum=v2ray.core.transport.internet.tls.Version" json:"min_version,omitempty"`
+	MaxVersion Version `protobuf:"varint,7,opt,name=max_version,json=maxVersion,enum=v2ray.core.transport.internet.tls.Version" json:"max_version,omitempty"`
+	// IDs of cipher suites in order of preference, for TLS 1.2 and below. Cipher suites of TLS 1.3 are not
+	// configurable. Defaults of Go are used if empty.
+	CipherSuites []uint32 `protobuf:"varint,8,rep,packed,name=cipher_suites,json=cipherSuites" json:"cipher_suites,omitempty"`
+	// IDs of elliptic curves in order of preference. Defaults of Go are used if empty.
+	CurvePreferences []uint32 `protobuf:"varint,9,rep,packed,name=curve_preferences,json=curvePreferences" json:"curve_preferences,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
@@ -63,24 +109,38 @@ func (m *Config) GetCertificate() []*Certificate {
 func init() {
 	proto.RegisterType((*Certificate)(nil), "v2ray.core.transport.internet.tls.Certificate")
 	proto.RegisterType((*Config)(nil), "v2ray.core.transport.internet.tls.Config")
+	proto.RegisterEnum("v2ray.core.transport.internet.tls.Version", Version_name, Version_value)
 }
 
 func init() { proto.RegisterFile("v2ray.com/core/transport/internet/tls/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 218 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x50, 0xb1, 0x4a, 0x03, 0x41,
-	0x10, 0xe5, 0x72, 0x10, 0x64, 0x57, 0x45, 0xb6, 0x4a, 0x79, 0x06, 0x02, 0xa9, 0x66, 0xe1, 0xac,
-	0x2c, 0x4d, 0x2a, 0xb1, 0x09, 0x57, 0xda, 0xc8, 0xba, 0x4c, 0x64, 0x61, 0xb3, 0x13, 0x66, 0x47,
-	0x25, 0xa5, 0x7f, 0x2e, 0xd9, 0x78, 0x72, 0x57, 0x5d, 0x37, 0xf3, 0xe6, 0xcd, 0x7b, 0x8f, 0xa7,
-	0xda, 0xaf, 0x96, 0xdd, 0x09, 0x3c, 0x1d, 0xac, 0x27, 0x46, 0x2b, 0xec, 0x52, 0x3e, 0x12, 0x8b,
-	0x0d, 0x49, 0x90, 0x13, 0x8a, 0x95, 0x98, 0xad, 0xa7, 0xb4, 0x0f, 0x1f, 0x70, 0x64, 0x12, 0x32,
-	0xf7, 0xfd, 0x0f, 0x23, 0xfc, 0xf3, 0xa1, 0xe7, 0x83, 0xc4, 0xbc, 0x7c, 0x52, 0x7a, 0x8b, 0x2c,
-	0x61, 0x1f, 0xbc, 0x13, 0x34, 0xcd, 0x68, 0x5d, 0x54, 0x4d, 0xb5, 0xbe, 0xee, 0x46, 0x8c, 0x3b,
-	0x55, 0xbf, 0xe0, 0x69, 0x31, 0x2b, 0x97, 0xf3, 0xb8, 0xfc, 0xa9, 0xd4, 0x7c, 0x5b, 0x6c, 0xcd,
-	0x4a, 0xdd, 0xba, 0x18, 0xe9, 0xfb, 0x2d, 0xa4, 0x8c, 0xfe, 0x93, 0x2f, 0x0a, 0x57, 0xdd, 0x4d,
-	0x41, 0x9f, 0xff, 0x40, 0xb3, 0x53, 0xda, 0x0f, 0x5c, 0x66, 0x4d, 0xbd, 0xd6, 0x2d, 0xc0, 0x64,
-	0x5a, 0x18, 0x04, 0xe9, 0x86, 0x12, 0x9b, 0x47, 0xb5, 0xf2, 0x74, 0x98, 0x56, 0xd8, 0xe8, 0x4b,
-	0xd2, 0xdd, 0xb9, 0x9f, 0xd7, 0x5a, 0x62, 0x7e, 0x9f, 0x97, 0xae, 0x1e, 0x7e, 0x03, 0x00, 0x00,
-	0xff, 0xff, 0x5d, 0x97, 0x5b, 0x86, 0x61, 0x01, 0x00, 0x00,
+	// 428 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x9c, 0x52, 0xcf, 0x6f, 0xd3, 0x30,
+	0x14, 0x26, 0xcd, 0xd6, 0xae, 0x2f, 0xeb, 0x14, 0x7c, 0xb2, 0xb8, 0x10, 0x86, 0x26, 0x45, 0x03,
+	0xa5, 0x2c, 0x3b, 0x21, 0x71, 0xa1, 0xe5, 0x82, 0x86, 0x50, 0x95, 0x22, 0x0e, 0x5c, 0x22, 0xcf,
+	0xbc, 0x32, 0x4b, 0x89, 0x1d, 0x3d, 0x3b, 0x65, 0xdb, 0x9f, 0xce, 0x09, 0xd5, 0x69, 0x4a, 0x77,
+	0x1a, 0xda, 0xed, 0xf3, 0xf7, 0xcb, 0xef, 0x49, 0x0f, 0xf2, 0x75, 0x4e, 0xe2, 0x2e, 0x93, 0xa6,
+	0x9e, 0x4a, 0x43, 0x38, 0x75, 0x24, 0xb4, 0x6d, 0x0c, 0xb9, 0xa9, 0xd2, 0x0e, 0x49, 0xa3, 0x9b,
+	0xba, 0xca, 0x4e, 0xa5, 0xd1, 0x2b, 0xf5, 0x2b, 0x6b, 0xc8, 0x38, 0xc3, 0x5e, 0xf5, 0x19, 0xc2,
+	0x6c, 0xe7, 0xcf, 0x7a, 0x7f, 0xe6, 0x2a, 0x7b, 0xfa, 0x11, 0xa2, 0x39, 0x92, 0x53, 0x2b, 0x25,
+	0x85, 0x43, 0x96, 0x3c, 0x78, 0xf2, 0x20, 0x09, 0xd2, 0xe3, 0xe2, 0x81, 0x23, 0x86, 0xf0, 0x0a,
+	0xef, 0xf8, 0xc0, 0x2b, 0x1b, 0x78, 0xfa, 0x27, 0x84, 0xe1, 0xdc, 0x7f, 0xcb, 0xce, 0xe0, 0x44,
+	0x54, 0x95, 0xf9, 0x5d, 0x2a, 0x6d, 0x51, 0xb6, 0xd4, 0x35, 0x1c, 0x15, 0x13, 0xcf, 0x7e, 0xde,
+	0x92, 0x6c, 0x01, 0x91, 0xdc, 0xfb, 0x65, 0x90, 0x84, 0x69, 0x94, 0x67, 0xd9, 0xa3, 0xd3, 0x66,
+	0x7b, 0x83, 0x14, 0xfb, 0x15, 0xec, 0x03, 0xbc, 0xf8, 0xa9, 0xac, 0xb8, 0xae, 0xb0, 0xb4, 0x68,
+	0xad, 0x32, 0xba, 0x24, 0xb4, 0x6d, 0xdd, 0x38, 0x65, 0x34, 0x0f, 0xfd, 0x10, 0x7c, 0xeb, 0x58,
+	0x76, 0x86, 0x62, 0xa7, 0xb3, 0xb7, 0xc0, 0xfa, 0x94, 0x14, 0xf2, 0x06, 0x4b, 0xab, 0xee, 0x91,
+	0x1f, 0x24, 0x41, 0x3a, 0x29, 0xe2, 0xad, 0x32, 0xdf, 0x08, 0x4b, 0x75, 0x8f, 0xec, 0x25, 0x44,
+	0x16, 0x69, 0x8d, 0x54, 0x6a, 0x51, 0x23, 0x3f, 0x4c, 0x82, 0x74, 0x5c, 0x40, 0x47, 0x7d, 0x15,
+	0x35, 0xb2, 0x2b, 0x88, 0x6a, 0xa5, 0xcb, 0x35, 0xd2, 0x26, 0xc8, 0x87, 0x49, 0x90, 0x9e, 0xe4,
+	0xe7, 0xff, 0xb1, 0xde, 0xf7, 0x2e, 0x51, 0x40, 0xad, 0xf4, 0x16, 0xfb, 0x32, 0x71, 0xbb, 0x2b,
+	0x1b, 0x3d, 0xa1, 0x4c, 0xdc, 0xf6, 0x65, 0xaf, 0x61, 0x22, 0x55, 0x73, 0x83, 0x54, 0xda, 0x56,
+	0x39, 0xb4, 0xfc, 0x28, 0x09, 0xd3, 0x49, 0x71, 0xdc, 0x91, 0x4b, 0xcf, 0xb1, 0x37, 0xf0, 0x5c,
+	0xb6, 0xb4, 0xc6, 0xb2, 0x21, 0x5c, 0x21, 0xa1, 0x96, 0x68, 0xf9, 0xd8, 0x1b, 0x63, 0x2f, 0x2c,
+	0xfe, 0xf1, 0xe7, 0x33, 0x18, 0xf5, 0xe5, 0x11, 0x8c, 0x3e, 0xe1, 0x4a, 0xb4, 0x95, 0x8b, 0x9f,
+	0xb1, 0x31, 0x1c, 0x7e, 0xfb, 0xb2, 0xbc, 0x78, 0x17, 0x07, 0x3d, 0xbc, 0x88, 0x07, 0x3d, 0xcc,
+	0xe3, 0xb0, 0x87, 0x97, 0xf1, 0xc1, 0xec, 0x3d, 0x9c, 0x49, 0x53, 0x3f, 0xbe, 0xd2, 0x2c, 0xea,
+	0xce, 0x6c, 0xb1, 0x39, 0xee, 0x1f, 0xa1, 0xab, 0xec, 0xf5, 0xd0, 0x1f, 0xfa, 0xe5, 0xdf, 0x00,
+	0x00, 0x00, 0xff, 0xff, 0x41, 0x6d, 0x2b, 0x98, 0x1e, 0x03, 0x00, 0x00,
 }
@@ -0,0 +1,188 @@
+package tls_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet/tls"
+)
+
+func newCertificate(assert *assert.Assert) *Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Error(err).IsNil()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"v2ray.com"},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Error(err).IsNil()
+	rawKey, err := x509.MarshalECPrivateKey(key)
+	assert.Error(err).IsNil()
+	return &Certificate{
+		Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}),
+		Key:         pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawKey}),
+	}
+}
+
+// handshake connects to listener with a new client config from config, and returns whether the session
+// is resumed.
+func handshake(assert *assert.Assert, listener net.Listener, config *Config) bool {
+	rawConn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Error(err).IsNil()
+	conn := tls.Client(rawConn, config.GetTLSConfig())
+	defer conn.Close()
+
+	// Reads the byte from server, so that session ticket sent after handshake is received.
+	b := make([]byte, 1)
+	_, err = conn.Read(b)
+	assert.Error(err).IsNil()
+	return conn.ConnectionState().DidResume
+}
+
+func serve(listener net.Listener, config *tls.Config) {
+	for {
+		rawConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			conn := tls.Server(rawConn, config)
+			defer conn.Close()
+			conn.Write([]byte{'v'})
+			b := make([]byte, 1)
+			conn.Read(b)
+		}()
+	}
+}
+
+func testSessionResumption(assert *assert.Assert, disabled bool) (bool, bool) {
+	serverConfig := &Config{
+		Certificate:              []*Certificate{newCertificate(assert)},
+		DisableSessionResumption: disabled,
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go serve(listener, serverConfig.GetTLSConfig())
+
+	clientConfig := &Config{
+		AllowInsecure:            true,
+		DisableSessionResumption: disabled,
+		SessionCacheSize:         7,
+	}
+	return handshake(assert, listener, clientConfig), handshake(assert, listener, clientConfig)
+}
+
+func TestSessionResumption(t *testing.T) {
+	assert := assert.On(t)
+
+	first, second := testSessionResumption(assert, false)
+	assert.Bool(first).IsFalse()
+	assert.Bool(second).IsTrue()
+}
+
+func TestSessionResumptionDisabled(t *testing.T) {
+	assert := assert.On(t)
+
+	first, second := testSessionResumption(assert, true)
+	assert.Bool(first).IsFalse()
+	assert.Bool(second).IsFalse()
+}
+
+func TestServerName(t *testing.T) {
+	assert := assert.On(t)
+
+	config := &Config{
+		ServerName: "www.v2ray.com",
+	}
+	assert.String(config.GetTLSConfig().ServerName).Equals("www.v2ray.com")
+}
+
+// connect handshakes with listener with a new client config from config, and returns the connection state.
+func connect(assert *assert.Assert, listener net.Listener, config *Config) (tls.ConnectionState, error) {
+	rawConn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Error(err).IsNil()
+	conn := tls.Client(rawConn, config.GetTLSConfig())
+	defer conn.Close()
+	err = conn.Handshake()
+	return conn.ConnectionState(), err
+}
+
+func TestVersionRange(t *testing.T) {
+	assert := assert.On(t)
+
+	serverConfig := &Config{
+		Certificate: []*Certificate{newCertificate(assert)},
+		MinVersion:  Version_TLS13,
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go serve(listener, serverConfig.GetTLSConfig())
+
+	state, err := connect(assert, listener, &Config{AllowInsecure: true})
+	assert.Error(err).IsNil()
+	assert.Uint16(state.Version).Equals(tls.VersionTLS13)
+
+	_, err = connect(assert, listener, &Config{AllowInsecure: true, MaxVersion: Version_TLS12})
+	assert.Error(err).IsNotNil()
+}
+
+func TestCipherSuites(t *testing.T) {
+	assert := assert.On(t)
+
+	suite, err := ParseCipherSuite("TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384")
+	assert.Error(err).IsNil()
+	curve, err := ParseCurve("P384")
+	assert.Error(err).IsNil()
+
+	serverConfig := &Config{
+		Certificate: []*Certificate{newCertificate(assert)},
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go serve(listener, serverConfig.GetTLSConfig())
+
+	state, err := connect(assert, listener, &Config{
+		AllowInsecure:    true,
+		MaxVersion:       Version_TLS12,
+		CipherSuites:     []uint32{suite},
+		CurvePreferences: []uint32{curve},
+	})
+	assert.Error(err).IsNil()
+	assert.Uint16(state.CipherSuite).Equals(tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384)
+	assert.Uint16(uint16(state.CurveID)).Equals(uint16(tls.CurveP384))
+}
+
+func TestParseNames(t *testing.T) {
+	assert := assert.On(t)
+
+	version, err := ParseVersion("TLS1.3")
+	assert.Error(err).IsNil()
+	assert.Bool(version == Version_TLS13).IsTrue()
+	_, err = ParseVersion("1.4")
+	assert.Error(err).IsNotNil()
+
+	_, err = ParseCipherSuite("TLS_ECDHE_ECDSA_WITH_AES_512_GCM_SHA384")
+	assert.Error(err).IsNotNil()
+	_, err = ParseCipherSuite("TLS_AES_128_GCM_SHA256")
+	assert.Error(err).IsNotNil()
+
+	curve, err := ParseCurve("CurveP256")
+	assert.Error(err).IsNil()
+	assert.Uint32(curve).Equals(uint32(tls.CurveP256))
+	_, err = ParseCurve("P224")
+	assert.Error(err).IsNotNil()
+}
@@ -1,7 +1,15 @@
 package tls
 
 import (
+	"context"
 	"crypto/tls"
+	"net"
+	"time"
+)
+
+const (
+	// Longest time of TLS handshakes by dialers, unless their dials have an earlier deadline.
+	clientHandshakeTimeout = 30 * time.Second
 )
 
 type Connection struct {
@@ -19,3 +27,22 @@ func NewConnection(conn *tls.Conn) *Connection {
 		Conn: conn,
 	}
 }
+
+// ClientHandshake returns a TLS client connection of config on conn, after its handshake. The handshake is
+// cancelled by ctx if not nil, and fails at the deadline of ctx or after clientHandshakeTimeout, whichever
+// is earlier, so that a stalled server doesn't hold the dial forever.
+func ClientHandshake(ctx context.Context, conn net.Conn, config *tls.Config) (*tls.Conn, error) {
+	deadline := time.Now().Add(clientHandshakeTimeout)
+	if ctx == nil {
+		ctx = context.Background()
+	} else if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
@@ -0,0 +1,41 @@
+package tls_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/internet/tls"
+)
+
+func TestClientHandshakeDeadline(t *testing.T) {
+	assert := assert.On(t)
+
+	// The server accepts connections, and never handshakes.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = ClientHandshake(ctx, conn, &tls.Config{InsecureSkipVerify: true})
+	assert.Error(err).IsNotNil()
+	assert.Bool(time.Since(start) < time.Second).IsTrue()
+}
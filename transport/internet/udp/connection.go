@@ -19,7 +19,7 @@ func (this *Connection) SetReusable(b bool) {}
 
 func init() {
 	internet.UDPDialer = func(src v2net.Address, dest v2net.Destination, options internet.DialerOptions) (internet.Connection, error) {
-		conn, err := internet.DialToDest(src, dest)
+		conn, err := internet.DialToDestWithOptions(src, dest, options)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,74 @@
+package internet
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/transport/internet/internal"
+)
+
+var (
+	ErrUpstreamAuthRequired = errors.New("Internet|Upstream: HTTP proxy requires authentication (407).")
+)
+
+// httpConnect opens a tunnel to dest by HTTP CONNECT method on conn. It returns the connection of the
+// tunnel, which may have buffered data from dest.
+func (this *UpstreamProxy) httpConnect(conn net.Conn, dest v2net.Destination) (net.Conn, error) {
+	target := dest.NetAddr()
+	request := "CONNECT " + target + " HTTP/1.1\r\nHost: " + target + "\r\n"
+	if len(this.Username) > 0 {
+		credential := base64.StdEncoding.EncodeToString([]byte(this.Username + ":" + this.Password))
+		request += "Proxy-Authorization: Basic " + credential + "\r\n"
+	}
+	request += "\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return conn, errors.New("Internet|Upstream: Failed to write CONNECT request: " + err.Error())
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return conn, errors.New("Internet|Upstream: Failed to read CONNECT response: " + err.Error())
+	}
+	switch {
+	case response.StatusCode == http.StatusProxyAuthRequired && len(this.Username) > 0:
+		return conn, ErrUpstreamAuthFailed
+	case response.StatusCode == http.StatusProxyAuthRequired:
+		return conn, ErrUpstreamAuthRequired
+	case response.StatusCode/100 != 2:
+		return conn, errors.New("Internet|Upstream: HTTP proxy failed to connect to " + dest.String() + ", status " + strconv.Itoa(response.StatusCode) + ".")
+	}
+	if reader.Buffered() == 0 {
+		return conn, nil
+	}
+	return &bufferedConn{
+		Conn:   conn,
+		reader: reader,
+	}, nil
+}
+
+// bufferedConn reads data that was buffered during handshake, before reading the underlying connection.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (this *bufferedConn) Read(b []byte) (int, error) {
+	return this.reader.Read(b)
+}
+
+func (this *bufferedConn) SysFd() (int, error) {
+	return internal.GetSysFd(this.Conn)
+}
+
+func (this *bufferedConn) CloseWrite() error {
+	if conn, ok := this.Conn.(*net.TCPConn); ok {
+		return conn.CloseWrite()
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package internet
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"v2ray.com/core/common/log"
+	v2net "v2ray.com/core/common/net"
+)
+
+const (
+	socks5Version          = 0x05
+	socks5AuthNone         = 0x00
+	socks5AuthUserPass     = 0x02
+	socks5AuthNoAcceptable = 0xFF
+	socks5CommandConnect   = 0x01
+	socks5AddrTypeIPv4     = 0x01
+	socks5AddrTypeDomain   = 0x03
+	socks5AddrTypeIPv6     = 0x04
+
+	upstreamHandshakeTimeout = time.Second * 30
+)
+
+var (
+	ErrUpstreamAuthFailed     = errors.New("Internet|Upstream: Authentication failed.")
+	ErrUpstreamUDPUnsupported = errors.New("Internet|Upstream: UDP is not supported through upstream proxy.")
+)
+
+func (this *ProxyConfig) HasUpstream() bool {
+	return this != nil && this.Upstream != nil
+}
+
+// SupportsNetwork returns true if connections to destinations in network can be made with these options.
+// UDP is not supported through upstream proxy.
+func (this DialerOptions) SupportsNetwork(network v2net.Network) bool {
+	return network != v2net.Network_UDP || !this.Proxy.HasUpstream()
+}
+
+func (this *UpstreamProxy) Destination() v2net.Destination {
+	return v2net.TCPDestination(this.Address.AsAddress(), v2net.Port(this.Port))
+}
+
+// Dial connects to dest through the upstream proxy.
+func (this *UpstreamProxy) Dial(src v2net.Address, dest v2net.Destination) (net.Conn, error) {
+	if dest.Network != v2net.Network_TCP {
+		return nil, ErrUpstreamUDPUnsupported
+	}
+	log.Info("Internet|Upstream: Dialing ", dest, " via ", this.Protocol, " proxy ", this.Destination())
+	conn, err := DialToDest(src, this.Destination())
+	if err != nil {
+		return nil, errors.New("Internet|Upstream: Failed to dial " + this.Protocol.String() + " proxy: " + err.Error())
+	}
+	conn.SetDeadline(time.Now().Add(upstreamHandshakeTimeout))
+	if this.Protocol == UpstreamProxy_HTTP {
+		conn, err = this.httpConnect(conn, dest)
+	} else {
+		err = this.handshake(conn, dest)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func (this *UpstreamProxy) handshake(conn net.Conn, dest v2net.Destination) error {
+	method := byte(socks5AuthNone)
+	if len(this.Username) > 0 {
+		method = socks5AuthUserPass
+	}
+	if _, err := conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return errors.New("Internet|Upstream: Failed to write authentication request: " + err.Error())
+	}
+	response := make([]byte, 2)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return errors.New("Internet|Upstream: Failed to read authentication response: " + err.Error())
+	}
+	if response[0] != socks5Version {
+		return errors.New("Internet|Upstream: Unexpected SOCKS version: " + strconv.Itoa(int(response[0])))
+	}
+	if response[1] != method {
+		if response[1] == socks5AuthNoAcceptable && method == socks5AuthNone {
+			return errors.New("Internet|Upstream: SOCKS5 proxy requires authentication.")
+		}
+		return errors.New("Internet|Upstream: Unsupported authentication method: " + strconv.Itoa(int(response[1])))
+	}
+
+	if method == socks5AuthUserPass {
+		if len(this.Username) > 255 || len(this.Password) > 255 {
+			return errors.New("Internet|Upstream: Username or password too long.")
+		}
+		request := make([]byte, 0, 3+len(this.Username)+len(this.Password))
+		request = append(request, 0x01, byte(len(this.Username)))
+		request = append(request, this.Username...)
+		request = append(request, byte(len(this.Password)))
+		request = append(request, this.Password...)
+		if _, err := conn.Write(request); err != nil {
+			return errors.New("Internet|Upstream: Failed to write user/pass: " + err.Error())
+		}
+		if _, err := io.ReadFull(conn, response); err != nil {
+			return errors.New("Internet|Upstream: Failed to read user/pass response: " + err.Error())
+		}
+		if response[1] != 0x00 {
+			return ErrUpstreamAuthFailed
+		}
+	}
+
+	request := []byte{socks5Version, socks5CommandConnect, 0x00}
+	switch dest.Address.Family() {
+	case v2net.AddressFamilyIPv4:
+		request = append(request, socks5AddrTypeIPv4)
+		request = append(request, dest.Address.IP().To4()...)
+	case v2net.AddressFamilyIPv6:
+		request = append(request, socks5AddrTypeIPv6)
+		request = append(request, dest.Address.IP().To16()...)
+	case v2net.AddressFamilyDomain:
+		domain := dest.Address.Domain()
+		if len(domain) > 255 {
+			return errors.New("Internet|Upstream: Domain too long: " + domain)
+		}
+		request = append(request, socks5AddrTypeDomain, byte(len(domain)))
+		request = append(request, domain...)
+	}
+	request = append(request, byte(dest.Port>>8), byte(dest.Port))
+	if _, err := conn.Write(request); err != nil {
+		return errors.New("Internet|Upstream: Failed to write connect request: " + err.Error())
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return errors.New("Internet|Upstream: Failed to read connect response: " + err.Error())
+	}
+	if header[1] != 0x00 {
+		return errors.New("Internet|Upstream: SOCKS5 proxy failed to connect to " + dest.String() + ", reply code " + strconv.Itoa(int(header[1])))
+	}
+	var addrLen int
+	switch header[3] {
+	case socks5AddrTypeIPv4:
+		addrLen = 4
+	case socks5AddrTypeIPv6:
+		addrLen = 16
+	case socks5AddrTypeDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return errors.New("Internet|Upstream: Failed to read bound address: " + err.Error())
+		}
+		addrLen = int(length[0])
+	default:
+		return errors.New("Internet|Upstream: Unknown address type: " + strconv.Itoa(int(header[3])))
+	}
+	// Bound address and port are not used.
+	if _, err := io.ReadFull(conn, make([]byte, addrLen+2)); err != nil {
+		return errors.New("Internet|Upstream: Failed to read bound address: " + err.Error())
+	}
+	return nil
+}
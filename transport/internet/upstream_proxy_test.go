@@ -0,0 +1,219 @@
+package internet_test
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+
+	v2net "v2ray.com/core/common/net"
+	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/testing/servers/tcp"
+	. "v2ray.com/core/transport/internet"
+)
+
+// serveSocks5 accepts one connection, authenticates it with the given username and password, and
+// relays it to the requested destination.
+func serveSocks5(listener net.Listener, username, password string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+	if methods[0] != 0x02 {
+		conn.Write([]byte{0x05, 0xFF})
+		return
+	}
+	conn.Write([]byte{0x05, 0x02})
+
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	user := make([]byte, header[1])
+	io.ReadFull(conn, user)
+	io.ReadFull(conn, header[:1])
+	pass := make([]byte, header[0])
+	io.ReadFull(conn, pass)
+	if string(user) != username || string(pass) != password {
+		conn.Write([]byte{0x01, 0x01})
+		return
+	}
+	conn.Write([]byte{0x01, 0x00})
+
+	request := make([]byte, 4+4+2)
+	if _, err := io.ReadFull(conn, request); err != nil || request[3] != 0x01 {
+		return
+	}
+	target := net.IP(request[4:8]).String() + ":" + strconv.Itoa(int(binary.BigEndian.Uint16(request[8:])))
+	targetConn, err := net.Dial("tcp", target)
+	if err != nil {
+		conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer targetConn.Close()
+	conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(targetConn, conn)
+	io.Copy(conn, targetConn)
+}
+
+func TestDialThroughUpstreamProxy(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{
+		MsgProcessor: func(data []byte) []byte {
+			return append([]byte("Processed: "), data...)
+		},
+	}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go serveSocks5(listener, "v2ray", "v2ray-password")
+
+	upstream := &UpstreamProxy{
+		Address:  v2net.NewIPOrDomain(v2net.LocalHostIP),
+		Port:     uint32(listener.Addr().(*net.TCPAddr).Port),
+		Username: "v2ray",
+		Password: "v2ray-password",
+	}
+	conn, err := DialToDestWithOptions(nil, dest, DialerOptions{
+		Proxy: &ProxyConfig{Upstream: upstream},
+	})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("Test"))
+	assert.Error(err).IsNil()
+	response := make([]byte, len("Processed: Test"))
+	_, err = io.ReadFull(conn, response)
+	assert.Error(err).IsNil()
+	assert.String(string(response)).Equals("Processed: Test")
+}
+
+func TestUpstreamProxyAuthFailure(t *testing.T) {
+	assert := assert.On(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go serveSocks5(listener, "v2ray", "v2ray-password")
+
+	upstream := &UpstreamProxy{
+		Address:  v2net.NewIPOrDomain(v2net.LocalHostIP),
+		Port:     uint32(listener.Addr().(*net.TCPAddr).Port),
+		Username: "v2ray",
+		Password: "wrong-password",
+	}
+	_, err = upstream.Dial(nil, v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(80)))
+	assert.Error(err).Equals(ErrUpstreamAuthFailed)
+}
+
+// serveHTTPConnect accepts one connection, checks its Basic authentication if credential is not empty,
+// and relays CONNECT requests to their targets.
+func serveHTTPConnect(listener net.Listener, credential string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	request, err := http.ReadRequest(reader)
+	if err != nil || request.Method != http.MethodConnect {
+		return
+	}
+	if len(credential) > 0 && request.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(credential)) {
+		conn.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\nProxy-Authenticate: Basic realm=\"v2ray\"\r\n\r\n"))
+		return
+	}
+	targetConn, err := net.Dial("tcp", request.Host)
+	if err != nil {
+		conn.Write([]byte("HTTP/1.1 502 Bad Gateway\r\n\r\n"))
+		return
+	}
+	defer targetConn.Close()
+	conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+
+	go io.Copy(targetConn, reader)
+	io.Copy(conn, targetConn)
+}
+
+func TestDialThroughHTTPUpstreamProxy(t *testing.T) {
+	assert := assert.On(t)
+
+	server := &tcp.Server{
+		MsgProcessor: func(data []byte) []byte {
+			return append([]byte("Processed: "), data...)
+		},
+	}
+	dest, err := server.Start()
+	assert.Error(err).IsNil()
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Error(err).IsNil()
+	defer listener.Close()
+	go serveHTTPConnect(listener, "v2ray:v2ray-password")
+
+	upstream := &UpstreamProxy{
+		Address:  v2net.NewIPOrDomain(v2net.LocalHostIP),
+		Port:     uint32(listener.Addr().(*net.TCPAddr).Port),
+		Username: "v2ray",
+		Password: "v2ray-password",
+		Protocol: UpstreamProxy_HTTP,
+	}
+	conn, err := DialToDestWithOptions(nil, dest, DialerOptions{
+		Proxy: &ProxyConfig{Upstream: upstream},
+	})
+	assert.Error(err).IsNil()
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("Test"))
+	assert.Error(err).IsNil()
+	response := make([]byte, len("Processed: Test"))
+	_, err = io.ReadFull(conn, response)
+	assert.Error(err).IsNil()
+	assert.String(string(response)).Equals("Processed: Test")
+}
+
+func TestHTTPUpstreamProxyAuthRequired(t *testing.T) {
+	assert := assert.On(t)
+
+	for _, password := range []string{"", "wrong-password"} {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Error(err).IsNil()
+		go serveHTTPConnect(listener, "v2ray:v2ray-password")
+
+		upstream := &UpstreamProxy{
+			Address:  v2net.NewIPOrDomain(v2net.LocalHostIP),
+			Port:     uint32(listener.Addr().(*net.TCPAddr).Port),
+			Protocol: UpstreamProxy_HTTP,
+		}
+		expected := ErrUpstreamAuthRequired
+		if len(password) > 0 {
+			upstream.Username = "v2ray"
+			upstream.Password = password
+			expected = ErrUpstreamAuthFailed
+		}
+		_, err = upstream.Dial(nil, v2net.TCPDestination(v2net.LocalHostIP, v2net.Port(80)))
+		assert.Error(err).Equals(expected)
+		listener.Close()
+	}
+}
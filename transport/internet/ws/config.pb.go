@@ -43,6 +43,8 @@ type Config struct {
 	ConnectionReuse *ConnectionReuse `protobuf:"bytes,1,opt,name=connection_reuse,json=connectionReuse" json:"connection_reuse,omitempty"`
 	// URL path to the WebSocket service. Empty value means root(/).
 	Path string `protobuf:"bytes,2,opt,name=path" json:"path,omitempty"`
+	// Host header of WebSocket requests. Address of destination is used if not set.
+	Host string `protobuf:"bytes,3,opt,name=host" json:"host,omitempty"`
 }
 
 func (m *Config) Reset()                    { *m = Config{} }
@@ -65,18 +67,19 @@ func init() {
 func init() { proto.RegisterFile("v2ray.com/core/transport/internet/ws/config.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 204 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x90, 0x31, 0x4b, 0xc6, 0x30,
-	0x10, 0x86, 0xe9, 0x87, 0x14, 0xcd, 0x37, 0x54, 0x32, 0x48, 0xc7, 0x52, 0x1c, 0xea, 0x92, 0xd0,
-	0x3a, 0xb8, 0xb7, 0x7f, 0x40, 0x32, 0x8a, 0x20, 0x69, 0x38, 0xb5, 0x60, 0xef, 0xca, 0x25, 0x5a,
-	0xf4, 0xd7, 0x4b, 0xa3, 0xed, 0xd0, 0xa5, 0x5b, 0x02, 0xef, 0xf3, 0x3e, 0x77, 0x27, 0xea, 0xaf,
-	0x86, 0xed, 0xb7, 0x72, 0x34, 0x6a, 0x47, 0x0c, 0x3a, 0xb0, 0x45, 0x3f, 0x11, 0x07, 0x3d, 0x60,
-	0x00, 0x46, 0x08, 0x7a, 0xf6, 0xda, 0x11, 0xbe, 0x0e, 0x6f, 0x6a, 0x62, 0x0a, 0x24, 0x8b, 0x15,
-	0x61, 0x50, 0x5b, 0x5c, 0xad, 0x71, 0x35, 0xfb, 0xf2, 0x4e, 0x64, 0x1d, 0x21, 0x82, 0x0b, 0x03,
-	0xa1, 0x81, 0x4f, 0x0f, 0xf2, 0x46, 0xa4, 0x80, 0xb6, 0xff, 0x80, 0x3c, 0x29, 0x92, 0xea, 0xd2,
-	0xfc, 0xff, 0xca, 0x1f, 0x91, 0x76, 0xb1, 0x5c, 0x3e, 0x8b, 0x6b, 0xb7, 0x41, 0x2f, 0xbc, 0x50,
-	0x31, 0x7b, 0x6e, 0x6a, 0x75, 0x64, 0x54, 0x3b, 0x9d, 0xc9, 0xdc, 0xce, 0x2f, 0xc5, 0xc5, 0x64,
-	0xc3, 0x7b, 0x7e, 0x2a, 0x92, 0xea, 0xca, 0xc4, 0x77, 0xfb, 0x20, 0x6e, 0x1d, 0x8d, 0x87, 0xe5,
-	0xed, 0xf9, 0x6f, 0xc2, 0xc7, 0x65, 0xfb, 0xa7, 0xd3, 0xec, 0xfb, 0x34, 0x1e, 0xe2, 0xfe, 0x37,
-	0x00, 0x00, 0xff, 0xff, 0x28, 0x43, 0x58, 0xd9, 0x3d, 0x01, 0x00, 0x00,
+	// 217 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x84, 0x50, 0xb1, 0x4a, 0x03, 0x41,
+	0x10, 0x65, 0xa3, 0x1c, 0xba, 0x29, 0x22, 0x5b, 0xc8, 0x95, 0x47, 0xb0, 0x88, 0xcd, 0x2e, 0x89,
+	0x85, 0x7d, 0xf2, 0x03, 0xb2, 0xa5, 0x08, 0xb2, 0x59, 0x46, 0x73, 0x60, 0x66, 0x8e, 0xd9, 0xd1,
+	0xc3, 0x9f, 0xf0, 0x9b, 0x65, 0x37, 0xc9, 0x15, 0xd7, 0x5c, 0xf7, 0xe6, 0xf1, 0xde, 0xbc, 0xc7,
+	0xd3, 0xeb, 0x9f, 0x0d, 0x87, 0x5f, 0x1b, 0xe9, 0xe8, 0x22, 0x31, 0x38, 0xe1, 0x80, 0xa9, 0x23,
+	0x16, 0xd7, 0xa2, 0x00, 0x23, 0x88, 0xeb, 0x93, 0x8b, 0x84, 0x1f, 0xed, 0xa7, 0xed, 0x98, 0x84,
+	0x4c, 0x73, 0xb1, 0x30, 0xd8, 0x41, 0x6e, 0x2f, 0x72, 0xdb, 0xa7, 0xe5, 0xa3, 0x5e, 0xec, 0x08,
+	0x11, 0xa2, 0xb4, 0x84, 0x1e, 0xbe, 0x13, 0x98, 0x7b, 0x5d, 0x01, 0x86, 0xfd, 0x17, 0xd4, 0xaa,
+	0x51, 0xab, 0x1b, 0x7f, 0xbe, 0x96, 0x7f, 0x4a, 0x57, 0xbb, 0xf2, 0xdd, 0xbc, 0xe9, 0xbb, 0x38,
+	0xb8, 0xde, 0x39, 0xdb, 0x8a, 0x78, 0xbe, 0x59, 0xdb, 0xa9, 0x48, 0x3b, 0xca, 0xf3, 0x8b, 0x38,
+	0x2a, 0x60, 0xf4, 0x75, 0x17, 0xe4, 0x50, 0xcf, 0x1a, 0xb5, 0xba, 0xf5, 0x05, 0x67, 0xee, 0x40,
+	0x49, 0xea, 0xab, 0x13, 0x97, 0xf1, 0xf6, 0x59, 0x3f, 0x44, 0x3a, 0x4e, 0x06, 0x6e, 0xe7, 0xa7,
+	0xd6, 0x2f, 0x79, 0x92, 0xd7, 0x59, 0x9f, 0xf6, 0x55, 0x59, 0xe7, 0xe9, 0x3f, 0x00, 0x00, 0xff,
+	0xff, 0xe1, 0x9e, 0x9f, 0x72, 0x52, 0x01, 0x00, 0x00,
 }
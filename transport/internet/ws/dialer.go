@@ -1,9 +1,12 @@
 package ws
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
+	"net/http"
 
 	"github.com/gorilla/websocket"
 	"v2ray.com/core/common/log"
@@ -57,8 +60,23 @@ func wsDial(src v2net.Address, dest v2net.Destination, options internet.DialerOp
 	}
 	wsSettings := networkSettings.(*Config)
 
+	// TLS is negotiated in NetDial rather than by the WebSocket dialer, so that its failures are told
+	// from failures of TCP and of the upgrade.
+	var tlsConfig *tls.Config
 	commonDial := func(network, addr string) (net.Conn, error) {
-		return internet.DialToDest(src, dest)
+		conn, err := internet.DialToDestWithOptions(src, dest, options)
+		if err != nil {
+			return nil, internet.NewLayerError(internet.LayerTCP, err)
+		}
+		if tlsConfig == nil {
+			return conn, nil
+		}
+		tlsConn, err := v2tls.ClientHandshake(options.Context, conn, tlsConfig)
+		if err != nil {
+			conn.Close()
+			return nil, internet.NewLayerError(internet.LayerTLS, err)
+		}
+		return tlsConn, nil
 	}
 
 	dialer := websocket.Dialer{
@@ -67,20 +85,21 @@ func wsDial(src v2net.Address, dest v2net.Destination, options internet.DialerOp
 		WriteBufferSize: 65536,
 	}
 
+	// Always "ws", as TLS is already negotiated in NetDial, and the WebSocket dialer would negotiate it
+	// again for "wss". The upgrade request is the same either way, as the scheme isn't sent. Only the URL in
+	// logs and errors tells it.
 	protocol := "ws"
 
 	if options.Stream != nil && options.Stream.HasSecuritySettings() {
-		protocol = "wss"
 		securitySettings, err := options.Stream.GetEffectiveSecuritySettings()
 		if err != nil {
 			log.Error("WebSocket: Failed to create security settings: ", err)
 			return nil, err
 		}
-		tlsConfig, ok := securitySettings.(*v2tls.Config)
-		if ok {
-			dialer.TLSClientConfig = tlsConfig.GetTLSConfig()
-			if dest.Address.Family().IsDomain() {
-				dialer.TLSClientConfig.ServerName = dest.Address.Domain()
+		if tlsSettings, ok := securitySettings.(*v2tls.Config); ok {
+			tlsConfig = tlsSettings.GetTLSConfig()
+			if len(tlsConfig.ServerName) == 0 && dest.Address.Family().IsDomain() {
+				tlsConfig.ServerName = dest.Address.Domain()
 			}
 		}
 	}
@@ -89,13 +108,22 @@ func wsDial(src v2net.Address, dest v2net.Destination, options internet.DialerOp
 		return fmt.Sprintf("%v://%v/%v", pto, dst.NetAddr(), path)
 	}(dest, protocol, wsSettings.Path)
 
-	conn, resp, err := dialer.Dial(uri, nil)
+	var header http.Header
+	if len(wsSettings.Host) > 0 {
+		header = http.Header{"Host": []string{wsSettings.Host}}
+	}
+
+	conn, resp, err := dialer.Dial(uri, header)
 	if err != nil {
 		if resp != nil {
 			reason, reasonerr := ioutil.ReadAll(resp.Body)
 			log.Info(string(reason), reasonerr)
 		}
-		return nil, err
+		var layerErr *internet.LayerError
+		if errors.As(err, &layerErr) {
+			return nil, layerErr
+		}
+		return nil, internet.NewLayerError(internet.LayerWebSocket, err)
 	}
 	return func() internet.Connection {
 		connv2ray := &wsconn{
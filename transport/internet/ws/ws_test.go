@@ -8,6 +8,7 @@ import (
 	"v2ray.com/core/common/loader"
 	v2net "v2ray.com/core/common/net"
 	"v2ray.com/core/testing/assert"
+	"v2ray.com/core/testing/servers/tcp"
 	"v2ray.com/core/transport/internet"
 	v2tls "v2ray.com/core/transport/internet/tls"
 	. "v2ray.com/core/transport/internet/ws"
@@ -158,3 +159,32 @@ func ReadFile(file string, assert *assert.Assert) []byte {
 	assert.Error(err).IsNil()
 	return b
 }
+
+func TestDialFailedLayer(t *testing.T) {
+	assert := assert.On(t)
+
+	// A plain TCP server fails both TLS and WebSocket upgrade.
+	tcpServer := &tcp.Server{
+		MsgProcessor: func(data []byte) []byte {
+			return []byte("HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n")
+		},
+	}
+	dest, err := tcpServer.Start()
+	assert.Error(err).IsNil()
+	defer tcpServer.Close()
+
+	stream := &internet.StreamConfig{
+		Network: v2net.Network_WebSocket,
+	}
+	_, err = Dial(v2net.AnyIP, dest, internet.DialerOptions{Stream: stream})
+	assert.String(internet.FailedLayer(err)).Equals(internet.LayerWebSocket)
+
+	stream.SecurityType = loader.GetType(new(v2tls.Config))
+	stream.SecuritySettings = []*loader.TypedSettings{loader.NewTypedSettings(&v2tls.Config{AllowInsecure: true})}
+	_, err = Dial(v2net.AnyIP, dest, internet.DialerOptions{Stream: stream})
+	assert.String(internet.FailedLayer(err)).Equals(internet.LayerTLS)
+
+	tcpServer.Close()
+	_, err = Dial(v2net.AnyIP, dest, internet.DialerOptions{Stream: stream})
+	assert.String(internet.FailedLayer(err)).Equals(internet.LayerTCP)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"v2ray.com/core/common/alloc"
@@ -25,6 +26,15 @@ func NewRay() Ray {
 	}
 }
 
+// LimitInFlight limits bytes in flight of both streams of link with limiter, if link is created by
+// NewRay. Bytes already in the streams are not counted.
+func LimitInFlight(link OutboundRay, limiter *InFlightLimiter) {
+	if direct, ok := link.(*directRay); ok {
+		direct.Input.SetInFlightLimiter(limiter)
+		direct.Output.SetInFlightLimiter(limiter)
+	}
+}
+
 type directRay struct {
 	Input  *Stream
 	Output *Stream
@@ -47,46 +57,154 @@ func (this *directRay) InboundOutput() InputStream {
 }
 
 type Stream struct {
-	access sync.RWMutex
-	closed bool
-	buffer chan *alloc.Buffer
+	access      sync.RWMutex
+	closed      bool
+	buffer      chan *alloc.Buffer
+	err         error
+	established chan struct{}
+	establish   sync.Once
+	limiter     *InFlightLimiter
+	// Bytes in the stream that are acquired in limiter.
+	inFlight int64
 }
 
 func NewStream() *Stream {
 	return &Stream{
-		buffer: make(chan *alloc.Buffer, bufferSize),
+		buffer:      make(chan *alloc.Buffer, bufferSize),
+		established: make(chan struct{}),
 	}
 }
 
-func (this *Stream) Read() (*alloc.Buffer, error) {
-	if this.buffer == nil {
-		return nil, io.EOF
+func (this *Stream) Established() {
+	this.establish.Do(func() {
+		close(this.established)
+	})
+}
+
+func (this *Stream) WaitEstablished() error {
+	<-this.established
+	return this.Err()
+}
+
+// SetInFlightLimiter limits bytes in flight of the stream with limiter. Writes wait until the bytes can be
+// in flight. It must be called at most once.
+func (this *Stream) SetInFlightLimiter(limiter *InFlightLimiter) {
+	this.access.Lock()
+	defer this.access.Unlock()
+	this.limiter = limiter
+}
+
+func (this *Stream) inFlightLimiter() *InFlightLimiter {
+	this.access.RLock()
+	defer this.access.RUnlock()
+	return this.limiter
+}
+
+// takeInFlight takes at most size bytes of those acquired in the limiter, and returns the number taken.
+func (this *Stream) takeInFlight(size int64) int64 {
+	for {
+		inFlight := atomic.LoadInt64(&this.inFlight)
+		taken := size
+		if taken > inFlight {
+			taken = inFlight
+		}
+		if taken == 0 || atomic.CompareAndSwapInt64(&this.inFlight, inFlight, inFlight-taken) {
+			return taken
+		}
 	}
+}
+
+// onRead releases bytes of data read from the stream in limiter, if any.
+func (this *Stream) onRead(limiter *InFlightLimiter, data *alloc.Buffer) {
+	if limiter != nil {
+		limiter.Release(int(this.takeInFlight(int64(data.Len()))))
+	}
+}
+
+func (this *Stream) Err() error {
+	this.access.RLock()
+	defer this.access.RUnlock()
+	return this.err
+}
+
+func (this *Stream) Read() (*alloc.Buffer, error) {
 	this.access.RLock()
 	if this.buffer == nil {
 		this.access.RUnlock()
 		return nil, io.EOF
 	}
 	channel := this.buffer
+	limiter := this.limiter
 	this.access.RUnlock()
 	result, open := <-channel
 	if !open {
 		return nil, io.EOF
 	}
+	this.onRead(limiter, result)
 	return result, nil
 }
 
+func (this *Stream) ReadTimeout(timeout time.Duration) (*alloc.Buffer, error) {
+	this.access.RLock()
+	if this.buffer == nil {
+		this.access.RUnlock()
+		return nil, io.EOF
+	}
+	channel := this.buffer
+	limiter := this.limiter
+	this.access.RUnlock()
+	select {
+	case result, open := <-channel:
+		if !open {
+			return nil, io.EOF
+		}
+		this.onRead(limiter, result)
+		return result, nil
+	case <-time.After(timeout):
+		return nil, ErrIOTimeout
+	}
+}
+
 func (this *Stream) Write(data *alloc.Buffer) error {
-	for !this.closed {
+	limiter := this.inFlightLimiter()
+	size := data.Len()
+	if limiter != nil {
+		if err := this.acquire(limiter, size); err != nil {
+			return err
+		}
+	}
+	for {
 		err := this.TryWriteOnce(data)
 		if err != ErrIOTimeout {
+			if err != nil && limiter != nil {
+				limiter.Release(int(this.takeInFlight(int64(size))))
+			}
 			return err
 		}
 	}
-	return io.EOF
+}
+
+// acquire waits until size bytes can be in flight in limiter. It returns io.EOF if the stream is closed
+// meanwhile. A stream without bytes in flight doesn't wait, so that streams whose readers stall can't stop
+// the other streams sharing the limiter.
+func (this *Stream) acquire(limiter *InFlightLimiter, size int) error {
+	idle := func() bool {
+		return atomic.LoadInt64(&this.inFlight) == 0
+	}
+	for !limiter.tryAcquire(size, 2*time.Second, idle) {
+		this.access.RLock()
+		closed := this.closed
+		this.access.RUnlock()
+		if closed {
+			return io.EOF
+		}
+	}
+	atomic.AddInt64(&this.inFlight, int64(size))
+	return nil
 }
 
 func (this *Stream) TryWriteOnce(data *alloc.Buffer) error {
+	this.Established()
 	this.access.RLock()
 	defer this.access.RUnlock()
 	if this.closed {
@@ -101,22 +219,24 @@ func (this *Stream) TryWriteOnce(data *alloc.Buffer) error {
 }
 
 func (this *Stream) Close() {
-	if this.closed {
-		return
-	}
+	this.CloseError(nil)
+}
+
+// CloseError closes the stream with err. It has no effect if the stream is already closed.
+func (this *Stream) CloseError(err error) {
 	this.access.Lock()
-	defer this.access.Unlock()
 	if this.closed {
+		this.access.Unlock()
 		return
 	}
 	this.closed = true
+	this.err = err
 	close(this.buffer)
+	this.access.Unlock()
+	this.Established()
 }
 
 func (this *Stream) Release() {
-	if this.buffer == nil {
-		return
-	}
 	this.Close()
 	this.access.Lock()
 	defer this.access.Unlock()
@@ -127,4 +247,7 @@ func (this *Stream) Release() {
 		data.Release()
 	}
 	this.buffer = nil
+	if this.limiter != nil {
+		this.limiter.Release(int(this.takeInFlight(atomic.LoadInt64(&this.inFlight))))
+	}
 }
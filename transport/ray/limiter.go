@@ -0,0 +1,88 @@
+package ray
+
+import (
+	"sync"
+	"time"
+)
+
+// InFlightLimiter limits bytes in flight of all streams sharing it. Bytes are in flight from being written
+// to a stream until they are read from it, or the stream is released. Each stream may always have one
+// write in flight, so the total may exceed the limit by a write of each stream.
+type InFlightLimiter struct {
+	access   sync.Mutex
+	max      int64
+	inFlight int64
+	waiting  int
+	// Closed when bytes are released while writers are waiting.
+	released chan struct{}
+}
+
+// NewInFlightLimiter creates a limiter of max bytes in flight.
+func NewInFlightLimiter(max int64) *InFlightLimiter {
+	return &InFlightLimiter{
+		max:      max,
+		released: make(chan struct{}),
+	}
+}
+
+// TryAcquire waits until size bytes can be in flight, for at most timeout. It returns false if they still
+// can't after timeout. Writes larger than the limit are accepted when nothing else is in flight, so they
+// don't wait forever.
+func (this *InFlightLimiter) TryAcquire(size int, timeout time.Duration) bool {
+	return this.tryAcquire(size, timeout, nil)
+}
+
+// tryAcquire is TryAcquire() for a stream, which doesn't wait whenever idle returns true, i.e., the stream
+// has nothing in flight.
+func (this *InFlightLimiter) tryAcquire(size int, timeout time.Duration, idle func() bool) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		this.access.Lock()
+		if this.inFlight == 0 || this.inFlight+int64(size) <= this.max || (idle != nil && idle()) {
+			this.inFlight += int64(size)
+			this.access.Unlock()
+			return true
+		}
+		this.waiting++
+		released := this.released
+		this.access.Unlock()
+
+		var timedOut bool
+		select {
+		case <-released:
+		case <-timer.C:
+			timedOut = true
+		}
+
+		this.access.Lock()
+		this.waiting--
+		this.access.Unlock()
+		if timedOut {
+			return false
+		}
+	}
+}
+
+// Release releases size bytes acquired before.
+func (this *InFlightLimiter) Release(size int) {
+	if size == 0 {
+		return
+	}
+	this.access.Lock()
+	defer this.access.Unlock()
+
+	this.inFlight -= int64(size)
+	if this.waiting > 0 {
+		close(this.released)
+		this.released = make(chan struct{})
+	}
+}
+
+// InFlight returns bytes in flight at the moment.
+func (this *InFlightLimiter) InFlight() int64 {
+	this.access.Lock()
+	defer this.access.Unlock()
+	return this.inFlight
+}
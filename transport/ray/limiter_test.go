@@ -0,0 +1,109 @@
+package ray_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"v2ray.com/core/common/alloc"
+	"v2ray.com/core/testing/assert"
+	. "v2ray.com/core/transport/ray"
+)
+
+const chunkSize = 1024
+
+// writeChunks writes chunks to stream until it fails, and sends the total bytes written after each chunk.
+// written is closed when writing stops.
+func writeChunks(stream OutputStream, written chan<- int) {
+	defer close(written)
+	total := 0
+	for stream.Write(alloc.NewLocalBuffer(chunkSize).Clear().Append(make([]byte, chunkSize))) == nil {
+		total += chunkSize
+		written <- total
+	}
+}
+
+// lastWritten returns the latest total from written, once it stops growing for a while.
+func lastWritten(written <-chan int, total int) int {
+	for {
+		select {
+		case value, open := <-written:
+			if !open {
+				return total
+			}
+			total = value
+		case <-time.After(200 * time.Millisecond):
+			return total
+		}
+	}
+}
+
+func TestInFlightBytesUnderStalledReader(t *testing.T) {
+	assert := assert.On(t)
+
+	limiter := NewInFlightLimiter(16 * chunkSize)
+	stalled := NewRay()
+	LimitInFlight(stalled, limiter)
+	other := NewRay()
+	LimitInFlight(other, limiter)
+
+	// Nothing reads the stalled connection, so far fewer bytes than the capacity of the stream are buffered.
+	written := make(chan int, 1024)
+	go writeChunks(stalled.InboundInput(), written)
+	stalledTotal := lastWritten(written, 0)
+	assert.Int(stalledTotal).Equals(16 * chunkSize)
+	assert.Int64(limiter.InFlight()).Equals(16 * chunkSize)
+
+	// Other connections of the outbound still move a chunk at a time, beyond the limit.
+	otherWritten := make(chan int, 1024)
+	go writeChunks(other.OutboundOutput(), otherWritten)
+	otherTotal := lastWritten(otherWritten, 0)
+	assert.Int(otherTotal).Equals(chunkSize)
+	assert.Int64(limiter.InFlight()).Equals(17 * chunkSize)
+	for i := 0; i < 4; i++ {
+		buffer, err := other.InboundOutput().Read()
+		assert.Error(err).IsNil()
+		buffer.Release()
+		otherTotal = lastWritten(otherWritten, otherTotal)
+		assert.Int(otherTotal).Equals((i + 2) * chunkSize)
+	}
+
+	// Draining the stalled connection lets writers continue, up to the limit again.
+	for i := 0; i < 4; i++ {
+		buffer, err := stalled.OutboundInput().Read()
+		assert.Error(err).IsNil()
+		buffer.Release()
+	}
+	stalledTotal = lastWritten(written, stalledTotal)
+	otherTotal = lastWritten(otherWritten, otherTotal)
+	assert.Int(stalledTotal + otherTotal).Equals(24 * chunkSize)
+	assert.Int64(limiter.InFlight()).Equals(16 * chunkSize)
+
+	// Bytes of released streams are no longer in flight, and writers waiting on them stop.
+	stalled.OutboundInput().Release()
+	other.InboundOutput().Release()
+	for range written {
+	}
+	for range otherWritten {
+	}
+	assert.Int64(limiter.InFlight()).Equals(0)
+}
+
+func TestInFlightLimiterAcceptsLargeWrite(t *testing.T) {
+	assert := assert.On(t)
+
+	limiter := NewInFlightLimiter(chunkSize)
+	link := NewRay()
+	LimitInFlight(link, limiter)
+
+	assert.Error(link.InboundInput().Write(alloc.NewLocalBuffer(2 * chunkSize).Clear().Append(make([]byte, 2*chunkSize)))).IsNil()
+	assert.Bool(limiter.TryAcquire(1, 100*time.Millisecond)).IsFalse()
+
+	link.InboundInput().Close()
+	buffer, err := link.OutboundInput().Read()
+	assert.Error(err).IsNil()
+	assert.Int(buffer.Len()).Equals(2 * chunkSize)
+	_, err = link.OutboundInput().Read()
+	assert.Error(err).Equals(io.EOF)
+	assert.Int64(limiter.InFlight()).Equals(0)
+}
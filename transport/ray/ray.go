@@ -1,6 +1,9 @@
 package ray
 
 import (
+	"time"
+
+	"v2ray.com/core/common/alloc"
 	v2io "v2ray.com/core/common/io"
 )
 
@@ -37,10 +40,21 @@ type Ray interface {
 
 type InputStream interface {
 	v2io.Reader
+	// ReadTimeout reads like Read(), but returns ErrIOTimeout if nothing is available within timeout.
+	ReadTimeout(timeout time.Duration) (*alloc.Buffer, error)
+	// WaitEstablished blocks until the writer establishes its connection, writes any data, or closes the
+	// stream. It returns the error that the stream is closed with, if any.
+	WaitEstablished() error
+	// Err returns the error that the stream is closed with, or nil if it is open or closed normally.
+	Err() error
 	Close()
 }
 
 type OutputStream interface {
 	v2io.Writer
+	// Established tells the reader that the connection of the writer is established.
+	Established()
+	// CloseError closes the stream with err, e.g., the reason that an outbound fails to connect.
+	CloseError(err error)
 	Close()
 }
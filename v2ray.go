@@ -1,6 +1,8 @@
 package core
 
 import (
+	"sync/atomic"
+
 	"v2ray.com/core/app"
 	"v2ray.com/core/app/dispatcher"
 	dispatchers "v2ray.com/core/app/dispatcher/impl"
@@ -22,13 +24,30 @@ type Point struct {
 	outboundHandlers       []proxy.OutboundHandler
 	taggedOutboundHandlers map[string]proxy.OutboundHandler
 
+	// *loadedConfig, which is replaced on reload.
+	loaded atomic.Value
+
 	space app.Space
+	// Hooks of connections of all outbounds, or nil.
+	hooks proxy.ConnectionHooks
+	// Taken by a reload or Close in progress, so that they don't interleave.
+	reloadSlot chan bool
+	// Whether the Point is closed, which is guarded by reloadSlot.
+	closed bool
 }
 
 // NewPoint returns a new Point server based on given configuration.
 // The server is not started at this point.
 func NewPoint(pConfig *Config) (*Point, error) {
+	return NewPointWithHooks(pConfig, nil)
+}
+
+// NewPointWithHooks is NewPoint with hooks called by outbound handlers on their connections, including
+// handlers created on reload. Outbounds that don't support hooks ignore them.
+func NewPointWithHooks(pConfig *Config, hooks proxy.ConnectionHooks) (*Point, error) {
 	var vpoint = new(Point)
+	vpoint.hooks = hooks
+	vpoint.reloadSlot = make(chan bool, 1)
 
 	if err := pConfig.Transport.Apply(); err != nil {
 		return nil, err
@@ -46,6 +65,7 @@ func NewPoint(pConfig *Config) (*Point, error) {
 	vpoint.space.BindApp(proxyman.APP_ID_OUTBOUND_MANAGER, outboundHandlerManager)
 
 	proxyDialer := proxydialer.NewOutboundProxy(space)
+	proxyDialer.SetFailClosed(pConfig.FailClosed)
 	proxyDialer.RegisterDialer()
 	space.BindApp(proxydialer.APP_ID, proxyDialer)
 
@@ -60,21 +80,13 @@ func NewPoint(pConfig *Config) (*Point, error) {
 	}
 
 	if !space.HasApp(dns.APP_ID) {
-		dnsServer := dns.NewCacheServer(space, &dns.Config{
-			NameServers: []*v2net.Endpoint{
-				{
-					Address: &v2net.IPOrDomain{
-						Address: &v2net.IPOrDomain_Domain{
-							Domain: "localhost",
-						},
-					},
-				},
-			},
-		})
+		dnsServer := dns.NewCacheServer(space, defaultDNSConfig())
 		space.BindApp(dns.APP_ID, dnsServer)
 	}
 
-	vpoint.space.BindApp(dispatcher.APP_ID, dispatchers.NewDefaultDispatcher(vpoint.space))
+	defaultDispatcher := dispatchers.NewDefaultDispatcher(vpoint.space)
+	defaultDispatcher.SetFailClosed(pConfig.FailClosed)
+	vpoint.space.BindApp(dispatcher.APP_ID, defaultDispatcher)
 
 	vpoint.inboundHandlers = make([]InboundDetourHandler, 0, 8)
 	vpoint.taggedInboundHandlers = make(map[string]InboundDetourHandler)
@@ -106,46 +118,103 @@ func NewPoint(pConfig *Config) (*Point, error) {
 		}
 	}
 
-	vpoint.outboundHandlers = make([]proxy.OutboundHandler, 0, 8)
-	vpoint.taggedOutboundHandlers = make(map[string]proxy.OutboundHandler)
-	for idx, outbound := range pConfig.Outbound {
+	defaultHandler, outboundHandlers, taggedOutboundHandlers, err := createOutboundHandlers(vpoint.space, pConfig.Outbound, hooks)
+	if err != nil {
+		return nil, err
+	}
+	outboundHandlerManager.ReplaceHandlers(defaultHandler, taggedOutboundHandlers)
+	vpoint.outboundHandlers = outboundHandlers
+	vpoint.taggedOutboundHandlers = taggedOutboundHandlers
+	vpoint.setLoadedConfig(pConfig, outboundHandlers)
+	vpoint.registerConfigApi(vpoint.space)
+
+	if err := vpoint.space.Initialize(); err != nil {
+		return nil, err
+	}
+
+	return vpoint, nil
+}
+
+// defaultDNSConfig returns the DNS config used if none is configured, which resolves domains by the
+// system.
+func defaultDNSConfig() *dns.Config {
+	return &dns.Config{
+		NameServers: []*v2net.Endpoint{
+			{
+				Address: &v2net.IPOrDomain{
+					Address: &v2net.IPOrDomain_Domain{
+						Domain: "localhost",
+					},
+				},
+			},
+		},
+	}
+}
+
+// createOutboundHandlers creates handlers of all outbound configs, with hooks of their connections. It
+// returns the default handler, all handlers and tagged handlers by tag.
+func createOutboundHandlers(space app.Space, configs []*OutboundConnectionConfig, hooks proxy.ConnectionHooks) (proxy.OutboundHandler, []proxy.OutboundHandler, map[string]proxy.OutboundHandler, error) {
+	var defaultHandler proxy.OutboundHandler
+	handlers := make([]proxy.OutboundHandler, 0, 8)
+	taggedHandlers := make(map[string]proxy.OutboundHandler)
+	for idx, outbound := range configs {
 		outboundSettings, err := outbound.GetTypedSettings()
 		if err != nil {
-			return nil, err
+			closeOutboundHandlers(handlers)
+			return nil, nil, nil, err
 		}
 		outboundHandler, err := proxyregistry.CreateOutboundHandler(
-			outbound.Settings.Type, vpoint.space, outboundSettings, &proxy.OutboundHandlerMeta{
+			outbound.Settings.Type, space, outboundSettings, &proxy.OutboundHandlerMeta{
 				Tag:            outbound.Tag,
 				Address:        outbound.GetSendThroughValue(),
 				StreamSettings: outbound.StreamSettings,
 				ProxySettings:  outbound.ProxySettings,
+				Hooks:          hooks,
 			})
 		if err != nil {
 			log.Error("Point: Failed to create detour outbound connection handler: ", err)
-			return nil, err
+			closeOutboundHandlers(handlers)
+			return nil, nil, nil, err
+		}
+		if outbound.MaxInFlightBytes > 0 {
+			outboundHandler = proxy.NewFlowControlledOutboundHandler(outboundHandler, int64(outbound.MaxInFlightBytes))
 		}
 		if idx == 0 {
-			outboundHandlerManager.SetDefaultHandler(outboundHandler)
+			defaultHandler = outboundHandler
 		}
 		if len(outbound.Tag) > 0 {
-			outboundHandlerManager.SetHandler(outbound.Tag, outboundHandler)
-			vpoint.taggedOutboundHandlers[outbound.Tag] = outboundHandler
+			taggedHandlers[outbound.Tag] = outboundHandler
 		}
 
-		vpoint.outboundHandlers = append(vpoint.outboundHandlers, outboundHandler)
+		handlers = append(handlers, outboundHandler)
 	}
+	return defaultHandler, handlers, taggedHandlers, nil
+}
 
-	if err := vpoint.space.Initialize(); err != nil {
-		return nil, err
+// closeOutboundHandlers stops background work of handlers, e.g., subscription refreshing.
+func closeOutboundHandlers(handlers []proxy.OutboundHandler) {
+	for _, handler := range handlers {
+		proxy.CloseOutboundHandler(handler)
 	}
-
-	return vpoint, nil
 }
 
+// Close stops all inbounds and outbounds, after the reload in progress, if any. It does nothing if the Point
+// is already closed.
 func (this *Point) Close() {
+	this.reloadSlot <- true
+	defer func() {
+		<-this.reloadSlot
+	}()
+
+	if this.closed {
+		return
+	}
+	this.closed = true
+
 	for _, inbound := range this.inboundHandlers {
 		inbound.Close()
 	}
+	closeOutboundHandlers(this.outboundHandlers)
 }
 
 // Start starts the Point server, and return any error during the process.